@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/agent"
+)
+
+// runAgent serves the agent API on a monitored host, so that the "agent"
+// check runner of a gogios daemon can run that host's checks locally.
+func runAgent(args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s agent --token-file <file> [--listen <addr>] [--ssl-cert <file> --ssl-key <file>] [--max-concurrent <n>] [--timeout <seconds>]\n", os.Args[0])
+		os.Exit(1)
+	}
+	cfg := agent.ServerConfig{Listen: ":" + strconv.Itoa(agent.DefaultPort)}
+	var tokenFile string
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			usage()
+		}
+		val := args[i+1]
+		switch args[i] {
+		case "--listen":
+			cfg.Listen = val
+		case "--token-file":
+			tokenFile = val
+		case "--ssl-cert":
+			cfg.SSLCert = val
+		case "--ssl-key":
+			cfg.SSLKey = val
+		case "--max-concurrent", "--timeout":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				usage()
+			}
+			if args[i] == "--timeout" {
+				cfg.DefaultTimeout = time.Duration(n) * time.Second
+			} else {
+				cfg.MaxConcurrent = n
+			}
+		default:
+			usage()
+		}
+		i++
+	}
+	if tokenFile == "" {
+		usage()
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	cfg.Token = strings.TrimSpace(string(data))
+
+	srv, err := agent.NewServer(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	addr, err := srv.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("gogios agent listening on %s\n", addr)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+	srv.Stop()
+}
@@ -0,0 +1,1691 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/checker"
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/extcmd"
+	"github.com/oceanplexian/gogios/internal/logging"
+	"github.com/oceanplexian/gogios/internal/notify"
+	"github.com/oceanplexian/gogios/internal/objects"
+	"github.com/oceanplexian/gogios/internal/resultq"
+	"github.com/oceanplexian/gogios/internal/scheduler"
+)
+
+// parseDowntimeArgs reads the downtime arguments that follow the object
+// name in the downtime commands: start;end;fixed;trigger_id;duration;
+// author;comment. The caller fills in the object and ensures there are
+// seven arguments.
+func parseDowntimeArgs(dtType int, args []string) *downtime.Downtime {
+	var startTS, endTS, triggerID, duration int64
+	fmt.Sscanf(args[0], "%d", &startTS)
+	fmt.Sscanf(args[1], "%d", &endTS)
+	fmt.Sscanf(args[3], "%d", &triggerID)
+	fmt.Sscanf(args[4], "%d", &duration)
+	return &downtime.Downtime{
+		Type:        dtType,
+		StartTime:   time.Unix(startTS, 0),
+		EndTime:     time.Unix(endTS, 0),
+		Fixed:       args[2] == "1",
+		TriggeredBy: uint64(triggerID),
+		Duration:    time.Duration(duration) * time.Second,
+		Author:      args[5],
+		Comment:     args[6],
+	}
+}
+
+// registerCommandHandlers registers the daemon's handlers for external
+// commands with p.
+func registerCommandHandlers(
+	p *extcmd.Processor,
+	store *objects.ObjectStore,
+	gs *objects.GlobalState,
+	sched *scheduler.Scheduler,
+	notifEngine *notify.NotificationEngine,
+	commentMgr *downtime.CommentManager,
+	downtimeMgr *downtime.DowntimeManager,
+	blackoutMgr *downtime.BlackoutManager,
+	eventHandlers *checker.EventHandlers,
+	logger *logging.Logger,
+	results *resultq.Queue,
+) {
+	// System commands
+	p.RegisterHandler("ENABLE_NOTIFICATIONS", func(cmd *extcmd.Command) {
+		gs.EnableNotifications = true
+		logger.Log("EXTERNAL COMMAND: ENABLE_NOTIFICATIONS")
+	})
+	p.RegisterHandler("DISABLE_NOTIFICATIONS", func(cmd *extcmd.Command) {
+		gs.EnableNotifications = false
+		logger.Log("EXTERNAL COMMAND: DISABLE_NOTIFICATIONS")
+	})
+	p.RegisterHandler("START_EXECUTING_SVC_CHECKS", func(cmd *extcmd.Command) {
+		gs.ExecuteServiceChecks = true
+		logger.Log("EXTERNAL COMMAND: START_EXECUTING_SVC_CHECKS")
+	})
+	p.RegisterHandler("STOP_EXECUTING_SVC_CHECKS", func(cmd *extcmd.Command) {
+		gs.ExecuteServiceChecks = false
+		logger.Log("EXTERNAL COMMAND: STOP_EXECUTING_SVC_CHECKS")
+	})
+	// Gogios extension: hold all active check dispatching, e.g. while the
+	// storage plugins write to is down, without stopping the process.
+	p.RegisterHandler("PAUSE_SCHEDULER", func(cmd *extcmd.Command) {
+		logger.Log("EXTERNAL COMMAND: PAUSE_SCHEDULER")
+		if !sched.Pause() {
+			return
+		}
+		gs.SchedulerPaused = true
+		gs.SchedulerPausedSince = time.Now()
+		logger.Log("Scheduler paused: no active checks will be dispatched until RESUME_SCHEDULER")
+	})
+	p.RegisterHandler("RESUME_SCHEDULER", func(cmd *extcmd.Command) {
+		logger.Log("EXTERNAL COMMAND: RESUME_SCHEDULER")
+		if !sched.Resume() {
+			return
+		}
+		logger.Log("Scheduler resumed after %s paused", time.Since(gs.SchedulerPausedSince).Round(time.Second))
+		gs.SchedulerPaused = false
+		gs.SchedulerPausedSince = time.Time{}
+	})
+	// Gogios extension: blackout windows suppress notifications for matching
+	// objects without touching contacts or timeperiods.
+	// ADD_BLACKOUT;<name>;<start_time>;<end_time>;<host_name>;<hostgroup_name>;<service_description>;<servicegroup_name>;<author>;<comment>
+	p.RegisterHandler("ADD_BLACKOUT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 9 || cmd.Args[0] == "" {
+			cmd.Fail("blackout name is required")
+			return
+		}
+		b := &objects.Blackout{
+			Name:                cmd.Args[0],
+			HostNames:           splitCommandList(cmd.Args[3]),
+			HostGroups:          splitCommandList(cmd.Args[4]),
+			ServiceDescriptions: splitCommandList(cmd.Args[5]),
+			ServiceGroups:       splitCommandList(cmd.Args[6]),
+			Author:              cmd.Args[7],
+			Comment:             strings.Join(cmd.Args[8:], ";"),
+			Runtime:             true,
+		}
+		var startTS, endTS int64
+		fmt.Sscanf(cmd.Args[1], "%d", &startTS)
+		fmt.Sscanf(cmd.Args[2], "%d", &endTS)
+		if startTS > 0 {
+			b.StartTime = time.Unix(startTS, 0)
+		}
+		if endTS > 0 {
+			b.EndTime = time.Unix(endTS, 0)
+		}
+		if !b.EndTime.IsZero() && (!b.EndTime.After(b.StartTime) || !b.EndTime.After(time.Now())) {
+			logger.Log("Warning: Refusing ADD_BLACKOUT '%s': end time is not in the future or not after the start time", b.Name)
+			cmd.Fail("end time is not in the future or not after the start time")
+			return
+		}
+		for _, hg := range b.HostGroups {
+			if store.GetHostGroup(hg) == nil {
+				logger.Log("Warning: Refusing ADD_BLACKOUT '%s': hostgroup '%s' not found", b.Name, hg)
+				cmd.Fail("hostgroup '%s' not found", hg)
+				return
+			}
+		}
+		for _, sg := range b.ServiceGroups {
+			if store.GetServiceGroup(sg) == nil {
+				logger.Log("Warning: Refusing ADD_BLACKOUT '%s': servicegroup '%s' not found", b.Name, sg)
+				cmd.Fail("servicegroup '%s' not found", sg)
+				return
+			}
+		}
+		blackoutMgr.Add(b)
+		logger.Log("EXTERNAL COMMAND: ADD_BLACKOUT;%s", b.Name)
+	})
+	p.RegisterHandler("DEL_BLACKOUT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		if !blackoutMgr.Remove(cmd.Args[0]) {
+			logger.Log("Warning: DEL_BLACKOUT: no blackout named '%s'", cmd.Args[0])
+			cmd.Fail("no blackout named '%s'", cmd.Args[0])
+			return
+		}
+		logger.Log("EXTERNAL COMMAND: DEL_BLACKOUT;%s", cmd.Args[0])
+	})
+	p.RegisterHandler("START_EXECUTING_HOST_CHECKS", func(cmd *extcmd.Command) {
+		gs.ExecuteHostChecks = true
+		logger.Log("EXTERNAL COMMAND: START_EXECUTING_HOST_CHECKS")
+	})
+	p.RegisterHandler("STOP_EXECUTING_HOST_CHECKS", func(cmd *extcmd.Command) {
+		gs.ExecuteHostChecks = false
+		logger.Log("EXTERNAL COMMAND: STOP_EXECUTING_HOST_CHECKS")
+	})
+	p.RegisterHandler("ENABLE_EVENT_HANDLERS", func(cmd *extcmd.Command) {
+		gs.EnableEventHandlers = true
+		logger.Log("EXTERNAL COMMAND: ENABLE_EVENT_HANDLERS")
+	})
+	p.RegisterHandler("DISABLE_EVENT_HANDLERS", func(cmd *extcmd.Command) {
+		gs.EnableEventHandlers = false
+		logger.Log("EXTERNAL COMMAND: DISABLE_EVENT_HANDLERS")
+	})
+	p.RegisterHandler("ENABLE_FLAP_DETECTION", func(cmd *extcmd.Command) {
+		gs.EnableFlapDetection = true
+		logger.Log("EXTERNAL COMMAND: ENABLE_FLAP_DETECTION")
+	})
+	p.RegisterHandler("DISABLE_FLAP_DETECTION", func(cmd *extcmd.Command) {
+		gs.EnableFlapDetection = false
+		logger.Log("EXTERNAL COMMAND: DISABLE_FLAP_DETECTION")
+	})
+
+	// Process passive check results
+	p.RegisterHandler("PROCESS_SERVICE_CHECK_RESULT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 4 {
+			return
+		}
+		hostName := cmd.Args[0]
+		svcDesc := cmd.Args[1]
+		rc := 0
+		fmt.Sscanf(cmd.Args[2], "%d", &rc)
+		output := cmd.Args[3]
+
+		svc := store.GetService(hostName, svcDesc)
+		if svc == nil {
+			cmd.Fail("service '%s' on host '%s' not found", svcDesc, hostName)
+			return
+		}
+		now := time.Now()
+		sched.SendCommand(scheduler.Command{Name: "_INTERNAL_RESULT"})
+		// Send directly as check result
+		cr := &objects.CheckResult{
+			HostName:           hostName,
+			ServiceDescription: svcDesc,
+			CheckType:          objects.CheckTypePassive,
+			ReturnCode:         rc,
+			Output:             output,
+			StartTime:          now,
+			FinishTime:         now,
+			ExitedOK:           true,
+			Source:             cmd.Source,
+		}
+		// Queued without blocking: we're on the command handler goroutine
+		// holding the store lock the scheduler needs to drain results.
+		if !results.Submit(cr) {
+			logger.Log("Warning: Result queue full, dropping passive check result for service '%s' on host '%s'", svcDesc, hostName)
+			cmd.Fail("result queue full")
+		}
+	})
+
+	p.RegisterHandler("PROCESS_HOST_CHECK_RESULT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 3 {
+			return
+		}
+		hostName := cmd.Args[0]
+		rc := 0
+		fmt.Sscanf(cmd.Args[1], "%d", &rc)
+		output := cmd.Args[2]
+
+		host := store.GetHost(hostName)
+		if host == nil {
+			cmd.Fail("host '%s' not found", hostName)
+			return
+		}
+		now := time.Now()
+		cr := &objects.CheckResult{
+			HostName:   hostName,
+			CheckType:  objects.CheckTypePassive,
+			ReturnCode: rc,
+			Output:     output,
+			StartTime:  now,
+			FinishTime: now,
+			ExitedOK:   true,
+			Source:     cmd.Source,
+		}
+		if !results.Submit(cr) {
+			logger.Log("Warning: Result queue full, dropping passive check result for host '%s'", hostName)
+			cmd.Fail("result queue full")
+		}
+	})
+
+	// Schedule forced checks
+	p.RegisterHandler("SCHEDULE_FORCED_SVC_CHECK", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 3 {
+			return
+		}
+		hostName := cmd.Args[0]
+		svcDesc := cmd.Args[1]
+		var checkTime int64
+		fmt.Sscanf(cmd.Args[2], "%d", &checkTime)
+		sched.AddEvent(&scheduler.Event{
+			Type:               scheduler.EventServiceCheck,
+			RunTime:            time.Unix(checkTime, 0),
+			HostName:           hostName,
+			ServiceDescription: svcDesc,
+			CheckOptions:       objects.CheckOptionForceExecution,
+		})
+		logger.Log("EXTERNAL COMMAND: SCHEDULE_FORCED_SVC_CHECK;%s;%s;%d", hostName, svcDesc, checkTime)
+	})
+
+	p.RegisterHandler("SCHEDULE_FORCED_HOST_CHECK", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 2 {
+			return
+		}
+		hostName := cmd.Args[0]
+		var checkTime int64
+		fmt.Sscanf(cmd.Args[1], "%d", &checkTime)
+		sched.AddEvent(&scheduler.Event{
+			Type:         scheduler.EventHostCheck,
+			RunTime:      time.Unix(checkTime, 0),
+			HostName:     hostName,
+			CheckOptions: objects.CheckOptionForceExecution,
+		})
+		logger.Log("EXTERNAL COMMAND: SCHEDULE_FORCED_HOST_CHECK;%s;%d", hostName, checkTime)
+	})
+
+	// Acknowledge problems
+	p.RegisterHandler("ACKNOWLEDGE_SVC_PROBLEM", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 7 {
+			return
+		}
+		hostName := cmd.Args[0]
+		svcDesc := cmd.Args[1]
+		svc := store.GetService(hostName, svcDesc)
+		if svc == nil {
+			cmd.Fail("service '%s' on host '%s' not found", svcDesc, hostName)
+			return
+		}
+		sticky := cmd.Args[2] == "2"
+		sendNotif, notifOpts := ackNotify(cmd.Args[3])
+		// persistent := cmd.Args[4] == "1"
+		author := cmd.Args[5]
+		comment := cmd.Args[6]
+
+		if sticky {
+			svc.AckType = objects.AckSticky
+		} else {
+			svc.AckType = objects.AckNormal
+		}
+		svc.ProblemAcknowledged = true
+
+		if sendNotif {
+			notifEngine.ServiceNotification(svc, objects.NotificationAcknowledgement, author, comment, notifOpts)
+		}
+		logger.Log("EXTERNAL COMMAND: ACKNOWLEDGE_SVC_PROBLEM;%s;%s", hostName, svcDesc)
+	})
+
+	p.RegisterHandler("ACKNOWLEDGE_HOST_PROBLEM", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 6 {
+			return
+		}
+		hostName := cmd.Args[0]
+		host := store.GetHost(hostName)
+		if host == nil {
+			cmd.Fail("host '%s' not found", hostName)
+			return
+		}
+		// The sticky field takes 0-2 as in Nagios; adding 4 (propagate)
+		// also acknowledges the host's services that have a problem.
+		var flags int
+		fmt.Sscanf(cmd.Args[1], "%d", &flags)
+		ackType := objects.AckNormal
+		if flags&^4 == 2 {
+			ackType = objects.AckSticky
+		}
+		sendNotif, notifOpts := ackNotify(cmd.Args[2])
+		author := cmd.Args[4]
+		comment := cmd.Args[5]
+
+		host.AckType = ackType
+		host.ProblemAcknowledged = true
+
+		if sendNotif {
+			notifEngine.HostNotification(host, objects.NotificationAcknowledgement, author, comment, notifOpts)
+		}
+		logger.Log("EXTERNAL COMMAND: ACKNOWLEDGE_HOST_PROBLEM;%s", hostName)
+
+		if flags&4 == 0 {
+			return
+		}
+		for _, svc := range store.GetServicesForHost(hostName) {
+			if svc.CurrentState == objects.ServiceOK || svc.ProblemAcknowledged {
+				continue
+			}
+			svc.AckType = ackType
+			svc.ProblemAcknowledged = true
+			if sendNotif {
+				notifEngine.ServiceNotification(svc, objects.NotificationAcknowledgement, author, comment, notifOpts)
+			}
+		}
+	})
+
+	// Gogios extensions: acknowledge every host, or every service, whose
+	// custom variables match a selector such as _ENV=staging, or whose tags
+	// match one such as env=staging, and that has an unacknowledged
+	// problem. The selector replaces the host name; the other arguments are
+	// those of ACKNOWLEDGE_HOST_PROBLEM, without the propagate flag.
+	parseCustomVarSelector := func(s string) (objects.Selector, error) {
+		sel, err := objects.ParseCustomVarSelector(s)
+		return sel, err
+	}
+	parseTagSelector := func(s string) (objects.Selector, error) {
+		sel, err := objects.ParseTagSelector(s)
+		return sel, err
+	}
+	acknowledgeSelector := func(cmdName string, services bool, parse func(string) (objects.Selector, error)) func(*extcmd.Command) {
+		return func(cmd *extcmd.Command) {
+			if len(cmd.Args) < 6 {
+				return
+			}
+			sel, err := parse(cmd.Args[0])
+			if err != nil {
+				logger.Log("Warning: Ignoring %s: %v", cmdName, err)
+				cmd.Fail("%v", err)
+				return
+			}
+			ackType := objects.AckNormal
+			if cmd.Args[1] == "2" {
+				ackType = objects.AckSticky
+			}
+			sendNotif, notifOpts := ackNotify(cmd.Args[2])
+			author := cmd.Args[4]
+			comment := cmd.Args[5]
+			acked := 0
+			if services {
+				for _, svc := range store.SelectServices(sel) {
+					if svc.CurrentState == objects.ServiceOK || svc.ProblemAcknowledged {
+						continue
+					}
+					svc.AckType = ackType
+					svc.ProblemAcknowledged = true
+					acked++
+					if sendNotif {
+						notifEngine.ServiceNotification(svc, objects.NotificationAcknowledgement, author, comment, notifOpts)
+					}
+				}
+			} else {
+				for _, h := range store.SelectHosts(sel) {
+					if h.CurrentState == objects.HostUp || h.ProblemAcknowledged {
+						continue
+					}
+					h.AckType = ackType
+					h.ProblemAcknowledged = true
+					acked++
+					if sendNotif {
+						notifEngine.HostNotification(h, objects.NotificationAcknowledgement, author, comment, notifOpts)
+					}
+				}
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s;%d acknowledged", cmdName, cmd.Args[0], acked)
+		}
+	}
+	p.RegisterHandler("ACKNOWLEDGE_CUSTOMVAR_HOST_PROBLEM", acknowledgeSelector("ACKNOWLEDGE_CUSTOMVAR_HOST_PROBLEM", false, parseCustomVarSelector))
+	p.RegisterHandler("ACKNOWLEDGE_CUSTOMVAR_SVC_PROBLEM", acknowledgeSelector("ACKNOWLEDGE_CUSTOMVAR_SVC_PROBLEM", true, parseCustomVarSelector))
+	p.RegisterHandler("ACKNOWLEDGE_TAG_HOST_PROBLEM", acknowledgeSelector("ACKNOWLEDGE_TAG_HOST_PROBLEM", false, parseTagSelector))
+	p.RegisterHandler("ACKNOWLEDGE_TAG_SVC_PROBLEM", acknowledgeSelector("ACKNOWLEDGE_TAG_SVC_PROBLEM", true, parseTagSelector))
+
+	// Schedule downtimes. With downtime_no_overlap set, a downtime that
+	// overlaps one on the same object is refused.
+	//
+	// scheduleDowntime validates and schedules d for cmdName, logging a
+	// warning naming object and returning the reason when it is refused. armDowntime then starts a
+	// fixed downtime whose start time has passed and sets its end timer;
+	// it is separate so the EXTERNAL COMMAND line is logged first.
+	scheduleDowntime := func(cmdName, object string, d *downtime.Downtime) (uint64, error) {
+		if err := downtimeMgr.Validate(d, time.Now()); err != nil {
+			logger.Log("Warning: Refusing %s for %s: %v", cmdName, object, err)
+			return 0, err
+		}
+		if !downtimeMgr.NoOverlap() {
+			return downtimeMgr.Schedule(d), nil
+		}
+		id, err := downtimeMgr.ScheduleNoOverlap(d)
+		if err != nil {
+			logger.Log("Warning: Refusing %s for %s: %v", cmdName, object, err)
+			return 0, err
+		}
+		return id, nil
+	}
+	armDowntime := func(id uint64, d *downtime.Downtime) {
+		if d.Fixed && !d.StartTime.After(time.Now()) {
+			downtimeMgr.HandleStart(id)
+		}
+		downtimeMgr.ScheduleEnd(id, d.EndTime)
+	}
+
+	// parseHostDowntime reads the arguments shared by the host downtime
+	// commands: host;start;end;fixed;trigger_id;duration;author;comment.
+	parseHostDowntime := func(cmd *extcmd.Command) (*objects.Host, *downtime.Downtime) {
+		if len(cmd.Args) < 8 {
+			return nil, nil
+		}
+		host := store.GetHost(cmd.Args[0])
+		if host == nil {
+			cmd.Fail("host '%s' not found", cmd.Args[0])
+			return nil, nil
+		}
+		d := parseDowntimeArgs(objects.HostDowntimeType, cmd.Args[1:])
+		d.HostName = host.Name
+		return host, d
+	}
+
+	p.RegisterHandler("SCHEDULE_HOST_DOWNTIME", func(cmd *extcmd.Command) {
+		host, d := parseHostDowntime(cmd)
+		if host == nil {
+			return
+		}
+		id, err := scheduleDowntime("SCHEDULE_HOST_DOWNTIME", fmt.Sprintf("host '%s'", host.Name), d)
+		if err != nil {
+			cmd.Fail("%v", err)
+			return
+		}
+		logger.Log("EXTERNAL COMMAND: SCHEDULE_HOST_DOWNTIME;%s", host.Name)
+		armDowntime(id, d)
+	})
+
+	// SCHEDULE_AND_PROPAGATE_HOST_DOWNTIME gives every host below the host
+	// in the parents tree its own copy of the downtime.
+	// SCHEDULE_AND_PROPAGATE_TRIGGERED_HOST_DOWNTIME makes the copies
+	// triggered by the host's downtime, so they start and end with it.
+	// Children are skipped, with a warning, where downtime_no_overlap
+	// refuses them.
+	propagateHostDowntime := func(cmdName string, triggered bool) func(*extcmd.Command) {
+		return func(cmd *extcmd.Command) {
+			host, d := parseHostDowntime(cmd)
+			if host == nil {
+				return
+			}
+			id, err := scheduleDowntime(cmdName, fmt.Sprintf("host '%s'", host.Name), d)
+			if err != nil {
+				cmd.Fail("%v", err)
+				return
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s", cmdName, host.Name)
+			var triggerID uint64
+			if triggered {
+				triggerID = id
+			}
+			for _, cd := range downtime.ChildDowntimes(host, d, triggerID) {
+				cid, err := scheduleDowntime(cmdName, fmt.Sprintf("child host '%s'", cd.HostName), cd)
+				if err == nil && !triggered {
+					armDowntime(cid, cd)
+				}
+			}
+			// Started after the children exist so triggered ones start with it.
+			armDowntime(id, d)
+		}
+	}
+	p.RegisterHandler("SCHEDULE_AND_PROPAGATE_HOST_DOWNTIME", propagateHostDowntime("SCHEDULE_AND_PROPAGATE_HOST_DOWNTIME", false))
+	p.RegisterHandler("SCHEDULE_AND_PROPAGATE_TRIGGERED_HOST_DOWNTIME", propagateHostDowntime("SCHEDULE_AND_PROPAGATE_TRIGGERED_HOST_DOWNTIME", true))
+
+	// Gogios extensions: downtime for every host, or every service, whose
+	// custom variables or tags match a selector as for the acknowledgements
+	// above. The selector replaces the host name; the other arguments are
+	// those of SCHEDULE_HOST_DOWNTIME. Each match gets its own downtime.
+	scheduleSelectorDowntime := func(cmdName string, services bool, parse func(string) (objects.Selector, error)) func(*extcmd.Command) {
+		return func(cmd *extcmd.Command) {
+			if len(cmd.Args) < 8 {
+				return
+			}
+			sel, err := parse(cmd.Args[0])
+			if err != nil {
+				logger.Log("Warning: Ignoring %s: %v", cmdName, err)
+				cmd.Fail("%v", err)
+				return
+			}
+			dtType := objects.HostDowntimeType
+			if services {
+				dtType = objects.ServiceDowntimeType
+			}
+			tmpl := parseDowntimeArgs(dtType, cmd.Args[1:])
+			var downtimes []*downtime.Downtime
+			if services {
+				for _, svc := range store.SelectServices(sel) {
+					d := *tmpl
+					d.HostName = svc.Host.Name
+					d.ServiceDescription = svc.Description
+					downtimes = append(downtimes, &d)
+				}
+			} else {
+				for _, h := range store.SelectHosts(sel) {
+					d := *tmpl
+					d.HostName = h.Name
+					downtimes = append(downtimes, &d)
+				}
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s;%d matched", cmdName, cmd.Args[0], len(downtimes))
+			for _, d := range downtimes {
+				object := fmt.Sprintf("host '%s'", d.HostName)
+				if services {
+					object = fmt.Sprintf("service '%s' on host '%s'", d.ServiceDescription, d.HostName)
+				}
+				if id, err := scheduleDowntime(cmdName, object, d); err == nil {
+					armDowntime(id, d)
+				}
+			}
+		}
+	}
+	p.RegisterHandler("SCHEDULE_CUSTOMVAR_HOST_DOWNTIME", scheduleSelectorDowntime("SCHEDULE_CUSTOMVAR_HOST_DOWNTIME", false, parseCustomVarSelector))
+	p.RegisterHandler("SCHEDULE_CUSTOMVAR_SVC_DOWNTIME", scheduleSelectorDowntime("SCHEDULE_CUSTOMVAR_SVC_DOWNTIME", true, parseCustomVarSelector))
+	p.RegisterHandler("SCHEDULE_TAG_HOST_DOWNTIME", scheduleSelectorDowntime("SCHEDULE_TAG_HOST_DOWNTIME", false, parseTagSelector))
+	p.RegisterHandler("SCHEDULE_TAG_SVC_DOWNTIME", scheduleSelectorDowntime("SCHEDULE_TAG_SVC_DOWNTIME", true, parseTagSelector))
+
+	p.RegisterHandler("SCHEDULE_SVC_DOWNTIME", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 9 {
+			return
+		}
+		hostName := cmd.Args[0]
+		svcDesc := cmd.Args[1]
+		svc := store.GetService(hostName, svcDesc)
+		if svc == nil {
+			cmd.Fail("service '%s' on host '%s' not found", svcDesc, hostName)
+			return
+		}
+		var startTS, endTS, triggerID, duration int64
+		fixed := cmd.Args[4] == "1"
+		fmt.Sscanf(cmd.Args[2], "%d", &startTS)
+		fmt.Sscanf(cmd.Args[3], "%d", &endTS)
+		fmt.Sscanf(cmd.Args[5], "%d", &triggerID)
+		fmt.Sscanf(cmd.Args[6], "%d", &duration)
+
+		d := &downtime.Downtime{
+			Type:               objects.ServiceDowntimeType,
+			HostName:           hostName,
+			ServiceDescription: svcDesc,
+			StartTime:          time.Unix(startTS, 0),
+			EndTime:            time.Unix(endTS, 0),
+			Fixed:              fixed,
+			TriggeredBy:        uint64(triggerID),
+			Duration:           time.Duration(duration) * time.Second,
+			Author:             cmd.Args[7],
+			Comment:            cmd.Args[8],
+		}
+		id, err := scheduleDowntime("SCHEDULE_SVC_DOWNTIME", fmt.Sprintf("service '%s' on host '%s'", svcDesc, hostName), d)
+		if err != nil {
+			cmd.Fail("%v", err)
+			return
+		}
+		logger.Log("EXTERNAL COMMAND: SCHEDULE_SVC_DOWNTIME;%s;%s", hostName, svcDesc)
+		armDowntime(id, d)
+	})
+
+	p.RegisterHandler("DEL_HOST_DOWNTIME", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		var id uint64
+		fmt.Sscanf(cmd.Args[0], "%d", &id)
+		downtimeMgr.Unschedule(id)
+		logger.Log("EXTERNAL COMMAND: DEL_HOST_DOWNTIME;%d", id)
+	})
+
+	p.RegisterHandler("DEL_SVC_DOWNTIME", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		var id uint64
+		fmt.Sscanf(cmd.Args[0], "%d", &id)
+		downtimeMgr.Unschedule(id)
+		logger.Log("EXTERNAL COMMAND: DEL_SVC_DOWNTIME;%d", id)
+	})
+
+	// Comments
+	p.RegisterHandler("ADD_HOST_COMMENT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 4 {
+			return
+		}
+		hostName := cmd.Args[0]
+		if store.GetHost(hostName) == nil {
+			cmd.Fail("host '%s' not found", hostName)
+			return
+		}
+		id := commentMgr.Add(&downtime.Comment{
+			CommentType: objects.HostCommentType,
+			EntryType:   objects.UserCommentEntry,
+			Source:      1,
+			Persistent:  cmd.Args[1] == "1",
+			HostName:    hostName,
+			Author:      cmd.Args[2],
+			Data:        cmd.Args[3],
+		})
+		logger.Log("EXTERNAL COMMAND: ADD_HOST_COMMENT;%s;%d", hostName, id)
+	})
+
+	p.RegisterHandler("ADD_SVC_COMMENT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 5 {
+			return
+		}
+		hostName := cmd.Args[0]
+		svcDesc := cmd.Args[1]
+		if store.GetService(hostName, svcDesc) == nil {
+			cmd.Fail("service '%s' on host '%s' not found", svcDesc, hostName)
+			return
+		}
+		id := commentMgr.Add(&downtime.Comment{
+			CommentType:        objects.ServiceCommentType,
+			EntryType:          objects.UserCommentEntry,
+			Source:             1,
+			Persistent:         cmd.Args[2] == "1",
+			HostName:           hostName,
+			ServiceDescription: svcDesc,
+			Author:             cmd.Args[3],
+			Data:               cmd.Args[4],
+		})
+		logger.Log("EXTERNAL COMMAND: ADD_SVC_COMMENT;%s;%s;%d", hostName, svcDesc, id)
+	})
+
+	for _, name := range []string{"DEL_HOST_COMMENT", "DEL_SVC_COMMENT"} {
+		p.RegisterHandler(name, func(cmd *extcmd.Command) {
+			if len(cmd.Args) < 1 {
+				return
+			}
+			var id uint64
+			fmt.Sscanf(cmd.Args[0], "%d", &id)
+			commentMgr.Delete(id)
+			logger.Log("EXTERNAL COMMAND: %s;%d", name, id)
+		})
+	}
+
+	p.RegisterHandler("DEL_ALL_HOST_COMMENTS", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		n := commentMgr.DeleteAllForHost(cmd.Args[0])
+		logger.Log("EXTERNAL COMMAND: DEL_ALL_HOST_COMMENTS;%s (%d deleted)", cmd.Args[0], n)
+	})
+
+	p.RegisterHandler("DEL_ALL_SVC_COMMENTS", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 2 {
+			return
+		}
+		n := commentMgr.DeleteAllForService(cmd.Args[0], cmd.Args[1])
+		logger.Log("EXTERNAL COMMAND: DEL_ALL_SVC_COMMENTS;%s;%s (%d deleted)", cmd.Args[0], cmd.Args[1], n)
+	})
+
+	// Remove acknowledgement
+	p.RegisterHandler("REMOVE_SVC_ACKNOWLEDGEMENT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 2 {
+			return
+		}
+		hostName := cmd.Args[0]
+		svcDesc := cmd.Args[1]
+		svc := store.GetService(hostName, svcDesc)
+		if svc == nil {
+			cmd.Fail("service '%s' on host '%s' not found", svcDesc, hostName)
+			return
+		}
+		svc.ProblemAcknowledged = false
+		svc.AckType = objects.AckNone
+		logger.Log("EXTERNAL COMMAND: REMOVE_SVC_ACKNOWLEDGEMENT;%s;%s", hostName, svcDesc)
+	})
+
+	p.RegisterHandler("REMOVE_HOST_ACKNOWLEDGEMENT", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		hostName := cmd.Args[0]
+		host := store.GetHost(hostName)
+		if host == nil {
+			cmd.Fail("host '%s' not found", hostName)
+			return
+		}
+		host.ProblemAcknowledged = false
+		host.AckType = objects.AckNone
+		logger.Log("EXTERNAL COMMAND: REMOVE_HOST_ACKNOWLEDGEMENT;%s", hostName)
+	})
+
+	// Per-host/service notification and check toggles
+	p.RegisterHandler("DISABLE_HOST_NOTIFICATIONS", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		host := store.GetHost(cmd.Args[0])
+		if host != nil {
+			host.NotificationsEnabled = false
+		} else {
+			cmd.Fail("host '%s' not found", cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: DISABLE_HOST_NOTIFICATIONS;%s", cmd.Args[0])
+	})
+
+	p.RegisterHandler("ENABLE_HOST_NOTIFICATIONS", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		host := store.GetHost(cmd.Args[0])
+		if host != nil {
+			host.NotificationsEnabled = true
+		} else {
+			cmd.Fail("host '%s' not found", cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: ENABLE_HOST_NOTIFICATIONS;%s", cmd.Args[0])
+	})
+
+	p.RegisterHandler("DISABLE_SVC_NOTIFICATIONS", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 2 {
+			return
+		}
+		svc := store.GetService(cmd.Args[0], cmd.Args[1])
+		if svc != nil {
+			svc.NotificationsEnabled = false
+		} else {
+			cmd.Fail("service '%s' on host '%s' not found", cmd.Args[1], cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: DISABLE_SVC_NOTIFICATIONS;%s;%s", cmd.Args[0], cmd.Args[1])
+	})
+
+	p.RegisterHandler("ENABLE_SVC_NOTIFICATIONS", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 2 {
+			return
+		}
+		svc := store.GetService(cmd.Args[0], cmd.Args[1])
+		if svc != nil {
+			svc.NotificationsEnabled = true
+		} else {
+			cmd.Fail("service '%s' on host '%s' not found", cmd.Args[1], cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: ENABLE_SVC_NOTIFICATIONS;%s;%s", cmd.Args[0], cmd.Args[1])
+	})
+
+	p.RegisterHandler("DISABLE_HOST_CHECK", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		hst := store.GetHost(cmd.Args[0])
+		if hst != nil {
+			hst.ActiveChecksEnabled = false
+			hst.InvalidateChecks()
+		} else {
+			cmd.Fail("host '%s' not found", cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: DISABLE_HOST_CHECK;%s", cmd.Args[0])
+	})
+
+	p.RegisterHandler("ENABLE_HOST_CHECK", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 1 {
+			return
+		}
+		hst := store.GetHost(cmd.Args[0])
+		if hst != nil {
+			hst.ActiveChecksEnabled = true
+		} else {
+			cmd.Fail("host '%s' not found", cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: ENABLE_HOST_CHECK;%s", cmd.Args[0])
+	})
+
+	p.RegisterHandler("DISABLE_SVC_CHECK", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 2 {
+			return
+		}
+		svc := store.GetService(cmd.Args[0], cmd.Args[1])
+		if svc != nil {
+			svc.ActiveChecksEnabled = false
+			svc.InvalidateChecks()
+		} else {
+			cmd.Fail("service '%s' on host '%s' not found", cmd.Args[1], cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: DISABLE_SVC_CHECK;%s;%s", cmd.Args[0], cmd.Args[1])
+	})
+
+	p.RegisterHandler("ENABLE_SVC_CHECK", func(cmd *extcmd.Command) {
+		if len(cmd.Args) < 2 {
+			return
+		}
+		svc := store.GetService(cmd.Args[0], cmd.Args[1])
+		if svc != nil {
+			svc.ActiveChecksEnabled = true
+		} else {
+			cmd.Fail("service '%s' on host '%s' not found", cmd.Args[1], cmd.Args[0])
+		}
+		logger.Log("EXTERNAL COMMAND: ENABLE_SVC_CHECK;%s;%s", cmd.Args[0], cmd.Args[1])
+	})
+
+	// Per-object passive checks. Results for an object with passive checks
+	// disabled are discarded when processed.
+	passiveChecksHost := func(name string, enabled bool) {
+		p.RegisterHandler(name, func(cmd *extcmd.Command) {
+			if len(cmd.Args) < 1 {
+				return
+			}
+			hst := store.GetHost(cmd.Args[0])
+			if hst == nil {
+				cmd.Fail("host '%s' not found", cmd.Args[0])
+			} else if hst.PassiveChecksEnabled != enabled {
+				hst.PassiveChecksEnabled = enabled
+				hst.ModifiedAttributes |= objects.ModAttrPassiveChecksEnabled
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s", name, cmd.Args[0])
+		})
+	}
+	passiveChecksHost("ENABLE_PASSIVE_HOST_CHECKS", true)
+	passiveChecksHost("DISABLE_PASSIVE_HOST_CHECKS", false)
+	passiveChecksSvc := func(name string, enabled bool) {
+		p.RegisterHandler(name, func(cmd *extcmd.Command) {
+			if len(cmd.Args) < 2 {
+				return
+			}
+			svc := store.GetService(cmd.Args[0], cmd.Args[1])
+			if svc == nil {
+				cmd.Fail("service '%s' on host '%s' not found", cmd.Args[1], cmd.Args[0])
+			} else if svc.PassiveChecksEnabled != enabled {
+				svc.PassiveChecksEnabled = enabled
+				svc.ModifiedAttributes |= objects.ModAttrPassiveChecksEnabled
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s;%s", name, cmd.Args[0], cmd.Args[1])
+		})
+	}
+	passiveChecksSvc("ENABLE_PASSIVE_SVC_CHECKS", true)
+	passiveChecksSvc("DISABLE_PASSIVE_SVC_CHECKS", false)
+
+	// Obsessing: whether ocsp_command/ochp_command run for results.
+	p.RegisterHandler("START_OBSESSING_OVER_SVC_CHECKS", func(cmd *extcmd.Command) {
+		gs.ObsessOverServices = true
+		gs.ModifiedServiceAttributes |= objects.ModAttrObsessiveHandlerEnabled
+		logger.Log("EXTERNAL COMMAND: START_OBSESSING_OVER_SVC_CHECKS")
+	})
+	p.RegisterHandler("STOP_OBSESSING_OVER_SVC_CHECKS", func(cmd *extcmd.Command) {
+		gs.ObsessOverServices = false
+		gs.ModifiedServiceAttributes |= objects.ModAttrObsessiveHandlerEnabled
+		logger.Log("EXTERNAL COMMAND: STOP_OBSESSING_OVER_SVC_CHECKS")
+	})
+	p.RegisterHandler("START_OBSESSING_OVER_HOST_CHECKS", func(cmd *extcmd.Command) {
+		gs.ObsessOverHosts = true
+		gs.ModifiedHostAttributes |= objects.ModAttrObsessiveHandlerEnabled
+		logger.Log("EXTERNAL COMMAND: START_OBSESSING_OVER_HOST_CHECKS")
+	})
+	p.RegisterHandler("STOP_OBSESSING_OVER_HOST_CHECKS", func(cmd *extcmd.Command) {
+		gs.ObsessOverHosts = false
+		gs.ModifiedHostAttributes |= objects.ModAttrObsessiveHandlerEnabled
+		logger.Log("EXTERNAL COMMAND: STOP_OBSESSING_OVER_HOST_CHECKS")
+	})
+	obsessOverHost := func(name string, enabled bool) {
+		p.RegisterHandler(name, func(cmd *extcmd.Command) {
+			if len(cmd.Args) < 1 {
+				return
+			}
+			hst := store.GetHost(cmd.Args[0])
+			if hst == nil {
+				cmd.Fail("host '%s' not found", cmd.Args[0])
+			} else if hst.ObsessOver != enabled {
+				hst.ObsessOver = enabled
+				hst.ModifiedAttributes |= objects.ModAttrObsessiveHandlerEnabled
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s", name, cmd.Args[0])
+		})
+	}
+	obsessOverHost("START_OBSESSING_OVER_HOST", true)
+	obsessOverHost("STOP_OBSESSING_OVER_HOST", false)
+	obsessOverSvc := func(name string, enabled bool) {
+		p.RegisterHandler(name, func(cmd *extcmd.Command) {
+			if len(cmd.Args) < 2 {
+				return
+			}
+			svc := store.GetService(cmd.Args[0], cmd.Args[1])
+			if svc == nil {
+				cmd.Fail("service '%s' on host '%s' not found", cmd.Args[1], cmd.Args[0])
+			} else if svc.ObsessOver != enabled {
+				svc.ObsessOver = enabled
+				svc.ModifiedAttributes |= objects.ModAttrObsessiveHandlerEnabled
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s;%s", name, cmd.Args[0], cmd.Args[1])
+		})
+	}
+	obsessOverSvc("START_OBSESSING_OVER_SVC", true)
+	obsessOverSvc("STOP_OBSESSING_OVER_SVC", false)
+
+	// The remaining commands of the Nagios set. Each looks up the objects
+	// named by its first arguments, failing when one is not found, and
+	// logs itself once it has been carried out.
+	registerLogged := func(name string, h extcmd.Handler) {
+		p.RegisterHandler(name, func(cmd *extcmd.Command) {
+			h(cmd)
+			if cmd.Err != nil {
+				return
+			}
+			if len(cmd.Args) == 0 {
+				logger.Log("EXTERNAL COMMAND: %s", name)
+			} else {
+				logger.Log("EXTERNAL COMMAND: %s;%s", name, strings.Join(cmd.Args, ";"))
+			}
+		})
+	}
+	lookupHost := func(cmd *extcmd.Command, name string) *objects.Host {
+		h := store.GetHost(name)
+		if h == nil {
+			cmd.Fail("host '%s' not found", name)
+		}
+		return h
+	}
+	lookupService := func(cmd *extcmd.Command) *objects.Service {
+		svc := store.GetService(cmd.Args[0], cmd.Args[1])
+		if svc == nil {
+			cmd.Fail("service '%s' on host '%s' not found", cmd.Args[1], cmd.Args[0])
+		}
+		return svc
+	}
+	lookupHostGroup := func(cmd *extcmd.Command) *objects.HostGroup {
+		hg := store.GetHostGroup(cmd.Args[0])
+		if hg == nil {
+			cmd.Fail("hostgroup '%s' not found", cmd.Args[0])
+		}
+		return hg
+	}
+	lookupServiceGroup := func(cmd *extcmd.Command) *objects.ServiceGroup {
+		sg := store.GetServiceGroup(cmd.Args[0])
+		if sg == nil {
+			cmd.Fail("servicegroup '%s' not found", cmd.Args[0])
+		}
+		return sg
+	}
+	lookupContact := func(cmd *extcmd.Command) *objects.Contact {
+		c := store.GetContact(cmd.Args[0])
+		if c == nil {
+			cmd.Fail("contact '%s' not found", cmd.Args[0])
+		}
+		return c
+	}
+	lookupTimeperiod := func(cmd *extcmd.Command, name string) *objects.Timeperiod {
+		tp := store.GetTimeperiod(name)
+		if tp == nil {
+			cmd.Fail("timeperiod '%s' not found", name)
+		}
+		return tp
+	}
+	// lookupCommand resolves a "name!arg1!..." command spec, as in a
+	// check_command directive.
+	lookupCommand := func(cmd *extcmd.Command, spec string) (*objects.Command, string) {
+		name, args, _ := strings.Cut(spec, "!")
+		c := store.GetCommand(name)
+		if c == nil {
+			cmd.Fail("command '%s' not found", name)
+		}
+		return c, args
+	}
+	unixArg := func(s string) time.Time {
+		var ts int64
+		fmt.Sscanf(s, "%d", &ts)
+		return time.Unix(ts, 0)
+	}
+	intArg := func(s string) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(s))
+		return n
+	}
+
+	// Program-wide settings. Changes are recorded in the global modified
+	// attributes, of hosts, services or both.
+	globalFlag := func(on, off string, flag *bool, attr uint64, modified ...*uint64) {
+		for _, enabled := range []bool{true, false} {
+			name := off
+			if enabled {
+				name = on
+			}
+			registerLogged(name, func(cmd *extcmd.Command) {
+				if *flag == enabled {
+					return
+				}
+				*flag = enabled
+				for _, m := range modified {
+					*m |= attr
+				}
+			})
+		}
+	}
+	globalFlag("START_ACCEPTING_PASSIVE_SVC_CHECKS", "STOP_ACCEPTING_PASSIVE_SVC_CHECKS", &gs.AcceptPassiveServiceChecks,
+		objects.ModAttrPassiveChecksEnabled, &gs.ModifiedServiceAttributes)
+	globalFlag("START_ACCEPTING_PASSIVE_HOST_CHECKS", "STOP_ACCEPTING_PASSIVE_HOST_CHECKS", &gs.AcceptPassiveHostChecks,
+		objects.ModAttrPassiveChecksEnabled, &gs.ModifiedHostAttributes)
+	globalFlag("ENABLE_SERVICE_FRESHNESS_CHECKS", "DISABLE_SERVICE_FRESHNESS_CHECKS", &gs.CheckServiceFreshness,
+		objects.ModAttrFreshnessChecksEnabled, &gs.ModifiedServiceAttributes)
+	globalFlag("ENABLE_HOST_FRESHNESS_CHECKS", "DISABLE_HOST_FRESHNESS_CHECKS", &gs.CheckHostFreshness,
+		objects.ModAttrFreshnessChecksEnabled, &gs.ModifiedHostAttributes)
+	globalFlag("ENABLE_PERFORMANCE_DATA", "DISABLE_PERFORMANCE_DATA", &gs.ProcessPerformanceData,
+		objects.ModAttrPerformanceDataEnabled, &gs.ModifiedHostAttributes, &gs.ModifiedServiceAttributes)
+
+	globalEventHandler := func(name string, service bool) {
+		registerLogged(name, func(cmd *extcmd.Command) {
+			spec := cmd.Args[0]
+			if err := eventHandlers.SetGlobal(store, service, spec); err != nil {
+				cmd.Fail("%v", err)
+				return
+			}
+			if service {
+				gs.GlobalServiceEventHandler = spec
+				gs.ModifiedServiceAttributes |= objects.ModAttrEventHandlerCommand
+			} else {
+				gs.GlobalHostEventHandler = spec
+				gs.ModifiedHostAttributes |= objects.ModAttrEventHandlerCommand
+			}
+		})
+	}
+	globalEventHandler("CHANGE_GLOBAL_HOST_EVENT_HANDLER", false)
+	globalEventHandler("CHANGE_GLOBAL_SVC_EVENT_HANDLER", true)
+
+	registerLogged("SAVE_STATE_INFORMATION", func(cmd *extcmd.Command) {
+		sched.AddEvent(&scheduler.Event{Type: scheduler.EventRetentionSave, RunTime: time.Now()})
+	})
+	// A restart is a reload, as for SIGHUP.
+	for _, name := range []string{"RESTART_PROGRAM", "RESTART_PROCESS"} {
+		registerLogged(name, func(cmd *extcmd.Command) {
+			if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+				cmd.Fail("%v", err)
+			}
+		})
+	}
+
+	// Per-object settings, named by the modified attribute bit that records
+	// a change to them.
+	setHostFlag := func(h *objects.Host, attr uint64, enabled bool) {
+		var flag *bool
+		switch attr {
+		case objects.ModAttrNotificationsEnabled:
+			flag = &h.NotificationsEnabled
+		case objects.ModAttrActiveChecksEnabled:
+			flag = &h.ActiveChecksEnabled
+		case objects.ModAttrPassiveChecksEnabled:
+			flag = &h.PassiveChecksEnabled
+		case objects.ModAttrEventHandlerEnabled:
+			flag = &h.EventHandlerEnabled
+		case objects.ModAttrFlapDetectionEnabled:
+			flag = &h.FlapDetectionEnabled
+		}
+		if *flag == enabled {
+			return
+		}
+		*flag = enabled
+		h.ModifiedAttributes |= attr
+		if attr == objects.ModAttrActiveChecksEnabled && !enabled {
+			h.InvalidateChecks()
+		}
+	}
+	setServiceFlag := func(svc *objects.Service, attr uint64, enabled bool) {
+		var flag *bool
+		switch attr {
+		case objects.ModAttrNotificationsEnabled:
+			flag = &svc.NotificationsEnabled
+		case objects.ModAttrActiveChecksEnabled:
+			flag = &svc.ActiveChecksEnabled
+		case objects.ModAttrPassiveChecksEnabled:
+			flag = &svc.PassiveChecksEnabled
+		case objects.ModAttrEventHandlerEnabled:
+			flag = &svc.EventHandlerEnabled
+		case objects.ModAttrFlapDetectionEnabled:
+			flag = &svc.FlapDetectionEnabled
+		}
+		if *flag == enabled {
+			return
+		}
+		*flag = enabled
+		svc.ModifiedAttributes |= attr
+		if attr == objects.ModAttrActiveChecksEnabled && !enabled {
+			svc.InvalidateChecks()
+		}
+	}
+
+	// toggle registers ENABLE_<suffix> and DISABLE_<suffix>, which set the
+	// setting attr of the hosts and services targets returns for them.
+	type targetsFunc func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service)
+	toggle := func(suffix string, attr uint64, targets targetsFunc) {
+		for _, enabled := range []bool{true, false} {
+			name := "DISABLE_" + suffix
+			if enabled {
+				name = "ENABLE_" + suffix
+			}
+			registerLogged(name, func(cmd *extcmd.Command) {
+				hosts, services := targets(cmd)
+				for _, h := range hosts {
+					setHostFlag(h, attr, enabled)
+				}
+				for _, svc := range services {
+					setServiceFlag(svc, attr, enabled)
+				}
+			})
+		}
+	}
+	theHost := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+			return []*objects.Host{h}, nil
+		}
+		return nil, nil
+	}
+	theHostServices := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+			return nil, h.Services
+		}
+		return nil, nil
+	}
+	theService := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		if svc := lookupService(cmd); svc != nil {
+			return nil, []*objects.Service{svc}
+		}
+		return nil, nil
+	}
+	hostGroupHosts := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		if hg := lookupHostGroup(cmd); hg != nil {
+			return hg.Members, nil
+		}
+		return nil, nil
+	}
+	hostGroupServices := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		hg := lookupHostGroup(cmd)
+		if hg == nil {
+			return nil, nil
+		}
+		var services []*objects.Service
+		for _, h := range hg.Members {
+			services = append(services, h.Services...)
+		}
+		return nil, services
+	}
+	serviceGroupHosts := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		sg := lookupServiceGroup(cmd)
+		if sg == nil {
+			return nil, nil
+		}
+		var hosts []*objects.Host
+		seen := make(map[*objects.Host]bool)
+		for _, svc := range sg.Members {
+			if !seen[svc.Host] {
+				seen[svc.Host] = true
+				hosts = append(hosts, svc.Host)
+			}
+		}
+		return hosts, nil
+	}
+	serviceGroupServices := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		if sg := lookupServiceGroup(cmd); sg != nil {
+			return nil, sg.Members
+		}
+		return nil, nil
+	}
+	// beyondHost returns the hosts below h in the parents tree.
+	beyondHost := func(h *objects.Host) []*objects.Host {
+		var hosts []*objects.Host
+		seen := map[*objects.Host]bool{h: true}
+		queue := h.Children
+		for len(queue) > 0 {
+			c := queue[0]
+			queue = queue[1:]
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			hosts = append(hosts, c)
+			queue = append(queue, c.Children...)
+		}
+		return hosts
+	}
+	hostAndChildren := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+			return append([]*objects.Host{h}, beyondHost(h)...), nil
+		}
+		return nil, nil
+	}
+	allBeyondHost := func(cmd *extcmd.Command) ([]*objects.Host, []*objects.Service) {
+		h := lookupHost(cmd, cmd.Args[0])
+		if h == nil {
+			return nil, nil
+		}
+		hosts := beyondHost(h)
+		var services []*objects.Service
+		for _, c := range hosts {
+			services = append(services, c.Services...)
+		}
+		return hosts, services
+	}
+
+	toggle("HOST_EVENT_HANDLER", objects.ModAttrEventHandlerEnabled, theHost)
+	toggle("HOST_FLAP_DETECTION", objects.ModAttrFlapDetectionEnabled, theHost)
+	toggle("HOST_SVC_CHECKS", objects.ModAttrActiveChecksEnabled, theHostServices)
+	toggle("HOST_SVC_NOTIFICATIONS", objects.ModAttrNotificationsEnabled, theHostServices)
+	toggle("HOST_AND_CHILD_NOTIFICATIONS", objects.ModAttrNotificationsEnabled, hostAndChildren)
+	toggle("ALL_NOTIFICATIONS_BEYOND_HOST", objects.ModAttrNotificationsEnabled, allBeyondHost)
+	toggle("SVC_EVENT_HANDLER", objects.ModAttrEventHandlerEnabled, theService)
+	toggle("SVC_FLAP_DETECTION", objects.ModAttrFlapDetectionEnabled, theService)
+	toggle("HOSTGROUP_HOST_NOTIFICATIONS", objects.ModAttrNotificationsEnabled, hostGroupHosts)
+	toggle("HOSTGROUP_SVC_NOTIFICATIONS", objects.ModAttrNotificationsEnabled, hostGroupServices)
+	toggle("HOSTGROUP_HOST_CHECKS", objects.ModAttrActiveChecksEnabled, hostGroupHosts)
+	toggle("HOSTGROUP_SVC_CHECKS", objects.ModAttrActiveChecksEnabled, hostGroupServices)
+	toggle("HOSTGROUP_PASSIVE_HOST_CHECKS", objects.ModAttrPassiveChecksEnabled, hostGroupHosts)
+	toggle("HOSTGROUP_PASSIVE_SVC_CHECKS", objects.ModAttrPassiveChecksEnabled, hostGroupServices)
+	toggle("SERVICEGROUP_HOST_NOTIFICATIONS", objects.ModAttrNotificationsEnabled, serviceGroupHosts)
+	toggle("SERVICEGROUP_SVC_NOTIFICATIONS", objects.ModAttrNotificationsEnabled, serviceGroupServices)
+	toggle("SERVICEGROUP_HOST_CHECKS", objects.ModAttrActiveChecksEnabled, serviceGroupHosts)
+	toggle("SERVICEGROUP_SVC_CHECKS", objects.ModAttrActiveChecksEnabled, serviceGroupServices)
+	toggle("SERVICEGROUP_PASSIVE_HOST_CHECKS", objects.ModAttrPassiveChecksEnabled, serviceGroupHosts)
+	toggle("SERVICEGROUP_PASSIVE_SVC_CHECKS", objects.ModAttrPassiveChecksEnabled, serviceGroupServices)
+
+	// Contact notifications, recorded in the contact's host or service
+	// modified attributes.
+	contactToggle := func(suffix string, service bool, targets func(cmd *extcmd.Command) []*objects.Contact) {
+		for _, enabled := range []bool{true, false} {
+			name := "DISABLE_" + suffix
+			if enabled {
+				name = "ENABLE_" + suffix
+			}
+			registerLogged(name, func(cmd *extcmd.Command) {
+				for _, c := range targets(cmd) {
+					flag, modified := &c.HostNotificationsEnabled, &c.ModifiedHostAttributes
+					if service {
+						flag, modified = &c.ServiceNotificationsEnabled, &c.ModifiedServiceAttributes
+					}
+					if *flag != enabled {
+						*flag = enabled
+						*modified |= objects.ModAttrNotificationsEnabled
+					}
+				}
+			})
+		}
+	}
+	theContact := func(cmd *extcmd.Command) []*objects.Contact {
+		if c := lookupContact(cmd); c != nil {
+			return []*objects.Contact{c}
+		}
+		return nil
+	}
+	contactGroupMembers := func(cmd *extcmd.Command) []*objects.Contact {
+		cg := store.GetContactGroup(cmd.Args[0])
+		if cg == nil {
+			cmd.Fail("contactgroup '%s' not found", cmd.Args[0])
+			return nil
+		}
+		return cg.Members
+	}
+	contactToggle("CONTACT_HOST_NOTIFICATIONS", false, theContact)
+	contactToggle("CONTACT_SVC_NOTIFICATIONS", true, theContact)
+	contactToggle("CONTACTGROUP_HOST_NOTIFICATIONS", false, contactGroupMembers)
+	contactToggle("CONTACTGROUP_SVC_NOTIFICATIONS", true, contactGroupMembers)
+
+	// Check scheduling. The forced variants run the check even when active
+	// checks are disabled or it is outside its check period.
+	scheduleCheck := func(h *objects.Host, svc *objects.Service, at time.Time, forced bool) {
+		e := &scheduler.Event{Type: scheduler.EventHostCheck, RunTime: at, HostName: h.Name}
+		if svc != nil {
+			e.Type = scheduler.EventServiceCheck
+			e.ServiceDescription = svc.Description
+		}
+		if forced {
+			e.CheckOptions = objects.CheckOptionForceExecution
+		}
+		sched.AddEvent(e)
+	}
+	registerLogged("SCHEDULE_HOST_CHECK", func(cmd *extcmd.Command) {
+		if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+			scheduleCheck(h, nil, unixArg(cmd.Args[1]), false)
+		}
+	})
+	registerLogged("SCHEDULE_SVC_CHECK", func(cmd *extcmd.Command) {
+		if svc := lookupService(cmd); svc != nil {
+			scheduleCheck(svc.Host, svc, unixArg(cmd.Args[2]), false)
+		}
+	})
+	for _, forced := range []bool{false, true} {
+		name := "SCHEDULE_HOST_SVC_CHECKS"
+		if forced {
+			name = "SCHEDULE_FORCED_HOST_SVC_CHECKS"
+		}
+		registerLogged(name, func(cmd *extcmd.Command) {
+			if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+				for _, svc := range h.Services {
+					scheduleCheck(h, svc, unixArg(cmd.Args[1]), forced)
+				}
+			}
+		})
+	}
+
+	// Notifications
+	registerLogged("DELAY_HOST_NOTIFICATION", func(cmd *extcmd.Command) {
+		if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+			h.NextNotification = unixArg(cmd.Args[1])
+			if notifEngine.OnNextNotification != nil {
+				notifEngine.OnNextNotification(h, nil)
+			}
+		}
+	})
+	registerLogged("DELAY_SVC_NOTIFICATION", func(cmd *extcmd.Command) {
+		if svc := lookupService(cmd); svc != nil {
+			svc.NextNotification = unixArg(cmd.Args[2])
+			if notifEngine.OnNextNotification != nil {
+				notifEngine.OnNextNotification(svc.Host, svc)
+			}
+		}
+	})
+	// The options field takes the Nagios bits: 1 broadcast, 2 forced,
+	// 4 increment the notification number.
+	registerLogged("SEND_CUSTOM_HOST_NOTIFICATION", func(cmd *extcmd.Command) {
+		if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+			notifEngine.HostNotification(h, objects.NotificationCustom, cmd.Args[2], cmd.Args[3], intArg(cmd.Args[1]))
+		}
+	})
+	registerLogged("SEND_CUSTOM_SVC_NOTIFICATION", func(cmd *extcmd.Command) {
+		if svc := lookupService(cmd); svc != nil {
+			notifEngine.ServiceNotification(svc, objects.NotificationCustom, cmd.Args[3], cmd.Args[4], intArg(cmd.Args[2]))
+		}
+	})
+	registerLogged("SET_HOST_NOTIFICATION_NUMBER", func(cmd *extcmd.Command) {
+		if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+			h.CurrentNotificationNumber = max(intArg(cmd.Args[1]), 0)
+		}
+	})
+	registerLogged("SET_SVC_NOTIFICATION_NUMBER", func(cmd *extcmd.Command) {
+		if svc := lookupService(cmd); svc != nil {
+			svc.CurrentNotificationNumber = max(intArg(cmd.Args[2]), 0)
+		}
+	})
+
+	// Object attribute changes. change registers the command named by
+	// pattern with %s replaced by HOST and by SVC; set applies the value in
+	// args to the host's or service's fields and returns the modified
+	// attribute bit, or fails cmd.
+	type objectFields struct {
+		checkCommand       **objects.Command
+		checkCommandArgs   *string
+		eventHandler       **objects.Command
+		eventHandlerArgs   *string
+		checkInterval      *float64
+		retryInterval      *float64
+		maxCheckAttempts   *int
+		currentAttempt     *int
+		checkPeriod        **objects.Timeperiod
+		notificationPeriod **objects.Timeperiod
+		customVars         map[string]string
+		modified           *uint64
+	}
+	hostFields := func(h *objects.Host) objectFields {
+		return objectFields{&h.CheckCommand, &h.CheckCommandArgs, &h.EventHandler, &h.EventHandlerArgs,
+			&h.CheckInterval, &h.RetryInterval, &h.MaxCheckAttempts, &h.CurrentAttempt,
+			&h.CheckPeriod, &h.NotificationPeriod, h.CustomVars, &h.ModifiedAttributes}
+	}
+	serviceFields := func(svc *objects.Service) objectFields {
+		return objectFields{&svc.CheckCommand, &svc.CheckCommandArgs, &svc.EventHandler, &svc.EventHandlerArgs,
+			&svc.CheckInterval, &svc.RetryInterval, &svc.MaxCheckAttempts, &svc.CurrentAttempt,
+			&svc.CheckPeriod, &svc.NotificationPeriod, svc.CustomVars, &svc.ModifiedAttributes}
+	}
+	change := func(pattern string, set func(cmd *extcmd.Command, f objectFields, args []string) uint64) {
+		registerLogged(fmt.Sprintf(pattern, "HOST"), func(cmd *extcmd.Command) {
+			if h := lookupHost(cmd, cmd.Args[0]); h != nil {
+				f := hostFields(h)
+				*f.modified |= set(cmd, f, cmd.Args[1:])
+			}
+		})
+		registerLogged(fmt.Sprintf(pattern, "SVC"), func(cmd *extcmd.Command) {
+			if svc := lookupService(cmd); svc != nil {
+				f := serviceFields(svc)
+				*f.modified |= set(cmd, f, cmd.Args[2:])
+			}
+		})
+	}
+	change("CHANGE_%s_CHECK_COMMAND", func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+		c, cargs := lookupCommand(cmd, args[0])
+		if c == nil {
+			return 0
+		}
+		*f.checkCommand, *f.checkCommandArgs = c, cargs
+		return objects.ModAttrCheckCommand
+	})
+	// An empty event handler removes the object's own one.
+	change("CHANGE_%s_EVENT_HANDLER", func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+		var c *objects.Command
+		var cargs string
+		if args[0] != "" {
+			if c, cargs = lookupCommand(cmd, args[0]); c == nil {
+				return 0
+			}
+		}
+		*f.eventHandler, *f.eventHandlerArgs = c, cargs
+		return objects.ModAttrEventHandlerCommand
+	})
+	checkInterval := func(retry bool) func(*extcmd.Command, objectFields, []string) uint64 {
+		return func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+			v, _ := strconv.ParseFloat(strings.TrimSpace(args[0]), 64)
+			if v < 0 {
+				cmd.Fail("invalid check_interval '%s'", args[0])
+				return 0
+			}
+			if retry {
+				*f.retryInterval = v
+				return objects.ModAttrRetryCheckInterval
+			}
+			*f.checkInterval = v
+			return objects.ModAttrNormalCheckInterval
+		}
+	}
+	change("CHANGE_NORMAL_%s_CHECK_INTERVAL", checkInterval(false))
+	change("CHANGE_RETRY_%s_CHECK_INTERVAL", checkInterval(true))
+	change("CHANGE_MAX_%s_CHECK_ATTEMPTS", func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+		n := intArg(args[0])
+		if n < 1 {
+			cmd.Fail("invalid check_attempts '%s'", args[0])
+			return 0
+		}
+		*f.maxCheckAttempts = n
+		*f.currentAttempt = min(*f.currentAttempt, n)
+		return objects.ModAttrMaxCheckAttempts
+	})
+	change("CHANGE_%s_CHECK_TIMEPERIOD", func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+		tp := lookupTimeperiod(cmd, args[0])
+		if tp == nil {
+			return 0
+		}
+		*f.checkPeriod = tp
+		return objects.ModAttrCheckTimeperiod
+	})
+	change("CHANGE_%s_NOTIFICATION_TIMEPERIOD", func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+		tp := lookupTimeperiod(cmd, args[0])
+		if tp == nil {
+			return 0
+		}
+		*f.notificationPeriod = tp
+		return objects.ModAttrNotificationTimeperiod
+	})
+	// Custom variables are named without the leading underscore. As in
+	// Nagios, only variables the object defines can be changed.
+	setCustomVar := func(cmd *extcmd.Command, vars map[string]string, name, value string) bool {
+		key := strings.ToUpper(strings.TrimPrefix(name, "_"))
+		if _, ok := vars[key]; !ok {
+			cmd.Fail("no custom variable '_%s'", key)
+			return false
+		}
+		vars[key] = value
+		return true
+	}
+	change("CHANGE_CUSTOM_%s_VAR", func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+		if !setCustomVar(cmd, f.customVars, args[0], args[1]) {
+			return 0
+		}
+		return objects.ModAttrCustomVariable
+	})
+	// CHANGE_*_MODATTR replaces the modified attributes, typically with 0
+	// so retention stops carrying the changes over.
+	change("CHANGE_%s_MODATTR", func(cmd *extcmd.Command, f objectFields, args []string) uint64 {
+		v, _ := strconv.ParseUint(strings.TrimSpace(args[0]), 10, 64)
+		*f.modified = v
+		return 0
+	})
+
+	contactTimeperiod := func(name string, service bool) {
+		registerLogged(name, func(cmd *extcmd.Command) {
+			c := lookupContact(cmd)
+			if c == nil {
+				return
+			}
+			tp := lookupTimeperiod(cmd, cmd.Args[1])
+			if tp == nil {
+				return
+			}
+			if service {
+				c.ServiceNotificationPeriod = tp
+				c.ModifiedServiceAttributes |= objects.ModAttrNotificationTimeperiod
+			} else {
+				c.HostNotificationPeriod = tp
+				c.ModifiedHostAttributes |= objects.ModAttrNotificationTimeperiod
+			}
+		})
+	}
+	contactTimeperiod("CHANGE_CONTACT_HOST_NOTIFICATION_TIMEPERIOD", false)
+	contactTimeperiod("CHANGE_CONTACT_SVC_NOTIFICATION_TIMEPERIOD", true)
+	registerLogged("CHANGE_CUSTOM_CONTACT_VAR", func(cmd *extcmd.Command) {
+		if c := lookupContact(cmd); c != nil && setCustomVar(cmd, c.CustomVars, cmd.Args[1], cmd.Args[2]) {
+			c.ModifiedAttributes |= objects.ModAttrCustomVariable
+		}
+	})
+	contactModAttr := func(name string, field func(c *objects.Contact) *uint64) {
+		registerLogged(name, func(cmd *extcmd.Command) {
+			if c := lookupContact(cmd); c != nil {
+				*field(c), _ = strconv.ParseUint(strings.TrimSpace(cmd.Args[1]), 10, 64)
+			}
+		})
+	}
+	contactModAttr("CHANGE_CONTACT_MODATTR", func(c *objects.Contact) *uint64 { return &c.ModifiedAttributes })
+	contactModAttr("CHANGE_CONTACT_MODHATTR", func(c *objects.Contact) *uint64 { return &c.ModifiedHostAttributes })
+	contactModAttr("CHANGE_CONTACT_MODSATTR", func(c *objects.Contact) *uint64 { return &c.ModifiedServiceAttributes })
+
+	// Downtime for many objects at once. Each host, or service, targets
+	// returns gets its own copy of the downtime; the command fails only
+	// when none could be scheduled.
+	groupDowntime := func(name string, targets targetsFunc) {
+		p.RegisterHandler(name, func(cmd *extcmd.Command) {
+			hosts, services := targets(cmd)
+			if cmd.Err != nil {
+				return
+			}
+			var downtimes []*downtime.Downtime
+			var objectNames []string
+			tmpl := parseDowntimeArgs(objects.HostDowntimeType, cmd.Args[1:])
+			for _, h := range hosts {
+				d := *tmpl
+				d.HostName = h.Name
+				downtimes = append(downtimes, &d)
+				objectNames = append(objectNames, fmt.Sprintf("host '%s'", h.Name))
+			}
+			for _, svc := range services {
+				d := *tmpl
+				d.Type = objects.ServiceDowntimeType
+				d.HostName = svc.Host.Name
+				d.ServiceDescription = svc.Description
+				downtimes = append(downtimes, &d)
+				objectNames = append(objectNames, fmt.Sprintf("service '%s' on host '%s'", svc.Description, svc.Host.Name))
+			}
+			logger.Log("EXTERNAL COMMAND: %s;%s;%d matched", name, cmd.Args[0], len(downtimes))
+			var lastErr error
+			scheduled := 0
+			for i, d := range downtimes {
+				id, err := scheduleDowntime(name, objectNames[i], d)
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				armDowntime(id, d)
+				scheduled++
+			}
+			if scheduled == 0 && lastErr != nil {
+				cmd.Fail("%v", lastErr)
+			}
+		})
+	}
+	groupDowntime("SCHEDULE_HOST_SVC_DOWNTIME", theHostServices)
+	groupDowntime("SCHEDULE_HOSTGROUP_HOST_DOWNTIME", hostGroupHosts)
+	groupDowntime("SCHEDULE_HOSTGROUP_SVC_DOWNTIME", hostGroupServices)
+	groupDowntime("SCHEDULE_SERVICEGROUP_HOST_DOWNTIME", serviceGroupHosts)
+	groupDowntime("SCHEDULE_SERVICEGROUP_SVC_DOWNTIME", serviceGroupServices)
+
+	// DEL_DOWNTIME_BY_* cancel the downtimes matching a filter. Empty
+	// filter fields, and a start time of 0, match any downtime.
+	deleteDowntimes := func(cmd *extcmd.Command, match func(d *downtime.Downtime) bool) {
+		n := 0
+		for _, d := range downtimeMgr.All() {
+			if match(d) {
+				downtimeMgr.Unschedule(d.DowntimeID)
+				n++
+			}
+		}
+		logger.Log("EXTERNAL COMMAND: %s;%s (%d deleted)", cmd.Name, strings.Join(cmd.Args, ";"), n)
+	}
+	// downtimeFilter matches the optional service, start time and comment
+	// fields of args.
+	downtimeFilter := func(args []string) func(d *downtime.Downtime) bool {
+		field := func(i int) string {
+			if i < len(args) {
+				return args[i]
+			}
+			return ""
+		}
+		svcDesc, comment := field(0), field(2)
+		var start int64
+		fmt.Sscanf(field(1), "%d", &start)
+		return func(d *downtime.Downtime) bool {
+			return (svcDesc == "" || d.ServiceDescription == svcDesc) &&
+				(start == 0 || d.StartTime.Unix() == start) &&
+				(comment == "" || d.Comment == comment)
+		}
+	}
+	p.RegisterHandler("DEL_DOWNTIME_BY_HOST_NAME", func(cmd *extcmd.Command) {
+		match := downtimeFilter(cmd.Args[1:])
+		deleteDowntimes(cmd, func(d *downtime.Downtime) bool {
+			return d.HostName == cmd.Args[0] && match(d)
+		})
+	})
+	p.RegisterHandler("DEL_DOWNTIME_BY_HOSTGROUP_NAME", func(cmd *extcmd.Command) {
+		hg := lookupHostGroup(cmd)
+		if hg == nil {
+			return
+		}
+		hostName := ""
+		if len(cmd.Args) > 1 {
+			hostName = cmd.Args[1]
+		}
+		members := make(map[string]bool, len(hg.Members))
+		for _, h := range hg.Members {
+			members[h.Name] = true
+		}
+		match := downtimeFilter(cmd.Args[min(2, len(cmd.Args)):])
+		deleteDowntimes(cmd, func(d *downtime.Downtime) bool {
+			return members[d.HostName] && (hostName == "" || d.HostName == hostName) && match(d)
+		})
+	})
+	p.RegisterHandler("DEL_DOWNTIME_BY_START_TIME_COMMENT", func(cmd *extcmd.Command) {
+		comment := ""
+		if len(cmd.Args) > 1 {
+			comment = cmd.Args[1]
+		}
+		if intArg(cmd.Args[0]) == 0 && comment == "" {
+			cmd.Fail("a start time or comment is required")
+			return
+		}
+		deleteDowntimes(cmd, downtimeFilter([]string{"", cmd.Args[0], comment}))
+	})
+
+	// Shutdown
+	p.RegisterHandler("SHUTDOWN_PROCESS", func(cmd *extcmd.Command) {
+		logger.Log("EXTERNAL COMMAND: SHUTDOWN_PROCESS")
+		sched.Stop()
+	})
+	p.RegisterHandler("SHUTDOWN_PROGRAM", func(cmd *extcmd.Command) {
+		logger.Log("EXTERNAL COMMAND: SHUTDOWN_PROGRAM")
+		sched.Stop()
+	})
+}
+
+// ackNotify reads the notify field of an acknowledgement command, which
+// takes 0/1 as in Nagios. 2 (Gogios extension) sends the notification to
+// every escalation level the problem's notifications have reached as well
+// as to the object's own contacts.
+func ackNotify(field string) (bool, int) {
+	switch field {
+	case "1":
+		return true, 0
+	case "2":
+		return true, objects.NotificationOptionAllEscalations
+	}
+	return false, 0
+}
+
+// splitCommandList splits a comma-separated external command argument,
+// dropping empty entries.
+func splitCommandList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
-	_ "net/http/pprof" // exposes /debug/pprof on port 6060 for profiling
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -17,18 +22,30 @@ import (
 	// scheduler, eating throughput to CFS throttling and context switches.
 	_ "go.uber.org/automaxprocs"
 
+	"github.com/oceanplexian/gogios/internal/agent"
 	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/api/grpcadmin"
 	"github.com/oceanplexian/gogios/internal/api/livestatus"
+	"github.com/oceanplexian/gogios/internal/api/rest"
 	"github.com/oceanplexian/gogios/internal/checker"
 	"github.com/oceanplexian/gogios/internal/config"
+	"github.com/oceanplexian/gogios/internal/debugserver"
+	"github.com/oceanplexian/gogios/internal/dependency"
+	"github.com/oceanplexian/gogios/internal/diagnostics"
 	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/eventbus"
 	"github.com/oceanplexian/gogios/internal/extcmd"
+	"github.com/oceanplexian/gogios/internal/icinga2"
 	"github.com/oceanplexian/gogios/internal/logging"
 	"github.com/oceanplexian/gogios/internal/macros"
 	"github.com/oceanplexian/gogios/internal/notify"
 	"github.com/oceanplexian/gogios/internal/nrdp"
 	"github.com/oceanplexian/gogios/internal/objects"
+	"github.com/oceanplexian/gogios/internal/perfdata"
+	"github.com/oceanplexian/gogios/internal/replay"
+	"github.com/oceanplexian/gogios/internal/resultq"
 	"github.com/oceanplexian/gogios/internal/scheduler"
+	"github.com/oceanplexian/gogios/internal/selfcheck"
 	"github.com/oceanplexian/gogios/internal/status"
 )
 
@@ -39,6 +56,38 @@ func main() {
 	var verifyCount int
 	var daemonMode, testScheduling, enableTimingPoint bool
 	var verboseChecks, verboseLivestatus bool
+	var simulate, failOnWarnings bool
+	var precacheObjects, usePrecachedObjects bool
+	var explainType, explainName string
+	var previewTarget, previewState string
+	var previewNumber int
+	var exportSnapshot, importSnapshot string
+	var exportDependencies string
+
+	if len(os.Args) > 1 && os.Args[1] == "convert-retention" {
+		runConvertRetention(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "convert-icinga2" {
+		runConvertIcinga2(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		runStats(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		runSchema(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "agent" {
+		runAgent(os.Args[2:])
+		return
+	}
 
 	// Manual arg parsing to support -v -v (double verbose) like Nagios
 	var configFile string
@@ -52,12 +101,52 @@ func main() {
 			testScheduling = true
 		case "-d", "--daemon":
 			daemonMode = true
+		case "-p", "--precache-objects":
+			precacheObjects = true
+		case "-u", "--use-precached-objects":
+			usePrecachedObjects = true
 		case "-T", "--enable-timing-point":
 			enableTimingPoint = true
 		case "--verbose-checks":
 			verboseChecks = true
 		case "--verbose-livestatus":
 			verboseLivestatus = true
+		case "--simulate":
+			simulate = true
+		case "--fail-on-warnings":
+			failOnWarnings = true
+		case "--preview-escalation", "--notification-number", "--state", "--export-snapshot", "--import-snapshot", "--export-dependencies":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Option %s requires an argument\n", arg)
+				os.Exit(1)
+			}
+			i++
+			switch arg {
+			case "--preview-escalation":
+				previewTarget = args[i]
+			case "--notification-number":
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 1 {
+					fmt.Fprintf(os.Stderr, "Invalid notification number: %s\n", args[i])
+					os.Exit(1)
+				}
+				previewNumber = n
+			case "--state":
+				previewState = args[i]
+			case "--export-snapshot":
+				exportSnapshot = args[i]
+			case "--import-snapshot":
+				importSnapshot = args[i]
+			case "--export-dependencies":
+				exportDependencies = args[i]
+			}
+		case "--explain":
+			if i+2 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Option --explain requires an object type and name")
+				os.Exit(1)
+			}
+			explainType, explainName = args[i+1], args[i+2]
+			i += 2
 		case "-h", "--help":
 			printUsage()
 			os.Exit(0)
@@ -76,6 +165,10 @@ func main() {
 							testScheduling = true
 						case 'd':
 							daemonMode = true
+						case 'p':
+							precacheObjects = true
+						case 'u':
+							usePrecachedObjects = true
 						case 'T':
 							enableTimingPoint = true
 						default:
@@ -100,8 +193,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if precacheObjects && verifyCount == 0 {
+		fmt.Fprintln(os.Stderr, "Option -p requires -v")
+		os.Exit(1)
+	}
+	if explainType != "" && verifyCount == 0 {
+		fmt.Fprintln(os.Stderr, "Option --explain requires -v")
+		os.Exit(1)
+	}
 	if verifyCount > 0 {
-		runVerify(configFile, verifyCount)
+		runVerify(configFile, verifyCount, failOnWarnings, precacheObjects, explainType, explainName)
 		return
 	}
 
@@ -110,6 +211,21 @@ func main() {
 		return
 	}
 
+	if previewTarget != "" {
+		runEscalationPreview(configFile, previewTarget, previewNumber, previewState)
+		return
+	}
+
+	if exportSnapshot != "" {
+		runSnapshotExport(configFile, exportSnapshot)
+		return
+	}
+
+	if exportDependencies != "" {
+		runDependencyExport(configFile, exportDependencies)
+		return
+	}
+
 	_ = enableTimingPoint // reserved for future use
 
 	var verbosity int
@@ -120,7 +236,7 @@ func main() {
 		verbosity |= logging.VerboseLivestatus
 	}
 
-	runDaemon(configFile, daemonMode, verbosity)
+	runDaemon(configFile, daemonMode, simulate, usePrecachedObjects, verbosity, importSnapshot)
 }
 
 func printUsage() {
@@ -129,22 +245,41 @@ func printUsage() {
 	fmt.Println("License: MIT")
 	fmt.Println()
 	fmt.Printf("Usage: %s [options] <main_config_file>\n", os.Args[0])
+	fmt.Printf("       %s convert-retention <nagios_retention_file> [<output_file>]\n", os.Args[0])
+	fmt.Printf("       %s convert-icinga2 <icinga2_conf_file>...\n", os.Args[0])
+	fmt.Printf("       %s stats <main_config_file>\n", os.Args[0])
+	fmt.Printf("       %s replay [--snapshot <file>] <main_config_file> <log_file>...\n", os.Args[0])
+	fmt.Printf("       %s schema\n", os.Args[0])
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println()
-	fmt.Println("  -v, --verify-config          Verify all configuration data (-v -v for more info)")
+	fmt.Println("  -v, --verify-config          Verify all configuration data (-v -v for more info, -v -v -v to print resolved objects)")
 	fmt.Println("  -s, --test-scheduling        Shows projected/recommended check scheduling and other")
 	fmt.Println("                               diagnostic info based on the current configuration files.")
 	fmt.Println("  -T, --enable-timing-point     Enable timed commentary on initialization")
 	fmt.Println("  -d, --daemon                  Starts Gogios in daemon mode, instead of as a foreground process")
+	fmt.Println("  -p, --precache-objects        With -v, write the resolved objects to precached_object_file")
+	fmt.Println("  -u, --use-precached-objects   Read objects from precached_object_file instead of cfg_file/cfg_dir")
 	fmt.Println("      --verbose-checks          Log every check result (host/service, state, output)")
 	fmt.Println("      --verbose-livestatus      Log every Livestatus query and command")
+	fmt.Println("      --simulate                Generate synthetic check results instead of running plugins")
+	fmt.Println("      --fail-on-warnings        With -v, exit 1 when there are warnings")
+	fmt.Println("      --explain <type> <name>   With -v, print an object as resolved through its templates, with")
+	fmt.Println("                                where each value came from (<host>;<service> for services)")
+	fmt.Println("      --preview-escalation <host>[;<service>]")
+	fmt.Println("                                Show which contacts each notification would reach and why")
+	fmt.Println("      --notification-number <n> Preview only notification n (default: walk the whole chain)")
+	fmt.Println("      --state <state>           State to preview (default CRITICAL or DOWN)")
+	fmt.Println("      --export-snapshot <file>  Write retained state as a JSON snapshot (- for stdout) and exit")
+	fmt.Println("      --import-snapshot <file>  Start with state from a JSON snapshot instead of retention data")
+	fmt.Println("      --export-dependencies <dot|json>")
+	fmt.Println("                                Write the host/service dependency graph to stdout and exit")
 	fmt.Println("  -V, --version                 Print version information")
 	fmt.Println("  -h, --help                    Print this help message")
 	fmt.Println()
 }
 
-func runVerify(configFile string, verbosity int) {
+func runVerify(configFile string, verbosity int, failOnWarnings, precache bool, explainType, explainName string) {
 	fmt.Printf("\nGogios %s\n", version)
 	fmt.Println("Copyright (c) 2024-present Gogios Contributors")
 	fmt.Print("License: MIT\n\n")
@@ -153,10 +288,15 @@ func runVerify(configFile string, verbosity int) {
 	result, errs := config.VerifyConfig(configFile)
 	if len(errs) > 0 {
 		fmt.Println()
+		warnings := verifyWarnings(result)
+		for _, w := range warnings {
+			fmt.Printf("Warning: %s\n", w)
+		}
 		for _, err := range errs {
 			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		}
-		fmt.Printf("\nTotal Errors: %d\n", len(errs))
+		fmt.Printf("\nTotal Warnings: %d\n", len(warnings))
+		fmt.Printf("Total Errors:   %d\n", len(errs))
 		os.Exit(1)
 	}
 
@@ -204,6 +344,25 @@ func runVerify(configFile string, verbosity int) {
 		}
 		fmt.Println()
 	}
+	if verbosity >= 3 {
+		// -vvv: print every object as it is after template inheritance
+		fmt.Println("Resolved object definitions...")
+		fmt.Println()
+		for _, obj := range result.Objects {
+			if !obj.Register() {
+				continue
+			}
+			fmt.Printf("# %s:%d\n%s\n", obj.File, obj.Line, obj.Format(false))
+		}
+	}
+	if explainType != "" {
+		text, err := config.Explain(configFile, store, explainType, explainName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(text)
+	}
 
 	fmt.Printf("Checked %d commands.\n", len(store.Commands))
 	fmt.Printf("Checked %d contacts.\n", len(store.Contacts))
@@ -218,13 +377,52 @@ func runVerify(configFile string, verbosity int) {
 	fmt.Printf("Checked %d host escalations.\n", len(store.HostEscalations))
 	fmt.Printf("Checked %d service escalations.\n", len(store.ServiceEscalations))
 	fmt.Println()
-	fmt.Println("Total Warnings: 0")
+	warnings := verifyWarnings(result)
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	if len(warnings) > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("Total Warnings: %d\n", len(warnings))
 	fmt.Println("Total Errors:   0")
 	fmt.Println()
+	if failOnWarnings && len(warnings) > 0 {
+		fmt.Println("***> Warnings were found during the pre-flight check and --fail-on-warnings is set")
+		os.Exit(1)
+	}
+	if precache {
+		path := result.MainCfg.PrecachedObjectFile
+		if path == "" {
+			fmt.Fprintln(os.Stderr, "Error: precached_object_file is not set")
+			os.Exit(1)
+		}
+		if err := config.WritePrecachedObjects(path, result.Objects, result.MainCfg.NRDPDynamicConfigFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Could not write precached object file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Object precache file created:\n%s\n\n", path)
+	}
 	fmt.Println("Things look okay - No serious problems were detected during the pre-flight check")
 	os.Exit(0)
 }
 
+// verifyWarnings returns what -v warns about: unknown and deprecated
+// names, then likely mistakes in the objects. result may be nil.
+func verifyWarnings(result *config.LoadResult) []string {
+	if result == nil {
+		return nil
+	}
+	var warnings []string
+	for _, u := range result.Unknown {
+		warnings = append(warnings, u.String())
+	}
+	for _, d := range result.Deprecated {
+		warnings = append(warnings, d.String())
+	}
+	return append(warnings, config.Warnings(result.Store)...)
+}
+
 func runSchedulingTest(configFile string) {
 	fmt.Printf("\nGogios %s\n", version)
 	fmt.Print("Copyright (c) 2024-present Gogios Contributors\n\n")
@@ -305,7 +503,481 @@ func runSchedulingTest(configFile string) {
 	fmt.Println()
 }
 
-func runDaemon(configFile string, daemonMode bool, verbosity int) {
+func runEscalationPreview(configFile, target string, number int, stateName string) {
+	result, err := config.LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	store := result.Store
+
+	hostName, svcDesc, isService := strings.Cut(target, ";")
+	var preview func(n int) *notify.EscalationPreview
+	var state int
+	var ok bool
+	if isService {
+		svc := store.GetService(hostName, svcDesc)
+		if svc == nil {
+			fmt.Fprintf(os.Stderr, "Error: service '%s' on host '%s' not found\n", svcDesc, hostName)
+			os.Exit(1)
+		}
+		if state, ok = parseStateName(stateName, "CRITICAL", []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}); !ok {
+			fmt.Fprintf(os.Stderr, "Error: invalid service state '%s'\n", stateName)
+			os.Exit(1)
+		}
+		preview = func(n int) *notify.EscalationPreview {
+			return notify.PreviewServiceEscalation(svc, n, state, time.Now())
+		}
+	} else {
+		hst := store.GetHost(hostName)
+		if hst == nil {
+			fmt.Fprintf(os.Stderr, "Error: host '%s' not found\n", hostName)
+			os.Exit(1)
+		}
+		if state, ok = parseStateName(stateName, "DOWN", []string{"UP", "DOWN", "UNREACHABLE"}); !ok {
+			fmt.Fprintf(os.Stderr, "Error: invalid host state '%s'\n", stateName)
+			os.Exit(1)
+		}
+		preview = func(n int) *notify.EscalationPreview {
+			return notify.PreviewHostEscalation(hst, n, state, time.Now())
+		}
+	}
+
+	if number > 0 {
+		fmt.Print(preview(number))
+		return
+	}
+	// Walk every notification number up to one past the last escalation
+	// step so the hand-back to the object's own contacts is shown too.
+	last := notify.LastEscalationNotification(preview(1).Steps) + 1
+	for n := 1; n <= last; n++ {
+		if n > 1 {
+			fmt.Println()
+		}
+		fmt.Print(preview(n))
+	}
+}
+
+// prepareOneOffCheck resolves a RunCheck request to its macro-expanded
+// command line, execution environment and timeout, under a read lock on
+// the store. The check itself runs after the lock is released.
+func prepareOneOffCheck(store *objects.ObjectStore, expander *macros.Expander, cfg *objects.Config, req grpcadmin.RunCheckRequest) (string, checker.ExecEnv, time.Duration, error) {
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+
+	host := store.GetHost(req.HostName)
+	if host == nil {
+		return "", checker.ExecEnv{}, 0, fmt.Errorf("host '%s' %w", req.HostName, grpcadmin.ErrNotFound)
+	}
+	var svc *objects.Service
+	checkCmd, checkArgs := host.CheckCommand, host.CheckCommandArgs
+	timeout := time.Duration(cfg.HostCheckTimeout) * time.Second
+	if req.ServiceDescription != "" {
+		if svc = store.GetService(req.HostName, req.ServiceDescription); svc == nil {
+			return "", checker.ExecEnv{}, 0, fmt.Errorf("service '%s' on host '%s' %w", req.ServiceDescription, req.HostName, grpcadmin.ErrNotFound)
+		}
+		checkCmd, checkArgs = svc.CheckCommand, svc.CheckCommandArgs
+		timeout = time.Duration(cfg.ServiceCheckTimeout) * time.Second
+	}
+	if req.Timeout > 0 {
+		timeout = req.Timeout
+	}
+
+	// The object's $ARGn$ apply to an override too, so a variant of the
+	// configured command line can be tried as is.
+	var args []string
+	if checkArgs != "" {
+		args = strings.Split(checkArgs, "!")
+	}
+	raw := req.CommandLine
+	if raw == "" {
+		if checkCmd == nil {
+			return "", checker.ExecEnv{}, 0, fmt.Errorf("no check command defined and none given")
+		}
+		raw = checkCmd.CommandLine
+	}
+	env, err := checker.ExecEnvFor(host, svc)
+	if err != nil {
+		return "", checker.ExecEnv{}, 0, err
+	}
+	return expander.Expand(raw, host, svc, args), env, timeout, nil
+}
+
+// parseStateName maps a state name (case-insensitive) or number to its
+// index in names, returning def's index for an empty name.
+func parseStateName(name, def string, names []string) (int, bool) {
+	if name == "" {
+		name = def
+	}
+	for i, n := range names {
+		if strings.EqualFold(name, n) || name == strconv.Itoa(i) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// runConvertRetention converts a Nagios retention.dat into one holding only
+// the fields gogios restores, and reports the rest on stderr.
+func runConvertRetention(args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s convert-retention <nagios_retention_file> [<output_file>]\n", os.Args[0])
+		os.Exit(1)
+	}
+	in, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	out := os.Stdout
+	if len(args) == 2 {
+		if out, err = os.Create(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	report, err := status.ConvertRetention(in, out)
+	if len(args) == 2 {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to convert %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stderr, report.String())
+}
+
+// runConvertIcinga2 prints Nagios object definitions converted from Icinga2
+// configuration files to stdout, and everything it skipped to stderr.
+func runConvertIcinga2(files []string) {
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s convert-icinga2 <icinga2_conf_file>...\n", os.Args[0])
+		os.Exit(1)
+	}
+	total := make(map[string]int)
+	var warnings int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		res, err := icinga2.Convert(file, string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("# Converted from %s by gogios convert-icinga2\n\n%s", file, res.Config)
+		for t, n := range res.Counts {
+			total[t] += n
+		}
+		for _, w := range res.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		warnings += len(res.Warnings)
+	}
+	types := make([]string, 0, len(total))
+	for t := range total {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	fmt.Fprintf(os.Stderr, "Converted")
+	for _, t := range types {
+		fmt.Fprintf(os.Stderr, " %d %s,", total[t], t)
+	}
+	fmt.Fprintf(os.Stderr, " with %d warnings\n", warnings)
+}
+
+// runStats prints a nagiostats-style summary of the running daemon. It reads
+// everything from Livestatus rather than status.dat, so it works with
+// status_file=none.
+func runStats(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s stats <main_config_file>\n", os.Args[0])
+		os.Exit(1)
+	}
+	mainCfg, err := config.ReadMainConfig(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	client, err := livestatus.NewClient(mainCfg.QuerySocket, mainCfg.LivestatusTCP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	query := func(request string) []interface{} {
+		rows, err := client.Query(request)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Livestatus query failed: %s\n", err)
+			os.Exit(1)
+		}
+		if len(rows) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: Livestatus returned %d rows, want 1\n", len(rows))
+			os.Exit(1)
+		}
+		return rows[0]
+	}
+	num := func(v interface{}) int64 {
+		f, _ := v.(float64)
+		return int64(f)
+	}
+	// noisiest returns the columns of the row with the most notifications
+	// in 24 hours, or "none".
+	noisiest := func(table, columns string) string {
+		rows, err := client.Query("GET " + table + "\nColumns: " + columns + " notifications_24h\n" +
+			"Filter: notifications_24h > 0\nSort: notifications_24h desc\nLimit: 1")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Livestatus query failed: %s\n", err)
+			os.Exit(1)
+		}
+		if len(rows) == 0 {
+			return "none"
+		}
+		r := rows[0]
+		names := make([]string, len(r)-1)
+		for i := range names {
+			names[i] = fmt.Sprint(r[i])
+		}
+		return fmt.Sprintf("%s (%d)", strings.Join(names, ";"), num(r[len(r)-1]))
+	}
+
+	prog := query("GET status\nColumns: program_version nagios_pid program_start")
+	hosts := query("GET hosts\nStats: state >= 0\nStats: has_been_checked = 1\n" +
+		"Stats: has_been_checked = 1\nStats: state = 0\nStatsAnd: 2\n" +
+		"Stats: state = 1\nStats: state = 2\n" +
+		"Stats: is_flapping = 1\nStats: scheduled_downtime_depth > 0")
+	svcs := query("GET services\nStats: state >= 0\nStats: has_been_checked = 1\n" +
+		"Stats: has_been_checked = 1\nStats: state = 0\nStatsAnd: 2\n" +
+		"Stats: state = 1\nStats: state = 3\nStats: state = 2\n" +
+		"Stats: is_flapping = 1\nStats: scheduled_downtime_depth > 0")
+	notifs := query("GET contacts\nStats: sum notifications_1h\nStats: sum notifications_24h\n" +
+		"Stats: sum notifications_capped_1h\nStats: sum notifications_capped_24h")
+
+	source := "unix:" + client.Addr
+	if client.Network == "tcp" {
+		source = "tcp:" + client.Addr
+	}
+	start := time.Unix(num(prog[2]), 0)
+	fmt.Printf("\nGogios Stats\n")
+	fmt.Printf("------------\n")
+	fmt.Printf("Status Source:                          Livestatus (%s)\n", source)
+	fmt.Printf("Program Version:                        %v\n", prog[0])
+	fmt.Printf("Gogios PID:                             %d\n", num(prog[1]))
+	fmt.Printf("Program Start Time:                     %s\n", start.Format("Mon Jan 02 15:04:05 MST 2006"))
+	fmt.Printf("Running Time:                           %s\n", time.Since(start).Round(time.Second))
+	fmt.Println()
+	fmt.Printf("Total Services:                         %d\n", num(svcs[0]))
+	fmt.Printf("Services Checked:                       %d\n", num(svcs[1]))
+	fmt.Printf("Services Ok/Warn/Unk/Crit:              %d / %d / %d / %d\n", num(svcs[2]), num(svcs[3]), num(svcs[4]), num(svcs[5]))
+	fmt.Printf("Services Flapping:                      %d\n", num(svcs[6]))
+	fmt.Printf("Services In Downtime:                   %d\n", num(svcs[7]))
+	fmt.Println()
+	fmt.Printf("Total Hosts:                            %d\n", num(hosts[0]))
+	fmt.Printf("Hosts Checked:                          %d\n", num(hosts[1]))
+	fmt.Printf("Hosts Up/Down/Unreach:                  %d / %d / %d\n", num(hosts[2]), num(hosts[3]), num(hosts[4]))
+	fmt.Printf("Hosts Flapping:                         %d\n", num(hosts[5]))
+	fmt.Printf("Hosts In Downtime:                      %d\n", num(hosts[6]))
+	fmt.Println()
+	fmt.Printf("Notifications Last 1/24 Hours:          %d / %d\n", num(notifs[0]), num(notifs[1]))
+	fmt.Printf("Capped Notifications Last 1/24 Hours:   %d / %d\n", num(notifs[2]), num(notifs[3]))
+	fmt.Printf("Most Notified Contact (24 Hours):       %s\n", noisiest("contacts", "name"))
+	fmt.Printf("Noisiest Service (24 Hours):            %s\n", noisiest("services", "host_name description"))
+	fmt.Printf("Noisiest Host (24 Hours):               %s\n", noisiest("hosts", "name"))
+	fmt.Println()
+}
+
+// runSchema writes the JSON Schema of the main config directives and object
+// attributes, for editors and config linters.
+func runSchema(args []string) {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s schema\n", os.Args[0])
+		os.Exit(1)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(config.Schema()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// runReplay replays the HOST and SERVICE ALERT lines of archived logs
+// through the state machine, reports every alert the current configuration
+// and engine would have logged differently, and optionally writes the
+// reconstructed state as a JSON snapshot. It exits 1 on any mismatch.
+func runReplay(args []string) {
+	var snapshotPath string
+	if len(args) >= 2 && args[0] == "--snapshot" {
+		snapshotPath = args[1]
+		args = args[2:]
+	}
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s replay [--snapshot <file>] <main_config_file> <log_file>...\n", os.Args[0])
+		os.Exit(1)
+	}
+	result, err := config.LoadConfig(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	mainCfg := result.MainCfg
+	cfg := objects.DefaultConfig()
+	cfg.UseAggressiveHostChecking = mainCfg.UseAggressiveHostChecking
+	cfg.TranslatePassiveHostChecks = mainCfg.TranslatePassiveHostChecks
+	exitCodes, err := checker.ParseExitCodeMap(mainCfg.ExitCodeMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	var events []replay.Event
+	for _, path := range args[1:] {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		evs, err := replay.ReadEvents(f, path)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to read %s: %s\n", path, err)
+			os.Exit(1)
+		}
+		events = append(events, evs...)
+	}
+
+	rep := replay.New(result.Store, cfg, exitCodes).Replay(events)
+	report := os.Stdout
+	if snapshotPath == "-" {
+		report = os.Stderr // keep the snapshot on stdout parseable
+	}
+	for _, m := range rep.Mismatches {
+		fmt.Fprintln(report, m)
+	}
+	fmt.Fprintf(report, "Replayed %d alerts from %d events (%d retries added, %d for unknown objects), %d mismatches\n",
+		rep.Replayed, rep.Events, rep.Synthetic, rep.Unknown, len(rep.Mismatches))
+
+	if snapshotPath != "" {
+		commentMgr := downtime.NewCommentManager(1)
+		snap := status.BuildSnapshot(&status.RetentionWriter{
+			Store:     result.Store,
+			Global:    &objects.GlobalState{},
+			Comments:  commentMgr,
+			Downtimes: downtime.NewDowntimeManager(1, commentMgr, result.Store),
+			Version:   version,
+		})
+		out := os.Stdout
+		if snapshotPath != "-" {
+			if out, err = os.Create(snapshotPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+		}
+		err = status.WriteSnapshot(out, snap)
+		if snapshotPath != "-" {
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to write snapshot: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(rep.Mismatches) > 0 {
+		os.Exit(1)
+	}
+}
+
+// runSnapshotExport loads the configuration and the retention file and
+// writes them out as a JSON snapshot, for backups taken while the daemon is
+// stopped. A running daemon serves the same document at /debug/snapshot.
+func runSnapshotExport(configFile, path string) {
+	store, globalState, commentMgr, downtimeMgr := loadRetainedState(configFile)
+	snap := status.BuildSnapshot(&status.RetentionWriter{
+		Store:     store,
+		Global:    globalState,
+		Comments:  commentMgr,
+		Downtimes: downtimeMgr,
+		Version:   version,
+	})
+
+	var err error
+	out := os.Stdout
+	if path != "-" {
+		if out, err = os.Create(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	err = status.WriteSnapshot(out, snap)
+	if path != "-" {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to write snapshot: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadRetainedState loads the configuration and, if present, the
+// retention file, for the commands that report on state while the daemon
+// is stopped.
+func loadRetainedState(configFile string) (*objects.ObjectStore, *objects.GlobalState, *downtime.CommentManager, *downtime.DowntimeManager) {
+	result, err := config.LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	mainCfg := result.MainCfg
+	store := result.Store
+	globalState := &objects.GlobalState{}
+	commentMgr := downtime.NewCommentManager(1)
+	downtimeMgr := downtime.NewDowntimeManager(1, commentMgr, store)
+	if _, err := os.Stat(mainCfg.StateRetentionFile); err == nil {
+		retReader := &status.RetentionReader{
+			Store:     store,
+			Global:    globalState,
+			Comments:  commentMgr,
+			Downtimes: downtimeMgr,
+		}
+		if err := retReader.Read(mainCfg.StateRetentionFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to read retention data: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	return store, globalState, commentMgr, downtimeMgr
+}
+
+// runDependencyExport writes the dependency graph, with states from the
+// retention file, in DOT or JSON.
+func runDependencyExport(configFile, format string) {
+	if format != "dot" && format != "json" {
+		fmt.Fprintf(os.Stderr, "Invalid dependency graph format: %s (want dot or json)\n", format)
+		os.Exit(1)
+	}
+	store, _, _, _ := loadRetainedState(configFile)
+	g := dependency.BuildGraph(store)
+	var err error
+	if format == "dot" {
+		err = g.WriteDOT(os.Stdout)
+	} else {
+		err = g.WriteJSON(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func runDaemon(configFile string, daemonMode, simulate, usePrecached bool, verbosity int, importSnapshot string) {
 	if !daemonMode {
 		fmt.Printf("\nGogios %s\n", version)
 		fmt.Println("Copyright (c) 2024-present Gogios Contributors")
@@ -313,7 +985,16 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	}
 
 	// --- Load configuration ---
-	result, err := config.LoadConfig(configFile)
+	// The parse cache is kept for SIGHUP reloads, which then only tokenize
+	// files that changed. With -u, reloads read the precached objects too.
+	parseCache := config.NewParseCache()
+	loadConfig := func() (*config.LoadResult, error) {
+		if usePrecached {
+			return config.LoadPrecachedConfig(configFile)
+		}
+		return config.LoadConfigCached(configFile, parseCache)
+	}
+	result, err := loadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
@@ -344,6 +1025,7 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	cfg.MaxServiceCheckSpread = mainCfg.MaxServiceCheckSpread
 	cfg.MaxHostCheckSpread = mainCfg.MaxHostCheckSpread
 	cfg.CheckReaperInterval = mainCfg.CheckResultReaperFrequency
+	cfg.CheckHistorySize = mainCfg.CheckHistorySize
 	cfg.UserMacros = result.UserMacros
 
 	// Map timeout state
@@ -358,6 +1040,26 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 		cfg.ServiceCheckTimeoutState = objects.ServiceCritical
 	}
 
+	exitCodes, err := checker.ParseExitCodeMap(mainCfg.ExitCodeMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	concurrencyLimits, err := checker.ParseConcurrencyClasses(mainCfg.CheckConcurrencyClasses)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	var executorRoutes []checker.ExecutorRoute
+	for _, s := range mainCfg.CheckExecutorRoutes {
+		rt, err := checker.ParseExecutorRoute(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		executorRoutes = append(executorRoutes, rt)
+	}
+
 	// Map log rotation method
 	logRotation := objects.LogRotationNone
 	switch mainCfg.LogRotationMethod {
@@ -408,6 +1110,7 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 		filepath.Dir(mainCfg.LogFile),
 		filepath.Dir(mainCfg.StatusFile),
 		filepath.Dir(mainCfg.StateRetentionFile),
+		filepath.Dir(mainCfg.HeartbeatFile),
 		mainCfg.LogArchivePath,
 		mainCfg.CheckResultPath,
 		filepath.Dir(mainCfg.CommandFile),
@@ -436,11 +1139,41 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	// Set verbosity flags from CLI
 	nagLogger.Verbosity = verbosity
 
+	if mainCfg.AlertForwardTarget != "" {
+		fwd, err := logging.NewForwarder(mainCfg.AlertForwardTarget, mainCfg.AlertForwardFormat, mainCfg.AlertForwardBuffer)
+		if err != nil {
+			nagLogger.Log("Warning: %v", err)
+		} else {
+			fwd.SetLogger(nagLogger.Log)
+			nagLogger.SetForwarder(fwd)
+			fwd.Start()
+			defer fwd.Stop()
+		}
+	}
+
 	nagLogger.Log("Gogios %s starting... (PID=%d)", version, os.Getpid())
 	nagLogger.Log("Local time is %s", time.Now().Format("Mon Jan 02 15:04:05 MST 2006"))
 	nagLogger.Log("LOG VERSION: 2.0")
 	nagLogger.Log("Finished loading configuration with %d hosts, %d services",
 		len(store.Hosts), len(store.Services))
+	for _, u := range result.Unknown {
+		nagLogger.Log("Warning: %s", u)
+	}
+	for _, d := range result.Deprecated {
+		nagLogger.Log("Warning: %s", d)
+	}
+
+	// writeObjectCache writes object_cache_file for a loaded configuration,
+	// then drops the definitions it was written from.
+	writeObjectCache := func(r *config.LoadResult) {
+		if mainCfg.ObjectCacheFile != "" {
+			if err := config.WriteObjectCache(mainCfg.ObjectCacheFile, r.Objects); err != nil {
+				nagLogger.Log("Warning: Could not write object cache file '%s': %v", mainCfg.ObjectCacheFile, err)
+			}
+		}
+		r.Objects = nil
+	}
+	writeObjectCache(result)
 
 	// --- Initialize subsystems ---
 
@@ -448,45 +1181,123 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	commentMgr := downtime.NewCommentManager(1)
 	downtimeMgr := downtime.NewDowntimeManager(1, commentMgr, store)
 	downtimeMgr.SetLogger(nagLogger)
+	downtimeMgr.SetMaxDuration(time.Duration(mainCfg.MaxDowntimeDuration) * time.Second)
+	downtimeMgr.SetNoOverlap(mainCfg.DowntimeNoOverlap)
+	blackoutMgr := downtime.NewBlackoutManager(store)
 
-	// Macro expander
+	// Macro expander. Summary macros are computed at most once per
+	// scheduler iteration.
+	macroSummary := macros.NewSummaryCache(store)
 	macroExpander := &macros.Expander{
-		Cfg:        cfg,
-		HostLookup: store.GetHost,
-		SvcLookup:  store.GetService,
+		Cfg:                cfg,
+		HostLookup:         store.GetHost,
+		SvcLookup:          store.GetService,
+		TimeperiodLookup:   store.GetTimeperiod,
+		HostGroupLookup:    store.GetHostGroup,
+		ServiceGroupLookup: store.GetServiceGroup,
+		Summary:            macroSummary,
 	}
 
 	// Notification engine
 	notifEngine := notify.NewNotificationEngine(globalState, store, nagLogger)
-
-	// Status writer
-	statusWriter := &status.StatusWriter{
-		Path:      mainCfg.StatusFile,
-		Store:     store,
-		Global:    globalState,
-		Comments:  commentMgr,
-		Downtimes: downtimeMgr,
-		Version:   "1.0.0",
+	notifEngine.CmdExecutor.Timeout = time.Duration(mainCfg.NotificationTimeout) * time.Second
+	notifEngine.CmdExecutor.SetWorkers(mainCfg.NotificationWorkers)
+	notifEngine.HostDigestLine = mainCfg.HostDigestLine
+	notifEngine.ServiceDigestLine = mainCfg.ServiceDigestLine
+	notifEngine.Blackouts = blackoutMgr
+	notifEngine.AckAllEscalations = mainCfg.AckNotifyAllEscalations
+	notifEngine.HourlyCap = mainCfg.NotificationHourlyCap
+	notifEngine.OverflowContactGroup = mainCfg.NotificationOverflowContactGroup
+	if name := mainCfg.NotificationOverflowContactGroup; name != "" && store.GetContactGroup(name) == nil {
+		nagLogger.Log("Warning: notification_overflow_contactgroup '%s' is not a contact group; notifications over the hourly cap are held back", name)
+	}
+	notifEngine.Macros = macroExpander
+
+	// Status writer. With status_file=none there is none and status is
+	// only available through Livestatus.
+	var statusWriter *status.StatusWriter
+	if mainCfg.StatusFile != "" {
+		statusWriter = &status.StatusWriter{
+			Path:      mainCfg.StatusFile,
+			Store:     store,
+			Global:    globalState,
+			Comments:  commentMgr,
+			Downtimes: downtimeMgr,
+			Version:   "1.0.0",
+		}
+	} else if mainCfg.QuerySocket == "" && mainCfg.LivestatusTCP == "" {
+		nagLogger.Log("Warning: status_file=none and no Livestatus listener is configured; current status will not be readable")
 	}
 
 	// Retention writer/reader
 	retentionWriter := &status.RetentionWriter{
-		Path: mainCfg.StateRetentionFile,
+		Path:      mainCfg.StateRetentionFile,
 		Store:     store,
 		Global:    globalState,
 		Comments:  commentMgr,
 		Downtimes: downtimeMgr,
+		Blackouts: blackoutMgr,
 		Version:   "1.0.0",
+		Shards:    mainCfg.RetentionShards,
 	}
 
-	// Load retention data if it exists
-	if mainCfg.RetainStateInformation {
+	// Self-check pseudo host. Registered before retention is read so its
+	// objects keep their state; probes are attached once each subsystem
+	// exists.
+	var selfMon *selfcheck.Monitor
+	if mainCfg.SelfCheck {
+		selfMon = selfcheck.New(mainCfg.SelfCheckHostName, store, time.Duration(mainCfg.SelfCheckInterval)*time.Second)
+		selfMon.SetLogger(func(format string, args ...interface{}) {
+			nagLogger.Log(format, args...)
+		})
+		selfMon.SetContactGroups(mainCfg.SelfCheckContactGroups)
+		selfMon.AddService("Scheduler", nil)
+		selfMon.AddService("Result Queue", nil)
+		if mainCfg.QuerySocket != "" || mainCfg.LivestatusTCP != "" {
+			selfMon.AddService("Livestatus", nil)
+		}
+		if mainCfg.NRDPListen != "" {
+			selfMon.AddService("NRDP", nil)
+			if mainCfg.NRDPSenderStaleThreshold > 0 {
+				selfMon.AddService("NRDP Senders", nil)
+			}
+		}
+		selfMon.Register()
+	}
+
+	// Load retention data if it exists, or the snapshot given on the
+	// command line in its place.
+	if importSnapshot != "" {
+		retReader := &status.RetentionReader{
+			Store:     store,
+			Global:    globalState,
+			Comments:  commentMgr,
+			Downtimes: downtimeMgr,
+			Blackouts: blackoutMgr,
+		}
+		f, err := os.Open(importSnapshot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		st, err := status.ImportSnapshot(f, retReader)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to import snapshot %s: %s\n", importSnapshot, err)
+			os.Exit(1)
+		}
+		nagLogger.Log("Imported snapshot %s: %d hosts, %d services, %d contacts, %d comments, %d downtimes (%d entries skipped)",
+			importSnapshot, st.Hosts, st.Services, st.Contacts, st.Comments, st.Downtimes, st.Skipped)
+		downtimeMgr.CheckExpired()
+		downtimeMgr.ReconcileDepths()
+	} else if mainCfg.RetainStateInformation {
 		if _, err := os.Stat(mainCfg.StateRetentionFile); err == nil {
 			retReader := &status.RetentionReader{
 				Store:     store,
 				Global:    globalState,
 				Comments:  commentMgr,
 				Downtimes: downtimeMgr,
+				Blackouts: blackoutMgr,
 			}
 			if err := retReader.Read(mainCfg.StateRetentionFile); err != nil {
 				nagLogger.Log("Warning: Failed to read retention data: %v", err)
@@ -494,7 +1305,7 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 				nagLogger.Log("Successfully read retention data from %s", mainCfg.StateRetentionFile)
 			}
 			// Sweep downtimes whose end-time passed while we were stopped.
-			// HandleEnd goroutine timers don't survive restart (KANB-109), so
+			// ScheduleEnd timers don't survive restart (KANB-109), so
 			// retention.dat can carry both in-effect downtimes with expired
 			// EndTimes AND phantom scheduled_downtime_depth on hosts/services
 			// whose triggering downtime is already gone. Run CheckExpired
@@ -507,14 +1318,239 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	}
 
 	// --- Check executor ---
-	resultCh := make(chan *objects.CheckResult, 65536)
-	executor := checker.NewExecutor(mainCfg.MaxConcurrentChecks, resultCh)
+	resultCh := make(chan *objects.CheckResult, mainCfg.ResultQueueSize)
+	// Producers that must not block (NRDP, passive commands, self-check, the
+	// scheduler callbacks) submit through resultQueue, which spills to disk
+	// when resultCh is full.
+	resultQueue, err := resultq.New(resultCh, mainCfg.ResultSpillFile, mainCfg.ResultSpillMaxSize)
+	if err != nil {
+		nagLogger.Log("Warning: %v; results will be dropped when the result queue is full", err)
+		resultQueue, _ = resultq.New(resultCh, "", 0)
+	}
+	resultQueue.SetLogger(func(format string, args ...interface{}) {
+		nagLogger.Log(format, args...)
+	})
+	resultQueue.Start()
+	var executor *checker.Router
+	checkLimits := checker.Rlimits{
+		CPU:    mainCfg.CheckRlimitCPU,
+		Memory: mainCfg.CheckRlimitMemory,
+		NoFile: mainCfg.CheckRlimitNoFile,
+	}
+	// gogios_cluster reads member states from the store, so it is
+	// registered here rather than from an init function.
+	checker.RegisterBuiltin("gogios_cluster", checker.NewClusterCheck(store))
+
+	var localExec *checker.Executor
+	var sshExec *checker.SSHExecutor
+	var agentExec *agent.Executor
+	var concurrency *checker.ConcurrencyClasses
+	// Host addresses and concurrency classes only change on reload, so
+	// runners look them up in snapshot maps rather than touch the store
+	// without its lock.
+	var hostAddrs, checkClasses atomic.Pointer[map[string]string]
+	simulated := simulate || mainCfg.SimulationMode
+	if simulated {
+		// Synthetic results only; no plugins, shells or SSH connections.
+		executor = checker.NewRouter("simulated", checker.NewSimExecutor(checker.SimConfig{
+			WarningRate:   mainCfg.SimulationWarningRate,
+			CriticalRate:  mainCfg.SimulationCriticalRate,
+			UnknownRate:   mainCfg.SimulationUnknownRate,
+			HostDownRate:  mainCfg.SimulationHostDownRate,
+			RecoveryRate:  mainCfg.SimulationRecoveryRate,
+			Latency:       mainCfg.SimulationLatency,
+			LatencyMean:   time.Duration(mainCfg.SimulationLatencyMean) * time.Millisecond,
+			LatencyStdDev: time.Duration(mainCfg.SimulationLatencyStdDev) * time.Millisecond,
+		}, resultCh))
+		notifEngine.CmdExecutor.DryRun = true
+		nagLogger.Log("SIMULATION MODE: check plugins, notification, event handler, OCSP/OCHP and perfdata commands will not be executed, results are synthetic")
+	} else {
+		if mainCfg.CheckCgroup != "" {
+			if err := checker.SetCheckCgroup(mainCfg.CheckCgroup); err != nil {
+				nagLogger.Log("Warning: %v; check plugins run outside a cgroup", err)
+			}
+		}
+		if mainCfg.ImportanceScheduling {
+			localExec = checker.NewImportanceExecutor(mainCfg.MaxConcurrentChecks, resultCh)
+		} else {
+			localExec = checker.NewExecutor(mainCfg.MaxConcurrentChecks, resultCh)
+		}
+		executor = checker.NewRouter("local", localExec)
+		if mainCfg.SSHKeyFile != "" {
+			sshExec, err = checker.NewSSHExecutor(mainCfg.MaxConcurrentChecks, resultCh, checker.SSHConfig{
+				User:                  mainCfg.SSHUser,
+				Port:                  mainCfg.SSHPort,
+				KeyFile:               mainCfg.SSHKeyFile,
+				KnownHostsFile:        mainCfg.SSHKnownHostsFile,
+				InsecureIgnoreHostKey: mainCfg.SSHInsecureIgnoreHostKey,
+				ConnectTimeout:        time.Duration(mainCfg.SSHConnectTimeout) * time.Second,
+			})
+			if err != nil {
+				nagLogger.Log("Warning: SSH check executor disabled: %v", err)
+				sshExec = nil
+			} else {
+				addrs := hostAddresses(store)
+				hostAddrs.Store(&addrs)
+				sshExec.AddressLookup = func(name string) string { return (*hostAddrs.Load())[name] }
+				executor.Register("ssh", sshExec)
+			}
+		}
+		if mainCfg.AgentTokenFile != "" {
+			agentExec, err = agent.NewExecutor(mainCfg.MaxConcurrentChecks, resultCh, agent.ExecutorConfig{
+				Port:      mainCfg.AgentPort,
+				TokenFile: mainCfg.AgentTokenFile,
+				TLS:       mainCfg.AgentTLS,
+				CAFile:    mainCfg.AgentCAFile,
+			})
+			if err != nil {
+				nagLogger.Log("Warning: agent check executor disabled: %v", err)
+				agentExec = nil
+			} else {
+				addrs := hostAddresses(store)
+				hostAddrs.Store(&addrs)
+				agentExec.AddressLookup = func(name string) string { return (*hostAddrs.Load())[name] }
+				executor.Register("agent", agentExec)
+			}
+		}
+		if len(concurrencyLimits) > 0 {
+			classes := concurrencyClassMap(store, concurrencyLimits, nagLogger)
+			checkClasses.Store(&classes)
+			concurrency = checker.NewConcurrencyClasses(concurrencyLimits, func(hostName, svcDesc string) string {
+				return (*checkClasses.Load())[hostName+"\t"+svcDesc]
+			})
+			localExec.Concurrency = concurrency
+			if sshExec != nil {
+				sshExec.Concurrency = concurrency
+			}
+		}
+		if !executor.SetDefault(mainCfg.CheckExecutor) {
+			nagLogger.Log("Warning: check_executor '%s' is not available, using local", mainCfg.CheckExecutor)
+		}
+		for _, rt := range executorRoutes {
+			if executor.Get(rt.Runner) == nil {
+				nagLogger.Log("Warning: check_executor_route runner '%s' is not available; its hosts use the default", rt.Runner)
+			}
+			executor.AddRoute(rt)
+		}
+	}
+
+	// submitCheck routes a check to its host's runner and returns the
+	// runner's name. check_by_ssh command lines are run over the SSH
+	// runner's pooled connections instead of forking the plugin, when the
+	// runner is configured. One with options the native path can't honor
+	// forks the plugin, and the reason is logged once per host or service.
+	// Resource limits bound forked plugin processes only: a native
+	// check_by_ssh starts none, and env.Wrap leaves builtins unwrapped.
+	var bySSHRefused sync.Map
+	submitCheck := func(host *objects.Host, svcDesc string, env checker.ExecEnv, expanded string, timeout time.Duration, options int, latency float64, importance uint) string {
+		if sshExec != nil && mainCfg.NativeCheckBySSH {
+			native := env
+			native.Limits = checker.Rlimits{}
+			bs, err := checker.ParseBySSH(native.Wrap(expanded))
+			if err == nil {
+				sshExec.SubmitBySSH(bs, host.Name, svcDesc, timeout, options, objects.CheckTypeActive, latency)
+				return "ssh"
+			}
+			if !errors.Is(err, checker.ErrNotBySSH) {
+				if _, seen := bySSHRefused.LoadOrStore(host.Name+";"+svcDesc, true); !seen {
+					object := fmt.Sprintf("host '%s'", host.Name)
+					if svcDesc != "" {
+						object = fmt.Sprintf("service '%s' on host '%s'", svcDesc, host.Name)
+					}
+					nagLogger.Log("Warning: Running check_by_ssh as a plugin for %s: %v", object, err)
+				}
+			}
+		}
+		env.Limits = env.Limits.Or(checkLimits)
+		command := env.Wrap(expanded)
+		name, runner := executor.Route(host)
+		if is, ok := runner.(checker.ImportanceSubmitter); ok && mainCfg.ImportanceScheduling {
+			is.SubmitImportance(importance, host.Name, svcDesc, command, timeout, options, objects.CheckTypeActive, latency)
+			return name
+		}
+		runner.Submit(host.Name, svcDesc, command, timeout, options, objects.CheckTypeActive, latency)
+		return name
+	}
+
+	// --- Event bus publisher ---
+	var publisher *eventbus.Publisher
+	if mainCfg.EventPublisher != "" {
+		publisher, err = eventbus.New(eventbus.Config{
+			Driver:        mainCfg.EventPublisher,
+			URL:           mainCfg.EventPublisherURL,
+			SubjectPrefix: mainCfg.EventPublisherPrefix,
+			Format:        mainCfg.EventPublisherFormat,
+		})
+		if err != nil {
+			nagLogger.Log("Warning: %v", err)
+			publisher = nil
+		} else {
+			publisher.SetLogger(nagLogger.Log)
+			publisher.Start()
+			defer publisher.Stop()
+			nagLogger.Log("Publishing events to %s (%s)", mainCfg.EventPublisherURL, mainCfg.EventPublisher)
+		}
+	}
 
 	// --- Service result handler ---
+	// Event hooks dispatch external commands on state changes. The command
+	// processor is set up further down.
+	var cmdProcessor *extcmd.Processor
+	eventHooks := &checker.EventHooks{
+		Global: globalState,
+		Expand: macroExpander.Expand,
+		Dispatch: func(line string) error {
+			if cmdProcessor == nil {
+				return fmt.Errorf("external commands are disabled")
+			}
+			cmd, err := extcmd.Parse(fmt.Sprintf("[%d] %s", time.Now().Unix(), line))
+			if err != nil {
+				return err
+			}
+			cmdProcessor.DispatchFrom("event hook", cmd.Name, cmd.Args)
+			return nil
+		},
+		OnRun: func(h *objects.Host, svc *objects.Service) {
+			if svc != nil {
+				nagLogger.LogEventHandler(false, false, h.Name, svc.Description,
+					svc.CurrentState, svc.StateType, svc.CurrentAttempt, "_"+checker.EventHookCommandCustomVar)
+				return
+			}
+			nagLogger.LogEventHandler(false, true, h.Name, "",
+				h.CurrentState, h.StateType, h.CurrentAttempt, "_"+checker.EventHookCommandCustomVar)
+		},
+	}
+	eventHooks.SetLogger(nagLogger.Log)
+
+	// Event handlers run commands on state changes.
+	eventHandlerTimeout := time.Duration(mainCfg.EventHandlerTimeout) * time.Second
+	eventHandlers, err := checker.NewEventHandlers(store, globalState, mainCfg.GlobalHostEventHandler,
+		mainCfg.GlobalServiceEventHandler, eventHandlerTimeout)
+	if err != nil {
+		nagLogger.Log("Warning: %v, global event handlers disabled", err)
+		eventHandlers, _ = checker.NewEventHandlers(store, globalState, "", "", eventHandlerTimeout)
+	}
+	eventHandlers.Expand = macroExpander.Expand
+	eventHandlers.DryRun = simulated
+	eventHandlers.OnRun = func(global bool, h *objects.Host, svc *objects.Service, handler string) {
+		if svc != nil {
+			nagLogger.LogEventHandler(global, false, h.Name, svc.Description,
+				svc.CurrentState, svc.StateType, svc.CurrentAttempt, handler)
+			return
+		}
+		nagLogger.LogEventHandler(global, true, h.Name, "",
+			h.CurrentState, h.StateType, h.CurrentAttempt, handler)
+	}
+	eventHandlers.SetLogger(nagLogger.Log)
+
 	svcHandler := &checker.ServiceResultHandler{
-		Cfg: cfg,
+		Cfg:        cfg,
+		ExitCodes:  exitCodes,
 		HostLookup: store.GetHost,
 		OnNotification: func(svc *objects.Service, notifType int) {
+			nagLogger.LogVerbose(logging.VerboseChecks, "NOTIFICATION TRIGGER: %s;%s;exec_id=%d;%s",
+				svc.Host.Name, svc.Description, svc.ExecutionID,
+				objects.NotificationTypeName(notifType, svc.CurrentState, false))
 			notifEngine.ServiceNotification(svc, notifType, "", "", 0)
 		},
 		OnStateChange: func(svc *objects.Service, oldState, newState int, hardChange bool) {
@@ -523,13 +1559,24 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 			nagLogger.Log("SERVICE ALERT: %s;%s;%s;%s;%d;%s",
 				svc.Host.Name, svc.Description, stateStr, typeStr,
 				svc.CurrentAttempt, svc.PluginOutput)
+			if publisher != nil {
+				publisher.PublishServiceStateChange(svc, oldState, newState, hardChange)
+			}
+			eventHandlers.Service(svc)
+			eventHooks.Service(svc, time.Now())
+		},
+		OnHardStateChange: func(svc *objects.Service) {
+			nagLogger.LogServiceStateCause(svc.Host.Name, svc.Description, svc.CurrentState, svc.LastHardStateCause)
 		},
 	}
 
 	// --- Host result handler ---
 	hostHandler := &checker.HostResultHandler{
-		Cfg: cfg,
+		Cfg:       cfg,
+		ExitCodes: exitCodes,
 		OnNotification: func(h *objects.Host, notifType int) {
+			nagLogger.LogVerbose(logging.VerboseChecks, "NOTIFICATION TRIGGER: %s;exec_id=%d;%s",
+				h.Name, h.ExecutionID, objects.NotificationTypeName(notifType, h.CurrentState, true))
 			notifEngine.HostNotification(h, notifType, "", "", 0)
 		},
 		OnStateChange: func(h *objects.Host, oldState, newState int, hardChange bool) {
@@ -537,9 +1584,55 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 			typeStr := objects.StateTypeName(h.StateType)
 			nagLogger.Log("HOST ALERT: %s;%s;%s;%d;%s",
 				h.Name, stateStr, typeStr, h.CurrentAttempt, h.PluginOutput)
+			if publisher != nil {
+				publisher.PublishHostStateChange(h, oldState, newState, hardChange)
+			}
+			eventHandlers.Host(h)
+			eventHooks.Host(h, time.Now())
+		},
+		OnHardStateChange: func(h *objects.Host) {
+			nagLogger.LogHostStateCause(h.Name, h.CurrentState, h.LastHardStateCause)
 		},
 	}
 
+	// Obsessive compulsive service/host processors.
+	obsessor, err := checker.NewObsessor(store, globalState, mainCfg.OCSPCommand, mainCfg.OCHPCommand,
+		time.Duration(mainCfg.OCSPTimeout)*time.Second, time.Duration(mainCfg.OCHPTimeout)*time.Second)
+	if err != nil {
+		nagLogger.Log("Warning: %v, obsessing disabled", err)
+		obsessor, _ = checker.NewObsessor(store, globalState, "", "", 0, 0)
+	}
+	obsessor.Expand = macroExpander.Expand
+	obsessor.DryRun = simulated
+	obsessor.SetLogger(func(format string, args ...interface{}) {
+		nagLogger.Log(format, args...)
+	})
+
+	// Performance data commands and files.
+	globalState.HostPerfdataCommand = mainCfg.HostPerfdataCommand
+	globalState.ServicePerfdataCommand = mainCfg.ServicePerfdataCommand
+	globalState.HostPerfdataFile = mainCfg.HostPerfdataFile
+	globalState.ServicePerfdataFile = mainCfg.ServicePerfdataFile
+	globalState.HostPerfdataFileTemplate = mainCfg.HostPerfdataFileTemplate
+	globalState.ServicePerfdataFileTemplate = mainCfg.ServicePerfdataFileTemplate
+	globalState.HostPerfdataFileMode = perfdata.ParseFileMode(mainCfg.HostPerfdataFileMode)
+	globalState.ServicePerfdataFileMode = perfdata.ParseFileMode(mainCfg.ServicePerfdataFileMode)
+	globalState.HostPerfdataFileProcessingCommand = mainCfg.HostPerfdataFileProcessingCommand
+	globalState.ServicePerfdataFileProcessingCommand = mainCfg.ServicePerfdataFileProcessingCommand
+	globalState.HostPerfdataFileProcessingInterval = int(mainCfg.HostPerfdataFileProcessingInterval)
+	globalState.ServicePerfdataFileProcessingInterval = int(mainCfg.ServicePerfdataFileProcessingInterval)
+	globalState.HostPerfdataProcessEmptyResults = mainCfg.HostPerfdataProcessEmptyResults
+	globalState.ServicePerfdataProcessEmptyResults = mainCfg.ServicePerfdataProcessEmptyResults
+	perfProcessor := perfdata.NewProcessor(globalState)
+	perfProcessor.Expand = macroExpander.Expand
+	perfProcessor.Timeout = time.Duration(mainCfg.PerfdataTimeout) * time.Second
+	perfProcessor.DryRun = simulated
+	perfProcessor.SetLogger(func(format string, args ...interface{}) {
+		nagLogger.Log(format, args...)
+	})
+	perfProcessor.Start(store)
+	defer perfProcessor.Close()
+
 	// --- Scheduler ---
 	sched := scheduler.New(cfg, store.Hosts, store.Services, resultCh)
 
@@ -554,23 +1647,46 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 		}
 		rawCmd := svc.CheckCommand.CommandLine
 		expanded := macroExpander.Expand(rawCmd, svc.Host, svc, args)
+		env, err := checker.ExecEnvFor(svc.Host, svc)
+		if err != nil {
+			resultQueue.Submit(execEnvError(svc.Host.Name, svc.Description, options, svc.Latency, err))
+			return
+		}
 		timeout := time.Duration(cfg.ServiceCheckTimeout) * time.Second
-		executor.Submit(svc.Host.Name, svc.Description, expanded, timeout, options, objects.CheckTypeActive, svc.Latency)
+		nagLogger.LogVerbose(logging.VerboseChecks, "CHECK DISPATCH: %s;%s;exec_id=%d;%s",
+			svc.Host.Name, svc.Description, svc.ExecutionID, svc.CheckCommand.Name)
+		svc.CheckExecutor = submitCheck(svc.Host, svc.Description, env, expanded, timeout, options, svc.Latency, svc.HourlyValue)
 	}
 
 	sched.OnRunHostCheck = func(host *objects.Host, options int) {
 		if host.CheckCommand == nil {
-			// Hosts without check commands are assumed UP
-			resultCh <- &objects.CheckResult{
-				HostName:      host.Name,
-				CheckType:     objects.CheckTypeActive,
-				CheckOptions:  options,
-				ReturnCode:    0,
-				Output:        "(No check command defined - host assumed UP)",
-				StartTime:     time.Now(),
-				FinishTime:    time.Now(),
-				ExitedOK:      true,
-				Latency:       host.Latency,
+			// Hosts without check commands are assumed UP, unless
+			// host_no_check_state or _NO_CHECK_STATE says otherwise,
+			// or host_state_from_services finds their services silent.
+			mode, err := checker.NoCheckState(host, mainCfg.HostNoCheckState)
+			if err != nil {
+				nagLogger.Log("Warning: Host '%s': %v, using '%s'", host.Name, err, mode)
+			}
+			now := time.Now()
+			if mode != checker.NoCheckPending && mainCfg.HostStateFromServices > 0 {
+				maxAge := time.Duration(mainCfg.HostStateFromServices) * time.Second
+				if cr := checker.StaleServicesResult(host, maxAge, options, now); cr != nil {
+					resultCh <- cr
+					return
+				}
+			}
+			if cr := checker.NoCheckResult(host, mode, options, now); cr != nil {
+				resultCh <- cr
+				return
+			}
+			// Left PENDING; look again at the next check interval.
+			hostHandler.SkipResult(host, now)
+			if host.CheckInterval > 0 {
+				sched.AddEvent(&scheduler.Event{
+					Type:     scheduler.EventHostCheck,
+					RunTime:  host.NextCheck,
+					HostName: host.Name,
+				})
 			}
 			return
 		}
@@ -581,8 +1697,15 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 		}
 		rawCmd := host.CheckCommand.CommandLine
 		expanded := macroExpander.Expand(rawCmd, host, nil, args)
+		env, err := checker.ExecEnvFor(host, nil)
+		if err != nil {
+			resultQueue.Submit(execEnvError(host.Name, "", options, host.Latency, err))
+			return
+		}
 		timeout := time.Duration(cfg.HostCheckTimeout) * time.Second
-		executor.Submit(host.Name, "", expanded, timeout, options, objects.CheckTypeActive, host.Latency)
+		nagLogger.LogVerbose(logging.VerboseChecks, "CHECK DISPATCH: %s;exec_id=%d;%s",
+			host.Name, host.ExecutionID, host.CheckCommand.Name)
+		host.CheckExecutor = submitCheck(host, "", env, expanded, timeout, options, host.Latency, host.HourlyValue)
 	}
 
 	// Batch result processing — takes the write lock once for the whole batch
@@ -592,11 +1715,70 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	// once the server is live.
 	var nrdpTracker *nrdp.DynamicTracker
 
+	// Every intake, active or passive, converges on resultCh, so output is
+	// sanitized once here before anything stores, logs or publishes it.
+	sanitizer := checker.NewSanitizer(mainCfg.CheckOutputSanitization)
+
+	// Result middleware registered by init functions in this package's
+	// deployment-specific files, run before anything else sees a result.
+	resultPipeline := checker.NewResultPipeline()
+	resultPipeline.SetLogger(func(format string, args ...interface{}) {
+		nagLogger.Log(format, args...)
+	})
+	if resultPipeline.Len() > 0 {
+		nagLogger.Log("Result processing runs through %d result middleware", resultPipeline.Len())
+	}
+
+	// skipDroppedResult releases the object of an active check whose
+	// result middleware dropped, so the check is not left executing.
+	skipDroppedResult := func(cr *objects.CheckResult) {
+		nagLogger.LogVerbose(logging.VerboseChecks, "Check result for '%s;%s' dropped by result middleware",
+			cr.HostName, cr.ServiceDescription)
+		if cr.CheckType != objects.CheckTypeActive {
+			return
+		}
+		now := time.Now()
+		if cr.ServiceDescription != "" {
+			sched.DecrementRunningServiceChecks()
+			svc := store.GetService(cr.HostName, cr.ServiceDescription)
+			if svc == nil || (cr.CheckEpoch != 0 && cr.CheckEpoch != svc.CheckEpoch) {
+				return
+			}
+			svcHandler.SkipResult(svc, now)
+			sched.AddEvent(&scheduler.Event{
+				Type:               scheduler.EventServiceCheck,
+				RunTime:            svc.NextCheck,
+				HostName:           cr.HostName,
+				ServiceDescription: cr.ServiceDescription,
+			})
+			return
+		}
+		host := store.GetHost(cr.HostName)
+		if host == nil || (cr.CheckEpoch != 0 && cr.CheckEpoch != host.CheckEpoch) {
+			return
+		}
+		hostHandler.SkipResult(host, now)
+		sched.AddEvent(&scheduler.Event{
+			Type:     scheduler.EventHostCheck,
+			RunTime:  host.NextCheck,
+			HostName: cr.HostName,
+		})
+	}
+
 	sched.OnProcessResults = func(results []*objects.CheckResult) {
 		store.Mu.Lock()
 		defer store.Mu.Unlock()
 
 		for _, cr := range results {
+			if resultPipeline.Len() > 0 {
+				in := cr
+				if cr = resultPipeline.Apply(cr); cr == nil {
+					skipDroppedResult(in)
+					continue
+				}
+			}
+			sanitizer.Apply(cr)
+
 			// Dynamic NRDP registration: create missing hosts/services
 			// under the store lock we already hold — no extra sync.
 			if cr.DynamicRegister && nrdpTracker != nil {
@@ -616,16 +1798,45 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 				nrdpTracker.TouchRecord(cr.HostName, cr.ServiceDescription)
 			}
 
+			if publisher != nil {
+				publisher.PublishCheckResult(cr)
+			}
+
 			if cr.ServiceDescription != "" {
 				svc := store.GetService(cr.HostName, cr.ServiceDescription)
 				if svc == nil {
+					nagLogger.LogVerbose(logging.VerboseChecks, "Discarding check result for unknown service '%s' on host '%s'",
+						cr.ServiceDescription, cr.HostName)
+					if cr.CheckEpoch != 0 {
+						// Dispatched before a reload removed the service.
+						sched.DecrementRunningServiceChecks()
+					}
+					continue
+				}
+				if cr.CheckType == objects.CheckTypePassive && !svc.PassiveChecksEnabled {
+					nagLogger.LogVerbose(logging.VerboseChecks, "Discarding passive check result for service '%s' on host '%s': passive checks are disabled",
+						cr.ServiceDescription, cr.HostName)
+					continue
+				}
+				if cr.CheckEpoch != 0 && cr.CheckEpoch != svc.CheckEpoch {
+					// Launched before the service's checks were disabled or
+					// reconfigured, or before it was re-added.
+					nagLogger.LogVerbose(logging.VerboseChecks, "Discarding stale check result for service '%s' on host '%s' (exec_id=%d)",
+						cr.ServiceDescription, cr.HostName, cr.ExecutionID)
+					sched.DecrementRunningServiceChecks()
+					if !svc.ActiveChecksEnabled {
+						svc.IsExecuting = false
+					}
 					continue
 				}
+				svc.ExecutionID = cr.ExecutionID
 				svcHandler.HandleResult(svc, cr)
 				sched.DecrementRunningServiceChecks()
+				obsessor.Service(svc)
+				perfProcessor.UpdateServicePerfdata(svc)
 
-				nagLogger.LogVerbose(logging.VerboseChecks, "CHECK RESULT: %s;%s;%s;%d;%.3fs;%s",
-					cr.HostName, cr.ServiceDescription,
+				nagLogger.LogVerbose(logging.VerboseChecks, "CHECK RESULT: %s;%s;exec_id=%d;%s;%d;%.3fs;%s",
+					cr.HostName, cr.ServiceDescription, cr.ExecutionID,
 					objects.ServiceStateName(svc.CurrentState),
 					cr.ReturnCode, cr.FinishTime.Sub(cr.StartTime).Seconds(), cr.Output)
 
@@ -640,12 +1851,27 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 			} else {
 				host := store.GetHost(cr.HostName)
 				if host == nil {
+					nagLogger.LogVerbose(logging.VerboseChecks, "Discarding check result for unknown host '%s'", cr.HostName)
 					continue
 				}
+				if cr.CheckType == objects.CheckTypePassive && !host.PassiveChecksEnabled {
+					nagLogger.LogVerbose(logging.VerboseChecks, "Discarding passive check result for host '%s': passive checks are disabled", cr.HostName)
+					continue
+				}
+				if cr.CheckEpoch != 0 && cr.CheckEpoch != host.CheckEpoch {
+					nagLogger.LogVerbose(logging.VerboseChecks, "Discarding stale check result for host '%s' (exec_id=%d)", cr.HostName, cr.ExecutionID)
+					if !host.ActiveChecksEnabled {
+						host.IsExecuting = false
+					}
+					continue
+				}
+				host.ExecutionID = cr.ExecutionID
 				hostHandler.HandleResult(host, cr)
+				obsessor.Host(host)
+				perfProcessor.UpdateHostPerfdata(host)
 
-				nagLogger.LogVerbose(logging.VerboseChecks, "CHECK RESULT: %s;%s;%d;%.3fs;%s",
-					cr.HostName, objects.HostStateName(host.CurrentState),
+				nagLogger.LogVerbose(logging.VerboseChecks, "CHECK RESULT: %s;exec_id=%d;%s;%d;%.3fs;%s",
+					cr.HostName, cr.ExecutionID, objects.HostStateName(host.CurrentState),
 					cr.ReturnCode, cr.FinishTime.Sub(cr.StartTime).Seconds(), cr.Output)
 
 				downtimeMgr.CheckPendingFlexHostDowntime(cr.HostName, host.CurrentState)
@@ -659,9 +1885,24 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 		}
 	}
 
-	sched.OnStatusSave = func() {
-		if err := statusWriter.Write(); err != nil {
-			nagLogger.Log("Error writing status data: %v", err)
+	// Heartbeat for external watchdogs, served on the debug listener and
+	// written to heartbeat_file if set.
+	heartbeat := scheduler.NewHeartbeat(mainCfg.HeartbeatFile, time.Duration(mainCfg.HeartbeatInterval)*time.Second)
+	heartbeat.SetLogger(func(format string, args ...interface{}) {
+		nagLogger.Log(format, args...)
+	})
+	sched.OnIteration = func() {
+		macroSummary.Invalidate()
+		heartbeat.Beat(time.Now(), sched.QueueLen())
+	}
+	if statusWriter != nil {
+		sched.OnStatusSave = func() {
+			if cmdProcessor != nil {
+				defer cmdProcessor.HoldBatches()()
+			}
+			if err := statusWriter.Write(); err != nil {
+				nagLogger.Log("Error writing status data: %v", err)
+			}
 		}
 	}
 
@@ -683,11 +1924,36 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 
 	// Periodic downtime expiry sweep. This is the durable mechanism that
 	// decrements scheduled_downtime_depth when a downtime's EndTime passes.
-	// The goroutine timer in SCHEDULE_*_DOWNTIME handles the common case
+	// The ScheduleEnd timer in SCHEDULE_*_DOWNTIME handles the common case
 	// for downtimes whose end fires while gogios is running; this sweep is
 	// the backstop for restarts (KANB-109).
 	sched.OnExpireDowntime = func() {
 		downtimeMgr.CheckExpired()
+		for _, name := range blackoutMgr.Expire(time.Now()) {
+			nagLogger.Log("BLACKOUT ENDED: %s", name)
+		}
+	}
+
+	// Renotification: each problem notification queues an event at its
+	// next_notification time, which sends the next one if the problem is
+	// still there. Check results re-notify too; whichever comes first wins.
+	notifEngine.OnNextNotification = func(h *objects.Host, svc *objects.Service) {
+		e := &scheduler.Event{Type: scheduler.EventRenotification, RunTime: h.NextNotification, HostName: h.Name}
+		if svc != nil {
+			e.RunTime = svc.NextNotification
+			e.ServiceDescription = svc.Description
+		}
+		sched.AddEvent(e)
+	}
+	sched.OnRenotifyService = func(svc *objects.Service) {
+		store.Mu.Lock()
+		defer store.Mu.Unlock()
+		notifEngine.RenotifyService(svc)
+	}
+	sched.OnRenotifyHost = func(h *objects.Host) {
+		store.Mu.Lock()
+		defer store.Mu.Unlock()
+		notifEngine.RenotifyHost(h)
 	}
 
 	// Schedule the initial log rotation event if time-based rotation is enabled.
@@ -718,22 +1984,38 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	}
 
 	// --- External command processor ---
-	var cmdProcessor *extcmd.Processor
-	if mainCfg.CheckExternalCommands && mainCfg.CommandFile != "" {
+	if mainCfg.CheckExternalCommands && (mainCfg.CommandFile != "" || mainCfg.CommandSocket != "" ||
+		mainCfg.CommandTCPListen != "" || mainCfg.CommandHTTPListen != "") {
 		cmdProcessor = extcmd.NewProcessor(mainCfg.CommandFile, 256)
+		cmdProcessor.SetPipePermissions(os.FileMode(mainCfg.CommandFileMode), mainCfg.CommandFileGroup)
+		cmdProcessor.SetResponseDir(mainCfg.CommandResponseDir)
+		if mainCfg.CommandSocket != "" {
+			src := extcmd.NewDatagramSource(mainCfg.CommandSocket)
+			src.SetPermissions(os.FileMode(mainCfg.CommandFileMode), mainCfg.CommandFileGroup)
+			cmdProcessor.AddSource(src)
+		}
+		if mainCfg.CommandTCPListen != "" {
+			cmdProcessor.AddSource(extcmd.NewTCPSource(mainCfg.CommandTCPListen, mainCfg.CommandTokenHash))
+		}
+		if mainCfg.CommandHTTPListen != "" {
+			cmdProcessor.AddSource(extcmd.NewHTTPSource(mainCfg.CommandHTTPListen, mainCfg.CommandTokenHash))
+		}
 		cmdProcessor.SetLogger(func(format string, args ...interface{}) {
 			nagLogger.Log(format, args...)
 		})
 
 		// Register common command handlers
-		registerCommandHandlers(cmdProcessor, store, globalState, sched, notifEngine, commentMgr, downtimeMgr, nagLogger, resultCh)
+		registerCommandHandlers(cmdProcessor, store, globalState, sched, notifEngine, commentMgr, downtimeMgr, blackoutMgr, eventHandlers, nagLogger, resultQueue)
 		// Synchronize command handler state mutations with livestatus readers
 		cmdProcessor.StateMu = &store.Mu
+		cmdProcessor.QuietBatch = nagLogger.QuietExternalCommands
 
 		if err := cmdProcessor.Start(); err != nil {
 			nagLogger.Log("Warning: Failed to start command processor: %v", err)
 		} else {
-			nagLogger.Log("External command processor started on %s", mainCfg.CommandFile)
+			for _, src := range cmdProcessor.Sources() {
+				nagLogger.Log("External command processor started on %s", src)
+			}
 			// Drain commands into scheduler
 			go func() {
 				for cmd := range cmdProcessor.CommandChan() {
@@ -750,18 +2032,20 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	var livestatusServer *livestatus.Server
 	if mainCfg.QuerySocket != "" || mainCfg.LivestatusTCP != "" {
 		livestatusServer = livestatus.New(mainCfg.QuerySocket, mainCfg.LivestatusTCP)
+		livestatusServer.SetSocketPermissions(os.FileMode(mainCfg.QuerySocketMode), mainCfg.QuerySocketGroup)
 		apiState := &api.StateProvider{
-			Store:     store,
-			Global:    globalState,
-			Comments:  commentMgr,
-			Downtimes: downtimeMgr,
-			Logger:    nagLogger,
+			Store:          store,
+			Global:         globalState,
+			Comments:       commentMgr,
+			Downtimes:      downtimeMgr,
+			Logger:         nagLogger,
 			LogFile:        mainCfg.LogFile,
 			LogArchivePath: mainCfg.LogArchivePath,
+			LogIndex:       logging.NewLogIndex(mainCfg.LogFile, mainCfg.LogArchivePath),
 		}
 		cmdSink := api.CommandSink(func(name string, args []string) {
 			if cmdProcessor != nil {
-				cmdProcessor.Dispatch(name, args)
+				cmdProcessor.DispatchFrom("Livestatus", name, args)
 			}
 		})
 		batchCmdSink := api.BatchCommandSink(func(cmds []api.CommandEntry) {
@@ -773,6 +2057,7 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 						Timestamp: now,
 						Name:      c.Name,
 						Args:      c.Args,
+						Source:    "Livestatus",
 					}
 				}
 				cmdProcessor.DispatchBatch(batch)
@@ -791,6 +2076,86 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 		}
 	}
 
+	// --- gRPC admin API ---
+	var adminServer *grpcadmin.Server
+	if mainCfg.GRPCAdminListen != "" {
+		if mainCfg.GRPCAdminTokenHash == "" {
+			nagLogger.Log("Warning: gRPC admin API has no grpc_admin_token_hash, requests are not authenticated")
+		}
+		adminServer = grpcadmin.New(grpcadmin.Config{
+			Listen:    mainCfg.GRPCAdminListen,
+			TokenHash: mainCfg.GRPCAdminTokenHash,
+			SSLCert:   mainCfg.GRPCAdminSSLCert,
+			SSLKey:    mainCfg.GRPCAdminSSLKey,
+		}, &api.StateProvider{
+			Store:     store,
+			Global:    globalState,
+			Comments:  commentMgr,
+			Downtimes: downtimeMgr,
+			Logger:    nagLogger,
+		}, func(name string, args []string) {
+			if cmdProcessor != nil {
+				cmdProcessor.DispatchFrom("gRPC admin", name, args)
+			}
+		}, nagLogger)
+		adminServer.OnReload = func() error {
+			return syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		}
+		adminServer.OnRunCheck = func(req grpcadmin.RunCheckRequest) (*grpcadmin.RunCheckResult, error) {
+			cmdLine, env, timeout, err := prepareOneOffCheck(store, macroExpander, cfg, req)
+			if err != nil {
+				return nil, err
+			}
+			env.Limits = env.Limits.Or(checkLimits)
+			cr := checker.RunOnce(env.Wrap(cmdLine), timeout)
+			out := checker.ParseCheckOutput(cr.Output)
+			return &grpcadmin.RunCheckResult{
+				CommandLine:   cmdLine,
+				ReturnCode:    cr.ReturnCode,
+				Output:        out.ShortOutput,
+				LongOutput:    out.LongOutput,
+				PerfData:      out.PerfData,
+				ExecutionTime: cr.FinishTime.Sub(cr.StartTime),
+				TimedOut:      cr.EarlyTimeout,
+			}, nil
+		}
+		if err := adminServer.Start(); err != nil {
+			nagLogger.Log("Warning: Failed to start gRPC admin API: %v", err)
+		} else {
+			defer adminServer.Stop()
+			nagLogger.Log("gRPC admin API listening on %s", mainCfg.GRPCAdminListen)
+		}
+	}
+
+	// --- REST/JSON API ---
+	if mainCfg.RESTListen != "" {
+		if mainCfg.RESTTokenHash == "" {
+			nagLogger.Log("Warning: REST API has no rest_token_hash, requests are not authenticated")
+		}
+		restServer := rest.New(rest.Config{
+			Listen:    mainCfg.RESTListen,
+			TokenHash: mainCfg.RESTTokenHash,
+			SSLCert:   mainCfg.RESTSSLCert,
+			SSLKey:    mainCfg.RESTSSLKey,
+		}, &api.StateProvider{
+			Store:     store,
+			Global:    globalState,
+			Comments:  commentMgr,
+			Downtimes: downtimeMgr,
+			Logger:    nagLogger,
+		}, func(name string, args []string) {
+			if cmdProcessor != nil {
+				cmdProcessor.DispatchFrom("REST API", name, args)
+			}
+		}, nagLogger)
+		if err := restServer.Start(); err != nil {
+			nagLogger.Log("Warning: Failed to start REST API: %v", err)
+		} else {
+			defer restServer.Stop()
+			nagLogger.Log("REST API listening on %s", mainCfg.RESTListen)
+		}
+	}
+
 	// --- NRDP relay server ---
 	var nrdpServer *nrdp.Server
 	if mainCfg.NRDPListen != "" {
@@ -803,8 +2168,12 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 			DynamicPrune:   time.Duration(mainCfg.NRDPDynamicPrune) * time.Second,
 			SSLCert:        mainCfg.NRDPSSLCert,
 			SSLKey:         mainCfg.NRDPSSLKey,
+
+			SenderStaleThreshold: time.Duration(mainCfg.NRDPSenderStaleThreshold) * time.Second,
+			ExpectedSenders:      mainCfg.NRDPExpectedSenders,
 		}
 		nrdpServer = nrdp.New(nrdpCfg, store, resultCh, nagLogger)
+		nrdpServer.SetResultSink(resultQueue.Submit)
 		nrdpTracker = nrdpServer.Tracker() // wire into OnProcessResults closure
 
 		// Persist NRDP-discovered hosts/services to a generated .cfg so they
@@ -846,11 +2215,25 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	// --- Initialize scheduling ---
 	nagLogger.Log("Scheduling initial checks...")
 	sched.Init(store.Hosts, store.Services)
+	// Problems carried over in retention renotify at their saved
+	// next_notification.
+	for _, h := range store.Hosts {
+		if h.StateType == objects.StateTypeHard && h.CurrentState != objects.HostUp && !h.NextNotification.IsZero() && !h.NoMoreNotifications {
+			notifEngine.OnNextNotification(h, nil)
+		}
+	}
+	for _, svc := range store.Services {
+		if svc.StateType == objects.StateTypeHard && svc.CurrentState != objects.ServiceOK && !svc.NextNotification.IsZero() && !svc.NoMoreNotifications {
+			notifEngine.OnNextNotification(svc.Host, svc)
+		}
+	}
 	nagLogger.Log("Scheduled %d events in queue", sched.QueueLen())
 
 	// Write initial status
-	if err := statusWriter.Write(); err != nil {
-		nagLogger.Log("Warning: Failed to write initial status: %v", err)
+	if statusWriter != nil {
+		if err := statusWriter.Write(); err != nil {
+			nagLogger.Log("Warning: Failed to write initial status: %v", err)
+		}
 	}
 
 	// Log initial states if configured
@@ -870,6 +2253,76 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 
 	nagLogger.Log("Gogios ready. Entering main event loop.")
 
+	// reloadConfig re-reads the object configuration and swaps it in on
+	// the event loop. Hosts, services and contacts that are still defined
+	// keep their state, comments and downtimes; those that are gone lose
+	// them. A configuration that fails to load or validate is not applied.
+	// Of the main config directives only grpc_admin_token_hash is reloaded,
+	// so a leaked admin token can be revoked without a restart.
+	reloadConfig := func() {
+		start := time.Now()
+		next, err := loadConfig()
+		if err != nil {
+			nagLogger.Log("Error: Reload failed, keeping the running configuration: %v", err)
+			return
+		}
+		if errs := config.Validate(next.Store); len(errs) > 0 {
+			for _, e := range errs {
+				nagLogger.Log("Error: %v", e)
+			}
+			nagLogger.Log("Error: Reload failed with %d configuration errors, keeping the running configuration", len(errs))
+			return
+		}
+		for _, u := range next.Unknown {
+			nagLogger.Log("Warning: %s", u)
+		}
+		for _, d := range next.Deprecated {
+			nagLogger.Log("Warning: %s", d)
+		}
+		next.MainCfg.Unknown = mainCfg.Unknown
+		next.MainCfg.Deprecated = mainCfg.Deprecated
+		if adminServer != nil && next.MainCfg.GRPCAdminTokenHash != mainCfg.GRPCAdminTokenHash {
+			adminServer.SetTokenHash(next.MainCfg.GRPCAdminTokenHash)
+			mainCfg.GRPCAdminTokenHash = next.MainCfg.GRPCAdminTokenHash
+			if mainCfg.GRPCAdminTokenHash == "" {
+				nagLogger.Log("Warning: gRPC admin API has no grpc_admin_token_hash, requests are not authenticated")
+			} else {
+				nagLogger.Log("gRPC admin token hash changed, tokens accepted before are no longer valid")
+			}
+		}
+		if !reflect.DeepEqual(next.MainCfg, mainCfg) {
+			nagLogger.Log("Warning: Main config directives changed in %s take effect on restart", configFile)
+		}
+
+		var diff reloadDiff
+		sched.Do(func() {
+			store.Mu.Lock()
+			defer store.Mu.Unlock()
+			old := store.Replace(next.Store)
+			if selfMon != nil {
+				selfMon.Register()
+			}
+			status.CarryState(old, store)
+			diff = diffObjects(old, store)
+			dropRemovedObjects(store, commentMgr, downtimeMgr)
+			blackoutMgr.Reload(store)
+			cfg.UserMacros = next.UserMacros
+			sched.Reload(store.Hosts, store.Services)
+			if sshExec != nil || agentExec != nil {
+				addrs := hostAddresses(store)
+				hostAddrs.Store(&addrs)
+			}
+			if concurrency != nil {
+				classes := concurrencyClassMap(store, concurrencyLimits, nagLogger)
+				checkClasses.Store(&classes)
+			}
+		})
+		writeObjectCache(next)
+		nagLogger.Log("Reloaded configuration in %.3fs (%d files changed): %d hosts (%d added, %d removed), %d services (%d added, %d removed)",
+			time.Since(start).Seconds(), len(next.ChangedFiles), len(next.Store.Hosts), diff.hostsAdded, diff.hostsRemoved,
+			len(next.Store.Services), diff.servicesAdded, diff.servicesRemoved)
+	}
+
 	// --- Signal handling ---
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
@@ -882,13 +2335,84 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 				sched.Stop()
 				return
 			case syscall.SIGHUP:
-				nagLogger.Log("Caught SIGHUP, reloading not yet implemented")
+				nagLogger.Log("Caught SIGHUP, reloading object configuration...")
+				reloadConfig()
 			}
 		}
 	}()
 
-	// --- pprof debug endpoint ---
-	go func() { http.ListenAndServe("127.0.0.1:6060", nil) }()
+	// --- Debug listener (pprof + runtime metrics) ---
+	var debugServer *debugserver.Server
+	if mainCfg.DebugListen != "" {
+		debugServer = debugserver.New(mainCfg.DebugListen, nagLogger)
+		debugServer.AddGauge("checks_running", func() float64 { return float64(executor.JobsRunning()) })
+		debugServer.AddGauge("result_queue_length", func() float64 { return float64(len(resultCh)) })
+		debugServer.AddGauge("results_sanitized", func() float64 { return float64(sanitizer.Sanitized()) })
+		debugServer.AddGauge("results_dropped_by_middleware", func() float64 { return float64(resultPipeline.Dropped()) })
+		debugServer.AddGauges(func() map[string]float64 {
+			st := resultQueue.Stats()
+			return map[string]float64{
+				"result_spill_depth": float64(st.SpillDepth),
+				"results_spilled":    float64(st.Spilled),
+				"results_dropped":    float64(st.Dropped),
+			}
+		})
+		if concurrency != nil {
+			debugServer.AddGauges(func() map[string]float64 {
+				m := make(map[string]float64)
+				for _, c := range concurrency.Stats() {
+					prefix := "concurrency_class_" + c.Name + "_"
+					m[prefix+"limit"] = float64(c.Limit)
+					m[prefix+"running"] = float64(c.Running)
+					m[prefix+"waiting"] = float64(c.Waiting)
+				}
+				return m
+			})
+		}
+		if localExec != nil && mainCfg.ImportanceScheduling {
+			debugServer.AddGauge("check_queue_length", func() float64 { return float64(localExec.QueueLen()) })
+			debugServer.AddGauges(func() map[string]float64 {
+				m := make(map[string]float64)
+				for _, t := range localExec.ImportanceStats() {
+					prefix := fmt.Sprintf("importance_%d_", t.Importance)
+					m[prefix+"dispatched"] = float64(t.Dispatched)
+					m[prefix+"avg_wait_seconds"] = t.AvgWait().Seconds()
+					m[prefix+"max_wait_seconds"] = t.MaxWait.Seconds()
+				}
+				return m
+			})
+		}
+		debugServer.Handle("/debug/stale", diagnostics.StaleHandler(store, globalState))
+		debugServer.AddGauge("notification_command_failures", func() float64 {
+			var n int64
+			for _, st := range notifEngine.CmdExecutor.Stats() {
+				n += st.Failures
+			}
+			return float64(n)
+		})
+		debugServer.AddGauge("notification_commands_queued", func() float64 {
+			return float64(notifEngine.CmdExecutor.Queued())
+		})
+		debugServer.Handle("/debug/notification-commands", notifEngine.CmdExecutor.StatsHandler())
+		debugServer.Handle("/debug/snapshot", status.SnapshotHandler(retentionWriter))
+		debugServer.Handle("/debug/dependencies", dependency.GraphHandler(store))
+		debugServer.Handle("/debug/check-history", checker.HistoryHandler(store))
+		debugServer.Handle("/debug/heartbeat", heartbeat.Handler())
+		if nrdpServer != nil && nrdpServer.Senders() != nil {
+			debugServer.Handle("/debug/nrdp-senders", nrdpServer.Senders().Handler())
+		}
+		if err := debugServer.Start(); err != nil {
+			nagLogger.Log("Warning: Failed to start debug listener: %v", err)
+			debugServer = nil
+		}
+	}
+
+	// --- Self-check probes ---
+	if selfMon != nil {
+		attachSelfCheckProbes(selfMon, sched, executor, resultQueue, livestatusServer, nrdpServer)
+		selfMon.Start(resultQueue.Submit)
+		nagLogger.Log("Self-check enabled for host '%s' every %ds", selfMon.HostName(), mainCfg.SelfCheckInterval)
+	}
 
 	// --- Run main event loop (blocks until Stop) ---
 	sched.Run()
@@ -896,18 +2420,33 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	// --- Shutdown ---
 	nagLogger.Log("Shutting down...")
 
+	if selfMon != nil {
+		selfMon.Stop()
+	}
+
 	if nrdpServer != nil {
 		nrdpServer.Stop()
 	}
 
+	// Producers are stopped; keep anything still spilled for the next start.
+	resultQueue.Stop()
+
 	if livestatusServer != nil {
 		livestatusServer.Stop()
 	}
 
+	if debugServer != nil {
+		debugServer.Stop()
+	}
+
 	if cmdProcessor != nil {
 		cmdProcessor.Stop()
 	}
 
+	// Send notifications still waiting in a digest window and let them finish.
+	notifEngine.FlushDigests()
+	notifEngine.CmdExecutor.Wait()
+
 	// Save final retention data
 	if mainCfg.RetainStateInformation {
 		if err := retentionWriter.Write(); err != nil {
@@ -918,461 +2457,203 @@ func runDaemon(configFile string, daemonMode bool, verbosity int) {
 	}
 
 	// Write final status
-	statusWriter.Write()
+	if statusWriter != nil {
+		statusWriter.Write()
+	}
 
 	nagLogger.Log("Successfully shutdown... (PID=%d)", os.Getpid())
 }
 
-// registerCommandHandlers wires up the most common external commands.
-func registerCommandHandlers(
-	p *extcmd.Processor,
-	store *objects.ObjectStore,
-	gs *objects.GlobalState,
-	sched *scheduler.Scheduler,
-	notifEngine *notify.NotificationEngine,
-	commentMgr *downtime.CommentManager,
-	downtimeMgr *downtime.DowntimeManager,
-	logger *logging.Logger,
-	resultCh chan *objects.CheckResult,
-) {
-	// System commands
-	p.RegisterHandler("ENABLE_NOTIFICATIONS", func(cmd *extcmd.Command) {
-		gs.EnableNotifications = true
-		logger.Log("EXTERNAL COMMAND: ENABLE_NOTIFICATIONS")
-	})
-	p.RegisterHandler("DISABLE_NOTIFICATIONS", func(cmd *extcmd.Command) {
-		gs.EnableNotifications = false
-		logger.Log("EXTERNAL COMMAND: DISABLE_NOTIFICATIONS")
-	})
-	p.RegisterHandler("START_EXECUTING_SVC_CHECKS", func(cmd *extcmd.Command) {
-		gs.ExecuteServiceChecks = true
-		logger.Log("EXTERNAL COMMAND: START_EXECUTING_SVC_CHECKS")
-	})
-	p.RegisterHandler("STOP_EXECUTING_SVC_CHECKS", func(cmd *extcmd.Command) {
-		gs.ExecuteServiceChecks = false
-		logger.Log("EXTERNAL COMMAND: STOP_EXECUTING_SVC_CHECKS")
-	})
-	p.RegisterHandler("START_EXECUTING_HOST_CHECKS", func(cmd *extcmd.Command) {
-		gs.ExecuteHostChecks = true
-		logger.Log("EXTERNAL COMMAND: START_EXECUTING_HOST_CHECKS")
-	})
-	p.RegisterHandler("STOP_EXECUTING_HOST_CHECKS", func(cmd *extcmd.Command) {
-		gs.ExecuteHostChecks = false
-		logger.Log("EXTERNAL COMMAND: STOP_EXECUTING_HOST_CHECKS")
-	})
-	p.RegisterHandler("ENABLE_EVENT_HANDLERS", func(cmd *extcmd.Command) {
-		gs.EnableEventHandlers = true
-		logger.Log("EXTERNAL COMMAND: ENABLE_EVENT_HANDLERS")
-	})
-	p.RegisterHandler("DISABLE_EVENT_HANDLERS", func(cmd *extcmd.Command) {
-		gs.EnableEventHandlers = false
-		logger.Log("EXTERNAL COMMAND: DISABLE_EVENT_HANDLERS")
-	})
-	p.RegisterHandler("ENABLE_FLAP_DETECTION", func(cmd *extcmd.Command) {
-		gs.EnableFlapDetection = true
-		logger.Log("EXTERNAL COMMAND: ENABLE_FLAP_DETECTION")
-	})
-	p.RegisterHandler("DISABLE_FLAP_DETECTION", func(cmd *extcmd.Command) {
-		gs.EnableFlapDetection = false
-		logger.Log("EXTERNAL COMMAND: DISABLE_FLAP_DETECTION")
-	})
-
-	// Process passive check results
-	p.RegisterHandler("PROCESS_SERVICE_CHECK_RESULT", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 4 {
-			return
-		}
-		hostName := cmd.Args[0]
-		svcDesc := cmd.Args[1]
-		rc := 0
-		fmt.Sscanf(cmd.Args[2], "%d", &rc)
-		output := cmd.Args[3]
-
-		svc := store.GetService(hostName, svcDesc)
-		if svc == nil {
-			return
-		}
-		now := time.Now()
-		sched.SendCommand(scheduler.Command{Name: "_INTERNAL_RESULT"})
-		// Send directly as check result
-		cr := &objects.CheckResult{
-			HostName:           hostName,
-			ServiceDescription: svcDesc,
-			CheckType:          objects.CheckTypePassive,
-			ReturnCode:         rc,
-			Output:             output,
-			StartTime:          now,
-			FinishTime:         now,
-			ExitedOK:           true,
-		}
-		// Process inline since we're on the command handler goroutine
-		// The scheduler's OnProcessResult will be called via resultCh
-		go func() { resultCh <- cr }()
+// attachSelfCheckProbes wires the self-check services to the subsystems
+// they report on. Thresholds are fixed; override notification behaviour
+// with a regular host/service definition for the self-check host.
+func attachSelfCheckProbes(m *selfcheck.Monitor, sched *scheduler.Scheduler, executor *checker.Router,
+	results *resultq.Queue, ls *livestatus.Server, nrdpServer *nrdp.Server) {
+	m.SetProbe("Scheduler", func() (int, string) {
+		last := sched.LastIteration()
+		if last.IsZero() {
+			return 3, "UNKNOWN - main loop has not started"
+		}
+		age := time.Since(last).Seconds()
+		rc := selfcheck.Threshold(age, 60, 180)
+		if sched.Paused() && rc == 0 {
+			return 1, fmt.Sprintf("WARNING - check dispatching paused by PAUSE_SCHEDULER, %d checks running|loop_age=%.3fs;60;180;0 checks_running=%d",
+				executor.JobsRunning(), age, executor.JobsRunning())
+		}
+		return rc, fmt.Sprintf("%s - main loop last iterated %.1fs ago, %d checks running|loop_age=%.3fs;60;180;0 checks_running=%d",
+			selfcheck.StateLabel(rc), age, executor.JobsRunning(), age, executor.JobsRunning())
 	})
-
-	p.RegisterHandler("PROCESS_HOST_CHECK_RESULT", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 3 {
-			return
-		}
-		hostName := cmd.Args[0]
-		rc := 0
-		fmt.Sscanf(cmd.Args[1], "%d", &rc)
-		output := cmd.Args[2]
-
-		host := store.GetHost(hostName)
-		if host == nil {
-			return
-		}
-		now := time.Now()
-		cr := &objects.CheckResult{
-			HostName:   hostName,
-			CheckType:  objects.CheckTypePassive,
-			ReturnCode: rc,
-			Output:     output,
-			StartTime:  now,
-			FinishTime: now,
-			ExitedOK:   true,
-		}
-		go func() { resultCh <- cr }()
-	})
-
-	// Schedule forced checks
-	p.RegisterHandler("SCHEDULE_FORCED_SVC_CHECK", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 3 {
-			return
-		}
-		hostName := cmd.Args[0]
-		svcDesc := cmd.Args[1]
-		var checkTime int64
-		fmt.Sscanf(cmd.Args[2], "%d", &checkTime)
-		sched.AddEvent(&scheduler.Event{
-			Type:               scheduler.EventServiceCheck,
-			RunTime:            time.Unix(checkTime, 0),
-			HostName:           hostName,
-			ServiceDescription: svcDesc,
-			CheckOptions:       objects.CheckOptionForceExecution,
-		})
-		logger.Log("EXTERNAL COMMAND: SCHEDULE_FORCED_SVC_CHECK;%s;%s;%d", hostName, svcDesc, checkTime)
+	var lastDropped int64
+	m.SetProbe("Result Queue", func() (int, string) {
+		st := results.Stats()
+		pct := float64(st.Depth) * 100 / float64(st.Capacity)
+		rc := selfcheck.Threshold(pct, 50, 90)
+		if st.SpillDepth > 0 {
+			rc = max(rc, 1)
+		}
+		newDrops := st.Dropped - lastDropped
+		lastDropped = st.Dropped
+		if newDrops > 0 {
+			rc = 2
+		}
+		return rc, fmt.Sprintf("%s - %d of %d results queued (%.1f%%), %d spilled to disk, %d dropped since last probe|depth=%d;%d;%d;0;%d spill_depth=%d dropped=%dc",
+			selfcheck.StateLabel(rc), st.Depth, st.Capacity, pct, st.SpillDepth, newDrops,
+			st.Depth, st.Capacity/2, st.Capacity*9/10, st.Capacity, st.SpillDepth, st.Dropped)
 	})
-
-	p.RegisterHandler("SCHEDULE_FORCED_HOST_CHECK", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 2 {
-			return
-		}
-		hostName := cmd.Args[0]
-		var checkTime int64
-		fmt.Sscanf(cmd.Args[1], "%d", &checkTime)
-		sched.AddEvent(&scheduler.Event{
-			Type:         scheduler.EventHostCheck,
-			RunTime:      time.Unix(checkTime, 0),
-			HostName:     hostName,
-			CheckOptions: objects.CheckOptionForceExecution,
+	if ls != nil {
+		m.SetProbe("Livestatus", func() (int, string) {
+			rtt, err := ls.Ping(10 * time.Second)
+			if err != nil {
+				return 2, fmt.Sprintf("CRITICAL - status query failed: %v", err)
+			}
+			rc := selfcheck.Threshold(rtt.Seconds(), 1, 5)
+			return rc, fmt.Sprintf("%s - status query answered in %.3fs|time=%.6fs;1;5;0",
+				selfcheck.StateLabel(rc), rtt.Seconds(), rtt.Seconds())
 		})
-		logger.Log("EXTERNAL COMMAND: SCHEDULE_FORCED_HOST_CHECK;%s;%d", hostName, checkTime)
-	})
-
-	// Acknowledge problems
-	p.RegisterHandler("ACKNOWLEDGE_SVC_PROBLEM", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 7 {
-			return
-		}
-		hostName := cmd.Args[0]
-		svcDesc := cmd.Args[1]
-		svc := store.GetService(hostName, svcDesc)
-		if svc == nil {
-			return
-		}
-		sticky := cmd.Args[2] == "2"
-		sendNotif := cmd.Args[3] == "1"
-		// persistent := cmd.Args[4] == "1"
-		author := cmd.Args[5]
-		comment := cmd.Args[6]
-
-		if sticky {
-			svc.AckType = objects.AckSticky
-		} else {
-			svc.AckType = objects.AckNormal
-		}
-		svc.ProblemAcknowledged = true
-
-		if sendNotif {
-			notifEngine.ServiceNotification(svc, objects.NotificationAcknowledgement, author, comment, 0)
-		}
-		logger.Log("EXTERNAL COMMAND: ACKNOWLEDGE_SVC_PROBLEM;%s;%s", hostName, svcDesc)
-	})
-
-	p.RegisterHandler("ACKNOWLEDGE_HOST_PROBLEM", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 6 {
-			return
-		}
-		hostName := cmd.Args[0]
-		host := store.GetHost(hostName)
-		if host == nil {
-			return
-		}
-		sticky := cmd.Args[1] == "2"
-		sendNotif := cmd.Args[2] == "1"
-		author := cmd.Args[4]
-		comment := cmd.Args[5]
-
-		if sticky {
-			host.AckType = objects.AckSticky
-		} else {
-			host.AckType = objects.AckNormal
-		}
-		host.ProblemAcknowledged = true
-
-		if sendNotif {
-			notifEngine.HostNotification(host, objects.NotificationAcknowledgement, author, comment, 0)
-		}
-		logger.Log("EXTERNAL COMMAND: ACKNOWLEDGE_HOST_PROBLEM;%s", hostName)
-	})
-
-	// Schedule downtimes
-	p.RegisterHandler("SCHEDULE_HOST_DOWNTIME", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 8 {
-			return
-		}
-		hostName := cmd.Args[0]
-		host := store.GetHost(hostName)
-		if host == nil {
-			return
-		}
-		var startTS, endTS, triggerID, duration int64
-		fixed := cmd.Args[3] == "1"
-		fmt.Sscanf(cmd.Args[1], "%d", &startTS)
-		fmt.Sscanf(cmd.Args[2], "%d", &endTS)
-		fmt.Sscanf(cmd.Args[4], "%d", &triggerID)
-		fmt.Sscanf(cmd.Args[5], "%d", &duration)
-		author := cmd.Args[6]
-		comment := cmd.Args[7]
-
-		d := &downtime.Downtime{
-			Type:        objects.HostDowntimeType,
-			HostName:    hostName,
-			StartTime:   time.Unix(startTS, 0),
-			EndTime:     time.Unix(endTS, 0),
-			Fixed:       fixed,
-			TriggeredBy: uint64(triggerID),
-			Duration:    time.Duration(duration) * time.Second,
-			Author:      author,
-			Comment:     comment,
-		}
-		id := downtimeMgr.Schedule(d)
-		logger.Log("EXTERNAL COMMAND: SCHEDULE_HOST_DOWNTIME;%s", hostName)
-
-		// For fixed downtimes that start now or in the past, start immediately
-		if fixed && !time.Unix(startTS, 0).After(time.Now()) {
-			downtimeMgr.HandleStart(id)
-		}
-
-		// Schedule end via goroutine timer
-		endTime := time.Unix(endTS, 0)
-		go func(dtID uint64) {
-			wait := time.Until(endTime)
-			if wait > 0 {
-				time.Sleep(wait)
+	}
+	if nrdpServer != nil {
+		var lastDropped int64
+		m.SetProbe("NRDP", func() (int, string) {
+			rtt, err := nrdpServer.Ping(10 * time.Second)
+			if err != nil {
+				return 2, fmt.Sprintf("CRITICAL - endpoint not responding: %v", err)
 			}
-			downtimeMgr.HandleEnd(dtID)
-		}(id)
-	})
-
-	p.RegisterHandler("SCHEDULE_SVC_DOWNTIME", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 9 {
-			return
-		}
-		hostName := cmd.Args[0]
-		svcDesc := cmd.Args[1]
-		svc := store.GetService(hostName, svcDesc)
-		if svc == nil {
-			return
-		}
-		var startTS, endTS, triggerID, duration int64
-		fixed := cmd.Args[4] == "1"
-		fmt.Sscanf(cmd.Args[2], "%d", &startTS)
-		fmt.Sscanf(cmd.Args[3], "%d", &endTS)
-		fmt.Sscanf(cmd.Args[5], "%d", &triggerID)
-		fmt.Sscanf(cmd.Args[6], "%d", &duration)
-		author := cmd.Args[7]
-		comment := cmd.Args[8]
-
-		d := &downtime.Downtime{
-			Type:               objects.ServiceDowntimeType,
-			HostName:           hostName,
-			ServiceDescription: svcDesc,
-			StartTime:          time.Unix(startTS, 0),
-			EndTime:            time.Unix(endTS, 0),
-			Fixed:              fixed,
-			TriggeredBy:        uint64(triggerID),
-			Duration:           time.Duration(duration) * time.Second,
-			Author:             author,
-			Comment:            comment,
-		}
-		id := downtimeMgr.Schedule(d)
-		logger.Log("EXTERNAL COMMAND: SCHEDULE_SVC_DOWNTIME;%s;%s", hostName, svcDesc)
-
-		// For fixed downtimes that start now or in the past, start immediately
-		if fixed && !time.Unix(startTS, 0).After(time.Now()) {
-			downtimeMgr.HandleStart(id)
-		}
-
-		// Schedule end via goroutine timer
-		endTime := time.Unix(endTS, 0)
-		go func(dtID uint64) {
-			wait := time.Until(endTime)
-			if wait > 0 {
-				time.Sleep(wait)
+			received, dropped := nrdpServer.Stats()
+			newDrops := dropped - lastDropped
+			lastDropped = dropped
+			rc := selfcheck.Threshold(rtt.Seconds(), 1, 5)
+			if newDrops > 0 {
+				rc = max(rc, 1)
 			}
-			downtimeMgr.HandleEnd(dtID)
-		}(id)
-	})
-
-	p.RegisterHandler("DEL_HOST_DOWNTIME", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 1 {
-			return
-		}
-		var id uint64
-		fmt.Sscanf(cmd.Args[0], "%d", &id)
-		downtimeMgr.Unschedule(id)
-		logger.Log("EXTERNAL COMMAND: DEL_HOST_DOWNTIME;%d", id)
-	})
-
-	p.RegisterHandler("DEL_SVC_DOWNTIME", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 1 {
-			return
-		}
-		var id uint64
-		fmt.Sscanf(cmd.Args[0], "%d", &id)
-		downtimeMgr.Unschedule(id)
-		logger.Log("EXTERNAL COMMAND: DEL_SVC_DOWNTIME;%d", id)
-	})
-
-	// Remove acknowledgement
-	p.RegisterHandler("REMOVE_SVC_ACKNOWLEDGEMENT", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 2 {
-			return
-		}
-		hostName := cmd.Args[0]
-		svcDesc := cmd.Args[1]
-		svc := store.GetService(hostName, svcDesc)
-		if svc == nil {
-			return
+			return rc, fmt.Sprintf("%s - endpoint answered in %.3fs, %d results received, %d dropped since last probe|time=%.6fs;1;5;0 received=%dc dropped=%dc",
+				selfcheck.StateLabel(rc), rtt.Seconds(), received, newDrops, rtt.Seconds(), received, dropped)
+		})
+		if senders := nrdpServer.Senders(); senders != nil {
+			m.SetProbe("NRDP Senders", func() (int, string) {
+				all := senders.Senders()
+				var stale []string
+				for _, s := range all {
+					if s.Stale {
+						stale = append(stale, s.Sender)
+					}
+				}
+				perf := fmt.Sprintf("senders=%d stale=%d;;1;0", len(all), len(stale))
+				if len(stale) > 0 {
+					return 2, fmt.Sprintf("CRITICAL - %d of %d senders silent for over %s: %s|%s",
+						len(stale), len(all), senders.Threshold(), strings.Join(stale, ", "), perf)
+				}
+				return 0, fmt.Sprintf("OK - %d senders submitting|%s", len(all), perf)
+			})
 		}
-		svc.ProblemAcknowledged = false
-		svc.AckType = objects.AckNone
-		logger.Log("EXTERNAL COMMAND: REMOVE_SVC_ACKNOWLEDGEMENT;%s;%s", hostName, svcDesc)
-	})
+	}
+}
 
-	p.RegisterHandler("REMOVE_HOST_ACKNOWLEDGEMENT", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 1 {
+// concurrencyClassMap maps "host\tservice" keys to the class of each check
+// whose concurrency class has a limit in check_concurrency_classes. A class
+// that objects use but that has no limit is logged, since its checks run
+// unlimited.
+func concurrencyClassMap(store *objects.ObjectStore, limits map[string]int, logger *logging.Logger) map[string]string {
+	classes := make(map[string]string)
+	unlimited := make(map[string]bool)
+	add := func(key, class string) {
+		if class == "" {
 			return
 		}
-		hostName := cmd.Args[0]
-		host := store.GetHost(hostName)
-		if host == nil {
+		if _, ok := limits[class]; !ok {
+			unlimited[class] = true
 			return
 		}
-		host.ProblemAcknowledged = false
-		host.AckType = objects.AckNone
-		logger.Log("EXTERNAL COMMAND: REMOVE_HOST_ACKNOWLEDGEMENT;%s", hostName)
-	})
-
-	// Per-host/service notification and check toggles
-	p.RegisterHandler("DISABLE_HOST_NOTIFICATIONS", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 1 {
-			return
-		}
-		host := store.GetHost(cmd.Args[0])
-		if host != nil {
-			host.NotificationsEnabled = false
-		}
-		logger.Log("EXTERNAL COMMAND: DISABLE_HOST_NOTIFICATIONS;%s", cmd.Args[0])
-	})
-
-	p.RegisterHandler("ENABLE_HOST_NOTIFICATIONS", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 1 {
-			return
-		}
-		host := store.GetHost(cmd.Args[0])
-		if host != nil {
-			host.NotificationsEnabled = true
-		}
-		logger.Log("EXTERNAL COMMAND: ENABLE_HOST_NOTIFICATIONS;%s", cmd.Args[0])
-	})
+		classes[key] = class
+	}
+	for _, h := range store.Hosts {
+		add(h.Name+"\t", checker.ConcurrencyClassFor(h, nil, h.CheckCommand))
+	}
+	for _, svc := range store.Services {
+		add(svc.Host.Name+"\t"+svc.Description, checker.ConcurrencyClassFor(svc.Host, svc, svc.CheckCommand))
+	}
+	for class := range unlimited {
+		logger.Log("Warning: Concurrency class '%s' is not in check_concurrency_classes; its checks are not limited", class)
+	}
+	return classes
+}
 
-	p.RegisterHandler("DISABLE_SVC_NOTIFICATIONS", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 2 {
-			return
-		}
-		svc := store.GetService(cmd.Args[0], cmd.Args[1])
-		if svc != nil {
-			svc.NotificationsEnabled = false
-		}
-		logger.Log("EXTERNAL COMMAND: DISABLE_SVC_NOTIFICATIONS;%s;%s", cmd.Args[0], cmd.Args[1])
-	})
+// hostAddresses maps host names to addresses, for the SSH runner.
+func hostAddresses(store *objects.ObjectStore) map[string]string {
+	addrs := make(map[string]string, len(store.Hosts))
+	for _, h := range store.Hosts {
+		addrs[h.Name] = h.Address
+	}
+	return addrs
+}
 
-	p.RegisterHandler("ENABLE_SVC_NOTIFICATIONS", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 2 {
-			return
-		}
-		svc := store.GetService(cmd.Args[0], cmd.Args[1])
-		if svc != nil {
-			svc.NotificationsEnabled = true
-		}
-		logger.Log("EXTERNAL COMMAND: ENABLE_SVC_NOTIFICATIONS;%s;%s", cmd.Args[0], cmd.Args[1])
-	})
+// reloadDiff counts the hosts and services a reload added and removed.
+type reloadDiff struct {
+	hostsAdded, hostsRemoved       int
+	servicesAdded, servicesRemoved int
+}
 
-	p.RegisterHandler("DISABLE_HOST_CHECK", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 1 {
-			return
+// diffObjects compares the hosts and services of the store before and
+// after a reload.
+func diffObjects(old, cur *objects.ObjectStore) reloadDiff {
+	var d reloadDiff
+	for _, h := range cur.Hosts {
+		if old.GetHost(h.Name) == nil {
+			d.hostsAdded++
 		}
-		hst := store.GetHost(cmd.Args[0])
-		if hst != nil {
-			hst.ActiveChecksEnabled = false
+	}
+	for _, h := range old.Hosts {
+		if cur.GetHost(h.Name) == nil {
+			d.hostsRemoved++
 		}
-		logger.Log("EXTERNAL COMMAND: DISABLE_HOST_CHECK;%s", cmd.Args[0])
-	})
-
-	p.RegisterHandler("ENABLE_HOST_CHECK", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 1 {
-			return
+	}
+	for _, svc := range cur.Services {
+		if old.GetService(svc.Host.Name, svc.Description) == nil {
+			d.servicesAdded++
 		}
-		hst := store.GetHost(cmd.Args[0])
-		if hst != nil {
-			hst.ActiveChecksEnabled = true
+	}
+	for _, svc := range old.Services {
+		if cur.GetService(svc.Host.Name, svc.Description) == nil {
+			d.servicesRemoved++
 		}
-		logger.Log("EXTERNAL COMMAND: ENABLE_HOST_CHECK;%s", cmd.Args[0])
-	})
+	}
+	return d
+}
 
-	p.RegisterHandler("DISABLE_SVC_CHECK", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 2 {
-			return
-		}
-		svc := store.GetService(cmd.Args[0], cmd.Args[1])
-		if svc != nil {
-			svc.ActiveChecksEnabled = false
+// dropRemovedObjects deletes the comments and downtimes of hosts and
+// services a reload removed, as a restart would.
+func dropRemovedObjects(store *objects.ObjectStore, comments *downtime.CommentManager, downtimes *downtime.DowntimeManager) {
+	exists := func(hostName, svcDesc string) bool {
+		if svcDesc == "" {
+			return store.GetHost(hostName) != nil
 		}
-		logger.Log("EXTERNAL COMMAND: DISABLE_SVC_CHECK;%s;%s", cmd.Args[0], cmd.Args[1])
-	})
-
-	p.RegisterHandler("ENABLE_SVC_CHECK", func(cmd *extcmd.Command) {
-		if len(cmd.Args) < 2 {
-			return
+		return store.GetService(hostName, svcDesc) != nil
+	}
+	for _, d := range downtimes.All() {
+		if !exists(d.HostName, d.ServiceDescription) {
+			downtimes.Unschedule(d.DowntimeID)
 		}
-		svc := store.GetService(cmd.Args[0], cmd.Args[1])
-		if svc != nil {
-			svc.ActiveChecksEnabled = true
+	}
+	for _, c := range comments.All() {
+		if !exists(c.HostName, c.ServiceDescription) {
+			comments.Delete(c.CommentID)
 		}
-		logger.Log("EXTERNAL COMMAND: ENABLE_SVC_CHECK;%s;%s", cmd.Args[0], cmd.Args[1])
-	})
+	}
+}
 
-	// Shutdown
-	p.RegisterHandler("SHUTDOWN_PROCESS", func(cmd *extcmd.Command) {
-		logger.Log("EXTERNAL COMMAND: SHUTDOWN_PROCESS")
-		sched.Stop()
-	})
-	p.RegisterHandler("SHUTDOWN_PROGRAM", func(cmd *extcmd.Command) {
-		logger.Log("EXTERNAL COMMAND: SHUTDOWN_PROGRAM")
-		sched.Stop()
-	})
+// execEnvError is the UNKNOWN result for a check whose _CHECK_CWD,
+// _CHECK_ENV or _CHECK_UMASK custom variable is invalid.
+func execEnvError(hostName, svcDesc string, options int, latency float64, err error) *objects.CheckResult {
+	now := time.Now()
+	return &objects.CheckResult{
+		HostName:           hostName,
+		ServiceDescription: svcDesc,
+		CheckType:          objects.CheckTypeActive,
+		CheckOptions:       options,
+		ReturnCode:         3,
+		Output:             fmt.Sprintf("(Could not execute plugin: %v)", err),
+		StartTime:          now,
+		FinishTime:         now,
+		ExitedOK:           false,
+		Latency:            latency,
+	}
 }
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/checker"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func startAgent(t *testing.T, token string) (*Server, string) {
+	t.Helper()
+	srv, err := NewServer(ServerConfig{Listen: "127.0.0.1:0", Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	return srv, addr.String()
+}
+
+func newTestExecutor(t *testing.T, token, addr string) (*Executor, chan *objects.CheckResult) {
+	t.Helper()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(token+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resultCh := make(chan *objects.CheckResult, 1)
+	e, err := NewExecutor(2, resultCh, ExecutorConfig{TokenFile: tokenFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.AddressLookup = func(string) string { return addr }
+	t.Cleanup(e.Stop)
+	return e, resultCh
+}
+
+func result(t *testing.T, ch chan *objects.CheckResult) *objects.CheckResult {
+	t.Helper()
+	select {
+	case cr := <-ch:
+		return cr
+	case <-time.After(10 * time.Second):
+		t.Fatal("no result")
+		return nil
+	}
+}
+
+func TestExecutorRunsCheckOnAgent(t *testing.T) {
+	_, addr := startAgent(t, "s3cret")
+	e, ch := newTestExecutor(t, "s3cret", addr)
+
+	e.Submit("web01", "Load", "echo 'WARNING - load 5|load=5'; exit 1", 5*time.Second, 0, objects.CheckTypeActive, 0.5)
+	cr := result(t, ch)
+	if cr.HostName != "web01" || cr.ServiceDescription != "Load" || cr.Latency != 0.5 {
+		t.Errorf("result not attributed to the check: %+v", cr)
+	}
+	if cr.ReturnCode != 1 || cr.Output != "WARNING - load 5|load=5\n" || !cr.ExitedOK {
+		t.Errorf("got %d %q, want the plugin's result", cr.ReturnCode, cr.Output)
+	}
+	if cr.Source != "agent "+addr {
+		t.Errorf("unexpected source %q", cr.Source)
+	}
+
+	e.Submit("web01", "Slow", "exec sleep 5", time.Second, 0, objects.CheckTypeActive, 0)
+	if cr := result(t, ch); !cr.EarlyTimeout || cr.ReturnCode != 2 {
+		t.Errorf("expected the agent to time the check out, got %d %q", cr.ReturnCode, cr.Output)
+	}
+}
+
+func TestExecutorRejectedToken(t *testing.T) {
+	_, addr := startAgent(t, "s3cret")
+	e, ch := newTestExecutor(t, "wrong", addr)
+
+	e.Submit("web01", "", "echo ok", 5*time.Second, 0, objects.CheckTypeActive, 0)
+	cr := result(t, ch)
+	if cr.ReturnCode != 3 || cr.ExitedOK || !strings.Contains(cr.Output, "invalid token") {
+		t.Errorf("got %d %q, want UNKNOWN for a rejected token", cr.ReturnCode, cr.Output)
+	}
+}
+
+func TestExecutorRunsBuiltinsLocally(t *testing.T) {
+	checker.RegisterBuiltin("gogios_test_agent", func(ctx context.Context, args []string) (int, string) {
+		return 0, "local " + strings.Join(args, " ")
+	})
+	srv, addr := startAgent(t, "s3cret")
+	srv.Run = func(command string, timeout time.Duration) *objects.CheckResult {
+		t.Errorf("builtin %q was sent to the agent", command)
+		return checker.RunOnce(command, timeout)
+	}
+	e, ch := newTestExecutor(t, "s3cret", addr)
+
+	e.Submit("web01", "Cluster", "gogios_test_agent -w 1", 5*time.Second, 0, objects.CheckTypeActive, 0)
+	if cr := result(t, ch); cr.ReturnCode != 0 || cr.Output != "local -w 1" || cr.HostName != "web01" {
+		t.Errorf("unexpected builtin result %+v", cr)
+	}
+}
+
+func TestNewServerRequiresToken(t *testing.T) {
+	if _, err := NewServer(ServerConfig{}); err == nil {
+		t.Error("expected an error without a token")
+	}
+}
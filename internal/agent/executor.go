@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/checker"
+	"github.com/oceanplexian/gogios/internal/objects"
+	"github.com/oceanplexian/gogios/internal/protowire"
+)
+
+// ExecutorConfig configures the agent check runner.
+type ExecutorConfig struct {
+	Port      int    // agent port (default DefaultPort)
+	TokenFile string // file holding the agents' bearer token; required
+	TLS       bool   // dial the agents over TLS
+	CAFile    string // CA bundle for TLS; empty uses the system roots
+}
+
+type job struct {
+	hostName     string
+	svcDesc      string
+	command      string
+	timeout      time.Duration
+	checkOptions int
+	checkType    int
+	latency      float64
+}
+
+// Executor runs check commands on the monitored host through its
+// agent. HTTP/2 multiplexes every check to one agent over a single
+// connection.
+type Executor struct {
+	jobCh       chan job
+	jobsRunning atomic.Int64
+	resultCh    chan *objects.CheckResult
+	client      *http.Client
+	scheme      string
+	port        int
+	token       string
+
+	// AddressLookup maps a host name to the address to connect to. When nil
+	// or when it returns "", the host name itself is dialed.
+	AddressLookup func(hostName string) string
+}
+
+var _ checker.CheckRunner = (*Executor)(nil)
+
+// NewExecutor creates an agent runner with maxConcurrent workers.
+func NewExecutor(maxConcurrent int, resultCh chan *objects.CheckResult, cfg ExecutorConfig) (*Executor, error) {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 64
+	}
+	if cfg.TokenFile == "" {
+		return nil, errors.New("agent executor: no token file configured")
+	}
+	data, err := os.ReadFile(cfg.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("agent executor: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("agent executor: %s is empty", cfg.TokenFile)
+	}
+
+	protocols := new(http.Protocols)
+	transport := &http.Transport{
+		Protocols:           protocols,
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	scheme := "http"
+	if cfg.TLS {
+		scheme = "https"
+		protocols.SetHTTP2(true)
+		transport.TLSClientConfig = &tls.Config{}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("agent executor: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("agent executor: no certificates in %s", cfg.CAFile)
+			}
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	} else {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	port := cfg.Port
+	if port <= 0 {
+		port = DefaultPort
+	}
+
+	e := &Executor{
+		jobCh:    make(chan job, maxConcurrent*4),
+		resultCh: resultCh,
+		client:   &http.Client{Transport: transport},
+		scheme:   scheme,
+		port:     port,
+		token:    token,
+	}
+	for i := 0; i < maxConcurrent; i++ {
+		go e.worker()
+	}
+	return e, nil
+}
+
+// Submit queues a check for execution on its host's agent without blocking.
+func (e *Executor) Submit(hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64) {
+	j := job{
+		hostName:     hostName,
+		svcDesc:      svcDesc,
+		command:      command,
+		timeout:      timeout,
+		checkOptions: checkOptions,
+		checkType:    checkType,
+		latency:      latency,
+	}
+	select {
+	case e.jobCh <- j:
+	default:
+		go func() { e.jobCh <- j }()
+	}
+}
+
+// JobsRunning returns the current number of executing checks.
+func (e *Executor) JobsRunning() int64 {
+	return e.jobsRunning.Load()
+}
+
+// Stop shuts down the workers and closes idle agent connections.
+func (e *Executor) Stop() {
+	close(e.jobCh)
+	e.client.CloseIdleConnections()
+}
+
+func (e *Executor) worker() {
+	for j := range e.jobCh {
+		e.jobsRunning.Add(1)
+		cr := e.run(j)
+		e.jobsRunning.Add(-1)
+		e.resultCh <- cr
+	}
+}
+
+func (e *Executor) target(hostName string) string {
+	addr := hostName
+	if e.AddressLookup != nil {
+		if a := e.AddressLookup(hostName); a != "" {
+			addr = a
+		}
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(e.port))
+	}
+	return addr
+}
+
+func (e *Executor) run(j job) *objects.CheckResult {
+	// Builtin checks probe from the monitoring server, as with the SSH
+	// runner; some, like gogios_cluster, need the daemon's state.
+	if _, _, ok := checker.LookupBuiltin(j.command); ok {
+		cr := checker.RunOnce(j.command, j.timeout)
+		cr.HostName, cr.ServiceDescription = j.hostName, j.svcDesc
+		cr.CheckType, cr.CheckOptions, cr.Latency = j.checkType, j.checkOptions, j.latency
+		return cr
+	}
+
+	target := e.target(j.hostName)
+	cr := &objects.CheckResult{
+		HostName:           j.hostName,
+		ServiceDescription: j.svcDesc,
+		CheckType:          j.checkType,
+		CheckOptions:       j.checkOptions,
+		Latency:            j.latency,
+		ExitedOK:           true,
+		Source:             "agent " + target,
+	}
+	cr.StartTime = time.Now()
+	res, err := e.call(target, j.command, j.timeout)
+	cr.FinishTime = time.Now()
+	cr.ExecutionTime = cr.FinishTime.Sub(cr.StartTime).Seconds()
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		cr.EarlyTimeout = true
+		cr.ReturnCode = 2
+		cr.Output = fmt.Sprintf("(Check timed out after %.0f seconds)", j.timeout.Seconds())
+	case err != nil:
+		cr.ReturnCode = 3
+		cr.ExitedOK = false
+		cr.Output = fmt.Sprintf("(Agent execution failed: %v)", err)
+	default:
+		cr.ReturnCode = res.returnCode
+		cr.Output = res.output
+		cr.EarlyTimeout = res.timedOut
+		if res.executionTime > 0 {
+			cr.ExecutionTime = res.executionTime.Seconds()
+		}
+	}
+	return cr
+}
+
+type runCheckResponse struct {
+	returnCode    int
+	output        string
+	timedOut      bool
+	executionTime time.Duration
+}
+
+// call runs command on the agent at target. The agent enforces timeout
+// itself; the call gets a few seconds more to deliver the result.
+func (e *Executor) call(target, command string, timeout time.Duration) (*runCheckResponse, error) {
+	req := &protowire.Encoder{}
+	req.String(1, command)
+	req.Int64(2, timeout.Milliseconds())
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+5*time.Second)
+	defer cancel()
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.scheme+"://"+target+runCheckPath, bytes.NewReader(frame(req.Bytes())))
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", "application/grpc")
+	hreq.Header.Set("TE", "trailers")
+	hreq.Header.Set("Authorization", "Bearer "+e.token)
+	resp, err := e.client.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status %s", resp.Status)
+	}
+	msg, frameErr := readFrame(resp.Body)
+	// The status arrives in the trailers, which are only complete once the
+	// body has been read to the end.
+	io.Copy(io.Discard, resp.Body)
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if status != "0" {
+		message := resp.Trailer.Get("Grpc-Message")
+		if message == "" {
+			message = resp.Header.Get("Grpc-Message")
+		}
+		return nil, fmt.Errorf("agent returned status %s: %s", status, message)
+	}
+	if frameErr != nil {
+		return nil, frameErr
+	}
+	fields, err := protowire.DecodeFields(msg)
+	if err != nil {
+		return nil, err
+	}
+	res := &runCheckResponse{}
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			res.returnCode = int(int32(f.Num))
+		case 2:
+			res.output = string(f.Data)
+		case 3:
+			res.timedOut = f.Num != 0
+		case 4:
+			res.executionTime = time.Duration(f.Num) * time.Microsecond
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,236 @@
+// Package agent runs checks on monitored hosts over gRPC. Server is the
+// service that "gogios agent" runs on each host, Executor the check runner
+// that sends a host's checks to it. The messages are defined in
+// agent.proto.
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/checker"
+	"github.com/oceanplexian/gogios/internal/objects"
+	"github.com/oceanplexian/gogios/internal/protowire"
+)
+
+// runCheckPath is the gRPC method path of Agent.RunCheck.
+const runCheckPath = "/gogios.agent.v1.Agent/RunCheck"
+
+// gRPC status codes used by the server.
+const (
+	codeInvalidArgument   = 3
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeUnauthenticated   = 16
+)
+
+// maxMessageSize bounds request and response messages.
+const maxMessageSize = 4 << 20
+
+// DefaultPort is the port "gogios agent" listens on and the agent runner
+// dials when none is configured.
+const DefaultPort = 5670
+
+// ServerConfig holds the agent server configuration.
+type ServerConfig struct {
+	Listen         string // e.g. ":5670"
+	Token          string // accepted bearer token; required
+	SSLCert        string // empty serves cleartext HTTP/2
+	SSLKey         string
+	MaxConcurrent  int           // checks run at once; further calls wait (default 32)
+	DefaultTimeout time.Duration // for calls without a timeout (default 60s)
+}
+
+// Server is the gRPC endpoint of "gogios agent".
+type Server struct {
+	cfg       ServerConfig
+	tokenHash [sha256.Size]byte
+	slots     chan struct{}
+	server    *http.Server
+
+	// Run executes one check. It defaults to checker.RunOnce, so builtin
+	// checks run in the agent and everything else under /bin/sh.
+	Run func(command string, timeout time.Duration) *objects.CheckResult
+}
+
+// NewServer creates an agent server. An empty token is refused: the
+// agent runs arbitrary command lines.
+func NewServer(cfg ServerConfig) (*Server, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("agent: no token configured")
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 32
+	}
+	if cfg.DefaultTimeout <= 0 {
+		cfg.DefaultTimeout = 60 * time.Second
+	}
+	return &Server{
+		cfg:       cfg,
+		tokenHash: sha256.Sum256([]byte(cfg.Token)),
+		slots:     make(chan struct{}, cfg.MaxConcurrent),
+		Run:       checker.RunOnce,
+	}, nil
+}
+
+// Handler returns the HTTP handler serving gRPC requests.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveGRPC)
+}
+
+// Start begins listening. Without TLS, HTTP/2 is served in cleartext with
+// prior knowledge.
+func (s *Server) Start() (net.Addr, error) {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	s.server = &http.Server{
+		Handler:     s.Handler(),
+		Protocols:   protocols,
+		IdleTimeout: 5 * time.Minute,
+	}
+	ln, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("agent listen %s: %w", s.cfg.Listen, err)
+	}
+	go func() {
+		var err error
+		if s.cfg.SSLCert != "" && s.cfg.SSLKey != "" {
+			err = s.server.ServeTLS(ln, s.cfg.SSLCert, s.cfg.SSLKey)
+		} else {
+			err = s.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Error: agent server: %v", err)
+		}
+	}()
+	return ln.Addr(), nil
+}
+
+// Stop shuts down the server, waiting up to 5 seconds for running checks.
+func (s *Server) Stop() {
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	}
+}
+
+type rpcError struct {
+	code int
+	msg  string
+}
+
+func (e *rpcError) Error() string { return e.msg }
+
+func (s *Server) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Add("Trailer", "Grpc-Status")
+	w.Header().Add("Trailer", "Grpc-Message")
+
+	resp, err := s.dispatch(r)
+	w.WriteHeader(http.StatusOK)
+	if err == nil {
+		w.Write(frame(resp))
+		w.Header().Set("Grpc-Status", "0")
+		return
+	}
+	code := codeInvalidArgument
+	var re *rpcError
+	if errors.As(err, &re) {
+		code = re.code
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", err.Error())
+}
+
+func (s *Server) dispatch(r *http.Request) ([]byte, error) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	sum := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(sum[:], s.tokenHash[:]) != 1 {
+		return nil, &rpcError{codeUnauthenticated, "invalid token"}
+	}
+	if r.URL.Path != runCheckPath {
+		return nil, &rpcError{codeUnimplemented, "unknown method"}
+	}
+	req, err := readFrame(r.Body)
+	if err != nil {
+		return nil, &rpcError{codeInvalidArgument, err.Error()}
+	}
+	fields, err := protowire.DecodeFields(req)
+	if err != nil {
+		return nil, &rpcError{codeInvalidArgument, err.Error()}
+	}
+	var command string
+	timeout := s.cfg.DefaultTimeout
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			command = string(f.Data)
+		case 2:
+			if f.Num > 0 {
+				timeout = time.Duration(f.Num) * time.Millisecond
+			}
+		}
+	}
+	if strings.TrimSpace(command) == "" {
+		return nil, &rpcError{codeInvalidArgument, "empty command_line"}
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-r.Context().Done():
+		return nil, &rpcError{codeResourceExhausted, "no check slot before the call was cancelled"}
+	}
+	cr := s.Run(command, timeout)
+	<-s.slots
+
+	e := &protowire.Encoder{}
+	e.Int32(1, cr.ReturnCode)
+	e.String(2, cr.Output)
+	e.Bool(3, cr.EarlyTimeout)
+	e.Int64(4, cr.FinishTime.Sub(cr.StartTime).Microseconds())
+	return e.Bytes(), nil
+}
+
+// frame prefixes msg with the gRPC length-prefixed message header.
+func frame(msg []byte) []byte {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// readFrame reads one uncompressed gRPC message.
+func readFrame(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, errors.New("missing message frame")
+	}
+	if hdr[0] != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxMessageSize {
+		return nil, errors.New("message too large")
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, errors.New("truncated message frame")
+	}
+	return msg, nil
+}
@@ -0,0 +1,556 @@
+// Package grpcadmin serves the admin API defined in admin.proto over gRPC.
+//
+// The gRPC framing is implemented directly on top of net/http's HTTP/2
+// support and messages are encoded with internal/protowire, which keeps the
+// binary free of the gRPC and protobuf runtimes. Only unary calls with uncompressed messages are
+// supported, which is all admin.proto uses.
+package grpcadmin
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/logging"
+	"github.com/oceanplexian/gogios/internal/objects"
+	"github.com/oceanplexian/gogios/internal/protowire"
+)
+
+// gRPC status codes used by the server.
+const (
+	codeInvalidArgument  = 3
+	codeNotFound         = 5
+	codePermissionDenied = 7
+	codeUnimplemented    = 12
+	codeInternal         = 13
+	codeUnauthenticated  = 16
+)
+
+// maxMessageSize bounds request messages.
+const maxMessageSize = 4 << 20
+
+// Config holds the admin server configuration.
+type Config struct {
+	Listen    string // e.g. "127.0.0.1:5669"
+	TokenHash string // bcrypt hash of the accepted bearer token; empty disables auth
+	SSLCert   string
+	SSLKey    string
+}
+
+type rpcError struct {
+	code int
+	msg  string
+}
+
+func (e *rpcError) Error() string { return e.msg }
+
+func rpcErrorf(code int, format string, args ...interface{}) error {
+	return &rpcError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+type method func(req []byte) ([]byte, error)
+
+// ErrNotFound is wrapped by OnRunCheck errors for an unknown host or
+// service, which are answered with NOT_FOUND.
+var ErrNotFound = errors.New("not found")
+
+// RunCheckRequest is a decoded RunCheck call.
+type RunCheckRequest struct {
+	HostName           string
+	ServiceDescription string        // empty for a host check
+	CommandLine        string        // empty runs the configured check command
+	Timeout            time.Duration // 0 uses service/host_check_timeout
+}
+
+// RunCheckResult is the outcome of a one-off check.
+type RunCheckResult struct {
+	CommandLine   string // after macro expansion
+	ReturnCode    int
+	Output        string
+	LongOutput    string
+	PerfData      string
+	ExecutionTime time.Duration
+	TimedOut      bool
+}
+
+// Server is the gRPC admin endpoint.
+type Server struct {
+	cfg     Config
+	state   *api.StateProvider
+	sink    api.CommandSink
+	logger  *logging.Logger
+	methods map[string]method
+	server  *http.Server
+
+	// authMu guards cfg.TokenHash and accepted, the SHA-256 of the last
+	// token that matched it, so a client's repeated calls skip bcrypt.
+	// SetTokenHash clears accepted.
+	authMu   sync.Mutex
+	accepted *[sha256.Size]byte
+
+	// OnReload is invoked by the Reload RPC. When nil, Reload returns
+	// UNIMPLEMENTED.
+	OnReload func() error
+
+	// OnRunCheck runs a one-off check for the RunCheck RPC and waits for
+	// it. It must not change the object's state. When nil, RunCheck
+	// returns UNIMPLEMENTED.
+	OnRunCheck func(req RunCheckRequest) (*RunCheckResult, error)
+}
+
+// New creates an admin server.
+func New(cfg Config, state *api.StateProvider, sink api.CommandSink, logger *logging.Logger) *Server {
+	s := &Server{cfg: cfg, state: state, sink: sink, logger: logger}
+	const prefix = "/gogios.admin.v1.Admin/"
+	s.methods = map[string]method{
+		prefix + "GetProgramStatus": s.getProgramStatus,
+		prefix + "GetHost":          s.getHost,
+		prefix + "ListHosts":        s.listHosts,
+		prefix + "GetService":       s.getService,
+		prefix + "ListServices":     s.listServices,
+		prefix + "SubmitCommand":    s.submitCommand,
+		prefix + "Reload":           s.reload,
+		prefix + "RunCheck":         s.runCheck,
+	}
+	return s
+}
+
+// Handler returns the HTTP handler serving gRPC requests.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveGRPC)
+}
+
+// Start begins listening. Without TLS, HTTP/2 is served in cleartext with
+// prior knowledge (what gRPC clients use for insecure channels).
+func (s *Server) Start() error {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	s.server = &http.Server{
+		Addr:        s.cfg.Listen,
+		Handler:     s.Handler(),
+		Protocols:   protocols,
+		IdleTimeout: 5 * time.Minute,
+	}
+	ln, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("gRPC admin listen %s: %w", s.cfg.Listen, err)
+	}
+	go func() {
+		var err error
+		if s.cfg.SSLCert != "" && s.cfg.SSLKey != "" {
+			err = s.server.ServeTLS(ln, s.cfg.SSLCert, s.cfg.SSLKey)
+		} else {
+			err = s.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed && s.logger != nil {
+			s.logger.Log("Error: gRPC admin server: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop shuts down the server.
+func (s *Server) Stop() {
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	}
+}
+
+func (s *Server) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Add("Trailer", "Grpc-Status")
+	w.Header().Add("Trailer", "Grpc-Message")
+
+	resp, err := s.dispatch(r)
+	if err == nil {
+		frame := make([]byte, 5, 5+len(resp))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+		frame = append(frame, resp...)
+		w.WriteHeader(http.StatusOK)
+		w.Write(frame)
+		w.Header().Set("Grpc-Status", "0")
+		return
+	}
+
+	code := codeInternal
+	var re *rpcError
+	if errors.As(err, &re) {
+		code = re.code
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", percentEncode(err.Error()))
+}
+
+func (s *Server) dispatch(r *http.Request) ([]byte, error) {
+	if err := s.authenticate(r); err != nil {
+		return nil, err
+	}
+	m := s.methods[r.URL.Path]
+	if m == nil {
+		return nil, rpcErrorf(codeUnimplemented, "unknown method %s", r.URL.Path)
+	}
+
+	var hdr [5]byte
+	if _, err := io.ReadFull(r.Body, hdr[:]); err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "missing message frame")
+	}
+	if hdr[0] != 0 {
+		return nil, rpcErrorf(codeUnimplemented, "compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxMessageSize {
+		return nil, rpcErrorf(codeInvalidArgument, "message too large")
+	}
+	req := make([]byte, n)
+	if _, err := io.ReadFull(r.Body, req); err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "truncated message frame")
+	}
+	return m(req)
+}
+
+// SetTokenHash replaces the bcrypt hash of the accepted bearer token, e.g.
+// after grpc_admin_token_hash changed on reload. Tokens accepted under the
+// old hash are forgotten.
+func (s *Server) SetTokenHash(hash string) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	s.cfg.TokenHash = hash
+	s.accepted = nil
+}
+
+func (s *Server) tokenHash() string {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	return s.cfg.TokenHash
+}
+
+func (s *Server) authenticate(r *http.Request) error {
+	s.authMu.Lock()
+	hash, accepted := s.cfg.TokenHash, s.accepted
+	s.authMu.Unlock()
+	if hash == "" {
+		return nil
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return rpcErrorf(codeUnauthenticated, "missing bearer token")
+	}
+	sum := sha256.Sum256([]byte(token))
+	if accepted != nil && subtle.ConstantTimeCompare(sum[:], accepted[:]) == 1 {
+		return nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(token)) != nil {
+		return rpcErrorf(codeUnauthenticated, "invalid token")
+	}
+	s.authMu.Lock()
+	// Only cache the token if the hash it matched is still current.
+	if s.cfg.TokenHash == hash {
+		s.accepted = &sum
+	}
+	s.authMu.Unlock()
+	return nil
+}
+
+// percentEncode escapes a grpc-message value per the gRPC HTTP/2 spec.
+func percentEncode(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// --- RPC implementations ---
+
+func (s *Server) getProgramStatus(req []byte) ([]byte, error) {
+	store := s.state.Store
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	gs := s.state.Global
+	e := &protowire.Encoder{}
+	e.Int64(1, gs.ProgramStart.Unix())
+	e.Int32(2, gs.PID)
+	e.Bool(3, gs.EnableNotifications)
+	e.Bool(4, gs.ExecuteServiceChecks)
+	e.Bool(5, gs.ExecuteHostChecks)
+	e.Bool(6, gs.EnableEventHandlers)
+	e.Bool(7, gs.EnableFlapDetection)
+	e.Int32(8, len(store.Hosts))
+	e.Int32(9, len(store.Services))
+	e.Bool(10, gs.SchedulerPaused)
+	return e.Bytes(), nil
+}
+
+func (s *Server) getHost(req []byte) ([]byte, error) {
+	fields, err := protowire.DecodeFields(req)
+	if err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "%v", err)
+	}
+	var name string
+	for _, f := range fields {
+		if f.Number == 1 {
+			name = string(f.Data)
+		}
+	}
+	if name == "" {
+		return nil, rpcErrorf(codeInvalidArgument, "name is required")
+	}
+	s.state.Store.Mu.RLock()
+	defer s.state.Store.Mu.RUnlock()
+	h := s.state.Store.GetHost(name)
+	if h == nil {
+		return nil, rpcErrorf(codeNotFound, "host '%s' not found", name)
+	}
+	return encodeHost(h), nil
+}
+
+func (s *Server) listHosts(req []byte) ([]byte, error) {
+	fields, err := protowire.DecodeFields(req)
+	if err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "%v", err)
+	}
+	var group string
+	for _, f := range fields {
+		if f.Number == 1 {
+			group = string(f.Data)
+		}
+	}
+	store := s.state.Store
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	hosts := store.Hosts
+	if group != "" {
+		hg := store.GetHostGroup(group)
+		if hg == nil {
+			return nil, rpcErrorf(codeNotFound, "hostgroup '%s' not found", group)
+		}
+		hosts = hg.Members
+	}
+	e := &protowire.Encoder{}
+	for _, h := range hosts {
+		e.Message(1, encodeHost(h))
+	}
+	return e.Bytes(), nil
+}
+
+func (s *Server) getService(req []byte) ([]byte, error) {
+	fields, err := protowire.DecodeFields(req)
+	if err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "%v", err)
+	}
+	var hostName, desc string
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			hostName = string(f.Data)
+		case 2:
+			desc = string(f.Data)
+		}
+	}
+	if hostName == "" || desc == "" {
+		return nil, rpcErrorf(codeInvalidArgument, "host_name and description are required")
+	}
+	s.state.Store.Mu.RLock()
+	defer s.state.Store.Mu.RUnlock()
+	svc := s.state.Store.GetService(hostName, desc)
+	if svc == nil {
+		return nil, rpcErrorf(codeNotFound, "service '%s' on host '%s' not found", desc, hostName)
+	}
+	return encodeService(svc), nil
+}
+
+func (s *Server) listServices(req []byte) ([]byte, error) {
+	fields, err := protowire.DecodeFields(req)
+	if err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "%v", err)
+	}
+	var hostName string
+	for _, f := range fields {
+		if f.Number == 1 {
+			hostName = string(f.Data)
+		}
+	}
+	store := s.state.Store
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	e := &protowire.Encoder{}
+	for _, svc := range store.Services {
+		if hostName != "" && svc.Host.Name != hostName {
+			continue
+		}
+		e.Message(1, encodeService(svc))
+	}
+	return e.Bytes(), nil
+}
+
+func (s *Server) submitCommand(req []byte) ([]byte, error) {
+	fields, err := protowire.DecodeFields(req)
+	if err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "%v", err)
+	}
+	var name string
+	var args []string
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			name = string(f.Data)
+		case 2:
+			args = append(args, string(f.Data))
+		}
+	}
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" {
+		return nil, rpcErrorf(codeInvalidArgument, "name is required")
+	}
+	if s.sink == nil {
+		return nil, rpcErrorf(codeUnimplemented, "external commands are disabled")
+	}
+	if s.logger != nil {
+		s.logger.Log("EXTERNAL COMMAND: %s;%s (via gRPC admin API)", name, strings.Join(args, ";"))
+	}
+	s.sink(name, args)
+	return nil, nil
+}
+
+func (s *Server) reload(req []byte) ([]byte, error) {
+	if s.OnReload == nil {
+		return nil, rpcErrorf(codeUnimplemented, "reload is not supported")
+	}
+	if err := s.OnReload(); err != nil {
+		return nil, rpcErrorf(codeInternal, "reload failed: %v", err)
+	}
+	e := &protowire.Encoder{}
+	e.String(1, "reload triggered")
+	return e.Bytes(), nil
+}
+
+func (s *Server) runCheck(req []byte) ([]byte, error) {
+	// RunCheck executes arbitrary command lines, so unlike the other
+	// methods it is never served without authentication.
+	if s.tokenHash() == "" {
+		return nil, rpcErrorf(codePermissionDenied, "RunCheck requires grpc_admin_token_hash to be set")
+	}
+	if s.OnRunCheck == nil {
+		return nil, rpcErrorf(codeUnimplemented, "RunCheck is not supported")
+	}
+	fields, err := protowire.DecodeFields(req)
+	if err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "%v", err)
+	}
+	var rc RunCheckRequest
+	for _, f := range fields {
+		switch f.Number {
+		case 1:
+			rc.HostName = string(f.Data)
+		case 2:
+			rc.ServiceDescription = string(f.Data)
+		case 3:
+			rc.CommandLine = string(f.Data)
+		case 4:
+			rc.Timeout = time.Duration(f.Num) * time.Second
+		}
+	}
+	if rc.HostName == "" {
+		return nil, rpcErrorf(codeInvalidArgument, "host_name is required")
+	}
+	if s.logger != nil {
+		s.logger.Log("RUN CHECK: %s;%s (via gRPC admin API)", rc.HostName, rc.ServiceDescription)
+	}
+	res, err := s.OnRunCheck(rc)
+	if errors.Is(err, ErrNotFound) {
+		return nil, rpcErrorf(codeNotFound, "%v", err)
+	}
+	if err != nil {
+		return nil, rpcErrorf(codeInvalidArgument, "%v", err)
+	}
+	e := &protowire.Encoder{}
+	e.String(1, res.CommandLine)
+	e.Int32(2, res.ReturnCode)
+	e.String(3, res.Output)
+	e.String(4, res.LongOutput)
+	e.String(5, res.PerfData)
+	e.Int64(6, res.ExecutionTime.Milliseconds())
+	e.Bool(7, res.TimedOut)
+	return e.Bytes(), nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func encodeHost(h *objects.Host) []byte {
+	e := &protowire.Encoder{}
+	e.String(1, h.Name)
+	e.String(2, h.Alias)
+	e.String(3, h.Address)
+	e.Int32(4, h.CurrentState)
+	e.Int32(5, h.StateType)
+	e.Int32(6, h.CurrentAttempt)
+	e.Int32(7, h.MaxCheckAttempts)
+	e.String(8, h.PluginOutput)
+	e.String(9, h.PerfData)
+	e.Int64(10, unixOrZero(h.LastCheck))
+	e.Int64(11, unixOrZero(h.NextCheck))
+	e.Int64(12, unixOrZero(h.LastStateChange))
+	e.Bool(13, h.HasBeenChecked)
+	e.Bool(14, h.ProblemAcknowledged)
+	e.Int32(15, h.ScheduledDowntimeDepth)
+	e.Bool(16, h.ActiveChecksEnabled)
+	e.Bool(17, h.NotificationsEnabled)
+	groups := make([]string, len(h.HostGroups))
+	for i, hg := range h.HostGroups {
+		groups[i] = hg.Name
+	}
+	e.RepeatedString(18, groups)
+	return e.Bytes()
+}
+
+func encodeService(svc *objects.Service) []byte {
+	e := &protowire.Encoder{}
+	e.String(1, svc.Host.Name)
+	e.String(2, svc.Description)
+	e.Int32(3, svc.CurrentState)
+	e.Int32(4, svc.StateType)
+	e.Int32(5, svc.CurrentAttempt)
+	e.Int32(6, svc.MaxCheckAttempts)
+	e.String(7, svc.PluginOutput)
+	e.String(8, svc.PerfData)
+	e.Int64(9, unixOrZero(svc.LastCheck))
+	e.Int64(10, unixOrZero(svc.NextCheck))
+	e.Int64(11, unixOrZero(svc.LastStateChange))
+	e.Bool(12, svc.HasBeenChecked)
+	e.Bool(13, svc.ProblemAcknowledged)
+	e.Int32(14, svc.ScheduledDowntimeDepth)
+	e.Bool(15, svc.ActiveChecksEnabled)
+	e.Bool(16, svc.NotificationsEnabled)
+	return e.Bytes()
+}
@@ -0,0 +1,200 @@
+package grpcadmin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/objects"
+	"github.com/oceanplexian/gogios/internal/protowire"
+)
+
+func testServer(t *testing.T, tokenHash string) (*Server, *httptest.Server, *[]string) {
+	t.Helper()
+	store := objects.NewObjectStore()
+	h := &objects.Host{Name: "web01", Address: "10.0.0.1", CurrentState: objects.HostDown, HasBeenChecked: true}
+	store.AddHost(h)
+	store.AddService(&objects.Service{Host: h, Description: "HTTP", CurrentState: objects.ServiceCritical, PluginOutput: "down"})
+	var submitted []string
+	s := New(Config{TokenHash: tokenHash}, &api.StateProvider{Store: store, Global: &objects.GlobalState{PID: 42}},
+		func(name string, args []string) { submitted = append(submitted, name) }, nil)
+
+	ts := httptest.NewUnstartedServer(s.Handler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return s, ts, &submitted
+}
+
+// call performs a unary gRPC call and returns the response message, the
+// grpc-status trailer and grpc-message.
+func call(t *testing.T, ts *httptest.Server, method string, req []byte, token string) ([]byte, string, string) {
+	t.Helper()
+	frame := make([]byte, 5, 5+len(req))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(req)))
+	frame = append(frame, req...)
+	hreq, _ := http.NewRequest("POST", ts.URL+"/gogios.admin.v1.Admin/"+method, bytes.NewReader(frame))
+	hreq.Header.Set("Content-Type", "application/grpc")
+	if token != "" {
+		hreq.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(hreq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected HTTP/2, got %s", resp.Proto)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	var msg []byte
+	if len(body) >= 5 {
+		msg = body[5 : 5+binary.BigEndian.Uint32(body[1:5])]
+	}
+	return msg, resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+}
+
+func TestGetHostAndService(t *testing.T) {
+	_, ts, _ := testServer(t, "")
+
+	e := &protowire.Encoder{}
+	e.String(1, "web01")
+	msg, status, _ := call(t, ts, "GetHost", e.Bytes(), "")
+	if status != "0" {
+		t.Fatalf("GetHost status %q", status)
+	}
+	fields, err := protowire.DecodeFields(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[int]protowire.Field{}
+	for _, f := range fields {
+		got[f.Number] = f
+	}
+	if string(got[1].Data) != "web01" || string(got[3].Data) != "10.0.0.1" || got[4].Num != uint64(objects.HostDown) {
+		t.Errorf("unexpected host fields: %+v", got)
+	}
+
+	e = &protowire.Encoder{}
+	e.String(1, "nope")
+	if _, status, _ = call(t, ts, "GetHost", e.Bytes(), ""); status != "5" {
+		t.Errorf("unknown host: want NOT_FOUND (5), got %q", status)
+	}
+
+	msg, status, _ = call(t, ts, "ListServices", nil, "")
+	if status != "0" {
+		t.Fatalf("ListServices status %q", status)
+	}
+	fields, _ = protowire.DecodeFields(msg)
+	if len(fields) != 1 || fields[0].Number != 1 {
+		t.Fatalf("expected one repeated service, got %+v", fields)
+	}
+	svc, _ := protowire.DecodeFields(fields[0].Data)
+	if string(svc[1].Data) != "HTTP" {
+		t.Errorf("unexpected service description %q", svc[1].Data)
+	}
+
+	if _, status, _ = call(t, ts, "Nope", nil, ""); status != "12" {
+		t.Errorf("unknown method: want UNIMPLEMENTED (12), got %q", status)
+	}
+}
+
+func TestSubmitCommandRequiresToken(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	_, ts, submitted := testServer(t, string(hash))
+
+	e := &protowire.Encoder{}
+	e.String(1, "disable_notifications")
+	if _, status, _ := call(t, ts, "SubmitCommand", e.Bytes(), ""); status != "16" {
+		t.Errorf("no token: want UNAUTHENTICATED (16), got %q", status)
+	}
+	if _, status, _ := call(t, ts, "SubmitCommand", e.Bytes(), "wrong"); status != "16" {
+		t.Errorf("bad token: want UNAUTHENTICATED (16), got %q", status)
+	}
+	if _, status, msg := call(t, ts, "SubmitCommand", e.Bytes(), "s3cret"); status != "0" {
+		t.Fatalf("good token: status %q %s", status, msg)
+	}
+	if len(*submitted) != 1 || (*submitted)[0] != "DISABLE_NOTIFICATIONS" {
+		t.Errorf("command not dispatched: %v", *submitted)
+	}
+}
+
+func TestAuthenticateForgetsTokenAfterRotation(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	s, ts, _ := testServer(t, string(hash))
+
+	if _, status, _ := call(t, ts, "GetProgramStatus", nil, "wrong"); status != "16" {
+		t.Fatalf("bad token: want UNAUTHENTICATED (16), got %q", status)
+	}
+	if s.accepted != nil {
+		t.Fatal("a rejected token was cached")
+	}
+	if _, status, msg := call(t, ts, "GetProgramStatus", nil, "s3cret"); status != "0" {
+		t.Fatalf("good token: status %q %s", status, msg)
+	}
+	if s.accepted == nil {
+		t.Fatal("accepted token not cached")
+	}
+
+	rotated, _ := bcrypt.GenerateFromPassword([]byte("n3w"), bcrypt.MinCost)
+	s.SetTokenHash(string(rotated))
+	if _, status, _ := call(t, ts, "GetProgramStatus", nil, "s3cret"); status != "16" {
+		t.Errorf("old token after rotation: want UNAUTHENTICATED (16), got %q", status)
+	}
+	if _, status, msg := call(t, ts, "GetProgramStatus", nil, "n3w"); status != "0" {
+		t.Errorf("new token: status %q %s", status, msg)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	s, ts, _ := testServer(t, "")
+	e := &protowire.Encoder{}
+	e.String(1, "web01")
+	e.String(2, "HTTP")
+	e.String(3, "$USER1$/check_http -H $HOSTADDRESS$ -v")
+	e.Int32(4, 5)
+	if _, status, _ := call(t, ts, "RunCheck", e.Bytes(), ""); status != "7" {
+		t.Errorf("without a token hash: want PERMISSION_DENIED (7), got %q", status)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	s, ts, _ = testServer(t, string(hash))
+	var got RunCheckRequest
+	s.OnRunCheck = func(req RunCheckRequest) (*RunCheckResult, error) {
+		if req.ServiceDescription == "nope" {
+			return nil, fmt.Errorf("service 'nope' on host 'web01' %w", ErrNotFound)
+		}
+		got = req
+		return &RunCheckResult{CommandLine: "check_http -H 10.0.0.1 -v", ReturnCode: 2, Output: "CRITICAL", PerfData: "time=5s", TimedOut: true}, nil
+	}
+	msg, status, errMsg := call(t, ts, "RunCheck", e.Bytes(), "s3cret")
+	if status != "0" {
+		t.Fatalf("RunCheck status %q %s", status, errMsg)
+	}
+	if got.HostName != "web01" || got.ServiceDescription != "HTTP" || got.Timeout != 5*time.Second {
+		t.Errorf("unexpected request %+v", got)
+	}
+	fields, _ := protowire.DecodeFields(msg)
+	res := map[int]protowire.Field{}
+	for _, f := range fields {
+		res[f.Number] = f
+	}
+	if string(res[1].Data) != "check_http -H 10.0.0.1 -v" || res[2].Num != 2 || string(res[5].Data) != "time=5s" || res[7].Num != 1 {
+		t.Errorf("unexpected response fields: %+v", res)
+	}
+
+	e = &protowire.Encoder{}
+	e.String(1, "web01")
+	e.String(2, "nope")
+	if _, status, _ := call(t, ts, "RunCheck", e.Bytes(), "s3cret"); status != "5" {
+		t.Errorf("unknown service: want NOT_FOUND (5), got %q", status)
+	}
+}
@@ -0,0 +1,63 @@
+package livestatus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// BenchmarkExecuteQuery measures LQL query latency against stores of several
+// sizes (10 services per host), excluding socket I/O:
+//
+//	go test ./internal/api/livestatus -run '^$' -bench ExecuteQuery -benchmem
+func BenchmarkExecuteQuery(b *testing.B) {
+	queries := []struct {
+		name string
+		lql  string
+	}{
+		{"services_columns", "GET services\nColumns: host_name description state plugin_output\n"},
+		{"services_filter", "GET services\nColumns: host_name description state\nFilter: state = 2\n"},
+		{"services_stats", "GET services\nStats: state = 0\nStats: state = 1\nStats: state = 2\nStats: state = 3\n"},
+		{"hosts_json", "GET hosts\nColumns: name state num_services\nOutputFormat: json\n"},
+	}
+	for _, n := range []int{1000, 10000, 100000} {
+		provider := benchProvider(n)
+		for _, bq := range queries {
+			q, err := ParseQuery(bq.lql)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("services=%d/%s", n, bq.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					ExecuteQuery(q, provider)
+				}
+			})
+		}
+	}
+}
+
+func benchProvider(services int) *api.StateProvider {
+	store := objects.NewObjectStore()
+	now := time.Now()
+	for i := 0; i < services/10; i++ {
+		h := &objects.Host{Name: fmt.Sprintf("host%05d", i), HasBeenChecked: true, LastCheck: now}
+		store.AddHost(h)
+		for j := 0; j < 10; j++ {
+			svc := &objects.Service{
+				Host:           h,
+				Description:    fmt.Sprintf("svc%d", j),
+				CurrentState:   (i + j) % 4,
+				HasBeenChecked: true,
+				PluginOutput:   "OK - synthetic",
+				LastCheck:      now,
+			}
+			store.AddService(svc)
+			h.Services = append(h.Services, svc)
+		}
+	}
+	return &api.StateProvider{Store: store, Global: &objects.GlobalState{ProgramStart: now}}
+}
@@ -0,0 +1,73 @@
+package livestatus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/listenaddr"
+)
+
+// Client runs queries against a Livestatus server. Gogios's own tools use
+// it to read live state, so they keep working with status_file=none.
+type Client struct {
+	Network string // "unix" or "tcp"
+	Addr    string
+	Timeout time.Duration
+}
+
+// NewClient returns a client for the query socket, or for the first TCP
+// address when no socket is configured. It fails when neither is set.
+func NewClient(socketPath, tcpAddr string) (*Client, error) {
+	if socketPath != "" {
+		return &Client{Network: "unix", Addr: socketPath, Timeout: 10 * time.Second}, nil
+	}
+	if addrs := listenaddr.Split(tcpAddr); len(addrs) > 0 {
+		return &Client{Network: "tcp", Addr: addrs[0], Timeout: 10 * time.Second}, nil
+	}
+	return nil, fmt.Errorf("neither query_socket nor livestatus_tcp is configured")
+}
+
+// Query sends one GET request and returns its rows. The request is given
+// without OutputFormat or ResponseHeader, which the client sets itself.
+func (c *Client) Query(request string) ([][]interface{}, error) {
+	conn, err := net.DialTimeout(c.Network, c.Addr, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.Timeout))
+
+	request = strings.TrimRight(request, "\n") + "\nOutputFormat: json\nResponseHeader: fixed16\n\n"
+	if _, err := io.WriteString(conn, request); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading response header: %w", err)
+	}
+	code, err1 := strconv.Atoi(strings.TrimSpace(string(header[:3])))
+	size, err2 := strconv.Atoi(strings.TrimSpace(string(header[4:15])))
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("invalid response header %q", header)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if code != 200 {
+		return nil, fmt.Errorf("livestatus error %d: %s", code, strings.TrimSpace(string(body)))
+	}
+	var rows [][]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return rows, nil
+}
@@ -1,8 +1,6 @@
 package livestatus
 
 import (
-	"strings"
-
 	"github.com/oceanplexian/gogios/internal/api"
 )
 
@@ -12,25 +10,7 @@ func handleCommand(request string, sink api.CommandSink) {
 	if sink == nil {
 		return
 	}
-	// Strip "COMMAND " prefix
-	line := strings.TrimPrefix(request, "COMMAND ")
-	line = strings.TrimSpace(line)
-
-	// Skip optional timestamp in brackets
-	if strings.HasPrefix(line, "[") {
-		idx := strings.Index(line, "]")
-		if idx >= 0 {
-			line = strings.TrimSpace(line[idx+1:])
-		}
+	if entry := parseCommandEntry(request); entry != nil {
+		sink(entry.Name, entry.Args)
 	}
-
-	// Split command name from arguments
-	parts := strings.SplitN(line, ";", 2)
-	name := parts[0]
-	var args []string
-	if len(parts) > 1 {
-		args = strings.Split(parts[1], ";")
-	}
-
-	sink(name, args)
 }
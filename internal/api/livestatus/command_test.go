@@ -2,6 +2,7 @@ package livestatus
 
 import (
 	"net"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -201,6 +202,9 @@ func TestParseCommandEntry(t *testing.T) {
 		{"COMMAND ENABLE_NOTIFICATIONS", "ENABLE_NOTIFICATIONS", nil},
 		{"COMMAND [123] ENABLE_SVC_NOTIFICATIONS;host1;svc1", "ENABLE_SVC_NOTIFICATIONS", []string{"host1", "svc1"}},
 		{"COMMAND DO_THING;a;b;c", "DO_THING", []string{"a", "b", "c"}},
+		// The comment of a known command keeps its semicolons.
+		{"COMMAND [123] ACKNOWLEDGE_HOST_PROBLEM;web01;2;1;1;admin;see ticket;urgent", "ACKNOWLEDGE_HOST_PROBLEM",
+			[]string{"web01", "2", "1", "1", "admin", "see ticket;urgent"}},
 	}
 	for _, tt := range tests {
 		entry := parseCommandEntry(tt.input)
@@ -211,8 +215,8 @@ func TestParseCommandEntry(t *testing.T) {
 		if entry.Name != tt.wantName {
 			t.Errorf("parseCommandEntry(%q).Name = %q, want %q", tt.input, entry.Name, tt.wantName)
 		}
-		if len(entry.Args) != len(tt.wantArgs) {
-			t.Errorf("parseCommandEntry(%q).Args = %v, want %v", tt.input, entry.Args, tt.wantArgs)
+		if !slices.Equal(entry.Args, tt.wantArgs) {
+			t.Errorf("parseCommandEntry(%q).Args = %q, want %q", tt.input, entry.Args, tt.wantArgs)
 		}
 	}
 }
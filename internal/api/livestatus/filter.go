@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/objects"
 )
 
 // compareCtx carries pre-compiled state for filter evaluation to avoid
@@ -103,7 +104,9 @@ func compareValue(colVal interface{}, op, filterVal string, ctx ...*compareCtx)
 		}
 		return compareInt(iv, op, fv)
 	case []string:
-		return compareList(v, op, filterVal)
+		return compareList(v, op, filterVal, cc)
+	case tagList:
+		return compareTags(v, op, filterVal, cc)
 	case time.Time:
 		// Convert to Unix epoch for numeric comparison (Thruk filters on timestamps)
 		unix := int64(0)
@@ -223,7 +226,7 @@ func compareString(a, op, b string, ctx ...*compareCtx) bool {
 	}
 }
 
-func compareList(list []string, op, val string) bool {
+func compareList(list []string, op, val string, ctx ...*compareCtx) bool {
 	switch op {
 	case ">=":
 		// list contains val
@@ -251,7 +254,42 @@ func compareList(list []string, op, val string) bool {
 			return len(list) > 0
 		}
 		return true
+	case "~", "~~":
+		// some element matches
+		for _, s := range list {
+			if compareString(s, op, val, ctx...) {
+				return true
+			}
+		}
+		return false
+	case "!~", "!~~":
+		for _, s := range list {
+			if !compareString(s, op, val, ctx...) {
+				return false
+			}
+		}
+		return true
 	default:
 		return false
 	}
 }
+
+// tagList is the value of the tags columns (Gogios extension). Its >= and
+// !>= filters take a tag selector rather than a single element: a bare
+// name matches name=value labels too, and comma-separated terms must all
+// match, so "Filter: tags >= env=prod,team" selects the objects labelled
+// env=prod that have a team tag.
+type tagList []string
+
+func compareTags(tags tagList, op, val string, cc *compareCtx) bool {
+	switch op {
+	case ">=", "!>=":
+		sel, err := objects.ParseTagSelector(val)
+		if err != nil {
+			return op == "!>="
+		}
+		return sel.Match(tags) == (op == ">=")
+	default:
+		return compareList(tags, op, val, cc)
+	}
+}
@@ -272,3 +272,39 @@ func TestCompareList_UnknownOp(t *testing.T) {
 		t.Error("unknown op should return false")
 	}
 }
+
+func TestCompareList_Regex(t *testing.T) {
+	list := []string{"web01", "db01"}
+	if !compareList(list, "~", "^db") {
+		t.Error("list [web01,db01] ~ ^db should be true")
+	}
+	if compareList(list, "!~", "^db") {
+		t.Error("list [web01,db01] !~ ^db should be false")
+	}
+	if !compareList(list, "~~", "WEB") {
+		t.Error("list [web01,db01] ~~ WEB should be true")
+	}
+}
+
+func TestCompareValue_Tags(t *testing.T) {
+	tags := tagList{"env=prod", "pci", "team=web"}
+	tests := []struct {
+		op, val string
+		want    bool
+	}{
+		{">=", "env=prod", true},
+		{">=", "env", true},
+		{">=", "env=dev", false},
+		{">=", "env=prod,team=web,pci", true},
+		{">=", "env=prod,team=db", false},
+		{"!>=", "team=db", true},
+		{"!>=", "pci", false},
+		{"~", "^team=", true},
+		{"=", "", false},
+	}
+	for _, tt := range tests {
+		if got := compareValue(tags, tt.op, tt.val); got != tt.want {
+			t.Errorf("tags %s %q = %v, want %v", tt.op, tt.val, got, tt.want)
+		}
+	}
+}
@@ -23,11 +23,12 @@ func ExecuteQuery(q *Query, provider *api.StateProvider) string {
 	}
 
 	// For the log table, extract time bounds from filters so we can skip
-	// archive files that fall outside the requested range.
+	// archive files that fall outside the requested range. The bounds go
+	// on a copy, as queries run concurrently.
 	if q.Table == "log" {
-		min, max := extractTimeBounds(q.Filters)
-		provider.LogTimeMin = min
-		provider.LogTimeMax = max
+		lp := *provider
+		lp.LogTimeMin, lp.LogTimeMax = extractTimeBounds(q.Filters)
+		provider = &lp
 	}
 
 	// Snapshot the row pointers under a brief read lock, then release.
@@ -89,9 +90,11 @@ func ExecuteQuery(q *Query, provider *api.StateProvider) string {
 	// Determine columns to output
 	cols := q.Columns
 	if len(cols) == 0 {
-		// Default: all columns
-		for name := range table.Columns {
-			cols = append(cols, name)
+		// Default: all columns in documented order, with a header row
+		// unless the client turned headers off, as in MK Livestatus.
+		cols = table.ColumnNames()
+		if !q.columnHeadersSet {
+			q.ColumnHeaders = true
 		}
 	}
 
@@ -249,6 +252,8 @@ func formatValue(v interface{}) string {
 		return fmt.Sprintf("%d", val.Unix())
 	case []string:
 		return strings.Join(val, ",")
+	case tagList:
+		return strings.Join(val, ",")
 	default:
 		return fmt.Sprintf("%v", v)
 	}
@@ -266,6 +271,11 @@ func jsonSafe(v interface{}) interface{} {
 			return []string{}
 		}
 		return val
+	case tagList:
+		if val == nil {
+			return []string{}
+		}
+		return []string(val)
 	default:
 		return v
 	}
@@ -276,7 +286,7 @@ func jsonSafe(v interface{}) interface{} {
 // to skip log files that cannot contain matching entries.
 func extractTimeBounds(filters []*FilterExpr) (min, max time.Time) {
 	for _, f := range filters {
-		if f.Column != "time" || len(f.SubFilters) > 0 {
+		if f.Column != "time" || len(f.SubFilters) > 0 || f.IsNegate {
 			continue
 		}
 		v, err := strconv.ParseInt(f.Value, 10, 64)
@@ -293,6 +303,13 @@ func extractTimeBounds(filters []*FilterExpr) (min, max time.Time) {
 			if max.IsZero() || t.Before(max) {
 				max = t
 			}
+		case "=":
+			if min.IsZero() || t.After(min) {
+				min = t
+			}
+			if max.IsZero() || t.Before(max) {
+				max = t
+			}
 		}
 	}
 	return
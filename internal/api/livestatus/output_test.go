@@ -2,9 +2,16 @@ package livestatus
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/logging"
+	"github.com/oceanplexian/gogios/internal/objects"
 )
 
 func TestFormatValue_String(t *testing.T) {
@@ -160,3 +167,143 @@ func TestFormatWrappedJSON(t *testing.T) {
 		t.Error("missing 'total_count' key")
 	}
 }
+
+func TestExecuteQuery_DefaultColumns(t *testing.T) {
+	provider := benchProvider(10)
+	provider.Comments = downtime.NewCommentManager(1)
+	provider.Downtimes = downtime.NewDowntimeManager(1, provider.Comments, provider.Store)
+	q, err := ParseQuery("GET hosts\nFilter: name = host00000\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(ExecuteQuery(q, provider), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header and one row, got %d lines", len(lines))
+	}
+	want := strings.Join(Registry["hosts"].ColumnNames(), ";")
+	if lines[0] != want {
+		t.Errorf("header not in documented order:\n%s\nwant\n%s", lines[0], want)
+	}
+	if n := len(strings.Split(lines[1], ";")); n != len(Registry["hosts"].Columns) {
+		t.Errorf("expected %d values, got %d", len(Registry["hosts"].Columns), n)
+	}
+
+	q, _ = ParseQuery("GET hosts\nColumnHeaders: off\n")
+	if out := ExecuteQuery(q, provider); strings.Count(out, "\n") != 1 {
+		t.Errorf("expected no header with ColumnHeaders: off, got %q", out)
+	}
+}
+
+func TestExecuteQuery_LogTable(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "nagios.log")
+	lines := "[1000] SERVICE ALERT: web01;HTTP;CRITICAL;SOFT;1;connection refused\n" +
+		"[1060] SERVICE ALERT: web01;HTTP;CRITICAL;HARD;3;connection refused\n" +
+		"[1060] SERVICE NOTIFICATION: admin;web01;HTTP;CRITICAL;notify-by-email;connection refused\n" +
+		"[1200] HOST ALERT: web02;DOWN;HARD;2;PING CRITICAL\n"
+	if err := os.WriteFile(logFile, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider := benchProvider(0)
+	provider.LogFile = logFile
+	provider.LogIndex = logging.NewLogIndex(logFile, "")
+
+	q, err := ParseQuery("GET log\nColumns: lineno time class type host_name service_description state state_type attempt\n" +
+		"Filter: time >= 1050\nFilter: class = 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2;1060;1;SERVICE ALERT;web01;HTTP;2;HARD;3\n" +
+		"4;1200;1;HOST ALERT;web02;;1;HARD;2\n"
+	if got := ExecuteQuery(q, provider); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	q, _ = ParseQuery("GET log\nColumns: contact_name command_name\nFilter: class = 3\n")
+	if got := ExecuteQuery(q, provider); got != "admin;notify-by-email\n" {
+		t.Errorf("notification columns: got %q", got)
+	}
+
+	// Limit keeps the most recent entries.
+	q, _ = ParseQuery("GET log\nColumns: time\nLimit: 1\n")
+	if got := ExecuteQuery(q, provider); got != "1200\n" {
+		t.Errorf("Limit: got %q", got)
+	}
+}
+
+func TestExecuteQuery_CheckHistory(t *testing.T) {
+	provider := benchProvider(10)
+	svc := provider.Store.Services[0]
+	for i, state := range []int{objects.ServiceOK, objects.ServiceCritical, objects.ServiceCritical} {
+		objects.RecordCheck(&svc.CheckHistory, 2, objects.CheckRecord{
+			Time:      time.Unix(int64(1000+60*i), 0),
+			State:     state,
+			StateType: objects.StateTypeSoft,
+			Duration:  0.25,
+			Output:    "attempt " + strconv.Itoa(i),
+		})
+	}
+	q, err := ParseQuery("GET services\nColumns: check_history_states check_history\n" +
+		"Filter: host_name = host00000\nFilter: description = svc0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2,2;1060|2|0|0.250|attempt 1,1120|2|0|0.250|attempt 2\n"
+	if got := ExecuteQuery(q, provider); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	q, _ = ParseQuery("GET hosts\nColumns: check_history_states\nFilter: name = host00000\n")
+	if got := ExecuteQuery(q, provider); got != "\n" {
+		t.Errorf("host without results: got %q", got)
+	}
+}
+
+func TestExecuteQuery_ColumnsTable(t *testing.T) {
+	q, err := ParseQuery("GET columns\nColumns: table name type description\nFilter: table = columns\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ExecuteQuery(q, benchProvider(0))
+	want := "columns;description;string;A description of the column\n" +
+		"columns;name;string;The name of the column within the table\n" +
+		"columns;table;string;The name of the table\n" +
+		"columns;type;string;The data type of the column (int, float, string, time, list)\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	q, _ = ParseQuery("GET columns\nColumns: description\nFilter: table = hosts\nFilter: name = last_state_change\n")
+	if got := ExecuteQuery(q, benchProvider(0)); got != "Last state change\n" {
+		t.Errorf("expected a description derived from the name, got %q", got)
+	}
+}
+
+func TestExecuteQuery_DependencyTables(t *testing.T) {
+	provider := benchProvider(20)
+	hosts := provider.Store.Hosts
+	provider.Store.AddHostDependency(&objects.HostDependency{
+		Host:                       hosts[0],
+		DependentHost:              hosts[1],
+		InheritsParent:             true,
+		NotificationFailureOptions: objects.OptDown | objects.OptUnreachable,
+	})
+	master, dep := provider.Store.Services[0], provider.Store.Services[11]
+	provider.Store.AddServiceDependency(&objects.ServiceDependency{
+		Host:                    master.Host,
+		Service:                 master,
+		DependentHost:           dep.Host,
+		DependentService:        dep,
+		ExecutionFailureOptions: objects.OptCritical | objects.OptUnknown,
+	})
+
+	q, _ := ParseQuery("GET hostdependencies\nColumns: host_name dependent_host_name inherits_parent notification_failure_options execution_failure_options\n")
+	if got, want := ExecuteQuery(q, provider), "host00000;host00001;1;d,u;\n"; got != want {
+		t.Errorf("hostdependencies: got %q, want %q", got, want)
+	}
+	q, _ = ParseQuery("GET servicedependencies\nColumns: host_name service_description dependent_host_name dependent_service_description execution_failure_options\n")
+	want := master.Host.Name + ";" + master.Description + ";" + dep.Host.Name + ";" + dep.Description + ";u,c\n"
+	if got := ExecuteQuery(q, provider); got != want {
+		t.Errorf("servicedependencies: got %q, want %q", got, want)
+	}
+}
@@ -21,6 +21,8 @@ type Query struct {
 	KeepAlive      bool
 	ColumnHeaders  bool
 	AuthUser       string
+
+	columnHeadersSet bool // ColumnHeaders was given explicitly
 }
 
 // SortSpec describes a single sort directive.
@@ -91,6 +93,7 @@ func ParseQuery(request string) (*Query, error) {
 
 		case "ColumnHeaders":
 			q.ColumnHeaders = value == "on"
+			q.columnHeadersSet = true
 
 		case "Filter":
 			f, err := parseFilterExpr(value)
@@ -8,15 +8,21 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/extcmd"
+	"github.com/oceanplexian/gogios/internal/fileperm"
+	"github.com/oceanplexian/gogios/internal/listenaddr"
 	"github.com/oceanplexian/gogios/internal/logging"
 )
 
 // Server is the Livestatus query server. It listens on a Unix domain socket
-// and/or a TCP address and handles LQL queries.
+// and/or one or more TCP addresses and handles LQL queries.
 type Server struct {
 	socketPath    string
+	socketMode    os.FileMode
+	socketGroup   string
 	tcpAddr       string
 	provider      *api.StateProvider
 	cmdSink       api.CommandSink
@@ -26,10 +32,12 @@ type Server struct {
 	quit          chan struct{}
 }
 
-// New creates a new Livestatus server.
+// New creates a new Livestatus server. tcpAddr may list several
+// comma-separated addresses, as livestatus_tcp does.
 func New(socketPath, tcpAddr string) *Server {
 	return &Server{
 		socketPath: socketPath,
+		socketMode: 0660,
 		tcpAddr:    tcpAddr,
 		quit:       make(chan struct{}),
 	}
@@ -42,6 +50,13 @@ func (s *Server) SetBatchCommandSink(sink api.BatchCommandSink) {
 	s.batchCmdSink = sink
 }
 
+// SetSocketPermissions sets the mode and group (name or GID, empty to leave
+// unchanged) applied to the Unix socket when Start creates it.
+func (s *Server) SetSocketPermissions(mode os.FileMode, group string) {
+	s.socketMode = mode
+	s.socketGroup = group
+}
+
 // Start begins listening for connections.
 func (s *Server) Start(provider *api.StateProvider, cmdSink api.CommandSink) error {
 	s.provider = provider
@@ -54,20 +69,25 @@ func (s *Server) Start(provider *api.StateProvider, cmdSink api.CommandSink) err
 		if err != nil {
 			return fmt.Errorf("unix listen %s: %w", s.socketPath, err)
 		}
-		os.Chmod(s.socketPath, 0660)
+		if err := fileperm.Apply(s.socketPath, s.socketMode, s.socketGroup); err != nil {
+			ln.Close()
+			return fmt.Errorf("query socket permissions %s: %w", s.socketPath, err)
+		}
 		s.listeners = append(s.listeners, ln)
 		s.wg.Add(1)
 		go s.acceptLoop(ln)
 	}
 
 	if s.tcpAddr != "" {
-		ln, err := net.Listen("tcp", s.tcpAddr)
+		lns, err := listenaddr.Listen(s.tcpAddr)
 		if err != nil {
-			return fmt.Errorf("tcp listen %s: %w", s.tcpAddr, err)
+			return err
+		}
+		for _, ln := range lns {
+			s.listeners = append(s.listeners, ln)
+			s.wg.Add(1)
+			go s.acceptLoop(ln)
 		}
-		s.listeners = append(s.listeners, ln)
-		s.wg.Add(1)
-		go s.acceptLoop(ln)
 	}
 
 	return nil
@@ -85,6 +105,35 @@ func (s *Server) Stop() {
 	}
 }
 
+// Ping runs a minimal status query against every listener and returns the
+// slowest round trip. It exercises the accept loop and the store read lock,
+// so a wedged listener or a stuck writer shows up as a timeout.
+func (s *Server) Ping(timeout time.Duration) (time.Duration, error) {
+	if len(s.listeners) == 0 {
+		return 0, fmt.Errorf("not listening")
+	}
+	var slowest time.Duration
+	for _, ln := range s.listeners {
+		addr := ln.Addr()
+		start := time.Now()
+		conn, err := net.DialTimeout(addr.Network(), addr.String(), timeout)
+		if err != nil {
+			return 0, err
+		}
+		conn.SetDeadline(start.Add(timeout))
+		_, err = conn.Write([]byte("GET status\nColumns: program_start\n\n"))
+		if err == nil {
+			_, err = io.ReadAll(conn)
+		}
+		conn.Close()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", addr, err)
+		}
+		slowest = max(slowest, time.Since(start))
+	}
+	return slowest, nil
+}
+
 func (s *Server) acceptLoop(ln net.Listener) {
 	defer s.wg.Done()
 	for {
@@ -127,7 +176,8 @@ func (s *Server) handleConnection(conn net.Conn) {
 			return
 		}
 
-		// Handle COMMAND before parsing as query — COMMANDs have no headers
+		// Handle COMMAND before parsing as query — the only header a
+		// COMMAND takes is AuthUser.
 		firstLine := strings.SplitN(strings.TrimSpace(request), "\n", 2)[0]
 		if strings.HasPrefix(firstLine, "COMMAND ") {
 			if s.provider.Logger != nil {
@@ -135,7 +185,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 			}
 			// Queue the command for batch dispatch instead of executing immediately.
 			entry := parseCommandEntry(firstLine)
-			if entry != nil {
+			if entry != nil && s.commandAllowed(entry, commandAuthUser(request), conn) {
 				pendingCmds = append(pendingCmds, *entry)
 			}
 			// Per spec: commands are fire-and-forget, no response.
@@ -190,7 +240,9 @@ func (s *Server) flushCommands(cmds []api.CommandEntry, conn net.Conn) {
 }
 
 // parseCommandEntry extracts the command name and args from a COMMAND line
-// without invoking the sink. Returns nil for unparseable input.
+// without invoking the sink. Returns nil for unparseable input. Args are
+// split as the command table in extcmd says, so the comment of a known
+// command may contain semicolons.
 func parseCommandEntry(request string) *api.CommandEntry {
 	line := strings.TrimPrefix(request, "COMMAND ")
 	line = strings.TrimSpace(line)
@@ -210,11 +262,36 @@ func parseCommandEntry(request string) *api.CommandEntry {
 	}
 	var args []string
 	if len(parts) > 1 {
-		args = strings.Split(parts[1], ";")
+		args = extcmd.SplitArgs(name, parts[1])
 	}
 	return &api.CommandEntry{Name: name, Args: args}
 }
 
+// commandAuthUser returns the AuthUser header of a COMMAND request, empty
+// when there is none.
+func commandAuthUser(request string) string {
+	for _, line := range strings.Split(request, "\n")[1:] {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "AuthUser:"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// commandAllowed reports whether the command may run for authUser, logging
+// the refusal when the contact has can_submit_commands disabled or does not
+// exist.
+func (s *Server) commandAllowed(entry *api.CommandEntry, authUser string, conn net.Conn) bool {
+	if s.provider.Store == nil || s.provider.CanSubmitCommands(authUser) {
+		return true
+	}
+	if s.provider.Logger != nil {
+		s.provider.Logger.Log("Warning: Livestatus: Refusing external command %s from contact '%s' (%s): contact may not submit commands",
+			entry.Name, authUser, conn.RemoteAddr())
+	}
+	return false
+}
+
 func readRequest(reader *bufio.Reader) (string, error) {
 	var lines []string
 	for {
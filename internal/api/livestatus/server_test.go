@@ -0,0 +1,97 @@
+package livestatus
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestServerPing(t *testing.T) {
+	s := New("", "127.0.0.1:0")
+	if _, err := s.Ping(time.Second); err == nil {
+		t.Fatal("expected error before Start")
+	}
+	if err := s.Start(benchProvider(10), nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	rtt, err := s.Ping(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if rtt <= 0 {
+		t.Errorf("expected positive round trip, got %v", rtt)
+	}
+}
+
+func TestClientQuery(t *testing.T) {
+	if _, err := NewClient("", ""); err == nil {
+		t.Fatal("expected an error without a socket or TCP address")
+	}
+	s := New("", "127.0.0.1:0")
+	if err := s.Start(benchProvider(20), nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	c, err := NewClient("", s.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := c.Query("GET services\nStats: state >= 0\nStats: has_been_checked = 1")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0]) != 2 || rows[0][0] != float64(20) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+	if _, err := c.Query("GET nosuchtable"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestServerCommandAuthUser(t *testing.T) {
+	p := benchProvider(10)
+	p.Store.AddContact(&objects.Contact{Name: "alice", CanSubmitCommands: true})
+	p.Store.AddContact(&objects.Contact{Name: "bob"})
+
+	got := make(chan string, 8)
+	s := New("", "127.0.0.1:0")
+	if err := s.Start(p, func(name string, args []string) { got <- name }); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "COMMAND [1] ENABLE_NOTIFICATIONS\n\n"+
+		"COMMAND [1] DISABLE_NOTIFICATIONS\nAuthUser: bob\n\n"+
+		"COMMAND [1] DISABLE_FLAP_DETECTION\nAuthUser: nobody\n\n"+
+		"COMMAND [1] ENABLE_FLAP_DETECTION\nAuthUser: alice\n\n")
+	conn.Close()
+
+	var names []string
+	for len(names) < 2 {
+		select {
+		case n := <-got:
+			names = append(names, n)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out, got %v", names)
+		}
+	}
+	select {
+	case n := <-got:
+		names = append(names, n)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if strings.Join(names, ",") != "ENABLE_NOTIFICATIONS,ENABLE_FLAP_DETECTION" {
+		t.Errorf("dispatched %v, want only the unauthenticated and alice's commands", names)
+	}
+}
@@ -17,8 +17,10 @@ func columnsTable() *Table {
 		Name: "columns",
 		GetRows: func(p *api.StateProvider) []interface{} {
 			var rows []interface{}
-			for tableName, table := range Registry {
-				for _, col := range table.Columns {
+			for _, tableName := range TableNames() {
+				table := Registry[tableName]
+				for _, name := range table.ColumnNames() {
+					col := table.Columns[name]
 					rows = append(rows, &columnRow{
 						table:       tableName,
 						name:        col.Name,
@@ -30,10 +32,10 @@ func columnsTable() *Table {
 			return rows
 		},
 		Columns: map[string]*Column{
-			"table":       {Name: "table", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).table }},
-			"name":        {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).name }},
-			"description": {Name: "description", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).description }},
-			"type":        {Name: "type", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).colType }},
+			"table":       {Name: "table", Description: "The name of the table", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).table }},
+			"name":        {Name: "name", Description: "The name of the column within the table", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).name }},
+			"description": {Name: "description", Description: "A description of the column", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).description }},
+			"type":        {Name: "type", Description: "The data type of the column (int, float, string, time, list)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*columnRow).colType }},
 		},
 	}
 }
@@ -16,8 +16,9 @@ func commandsTable() *Table {
 			return rows
 		},
 		Columns: map[string]*Column{
-			"name": {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Command).Name }},
-			"line": {Name: "line", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Command).CommandLine }},
+			"name":          {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Command).Name }},
+			"config_source": {Name: "config_source", Description: "File and line of the object definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Command).ConfigSource }},
+			"line":          {Name: "line", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Command).CommandLine }},
 		},
 	}
 }
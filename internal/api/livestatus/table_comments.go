@@ -3,6 +3,7 @@ package livestatus
 import (
 	"github.com/oceanplexian/gogios/internal/api"
 	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/objects"
 )
 
 func commentsTable() *Table {
@@ -29,6 +30,34 @@ func commentsTable() *Table {
 			"persistent": {Name: "persistent", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*downtime.Comment).Persistent) }},
 			"expires": {Name: "expires", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*downtime.Comment).Expires) }},
 			"expire_time": {Name: "expire_time", Type: "time", Extract: func(r interface{}) interface{} { return r.(*downtime.Comment).ExpireTime }},
+			"is_service": {Name: "is_service", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*downtime.Comment).CommentType == objects.ServiceCommentType) }},
+
+			// Joined host/service columns used by Thruk's comment pages.
+			"host_alias": {Name: "host_alias", Type: "string", ProviderExtract: func(r interface{}, p *api.StateProvider) interface{} {
+				if h := p.Store.GetHost(r.(*downtime.Comment).HostName); h != nil {
+					return h.Alias
+				}
+				return ""
+			}},
+			"host_address": {Name: "host_address", Type: "string", ProviderExtract: func(r interface{}, p *api.StateProvider) interface{} {
+				if h := p.Store.GetHost(r.(*downtime.Comment).HostName); h != nil {
+					return h.Address
+				}
+				return ""
+			}},
+			"host_display_name": {Name: "host_display_name", Type: "string", ProviderExtract: func(r interface{}, p *api.StateProvider) interface{} {
+				if h := p.Store.GetHost(r.(*downtime.Comment).HostName); h != nil {
+					return h.DisplayName
+				}
+				return ""
+			}},
+			"service_display_name": {Name: "service_display_name", Type: "string", ProviderExtract: func(r interface{}, p *api.StateProvider) interface{} {
+				c := r.(*downtime.Comment)
+				if svc := p.Store.GetService(c.HostName, c.ServiceDescription); svc != nil {
+					return svc.DisplayName
+				}
+				return ""
+			}},
 		},
 	}
 }
@@ -16,8 +16,9 @@ func contactgroupsTable() *Table {
 			return rows
 		},
 		Columns: map[string]*Column{
-			"name":  {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ContactGroup).Name }},
-			"alias": {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ContactGroup).Alias }},
+			"name":          {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ContactGroup).Name }},
+			"config_source": {Name: "config_source", Description: "File and line of the object definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ContactGroup).ConfigSource }},
+			"alias":         {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ContactGroup).Alias }},
 			"members": {Name: "members", Type: "list", Extract: func(r interface{}) interface{} {
 				names := make([]string, 0)
 				for _, c := range r.(*objects.ContactGroup).Members {
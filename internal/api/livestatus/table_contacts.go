@@ -1,6 +1,8 @@
 package livestatus
 
 import (
+	"time"
+
 	"github.com/oceanplexian/gogios/internal/api"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
@@ -16,10 +18,11 @@ func contactsTable() *Table {
 			return rows
 		},
 		Columns: map[string]*Column{
-			"name":  {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Name }},
-			"alias": {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Alias }},
-			"email": {Name: "email", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Email }},
-			"pager": {Name: "pager", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Pager }},
+			"name":          {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Name }},
+			"config_source": {Name: "config_source", Description: "File and line of the object definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).ConfigSource }},
+			"alias":         {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Alias }},
+			"email":         {Name: "email", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Email }},
+			"pager":         {Name: "pager", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Contact).Pager }},
 			"host_notifications_enabled": {Name: "host_notifications_enabled", Type: "int", Extract: func(r interface{}) interface{} {
 				return boolToInt(r.(*objects.Contact).HostNotificationsEnabled)
 			}},
@@ -41,6 +44,18 @@ func contactsTable() *Table {
 				}
 				return ""
 			}},
+			"notifications_1h": {Name: "notifications_1h", Description: "Notifications sent to the contact in the last hour (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Contact).NotificationCounts.Since(time.Now(), time.Hour)
+			}},
+			"notifications_24h": {Name: "notifications_24h", Description: "Notifications sent to the contact in the last 24 hours (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Contact).NotificationCounts.Since(time.Now(), 24*time.Hour)
+			}},
+			"notifications_capped_1h": {Name: "notifications_capped_1h", Description: "Notifications held back by the hourly cap in the last hour (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Contact).CappedCounts.Since(time.Now(), time.Hour)
+			}},
+			"notifications_capped_24h": {Name: "notifications_capped_24h", Description: "Notifications held back by the hourly cap in the last 24 hours (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Contact).CappedCounts.Since(time.Now(), 24*time.Hour)
+			}},
 			"custom_variable_names": {Name: "custom_variable_names", Type: "list", Extract: func(r interface{}) interface{} {
 				var names []string
 				for k := range r.(*objects.Contact).CustomVars {
@@ -0,0 +1,119 @@
+package livestatus
+
+import (
+	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// hostFailureOptions lists host dependency failure options as the letters
+// used in the object configuration.
+func hostFailureOptions(opts uint32) []string {
+	return optionLetters(opts, []uint32{objects.OptOK, objects.OptDown, objects.OptUnreachable, objects.OptPending}, "odup")
+}
+
+// serviceFailureOptions lists service dependency failure options as the
+// letters used in the object configuration.
+func serviceFailureOptions(opts uint32) []string {
+	return optionLetters(opts, []uint32{objects.OptOK, objects.OptWarning, objects.OptUnknown, objects.OptCritical, objects.OptPending}, "owucp")
+}
+
+func optionLetters(opts uint32, bits []uint32, letters string) []string {
+	out := make([]string, 0, len(bits))
+	for i, bit := range bits {
+		if opts&bit != 0 {
+			out = append(out, letters[i:i+1])
+		}
+	}
+	return out
+}
+
+func periodName(tp *objects.Timeperiod) string {
+	if tp == nil {
+		return ""
+	}
+	return tp.Name
+}
+
+func hostdependenciesTable() *Table {
+	return &Table{
+		Name: "hostdependencies",
+		GetRows: func(p *api.StateProvider) []interface{} {
+			rows := make([]interface{}, len(p.Store.HostDependencies))
+			for i, d := range p.Store.HostDependencies {
+				rows[i] = d
+			}
+			return rows
+		},
+		Columns: map[string]*Column{
+			"host_name": {Name: "host_name", Type: "string", Description: "Name of the master host", Extract: func(r interface{}) interface{} {
+				return r.(*objects.HostDependency).Host.Name
+			}},
+			"dependent_host_name": {Name: "dependent_host_name", Type: "string", Description: "Name of the dependent host", Extract: func(r interface{}) interface{} {
+				return r.(*objects.HostDependency).DependentHost.Name
+			}},
+			"host_state": {Name: "host_state", Type: "int", Description: "Current state of the master host", Extract: func(r interface{}) interface{} {
+				return r.(*objects.HostDependency).Host.CurrentState
+			}},
+			"dependent_host_state": {Name: "dependent_host_state", Type: "int", Description: "Current state of the dependent host", Extract: func(r interface{}) interface{} {
+				return r.(*objects.HostDependency).DependentHost.CurrentState
+			}},
+			"dependency_period": {Name: "dependency_period", Type: "string", Extract: func(r interface{}) interface{} {
+				return periodName(r.(*objects.HostDependency).DependencyPeriod)
+			}},
+			"inherits_parent": {Name: "inherits_parent", Type: "int", Extract: func(r interface{}) interface{} {
+				return boolToInt(r.(*objects.HostDependency).InheritsParent)
+			}},
+			"execution_failure_options": {Name: "execution_failure_options", Type: "list", Extract: func(r interface{}) interface{} {
+				return hostFailureOptions(r.(*objects.HostDependency).ExecutionFailureOptions)
+			}},
+			"notification_failure_options": {Name: "notification_failure_options", Type: "list", Extract: func(r interface{}) interface{} {
+				return hostFailureOptions(r.(*objects.HostDependency).NotificationFailureOptions)
+			}},
+		},
+	}
+}
+
+func servicedependenciesTable() *Table {
+	return &Table{
+		Name: "servicedependencies",
+		GetRows: func(p *api.StateProvider) []interface{} {
+			rows := make([]interface{}, len(p.Store.ServiceDependencies))
+			for i, d := range p.Store.ServiceDependencies {
+				rows[i] = d
+			}
+			return rows
+		},
+		Columns: map[string]*Column{
+			"host_name": {Name: "host_name", Type: "string", Description: "Host of the master service", Extract: func(r interface{}) interface{} {
+				return r.(*objects.ServiceDependency).Host.Name
+			}},
+			"service_description": {Name: "service_description", Type: "string", Description: "Description of the master service", Extract: func(r interface{}) interface{} {
+				return r.(*objects.ServiceDependency).Service.Description
+			}},
+			"dependent_host_name": {Name: "dependent_host_name", Type: "string", Description: "Host of the dependent service", Extract: func(r interface{}) interface{} {
+				return r.(*objects.ServiceDependency).DependentHost.Name
+			}},
+			"dependent_service_description": {Name: "dependent_service_description", Type: "string", Description: "Description of the dependent service", Extract: func(r interface{}) interface{} {
+				return r.(*objects.ServiceDependency).DependentService.Description
+			}},
+			"service_state": {Name: "service_state", Type: "int", Description: "Current state of the master service", Extract: func(r interface{}) interface{} {
+				return r.(*objects.ServiceDependency).Service.CurrentState
+			}},
+			"dependent_service_state": {Name: "dependent_service_state", Type: "int", Description: "Current state of the dependent service", Extract: func(r interface{}) interface{} {
+				return r.(*objects.ServiceDependency).DependentService.CurrentState
+			}},
+			"dependency_period": {Name: "dependency_period", Type: "string", Extract: func(r interface{}) interface{} {
+				return periodName(r.(*objects.ServiceDependency).DependencyPeriod)
+			}},
+			"inherits_parent": {Name: "inherits_parent", Type: "int", Extract: func(r interface{}) interface{} {
+				return boolToInt(r.(*objects.ServiceDependency).InheritsParent)
+			}},
+			"execution_failure_options": {Name: "execution_failure_options", Type: "list", Extract: func(r interface{}) interface{} {
+				return serviceFailureOptions(r.(*objects.ServiceDependency).ExecutionFailureOptions)
+			}},
+			"notification_failure_options": {Name: "notification_failure_options", Type: "list", Extract: func(r interface{}) interface{} {
+				return serviceFailureOptions(r.(*objects.ServiceDependency).NotificationFailureOptions)
+			}},
+		},
+	}
+}
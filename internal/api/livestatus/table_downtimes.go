@@ -29,6 +29,12 @@ func downtimesTable() *Table {
 			"fixed": {Name: "fixed", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*downtime.Downtime).Fixed) }},
 			"duration": {Name: "duration", Type: "int", Extract: func(r interface{}) interface{} { return int(r.(*downtime.Downtime).Duration.Seconds()) }},
 			"triggered_by": {Name: "triggered_by", Type: "int", Extract: func(r interface{}) interface{} { return int(r.(*downtime.Downtime).TriggeredBy) }},
+			"is_pending": {Name: "is_pending", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(!r.(*downtime.Downtime).IsInEffect) }},
+			// window_start/window_end bound the span the downtime can cover
+			// (see Downtime.Window), for overlap and coverage queries like
+			// "Filter: window_start < Y" plus "Filter: window_end > X".
+			"window_start": {Name: "window_start", Type: "time", Extract: func(r interface{}) interface{} { start, _ := r.(*downtime.Downtime).Window(); return start }},
+			"window_end": {Name: "window_end", Type: "time", Extract: func(r interface{}) interface{} { _, end := r.(*downtime.Downtime).Window(); return end }},
 		},
 	}
 }
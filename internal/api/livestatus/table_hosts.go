@@ -22,6 +22,7 @@ func hostsTable() *Table {
 		},
 		Columns: map[string]*Column{
 			"name":            {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).Name }},
+			"config_source": {Name: "config_source", Description: "File and line of the object definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).ConfigSource }},
 			"display_name":    {Name: "display_name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).DisplayName }},
 			"alias":           {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).Alias }},
 			"address":         {Name: "address", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).Address }},
@@ -29,6 +30,8 @@ func hostsTable() *Table {
 			"state_type":      {Name: "state_type", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).StateType }},
 			"plugin_output":   {Name: "plugin_output", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).PluginOutput }},
 			"long_plugin_output": {Name: "long_plugin_output", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LongPluginOutput }},
+			"check_source": {Name: "check_source", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).CheckSource }},
+			"check_executor": {Name: "check_executor", Description: "Check executor the last active check was sent to (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).CheckExecutor }},
 			"perf_data":       {Name: "perf_data", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).PerfData }},
 			"has_been_checked": {Name: "has_been_checked", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Host).HasBeenChecked) }},
 			"current_attempt": {Name: "current_attempt", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).CurrentAttempt }},
@@ -37,6 +40,10 @@ func hostsTable() *Table {
 			"next_check":      {Name: "next_check", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).NextCheck }},
 			"last_state_change": {Name: "last_state_change", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastStateChange }},
 			"last_hard_state_change": {Name: "last_hard_state_change", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastHardStateChange }},
+			"last_hard_state_change_check_type": {Name: "last_hard_state_change_check_type", Description: "Type of the check that caused the last hard state change: 0 active, 1 passive (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastHardStateCause.CheckType }},
+			"last_hard_state_change_source": {Name: "last_hard_state_change_source", Description: "check_source of the check that caused the last hard state change (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastHardStateCause.Source }},
+			"last_hard_state_change_check_time": {Name: "last_hard_state_change_check_time", Description: "Time the check that caused the last hard state change ran (Gogios extension)", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastHardStateCause.Time }},
+			"last_hard_state_change_output": {Name: "last_hard_state_change_output", Description: "Output of the check that caused the last hard state change (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastHardStateCause.Output }},
 			"last_hard_state": {Name: "last_hard_state", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastHardState }},
 			"last_time_up":    {Name: "last_time_up", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastTimeUp }},
 			"last_time_down":  {Name: "last_time_down", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastTimeDown }},
@@ -85,6 +92,7 @@ func hostsTable() *Table {
 			"percent_state_change":  {Name: "percent_state_change", Type: "float", Extract: func(r interface{}) interface{} { return r.(*objects.Host).PercentStateChange }},
 			"latency":               {Name: "latency", Type: "float", Extract: func(r interface{}) interface{} { return r.(*objects.Host).Latency }},
 			"execution_time":        {Name: "execution_time", Type: "float", Extract: func(r interface{}) interface{} { return r.(*objects.Host).ExecutionTime }},
+			"execution_id":          {Name: "execution_id", Type: "int", Extract: func(r interface{}) interface{} { return int64(r.(*objects.Host).ExecutionID) }},
 			"process_performance_data": {Name: "process_performance_data", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Host).ProcessPerfData) }},
 			"scheduled_downtime_depth": {Name: "scheduled_downtime_depth", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).ScheduledDowntimeDepth }},
 			"acknowledged":          {Name: "acknowledged", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Host).ProblemAcknowledged) }},
@@ -163,6 +171,7 @@ func hostsTable() *Table {
 				}
 				return names
 			}},
+			"tags": {Name: "tags", Description: "The tags of the host; >= filters take a tag selector (Gogios extension)", Type: "list", Extract: func(r interface{}) interface{} { return tagList(r.(*objects.Host).Tags) }},
 			"custom_variable_names": {Name: "custom_variable_names", Type: "list", Extract: func(r interface{}) interface{} {
 				var names []string
 				for k := range r.(*objects.Host).CustomVars {
@@ -191,6 +200,18 @@ func hostsTable() *Table {
 			"last_notification": {Name: "last_notification", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastNotification }},
 			"next_notification": {Name: "next_notification", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Host).NextNotification }},
 			"current_notification_number": {Name: "current_notification_number", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).CurrentNotificationNumber }},
+			"notifications_1h": {Name: "notifications_1h", Description: "Contact notifications sent about the host in the last hour (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Host).NotificationCounts.Since(time.Now(), time.Hour)
+			}},
+			"notifications_24h": {Name: "notifications_24h", Description: "Contact notifications sent about the host in the last 24 hours (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Host).NotificationCounts.Since(time.Now(), 24*time.Hour)
+			}},
+			"check_history": {Name: "check_history", Description: "The latest check results of the host, oldest first, as time|state|state_type|duration|output (Gogios extension)", Type: "list", Extract: func(r interface{}) interface{} {
+				return checkHistoryInfo(r.(*objects.Host).CheckHistory)
+			}},
+			"check_history_states": {Name: "check_history_states", Description: "The states of the latest check results of the host, oldest first (Gogios extension)", Type: "list", Extract: func(r interface{}) interface{} {
+				return checkHistoryStates(r.(*objects.Host).CheckHistory)
+			}},
 			"check_type": {Name: "check_type", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).CheckType }},
 			"last_state": {Name: "last_state", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Host).LastState }},
 			"should_be_scheduled": {Name: "should_be_scheduled", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Host).ShouldBeScheduled) }},
@@ -314,3 +335,23 @@ func commandStr(cmd *objects.Command, args string) string {
 	}
 	return cmd.Name
 }
+
+// checkHistoryInfo returns the results in hist, oldest first, as
+// "time|state|state_type|duration|output".
+func checkHistoryInfo(hist *objects.CheckHistory) []string {
+	infos := make([]string, 0, hist.Size())
+	for _, r := range hist.Records() {
+		infos = append(infos, fmt.Sprintf("%d|%d|%d|%.3f|%s", r.Time.Unix(), r.State, r.StateType, r.Duration, r.Output))
+	}
+	return infos
+}
+
+// checkHistoryStates returns the states of the results in hist, oldest
+// first.
+func checkHistoryStates(hist *objects.CheckHistory) []string {
+	states := make([]string, 0, hist.Size())
+	for _, r := range hist.Records() {
+		states = append(states, strconv.Itoa(r.State))
+	}
+	return states
+}
@@ -1,15 +1,12 @@
 package livestatus
 
 import (
-	"bufio"
-	"os"
-	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/logging"
 )
 
 // logEntry represents a parsed Nagios log line.
@@ -24,7 +21,10 @@ type logEntry struct {
 	Message            string
 	Options            string
 	StateType          string
+	Attempt            int
 	ContactName        string
+	CommandName        string
+	LineNo             int
 }
 
 func logTable() *Table {
@@ -34,10 +34,20 @@ func logTable() *Table {
 			if p.LogFile == "" {
 				return nil
 			}
-			entries := loadAllLogs(p.LogFile, p.LogArchivePath, p.LogTimeMin, p.LogTimeMax)
-			rows := make([]interface{}, len(entries))
-			for i, e := range entries {
-				rows[i] = e
+			index := p.LogIndex
+			if index == nil {
+				index = logging.NewLogIndex(p.LogFile, p.LogArchivePath)
+			}
+			var rows []interface{}
+			err := index.Scan(p.LogTimeMin, p.LogTimeMax, func(line string, lineno int) bool {
+				if e := parseLogLine(line); e != nil {
+					e.LineNo = lineno
+					rows = append(rows, e)
+				}
+				return true
+			})
+			if err != nil && p.Logger != nil {
+				p.Logger.Log("Warning: Livestatus: Reading log files: %v", err)
 			}
 			return rows
 		},
@@ -75,127 +85,19 @@ func logTable() *Table {
 			"contact_name": {Name: "contact_name", Type: "string", Extract: func(r interface{}) interface{} {
 				return r.(*logEntry).ContactName
 			}},
+			"attempt": {Name: "attempt", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*logEntry).Attempt
+			}},
+			"command_name": {Name: "command_name", Type: "string", Extract: func(r interface{}) interface{} {
+				return r.(*logEntry).CommandName
+			}},
+			"lineno": {Name: "lineno", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*logEntry).LineNo
+			}},
 		},
 	}
 }
 
-// loadAllLogs reads the current log file and any archived logs.
-// When minTime/maxTime are non-zero, archive files outside the range are
-// skipped and individual lines outside the range are discarded during parsing.
-func loadAllLogs(logFile, archivePath string, minTime, maxTime time.Time) []*logEntry {
-	var entries []*logEntry
-
-	// Read archived logs first (older entries)
-	if archivePath != "" {
-		archiveFiles := findArchiveFiles(archivePath, minTime, maxTime)
-		for _, af := range archiveFiles {
-			entries = append(entries, parseLogFile(af, minTime, maxTime)...)
-		}
-	}
-
-	// Read current log file (newest entries)
-	entries = append(entries, parseLogFile(logFile, minTime, maxTime)...)
-
-	return entries
-}
-
-// archiveTime extracts the timestamp from archive filenames like
-// "nagios-MM-DD-YYYY-HH.log" and returns it. Returns zero time on
-// parse failure.
-func archiveTime(name string) time.Time {
-	// Strip nagios- prefix and .log suffix
-	base := filepath.Base(name)
-	base = strings.TrimPrefix(base, "nagios-")
-	base = strings.TrimSuffix(base, ".log")
-	t, err := time.Parse("01-02-2006-15", base)
-	if err != nil {
-		return time.Time{}
-	}
-	return t
-}
-
-// findArchiveFiles returns sorted archive log file paths (oldest first),
-// filtered to only include files that could contain entries in the
-// [minTime, maxTime] range.
-func findArchiveFiles(archivePath string, minTime, maxTime time.Time) []string {
-	matches, err := filepath.Glob(filepath.Join(archivePath, "*.log"))
-	if err != nil {
-		return nil
-	}
-	// Sort by filename so older archives come first
-	sort.Strings(matches)
-
-	if minTime.IsZero() && maxTime.IsZero() {
-		return matches
-	}
-
-	// Filter archives by timestamp. Each archive covers the period from
-	// its timestamp to the next archive's timestamp. We keep a file if
-	// its period overlaps [minTime, maxTime].
-	var filtered []string
-	for i, path := range matches {
-		fileTime := archiveTime(path)
-		if fileTime.IsZero() {
-			// Can't parse — include to be safe
-			filtered = append(filtered, path)
-			continue
-		}
-
-		// Determine the end of this file's time range: the start of the
-		// next archive, or "now" for the last archive.
-		var fileEnd time.Time
-		if i+1 < len(matches) {
-			fileEnd = archiveTime(matches[i+1])
-		}
-		if fileEnd.IsZero() {
-			fileEnd = time.Now()
-		}
-
-		// Skip if file ends before our min
-		if !minTime.IsZero() && fileEnd.Before(minTime) {
-			continue
-		}
-		// Skip if file starts after our max
-		if !maxTime.IsZero() && fileTime.After(maxTime) {
-			continue
-		}
-
-		filtered = append(filtered, path)
-	}
-	return filtered
-}
-
-func parseLogFile(path string, minTime, maxTime time.Time) []*logEntry {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil
-	}
-	defer f.Close()
-
-	hasMin := !minTime.IsZero()
-	hasMax := !maxTime.IsZero()
-
-	var entries []*logEntry
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 0, 256*1024), 1024*1024)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		e := parseLogLine(line)
-		if e == nil {
-			continue
-		}
-		if hasMin && e.Time.Before(minTime) {
-			continue
-		}
-		if hasMax && e.Time.After(maxTime) {
-			continue
-		}
-		entries = append(entries, e)
-	}
-	return entries
-}
-
 func parseLogLine(line string) *logEntry {
 	// Format: [timestamp] TYPE: details
 	if len(line) < 14 || line[0] != '[' {
@@ -250,6 +152,10 @@ func parseLogLine(line string) *logEntry {
 		parseFlappingAlert(e, detail, true)
 	case "SERVICE FLAPPING ALERT":
 		parseFlappingAlert(e, detail, false)
+	case "HOST STATE CAUSE":
+		parseStateCause(e, detail, true)
+	case "SERVICE STATE CAUSE":
+		parseStateCause(e, detail, false)
 	case "EXTERNAL COMMAND":
 		e.Options = detail
 	}
@@ -266,6 +172,7 @@ func parseHostAlert(e *logEntry, detail string) {
 	e.HostName = parts[0]
 	e.State = hostStateFromName(parts[1])
 	e.StateType = parts[2]
+	e.Attempt, _ = strconv.Atoi(parts[3])
 	if len(parts) >= 5 {
 		e.PluginOutput = parts[4]
 	}
@@ -281,6 +188,7 @@ func parseServiceAlert(e *logEntry, detail string) {
 	e.ServiceDescription = parts[1]
 	e.State = serviceStateFromName(parts[2])
 	e.StateType = parts[3]
+	e.Attempt, _ = strconv.Atoi(parts[4])
 	if len(parts) >= 6 {
 		e.PluginOutput = parts[5]
 	}
@@ -295,6 +203,9 @@ func parseHostNotification(e *logEntry, detail string) {
 	e.ContactName = parts[0]
 	e.HostName = parts[1]
 	e.State = hostStateFromName(parts[2])
+	if len(parts) >= 4 {
+		e.CommandName = parts[3]
+	}
 	if len(parts) >= 5 {
 		e.PluginOutput = parts[4]
 	}
@@ -310,6 +221,9 @@ func parseServiceNotification(e *logEntry, detail string) {
 	e.HostName = parts[1]
 	e.ServiceDescription = parts[2]
 	e.State = serviceStateFromName(parts[3])
+	if len(parts) >= 5 {
+		e.CommandName = parts[4]
+	}
 	if len(parts) >= 6 {
 		e.PluginOutput = parts[5]
 	}
@@ -329,6 +243,27 @@ func parseDowntimeAlert(e *logEntry, detail string, isHost bool) {
 	}
 }
 
+// parseStateCause: hostname;[svc;]state;check_type;source;check_time, with
+// check_type;source;check_time as the options.
+func parseStateCause(e *logEntry, detail string, isHost bool) {
+	n := 3
+	if !isHost {
+		n = 4
+	}
+	parts := strings.SplitN(detail, ";", n)
+	if len(parts) < n {
+		return
+	}
+	e.HostName = parts[0]
+	if isHost {
+		e.State = hostStateFromName(parts[1])
+	} else {
+		e.ServiceDescription = parts[1]
+		e.State = serviceStateFromName(parts[2])
+	}
+	e.Options = parts[n-1]
+}
+
 func parseFlappingAlert(e *logEntry, detail string, isHost bool) {
 	parts := strings.SplitN(detail, ";", 4)
 	if len(parts) < 2 {
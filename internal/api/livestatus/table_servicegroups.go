@@ -17,6 +17,7 @@ func servicegroupsTable() *Table {
 		},
 		Columns: map[string]*Column{
 			"name":  {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ServiceGroup).Name }},
+			"config_source": {Name: "config_source", Description: "File and line of the object definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ServiceGroup).ConfigSource }},
 			"alias": {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.ServiceGroup).Alias }},
 			"members": {Name: "members", Type: "list", Extract: func(r interface{}) interface{} {
 				names := make([]string, 0)
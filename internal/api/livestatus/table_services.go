@@ -25,6 +25,8 @@ func servicesTable() *Table {
 			"host_display_name": {Name: "host_display_name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.DisplayName }},
 			"host_alias":       {Name: "host_alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.Alias }},
 			"host_address":     {Name: "host_address", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.Address }},
+			"config_source": {Name: "config_source", Description: "File and line of the object definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).ConfigSource }},
+			"host_config_source": {Name: "host_config_source", Description: "File and line of the host definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.ConfigSource }},
 			"host_state":       {Name: "host_state", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.CurrentState }},
 			"host_has_been_checked": {Name: "host_has_been_checked", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Service).Host.HasBeenChecked) }},
 			"host_acknowledged": {Name: "host_acknowledged", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Service).Host.ProblemAcknowledged) }},
@@ -48,6 +50,8 @@ func servicesTable() *Table {
 			"state_type":      {Name: "state_type", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).StateType }},
 			"plugin_output":   {Name: "plugin_output", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).PluginOutput }},
 			"long_plugin_output": {Name: "long_plugin_output", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LongPluginOutput }},
+			"check_source": {Name: "check_source", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).CheckSource }},
+			"check_executor": {Name: "check_executor", Description: "Check executor the last active check was sent to (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).CheckExecutor }},
 			"perf_data":       {Name: "perf_data", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).PerfData }},
 			"has_been_checked": {Name: "has_been_checked", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Service).HasBeenChecked) }},
 			"current_attempt": {Name: "current_attempt", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).CurrentAttempt }},
@@ -56,6 +60,10 @@ func servicesTable() *Table {
 			"next_check":      {Name: "next_check", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).NextCheck }},
 			"last_state_change": {Name: "last_state_change", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastStateChange }},
 			"last_hard_state_change": {Name: "last_hard_state_change", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastHardStateChange }},
+			"last_hard_state_change_check_type": {Name: "last_hard_state_change_check_type", Description: "Type of the check that caused the last hard state change: 0 active, 1 passive (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastHardStateCause.CheckType }},
+			"last_hard_state_change_source": {Name: "last_hard_state_change_source", Description: "check_source of the check that caused the last hard state change (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastHardStateCause.Source }},
+			"last_hard_state_change_check_time": {Name: "last_hard_state_change_check_time", Description: "Time the check that caused the last hard state change ran (Gogios extension)", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastHardStateCause.Time }},
+			"last_hard_state_change_output": {Name: "last_hard_state_change_output", Description: "Output of the check that caused the last hard state change (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastHardStateCause.Output }},
 			"last_hard_state": {Name: "last_hard_state", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastHardState }},
 			"last_time_ok":      {Name: "last_time_ok", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastTimeOK }},
 			"last_time_warning": {Name: "last_time_warning", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastTimeWarning }},
@@ -99,6 +107,7 @@ func servicesTable() *Table {
 			"percent_state_change":  {Name: "percent_state_change", Type: "float", Extract: func(r interface{}) interface{} { return r.(*objects.Service).PercentStateChange }},
 			"latency":               {Name: "latency", Type: "float", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Latency }},
 			"execution_time":        {Name: "execution_time", Type: "float", Extract: func(r interface{}) interface{} { return r.(*objects.Service).ExecutionTime }},
+			"execution_id":          {Name: "execution_id", Type: "int", Extract: func(r interface{}) interface{} { return int64(r.(*objects.Service).ExecutionID) }},
 			"process_performance_data": {Name: "process_performance_data", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Service).ProcessPerfData) }},
 			"scheduled_downtime_depth": {Name: "scheduled_downtime_depth", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).ScheduledDowntimeDepth }},
 			"acknowledged":          {Name: "acknowledged", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Service).ProblemAcknowledged) }},
@@ -132,6 +141,7 @@ func servicesTable() *Table {
 				}
 				return names
 			}},
+			"tags": {Name: "tags", Description: "The tags of the service; >= filters take a tag selector (Gogios extension)", Type: "list", Extract: func(r interface{}) interface{} { return tagList(r.(*objects.Service).Tags) }},
 			"custom_variable_names": {Name: "custom_variable_names", Type: "list", Extract: func(r interface{}) interface{} {
 				var names []string
 				for k := range r.(*objects.Service).CustomVars {
@@ -160,6 +170,18 @@ func servicesTable() *Table {
 			"last_notification": {Name: "last_notification", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastNotification }},
 			"next_notification": {Name: "next_notification", Type: "time", Extract: func(r interface{}) interface{} { return r.(*objects.Service).NextNotification }},
 			"current_notification_number": {Name: "current_notification_number", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).CurrentNotificationNumber }},
+			"notifications_1h": {Name: "notifications_1h", Description: "Contact notifications sent about the service in the last hour (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Service).NotificationCounts.Since(time.Now(), time.Hour)
+			}},
+			"notifications_24h": {Name: "notifications_24h", Description: "Contact notifications sent about the service in the last 24 hours (Gogios extension)", Type: "int", Extract: func(r interface{}) interface{} {
+				return r.(*objects.Service).NotificationCounts.Since(time.Now(), 24*time.Hour)
+			}},
+			"check_history": {Name: "check_history", Description: "The latest check results of the service, oldest first, as time|state|state_type|duration|output (Gogios extension)", Type: "list", Extract: func(r interface{}) interface{} {
+				return checkHistoryInfo(r.(*objects.Service).CheckHistory)
+			}},
+			"check_history_states": {Name: "check_history_states", Description: "The states of the latest check results of the service, oldest first (Gogios extension)", Type: "list", Extract: func(r interface{}) interface{} {
+				return checkHistoryStates(r.(*objects.Service).CheckHistory)
+			}},
 			"check_type": {Name: "check_type", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).CheckType }},
 			"last_state": {Name: "last_state", Type: "int", Extract: func(r interface{}) interface{} { return r.(*objects.Service).LastState }},
 			"should_be_scheduled": {Name: "should_be_scheduled", Type: "int", Extract: func(r interface{}) interface{} { return boolToInt(r.(*objects.Service).ShouldBeScheduled) }},
@@ -249,6 +271,7 @@ func servicesTable() *Table {
 			"host_check_command": {Name: "host_check_command", Type: "string", Extract: func(r interface{}) interface{} {
 				return commandStr(r.(*objects.Service).Host.CheckCommand, r.(*objects.Service).Host.CheckCommandArgs)
 			}},
+			"host_tags": {Name: "host_tags", Description: "The tags of the host; >= filters take a tag selector (Gogios extension)", Type: "list", Extract: func(r interface{}) interface{} { return tagList(r.(*objects.Service).Host.Tags) }},
 			"host_custom_variable_names": {Name: "host_custom_variable_names", Type: "list", Extract: func(r interface{}) interface{} {
 				names := make([]string, 0)
 				for k := range r.(*objects.Service).Host.CustomVars {
@@ -298,6 +321,7 @@ func servicesTable() *Table {
 			"host_action_url_expanded": {Name: "host_action_url_expanded", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.ActionURL }},
 			"host_perf_data": {Name: "host_perf_data", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.PerfData }},
 			"host_plugin_output": {Name: "host_plugin_output", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.PluginOutput }},
+			"host_check_source": {Name: "host_check_source", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Service).Host.CheckSource }},
 			"host_parents": {Name: "host_parents", Type: "list", Extract: func(r interface{}) interface{} {
 				names := make([]string, 0)
 				for _, p := range r.(*objects.Service).Host.Parents {
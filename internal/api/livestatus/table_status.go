@@ -70,6 +70,12 @@ func statusTable() *Table {
 			"process_performance_data": {Name: "process_performance_data", Type: "int", Extract: func(r interface{}) interface{} {
 				return boolToInt(r.(*statusRow).p.Global.ProcessPerformanceData)
 			}},
+			"scheduler_paused": {Name: "scheduler_paused", Type: "int", Extract: func(r interface{}) interface{} {
+				return boolToInt(r.(*statusRow).p.Global.SchedulerPaused)
+			}},
+			"scheduler_paused_since": {Name: "scheduler_paused_since", Type: "time", Extract: func(r interface{}) interface{} {
+				return r.(*statusRow).p.Global.SchedulerPausedSince
+			}},
 			"check_external_commands": {Name: "check_external_commands", Type: "int", Extract: func(r interface{}) interface{} {
 				return 1 // always enabled
 			}},
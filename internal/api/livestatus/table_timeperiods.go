@@ -16,8 +16,9 @@ func timeperiodsTable() *Table {
 			return rows
 		},
 		Columns: map[string]*Column{
-			"name":  {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Timeperiod).Name }},
-			"alias": {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Timeperiod).Alias }},
+			"name":          {Name: "name", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Timeperiod).Name }},
+			"config_source": {Name: "config_source", Description: "File and line of the object definition (Gogios extension)", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Timeperiod).ConfigSource }},
+			"alias":         {Name: "alias", Type: "string", Extract: func(r interface{}) interface{} { return r.(*objects.Timeperiod).Alias }},
 		},
 	}
 }
@@ -1,6 +1,11 @@
 package livestatus
 
-import "github.com/oceanplexian/gogios/internal/api"
+import (
+	"sort"
+	"strings"
+
+	"github.com/oceanplexian/gogios/internal/api"
+)
 
 // Column describes a single column in a livestatus table.
 type Column struct {
@@ -26,15 +31,51 @@ type Table struct {
 	Name    string
 	Columns map[string]*Column
 	GetRows func(p *api.StateProvider) []interface{}
+
+	order []string // column names in documented order, set by registerTable
+}
+
+// ColumnNames returns the table's column names in documented order: the
+// order listed by GET columns and used when a query has no Columns header.
+func (t *Table) ColumnNames() []string {
+	return t.order
 }
 
 // Registry maps table names to Table definitions.
 var Registry = map[string]*Table{}
 
 func registerTable(t *Table) {
+	t.order = make([]string, 0, len(t.Columns))
+	for name, col := range t.Columns {
+		t.order = append(t.order, name)
+		if col.Description == "" {
+			col.Description = describeColumn(name)
+		}
+	}
+	sort.Strings(t.order)
 	Registry[t.Name] = t
 }
 
+// TableNames returns the registered table names in sorted order.
+func TableNames() []string {
+	names := make([]string, 0, len(Registry))
+	for name := range Registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describeColumn derives a description from a column name for columns that
+// do not document one, e.g. "last_state_change" -> "Last state change".
+func describeColumn(name string) string {
+	s := strings.ReplaceAll(name, "_", " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
 func init() {
 	registerTable(hostsTable())
 	registerTable(servicesTable())
@@ -44,6 +85,8 @@ func init() {
 	registerTable(timeperiodsTable())
 	registerTable(hostgroupsTable())
 	registerTable(servicegroupsTable())
+	registerTable(hostdependenciesTable())
+	registerTable(servicedependenciesTable())
 	registerTable(statusTable())
 	registerTable(columnsTable())
 	registerTable(commentsTable())
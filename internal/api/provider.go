@@ -17,6 +17,9 @@ type StateProvider struct {
 	Logger         *logging.Logger
 	LogFile        string
 	LogArchivePath string
+	// LogIndex indexes LogFile and its archives for the log table; nil
+	// reads them without an index.
+	LogIndex *logging.LogIndex
 
 	// LogTimeMin/LogTimeMax are optional hints extracted from query
 	// filters to limit which log files are loaded from disk.
@@ -36,3 +39,14 @@ type CommandEntry struct {
 	Name string
 	Args []string
 }
+
+// CanSubmitCommands reports whether the contact named authUser may submit
+// external commands. An empty authUser means the caller did not identify a
+// contact and is allowed, as before; an unknown contact is refused.
+func (p *StateProvider) CanSubmitCommands(authUser string) bool {
+	if authUser == "" {
+		return true
+	}
+	c := p.Store.GetContact(authUser)
+	return c != nil && c.CanSubmitCommands
+}
@@ -0,0 +1,192 @@
+package rest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// page is the pagination of a listing: at most limit items (0 is all)
+// whose key sorts after after.
+type page struct {
+	limit int
+	after string
+}
+
+// parsePage reads ?limit= and ?cursor=.
+func parsePage(q url.Values) (page, error) {
+	var p page
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("invalid limit '%s'", v)
+		}
+		p.limit = n
+	}
+	if v := q.Get("cursor"); v != "" {
+		after, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(after) == 0 {
+			return p, fmt.Errorf("invalid cursor '%s'", v)
+		}
+		p.after = string(after)
+	}
+	return p, nil
+}
+
+// paginate sorts items by key and returns those of the page, with the
+// cursor of the next page, or "" for the last one. Keys are unique, so a
+// cursor stays valid when objects are added or removed between requests.
+func paginate[T any](items []T, key func(T) string, p page) ([]T, string) {
+	if p.after != "" {
+		items = slices.DeleteFunc(items, func(v T) bool { return key(v) <= p.after })
+	}
+	slices.SortFunc(items, func(a, b T) int { return strings.Compare(key(a), key(b)) })
+	if p.limit == 0 || len(items) <= p.limit {
+		return items, ""
+	}
+	items = items[:p.limit]
+	return items, base64.RawURLEncoding.EncodeToString([]byte(key(items[len(items)-1])))
+}
+
+func hostKey(h *objects.Host) string { return h.Name }
+
+// serviceKey sorts services by host name, then description.
+func serviceKey(svc *objects.Service) string { return svc.Host.Name + "\x00" + svc.Description }
+
+// fieldSet is a sparse fieldset: the JSON fields of a type to return.
+type fieldSet struct {
+	names   []string
+	indexes []int
+}
+
+// parseFields reads ?fields= for values of type t. It returns nil, for all
+// fields, when the parameter is not given.
+func parseFields(q url.Values, t reflect.Type) (*fieldSet, error) {
+	v := q.Get("fields")
+	if v == "" {
+		return nil, nil
+	}
+	byName := make(map[string]int, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		byName[name] = i
+	}
+	fs := &fieldSet{}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		i, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field '%s'", name)
+		}
+		fs.names = append(fs.names, name)
+		fs.indexes = append(fs.indexes, i)
+	}
+	return fs, nil
+}
+
+// apply returns the selected fields of v, a struct of the type the set was
+// parsed for.
+func (fs *fieldSet) apply(v any) map[string]any {
+	rv := reflect.ValueOf(v)
+	out := make(map[string]any, len(fs.names))
+	for i, name := range fs.names {
+		out[name] = rv.Field(fs.indexes[i]).Interface()
+	}
+	return out
+}
+
+// parseStates reads a comma-separated ?state= list of state numbers or
+// names. It returns nil, for any state, when the parameter is not given.
+func parseStates(v string, names map[string]int) (map[int]bool, error) {
+	if v == "" {
+		return nil, nil
+	}
+	states := make(map[int]bool)
+	for _, s := range strings.Split(v, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		n, ok := names[s]
+		if i, err := strconv.Atoi(s); !ok && err == nil {
+			for _, state := range names {
+				if state == i {
+					n, ok = i, true
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid state '%s'", s)
+		}
+		states[n] = true
+	}
+	return states, nil
+}
+
+// parseTags reads a ?tag= selector. It returns nil, for any tags, when the
+// parameter is not given.
+func parseTags(v string) (objects.TagSelector, error) {
+	if v == "" {
+		return nil, nil
+	}
+	return objects.ParseTagSelector(v)
+}
+
+var hostStateNames = map[string]int{
+	"up":          objects.HostUp,
+	"down":        objects.HostDown,
+	"unreachable": objects.HostUnreachable,
+}
+
+var serviceStateNames = map[string]int{
+	"ok":       objects.ServiceOK,
+	"warning":  objects.ServiceWarning,
+	"critical": objects.ServiceCritical,
+	"unknown":  objects.ServiceUnknown,
+}
+
+// writeList writes the items of a listing, reduced to fields when not nil,
+// and links the next page when there is one.
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T, fields *fieldSet, next string) {
+	if next != "" {
+		q := r.URL.Query()
+		q.Set("cursor", next)
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, q.Encode()))
+	}
+	if fields == nil {
+		writeJSON(w, http.StatusOK, items)
+		return
+	}
+	out := make([]map[string]any, len(items))
+	for i, v := range items {
+		out[i] = fields.apply(v)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// writeObject writes one object, reduced to fields when not nil.
+func writeObject(w http.ResponseWriter, v any, fields *fieldSet) {
+	if fields == nil {
+		writeJSON(w, http.StatusOK, v)
+		return
+	}
+	writeJSON(w, http.StatusOK, fields.apply(v))
+}
+
+// parseListQuery reads the pagination and fields of a listing of values of
+// type t. It writes a 400 and returns false when either is invalid.
+func parseListQuery(w http.ResponseWriter, q url.Values, t reflect.Type) (page, *fieldSet, bool) {
+	p, err := parsePage(q)
+	if err == nil {
+		var fields *fieldSet
+		if fields, err = parseFields(q, t); err == nil {
+			return p, fields, true
+		}
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+	return p, nil, false
+}
@@ -0,0 +1,660 @@
+// Package rest serves a JSON API over HTTP: host and service status,
+// downtimes and comments, and a few commands (acknowledge, schedule a check,
+// schedule downtime) that are submitted as external commands.
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/listenaddr"
+	"github.com/oceanplexian/gogios/internal/logging"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// maxBodySize bounds request bodies.
+const maxBodySize = 1 << 20
+
+// Config holds the REST server configuration.
+type Config struct {
+	Listen    string // e.g. "127.0.0.1:5672"
+	TokenHash string // bcrypt hash of the accepted bearer token; empty disables auth
+	SSLCert   string
+	SSLKey    string
+}
+
+// Server is the REST API endpoint.
+type Server struct {
+	cfg    Config
+	state  *api.StateProvider
+	sink   api.CommandSink
+	logger *logging.Logger
+	mux    *http.ServeMux
+	server *http.Server
+	lns    []net.Listener
+
+	// now is the clock for default check and downtime times.
+	now func() time.Time
+}
+
+// New creates a REST server. sink receives the external commands built by
+// the command endpoints; nil disables them.
+func New(cfg Config, state *api.StateProvider, sink api.CommandSink, logger *logging.Logger) *Server {
+	s := &Server{cfg: cfg, state: state, sink: sink, logger: logger, now: time.Now}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/hosts", s.listHosts)
+	mux.HandleFunc("GET /api/v1/hosts/{host}", s.getHost)
+	mux.HandleFunc("GET /api/v1/services", s.listServices)
+	mux.HandleFunc("GET /api/v1/services/{host}/{service}", s.getService)
+	mux.HandleFunc("GET /api/v1/downtimes", s.listDowntimes)
+	mux.HandleFunc("GET /api/v1/comments", s.listComments)
+	mux.HandleFunc("POST /api/v1/hosts/{host}/acknowledge", s.acknowledge)
+	mux.HandleFunc("POST /api/v1/services/{host}/{service}/acknowledge", s.acknowledge)
+	mux.HandleFunc("POST /api/v1/hosts/{host}/check", s.scheduleCheck)
+	mux.HandleFunc("POST /api/v1/services/{host}/{service}/check", s.scheduleCheck)
+	mux.HandleFunc("POST /api/v1/hosts/{host}/downtime", s.scheduleDowntime)
+	mux.HandleFunc("POST /api/v1/services/{host}/{service}/downtime", s.scheduleDowntime)
+	s.mux = mux
+	return s
+}
+
+// Handler returns the HTTP handler serving the API, behind authentication.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.authenticate(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gogios"`)
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	})
+}
+
+// Start begins listening for API requests.
+func (s *Server) Start() error {
+	s.server = &http.Server{
+		Addr:         s.cfg.Listen,
+		Handler:      s.Handler(),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	lns, err := listenaddr.Listen(s.cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("rest api: %w", err)
+	}
+	s.lns = lns
+	for _, ln := range lns {
+		go func(ln net.Listener) {
+			var err error
+			if s.cfg.SSLCert != "" && s.cfg.SSLKey != "" {
+				err = s.server.ServeTLS(ln, s.cfg.SSLCert, s.cfg.SSLKey)
+			} else {
+				err = s.server.Serve(ln)
+			}
+			if err != nil && err != http.ErrServerClosed && s.logger != nil {
+				s.logger.Log("Error: REST API server on %s: %v", ln.Addr(), err)
+			}
+		}(ln)
+	}
+	return nil
+}
+
+// Stop shuts down the server.
+func (s *Server) Stop() {
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	}
+}
+
+func (s *Server) authenticate(r *http.Request) error {
+	if s.cfg.TokenHash == "" {
+		return nil
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return fmt.Errorf("missing bearer token")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(s.cfg.TokenHash), []byte(token)) != nil {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// --- Reads ---
+
+// Host is the JSON form of a host.
+type Host struct {
+	Name                   string   `json:"name"`
+	Alias                  string   `json:"alias"`
+	Address                string   `json:"address"`
+	State                  int      `json:"state"`
+	StateType              int      `json:"state_type"`
+	CurrentAttempt         int      `json:"current_attempt"`
+	MaxCheckAttempts       int      `json:"max_check_attempts"`
+	PluginOutput           string   `json:"plugin_output"`
+	PerfData               string   `json:"perf_data"`
+	LastCheck              int64    `json:"last_check"`
+	NextCheck              int64    `json:"next_check"`
+	LastStateChange        int64    `json:"last_state_change"`
+	LastHardStateChange    int64    `json:"last_hard_state_change"`
+	LastHardStateCause     *Cause   `json:"last_hard_state_cause"`
+	HasBeenChecked         bool     `json:"has_been_checked"`
+	Acknowledged           bool     `json:"acknowledged"`
+	ScheduledDowntimeDepth int      `json:"scheduled_downtime_depth"`
+	ActiveChecksEnabled    bool     `json:"active_checks_enabled"`
+	NotificationsEnabled   bool     `json:"notifications_enabled"`
+	HostGroups             []string `json:"host_groups"`
+	Tags                   []string `json:"tags"`
+}
+
+// Service is the JSON form of a service.
+type Service struct {
+	HostName               string   `json:"host_name"`
+	Description            string   `json:"description"`
+	State                  int      `json:"state"`
+	StateType              int      `json:"state_type"`
+	CurrentAttempt         int      `json:"current_attempt"`
+	MaxCheckAttempts       int      `json:"max_check_attempts"`
+	PluginOutput           string   `json:"plugin_output"`
+	PerfData               string   `json:"perf_data"`
+	LastCheck              int64    `json:"last_check"`
+	NextCheck              int64    `json:"next_check"`
+	LastStateChange        int64    `json:"last_state_change"`
+	LastHardStateChange    int64    `json:"last_hard_state_change"`
+	LastHardStateCause     *Cause   `json:"last_hard_state_cause"`
+	HasBeenChecked         bool     `json:"has_been_checked"`
+	Acknowledged           bool     `json:"acknowledged"`
+	ScheduledDowntimeDepth int      `json:"scheduled_downtime_depth"`
+	ActiveChecksEnabled    bool     `json:"active_checks_enabled"`
+	NotificationsEnabled   bool     `json:"notifications_enabled"`
+	Tags                   []string `json:"tags"`
+}
+
+// Cause is the JSON form of the check result that caused a hard state
+// change, null until one has since the start.
+type Cause struct {
+	CheckType string `json:"check_type"` // "active" or "passive"
+	Source    string `json:"source"`
+	CheckTime int64  `json:"check_time"`
+	Output    string `json:"output"`
+}
+
+// Downtime is the JSON form of a scheduled downtime.
+type Downtime struct {
+	ID                 uint64 `json:"id"`
+	HostName           string `json:"host_name"`
+	ServiceDescription string `json:"service_description,omitempty"`
+	EntryTime          int64  `json:"entry_time"`
+	StartTime          int64  `json:"start_time"`
+	EndTime            int64  `json:"end_time"`
+	Fixed              bool   `json:"fixed"`
+	Duration           int64  `json:"duration"`
+	TriggeredBy        uint64 `json:"triggered_by"`
+	InEffect           bool   `json:"in_effect"`
+	Author             string `json:"author"`
+	Comment            string `json:"comment"`
+}
+
+// Comment is the JSON form of a comment.
+type Comment struct {
+	ID                 uint64 `json:"id"`
+	HostName           string `json:"host_name"`
+	ServiceDescription string `json:"service_description,omitempty"`
+	EntryType          int    `json:"entry_type"`
+	EntryTime          int64  `json:"entry_time"`
+	Persistent         bool   `json:"persistent"`
+	Expires            bool   `json:"expires"`
+	ExpireTime         int64  `json:"expire_time"`
+	Author             string `json:"author"`
+	Comment            string `json:"comment"`
+}
+
+func (s *Server) listHosts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	p, fields, ok := parseListQuery(w, q, reflect.TypeFor[Host]())
+	if !ok {
+		return
+	}
+	states, err := parseStates(q.Get("state"), hostStateNames)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tags, err := parseTags(q.Get("tag"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	store := s.state.Store
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	hosts := store.Hosts
+	if group := q.Get("hostgroup"); group != "" {
+		hg := store.GetHostGroup(group)
+		if hg == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("hostgroup '%s' not found", group))
+			return
+		}
+		hosts = hg.Members
+	} else if tags != nil {
+		hosts = store.SelectHosts(tags)
+	}
+	matched := make([]*objects.Host, 0, len(hosts))
+	for _, h := range hosts {
+		if (states == nil || states[h.CurrentState]) && (tags == nil || tags.MatchHost(h)) {
+			matched = append(matched, h)
+		}
+	}
+	matched, next := paginate(matched, hostKey, p)
+	out := make([]Host, 0, len(matched))
+	for _, h := range matched {
+		out = append(out, hostJSON(h))
+	}
+	writeList(w, r, out, fields, next)
+}
+
+func (s *Server) getHost(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("host")
+	fields, err := parseFields(r.URL.Query(), reflect.TypeFor[Host]())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.state.Store.Mu.RLock()
+	defer s.state.Store.Mu.RUnlock()
+	h := s.state.Store.GetHost(name)
+	if h == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("host '%s' not found", name))
+		return
+	}
+	writeObject(w, hostJSON(h), fields)
+}
+
+func (s *Server) listServices(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	p, fields, ok := parseListQuery(w, q, reflect.TypeFor[Service]())
+	if !ok {
+		return
+	}
+	states, err := parseStates(q.Get("state"), serviceStateNames)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tags, err := parseTags(q.Get("tag"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hostName := q.Get("host")
+	store := s.state.Store
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	services := store.Services
+	if group := q.Get("servicegroup"); group != "" {
+		sg := store.GetServiceGroup(group)
+		if sg == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("servicegroup '%s' not found", group))
+			return
+		}
+		services = sg.Members
+	} else if tags != nil {
+		services = store.SelectServices(tags)
+	}
+	var inGroup map[*objects.Host]bool
+	if group := q.Get("hostgroup"); group != "" {
+		hg := store.GetHostGroup(group)
+		if hg == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("hostgroup '%s' not found", group))
+			return
+		}
+		inGroup = make(map[*objects.Host]bool, len(hg.Members))
+		for _, h := range hg.Members {
+			inGroup[h] = true
+		}
+	}
+	matched := make([]*objects.Service, 0)
+	for _, svc := range services {
+		if hostName != "" && svc.Host.Name != hostName {
+			continue
+		}
+		if inGroup != nil && !inGroup[svc.Host] {
+			continue
+		}
+		if states != nil && !states[svc.CurrentState] {
+			continue
+		}
+		if tags != nil && !tags.MatchService(svc) {
+			continue
+		}
+		matched = append(matched, svc)
+	}
+	matched, next := paginate(matched, serviceKey, p)
+	out := make([]Service, 0, len(matched))
+	for _, svc := range matched {
+		out = append(out, serviceJSON(svc))
+	}
+	writeList(w, r, out, fields, next)
+}
+
+func (s *Server) getService(w http.ResponseWriter, r *http.Request) {
+	hostName, desc := r.PathValue("host"), r.PathValue("service")
+	fields, err := parseFields(r.URL.Query(), reflect.TypeFor[Service]())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.state.Store.Mu.RLock()
+	defer s.state.Store.Mu.RUnlock()
+	svc := s.state.Store.GetService(hostName, desc)
+	if svc == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("service '%s' on host '%s' not found", desc, hostName))
+		return
+	}
+	writeObject(w, serviceJSON(svc), fields)
+}
+
+func (s *Server) listDowntimes(w http.ResponseWriter, r *http.Request) {
+	out := make([]Downtime, 0)
+	if s.state.Downtimes != nil {
+		for _, d := range s.state.Downtimes.All() {
+			out = append(out, downtimeJSON(d))
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) listComments(w http.ResponseWriter, r *http.Request) {
+	out := make([]Comment, 0)
+	if s.state.Comments != nil {
+		for _, c := range s.state.Comments.All() {
+			out = append(out, commentJSON(c))
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// --- Commands ---
+
+// AcknowledgeRequest is the body of an acknowledge request.
+type AcknowledgeRequest struct {
+	Author     string `json:"author"`
+	Comment    string `json:"comment"`
+	Sticky     bool   `json:"sticky"`
+	Notify     bool   `json:"notify"`
+	Persistent bool   `json:"persistent"`
+}
+
+// CheckRequest is the body of a check request, which may be empty.
+type CheckRequest struct {
+	Time int64 `json:"time"` // unix time to run the check; 0 is now
+}
+
+// DowntimeRequest is the body of a downtime request.
+type DowntimeRequest struct {
+	Start       int64  `json:"start"` // unix time; 0 is now
+	End         int64  `json:"end"`
+	Fixed       *bool  `json:"fixed"`    // default true
+	Duration    int64  `json:"duration"` // seconds, for flexible downtime
+	TriggeredBy uint64 `json:"triggered_by"`
+	Author      string `json:"author"`
+	Comment     string `json:"comment"`
+}
+
+// CommandResponse reports the external command a request was turned into.
+type CommandResponse struct {
+	Command string `json:"command"`
+}
+
+func (s *Server) acknowledge(w http.ResponseWriter, r *http.Request) {
+	var req AcknowledgeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Author == "" || req.Comment == "" {
+		writeError(w, http.StatusBadRequest, "author and comment are required")
+		return
+	}
+	sticky := "1"
+	if req.Sticky {
+		sticky = "2"
+	}
+	s.submit(w, r, "ACKNOWLEDGE_HOST_PROBLEM", "ACKNOWLEDGE_SVC_PROBLEM",
+		sticky, boolArg(req.Notify), boolArg(req.Persistent), req.Author, req.Comment)
+}
+
+func (s *Server) scheduleCheck(w http.ResponseWriter, r *http.Request) {
+	var req CheckRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Time == 0 {
+		req.Time = s.now().Unix()
+	}
+	s.submit(w, r, "SCHEDULE_FORCED_HOST_CHECK", "SCHEDULE_FORCED_SVC_CHECK",
+		strconv.FormatInt(req.Time, 10))
+}
+
+func (s *Server) scheduleDowntime(w http.ResponseWriter, r *http.Request) {
+	var req DowntimeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Author == "" || req.Comment == "" {
+		writeError(w, http.StatusBadRequest, "author and comment are required")
+		return
+	}
+	if req.Start == 0 {
+		req.Start = s.now().Unix()
+	}
+	if req.End <= req.Start {
+		writeError(w, http.StatusBadRequest, "end must be after start")
+		return
+	}
+	fixed := req.Fixed == nil || *req.Fixed
+	if !fixed && req.Duration <= 0 {
+		writeError(w, http.StatusBadRequest, "flexible downtime needs a duration")
+		return
+	}
+	s.submit(w, r, "SCHEDULE_HOST_DOWNTIME", "SCHEDULE_SVC_DOWNTIME",
+		strconv.FormatInt(req.Start, 10), strconv.FormatInt(req.End, 10), boolArg(fixed),
+		strconv.FormatUint(req.TriggeredBy, 10), strconv.FormatInt(req.Duration, 10),
+		req.Author, req.Comment)
+}
+
+// submit checks that the host or service of the request path exists and
+// sends hostCmd or svcCmd for it, with args after the host name and service
+// description.
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, hostCmd, svcCmd string, args ...string) {
+	if s.sink == nil {
+		writeError(w, http.StatusNotImplemented, "external commands are disabled")
+		return
+	}
+	hostName, desc := r.PathValue("host"), r.PathValue("service")
+	name := hostCmd
+	target := []string{hostName}
+	s.state.Store.Mu.RLock()
+	var found bool
+	if desc == "" {
+		found = s.state.Store.GetHost(hostName) != nil
+	} else {
+		found = s.state.Store.GetService(hostName, desc) != nil
+		name = svcCmd
+		target = append(target, desc)
+	}
+	s.state.Store.Mu.RUnlock()
+	if !found {
+		if desc == "" {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("host '%s' not found", hostName))
+		} else {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("service '%s' on host '%s' not found", desc, hostName))
+		}
+		return
+	}
+	args = append(target, args...)
+	if s.logger != nil {
+		s.logger.Log("EXTERNAL COMMAND: %s;%s (via REST API)", name, strings.Join(args, ";"))
+	}
+	s.sink(name, args)
+	writeJSON(w, http.StatusAccepted, CommandResponse{Command: name + ";" + strings.Join(args, ";")})
+}
+
+// --- Helpers ---
+
+// readJSON decodes the request body into v. An empty body leaves v as it
+// is. It writes a 400 and returns false when the body is not valid JSON.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return false
+	}
+	if len(body) > maxBodySize {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return false
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return true
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func boolArg(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func hostJSON(h *objects.Host) Host {
+	groups := make([]string, len(h.HostGroups))
+	for i, hg := range h.HostGroups {
+		groups[i] = hg.Name
+	}
+	return Host{
+		Name:                   h.Name,
+		Alias:                  h.Alias,
+		Address:                h.Address,
+		State:                  h.CurrentState,
+		StateType:              h.StateType,
+		CurrentAttempt:         h.CurrentAttempt,
+		MaxCheckAttempts:       h.MaxCheckAttempts,
+		PluginOutput:           h.PluginOutput,
+		PerfData:               h.PerfData,
+		LastCheck:              unixOrZero(h.LastCheck),
+		NextCheck:              unixOrZero(h.NextCheck),
+		LastStateChange:        unixOrZero(h.LastStateChange),
+		LastHardStateChange:    unixOrZero(h.LastHardStateChange),
+		LastHardStateCause:     causeJSON(h.LastHardStateCause),
+		HasBeenChecked:         h.HasBeenChecked,
+		Acknowledged:           h.ProblemAcknowledged,
+		ScheduledDowntimeDepth: h.ScheduledDowntimeDepth,
+		ActiveChecksEnabled:    h.ActiveChecksEnabled,
+		NotificationsEnabled:   h.NotificationsEnabled,
+		HostGroups:             groups,
+		Tags:                   append([]string{}, h.Tags...),
+	}
+}
+
+func serviceJSON(svc *objects.Service) Service {
+	return Service{
+		HostName:               svc.Host.Name,
+		Description:            svc.Description,
+		State:                  svc.CurrentState,
+		StateType:              svc.StateType,
+		CurrentAttempt:         svc.CurrentAttempt,
+		MaxCheckAttempts:       svc.MaxCheckAttempts,
+		PluginOutput:           svc.PluginOutput,
+		PerfData:               svc.PerfData,
+		LastCheck:              unixOrZero(svc.LastCheck),
+		NextCheck:              unixOrZero(svc.NextCheck),
+		LastStateChange:        unixOrZero(svc.LastStateChange),
+		LastHardStateChange:    unixOrZero(svc.LastHardStateChange),
+		LastHardStateCause:     causeJSON(svc.LastHardStateCause),
+		HasBeenChecked:         svc.HasBeenChecked,
+		Acknowledged:           svc.ProblemAcknowledged,
+		ScheduledDowntimeDepth: svc.ScheduledDowntimeDepth,
+		ActiveChecksEnabled:    svc.ActiveChecksEnabled,
+		NotificationsEnabled:   svc.NotificationsEnabled,
+		Tags:                   append([]string{}, svc.Tags...),
+	}
+}
+
+func causeJSON(c objects.StateChangeCause) *Cause {
+	if c.Time.IsZero() {
+		return nil
+	}
+	checkType := "active"
+	if c.CheckType == objects.CheckTypePassive {
+		checkType = "passive"
+	}
+	return &Cause{CheckType: checkType, Source: c.Source, CheckTime: c.Time.Unix(), Output: c.Output}
+}
+
+func downtimeJSON(d *downtime.Downtime) Downtime {
+	return Downtime{
+		ID:                 d.DowntimeID,
+		HostName:           d.HostName,
+		ServiceDescription: d.ServiceDescription,
+		EntryTime:          unixOrZero(d.EntryTime),
+		StartTime:          unixOrZero(d.StartTime),
+		EndTime:            unixOrZero(d.EndTime),
+		Fixed:              d.Fixed,
+		Duration:           int64(d.Duration / time.Second),
+		TriggeredBy:        d.TriggeredBy,
+		InEffect:           d.IsInEffect,
+		Author:             d.Author,
+		Comment:            d.Comment,
+	}
+}
+
+func commentJSON(c *downtime.Comment) Comment {
+	return Comment{
+		ID:                 c.CommentID,
+		HostName:           c.HostName,
+		ServiceDescription: c.ServiceDescription,
+		EntryType:          c.EntryType,
+		EntryTime:          unixOrZero(c.EntryTime),
+		Persistent:         c.Persistent,
+		Expires:            c.Expires,
+		ExpireTime:         unixOrZero(c.ExpireTime),
+		Author:             c.Author,
+		Comment:            c.Data,
+	}
+}
@@ -0,0 +1,222 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/oceanplexian/gogios/internal/api"
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func testServer(t *testing.T, tokenHash string) (*httptest.Server, *[]string) {
+	t.Helper()
+	store := objects.NewObjectStore()
+	h := &objects.Host{Name: "web01", Address: "10.0.0.1", CurrentState: objects.HostDown, HasBeenChecked: true, Tags: objects.ParseTags("env=prod,team=web")}
+	store.AddHost(h)
+	store.AddService(&objects.Service{Host: h, Description: "HTTP", CurrentState: objects.ServiceCritical, PluginOutput: "down"})
+	store.AddService(&objects.Service{Host: h, Description: "Disk /var", CurrentState: objects.ServiceOK})
+	h.Services = store.GetServicesForHost("web01")
+	db := &objects.Host{Name: "db01", HasBeenChecked: true, Tags: objects.ParseTags("env=prod")}
+	store.AddHost(db)
+	store.AddService(&objects.Service{Host: db, Description: "MySQL", CurrentState: objects.ServiceWarning, Tags: objects.ParseTags("team=db")})
+	db.Services = store.GetServicesForHost("db01")
+	store.AddHostGroup(&objects.HostGroup{Name: "web", Members: []*objects.Host{h}})
+	comments := downtime.NewCommentManager(1)
+	comments.Add(&downtime.Comment{CommentType: objects.HostCommentType, HostName: "web01", Author: "alice", Data: "rebooting"})
+	var submitted []string
+	s := New(Config{TokenHash: tokenHash}, &api.StateProvider{Store: store, Global: &objects.GlobalState{}, Comments: comments},
+		func(name string, args []string) { submitted = append(submitted, name+";"+strings.Join(args, ";")) }, nil)
+	s.now = func() time.Time { return time.Unix(1700000000, 0) }
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, &submitted
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, body, token string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestReads(t *testing.T) {
+	ts, _ := testServer(t, "")
+
+	code, body := do(t, ts, "GET", "/api/v1/hosts/web01", "", "")
+	var h Host
+	if code != 200 || json.Unmarshal([]byte(body), &h) != nil || h.Address != "10.0.0.1" || h.State != objects.HostDown {
+		t.Errorf("GET host = %d %s", code, body)
+	}
+	if code, _ := do(t, ts, "GET", "/api/v1/hosts/nope", "", ""); code != 404 {
+		t.Errorf("unknown host = %d, want 404", code)
+	}
+
+	code, body = do(t, ts, "GET", "/api/v1/services?host=web01", "", "")
+	var svcs []Service
+	if code != 200 || json.Unmarshal([]byte(body), &svcs) != nil || len(svcs) != 2 {
+		t.Errorf("GET services = %d %s", code, body)
+	}
+	// A slash in a description is escaped in the path.
+	code, body = do(t, ts, "GET", "/api/v1/services/web01/Disk%20%2Fvar", "", "")
+	var svc Service
+	if code != 200 || json.Unmarshal([]byte(body), &svc) != nil || svc.Description != "Disk /var" {
+		t.Errorf("GET service = %d %s", code, body)
+	}
+
+	code, body = do(t, ts, "GET", "/api/v1/comments", "", "")
+	var comments []Comment
+	if code != 200 || json.Unmarshal([]byte(body), &comments) != nil || len(comments) != 1 || comments[0].Comment != "rebooting" {
+		t.Errorf("GET comments = %d %s", code, body)
+	}
+	if code, body := do(t, ts, "GET", "/api/v1/downtimes", "", ""); code != 200 || strings.TrimSpace(body) != "[]" {
+		t.Errorf("GET downtimes = %d %s", code, body)
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	ts, _ := testServer(t, "")
+
+	// Listings are ordered by host name, then description, and each page
+	// links the next one until the last.
+	var got []string
+	path := "/api/v1/services?limit=2&fields=host_name,description"
+	for pages := 0; path != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("pagination did not end")
+		}
+		resp, err := ts.Client().Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var svcs []map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&svcs); err != nil || resp.StatusCode != 200 {
+			t.Fatalf("GET %s = %d %v", path, resp.StatusCode, err)
+		}
+		resp.Body.Close()
+		for _, svc := range svcs {
+			if len(svc) != 2 {
+				t.Errorf("fields not applied: %v", svc)
+			}
+			got = append(got, svc["host_name"].(string)+"/"+svc["description"].(string))
+		}
+		path = ""
+		if link := resp.Header.Get("Link"); link != "" {
+			path = strings.TrimPrefix(strings.TrimSuffix(link, `>; rel="next"`), "<")
+		}
+	}
+	if want := "db01/MySQL web01/Disk /var web01/HTTP"; strings.Join(got, " ") != want {
+		t.Errorf("paged services %q, want %q", strings.Join(got, " "), want)
+	}
+
+	for _, path := range []string{"/api/v1/hosts?limit=0", "/api/v1/hosts?cursor=!", "/api/v1/hosts?fields=name,nope",
+		"/api/v1/services?state=pending", "/api/v1/hosts/web01?fields=nope"} {
+		if code, body := do(t, ts, "GET", path, "", ""); code != 400 {
+			t.Errorf("GET %s = %d %s, want 400", path, code, body)
+		}
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	ts, _ := testServer(t, "")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/hosts?fields=name", `[{"name":"db01"},{"name":"web01"}]`},
+		{"/api/v1/hosts?state=down&fields=name", `[{"name":"web01"}]`},
+		{"/api/v1/hosts?hostgroup=web&state=0&fields=name", `[]`},
+		{"/api/v1/services?state=critical,warning&fields=description", `[{"description":"MySQL"},{"description":"HTTP"}]`},
+		{"/api/v1/services?hostgroup=web&state=2&fields=description,state", `[{"description":"HTTP","state":2}]`},
+		{"/api/v1/hosts/web01?fields=address", `{"address":"10.0.0.1"}`},
+		{"/api/v1/hosts?tag=env=prod,team&fields=name,tags", `[{"name":"web01","tags":["env=prod","team=web"]}]`},
+		{"/api/v1/hosts?tag=env&state=up&fields=name", `[{"name":"db01"}]`},
+		{"/api/v1/services?tag=team&fields=description", `[{"description":"MySQL"},{"description":"Disk /var"},{"description":"HTTP"}]`},
+		{"/api/v1/services?tag=env=prod,team=db&fields=description", `[{"description":"MySQL"}]`},
+	}
+	for _, tt := range tests {
+		if code, body := do(t, ts, "GET", tt.path, "", ""); code != 200 || strings.TrimSpace(body) != tt.want {
+			t.Errorf("GET %s = %d %s, want %s", tt.path, code, body, tt.want)
+		}
+	}
+	if code, _ := do(t, ts, "GET", "/api/v1/services?servicegroup=nope", "", ""); code != 404 {
+		t.Errorf("unknown servicegroup = %d, want 404", code)
+	}
+	if code, _ := do(t, ts, "GET", "/api/v1/hosts?tag=,", "", ""); code != 400 {
+		t.Errorf("empty tag selector = %d, want 400", code)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	ts, submitted := testServer(t, "")
+
+	tests := []struct {
+		path, body string
+		code       int
+		want       string
+	}{
+		{"/api/v1/services/web01/HTTP/acknowledge", `{"author":"alice","comment":"on it","sticky":true,"notify":true}`, 202,
+			"ACKNOWLEDGE_SVC_PROBLEM;web01;HTTP;2;1;0;alice;on it"},
+		{"/api/v1/hosts/web01/acknowledge", `{"author":"alice"}`, 400, ""},
+		{"/api/v1/hosts/web01/check", "", 202, "SCHEDULE_FORCED_HOST_CHECK;web01;1700000000"},
+		{"/api/v1/services/web01/HTTP/check", `{"time":1700000100}`, 202, "SCHEDULE_FORCED_SVC_CHECK;web01;HTTP;1700000100"},
+		{"/api/v1/hosts/web01/downtime", `{"end":1700003600,"author":"bob","comment":"patching"}`, 202,
+			"SCHEDULE_HOST_DOWNTIME;web01;1700000000;1700003600;1;0;0;bob;patching"},
+		{"/api/v1/services/web01/HTTP/downtime", `{"end":1700003600,"fixed":false,"author":"bob","comment":"x"}`, 400, ""},
+		{"/api/v1/services/web01/SSH/check", "", 404, ""},
+		{"/api/v1/hosts/web01/check", "{", 400, ""},
+	}
+	for _, tt := range tests {
+		*submitted = nil
+		code, body := do(t, ts, "POST", tt.path, tt.body, "")
+		if code != tt.code {
+			t.Errorf("POST %s = %d %s, want %d", tt.path, code, body, tt.code)
+			continue
+		}
+		if tt.want == "" {
+			if len(*submitted) != 0 {
+				t.Errorf("POST %s submitted %v", tt.path, *submitted)
+			}
+		} else if len(*submitted) != 1 || (*submitted)[0] != tt.want {
+			t.Errorf("POST %s submitted %v, want %q", tt.path, *submitted, tt.want)
+		}
+	}
+
+	if code, _ := do(t, ts, "GET", "/api/v1/hosts/web01/check", "", ""); code != 405 {
+		t.Errorf("GET on a command = %d, want 405", code)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, submitted := testServer(t, string(hash))
+
+	if code, _ := do(t, ts, "GET", "/api/v1/hosts", "", ""); code != 401 {
+		t.Errorf("no token = %d, want 401", code)
+	}
+	if code, _ := do(t, ts, "POST", "/api/v1/hosts/web01/check", "", "wrong"); code != 401 || len(*submitted) != 0 {
+		t.Errorf("wrong token = %d, submitted %v", code, *submitted)
+	}
+	if code, _ := do(t, ts, "GET", "/api/v1/hosts", "", "s3cret"); code != 200 {
+		t.Errorf("valid token = %d, want 200", code)
+	}
+}
@@ -0,0 +1,153 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// BuiltinCheck is a check implemented inside the daemon. args excludes the
+// program name. It returns a plugin return code and plugin output
+// (including any "|perfdata").
+type BuiltinCheck func(ctx context.Context, args []string) (int, string)
+
+var (
+	builtinMu     sync.RWMutex
+	builtinChecks = map[string]BuiltinCheck{}
+)
+
+// RegisterBuiltin makes a check available under name. A command line whose
+// program basename equals name runs in-process instead of being forked.
+func RegisterBuiltin(name string, check BuiltinCheck) {
+	builtinMu.Lock()
+	builtinChecks[name] = check
+	builtinMu.Unlock()
+}
+
+// LookupBuiltin parses a command line and returns the builtin check it
+// invokes along with its arguments.
+func LookupBuiltin(command string) (BuiltinCheck, []string, bool) {
+	trimmed := strings.TrimSpace(command)
+	end := strings.IndexAny(trimmed, " \t")
+	if end < 0 {
+		end = len(trimmed)
+	}
+	builtinMu.RLock()
+	check := builtinChecks[filepath.Base(trimmed[:end])]
+	builtinMu.RUnlock()
+	if check == nil {
+		return nil, nil, false
+	}
+	args, ok := shellSplit(trimmed)
+	if !ok {
+		return nil, nil, false
+	}
+	return check, args[1:], true
+}
+
+// runBuiltin runs job in-process if its command names a builtin check.
+// Returns nil when the command is not a builtin.
+func runBuiltin(job checkJob) *objects.CheckResult {
+	check, args, ok := LookupBuiltin(job.command)
+	if !ok {
+		return nil
+	}
+	cr := &objects.CheckResult{
+		HostName:           job.hostName,
+		ServiceDescription: job.svcDesc,
+		CheckType:          job.checkType,
+		CheckOptions:       job.checkOptions,
+		Latency:            job.latency,
+		ExitedOK:           true,
+		Source:             localSource,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), job.timeout)
+	defer cancel()
+
+	cr.StartTime = time.Now()
+	cr.ReturnCode, cr.Output = check(ctx, args)
+	cr.FinishTime = time.Now()
+	cr.ExecutionTime = cr.FinishTime.Sub(cr.StartTime).Seconds()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		cr.EarlyTimeout = true
+		cr.ReturnCode = 2
+		cr.Output = fmt.Sprintf("(Check timed out after %.0f seconds)", job.timeout.Seconds())
+	}
+	return cr
+}
+
+// worstState returns the more severe of two service states
+// (CRITICAL > WARNING > UNKNOWN > OK).
+func worstState(a, b int) int {
+	rank := func(s int) int {
+		switch s {
+		case objects.ServiceCritical:
+			return 3
+		case objects.ServiceWarning:
+			return 2
+		case objects.ServiceUnknown:
+			return 1
+		}
+		return 0
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// builtinFlags is a small getopt-style parser for builtin check arguments.
+// Short options take their value either attached (-H1.2.3.4) or as the next
+// argument; long options use --name=value or --name value.
+type builtinFlags struct {
+	args []string
+	i    int
+}
+
+// next returns the next option name and, if the option takes a value per
+// hasValue, its value. ok is false when arguments are exhausted; err is
+// non-empty on a malformed argument.
+func (f *builtinFlags) next(hasValue func(name string) bool) (name, val string, ok bool, err string) {
+	if f.i >= len(f.args) {
+		return "", "", false, ""
+	}
+	arg := f.args[f.i]
+	f.i++
+	if len(arg) < 2 || arg[0] != '-' {
+		return "", "", false, "unexpected argument '" + arg + "'"
+	}
+	attached, hasAttached := "", false
+	if strings.HasPrefix(arg, "--") {
+		name = arg
+		if eq := strings.IndexByte(arg, '='); eq > 0 {
+			name, attached, hasAttached = arg[:eq], arg[eq+1:], true
+		}
+	} else {
+		name = arg[:2]
+		if len(arg) > 2 {
+			attached, hasAttached = arg[2:], true
+		}
+	}
+	if !hasValue(name) {
+		if hasAttached {
+			return "", "", false, "option " + name + " does not take a value"
+		}
+		return name, "", true, ""
+	}
+	if hasAttached {
+		return name, attached, true, ""
+	}
+	if f.i >= len(f.args) {
+		return "", "", false, "option " + name + " requires a value"
+	}
+	val = f.args[f.i]
+	f.i++
+	return name, val, true, ""
+}
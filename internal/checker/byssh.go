@@ -0,0 +1,233 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BySSHCommand is a check_by_ssh invocation that can be run natively over the
+// SSH runner's pooled connections instead of forking the plugin.
+type BySSHCommand struct {
+	Host         string
+	Port         int // 0 = runner default
+	User         string
+	Command      string
+	Timeout      time.Duration // 0 = check timeout
+	SkipStdout   int           // leading stdout lines to drop (-S); -1 = all
+	SkipStderr   int           // leading stderr lines to ignore (-E); -1 = all
+	WarnOnStderr bool          // stderr output makes the check WARNING, not UNKNOWN (-W)
+}
+
+// ErrNotBySSH is returned by ParseBySSH for a command line that does not
+// invoke check_by_ssh, or that needs a shell to run.
+var ErrNotBySSH = errors.New("not a plain check_by_ssh command line")
+
+// ParseBySSH recognizes a command line that invokes check_by_ssh and extracts
+// the remote host, port, user and command. Only the options that map onto a
+// plain remote exec are accepted. Any other option (ssh client options,
+// passive mode, multiple commands, per-check identity files, ...) returns an
+// error naming it, and the real plugin should be forked instead.
+func ParseBySSH(cmdline string) (*BySSHCommand, error) {
+	args, ok := shellSplit(cmdline)
+	if !ok || len(args) == 0 || filepath.Base(args[0]) != "check_by_ssh" {
+		return nil, ErrNotBySSH
+	}
+	bs := &BySSHCommand{}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		name, val, hasVal := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			if eq := strings.IndexByte(arg, '='); eq > 0 {
+				name, val, hasVal = arg[:eq], arg[eq+1:], true
+			}
+		} else if len(arg) > 2 && arg[0] == '-' {
+			name, val, hasVal = arg[:2], arg[2:], true
+		}
+		next := func() (string, error) {
+			if hasVal {
+				return val, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("check_by_ssh: option %s needs a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+		// optional reads the optional line count of -E and -S, which like
+		// getopt's optional arguments must be attached: -E2 or --skip-stderr=2.
+		optional := func() (int, error) {
+			if !hasVal {
+				return -1, nil
+			}
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("check_by_ssh: invalid line count %q for %s", val, name)
+			}
+			return n, nil
+		}
+
+		var err error
+		switch name {
+		case "-H", "--hostname":
+			bs.Host, err = next()
+		case "-C", "--command":
+			bs.Command, err = next()
+		case "-l", "--logname":
+			bs.User, err = next()
+		case "-p", "--port":
+			var v string
+			if v, err = next(); err == nil {
+				if bs.Port, err = strconv.Atoi(v); err != nil {
+					err = fmt.Errorf("check_by_ssh: invalid port %q", v)
+				}
+			}
+		case "-t", "--timeout":
+			var v string
+			if v, err = next(); err == nil {
+				var secs int
+				if secs, err = strconv.Atoi(v); err != nil {
+					err = fmt.Errorf("check_by_ssh: invalid timeout %q", v)
+				}
+				bs.Timeout = time.Duration(secs) * time.Second
+			}
+		case "-E", "--skip-stderr":
+			bs.SkipStderr, err = optional()
+		case "-S", "--skip-stdout":
+			bs.SkipStdout, err = optional()
+		case "-W", "--warn-on-stderr":
+			bs.WarnOnStderr = true
+		case "-q", "--quiet", "-U", "--unknown-timeout":
+			// ssh's own warnings are never part of the output, and
+			// connection failures are always UNKNOWN
+		default:
+			return nil, fmt.Errorf("check_by_ssh: option %s is not supported natively", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if bs.Host == "" || bs.Command == "" {
+		return nil, errors.New("check_by_ssh: -H and -C are required")
+	}
+	return bs, nil
+}
+
+// result turns the remote command's output and exit status into the check
+// output and return code the way check_by_ssh does: skipped stdout lines are
+// dropped, and stderr output left after skipping replaces the output with
+// its first line and raises the state to at least UNKNOWN, or WARNING with
+// -W (a CRITICAL or WARNING exit status is kept).
+func (bs *BySSHCommand) result(stdout, stderr string, code int) (string, int) {
+	if errLines := splitLines(stderr); bs.SkipStderr >= 0 && len(errLines) > bs.SkipStderr {
+		floor := 3
+		if bs.WarnOnStderr {
+			floor = 1
+		}
+		return "Remote command execution failed: " + errLines[bs.SkipStderr], maxStateAlt(code, floor)
+	}
+	switch {
+	case bs.SkipStdout < 0:
+		stdout = ""
+	case bs.SkipStdout > 0:
+		lines := splitLines(stdout)
+		if bs.SkipStdout >= len(lines) {
+			stdout = ""
+		} else {
+			stdout = strings.Join(lines[bs.SkipStdout:], "\n")
+		}
+	}
+	return stdout, code
+}
+
+// maxStateAlt returns the worse of two plugin states, ranking
+// OK < UNKNOWN < WARNING < CRITICAL as the plugins' max_state_alt does.
+func maxStateAlt(a, b int) int {
+	rank := func(s int) int {
+		switch s {
+		case 2:
+			return 3
+		case 1:
+			return 2
+		case 3:
+			return 1
+		}
+		return 0
+	}
+	if rank(a) >= rank(b) {
+		return a
+	}
+	return b
+}
+
+// splitLines splits s into lines without a trailing empty one.
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// shellSplit splits a command line into words using POSIX shell quoting
+// rules for single quotes, double quotes and backslashes. It reports false
+// for anything that needs a real shell (unterminated quotes, expansions,
+// pipes, redirects, command separators).
+func shellSplit(s string) ([]string, bool) {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, false
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '$' || s[i] == '`' {
+					return nil, false
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, false
+			}
+			inWord = true
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, false
+			}
+			i++
+			cur.WriteByte(s[i])
+			inWord = true
+		case strings.IndexByte("|&;<>()$`*?[#~", c) >= 0:
+			return nil, false
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, true
+}
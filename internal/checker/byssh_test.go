@@ -0,0 +1,148 @@
+package checker
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestParseBySSH(t *testing.T) {
+	bs, err := ParseBySSH(`/usr/lib/nagios/plugins/check_by_ssh -H 10.0.0.5 -p 2222 -l monitor -t 20 -q -C "/usr/lib/nagios/plugins/check_disk -w 20% -c 10% -p /"`)
+	if err != nil {
+		t.Fatalf("expected check_by_ssh command line to parse: %v", err)
+	}
+	if bs.Host != "10.0.0.5" || bs.Port != 2222 || bs.User != "monitor" || bs.Timeout != 20*time.Second {
+		t.Errorf("unexpected parse: %+v", bs)
+	}
+	if bs.Command != "/usr/lib/nagios/plugins/check_disk -w 20% -c 10% -p /" {
+		t.Errorf("unexpected remote command %q", bs.Command)
+	}
+
+	bs, err = ParseBySSH(`check_by_ssh --hostname=db1 --command='uptime'`)
+	if err != nil || bs.Host != "db1" || bs.Command != "uptime" {
+		t.Errorf("long options: got %+v err=%v", bs, err)
+	}
+
+	bs, err = ParseBySSH(`check_by_ssh -H db1 -C uptime -E2 -S -W`)
+	if err != nil || bs.SkipStderr != 2 || bs.SkipStdout != -1 || !bs.WarnOnStderr {
+		t.Errorf("output options: got %+v err=%v", bs, err)
+	}
+}
+
+func TestParseBySSHNotBySSH(t *testing.T) {
+	cases := []string{
+		`/usr/lib/nagios/plugins/check_ping -H 10.0.0.5 -w 100,20% -c 500,60%`,
+		`check_by_ssh -H host -C "uptime" | tee /tmp/x`, // needs a shell
+		`check_by_ssh -H $HOST -C "echo $PATH"`,         // unexpanded variable
+	}
+	for _, c := range cases {
+		if bs, err := ParseBySSH(c); !errors.Is(err, ErrNotBySSH) {
+			t.Errorf("%q: expected ErrNotBySSH, got %+v, %v", c, bs, err)
+		}
+	}
+}
+
+func TestParseBySSHRejectsUnsupportedOptions(t *testing.T) {
+	cases := map[string]string{
+		`check_by_ssh -H host -o StrictHostKeyChecking=no -C uptime`:  "-o",
+		`check_by_ssh -H host -C uptime -O /var/spool/results -s svc`: "-O",
+		`check_by_ssh -H host -i /etc/other_key -C uptime`:            "-i",
+		`check_by_ssh -4 -H host -C uptime`:                           "-4",
+		`check_by_ssh -H host -C uptime -v`:                           "-v",
+		`check_by_ssh -H host`:                                        "required",
+		`check_by_ssh -H host -p ssh -C uptime`:                       "port",
+		`check_by_ssh -H host -C`:                                     "-C",
+	}
+	for c, want := range cases {
+		bs, err := ParseBySSH(c)
+		if err == nil || errors.Is(err, ErrNotBySSH) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error mentioning %q, got %+v, %v", c, want, bs, err)
+		}
+	}
+}
+
+func TestBySSHResult(t *testing.T) {
+	cases := []struct {
+		name       string
+		bs         BySSHCommand
+		stdout     string
+		stderr     string
+		code       int
+		wantOutput string
+		wantCode   int
+	}{
+		{"clean", BySSHCommand{}, "OK - fine\n", "", 0, "OK - fine\n", 0},
+		{"stderr makes OK unknown", BySSHCommand{}, "OK - fine\n", "bash: warning\n", 0,
+			"Remote command execution failed: bash: warning", 3},
+		{"stderr keeps critical", BySSHCommand{}, "CRITICAL\n", "oops\n", 2,
+			"Remote command execution failed: oops", 2},
+		{"warn on stderr", BySSHCommand{WarnOnStderr: true}, "", "oops\n", 3,
+			"Remote command execution failed: oops", 1},
+		{"skip some stderr", BySSHCommand{SkipStderr: 1}, "OK\n", "motd\nreal error\n", 0,
+			"Remote command execution failed: real error", 3},
+		{"skip all stderr", BySSHCommand{SkipStderr: -1}, "OK\n", "motd\n", 0, "OK\n", 0},
+		{"skip stdout", BySSHCommand{SkipStdout: 1}, "banner\nOK - fine\n", "", 0, "OK - fine", 0},
+	}
+	for _, c := range cases {
+		output, code := c.bs.result(c.stdout, c.stderr, c.code)
+		if output != c.wantOutput || code != c.wantCode {
+			t.Errorf("%s: got %q, %d; want %q, %d", c.name, output, code, c.wantOutput, c.wantCode)
+		}
+	}
+}
+
+// TestBySSHTransportFailureIsUnknown runs native check_by_ssh against a
+// closed port and against a server that is not SSH; both are UNKNOWN.
+func TestBySSHTransportFailureIsUnknown(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	garbage, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer garbage.Close()
+	go func() {
+		for {
+			conn, err := garbage.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+	garbagePort := garbage.Addr().(*net.TCPAddr).Port
+
+	resultCh := make(chan *objects.CheckResult, 2)
+	e, err := NewSSHExecutor(2, resultCh, SSHConfig{KeyFile: writeTestKey(t), InsecureIgnoreHostKey: true, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	for _, port := range []int{closedPort, garbagePort} {
+		bs, err := ParseBySSH("check_by_ssh -H 127.0.0.1 -p " + strconv.Itoa(port) + " -C uptime")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.SubmitBySSH(bs, "web01", "Uptime", 10*time.Second, 0, objects.CheckTypeActive, 0)
+		select {
+		case cr := <-resultCh:
+			if cr.ReturnCode != 3 || cr.ExitedOK || !strings.HasPrefix(cr.Output, "SSH connection failed: ") {
+				t.Errorf("port %d: got code %d exited_ok %v output %q, want UNKNOWN connection failure", port, cr.ReturnCode, cr.ExitedOK, cr.Output)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("port %d: no result", port)
+		}
+	}
+}
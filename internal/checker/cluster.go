@@ -0,0 +1,260 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// NewClusterCheck returns the gogios_cluster builtin, which reads member
+// states from store. Register it once the store is loaded:
+//
+//	checker.RegisterBuiltin("gogios_cluster", checker.NewClusterCheck(store))
+//
+// It works like check_cluster, but evaluates the current states directly
+// instead of taking them as $HOSTSTATEID$ / $SERVICESTATEID$ arguments:
+//
+//	--host | --service    cluster of hosts (default) or of services
+//	-l label              name used in the output
+//	-m members            comma-separated host names, or host:service pairs
+//	-g group              hostgroup (--host) or servicegroup (--service)
+//	-w range  -c range    thresholds on the number of members not UP / OK
+//
+// Ranges use the plugin threshold syntax: "2" alerts above 2, "2:" below 2,
+// "1:3" outside 1..3, "@1:3" inside it. Members that have not been checked
+// yet are counted as pending and never as problems.
+func NewClusterCheck(store *objects.ObjectStore) BuiltinCheck {
+	return func(ctx context.Context, args []string) (int, string) {
+		o, err := parseClusterCheckArgs(args)
+		if err != "" {
+			return objects.ServiceUnknown, "CLUSTER UNKNOWN - " + err
+		}
+		store.Mu.RLock()
+		counts, err := o.count(store)
+		store.Mu.RUnlock()
+		if err != "" {
+			return objects.ServiceUnknown, "CLUSTER UNKNOWN - " + err
+		}
+		return o.evaluate(counts)
+	}
+}
+
+type clusterCheckOpts struct {
+	services   bool
+	label      string
+	members    []string
+	group      string
+	warn, crit *thresholdRange
+}
+
+// clusterCounts holds the number of members in each state, indexed by
+// host or service state, plus those still pending.
+type clusterCounts struct {
+	states  [4]int
+	pending int
+}
+
+func parseClusterCheckArgs(args []string) (*clusterCheckOpts, string) {
+	o := &clusterCheckOpts{}
+	noValue := map[string]bool{"--host": true, "-h": true, "--service": true, "-s": true}
+	f := &builtinFlags{args: args}
+	for {
+		name, val, ok, err := f.next(func(n string) bool { return !noValue[n] })
+		if err != "" {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		switch name {
+		case "-h", "--host":
+			o.services = false
+		case "-s", "--service":
+			o.services = true
+		case "-l", "--label":
+			o.label = val
+		case "-m", "--members":
+			for _, m := range strings.Split(val, ",") {
+				if m = strings.TrimSpace(m); m != "" {
+					o.members = append(o.members, m)
+				}
+			}
+		case "-g", "--group":
+			o.group = val
+		case "-w", "--warning", "-c", "--critical":
+			r, rerr := parseThresholdRange(val)
+			if rerr != "" {
+				return nil, rerr
+			}
+			if name == "-w" || name == "--warning" {
+				o.warn = r
+			} else {
+				o.crit = r
+			}
+		default:
+			return nil, "unknown option " + name
+		}
+	}
+	if len(o.members) == 0 && o.group == "" {
+		return nil, "no members given (use -m or -g)"
+	}
+	if o.label == "" {
+		o.label = o.group
+		if o.label == "" {
+			o.label = "Cluster"
+		}
+	}
+	return o, ""
+}
+
+// count tallies the member states. The caller holds the store's read lock.
+func (o *clusterCheckOpts) count(store *objects.ObjectStore) (clusterCounts, string) {
+	var c clusterCounts
+	add := func(checked bool, state int) {
+		switch {
+		case !checked:
+			c.pending++
+		case state >= 0 && state < len(c.states):
+			c.states[state]++
+		}
+	}
+	if o.services {
+		var members []*objects.Service
+		if o.group != "" {
+			sg := store.GetServiceGroup(o.group)
+			if sg == nil {
+				return c, fmt.Sprintf("servicegroup '%s' is not defined", o.group)
+			}
+			members = append(members, sg.Members...)
+		}
+		for _, m := range o.members {
+			host, desc, ok := strings.Cut(m, ":")
+			if !ok {
+				return c, fmt.Sprintf("service member '%s' is not host:service", m)
+			}
+			svc := store.GetService(host, desc)
+			if svc == nil {
+				return c, fmt.Sprintf("service '%s' on host '%s' is not defined", desc, host)
+			}
+			members = append(members, svc)
+		}
+		for _, svc := range members {
+			add(svc.HasBeenChecked, svc.CurrentState)
+		}
+		return c, ""
+	}
+	var members []*objects.Host
+	if o.group != "" {
+		hg := store.GetHostGroup(o.group)
+		if hg == nil {
+			return c, fmt.Sprintf("hostgroup '%s' is not defined", o.group)
+		}
+		members = append(members, hg.Members...)
+	}
+	for _, m := range o.members {
+		h := store.GetHost(m)
+		if h == nil {
+			return c, fmt.Sprintf("host '%s' is not defined", m)
+		}
+		members = append(members, h)
+	}
+	for _, h := range members {
+		add(h.HasBeenChecked, h.CurrentState)
+	}
+	return c, ""
+}
+
+func (o *clusterCheckOpts) evaluate(c clusterCounts) (int, string) {
+	problems := 0
+	for state, n := range c.states {
+		if state != 0 {
+			problems += n
+		}
+	}
+	state := objects.ServiceOK
+	switch {
+	case o.crit != nil && o.crit.alert(problems):
+		state = objects.ServiceCritical
+	case o.warn != nil && o.warn.alert(problems):
+		state = objects.ServiceWarning
+	}
+	label := map[int]string{
+		objects.ServiceOK:       "CLUSTER OK",
+		objects.ServiceWarning:  "CLUSTER WARNING",
+		objects.ServiceCritical: "CLUSTER CRITICAL",
+	}[state]
+
+	var names []string
+	if o.services {
+		names = []string{"ok", "warning", "critical", "unknown"}
+	} else {
+		names = []string{"up", "down", "unreachable"}
+	}
+	var parts, perf []string
+	for i, name := range names {
+		parts = append(parts, fmt.Sprintf("%d %s", c.states[i], name))
+		perf = append(perf, fmt.Sprintf("%s=%d", name, c.states[i]))
+	}
+	parts = append(parts, fmt.Sprintf("%d pending", c.pending))
+	perf = append(perf, fmt.Sprintf("pending=%d", c.pending),
+		fmt.Sprintf("problems=%d;%s;%s;0", problems, o.warn.String(), o.crit.String()))
+	return state, fmt.Sprintf("%s: %s: %s |%s", label, o.label, strings.Join(parts, ", "), strings.Join(perf, " "))
+}
+
+// thresholdRange is a plugin threshold range. A value alerts when it lies
+// outside lo..hi, or inside it when inside is set.
+type thresholdRange struct {
+	spec   string
+	lo, hi float64
+	inside bool
+}
+
+func parseThresholdRange(spec string) (*thresholdRange, string) {
+	r := &thresholdRange{spec: spec, hi: math.Inf(1)}
+	s := spec
+	if strings.HasPrefix(s, "@") {
+		r.inside = true
+		s = s[1:]
+	}
+	lo, hi, hasColon := strings.Cut(s, ":")
+	if !hasColon {
+		lo, hi = "0", s
+	}
+	var err error
+	switch lo {
+	case "~":
+		r.lo = math.Inf(-1)
+	case "":
+		r.lo = 0
+	default:
+		if r.lo, err = strconv.ParseFloat(lo, 64); err != nil {
+			return nil, "invalid threshold '" + spec + "'"
+		}
+	}
+	if hi != "" {
+		if r.hi, err = strconv.ParseFloat(hi, 64); err != nil {
+			return nil, "invalid threshold '" + spec + "'"
+		}
+	}
+	if r.lo > r.hi {
+		return nil, "invalid threshold '" + spec + "': start is above end"
+	}
+	return r, ""
+}
+
+func (r *thresholdRange) alert(v int) bool {
+	in := float64(v) >= r.lo && float64(v) <= r.hi
+	return in == r.inside
+}
+
+// String returns the range as given, or "" for a nil range, for perfdata.
+func (r *thresholdRange) String() string {
+	if r == nil {
+		return ""
+	}
+	return r.spec
+}
@@ -0,0 +1,47 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestClusterCheck(t *testing.T) {
+	store := objects.NewObjectStore()
+	web := &objects.HostGroup{Name: "web"}
+	store.AddHostGroup(web)
+	for i, state := range []int{objects.HostUp, objects.HostDown, objects.HostUp, objects.HostUp} {
+		h := &objects.Host{Name: "web-" + string(rune('1'+i)), CurrentState: state, HasBeenChecked: i != 3}
+		store.AddHost(h)
+		web.Members = append(web.Members, h)
+	}
+	db1, db2 := store.GetHost("web-1"), store.GetHost("web-2")
+	store.AddService(&objects.Service{Host: db1, Description: "PgSQL", HasBeenChecked: true, CurrentState: objects.ServiceCritical})
+	store.AddService(&objects.Service{Host: db2, Description: "PgSQL", HasBeenChecked: true, CurrentState: objects.ServiceWarning})
+	check := NewClusterCheck(store)
+
+	tests := []struct {
+		args  []string
+		state int
+		want  string
+	}{
+		{[]string{"-g", "web", "-w", "0", "-c", "2"}, objects.ServiceWarning,
+			"CLUSTER WARNING: web: 2 up, 1 down, 0 unreachable, 1 pending |up=2 down=1 unreachable=0 pending=1 problems=1;0;2;0"},
+		{[]string{"-l", "Frontends", "-m", "web-1,web-3", "-c", "0"}, objects.ServiceOK, "CLUSTER OK: Frontends: 2 up,"},
+		{[]string{"--service", "-l", "Postgres", "-m", "web-1:PgSQL,web-2:PgSQL", "-w", "@1:1", "-c", "~:1"}, objects.ServiceCritical,
+			"CLUSTER CRITICAL: Postgres: 0 ok, 1 warning, 1 critical, 0 unknown"},
+		{[]string{"--service", "-m", "web-1:PgSQL", "-w", "1:"}, objects.ServiceOK, "CLUSTER OK: Cluster:"},
+		{[]string{"-m", "web-9"}, objects.ServiceUnknown, "host 'web-9' is not defined"},
+		{[]string{"--service", "-m", "web-1"}, objects.ServiceUnknown, "is not host:service"},
+		{[]string{"-w", "1"}, objects.ServiceUnknown, "no members given"},
+		{[]string{"-m", "web-1", "-w", "3:1"}, objects.ServiceUnknown, "start is above end"},
+	}
+	for _, tt := range tests {
+		state, out := check(context.Background(), tt.args)
+		if state != tt.state || !strings.Contains(out, tt.want) {
+			t.Errorf("%v: got %d %q, want %d containing %q", tt.args, state, out, tt.state, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// ConcurrencyClassCustomVar names the concurrency class of a service's or
+// host's checks. A service variable overrides the same variable on its
+// host, and both override the check command's concurrency_class.
+const ConcurrencyClassCustomVar = "CHECK_CONCURRENCY_CLASS"
+
+// ConcurrencyClassFor returns the concurrency class of a check on h (svc
+// is nil for host checks) running cmd, or "" for none.
+func ConcurrencyClassFor(h *objects.Host, svc *objects.Service, cmd *objects.Command) string {
+	if svc != nil {
+		if v, ok := svc.CustomVars[ConcurrencyClassCustomVar]; ok {
+			return strings.ToLower(strings.TrimSpace(v))
+		}
+	}
+	if h != nil {
+		if v, ok := h.CustomVars[ConcurrencyClassCustomVar]; ok {
+			return strings.ToLower(strings.TrimSpace(v))
+		}
+	}
+	if cmd != nil {
+		return strings.ToLower(strings.TrimSpace(cmd.ConcurrencyClass))
+	}
+	return ""
+}
+
+// ParseConcurrencyClasses parses check_concurrency_classes, a
+// comma-separated list of name:limit pairs such as "vmware-api:4,ipmi:16".
+func ParseConcurrencyClasses(s string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, limit, ok := strings.Cut(pair, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !ok || name == "" || err != nil || n < 1 {
+			return nil, fmt.Errorf("check_concurrency_classes: invalid entry '%s' (want name:limit, limit at least 1)", pair)
+		}
+		limits[name] = n
+	}
+	return limits, nil
+}
+
+// ConcurrencyClasses caps how many checks of each class run at once, across
+// every runner it is given to, so a fragile backend such as a vCenter API
+// sees a few checks at a time instead of the whole worker pool. Checks of a
+// class at its limit are held back, not failed, and run in submission order
+// as running ones finish; the time held counts as latency. Checks without
+// a class, or of a class with no limit, are not affected.
+type ConcurrencyClasses struct {
+	// ClassOf returns the class of a check. Runner workers call it without
+	// the object store lock, so it should read a snapshot.
+	ClassOf func(hostName, svcDesc string) string
+
+	mu      sync.Mutex
+	limits  map[string]int
+	running map[string]int
+	waiting map[string][]heldJob
+}
+
+type heldJob struct {
+	job  checkJob
+	held time.Time
+}
+
+// ConcurrencyClassStats describes one class for status output.
+type ConcurrencyClassStats struct {
+	Name    string
+	Limit   int
+	Running int
+	Waiting int
+}
+
+// NewConcurrencyClasses returns a limiter for the given class limits.
+func NewConcurrencyClasses(limits map[string]int, classOf func(hostName, svcDesc string) string) *ConcurrencyClasses {
+	return &ConcurrencyClasses{
+		ClassOf: classOf,
+		limits:  limits,
+		running: make(map[string]int),
+		waiting: make(map[string][]heldJob),
+	}
+}
+
+// run calls runFn for job unless its class is at its limit, in which case
+// job is held. When a check of a class finishes, the worker goes on to run
+// the class's oldest held check, if any. A nil receiver runs job directly.
+func (c *ConcurrencyClasses) run(job checkJob, runFn func(checkJob)) {
+	class, ok := c.acquire(job)
+	if !ok {
+		return
+	}
+	for {
+		runFn(job)
+		if class == "" {
+			return
+		}
+		if job, ok = c.release(class); !ok {
+			return
+		}
+	}
+}
+
+// acquire counts job as running in its class and returns the class, or
+// holds job and returns false when the class is full. Jobs outside a
+// limited class return "".
+func (c *ConcurrencyClasses) acquire(job checkJob) (string, bool) {
+	if c == nil || c.ClassOf == nil {
+		return "", true
+	}
+	class := c.ClassOf(job.hostName, job.svcDesc)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	limit, ok := c.limits[class]
+	if !ok {
+		return "", true
+	}
+	if c.running[class] >= limit {
+		c.waiting[class] = append(c.waiting[class], heldJob{job: job, held: time.Now()})
+		return "", false
+	}
+	c.running[class]++
+	return class, true
+}
+
+// release ends a running check of class. If a check of the class is held,
+// it takes over the slot and is returned for the caller to run.
+func (c *ConcurrencyClasses) release(class string) (checkJob, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if q := c.waiting[class]; len(q) > 0 {
+		next := q[0]
+		q[0] = heldJob{}
+		if len(q) == 1 {
+			delete(c.waiting, class)
+		} else {
+			c.waiting[class] = q[1:]
+		}
+		next.job.latency += time.Since(next.held).Seconds()
+		return next.job, true
+	}
+	c.running[class]--
+	return checkJob{}, false
+}
+
+// Stats returns every class with its limit and current load, by name.
+func (c *ConcurrencyClasses) Stats() []ConcurrencyClassStats {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := make([]ConcurrencyClassStats, 0, len(c.limits))
+	for name, limit := range c.limits {
+		stats = append(stats, ConcurrencyClassStats{
+			Name:    name,
+			Limit:   limit,
+			Running: c.running[name],
+			Waiting: len(c.waiting[name]),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
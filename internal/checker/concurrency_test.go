@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestParseConcurrencyClasses(t *testing.T) {
+	limits, err := ParseConcurrencyClasses("VMware-API:4, ipmi:16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits["vmware-api"] != 4 || limits["ipmi"] != 16 || len(limits) != 2 {
+		t.Errorf("got %v", limits)
+	}
+	for _, bad := range []string{"vmware", "vmware:0", ":4", "vmware:x"} {
+		if _, err := ParseConcurrencyClasses(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestConcurrencyClassFor(t *testing.T) {
+	cmd := &objects.Command{Name: "check_vmware", ConcurrencyClass: "vmware-api"}
+	h := &objects.Host{Name: "esx1", CustomVars: map[string]string{}}
+	svc := &objects.Service{Host: h, Description: "datastore", CustomVars: map[string]string{}}
+	if c := ConcurrencyClassFor(h, svc, cmd); c != "vmware-api" {
+		t.Errorf("expected the command's class, got %q", c)
+	}
+	h.CustomVars[ConcurrencyClassCustomVar] = "esx"
+	if c := ConcurrencyClassFor(h, svc, cmd); c != "esx" {
+		t.Errorf("expected the host's class, got %q", c)
+	}
+	svc.CustomVars[ConcurrencyClassCustomVar] = "Datastore"
+	if c := ConcurrencyClassFor(h, svc, cmd); c != "datastore" {
+		t.Errorf("expected the service's class, got %q", c)
+	}
+}
+
+func TestConcurrencyClassesLimitRunningChecks(t *testing.T) {
+	classes := NewConcurrencyClasses(map[string]int{"api": 2}, func(hostName, svcDesc string) string {
+		if hostName == "api-host" {
+			return "api"
+		}
+		return ""
+	})
+
+	var running, peak, ran atomic.Int64
+	var otherRan atomic.Int64
+	runFn := func(job checkJob) {
+		if job.hostName != "api-host" {
+			otherRan.Add(1)
+			return
+		}
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		ran.Add(1)
+	}
+
+	// Eight workers pick up ten class checks and one unclassed check.
+	jobs := make(chan checkJob, 11)
+	for i := 0; i < 10; i++ {
+		jobs <- checkJob{hostName: "api-host"}
+	}
+	jobs <- checkJob{hostName: "other"}
+	close(jobs)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				classes.run(job, runFn)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 class checks at once, saw %d", peak.Load())
+	}
+	if ran.Load() != 10 || otherRan.Load() != 1 {
+		t.Errorf("expected every check to run, got %d class and %d other", ran.Load(), otherRan.Load())
+	}
+	st := classes.Stats()
+	if len(st) != 1 || st[0].Running != 0 || st[0].Waiting != 0 || st[0].Limit != 2 {
+		t.Errorf("expected an idle class after the run, got %+v", st)
+	}
+}
+
+func TestConcurrencyClassesNil(t *testing.T) {
+	var classes *ConcurrencyClasses
+	called := false
+	classes.run(checkJob{hostName: "h"}, func(checkJob) { called = true })
+	if !called {
+		t.Error("a nil limiter should run the check directly")
+	}
+}
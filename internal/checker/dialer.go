@@ -0,0 +1,84 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxBuiltinDials bounds the connections the network builtins have open at
+// once, across all checks. Checks past it wait for a slot, within their
+// timeout, instead of exhausting file descriptors in a check storm.
+const maxBuiltinDials = 512
+
+// builtinDialer is shared by gogios_http, gogios_tcp and gogios_dns.
+var builtinDialer = newDialPool(maxBuiltinDials)
+
+// dialPool is a dialer with a fixed number of connection slots.
+type dialPool struct {
+	d     net.Dialer
+	slots chan struct{}
+}
+
+func newDialPool(size int) *dialPool {
+	return &dialPool{
+		// Checks open one connection and close it, so TCP keepalives only
+		// cost packets.
+		d:     net.Dialer{KeepAlive: -1},
+		slots: make(chan struct{}, size),
+	}
+}
+
+// acquire takes a slot, waiting until one is free or ctx is done. The
+// returned function gives it back.
+func (p *dialPool) acquire(ctx context.Context) (func(), error) {
+	select {
+	case p.slots <- struct{}{}:
+		return sync.OnceFunc(func() { <-p.slots }), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// DialContext connects to addr in a slot that is given back when the
+// connection is closed.
+func (p *dialPool) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.d.DialContext(ctx, network, addr)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &pooledConn{Conn: c, release: release}, nil
+}
+
+type pooledConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *pooledConn) Close() error {
+	err := c.Conn.Close()
+	c.release()
+	return err
+}
+
+// withCheckTimeout applies a -t option of secs to ctx. The executor's check
+// timeout already bounds ctx; the shorter of the two wins.
+func withCheckTimeout(ctx context.Context, secs string) (context.Context, context.CancelFunc, string) {
+	if secs == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, ""
+	}
+	n, err := strconv.ParseFloat(secs, 64)
+	if err != nil || n <= 0 {
+		return nil, nil, "invalid timeout '" + secs + "'"
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(n*float64(time.Second)))
+	return ctx, cancel, ""
+}
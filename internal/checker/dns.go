@@ -0,0 +1,290 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func init() {
+	RegisterBuiltin("gogios_dns", CheckDNS)
+}
+
+type dnsCheckOpts struct {
+	name       string
+	server     string // host:port; empty uses the system resolver
+	qtype      string
+	expect     []string
+	warn, crit float64 // response time thresholds in seconds; 0 = unset
+	timeout    string
+}
+
+// CheckDNS is the gogios_dns builtin. It accepts a check_dns-compatible
+// subset of options:
+//
+//	-H name  -s server[:port]  -q A|AAAA|CNAME|MX|NS|PTR|SRV|TXT
+//	-a answer[,answer...]  -w secs  -c secs  -t secs
+//
+// -a may be repeated, and is OK if any answer matches one of them. A/AAAA
+// answers may also be matched by CIDR. Concurrent checks asking the same
+// server the same question share one query.
+func CheckDNS(ctx context.Context, args []string) (int, string) {
+	o, err := parseDNSCheckArgs(args)
+	if err != "" {
+		return objects.ServiceUnknown, "DNS UNKNOWN - " + err
+	}
+	ctx, cancel, err := withCheckTimeout(ctx, o.timeout)
+	if err != "" {
+		return objects.ServiceUnknown, "DNS UNKNOWN - " + err
+	}
+	defer cancel()
+
+	start := time.Now()
+	answers, lerr := dnsQueries.lookup(ctx, o.server, o.qtype, o.name)
+	elapsed := time.Since(start).Seconds()
+	if lerr != nil {
+		var dnsErr *net.DNSError
+		switch {
+		case errors.Is(lerr, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return objects.ServiceCritical, "DNS CRITICAL - Socket timeout"
+		case errors.As(lerr, &dnsErr) && dnsErr.IsNotFound:
+			return objects.ServiceCritical, fmt.Sprintf("DNS CRITICAL - Domain '%s' was not found by the server", o.name)
+		}
+		return objects.ServiceCritical, "DNS CRITICAL - " + lerr.Error()
+	}
+	if len(answers) == 0 {
+		return objects.ServiceCritical, fmt.Sprintf("DNS CRITICAL - '%s' returned no %s records", o.name, o.qtype)
+	}
+
+	state := objects.ServiceOK
+	var problem string
+	if len(o.expect) > 0 && !dnsAnswerMatches(answers, o.expect) {
+		state = objects.ServiceCritical
+		problem = fmt.Sprintf("expected '%s' but got '%s'", strings.Join(o.expect, ","), strings.Join(answers, ","))
+	}
+	switch {
+	case o.crit > 0 && elapsed > o.crit:
+		state = worstState(state, objects.ServiceCritical)
+	case o.warn > 0 && elapsed > o.warn:
+		state = worstState(state, objects.ServiceWarning)
+	}
+
+	label := map[int]string{
+		objects.ServiceOK:       "DNS OK",
+		objects.ServiceWarning:  "DNS WARNING",
+		objects.ServiceCritical: "DNS CRITICAL",
+	}[state]
+	var sb strings.Builder
+	if problem != "" {
+		fmt.Fprintf(&sb, "%s - %s", label, problem)
+	} else {
+		fmt.Fprintf(&sb, "%s: %.3f seconds response time. %s returns %s", label, elapsed, o.name, strings.Join(answers, ","))
+	}
+	fmt.Fprintf(&sb, "|time=%.6fs;%s;%s;0.000000", elapsed, thresholdString(o.warn), thresholdString(o.crit))
+	return state, sb.String()
+}
+
+// dnsAnswerMatches reports whether any answer matches any expected value.
+func dnsAnswerMatches(answers, expect []string) bool {
+	for _, want := range expect {
+		_, cidr, cerr := net.ParseCIDR(want)
+		for _, got := range answers {
+			if cerr == nil {
+				if ip := net.ParseIP(got); ip != nil && cidr.Contains(ip) {
+					return true
+				}
+				continue
+			}
+			if strings.EqualFold(strings.TrimSuffix(got, "."), strings.TrimSuffix(want, ".")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dnsQueries coalesces concurrent identical queries.
+var dnsQueries = &dnsBatcher{calls: map[string]*dnsCall{}}
+
+type dnsBatcher struct {
+	mu    sync.Mutex
+	calls map[string]*dnsCall
+}
+
+type dnsCall struct {
+	done    chan struct{}
+	answers []string
+	err     error
+}
+
+// lookup runs the query, or waits for the same one already in flight. A
+// waiter whose own deadline is later than that of a query that timed out
+// queries again.
+func (b *dnsBatcher) lookup(ctx context.Context, server, qtype, name string) ([]string, error) {
+	key := server + "\x00" + qtype + "\x00" + strings.ToLower(name)
+	b.mu.Lock()
+	if c, ok := b.calls[key]; ok {
+		b.mu.Unlock()
+		select {
+		case <-c.done:
+			if errors.Is(c.err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return queryDNS(ctx, server, qtype, name)
+			}
+			return c.answers, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &dnsCall{done: make(chan struct{})}
+	b.calls[key] = c
+	b.mu.Unlock()
+
+	c.answers, c.err = queryDNS(ctx, server, qtype, name)
+	b.mu.Lock()
+	delete(b.calls, key)
+	b.mu.Unlock()
+	close(c.done)
+	return c.answers, c.err
+}
+
+// queryDNS asks server, or the system's name servers, for the qtype records
+// of name. The query holds a slot of the shared dialer.
+func queryDNS(ctx context.Context, server, qtype, name string) ([]string, error) {
+	release, err := builtinDialer.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	r := &net.Resolver{PreferGo: true}
+	if server != "" {
+		r.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return builtinDialer.d.DialContext(ctx, network, server)
+		}
+	}
+
+	var answers []string
+	switch qtype {
+	case "A", "AAAA":
+		network := "ip4"
+		if qtype == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, mx.Host)
+		}
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			answers = append(answers, ns.Host)
+		}
+	case "PTR":
+		names, err := r.LookupAddr(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = names
+	case "SRV":
+		_, srvs, err := r.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range srvs {
+			answers = append(answers, net.JoinHostPort(srv.Target, strconv.Itoa(int(srv.Port))))
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = txts
+	}
+	slices.Sort(answers)
+	return answers, nil
+}
+
+func parseDNSCheckArgs(args []string) (*dnsCheckOpts, string) {
+	o := &dnsCheckOpts{qtype: "A"}
+	f := &builtinFlags{args: args}
+	for {
+		name, val, ok, err := f.next(func(string) bool { return true })
+		if err != "" {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		switch name {
+		case "-H", "--hostname":
+			o.name = val
+		case "-s", "--server":
+			o.server = val
+		case "-q", "--querytype":
+			o.qtype = strings.ToUpper(val)
+			switch o.qtype {
+			case "A", "AAAA", "CNAME", "MX", "NS", "PTR", "SRV", "TXT":
+			default:
+				return nil, "unsupported query type '" + val + "'"
+			}
+		case "-a", "--expected-address":
+			for _, part := range strings.Split(val, ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					o.expect = append(o.expect, part)
+				}
+			}
+		case "-w", "--warning":
+			v, verr := strconv.ParseFloat(val, 64)
+			if verr != nil {
+				return nil, "invalid warning threshold '" + val + "'"
+			}
+			o.warn = v
+		case "-c", "--critical":
+			v, verr := strconv.ParseFloat(val, 64)
+			if verr != nil {
+				return nil, "invalid critical threshold '" + val + "'"
+			}
+			o.crit = v
+		case "-t", "--timeout":
+			o.timeout = val
+		default:
+			return nil, "unknown option " + name
+		}
+	}
+	if o.name == "" {
+		return nil, "no name to look up (-H)"
+	}
+	if o.server != "" {
+		if _, _, err := net.SplitHostPort(o.server); err != nil {
+			o.server = net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(o.server, "["), "]"), "53")
+		}
+	}
+	return o, ""
+}
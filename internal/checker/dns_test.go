@@ -0,0 +1,121 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// fakeDNSServer answers A queries for www.example.test with 192.0.2.10,
+// after delay, and NXDOMAIN for other names. It counts the A queries.
+func fakeDNSServer(t *testing.T, delay time.Duration) (string, *atomic.Int32) {
+	t.Helper()
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			q := buf[:n]
+			// Question name: labels from offset 12 up to the zero byte.
+			end := 12
+			var labels []string
+			for end < n && q[end] != 0 {
+				l := int(q[end])
+				labels = append(labels, string(q[end+1:end+1+l]))
+				end += 1 + l
+			}
+			qtype := binary.BigEndian.Uint16(q[end+1:])
+			question := q[12 : end+5]
+
+			resp := make([]byte, 12, 64)
+			copy(resp, q[:2])
+			flags := uint16(0x8180)
+			var answers uint16
+			if strings.Join(labels, ".") != "www.example.test" {
+				flags |= 3 // NXDOMAIN
+			} else if qtype == 1 {
+				answers = 1
+				queries.Add(1)
+				time.Sleep(delay)
+			}
+			binary.BigEndian.PutUint16(resp[2:], flags)
+			binary.BigEndian.PutUint16(resp[4:], 1)
+			binary.BigEndian.PutUint16(resp[6:], answers)
+			resp = append(resp, question...)
+			if answers == 1 {
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 10)
+			}
+			pc.WriteTo(resp, addr)
+		}
+	}()
+	return pc.LocalAddr().String(), &queries
+}
+
+func TestCheckDNS(t *testing.T) {
+	server, _ := fakeDNSServer(t, 0)
+	ctx := context.Background()
+
+	rc, out := CheckDNS(ctx, []string{"-H", "www.example.test", "-s", server, "-a", "192.0.2.10"})
+	if rc != objects.ServiceOK || !strings.HasPrefix(out, "DNS OK") || !strings.Contains(out, "returns 192.0.2.10|time=") {
+		t.Errorf("expected answer: rc=%d out=%q", rc, out)
+	}
+	if rc, out := CheckDNS(ctx, []string{"-H", "www.example.test", "-s", server, "-a", "192.0.2.0/24"}); rc != objects.ServiceOK {
+		t.Errorf("CIDR answer: rc=%d out=%q", rc, out)
+	}
+	rc, out = CheckDNS(ctx, []string{"-H", "www.example.test", "-s", server, "-a", "192.0.2.11"})
+	if rc != objects.ServiceCritical || !strings.Contains(out, "expected '192.0.2.11' but got '192.0.2.10'") {
+		t.Errorf("wrong answer: rc=%d out=%q", rc, out)
+	}
+	rc, out = CheckDNS(ctx, []string{"-H", "nope.example.test", "-s", server})
+	if rc != objects.ServiceCritical || !strings.Contains(out, "was not found") {
+		t.Errorf("NXDOMAIN: rc=%d out=%q", rc, out)
+	}
+	if rc, out := CheckDNS(ctx, []string{"-H", "www.example.test", "-q", "SOA"}); rc != objects.ServiceUnknown {
+		t.Errorf("unsupported query type: rc=%d out=%q", rc, out)
+	}
+}
+
+func TestCheckDNSTimeoutAndBatching(t *testing.T) {
+	server, queries := fakeDNSServer(t, 300*time.Millisecond)
+
+	start := time.Now()
+	rc, out := CheckDNS(context.Background(), []string{"-H", "www.example.test", "-s", server, "-t", "0.1"})
+	if rc != objects.ServiceCritical || !strings.Contains(out, "Socket timeout") {
+		t.Errorf("-t 0.1: rc=%d out=%q", rc, out)
+	}
+	if time.Since(start) > 250*time.Millisecond {
+		t.Errorf("-t 0.1 took %v", time.Since(start))
+	}
+
+	time.Sleep(400 * time.Millisecond) // let the timed-out query finish
+	queries.Store(0)
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rc, out := CheckDNS(context.Background(), []string{"-H", "www.example.test", "-s", server}); rc != objects.ServiceOK {
+				t.Errorf("batched: rc=%d out=%q", rc, out)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := queries.Load(); n >= 5 {
+		t.Errorf("5 concurrent identical checks sent %d queries", n)
+	}
+}
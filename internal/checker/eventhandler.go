@@ -0,0 +1,125 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// EventHandlers runs event handler commands on host and service state
+// changes, soft and hard, while enable_event_handlers is on: first the
+// global_host_event_handler or global_service_event_handler, then the
+// object's own event_handler if its event handler is enabled. Each command
+// runs in the background and is killed after event_handler_timeout.
+type EventHandlers struct {
+	Global *objects.GlobalState
+	// Expand expands the macros of a command line. The caller holds the
+	// store lock when Service or Host is called.
+	Expand func(cmdLine string, h *objects.Host, svc *objects.Service, args []string) string
+	// OnRun, if set, is called as a handler is run, with the store lock
+	// held. global tells a global handler from the object's own.
+	OnRun func(global bool, h *objects.Host, svc *objects.Service, handler string)
+	// DryRun skips running the commands (simulation mode). Handlers are
+	// still expanded and reported to OnRun.
+	DryRun bool
+
+	globalHostCmd, globalSvcCmd   *objects.Command
+	globalHostArgs, globalSvcArgs []string
+	timeout                       time.Duration
+	logFunc                       func(string, ...interface{})
+	run                           func(cmdLine string, timeout time.Duration) error
+}
+
+// NewEventHandlers resolves the global host and service event handler
+// commands, either of which may be empty, against the store's commands.
+func NewEventHandlers(store *objects.ObjectStore, gs *objects.GlobalState, globalHost, globalSvc string, timeout time.Duration) (*EventHandlers, error) {
+	e := &EventHandlers{
+		Global:  gs,
+		timeout: timeout,
+		logFunc: func(string, ...interface{}) {},
+		run:     runShellCommand,
+	}
+	var err error
+	if e.globalHostCmd, e.globalHostArgs, err = lookupCommand(store, globalHost); err != nil {
+		return nil, fmt.Errorf("global host event handler command %w", err)
+	}
+	if e.globalSvcCmd, e.globalSvcArgs, err = lookupCommand(store, globalSvc); err != nil {
+		return nil, fmt.Errorf("global service event handler command %w", err)
+	}
+	return e, nil
+}
+
+// SetGlobal replaces the global host event handler, or the global service
+// one when service is set, with the command spec "name!arg1!...", for
+// CHANGE_GLOBAL_*_EVENT_HANDLER. An empty spec removes the handler. The
+// caller holds the store lock.
+func (e *EventHandlers) SetGlobal(store *objects.ObjectStore, service bool, spec string) error {
+	cmd, args, err := lookupCommand(store, spec)
+	if err != nil {
+		return fmt.Errorf("command %w", err)
+	}
+	if service {
+		e.globalSvcCmd, e.globalSvcArgs = cmd, args
+	} else {
+		e.globalHostCmd, e.globalHostArgs = cmd, args
+	}
+	return nil
+}
+
+// SetLogger sets the function timeouts and failures are logged with.
+func (e *EventHandlers) SetLogger(fn func(string, ...interface{})) {
+	e.logFunc = fn
+}
+
+// Service runs the event handlers for a state change of svc.
+func (e *EventHandlers) Service(svc *objects.Service) {
+	if !e.Global.EnableEventHandlers {
+		return
+	}
+	if e.globalSvcCmd != nil {
+		what := fmt.Sprintf("Global service event handler command '%s' for service '%s' on host '%s'",
+			e.globalSvcCmd.Name, svc.Description, svc.Host.Name)
+		e.start(true, svc.Host, svc, e.globalSvcCmd, e.globalSvcArgs, what)
+	}
+	if svc.EventHandler != nil && svc.EventHandlerEnabled {
+		what := fmt.Sprintf("Service event handler command '%s' for service '%s' on host '%s'",
+			svc.EventHandler.Name, svc.Description, svc.Host.Name)
+		e.start(false, svc.Host, svc, svc.EventHandler, splitArgs(svc.EventHandlerArgs), what)
+	}
+}
+
+// Host runs the event handlers for a state change of h.
+func (e *EventHandlers) Host(h *objects.Host) {
+	if !e.Global.EnableEventHandlers {
+		return
+	}
+	if e.globalHostCmd != nil {
+		what := fmt.Sprintf("Global host event handler command '%s' for host '%s'", e.globalHostCmd.Name, h.Name)
+		e.start(true, h, nil, e.globalHostCmd, e.globalHostArgs, what)
+	}
+	if h.EventHandler != nil && h.EventHandlerEnabled {
+		what := fmt.Sprintf("Host event handler command '%s' for host '%s'", h.EventHandler.Name, h.Name)
+		e.start(false, h, nil, h.EventHandler, splitArgs(h.EventHandlerArgs), what)
+	}
+}
+
+func (e *EventHandlers) start(global bool, h *objects.Host, svc *objects.Service, cmd *objects.Command, args []string, what string) {
+	cmdLine := e.Expand(cmd.CommandLine, h, svc, args)
+	if e.OnRun != nil {
+		e.OnRun(global, h, svc, cmd.Name)
+	}
+	if e.DryRun {
+		return
+	}
+	go execLogged(e.run, e.logFunc, cmdLine, e.timeout, what)
+}
+
+// splitArgs splits the "!"-separated arguments of a command.
+func splitArgs(args string) []string {
+	if args == "" {
+		return nil
+	}
+	return strings.Split(args, "!")
+}
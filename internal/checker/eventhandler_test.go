@@ -0,0 +1,157 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestEventHandlers(t *testing.T) {
+	store := objects.NewObjectStore()
+	store.AddCommand(&objects.Command{Name: "log_change", CommandLine: "log $HOSTNAME$ $SERVICEDESC$ $ARG1$"})
+	restart := &objects.Command{Name: "restart", CommandLine: "restart $SERVICEDESC$ on $HOSTNAME$ $ARG1$ $ARG2$"}
+	store.AddCommand(restart)
+	gs := &objects.GlobalState{EnableEventHandlers: true}
+
+	if _, err := NewEventHandlers(store, gs, "", "missing", time.Second); err == nil {
+		t.Fatal("expected an error for an undefined command")
+	}
+	e, err := NewEventHandlers(store, gs, "", "log_change!global", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Expand = func(cmdLine string, h *objects.Host, svc *objects.Service, args []string) string {
+		desc := ""
+		if svc != nil {
+			desc = svc.Description
+		}
+		var a1, a2 string
+		if len(args) > 0 {
+			a1 = args[0]
+		}
+		if len(args) > 1 {
+			a2 = args[1]
+		}
+		r := strings.NewReplacer("$HOSTNAME$", h.Name, "$SERVICEDESC$", desc, "$ARG1$", a1, "$ARG2$", a2)
+		return r.Replace(cmdLine)
+	}
+	ran := make(chan string, 4)
+	e.run = func(cmdLine string, timeout time.Duration) error {
+		ran <- cmdLine
+		if strings.HasPrefix(cmdLine, "restart") {
+			return context.DeadlineExceeded
+		}
+		return nil
+	}
+	logged := make(chan string, 4)
+	e.SetLogger(func(format string, args ...interface{}) { logged <- args[0].(string) })
+	var runs []string
+	e.OnRun = func(global bool, h *objects.Host, svc *objects.Service, handler string) {
+		if global {
+			handler = "GLOBAL " + handler
+		}
+		runs = append(runs, handler)
+	}
+
+	h := &objects.Host{Name: "web-01", EventHandlerEnabled: true}
+	svc := &objects.Service{Host: h, Description: "HTTP", EventHandler: restart, EventHandlerArgs: "now!quietly", EventHandlerEnabled: true}
+
+	e.Service(svc)
+	got := []string{<-ran, <-ran}
+	if got[0] > got[1] {
+		got[0], got[1] = got[1], got[0]
+	}
+	if got[0] != "log web-01 HTTP global" || got[1] != "restart HTTP on web-01 now quietly" {
+		t.Errorf("ran %q", got)
+	}
+	if got := <-logged; !strings.HasPrefix(got, "Service event handler command 'restart'") {
+		t.Errorf("logged %q", got)
+	}
+	if strings.Join(runs, ",") != "GLOBAL log_change,restart" {
+		t.Errorf("runs = %v", runs)
+	}
+
+	// The global handler runs even when the service's handler is disabled;
+	// neither runs with event handlers disabled globally. The host has no
+	// handlers.
+	svc.EventHandlerEnabled = false
+	e.Service(svc)
+	if got := <-ran; got != "log web-01 HTTP global" {
+		t.Errorf("ran %q", got)
+	}
+	gs.EnableEventHandlers = false
+	e.Service(svc)
+	gs.EnableEventHandlers = true
+	e.Host(h)
+	select {
+	case got := <-ran:
+		t.Errorf("unexpected run %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEventHandlers_SetGlobal(t *testing.T) {
+	store := objects.NewObjectStore()
+	store.AddCommand(&objects.Command{Name: "log_change", CommandLine: "log $ARG1$"})
+	e, err := NewEventHandlers(store, &objects.GlobalState{}, "", "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetGlobal(store, false, "missing"); err == nil || e.globalHostCmd != nil {
+		t.Fatalf("undefined command: err = %v, cmd = %v", err, e.globalHostCmd)
+	}
+	if err := e.SetGlobal(store, true, "log_change!svc"); err != nil {
+		t.Fatal(err)
+	}
+	if e.globalSvcCmd == nil || e.globalSvcCmd.Name != "log_change" || len(e.globalSvcArgs) != 1 || e.globalHostCmd != nil {
+		t.Fatalf("service handler = %v %v, host handler = %v", e.globalSvcCmd, e.globalSvcArgs, e.globalHostCmd)
+	}
+	if err := e.SetGlobal(store, true, ""); err != nil || e.globalSvcCmd != nil {
+		t.Fatalf("removing: err = %v, cmd = %v", err, e.globalSvcCmd)
+	}
+}
+
+func TestEventHandlers_DryRunSkipsSimulatedResults(t *testing.T) {
+	store := objects.NewObjectStore()
+	restart := &objects.Command{Name: "restart", CommandLine: "restart"}
+	store.AddCommand(restart)
+	e, err := NewEventHandlers(store, &objects.GlobalState{EnableEventHandlers: true}, "", "restart", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Expand = func(cmdLine string, _ *objects.Host, _ *objects.Service, _ []string) string { return cmdLine }
+	e.DryRun = true
+	ran := make(chan string, 2)
+	e.run = func(cmdLine string, _ time.Duration) error {
+		ran <- cmdLine
+		return nil
+	}
+	var reported int
+	e.OnRun = func(bool, *objects.Host, *objects.Service, string) { reported++ }
+
+	resultCh := make(chan *objects.CheckResult, 1)
+	sim := NewSimExecutor(SimConfig{CriticalRate: 1, LatencyMean: time.Millisecond}, resultCh)
+	svc := newTestService()
+	svc.EventHandler, svc.EventHandlerEnabled = restart, true
+	h := &ServiceResultHandler{
+		Cfg:           newTestConfig(),
+		OnStateChange: func(svc *objects.Service, _, _ int, _ bool) { e.Service(svc) },
+	}
+	sim.Submit(svc.Host.Name, svc.Description, "ignored", time.Second, 0, objects.CheckTypeActive, 0)
+	h.HandleResult(svc, <-resultCh)
+
+	if svc.CurrentState != objects.ServiceCritical {
+		t.Fatalf("expected a simulated CRITICAL, got state %d", svc.CurrentState)
+	}
+	if reported != 2 {
+		t.Errorf("expected both handlers reported, got %d", reported)
+	}
+	select {
+	case got := <-ran:
+		t.Errorf("event handler %q forked in simulation mode", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
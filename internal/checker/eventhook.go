@@ -0,0 +1,118 @@
+package checker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/expr"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Custom variables of event hooks. Besides its event_handler command (see
+// EventHandlers), a host or service can carry an in-process hook: on each
+// state change the _EVENT_HOOK expression (see package expr) is evaluated
+// and, when it is true or not set, the _EVENT_HOOK_COMMAND external command
+// line (macros expanded) is dispatched, e.g.
+//
+//	_EVENT_HOOK          state_type == "HARD" && state_name == "CRITICAL"
+//	_EVENT_HOOK_COMMAND  SCHEDULE_FORCED_SVC_CHECK;$HOSTNAME$;Restart Web;$TIMET$
+const (
+	EventHookCustomVar        = "EVENT_HOOK"
+	EventHookCommandCustomVar = "EVENT_HOOK_COMMAND"
+)
+
+// EventHooks runs the event hooks of hosts and services whose event
+// handlers are enabled, while enable_event_handlers is on.
+type EventHooks struct {
+	Global *objects.GlobalState
+	// Expand expands the macros of a command line. The caller holds the
+	// store lock when Service or Host is called.
+	Expand func(cmdLine string, h *objects.Host, svc *objects.Service, args []string) string
+	// Dispatch submits an expanded external command line. It is called in
+	// the background, without the store lock.
+	Dispatch func(line string) error
+	// OnRun, if set, is called when a hook fires, with the store lock held.
+	OnRun func(h *objects.Host, svc *objects.Service)
+
+	mu      sync.Mutex
+	progs   map[string]*expr.Program
+	logFunc func(string, ...interface{})
+}
+
+// SetLogger sets the function hook errors are logged with.
+func (e *EventHooks) SetLogger(fn func(string, ...interface{})) {
+	e.logFunc = fn
+}
+
+func (e *EventHooks) log(format string, args ...interface{}) {
+	if e.logFunc != nil {
+		e.logFunc(format, args...)
+	}
+}
+
+// Service runs svc's event hook after a state change.
+func (e *EventHooks) Service(svc *objects.Service, now time.Time) {
+	if !e.Global.EnableEventHandlers || !svc.EventHandlerEnabled {
+		return
+	}
+	what := fmt.Sprintf("service '%s' on host '%s'", svc.Description, svc.Host.Name)
+	e.run(svc.CustomVars, func() expr.Env { return expr.ServiceEnv(svc, now) }, svc.Host, svc, what)
+}
+
+// Host runs h's event hook after a state change.
+func (e *EventHooks) Host(h *objects.Host, now time.Time) {
+	if !e.Global.EnableEventHandlers || !h.EventHandlerEnabled {
+		return
+	}
+	e.run(h.CustomVars, func() expr.Env { return expr.HostEnv(h, now) }, h, nil, "host '"+h.Name+"'")
+}
+
+func (e *EventHooks) run(vars map[string]string, env func() expr.Env, h *objects.Host, svc *objects.Service, what string) {
+	raw, ok := vars[EventHookCommandCustomVar]
+	if !ok || raw == "" {
+		return
+	}
+	if src, ok := vars[EventHookCustomVar]; ok {
+		prog := e.compile(src, what)
+		if prog == nil {
+			return
+		}
+		fire, err := prog.EvalBool(env())
+		if err != nil {
+			e.log("Warning: Event hook of %s failed: %v", what, err)
+			return
+		}
+		if !fire {
+			return
+		}
+	}
+	line := e.Expand(raw, h, svc, nil)
+	if e.OnRun != nil {
+		e.OnRun(h, svc)
+	}
+	go func() {
+		if err := e.Dispatch(line); err != nil {
+			e.log("Warning: Event hook of %s failed: %v", what, err)
+		}
+	}()
+}
+
+// compile returns the cached program for src, or nil if it does not
+// compile. Unlike a notification filter, a broken hook never fires.
+func (e *EventHooks) compile(src, what string) *expr.Program {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	prog, ok := e.progs[src]
+	if !ok {
+		var err error
+		if prog, err = expr.Compile(src, expr.ObjectVars); err != nil {
+			e.log("Warning: Ignoring _%s of %s: %v", EventHookCustomVar, what, err)
+		}
+		if e.progs == nil {
+			e.progs = make(map[string]*expr.Program)
+		}
+		e.progs[src] = prog
+	}
+	return prog
+}
@@ -0,0 +1,68 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestEventHooks(t *testing.T) {
+	gs := &objects.GlobalState{EnableEventHandlers: true}
+	dispatched := make(chan string, 4)
+	e := &EventHooks{
+		Global: gs,
+		Expand: func(cmdLine string, h *objects.Host, svc *objects.Service, args []string) string {
+			return strings.ReplaceAll(cmdLine, "$HOSTNAME$", h.Name)
+		},
+		Dispatch: func(line string) error { dispatched <- line; return nil },
+	}
+	var logged []string
+	e.SetLogger(func(format string, args ...interface{}) { logged = append(logged, format) })
+
+	h := &objects.Host{Name: "web01", EventHandlerEnabled: true}
+	svc := &objects.Service{
+		Host: h, Description: "HTTP", EventHandlerEnabled: true,
+		CurrentState: objects.ServiceCritical, StateType: objects.StateTypeSoft,
+		CustomVars: map[string]string{
+			EventHookCustomVar:        `state_type == "HARD" && state_name == "CRITICAL"`,
+			EventHookCommandCustomVar: "SCHEDULE_FORCED_HOST_CHECK;$HOSTNAME$;0",
+		},
+	}
+	now := time.Now()
+
+	e.Service(svc, now) // SOFT: the expression is false
+	svc.StateType = objects.StateTypeHard
+	gs.EnableEventHandlers = false
+	e.Service(svc, now) // disabled globally
+	gs.EnableEventHandlers = true
+	e.Service(svc, now)
+	select {
+	case line := <-dispatched:
+		if line != "SCHEDULE_FORCED_HOST_CHECK;web01;0" {
+			t.Errorf("dispatched %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("hook did not fire")
+	}
+
+	// A hook without an expression fires on every state change; one that
+	// does not compile never fires.
+	h.CustomVars = map[string]string{EventHookCommandCustomVar: "DISABLE_HOST_NOTIFICATIONS;$HOSTNAME$"}
+	e.Host(h, now)
+	if line := <-dispatched; line != "DISABLE_HOST_NOTIFICATIONS;web01" {
+		t.Errorf("dispatched %q", line)
+	}
+	h.CustomVars[EventHookCustomVar] = "state_nme == 'DOWN'"
+	e.Host(h, now)
+	e.Host(h, now)
+	select {
+	case line := <-dispatched:
+		t.Errorf("broken hook dispatched %q", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(logged) != 1 {
+		t.Errorf("expected the compile error to be logged once, got %q", logged)
+	}
+}
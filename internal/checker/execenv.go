@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Custom variables that adjust the environment a check plugin runs in. A
+// service variable overrides the same variable on its host.
+const (
+	CwdCustomVar   = "CHECK_CWD"   // working directory
+	EnvCustomVar   = "CHECK_ENV"   // comma-separated NAME=value pairs
+	UmaskCustomVar = "CHECK_UMASK" // octal umask
+)
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExecEnv is the working directory, extra environment, umask and resource
+// limits for one check. The zero value leaves the command unchanged.
+type ExecEnv struct {
+	Dir    string
+	Env    []string // NAME=value
+	Umask  string   // octal, e.g. "027"
+	Limits Rlimits
+}
+
+// ExecEnvFor reads the _CHECK_CWD, _CHECK_ENV, _CHECK_UMASK and
+// _CHECK_RLIMIT_* custom variables for a check on h (svc may be nil for
+// host checks).
+func ExecEnvFor(h *objects.Host, svc *objects.Service) (ExecEnv, error) {
+	lookup := func(name string) string {
+		if svc != nil {
+			if v, ok := svc.CustomVars[name]; ok {
+				return strings.TrimSpace(v)
+			}
+		}
+		if h != nil {
+			return strings.TrimSpace(h.CustomVars[name])
+		}
+		return ""
+	}
+
+	e := ExecEnv{Dir: lookup(CwdCustomVar), Umask: lookup(UmaskCustomVar)}
+	if e.Umask != "" {
+		if v, err := strconv.ParseUint(e.Umask, 8, 32); err != nil || v > 0777 {
+			return ExecEnv{}, fmt.Errorf("invalid _%s %q", UmaskCustomVar, e.Umask)
+		}
+	}
+	var err error
+	if e.Limits.CPU, err = parseRlimit(RlimitCPUCustomVar, lookup(RlimitCPUCustomVar)); err != nil {
+		return ExecEnv{}, err
+	}
+	if e.Limits.Memory, err = parseRlimit(RlimitMemoryCustomVar, lookup(RlimitMemoryCustomVar)); err != nil {
+		return ExecEnv{}, err
+	}
+	if e.Limits.NoFile, err = parseRlimit(RlimitNoFileCustomVar, lookup(RlimitNoFileCustomVar)); err != nil {
+		return ExecEnv{}, err
+	}
+	if env := lookup(EnvCustomVar); env != "" {
+		for _, pair := range strings.Split(env, ",") {
+			pair = strings.TrimSpace(pair)
+			name, _, ok := strings.Cut(pair, "=")
+			if !ok || !envNameRe.MatchString(name) {
+				return ExecEnv{}, fmt.Errorf("invalid _%s entry %q", EnvCustomVar, pair)
+			}
+			e.Env = append(e.Env, pair)
+		}
+	}
+	return e, nil
+}
+
+// Wrap prefixes command with the shell statements that apply e. Every
+// runner hands the command line to /bin/sh (the SSH runner to the remote
+// shell) in a subshell, so the settings last for this check only. A
+// working directory that cannot be entered fails the check as UNKNOWN.
+// Builtin checks run in-process, not in a shell, so their command lines
+// are returned unchanged; a prefix would hide the builtin's name.
+func (e ExecEnv) Wrap(command string) string {
+	if e.Dir == "" && len(e.Env) == 0 && e.Umask == "" && e.Limits == (Rlimits{}) {
+		return command
+	}
+	if _, _, ok := LookupBuiltin(command); ok {
+		return command
+	}
+	var b strings.Builder
+	b.WriteString(e.Limits.prefix())
+	if e.Umask != "" {
+		fmt.Fprintf(&b, "umask %s; ", e.Umask)
+	}
+	if e.Dir != "" {
+		fmt.Fprintf(&b, "cd %s || exit 3; ", shellQuote(e.Dir))
+	}
+	for _, kv := range e.Env {
+		name, value, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "export %s=%s; ", name, shellQuote(value))
+	}
+	b.WriteString(command)
+	return b.String()
+}
+
+// shellQuote single-quotes s for /bin/sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
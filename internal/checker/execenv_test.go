@@ -0,0 +1,81 @@
+package checker
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestExecEnvFor(t *testing.T) {
+	h := &objects.Host{Name: "h", CustomVars: map[string]string{
+		CwdCustomVar:   "/opt/vendor",
+		UmaskCustomVar: "022",
+	}}
+	svc := &objects.Service{Host: h, CustomVars: map[string]string{
+		UmaskCustomVar: "077",
+		EnvCustomVar:   "ORACLE_HOME=/opt/oracle, LANG=C",
+	}}
+	e, err := ExecEnvFor(h, svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Dir != "/opt/vendor" || e.Umask != "077" || strings.Join(e.Env, " ") != "ORACLE_HOME=/opt/oracle LANG=C" {
+		t.Errorf("unexpected env %+v", e)
+	}
+
+	svc.CustomVars[UmaskCustomVar] = "999"
+	if _, err := ExecEnvFor(h, svc); err == nil {
+		t.Error("expected an error for an invalid umask")
+	}
+	svc.CustomVars[UmaskCustomVar] = "077"
+	svc.CustomVars[EnvCustomVar] = "1BAD=x"
+	if _, err := ExecEnvFor(h, svc); err == nil {
+		t.Error("expected an error for an invalid variable name")
+	}
+
+	if got := (ExecEnv{}).Wrap("/bin/true"); got != "/bin/true" {
+		t.Errorf("zero ExecEnv changed the command: %q", got)
+	}
+}
+
+func TestExecEnvWrap_RunsInShell(t *testing.T) {
+	dir := t.TempDir()
+	e := ExecEnv{Dir: dir, Env: []string{"GREETING=it's here"}, Umask: "027"}
+	cr := new(Executor).runPlugin("h", "s", e.Wrap(`echo "$(pwd) $GREETING $(umask)"`), 5*time.Second, 0, 0, 0)
+	want := dir + " it's here 0027\n"
+	if cr.ReturnCode != 0 || cr.Output != want {
+		t.Errorf("got %d %q, want %q", cr.ReturnCode, cr.Output, want)
+	}
+
+	e.Dir = filepath.Join(dir, "missing")
+	cr = new(Executor).runPlugin("h", "s", e.Wrap("echo ok"), 5*time.Second, 0, 0, 0)
+	if cr.ReturnCode != 3 {
+		t.Errorf("expected UNKNOWN for a missing directory, got %d", cr.ReturnCode)
+	}
+}
+
+func TestExecEnvWrap_LeavesBuiltinsInProcess(t *testing.T) {
+	RegisterBuiltin("gogios_test_env", func(ctx context.Context, args []string) (int, string) {
+		return 0, "builtin " + strings.Join(args, " ")
+	})
+	h := &objects.Host{Name: "h", CustomVars: map[string]string{
+		EnvCustomVar: "LANG=C",
+		CwdCustomVar: "/nonexistent",
+	}}
+	e, err := ExecEnvFor(h, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := e.Wrap("gogios_test_env -H example.com")
+	if _, _, ok := LookupBuiltin(command); !ok {
+		t.Fatalf("wrapped command %q no longer names the builtin", command)
+	}
+	cr := RunOnce(command, 5*time.Second)
+	if cr.ReturnCode != 0 || cr.Output != "builtin -H example.com" {
+		t.Errorf("got %d %q, want the builtin's result", cr.ReturnCode, cr.Output)
+	}
+}
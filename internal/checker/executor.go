@@ -1,13 +1,13 @@
 package checker
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
 	"sync/atomic"
 	"syscall"
@@ -16,6 +16,10 @@ import (
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
+// localSource is the check_source reported for checks run by this process.
+// It follows the Nagios 4 "Core Worker <pid>" form that Thruk displays.
+var localSource = fmt.Sprintf("Core Worker %d", os.Getpid())
+
 // checkJob holds all parameters for a single check execution.
 type checkJob struct {
 	hostName     string
@@ -25,6 +29,7 @@ type checkJob struct {
 	checkOptions int
 	checkType    int
 	latency      float64
+	bySSH        *BySSHCommand // set for native check_by_ssh jobs (SSH runner only)
 }
 
 // Executor runs check plugins with a fixed-size worker pool.
@@ -40,6 +45,11 @@ type Executor struct {
 	resultCh    chan *objects.CheckResult
 	workers     int
 	sentinel    string
+	pq          *importanceQueue // non-nil for NewImportanceExecutor
+
+	// Concurrency, when set, limits checks per concurrency class. Set it
+	// before submitting checks.
+	Concurrency *ConcurrencyClasses
 }
 
 // NewExecutor creates an executor with the given concurrency limit.
@@ -48,7 +58,20 @@ func NewExecutor(maxConcurrent int, resultCh chan *objects.CheckResult) *Executo
 	if maxConcurrent <= 0 {
 		maxConcurrent = 256
 	}
+	return newExecutor(maxConcurrent, resultCh, nil)
+}
 
+// NewImportanceExecutor creates an executor that queues checks in a
+// priority queue instead of a FIFO channel. When every worker is busy,
+// the most important waiting check (see SubmitImportance) runs next.
+func NewImportanceExecutor(maxConcurrent int, resultCh chan *objects.CheckResult) *Executor {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 256
+	}
+	return newExecutor(maxConcurrent, resultCh, newImportanceQueue())
+}
+
+func newExecutor(maxConcurrent int, resultCh chan *objects.CheckResult, pq *importanceQueue) *Executor {
 	// Generate a random sentinel for fork server protocol
 	sentinelBytes := make([]byte, 16)
 	if _, err := rand.Read(sentinelBytes); err != nil {
@@ -57,10 +80,13 @@ func NewExecutor(maxConcurrent int, resultCh chan *objects.CheckResult) *Executo
 	sentinel := hex.EncodeToString(sentinelBytes)
 
 	e := &Executor{
-		jobCh:    make(chan checkJob, maxConcurrent*4),
 		resultCh: resultCh,
 		workers:  maxConcurrent,
 		sentinel: sentinel,
+		pq:       pq,
+	}
+	if pq == nil {
+		e.jobCh = make(chan checkJob, maxConcurrent*4)
 	}
 	for i := 0; i < maxConcurrent; i++ {
 		go e.forkServerWorker()
@@ -91,6 +117,28 @@ func (e *Executor) Submit(hostName, svcDesc, command string, timeout time.Durati
 		checkType:    checkType,
 		latency:      latency,
 	}
+	e.submit(job, 0)
+}
+
+// SubmitImportance is Submit with the check's importance. It only affects
+// ordering for executors created with NewImportanceExecutor.
+func (e *Executor) SubmitImportance(importance uint, hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64) {
+	e.submit(checkJob{
+		hostName:     hostName,
+		svcDesc:      svcDesc,
+		command:      command,
+		timeout:      timeout,
+		checkOptions: checkOptions,
+		checkType:    checkType,
+		latency:      latency,
+	}, importance)
+}
+
+func (e *Executor) submit(job checkJob, importance uint) {
+	if e.pq != nil {
+		e.pq.push(job, importance)
+		return
+	}
 	select {
 	case e.jobCh <- job:
 		// sent without blocking
@@ -100,8 +148,48 @@ func (e *Executor) Submit(hostName, svcDesc, command string, timeout time.Durati
 	}
 }
 
+// nextJob blocks until a worker should run another check. Importance
+// executors take the most important queued job, adding its time in the
+// queue to the check's latency.
+func (e *Executor) nextJob() (checkJob, bool) {
+	if e.pq == nil {
+		job, ok := <-e.jobCh
+		return job, ok
+	}
+	qj, ok := e.pq.pop()
+	if !ok {
+		return checkJob{}, false
+	}
+	wait := time.Since(qj.enqueued)
+	e.pq.record(qj.importance, wait)
+	qj.job.latency += wait.Seconds()
+	return qj.job, true
+}
+
+// QueueLen returns the number of checks waiting for a worker. It is only
+// tracked for importance executors.
+func (e *Executor) QueueLen() int {
+	if e.pq == nil {
+		return 0
+	}
+	return e.pq.len()
+}
+
+// ImportanceStats returns queue wait statistics per importance value,
+// highest importance first, or nil for FIFO executors.
+func (e *Executor) ImportanceStats() []ImportanceTierStats {
+	if e.pq == nil {
+		return nil
+	}
+	return e.pq.stats()
+}
+
 // Stop shuts down all workers. Blocks until all in-flight checks complete.
 func (e *Executor) Stop() {
+	if e.pq != nil {
+		e.pq.close()
+		return
+	}
 	close(e.jobCh)
 }
 
@@ -124,8 +212,13 @@ func (e *Executor) forkServerWorker() {
 		}
 	}()
 
-	for job := range e.jobCh {
+	runJob := func(job checkJob) {
 		e.jobsRunning.Add(1)
+		if cr := runBuiltin(job); cr != nil {
+			e.jobsRunning.Add(-1)
+			e.resultCh <- cr
+			return
+		}
 		cr := e.runViaShell(sw, job)
 		if cr == nil {
 			// Shell failed, try respawn
@@ -146,6 +239,14 @@ func (e *Executor) forkServerWorker() {
 		e.jobsRunning.Add(-1)
 		e.resultCh <- cr
 	}
+
+	for {
+		job, ok := e.nextJob()
+		if !ok {
+			return
+		}
+		e.Concurrency.run(job, runJob)
+	}
 }
 
 // runViaShell executes a check through the persistent shell worker.
@@ -162,6 +263,7 @@ func (e *Executor) runViaShell(sw *shellWorker, job checkJob) *objects.CheckResu
 		CheckOptions:       job.checkOptions,
 		Latency:            job.latency,
 		ExitedOK:           true,
+		Source:             sw.source(),
 	}
 
 	cr.StartTime = time.Now()
@@ -193,6 +295,18 @@ func (e *Executor) runViaShell(sw *shellWorker, job checkJob) *objects.CheckResu
 	return cr
 }
 
+// RunOnce runs command to completion on the calling goroutine, as a local
+// check runs without the fork server, and returns the result without
+// submitting it anywhere. Builtin checks run in-process as usual. It is
+// meant for one-off debugging runs.
+func RunOnce(command string, timeout time.Duration) *objects.CheckResult {
+	job := checkJob{command: command, timeout: timeout, checkOptions: objects.CheckOptionForceExecution, checkType: objects.CheckTypeActive}
+	if cr := runBuiltin(job); cr != nil {
+		return cr
+	}
+	return new(Executor).runPlugin("", "", command, timeout, objects.CheckOptionForceExecution, objects.CheckTypeActive, 0)
+}
+
 // runPlugin executes the command via direct fork+exec and captures output/return code.
 // Used as fallback when the fork server is unavailable.
 func (e *Executor) runPlugin(hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64) *objects.CheckResult {
@@ -203,6 +317,7 @@ func (e *Executor) runPlugin(hostName, svcDesc, command string, timeout time.Dur
 		CheckOptions:       checkOptions,
 		Latency:            latency,
 		ExitedOK:           true,
+		Source:             localSource,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -210,12 +325,18 @@ func (e *Executor) runPlugin(hostName, svcDesc, command string, timeout time.Dur
 
 	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdout, stderr := getOutputBuffer(), getOutputBuffer()
+	defer putOutputBuffer(stdout)
+	defer putOutputBuffer(stderr)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
 	cr.StartTime = time.Now()
-	err := cmd.Run()
+	err := cmd.Start()
+	if err == nil {
+		joinCheckCgroup(cmd.Process.Pid)
+		err = cmd.Wait()
+	}
 	cr.FinishTime = time.Now()
 	cr.ExecutionTime = cr.FinishTime.Sub(cr.StartTime).Seconds()
 
@@ -228,7 +349,10 @@ func (e *Executor) runPlugin(hostName, svcDesc, command string, timeout time.Dur
 			return cr
 		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				// Encode signal deaths as a shell does, like the fork server.
+				cr.ReturnCode = 128 + int(ws.Signal())
+			} else if ok {
 				cr.ReturnCode = ws.ExitStatus()
 			} else {
 				cr.ReturnCode = 2
@@ -247,17 +371,9 @@ func (e *Executor) runPlugin(hostName, svcDesc, command string, timeout time.Dur
 
 	// Capture output
 	if stdout.Len() > 0 {
-		out := stdout.String()
-		if len(out) > 8192 {
-			out = out[:8192]
-		}
-		cr.Output = out
+		cr.Output = outputString(stdout.Bytes())
 	} else if stderr.Len() > 0 {
-		out := stderr.String()
-		if len(out) > 8192 {
-			out = out[:8192]
-		}
-		cr.Output = "(No output on stdout) stderr: " + out
+		cr.Output = "(No output on stdout) stderr: " + outputString(stderr.Bytes())
 	}
 
 	return cr
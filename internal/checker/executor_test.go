@@ -143,3 +143,11 @@ func TestExecutorOverflowDoesNotBlock(t *testing.T) {
 		}
 	}
 }
+
+func TestRunPlugin_SignalReturnCode(t *testing.T) {
+	// A plugin killed by a signal reports 128+N, as the fork server does.
+	cr := new(Executor).runPlugin("h", "s", "kill -9 $$", 5*time.Second, 0, 0, 0)
+	if cr.ReturnCode != 137 || ExitSignal(cr.ReturnCode) != 9 {
+		t.Errorf("got return code %d, want 137", cr.ReturnCode)
+	}
+}
@@ -2,6 +2,7 @@ package checker
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -70,6 +71,7 @@ type shellWorker struct {
 	ctlFile  *os.File
 	sentinel string
 	alive    bool
+	out      bytes.Buffer // reused for each command's output
 }
 
 // newShellWorker starts a persistent /bin/sh process running the read-eval loop.
@@ -106,6 +108,7 @@ func newShellWorker(sentinel string) (*shellWorker, error) {
 
 	// Close our copy of the write end — the child has its own.
 	ctlW.Close()
+	joinCheckCgroup(cmd.Process.Pid)
 
 	scanner := bufio.NewScanner(stdout)
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) // up to 1MB lines
@@ -121,6 +124,15 @@ func newShellWorker(sentinel string) (*shellWorker, error) {
 	}, nil
 }
 
+// source returns the check_source for checks run by this worker, naming the
+// persistent shell's pid like a Nagios 4 core worker.
+func (sw *shellWorker) source() string {
+	if sw.cmd == nil || sw.cmd.Process == nil {
+		return localSource
+	}
+	return "Core Worker " + strconv.Itoa(sw.cmd.Process.Pid)
+}
+
 // Run sends a command to the persistent shell and reads output until the
 // sentinel line. Returns the captured output, the subshell's exit code, and
 // any error. On timeout, only the subshell's process group is killed — the
@@ -159,30 +171,33 @@ func (sw *shellWorker) Run(command string, timeout time.Duration) (output string
 		syscall.Kill(-pid, syscall.SIGKILL)
 	})
 
-	var b strings.Builder
-	sentinelPrefix := sw.sentinel + " "
+	// Lines are read as bytes and copied into the worker's buffer, which is
+	// reused for every command; only what is kept becomes a string.
+	b := &sw.out
+	b.Reset()
+	sentinelPrefix := []byte(sw.sentinel + " ")
 	for sw.stdout.Scan() {
-		line := sw.stdout.Text()
-		if strings.HasPrefix(line, sentinelPrefix) {
+		line := sw.stdout.Bytes()
+		if bytes.HasPrefix(line, sentinelPrefix) {
 			timer.Stop()
-			codeStr := line[len(sentinelPrefix):]
+			codeStr := string(line[len(sentinelPrefix):])
 			code, parseErr := strconv.Atoi(codeStr)
 			if parseErr != nil {
 				code = 2
 			}
-			out := b.String()
-			if len(out) > 8192 {
-				out = out[:8192]
-			}
+			out := outputString(b.Bytes())
 			if timedOut.Load() {
 				return out, 2, ErrCheckTimeout
 			}
 			return out, code, nil
 		}
+		if b.Len() > maxOutputLength {
+			continue // drained, but past what is kept
+		}
 		if b.Len() > 0 {
 			b.WriteByte('\n')
 		}
-		b.WriteString(line)
+		b.Write(line)
 	}
 
 	// Scanner stopped — worker died unexpectedly.
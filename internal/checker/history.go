@@ -0,0 +1,75 @@
+package checker
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// HistoryResult is one check result served by HistoryHandler.
+type HistoryResult struct {
+	Time      int64   `json:"time"`
+	State     int     `json:"state"`
+	StateType int     `json:"state_type"`
+	Duration  float64 `json:"duration"`
+	Output    string  `json:"output"`
+}
+
+// HistoryReport is the check history of a host or service.
+type HistoryReport struct {
+	Host    string          `json:"host_name"`
+	Service string          `json:"service_description,omitempty"`
+	Size    int             `json:"size"`
+	Results []HistoryResult `json:"results"`
+}
+
+// HistoryHandler serves the latest check results of the host named by the
+// host parameter, or of its service named by the service parameter, as
+// JSON, oldest first.
+func HistoryHandler(store *objects.ObjectStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hostName, desc := r.URL.Query().Get("host"), r.URL.Query().Get("service")
+		if hostName == "" {
+			http.Error(w, "host parameter required", http.StatusBadRequest)
+			return
+		}
+		store.Mu.RLock()
+		hist, ok := lookupHistory(store, hostName, desc)
+		store.Mu.RUnlock()
+		if !ok {
+			http.Error(w, "no such host or service", http.StatusNotFound)
+			return
+		}
+
+		report := HistoryReport{Host: hostName, Service: desc, Size: hist.Size(), Results: []HistoryResult{}}
+		for _, rec := range hist.Records() {
+			report.Results = append(report.Results, HistoryResult{
+				Time:      rec.Time.Unix(),
+				State:     rec.State,
+				StateType: rec.StateType,
+				Duration:  rec.Duration,
+				Output:    rec.Output,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	})
+}
+
+// lookupHistory returns the check history of the host, or of its service
+// when desc is set, and whether the object exists.
+func lookupHistory(store *objects.ObjectStore, hostName, desc string) (*objects.CheckHistory, bool) {
+	if desc != "" {
+		if svc := store.GetService(hostName, desc); svc != nil {
+			return svc.CheckHistory, true
+		}
+		return nil, false
+	}
+	if h := store.GetHost(hostName); h != nil {
+		return h.CheckHistory, true
+	}
+	return nil, false
+}
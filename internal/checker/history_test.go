@@ -0,0 +1,77 @@
+package checker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CheckHistorySize = 2
+	svc := newTestService()
+	store := objects.NewObjectStore()
+	store.AddHost(svc.Host)
+	store.AddService(svc)
+	h := &ServiceResultHandler{Cfg: cfg}
+
+	start := time.Unix(1700000000, 0)
+	for i, rc := range []int{0, 2, 2} {
+		now := start.Add(time.Duration(i) * time.Minute)
+		h.HandleResult(svc, &objects.CheckResult{
+			ReturnCode:    rc,
+			ExitedOK:      true,
+			Output:        "check output|time=1s",
+			StartTime:     now,
+			FinishTime:    now,
+			ExecutionTime: 0.5,
+		})
+	}
+
+	srv := httptest.NewServer(HistoryHandler(store))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "?host=testhost&service=testsvc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var report HistoryReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Size != 2 || len(report.Results) != 2 {
+		t.Fatalf("report = %+v, want the last 2 results", report)
+	}
+	first, last := report.Results[0], report.Results[1]
+	if first.Time != start.Add(time.Minute).Unix() || first.State != objects.ServiceCritical ||
+		first.StateType != objects.StateTypeSoft || first.Output != "check output" || first.Duration != 0.5 {
+		t.Errorf("first result = %+v", first)
+	}
+	if last.StateType != objects.StateTypeSoft || last.Time != start.Add(2*time.Minute).Unix() {
+		t.Errorf("last result = %+v", last)
+	}
+
+	// The host has no results yet; an unknown service is not found.
+	resp, err = http.Get(srv.URL + "?host=testhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = HistoryReport{}
+	json.NewDecoder(resp.Body).Decode(&report)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || report.Results == nil || len(report.Results) != 0 {
+		t.Errorf("host: status %d, report %+v", resp.StatusCode, report)
+	}
+	resp, err = http.Get(srv.URL + "?host=testhost&service=missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing service: status %d, want 404", resp.StatusCode)
+	}
+}
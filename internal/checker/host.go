@@ -9,8 +9,15 @@ import (
 // HostResultHandler processes host check results and manages state.
 type HostResultHandler struct {
 	Cfg *objects.Config
+	// ExitCodes maps out-of-bounds return codes to states, read as service
+	// states: OK is UP, WARNING is UP unless aggressive host checking is
+	// on, anything else is DOWN. nil maps them all to DOWN.
+	ExitCodes *ExitCodeMap
 	// OnStateChange is called on host state changes.
 	OnStateChange func(h *objects.Host, oldState, newState int, hardChange bool)
+	// OnHardStateChange is called after OnStateChange when the result
+	// changed the hard state, which LastHardStateCause now records.
+	OnHardStateChange func(h *objects.Host)
 	// OnNotification is called when a notification should be sent.
 	OnNotification func(h *objects.Host, notifType int)
 	// ScheduleHostCheck requests a host check (for parent/child propagation).
@@ -45,21 +52,24 @@ func (h *HostResultHandler) HandleResult(host *objects.Host, cr *objects.CheckRe
 	host.ExecutionTime = cr.ExecutionTime
 	host.LastCheck = cr.StartTime
 	host.HasBeenChecked = true
+	host.CheckSource = cr.Source
 
 	if cr.CheckOptions&objects.CheckOptionFreshnessCheck != 0 && host.IsBeingFreshened {
 		host.IsBeingFreshened = false
 	}
 
-	// Parse output
+	// Parse output, after an out-of-bounds return code has replaced it
+	mappedState, outOfBounds := h.ExitCodes.Normalize(cr)
 	parsed := ParseCheckOutput(cr.Output)
-	cr.Output = AugmentReturnCodeOutput(cr)
 	host.PluginOutput = parsed.ShortOutput
 	host.LongPluginOutput = parsed.LongOutput
 	host.PerfData = parsed.PerfData
 
 	// Determine new state
 	var newState int
-	if cr.CheckType == objects.CheckTypePassive && !h.Cfg.TranslatePassiveHostChecks {
+	if outOfBounds {
+		newState = GetHostCheckReturnCode(&objects.CheckResult{ReturnCode: mappedState, ExitedOK: true}, h.Cfg.UseAggressiveHostChecking)
+	} else if cr.CheckType == objects.CheckTypePassive && !h.Cfg.TranslatePassiveHostChecks {
 		newState = GetPassiveHostCheckReturnCode(cr.ReturnCode)
 	} else {
 		newState = GetHostCheckReturnCode(cr, h.Cfg.UseAggressiveHostChecking)
@@ -86,76 +96,42 @@ func (h *HostResultHandler) HandleResult(host *objects.Host, cr *objects.CheckRe
 	lastState := host.CurrentState
 	lastStateType := host.StateType
 	stateChange := newState != lastState
-	hardChange := false
 
 	// Update current state early so notification callbacks see the correct state.
-	// All state machine branches below use local lastState, not host.CurrentState.
+	// The state machine below uses local lastState, not host.CurrentState.
 	host.CurrentState = newState
 	host.LastState = lastState
 
 	// --- SOFT/HARD state machine ---
 
-	if newState == objects.HostUp {
-		// Recovery or continued UP
-		if lastState != objects.HostUp {
-			// Clear acknowledgement but preserve NotifiedOn until after
-			// the recovery notification is sent (viability check needs it).
-			host.ProblemAcknowledged = false
-			host.AckType = objects.AckNone
-			host.LastNotification = time.Time{}
-			host.NextNotification = time.Time{}
-			host.NoMoreNotifications = false
-			host.FirstProblemTime = time.Time{}
-			if lastStateType == objects.StateTypeHard {
-				hardChange = true
-				host.StateType = objects.StateTypeHard
-				host.CurrentAttempt = 1
-				if h.OnNotification != nil {
-					h.OnNotification(host, objects.NotificationNormal)
-				}
-			} else {
-				host.StateType = objects.StateTypeHard
-				host.CurrentAttempt = 1
-			}
-			// Now safe to clear notification tracking state
-			host.CurrentNotificationNumber = 0
-			host.NotifiedOn = 0
-		} else {
-			host.StateType = objects.StateTypeHard
-			host.CurrentAttempt = 1
-		}
-	} else if host.MaxCheckAttempts <= 1 {
-		// Immediate HARD
-		host.StateType = objects.StateTypeHard
-		host.CurrentAttempt = 1
-		if stateChange || lastStateType == objects.StateTypeSoft {
-			hardChange = true
-			if h.OnNotification != nil {
-				h.OnNotification(host, objects.NotificationNormal)
-			}
-		}
-	} else if lastState == objects.HostUp && cr.CheckType == objects.CheckTypeActive {
-		// Active check: first failure -> SOFT
-		host.StateType = objects.StateTypeSoft
-	} else if lastState == objects.HostUp && cr.CheckType == objects.CheckTypePassive {
-		// Passive checks default to immediate HARD
-		host.StateType = objects.StateTypeHard
-		host.CurrentAttempt = host.MaxCheckAttempts
-		hardChange = true
-		if h.OnNotification != nil {
-			h.OnNotification(host, objects.NotificationNormal)
-		}
-	} else if host.StateType == objects.StateTypeSoft {
-		if host.CurrentAttempt >= host.MaxCheckAttempts {
-			host.StateType = objects.StateTypeHard
-			hardChange = true
-			if h.OnNotification != nil {
-				h.OnNotification(host, objects.NotificationNormal)
-			}
-		}
-	} else {
-		// Continued HARD non-UP
-		host.CurrentAttempt = host.MaxCheckAttempts
+	t := HostTransition(HostAttempt{
+		LastState:        lastState,
+		LastStateType:    lastStateType,
+		CurrentAttempt:   host.CurrentAttempt,
+		MaxCheckAttempts: host.MaxCheckAttempts,
+		NewState:         newState,
+		Passive:          cr.CheckType == objects.CheckTypePassive,
+	})
+	hardChange := t.HardChange
+	if t.Recovery {
+		// Clear acknowledgement but preserve NotifiedOn until after
+		// the recovery notification is sent (viability check needs it).
+		host.ProblemAcknowledged = false
+		host.AckType = objects.AckNone
+		host.LastNotification = time.Time{}
+		host.NextNotification = time.Time{}
+		host.NoMoreNotifications = false
+		host.FirstProblemTime = time.Time{}
+	}
+	host.StateType = t.StateType
+	host.CurrentAttempt = t.CurrentAttempt
+	if t.Notify && h.OnNotification != nil {
+		h.OnNotification(host, objects.NotificationNormal)
+	}
+	if t.Recovery {
+		// Now safe to clear notification tracking state
+		host.CurrentNotificationNumber = 0
+		host.NotifiedOn = 0
 	}
 
 	// Non-sticky ack: clear on any state change
@@ -164,15 +140,26 @@ func (h *HostResultHandler) HandleResult(host *objects.Host, cr *objects.CheckRe
 		host.AckType = objects.AckNone
 	}
 
+	hardStateChanged := false
 	if hardChange || (host.StateType == objects.StateTypeHard && lastStateType == objects.StateTypeHard && stateChange) {
 		host.LastHardState = newState
 		host.LastHardStateChange = now
+		host.LastHardStateCause = objects.NewStateChangeCause(cr, host.PluginOutput)
+		hardStateChanged = true
 	}
 
 	if stateChange {
 		host.LastStateChange = now
 	}
 
+	objects.RecordCheck(&host.CheckHistory, h.Cfg.CheckHistorySize, objects.CheckRecord{
+		Time:      now,
+		State:     newState,
+		StateType: host.StateType,
+		Duration:  cr.ExecutionTime,
+		Output:    host.PluginOutput,
+	})
+
 	// Flap detection
 	if host.FlapDetectionEnabled {
 		UpdateFlapHistory(&host.StateHistory, &host.StateHistoryIndex, &host.PercentStateChange, newState)
@@ -198,6 +185,9 @@ func (h *HostResultHandler) HandleResult(host *objects.Host, cr *objects.CheckRe
 	if h.OnStateChange != nil && (stateChange || hardChange) {
 		h.OnStateChange(host, lastState, newState, hardChange)
 	}
+	if hardStateChanged && h.OnHardStateChange != nil {
+		h.OnHardStateChange(host)
+	}
 
 	return hardChange
 }
@@ -247,6 +237,14 @@ func (h *HostResultHandler) propagateChecks(host *objects.Host, oldState, newSta
 	}
 }
 
+// SkipResult releases host after its active check result was discarded
+// unprocessed, e.g. by result middleware, and schedules the next check at
+// the normal interval.
+func (h *HostResultHandler) SkipResult(host *objects.Host, now time.Time) {
+	host.IsExecuting = false
+	host.NextCheck = now.Add(h.normalCheckWindow(host))
+}
+
 func (h *HostResultHandler) normalCheckWindow(host *objects.Host) time.Duration {
 	il := h.Cfg.IntervalLength
 	if il <= 0 {
@@ -0,0 +1,391 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func init() {
+	RegisterBuiltin("gogios_http", CheckHTTP)
+}
+
+// maxHTTPBody caps how much of a response body is read for matching.
+const maxHTTPBody = 1 << 20
+
+type httpCheckOpts struct {
+	host, address string
+	port          int
+	ssl           bool
+	path          string
+	method        string
+	headers       [][2]string
+	body          string
+	regex         *regexp.Regexp
+	substring     string
+	expect        []int
+	onRedirect    int // service state, or -1 to follow
+	maxRedirects  int
+	warn, crit    float64 // response time thresholds in seconds; 0 = unset
+	certWarn      int     // days; -1 = unset
+	certCrit      int
+	noBody        bool
+	verifyCert    bool
+	network       string // "tcp", or "tcp4"/"tcp6" for -4/-6
+}
+
+// CheckHTTP is the gogios_http builtin. It accepts a check_http-compatible
+// subset of options:
+//
+//	-H host  -I address  -p port  -S  -u path  -j method  -k "Header: value"
+//	-P body  -r regex  -R regex (case-insensitive)  -s string
+//	-e codes  -f ok|warning|critical|follow  -w secs  -c secs
+//	-C warn_days[,crit_days]  -N  --max-redirects n  --verify-cert  -4  -6
+//
+// IPv6 addresses may be given with or without brackets.
+func CheckHTTP(ctx context.Context, args []string) (int, string) {
+	o, err := parseHTTPCheckArgs(args)
+	if err != "" {
+		return objects.ServiceUnknown, "HTTP UNKNOWN - " + err
+	}
+
+	target := o.address
+	if target == "" {
+		target = o.host
+	}
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+	scheme := "http"
+	if o.ssl {
+		scheme = "https"
+	}
+	port := o.port
+	if port == 0 {
+		port = 80
+		if o.ssl {
+			port = 443
+		}
+	}
+	url := scheme + "://" + net.JoinHostPort(target, strconv.Itoa(port)) + o.path
+
+	var reqBody io.Reader
+	if o.body != "" {
+		reqBody = strings.NewReader(o.body)
+	}
+	req, rerr := http.NewRequestWithContext(ctx, o.method, url, reqBody)
+	if rerr != nil {
+		return objects.ServiceUnknown, "HTTP UNKNOWN - " + rerr.Error()
+	}
+	if o.host != "" {
+		req.Host = o.host
+	}
+	req.Header.Set("User-Agent", "gogios_http")
+	for _, h := range o.headers {
+		if strings.EqualFold(h[0], "Host") {
+			req.Host = h[1]
+			continue
+		}
+		req.Header.Add(h[0], h[1])
+	}
+
+	// The first request goes to -I's address but is for the -H virtual
+	// host. A followed redirect to another host is for that host, so the
+	// TLS config is built per connection rather than pinned to -H.
+	serverName := o.host
+	if serverName == "" {
+		serverName = target
+	}
+	firstAddr := net.JoinHostPort(target, strconv.Itoa(port))
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return builtinDialer.DialContext(ctx, o.network, addr)
+			},
+			DialTLSContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				cfg := &tls.Config{
+					ServerName: serverName,
+					// Like check_http, don't fail on self-signed or mismatched
+					// certificates unless asked to; expiry is still reported.
+					InsecureSkipVerify: !o.verifyCert,
+				}
+				if addr != firstAddr {
+					cfg.ServerName, _, _ = net.SplitHostPort(addr)
+				}
+				conn, err := builtinDialer.DialContext(ctx, o.network, addr)
+				if err != nil {
+					return nil, err
+				}
+				tc := tls.Client(conn, cfg)
+				if err := tc.HandshakeContext(ctx); err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return tc, nil
+			},
+		},
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if o.onRedirect != -1 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > o.maxRedirects {
+				return fmt.Errorf("maximum redirection depth %d exceeded", o.maxRedirects)
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	resp, rerr := client.Do(req)
+	if rerr != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return objects.ServiceCritical, "HTTP CRITICAL - Socket timeout"
+		}
+		return objects.ServiceCritical, "HTTP CRITICAL - " + rerr.Error()
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if !o.noBody {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	}
+	elapsed := time.Since(start).Seconds()
+
+	state := objects.ServiceOK
+	var problems []string
+
+	if len(o.expect) > 0 {
+		matched := false
+		for _, code := range o.expect {
+			if resp.StatusCode == code {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			state = objects.ServiceCritical
+			problems = append(problems, fmt.Sprintf("Invalid HTTP response received from host: %s", resp.Status))
+		}
+	} else {
+		switch {
+		case resp.StatusCode >= 500:
+			state = objects.ServiceCritical
+		case resp.StatusCode >= 400:
+			state = objects.ServiceWarning
+		case resp.StatusCode >= 300 && o.onRedirect != -1:
+			state = o.onRedirect
+		}
+	}
+
+	if o.substring != "" && !strings.Contains(string(body), o.substring) {
+		state = objects.ServiceCritical
+		problems = append(problems, fmt.Sprintf("string '%s' not found on '%s'", o.substring, url))
+	}
+	if o.regex != nil && !o.regex.Match(body) {
+		state = objects.ServiceCritical
+		problems = append(problems, "pattern not found")
+	}
+
+	switch {
+	case o.crit > 0 && elapsed > o.crit:
+		state = worstState(state, objects.ServiceCritical)
+	case o.warn > 0 && elapsed > o.warn:
+		state = worstState(state, objects.ServiceWarning)
+	}
+
+	if o.certWarn >= 0 && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		days := int(math.Floor(time.Until(cert.NotAfter).Hours() / 24))
+		certState := objects.ServiceOK
+		switch {
+		case days < 0:
+			certState = objects.ServiceCritical
+		case o.certCrit >= 0 && days < o.certCrit:
+			certState = objects.ServiceCritical
+		case days < o.certWarn:
+			certState = objects.ServiceWarning
+		}
+		if certState != objects.ServiceOK {
+			problems = append(problems, fmt.Sprintf("Certificate '%s' expires in %d day(s) (%s)",
+				cert.Subject.CommonName, days, cert.NotAfter.Format("2006-01-02 15:04 -0700")))
+		}
+		state = worstState(state, certState)
+	}
+
+	label := map[int]string{
+		objects.ServiceOK:       "HTTP OK",
+		objects.ServiceWarning:  "HTTP WARNING",
+		objects.ServiceCritical: "HTTP CRITICAL",
+		objects.ServiceUnknown:  "HTTP UNKNOWN",
+	}[state]
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s %s", label, resp.Proto, resp.Status)
+	for _, p := range problems {
+		sb.WriteString(" - ")
+		sb.WriteString(p)
+	}
+	fmt.Fprintf(&sb, " - %d bytes in %.3f second response time |time=%.6fs;%s;%s;0.000000 size=%dB;;;0",
+		len(body), elapsed, elapsed, thresholdString(o.warn), thresholdString(o.crit), len(body))
+	return state, sb.String()
+}
+
+func thresholdString(v float64) string {
+	if v <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+func parseHTTPCheckArgs(args []string) (*httpCheckOpts, string) {
+	o := &httpCheckOpts{
+		path:         "/",
+		onRedirect:   objects.ServiceOK,
+		maxRedirects: 15,
+		certWarn:     -1,
+		certCrit:     -1,
+		network:      "tcp",
+	}
+	noValue := map[string]bool{
+		"-S": true, "--ssl": true, "-N": true, "--no-body": true, "--verify-cert": true, "--sni": true,
+		"-4": true, "--use-ipv4": true, "-6": true, "--use-ipv6": true,
+	}
+	f := &builtinFlags{args: args}
+	for {
+		name, val, ok, err := f.next(func(n string) bool { return !noValue[n] })
+		if err != "" {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		switch name {
+		case "-H", "--hostname":
+			o.host = val
+		case "-I", "--IP-address":
+			o.address = val
+		case "-p", "--port":
+			p, perr := strconv.Atoi(val)
+			if perr != nil || p <= 0 || p > 65535 {
+				return nil, "invalid port '" + val + "'"
+			}
+			o.port = p
+		case "-S", "--ssl":
+			o.ssl = true
+		case "--sni":
+			// SNI is always sent
+		case "-4", "--use-ipv4":
+			o.network = "tcp4"
+		case "-6", "--use-ipv6":
+			o.network = "tcp6"
+		case "-u", "--url":
+			if !strings.HasPrefix(val, "/") {
+				val = "/" + val
+			}
+			o.path = val
+		case "-j", "--method":
+			o.method = strings.ToUpper(val)
+		case "-k", "--header":
+			colon := strings.IndexByte(val, ':')
+			if colon <= 0 {
+				return nil, "invalid header '" + val + "'"
+			}
+			o.headers = append(o.headers, [2]string{strings.TrimSpace(val[:colon]), strings.TrimSpace(val[colon+1:])})
+		case "-P", "--post":
+			o.body = val
+		case "-r", "--regex", "-R", "--eregi":
+			expr := val
+			if name == "-R" || name == "--eregi" {
+				expr = "(?i)" + expr
+			}
+			re, rerr := regexp.Compile(expr)
+			if rerr != nil {
+				return nil, "invalid regex: " + rerr.Error()
+			}
+			o.regex = re
+		case "-s", "--string":
+			o.substring = val
+		case "-e", "--expect":
+			for _, part := range strings.Split(val, ",") {
+				code, cerr := strconv.Atoi(strings.TrimSpace(part))
+				if cerr != nil {
+					return nil, "invalid status code '" + part + "'"
+				}
+				o.expect = append(o.expect, code)
+			}
+		case "-f", "--onredirect":
+			switch strings.ToLower(val) {
+			case "ok":
+				o.onRedirect = objects.ServiceOK
+			case "warning":
+				o.onRedirect = objects.ServiceWarning
+			case "critical":
+				o.onRedirect = objects.ServiceCritical
+			case "follow":
+				o.onRedirect = -1
+			default:
+				return nil, "invalid onredirect option '" + val + "'"
+			}
+		case "--max-redirects":
+			n, nerr := strconv.Atoi(val)
+			if nerr != nil || n < 0 {
+				return nil, "invalid max-redirects '" + val + "'"
+			}
+			o.maxRedirects = n
+		case "-w", "--warning":
+			v, verr := strconv.ParseFloat(val, 64)
+			if verr != nil {
+				return nil, "invalid warning threshold '" + val + "'"
+			}
+			o.warn = v
+		case "-c", "--critical":
+			v, verr := strconv.ParseFloat(val, 64)
+			if verr != nil {
+				return nil, "invalid critical threshold '" + val + "'"
+			}
+			o.crit = v
+		case "-C", "--certificate":
+			parts := strings.SplitN(val, ",", 2)
+			w, werr := strconv.Atoi(parts[0])
+			if werr != nil {
+				return nil, "invalid certificate threshold '" + val + "'"
+			}
+			o.certWarn = w
+			if len(parts) == 2 {
+				c, cerr := strconv.Atoi(parts[1])
+				if cerr != nil {
+					return nil, "invalid certificate threshold '" + val + "'"
+				}
+				o.certCrit = c
+			}
+		case "-t", "--timeout":
+			// enforced by the executor's check timeout
+		case "-N", "--no-body":
+			o.noBody = true
+		case "--verify-cert":
+			o.verifyCert = true
+		default:
+			return nil, "unknown option " + name
+		}
+	}
+	if o.host == "" && o.address == "" {
+		return nil, "no host specified (-H or -I)"
+	}
+	if o.method == "" {
+		o.method = http.MethodGet
+		if o.body != "" {
+			o.method = http.MethodPost
+		}
+	}
+	return o, ""
+}
@@ -0,0 +1,155 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func splitHostPort(t *testing.T, url string) (string, string) {
+	t.Helper()
+	hostport := url[strings.Index(url, "://")+3:]
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port
+}
+
+func TestCheckHTTPStatusAndBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			if r.Header.Get("X-Token") != "abc" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"status":"healthy"}`))
+		case "/moved":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	host, port := splitHostPort(t, srv.URL)
+	ctx := context.Background()
+
+	rc, out := CheckHTTP(ctx, []string{"-I", host, "-p", port, "-u", "/health", "-k", "X-Token: abc", "-r", `"status":"healthy"`})
+	if rc != objects.ServiceOK || !strings.HasPrefix(out, "HTTP OK") || !strings.Contains(out, "|time=") {
+		t.Errorf("health: rc=%d out=%q", rc, out)
+	}
+
+	rc, out = CheckHTTP(ctx, []string{"-I", host, "-p", port, "-u", "/health"})
+	if rc != objects.ServiceWarning {
+		t.Errorf("403 should be WARNING, got rc=%d out=%q", rc, out)
+	}
+
+	rc, _ = CheckHTTP(ctx, []string{"-I", host, "-p", port, "-u", "/other"})
+	if rc != objects.ServiceCritical {
+		t.Errorf("500 should be CRITICAL, got rc=%d", rc)
+	}
+
+	rc, out = CheckHTTP(ctx, []string{"-I", host, "-p", port, "-u", "/health", "-k", "X-Token: abc", "-s", "nope"})
+	if rc != objects.ServiceCritical || !strings.Contains(out, "not found") {
+		t.Errorf("missing string: rc=%d out=%q", rc, out)
+	}
+
+	rc, _ = CheckHTTP(ctx, []string{"-I", host, "-p", port, "-u", "/moved", "-f", "critical"})
+	if rc != objects.ServiceCritical {
+		t.Errorf("redirect with -f critical: got rc=%d", rc)
+	}
+	rc, _ = CheckHTTP(ctx, []string{"-I", host, "-p", port, "-u", "/moved", "-f", "follow", "-e", "403"})
+	if rc != objects.ServiceOK {
+		t.Errorf("followed redirect should land on 403 and match -e, got rc=%d", rc)
+	}
+
+	rc, out = CheckHTTP(ctx, []string{"-p", port})
+	if rc != objects.ServiceUnknown {
+		t.Errorf("missing host should be UNKNOWN, got rc=%d out=%q", rc, out)
+	}
+}
+
+func TestCheckHTTPCertificateExpiry(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	host, port := splitHostPort(t, srv.URL)
+	notAfter := srv.Certificate().NotAfter
+	days := int(time.Until(notAfter).Hours() / 24)
+
+	rc, out := CheckHTTP(context.Background(), []string{"-I", host, "-p", port, "-S", "-C", "1,0"})
+	if rc != objects.ServiceOK {
+		t.Errorf("cert with %d days left and 1-day warning: rc=%d out=%q", days, rc, out)
+	}
+	rc, out = CheckHTTP(context.Background(), []string{"-I", host, "-p", port, "-S", "-C", strconv.Itoa(days + 10)})
+	if rc != objects.ServiceWarning || !strings.Contains(out, "expires in") {
+		t.Errorf("cert inside warning window: rc=%d out=%q", rc, out)
+	}
+	rc, _ = CheckHTTP(context.Background(), []string{"-I", host, "-p", port, "-S", "-C", strconv.Itoa(days+20) + "," + strconv.Itoa(days+10)})
+	if rc != objects.ServiceCritical {
+		t.Errorf("cert inside critical window: rc=%d", rc)
+	}
+}
+
+func TestCheckHTTPRedirectServerName(t *testing.T) {
+	names := make(chan string, 2)
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names <- r.TLS.ServerName
+	}))
+	defer other.Close()
+	_, otherPort := splitHostPort(t, other.URL)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names <- r.TLS.ServerName
+		http.Redirect(w, r, "https://localhost:"+otherPort+"/", http.StatusFound)
+	}))
+	defer srv.Close()
+	host, port := splitHostPort(t, srv.URL)
+
+	rc, out := CheckHTTP(context.Background(), []string{"-I", host, "-H", "www.example.com", "-p", port, "-S", "-f", "follow"})
+	if rc != objects.ServiceOK {
+		t.Fatalf("rc=%d out=%q", rc, out)
+	}
+	if first, second := <-names, <-names; first != "www.example.com" || second != "localhost" {
+		t.Errorf("SNI was %q then %q, want www.example.com then localhost", first, second)
+	}
+}
+
+func TestCheckHTTPIPv6(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("no IPv6 loopback:", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+	_, port := splitHostPort(t, srv.URL)
+	ctx := context.Background()
+
+	for _, addr := range []string{"::1", "[::1]"} {
+		if rc, out := CheckHTTP(ctx, []string{"-I", addr, "-p", port, "-6"}); rc != objects.ServiceOK {
+			t.Errorf("-I %s -6: rc=%d out=%q", addr, rc, out)
+		}
+	}
+	if rc, _ := CheckHTTP(ctx, []string{"-I", "::1", "-p", port, "-4"}); rc != objects.ServiceCritical {
+		t.Errorf("-4 against an IPv6 address should fail, got rc=%d", rc)
+	}
+}
+
+func TestLookupBuiltin(t *testing.T) {
+	if _, args, ok := LookupBuiltin(`/usr/local/bin/gogios_http -H example.com -u '/a b'`); !ok || len(args) != 4 || args[3] != "/a b" {
+		t.Errorf("expected gogios_http builtin with 4 args, got ok=%v args=%q", ok, args)
+	}
+	if _, _, ok := LookupBuiltin("/usr/lib/nagios/plugins/check_http -H example.com"); ok {
+		t.Error("check_http must not be treated as a builtin")
+	}
+}
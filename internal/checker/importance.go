@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ImportanceSubmitter is implemented by runners that can order queued
+// checks by importance (the host/service hourly_value) when saturated.
+type ImportanceSubmitter interface {
+	SubmitImportance(importance uint, hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64)
+}
+
+// ImportanceTierStats summarizes queue wait for checks of one importance.
+type ImportanceTierStats struct {
+	Importance uint
+	Dispatched int64
+	TotalWait  time.Duration
+	MaxWait    time.Duration
+}
+
+// AvgWait returns the mean queue wait.
+func (t ImportanceTierStats) AvgWait() time.Duration {
+	if t.Dispatched == 0 {
+		return 0
+	}
+	return t.TotalWait / time.Duration(t.Dispatched)
+}
+
+type queuedJob struct {
+	job        checkJob
+	importance uint
+	seq        uint64
+	enqueued   time.Time
+}
+
+// importanceHeap orders by importance (highest first), then FIFO.
+type importanceHeap []*queuedJob
+
+func (h importanceHeap) Len() int { return len(h) }
+func (h importanceHeap) Less(i, j int) bool {
+	if h[i].importance != h[j].importance {
+		return h[i].importance > h[j].importance
+	}
+	return h[i].seq < h[j].seq
+}
+func (h importanceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *importanceHeap) Push(x any)   { *h = append(*h, x.(*queuedJob)) }
+func (h *importanceHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// importanceQueue is an unbounded priority queue between Submit and the
+// worker pool. Jobs only wait here while every worker is busy, so ordering
+// matters exactly when the executor is saturated.
+type importanceQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  importanceHeap
+	seq    uint64
+	closed bool
+	tiers  map[uint]*ImportanceTierStats
+}
+
+func newImportanceQueue() *importanceQueue {
+	q := &importanceQueue{tiers: make(map[uint]*ImportanceTierStats)}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *importanceQueue) push(job checkJob, importance uint) {
+	q.mu.Lock()
+	if !q.closed {
+		q.seq++
+		heap.Push(&q.items, &queuedJob{job: job, importance: importance, seq: q.seq, enqueued: time.Now()})
+		q.cond.Signal()
+	}
+	q.mu.Unlock()
+}
+
+// pop blocks until a job is available. It returns false once the queue is
+// closed; jobs still queued at that point are dropped.
+func (q *importanceQueue) pop() (*queuedJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	return heap.Pop(&q.items).(*queuedJob), true
+}
+
+func (q *importanceQueue) record(importance uint, wait time.Duration) {
+	q.mu.Lock()
+	t := q.tiers[importance]
+	if t == nil {
+		t = &ImportanceTierStats{Importance: importance}
+		q.tiers[importance] = t
+	}
+	t.Dispatched++
+	t.TotalWait += wait
+	t.MaxWait = max(t.MaxWait, wait)
+	q.mu.Unlock()
+}
+
+func (q *importanceQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *importanceQueue) stats() []ImportanceTierStats {
+	q.mu.Lock()
+	result := make([]ImportanceTierStats, 0, len(q.tiers))
+	for _, t := range q.tiers {
+		result = append(result, *t)
+	}
+	q.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].Importance > result[j].Importance })
+	return result
+}
+
+func (q *importanceQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
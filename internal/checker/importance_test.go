@@ -0,0 +1,66 @@
+package checker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestImportanceQueueOrdering(t *testing.T) {
+	q := newImportanceQueue()
+	q.push(checkJob{svcDesc: "low-1"}, 1)
+	q.push(checkJob{svcDesc: "high"}, 100)
+	q.push(checkJob{svcDesc: "low-2"}, 1)
+	q.push(checkJob{svcDesc: "mid"}, 10)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		qj, ok := q.pop()
+		if !ok {
+			t.Fatal("queue closed early")
+		}
+		got = append(got, qj.job.svcDesc)
+	}
+	want := []string{"high", "mid", "low-1", "low-2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pop order = %v, want %v", got, want)
+		}
+	}
+
+	q.close()
+	if _, ok := q.pop(); ok {
+		t.Error("pop after close should fail")
+	}
+}
+
+func TestImportanceExecutorDispatchesImportantFirst(t *testing.T) {
+	resultCh := make(chan *objects.CheckResult, 10)
+	e := NewImportanceExecutor(1, resultCh)
+	defer e.Stop()
+
+	// Occupy the only worker so the next two checks queue up.
+	e.SubmitImportance(0, "h", "busy", "sleep 0.3", 5*time.Second, 0, 0, 0)
+	time.Sleep(50 * time.Millisecond)
+	e.SubmitImportance(1, "h", "low", "true", 5*time.Second, 0, 0, 0)
+	e.SubmitImportance(50, "h", "high", "true", 5*time.Second, 0, 0, 0)
+
+	var order []string
+	for i := 0; i < 3; i++ {
+		select {
+		case cr := <-resultCh:
+			order = append(order, cr.ServiceDescription)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out, got %v", order)
+		}
+	}
+	if order[1] != "high" || order[2] != "low" {
+		t.Errorf("dispatch order = %v, want busy, high, low", order)
+	}
+
+	stats := e.ImportanceStats()
+	if len(stats) != 3 || stats[0].Importance != 50 || stats[0].MaxWait < 100*time.Millisecond {
+		t.Errorf("unexpected tier stats %+v", stats)
+	}
+}
@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Custom variables that cap the resources of one check's plugin. A service
+// variable overrides the same variable on its host; both override the
+// check_rlimit_* defaults in nagios.cfg.
+const (
+	RlimitCPUCustomVar    = "CHECK_RLIMIT_CPU"    // CPU seconds
+	RlimitMemoryCustomVar = "CHECK_RLIMIT_MEMORY" // address space in MB
+	RlimitNoFileCustomVar = "CHECK_RLIMIT_NOFILE" // open file descriptors
+)
+
+// Rlimits are resource limits for a check plugin, set with ulimit in the
+// shell that runs it so they cover the plugin and everything it starts.
+// Zero leaves a limit as inherited.
+type Rlimits struct {
+	CPU    int // seconds of CPU time (ulimit -t)
+	Memory int // MB of address space (ulimit -v)
+	NoFile int // open file descriptors (ulimit -n)
+}
+
+// Or fills the limits l leaves unset from def.
+func (l Rlimits) Or(def Rlimits) Rlimits {
+	if l.CPU == 0 {
+		l.CPU = def.CPU
+	}
+	if l.Memory == 0 {
+		l.Memory = def.Memory
+	}
+	if l.NoFile == 0 {
+		l.NoFile = def.NoFile
+	}
+	return l
+}
+
+// prefix returns the ulimit statements for l. A limit the shell refuses,
+// e.g. one above the hard limit, fails the check as UNKNOWN rather than
+// running it unconfined.
+func (l Rlimits) prefix() string {
+	var b strings.Builder
+	if l.CPU > 0 {
+		fmt.Fprintf(&b, "ulimit -t %d || exit 3; ", l.CPU)
+	}
+	if l.Memory > 0 {
+		fmt.Fprintf(&b, "ulimit -v %d || exit 3; ", l.Memory*1024)
+	}
+	if l.NoFile > 0 {
+		fmt.Fprintf(&b, "ulimit -n %d || exit 3; ", l.NoFile)
+	}
+	return b.String()
+}
+
+func parseRlimit(name, v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid _%s %q", name, v)
+	}
+	return n, nil
+}
+
+// checkCgroupProcs is the cgroup.procs file of the cgroup check workers
+// join; empty when check_cgroup is not set.
+var checkCgroupProcs string
+
+// SetCheckCgroup makes the shells that run check plugins join the cgroup
+// v2 directory dir, creating it if needed, so the limits configured there
+// (memory.max, cpu.max, pids.max) bound all plugins together. It must be
+// called before any executor is created.
+func SetCheckCgroup(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("check_cgroup: %w", err)
+	}
+	procs := filepath.Join(dir, "cgroup.procs")
+	if _, err := os.Stat(procs); err != nil {
+		return fmt.Errorf("check_cgroup: %s is not a cgroup v2 directory", dir)
+	}
+	checkCgroupProcs = procs
+	return nil
+}
+
+// joinCheckCgroup moves process pid into the check cgroup, if any. It is
+// called right after a worker starts, before it runs a check, so every
+// plugin the worker starts is born inside the cgroup.
+func joinCheckCgroup(pid int) {
+	if checkCgroupProcs == "" {
+		return
+	}
+	if err := os.WriteFile(checkCgroupProcs, []byte(strconv.Itoa(pid)), 0); err != nil {
+		log.Printf("Warning: could not move check worker %d into %s: %v", pid, filepath.Dir(checkCgroupProcs), err)
+	}
+}
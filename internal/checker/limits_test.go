@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestExecEnvFor_Rlimits(t *testing.T) {
+	h := &objects.Host{Name: "h", CustomVars: map[string]string{
+		RlimitCPUCustomVar:    "10",
+		RlimitNoFileCustomVar: "64",
+	}}
+	svc := &objects.Service{Host: h, CustomVars: map[string]string{RlimitCPUCustomVar: "2"}}
+	e, err := ExecEnvFor(h, svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Rlimits{CPU: 2, NoFile: 64}); e.Limits != want {
+		t.Errorf("got %+v, want %+v", e.Limits, want)
+	}
+	if got, want := e.Limits.Or(Rlimits{CPU: 30, Memory: 512}), (Rlimits{CPU: 2, Memory: 512, NoFile: 64}); got != want {
+		t.Errorf("Or: got %+v, want %+v", got, want)
+	}
+
+	svc.CustomVars[RlimitMemoryCustomVar] = "lots"
+	if _, err := ExecEnvFor(h, svc); err == nil {
+		t.Error("expected an error for an invalid memory limit")
+	}
+}
+
+func TestRlimits_RunInShell(t *testing.T) {
+	e := ExecEnv{Limits: Rlimits{CPU: 7, Memory: 256, NoFile: 32}}
+	cr := new(Executor).runPlugin("h", "s", e.Wrap(`echo "$(ulimit -t) $(ulimit -v) $(ulimit -n)"`), 5*time.Second, 0, 0, 0)
+	if want := "7 262144 32\n"; cr.ReturnCode != 0 || cr.Output != want {
+		t.Errorf("got %d %q, want %q", cr.ReturnCode, cr.Output, want)
+	}
+
+	// Raising a limit past the hard limit fails the check instead of
+	// running it unconfined.
+	e = ExecEnv{Limits: Rlimits{NoFile: 1 << 30}}
+	cr = new(Executor).runPlugin("h", "s", "ulimit -H -n 64; "+e.Wrap("echo ran"), 5*time.Second, 0, 0, 0)
+	if cr.ReturnCode != 3 || strings.Contains(cr.Output, "ran") {
+		t.Errorf("expected UNKNOWN without running, got %d %q", cr.ReturnCode, cr.Output)
+	}
+}
+
+func TestSetCheckCgroup_RequiresCgroupDir(t *testing.T) {
+	if err := SetCheckCgroup(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory that is not a cgroup")
+	}
+	if checkCgroupProcs != "" {
+		t.Errorf("cgroup set after a failed SetCheckCgroup: %s", checkCgroupProcs)
+	}
+}
+
+func TestRlimits_GlobalDefaultKeepsBuiltins(t *testing.T) {
+	global := Rlimits{CPU: 30, Memory: 512, NoFile: 256}
+	var e ExecEnv
+	e.Limits = e.Limits.Or(global)
+
+	for _, name := range []string{"gogios_http", "gogios_dns", "gogios_tcp"} {
+		command := e.Wrap(name + " -H example.com")
+		if _, _, ok := LookupBuiltin(command); !ok {
+			t.Errorf("%s: wrapped command %q no longer resolves to the builtin", name, command)
+		}
+	}
+	if command := e.Wrap("/usr/lib/nagios/plugins/check_ping -H example.com"); !strings.HasPrefix(command, "ulimit -t 30 || exit 3; ") {
+		t.Errorf("forked plugin not limited: %q", command)
+	}
+}
@@ -0,0 +1,91 @@
+package checker
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// ResultMiddleware transforms a check result before it is processed. It
+// returns the result to carry on with: cr itself, edited in place, a
+// replacement, or nil to drop the result.
+type ResultMiddleware func(cr *objects.CheckResult) *objects.CheckResult
+
+type namedMiddleware struct {
+	name string
+	fn   ResultMiddleware
+}
+
+var (
+	middlewareMu     sync.Mutex
+	resultMiddleware []namedMiddleware
+)
+
+// RegisterResultMiddleware appends mw to the chain every check result
+// passes through, active or passive, in registration order. Call it from an
+// init function in a file added to cmd/gogios, the same way builtin checks
+// register, so deployments can normalize output, rewrite agent host names
+// or drop noisy results without patching gogios. name identifies mw in log
+// messages.
+func RegisterResultMiddleware(name string, mw ResultMiddleware) {
+	middlewareMu.Lock()
+	resultMiddleware = append(resultMiddleware, namedMiddleware{name, mw})
+	middlewareMu.Unlock()
+}
+
+// ResultPipeline runs the middleware registered when it was created.
+type ResultPipeline struct {
+	chain   []namedMiddleware
+	dropped atomic.Uint64
+	logFunc func(string, ...interface{})
+}
+
+// NewResultPipeline returns a pipeline over the registered middleware.
+func NewResultPipeline() *ResultPipeline {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	return &ResultPipeline{
+		chain:   append([]namedMiddleware(nil), resultMiddleware...),
+		logFunc: func(string, ...interface{}) {},
+	}
+}
+
+// SetLogger sets the function middleware panics are logged with.
+func (p *ResultPipeline) SetLogger(fn func(string, ...interface{})) {
+	p.logFunc = fn
+}
+
+// Len returns the number of middleware in the pipeline.
+func (p *ResultPipeline) Len() int {
+	return len(p.chain)
+}
+
+// Dropped returns the number of results dropped by middleware.
+func (p *ResultPipeline) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Apply runs cr through the chain and returns the result to process, or
+// nil if a middleware dropped it. A middleware that panics is logged and
+// skipped; the result carries on with any edits it made before panicking.
+func (p *ResultPipeline) Apply(cr *objects.CheckResult) *objects.CheckResult {
+	for _, mw := range p.chain {
+		if cr = p.call(mw, cr); cr == nil {
+			p.dropped.Add(1)
+			return nil
+		}
+	}
+	return cr
+}
+
+func (p *ResultPipeline) call(mw namedMiddleware, cr *objects.CheckResult) (out *objects.CheckResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logFunc("Error: Result middleware '%s' panicked on result for '%s;%s': %v",
+				mw.name, cr.HostName, cr.ServiceDescription, r)
+			out = cr
+		}
+	}()
+	return mw.fn(cr)
+}
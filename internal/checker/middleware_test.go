@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestResultPipeline(t *testing.T) {
+	saved := resultMiddleware
+	t.Cleanup(func() { resultMiddleware = saved })
+	resultMiddleware = nil
+
+	RegisterResultMiddleware("strip-domain", func(cr *objects.CheckResult) *objects.CheckResult {
+		cr.HostName, _, _ = strings.Cut(cr.HostName, ".")
+		return cr
+	})
+	RegisterResultMiddleware("drop-noise", func(cr *objects.CheckResult) *objects.CheckResult {
+		if cr.ServiceDescription == "noise" {
+			return nil
+		}
+		return cr
+	})
+	RegisterResultMiddleware("panics", func(cr *objects.CheckResult) *objects.CheckResult {
+		if cr.Output == "boom" {
+			panic("bad output")
+		}
+		return cr
+	})
+
+	p := NewResultPipeline()
+	var logged []string
+	p.SetLogger(func(format string, args ...interface{}) { logged = append(logged, format) })
+	if p.Len() != 3 {
+		t.Fatalf("expected 3 middleware, got %d", p.Len())
+	}
+
+	cr := p.Apply(&objects.CheckResult{HostName: "web-01.example.com", ServiceDescription: "HTTP"})
+	if cr == nil || cr.HostName != "web-01" {
+		t.Errorf("expected the host name rewritten, got %+v", cr)
+	}
+	if cr := p.Apply(&objects.CheckResult{HostName: "web-01", ServiceDescription: "noise"}); cr != nil {
+		t.Errorf("expected the result dropped, got %+v", cr)
+	}
+	if p.Dropped() != 1 {
+		t.Errorf("expected 1 dropped, got %d", p.Dropped())
+	}
+	cr = p.Apply(&objects.CheckResult{HostName: "db.example.com", Output: "boom"})
+	if cr == nil || cr.HostName != "db" || len(logged) != 1 {
+		t.Errorf("expected the panic logged and the result kept, got %+v, logged %v", cr, logged)
+	}
+
+	// Middleware registered later does not change an existing pipeline.
+	RegisterResultMiddleware("late", func(*objects.CheckResult) *objects.CheckResult { return nil })
+	if p.Len() != 3 {
+		t.Errorf("pipeline changed after creation: %d middleware", p.Len())
+	}
+}
@@ -0,0 +1,121 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// NoCheckStateCustomVar overrides host_no_check_state for one host.
+const NoCheckStateCustomVar = "NO_CHECK_STATE"
+
+// States for hosts without a check command.
+const (
+	NoCheckUp       = "up"       // assumed UP, as Nagios does
+	NoCheckPending  = "pending"  // never checked, stays PENDING
+	NoCheckServices = "services" // the worst state of the host's services
+)
+
+// ValidNoCheckState reports whether s is a host_no_check_state value.
+func ValidNoCheckState(s string) bool {
+	switch s {
+	case NoCheckUp, NoCheckPending, NoCheckServices:
+		return true
+	}
+	return false
+}
+
+// NoCheckState returns how h is checked when it has no check command: its
+// _NO_CHECK_STATE custom variable, or def if the variable is unset. An
+// invalid variable is returned as an error along with def.
+func NoCheckState(h *objects.Host, def string) (string, error) {
+	v, ok := h.CustomVars[NoCheckStateCustomVar]
+	if !ok {
+		return def, nil
+	}
+	v = strings.ToLower(strings.TrimSpace(v))
+	if !ValidNoCheckState(v) {
+		return def, fmt.Errorf("invalid _%s %q (want up, pending or services)", NoCheckStateCustomVar, v)
+	}
+	return v, nil
+}
+
+// NoCheckResult returns the result of a check of h, which has no check
+// command, in the given state mode. It returns nil when h should stay
+// PENDING: in pending mode, or in services mode while none of its services
+// has been checked. In services mode the worst service state, with
+// CRITICAL above UNKNOWN above WARNING, is the return code, which host
+// result handling maps to UP or DOWN like any host check's.
+func NoCheckResult(h *objects.Host, mode string, options int, now time.Time) *objects.CheckResult {
+	cr := &objects.CheckResult{
+		HostName:     h.Name,
+		CheckType:    objects.CheckTypeActive,
+		CheckOptions: options,
+		StartTime:    now,
+		FinishTime:   now,
+		ExitedOK:     true,
+		Latency:      h.Latency,
+	}
+	switch mode {
+	case NoCheckPending:
+		return nil
+	case NoCheckServices:
+		var counts [4]int
+		checked := 0
+		for _, svc := range h.Services {
+			if !svc.HasBeenChecked || svc.CurrentState < 0 || svc.CurrentState > 3 {
+				continue
+			}
+			counts[svc.CurrentState]++
+			checked++
+		}
+		if checked == 0 {
+			return nil
+		}
+		var parts []string
+		for _, state := range []int{objects.ServiceCritical, objects.ServiceUnknown, objects.ServiceWarning, objects.ServiceOK} {
+			if counts[state] == 0 {
+				continue
+			}
+			if len(parts) == 0 {
+				cr.ReturnCode = state
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", counts[state], objects.ServiceStateName(state)))
+		}
+		cr.Output = "(No check command defined - host state from services: " + strings.Join(parts, ", ") + ")"
+	default:
+		cr.Output = "(No check command defined - host assumed UP)"
+	}
+	return cr
+}
+
+// StaleServicesResult returns a DOWN result for h, which has no check
+// command, when its services have results but none newer than maxAge. It
+// returns nil while any service result is recent, or none has arrived yet,
+// leaving the host to its no-check state. Hosts whose agents submit only
+// service results, such as NRDP senders, then go DOWN when the agent stops.
+func StaleServicesResult(h *objects.Host, maxAge time.Duration, options int, now time.Time) *objects.CheckResult {
+	var newest time.Time
+	for _, svc := range h.Services {
+		if svc.HasBeenChecked && svc.LastCheck.After(newest) {
+			newest = svc.LastCheck
+		}
+	}
+	if newest.IsZero() || now.Sub(newest) <= maxAge {
+		return nil
+	}
+	return &objects.CheckResult{
+		HostName:     h.Name,
+		CheckType:    objects.CheckTypeActive,
+		CheckOptions: options,
+		ReturnCode:   objects.ServiceCritical,
+		Output: fmt.Sprintf("(No check command defined - no service results for %s, host assumed DOWN)",
+			now.Sub(newest).Round(time.Second)),
+		StartTime:  now,
+		FinishTime: now,
+		ExitedOK:   true,
+		Latency:    h.Latency,
+	}
+}
@@ -0,0 +1,80 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestNoCheckResult(t *testing.T) {
+	now := time.Now()
+	h := &objects.Host{Name: "pod-host", CustomVars: map[string]string{}}
+
+	if mode, err := NoCheckState(h, NoCheckUp); mode != NoCheckUp || err != nil {
+		t.Errorf("expected the default, got %q %v", mode, err)
+	}
+	h.CustomVars[NoCheckStateCustomVar] = "Services"
+	if mode, err := NoCheckState(h, NoCheckUp); mode != NoCheckServices || err != nil {
+		t.Errorf("expected services from the custom variable, got %q %v", mode, err)
+	}
+	h.CustomVars[NoCheckStateCustomVar] = "down"
+	if mode, err := NoCheckState(h, NoCheckPending); mode != NoCheckPending || err == nil {
+		t.Errorf("expected an error and the default, got %q %v", mode, err)
+	}
+
+	if cr := NoCheckResult(h, NoCheckUp, 0, now); cr == nil || cr.ReturnCode != 0 || !strings.Contains(cr.Output, "assumed UP") {
+		t.Errorf("up: got %+v", cr)
+	}
+	if cr := NoCheckResult(h, NoCheckPending, 0, now); cr != nil {
+		t.Errorf("pending: expected no result, got %+v", cr)
+	}
+
+	unchecked := &objects.Service{Host: h, Description: "new", CurrentState: objects.ServiceCritical}
+	h.Services = []*objects.Service{unchecked}
+	if cr := NoCheckResult(h, NoCheckServices, 0, now); cr != nil {
+		t.Errorf("services: expected no result before any service is checked, got %+v", cr)
+	}
+
+	h.Services = append(h.Services,
+		&objects.Service{Host: h, Description: "a", HasBeenChecked: true, CurrentState: objects.ServiceOK},
+		&objects.Service{Host: h, Description: "b", HasBeenChecked: true, CurrentState: objects.ServiceWarning},
+		&objects.Service{Host: h, Description: "c", HasBeenChecked: true, CurrentState: objects.ServiceOK},
+	)
+	cr := NoCheckResult(h, NoCheckServices, 0, now)
+	if cr == nil || cr.ReturnCode != objects.ServiceWarning {
+		t.Fatalf("services: expected WARNING, got %+v", cr)
+	}
+	if want := "host state from services: 1 WARNING, 2 OK)"; !strings.Contains(cr.Output, want) {
+		t.Errorf("output %q, want it to contain %q", cr.Output, want)
+	}
+
+	h.Services = append(h.Services, &objects.Service{Host: h, Description: "d", HasBeenChecked: true, CurrentState: objects.ServiceUnknown})
+	if cr := NoCheckResult(h, NoCheckServices, 0, now); cr == nil || cr.ReturnCode != objects.ServiceUnknown {
+		t.Errorf("services: expected UNKNOWN above WARNING, got %+v", cr)
+	}
+}
+
+func TestStaleServicesResult(t *testing.T) {
+	now := time.Now()
+	h := &objects.Host{Name: "nrdp-agent"}
+	if cr := StaleServicesResult(h, 10*time.Minute, 0, now); cr != nil {
+		t.Errorf("expected no result without services, got %+v", cr)
+	}
+	h.Services = []*objects.Service{
+		{Host: h, Description: "disk", HasBeenChecked: true, LastCheck: now.Add(-30 * time.Minute)},
+		{Host: h, Description: "load", HasBeenChecked: true, LastCheck: now.Add(-5 * time.Minute)},
+	}
+	if cr := StaleServicesResult(h, 10*time.Minute, 0, now); cr != nil {
+		t.Errorf("expected no result while a service result is recent, got %+v", cr)
+	}
+	h.Services[1].LastCheck = now.Add(-15 * time.Minute)
+	cr := StaleServicesResult(h, 10*time.Minute, 0, now)
+	if cr == nil || cr.ReturnCode != objects.ServiceCritical {
+		t.Fatalf("expected a DOWN result once every service result is stale, got %+v", cr)
+	}
+	if want := "no service results for 15m0s"; !strings.Contains(cr.Output, want) {
+		t.Errorf("output %q, want it to contain %q", cr.Output, want)
+	}
+}
@@ -0,0 +1,117 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Obsessor runs the obsessive compulsive service and host processor
+// commands (ocsp_command, ochp_command) after each check result, for
+// objects with obsess enabled while obsess_over_services or
+// obsess_over_hosts is on. Distributed setups use them to forward results
+// to a central server.
+type Obsessor struct {
+	Global *objects.GlobalState
+	// Expand expands the macros of a command line. The caller holds the
+	// store lock when Service or Host is called.
+	Expand func(cmdLine string, h *objects.Host, svc *objects.Service, args []string) string
+	// DryRun skips running the commands (simulation mode), so synthetic
+	// results are not forwarded.
+	DryRun bool
+
+	svcCmd, hostCmd         *objects.Command
+	svcArgs, hostArgs       []string
+	svcTimeout, hostTimeout time.Duration
+	logFunc                 func(string, ...interface{})
+	run                     func(cmdLine string, timeout time.Duration) error
+}
+
+// NewObsessor resolves the ocsp_command and ochp_command names, either of
+// which may be empty, against the store's commands.
+func NewObsessor(store *objects.ObjectStore, gs *objects.GlobalState, ocsp, ochp string, ocspTimeout, ochpTimeout time.Duration) (*Obsessor, error) {
+	o := &Obsessor{
+		Global:      gs,
+		svcTimeout:  ocspTimeout,
+		hostTimeout: ochpTimeout,
+		logFunc:     func(string, ...interface{}) {},
+		run:         runShellCommand,
+	}
+	var err error
+	if o.svcCmd, o.svcArgs, err = lookupCommand(store, ocsp); err != nil {
+		return nil, fmt.Errorf("obsessive compulsive service processor command %w", err)
+	}
+	if o.hostCmd, o.hostArgs, err = lookupCommand(store, ochp); err != nil {
+		return nil, fmt.Errorf("obsessive compulsive host processor command %w", err)
+	}
+	return o, nil
+}
+
+func lookupCommand(store *objects.ObjectStore, spec string) (*objects.Command, []string, error) {
+	if spec == "" {
+		return nil, nil, nil
+	}
+	parts := strings.Split(spec, "!")
+	cmd := store.GetCommand(parts[0])
+	if cmd == nil {
+		return nil, nil, fmt.Errorf("'%s' is not defined anywhere", parts[0])
+	}
+	return cmd, parts[1:], nil
+}
+
+// SetLogger sets the function timeouts and failures are logged with.
+func (o *Obsessor) SetLogger(fn func(string, ...interface{})) {
+	o.logFunc = fn
+}
+
+// Service runs the ocsp_command for svc's latest result, if obsessing over
+// it is enabled. The command runs in the background.
+func (o *Obsessor) Service(svc *objects.Service) {
+	if o.svcCmd == nil || o.DryRun || !o.Global.ObsessOverServices || !svc.ObsessOver {
+		return
+	}
+	cmdLine := o.Expand(o.svcCmd.CommandLine, svc.Host, svc, o.svcArgs)
+	what := fmt.Sprintf("OCSP command '%s' for service '%s' on host '%s'", o.svcCmd.Name, svc.Description, svc.Host.Name)
+	go o.exec(cmdLine, o.svcTimeout, what)
+}
+
+// Host runs the ochp_command for h's latest result, if obsessing over it is
+// enabled. The command runs in the background.
+func (o *Obsessor) Host(h *objects.Host) {
+	if o.hostCmd == nil || o.DryRun || !o.Global.ObsessOverHosts || !h.ObsessOver {
+		return
+	}
+	cmdLine := o.Expand(o.hostCmd.CommandLine, h, nil, o.hostArgs)
+	what := fmt.Sprintf("OCHP command '%s' for host '%s'", o.hostCmd.Name, h.Name)
+	go o.exec(cmdLine, o.hostTimeout, what)
+}
+
+func (o *Obsessor) exec(cmdLine string, timeout time.Duration, what string) {
+	execLogged(o.run, o.logFunc, cmdLine, timeout, what)
+}
+
+// execLogged runs cmdLine with run, logging a timeout or failure of what.
+func execLogged(run func(string, time.Duration) error, logFunc func(string, ...interface{}), cmdLine string, timeout time.Duration, what string) {
+	err := run(cmdLine, timeout)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		logFunc("Warning: %s timed out after %.0f seconds", what, timeout.Seconds())
+	case err != nil:
+		logFunc("Warning: %s failed: %v", what, err)
+	}
+}
+
+func runShellCommand(cmdLine string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := exec.CommandContext(ctx, "/bin/sh", "-c", cmdLine).Run()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestObsessor(t *testing.T) {
+	store := objects.NewObjectStore()
+	store.AddCommand(&objects.Command{Name: "submit_ocsp", CommandLine: "send $HOSTNAME$ $SERVICEDESC$ $ARG1$"})
+	store.AddCommand(&objects.Command{Name: "submit_ochp", CommandLine: "send $HOSTNAME$"})
+	gs := &objects.GlobalState{ObsessOverServices: true}
+
+	if _, err := NewObsessor(store, gs, "missing", "", time.Second, time.Second); err == nil {
+		t.Fatal("expected an error for an undefined command")
+	}
+	o, err := NewObsessor(store, gs, "submit_ocsp!central", "submit_ochp", time.Second, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Expand = func(cmdLine string, h *objects.Host, svc *objects.Service, args []string) string {
+		desc := ""
+		if svc != nil {
+			desc = svc.Description
+		}
+		r := strings.NewReplacer("$HOSTNAME$", h.Name, "$SERVICEDESC$", desc, "$ARG1$", strings.Join(args, ","))
+		return r.Replace(cmdLine)
+	}
+	ran := make(chan string, 4)
+	o.run = func(cmdLine string, timeout time.Duration) error {
+		ran <- cmdLine
+		return context.DeadlineExceeded
+	}
+	logged := make(chan string, 4)
+	o.SetLogger(func(format string, args ...interface{}) { logged <- format })
+
+	h := &objects.Host{Name: "web-01", ObsessOver: true}
+	svc := &objects.Service{Host: h, Description: "HTTP", ObsessOver: true}
+
+	o.Service(svc)
+	if got := <-ran; got != "send web-01 HTTP central" {
+		t.Errorf("ran %q", got)
+	}
+	if got := <-logged; !strings.Contains(got, "timed out") {
+		t.Errorf("logged %q", got)
+	}
+
+	// obsess_over_hosts is off, and the service has obsess disabled.
+	o.Host(h)
+	svc.ObsessOver = false
+	o.Service(svc)
+	select {
+	case got := <-ran:
+		t.Errorf("unexpected run %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	gs.ObsessOverHosts = true
+	o.Host(h)
+	if got := <-ran; got != "send web-01" {
+		t.Errorf("ran %q", got)
+	}
+
+	// Nothing is forwarded in simulation mode.
+	o.DryRun = true
+	o.Host(h)
+	select {
+	case got := <-ran:
+		t.Errorf("unexpected run in dry run %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,43 @@
+package checker
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxOutputLength is how much plugin output is kept from a check, like
+// MAX_PLUGIN_OUTPUT_LENGTH in Nagios.
+const maxOutputLength = 8192
+
+// maxPooledOutputBuffer is the largest output buffer returned to the pool;
+// a plugin that once printed megabytes should not keep them allocated.
+const maxPooledOutputBuffer = 64 * 1024
+
+// outputBufPool holds buffers for capturing plugin output, so a busy
+// executor does not allocate a fresh one (and grow it) for every check.
+var outputBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getOutputBuffer() *bytes.Buffer {
+	return outputBufPool.Get().(*bytes.Buffer)
+}
+
+// putOutputBuffer returns b to the pool. b must no longer be written to.
+func putOutputBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledOutputBuffer {
+		return
+	}
+	b.Reset()
+	outputBufPool.Put(b)
+}
+
+// outputString copies at most maxOutputLength bytes of captured output into
+// a new string. Copying only what is kept means a plugin that printed far
+// more does not leave the rest pinned behind the check result.
+func outputString(b []byte) string {
+	if len(b) > maxOutputLength {
+		b = b[:maxOutputLength]
+	}
+	return string(b)
+}
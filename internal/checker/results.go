@@ -1,6 +1,9 @@
 package checker
 
 import (
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/oceanplexian/gogios/internal/objects"
@@ -81,8 +84,16 @@ func ParseCheckOutput(raw string) ParsedOutput {
 		longLines[i] = strings.ReplaceAll(l, ";", ":")
 	}
 
-	p.LongOutput = strings.Join(longLines, "\\n")
-	p.PerfData = strings.Join(perfLines, " ")
+	// The parts are kept on the object until its next check, so none may
+	// point into raw. Outputs repeat across objects and checks and are
+	// interned; perfdata rarely repeats and is copied.
+	p.ShortOutput = objects.Intern(p.ShortOutput)
+	p.LongOutput = objects.Intern(strings.Join(longLines, "\\n"))
+	if len(perfLines) == 1 {
+		p.PerfData = strings.Clone(perfLines[0])
+	} else {
+		p.PerfData = strings.Join(perfLines, " ")
+	}
 
 	return p
 }
@@ -104,8 +115,6 @@ func GetServiceCheckReturnCode(cr *objects.CheckResult, timeoutState int) int {
 		return objects.ServiceCritical
 	case 3:
 		return objects.ServiceUnknown
-	case 126, 127:
-		return objects.ServiceCritical
 	default:
 		return objects.ServiceCritical
 	}
@@ -145,18 +154,145 @@ func GetPassiveHostCheckReturnCode(returnCode int) int {
 	}
 }
 
-// AugmentReturnCodeOutput adds special messages for return codes 126/127.
-func AugmentReturnCodeOutput(cr *objects.CheckResult) string {
-	output := cr.Output
-	switch cr.ReturnCode {
-	case 126:
-		if output == "" {
-			output = "(Return code of 126 is out of bounds - plugin may not be executable)"
+// ExitCodeMap maps plugin return codes outside 0-3 to service states.
+// Rules are tried in order; codes no rule matches get Default. A nil map
+// sends every out-of-bounds code to CRITICAL, as Nagios does.
+type ExitCodeMap struct {
+	rules   []exitCodeRule
+	Default int
+}
+
+type exitCodeRule struct {
+	lo, hi int
+	signal int // -1 for code rules, 0 for any signal
+	state  int
+}
+
+// maxSignal is the highest signal number a shell encodes as 128+N.
+const maxSignal = 64
+
+// ExitSignal returns the signal that killed a plugin with return code rc,
+// or 0 if it exited. Shells report a child killed by signal N as 128+N,
+// and the executor encodes signal deaths the same way.
+func ExitSignal(rc int) int {
+	if rc > 128 && rc <= 128+maxSignal {
+		return rc - 128
+	}
+	return 0
+}
+
+// ParseExitCodeMap parses an exit_code_map value: comma-separated
+// key=state pairs where key is a return code (4, -1), a range (4-125),
+// "negative", "signal" (any signal), "signalN" or "default", and state is
+// ok, warning, critical or unknown.
+func ParseExitCodeMap(s string) (*ExitCodeMap, error) {
+	m := &ExitCodeMap{Default: objects.ServiceCritical}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
 		}
-	case 127:
-		if output == "" {
-			output = "(Return code of 127 is out of bounds - plugin may be missing)"
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid exit_code_map entry %q (want code=state)", pair)
 		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		state, ok := parseServiceState(val)
+		if !ok {
+			return nil, fmt.Errorf("invalid exit_code_map state %q (want ok, warning, critical or unknown)", val)
+		}
+		r := exitCodeRule{signal: -1, state: state}
+		switch {
+		case key == "default":
+			m.Default = state
+			continue
+		case key == "negative":
+			r.lo, r.hi = math.MinInt, -1
+		case key == "signal":
+			r.signal = 0
+		case strings.HasPrefix(key, "signal"):
+			n, err := strconv.Atoi(key[len("signal"):])
+			if err != nil || n < 1 || n > maxSignal {
+				return nil, fmt.Errorf("invalid exit_code_map signal %q", key)
+			}
+			r.signal = n
+		default:
+			lo, hi, isRange := strings.Cut(key[min(1, len(key)):], "-")
+			if isRange {
+				lo = key[:1] + lo
+			} else {
+				lo, hi = key, key
+			}
+			var err1, err2 error
+			r.lo, err1 = strconv.Atoi(lo)
+			r.hi, err2 = strconv.Atoi(hi)
+			if err1 != nil || err2 != nil || r.lo > r.hi {
+				return nil, fmt.Errorf("invalid exit_code_map code %q", key)
+			}
+			if r.lo >= 0 && r.hi <= 3 {
+				return nil, fmt.Errorf("exit_code_map code %q is not out of bounds", key)
+			}
+		}
+		m.rules = append(m.rules, r)
+	}
+	return m, nil
+}
+
+func parseServiceState(s string) (int, bool) {
+	switch strings.ToLower(s) {
+	case "ok", "o":
+		return objects.ServiceOK, true
+	case "warning", "w":
+		return objects.ServiceWarning, true
+	case "critical", "c":
+		return objects.ServiceCritical, true
+	case "unknown", "u":
+		return objects.ServiceUnknown, true
+	}
+	return 0, false
+}
+
+// State returns the service state for the out-of-bounds return code rc.
+func (m *ExitCodeMap) State(rc int) int {
+	if m == nil {
+		return objects.ServiceCritical
+	}
+	sig := ExitSignal(rc)
+	for _, r := range m.rules {
+		if r.signal < 0 {
+			if rc >= r.lo && rc <= r.hi {
+				return r.state
+			}
+		} else if sig > 0 && (r.signal == 0 || r.signal == sig) {
+			return r.state
+		}
+	}
+	return m.Default
+}
+
+// Normalize handles a result whose plugin exited with a return code
+// outside 0-3, whether it came from the executor, NRDP or an external
+// command. The output becomes Nagios' "(Return code of N is out of
+// bounds)" line, followed by what the plugin printed, and the mapped
+// service state is returned. ok is false when the code is in bounds or
+// the plugin did not exit normally.
+func (m *ExitCodeMap) Normalize(cr *objects.CheckResult) (state int, ok bool) {
+	if cr.EarlyTimeout || !cr.ExitedOK || (cr.ReturnCode >= 0 && cr.ReturnCode <= 3) {
+		return 0, false
+	}
+	msg := fmt.Sprintf("(Return code of %d is out of bounds", cr.ReturnCode)
+	switch {
+	case cr.ReturnCode == 126:
+		msg += " - plugin may not be executable"
+	case cr.ReturnCode == 127:
+		msg += " - plugin may be missing"
+	case ExitSignal(cr.ReturnCode) > 0:
+		msg += fmt.Sprintf(" - plugin was killed by signal %d", ExitSignal(cr.ReturnCode))
+	}
+	msg += ")"
+	if out := strings.TrimLeft(cr.Output, "\n"); out != "" {
+		msg += "\n" + out
 	}
-	return output
+	cr.Output = msg
+	return m.State(cr.ReturnCode), true
 }
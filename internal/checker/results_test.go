@@ -1,6 +1,10 @@
 package checker
 
 import (
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/oceanplexian/gogios/internal/objects"
@@ -54,6 +58,55 @@ func TestParseCheckOutput_SemicolonReplacement(t *testing.T) {
 	}
 }
 
+// TestParseCheckOutputHeap checks that the short outputs kept on objects
+// neither pin the raw output they were parsed from nor repeat the text for
+// every object reporting the same thing.
+func TestParseCheckOutputHeap(t *testing.T) {
+	const n = 20000
+	rawOutput := func(i int) string {
+		return fmt.Sprintf("DISK OK - all partitions below thresholds | %s\ncheck %d", strings.Repeat("/=1024MB;2048;4096;0;8192 ", 20), i)
+	}
+	// The least of a few runs, as goroutines left by other tests may
+	// allocate meanwhile.
+	heapAfter := func(build func() []string) uint64 {
+		least := uint64(math.MaxUint64)
+		for run := 0; run < 3; run++ {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			keep := build()
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(keep)
+			if after.HeapAlloc < before.HeapAlloc {
+				return 0
+			}
+			least = min(least, after.HeapAlloc-before.HeapAlloc)
+		}
+		return least
+	}
+	// Before: the short output was a slice of the raw output.
+	sliced := heapAfter(func() []string {
+		out := make([]string, n)
+		for i := range out {
+			raw := rawOutput(i)
+			out[i] = strings.TrimSpace(raw[:strings.Index(raw, "|")])
+		}
+		return out
+	})
+	parsed := heapAfter(func() []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = ParseCheckOutput(rawOutput(i)).ShortOutput
+		}
+		return out
+	})
+	t.Logf("heap for %d short outputs: %d bytes sliced from raw, %d bytes parsed", n, sliced, parsed)
+	if parsed*3 > sliced {
+		t.Errorf("expected parsed outputs to hold under a third of the heap, got %d vs %d bytes", parsed, sliced)
+	}
+}
+
 func TestGetServiceCheckReturnCode(t *testing.T) {
 	tests := []struct {
 		rc       int
@@ -107,3 +160,93 @@ func TestGetHostCheckReturnCode(t *testing.T) {
 		}
 	}
 }
+
+func TestParseExitCodeMap(t *testing.T) {
+	m, err := ParseExitCodeMap("127=unknown, 4-125=warning, negative=ok, signal9=unknown, signal=w, default=critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rc   int
+		want int
+	}{
+		{127, objects.ServiceUnknown},
+		{4, objects.ServiceWarning},
+		{125, objects.ServiceWarning},
+		{-1, objects.ServiceOK},
+		{137, objects.ServiceUnknown}, // SIGKILL
+		{143, objects.ServiceWarning}, // SIGTERM
+		{126, objects.ServiceCritical},
+		{255, objects.ServiceCritical},
+	}
+	for _, tt := range tests {
+		if got := m.State(tt.rc); got != tt.want {
+			t.Errorf("rc=%d: got %d want %d", tt.rc, got, tt.want)
+		}
+	}
+
+	if got := (*ExitCodeMap)(nil).State(200); got != objects.ServiceCritical {
+		t.Errorf("nil map: got %d want CRITICAL", got)
+	}
+	if m, err := ParseExitCodeMap("-5--2=unknown"); err != nil || m.State(-3) != objects.ServiceUnknown {
+		t.Errorf("negative range: %v", err)
+	}
+	for _, bad := range []string{"4", "4=bad", "2=unknown", "9-4=ok", "signal99=ok", "x=ok"} {
+		if _, err := ParseExitCodeMap(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestExitCodeMap_Normalize(t *testing.T) {
+	tests := []struct {
+		rc     int
+		output string
+		want   string
+	}{
+		{127, "", "(Return code of 127 is out of bounds - plugin may be missing)"},
+		{126, "", "(Return code of 126 is out of bounds - plugin may not be executable)"},
+		{137, "", "(Return code of 137 is out of bounds - plugin was killed by signal 9)"},
+		{-1, "", "(Return code of -1 is out of bounds)"},
+		{42, "OK - fine | x=1", "(Return code of 42 is out of bounds)\nOK - fine | x=1"},
+	}
+	for _, tt := range tests {
+		cr := &objects.CheckResult{ReturnCode: tt.rc, Output: tt.output, ExitedOK: true}
+		if _, ok := (*ExitCodeMap)(nil).Normalize(cr); !ok || cr.Output != tt.want {
+			t.Errorf("rc=%d: got %v %q want %q", tt.rc, ok, cr.Output, tt.want)
+		}
+	}
+
+	cr := &objects.CheckResult{ReturnCode: 2, Output: "CRITICAL", ExitedOK: true}
+	if _, ok := (*ExitCodeMap)(nil).Normalize(cr); ok || cr.Output != "CRITICAL" {
+		t.Errorf("in-bounds code rewritten: %q", cr.Output)
+	}
+}
+
+func TestResultHandlers_ExitCodeMap(t *testing.T) {
+	m, err := ParseExitCodeMap("127=unknown,4=warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestService()
+	sh := &ServiceResultHandler{Cfg: newTestConfig(), ExitCodes: m}
+	sh.HandleResult(svc, &objects.CheckResult{ReturnCode: 127, Output: "sh: not found", ExitedOK: true})
+	if svc.CurrentState != objects.ServiceUnknown {
+		t.Errorf("service state: got %d want UNKNOWN", svc.CurrentState)
+	}
+	if want := "(Return code of 127 is out of bounds - plugin may be missing)"; svc.PluginOutput != want || svc.LongPluginOutput != "sh: not found" {
+		t.Errorf("service output: got %q / %q", svc.PluginOutput, svc.LongPluginOutput)
+	}
+
+	// A passive host result is mapped too, not read as UNREACHABLE.
+	host := newTestHost()
+	hh := &HostResultHandler{Cfg: newTestConfig(), ExitCodes: m}
+	hh.HandleResult(host, &objects.CheckResult{ReturnCode: 4, ExitedOK: true, CheckType: objects.CheckTypePassive})
+	if host.CurrentState != objects.HostUp {
+		t.Errorf("host state: got %d want UP", host.CurrentState)
+	}
+	hh.HandleResult(host, &objects.CheckResult{ReturnCode: 127, ExitedOK: true, CheckType: objects.CheckTypePassive})
+	if host.CurrentState != objects.HostDown {
+		t.Errorf("host state: got %d want DOWN", host.CurrentState)
+	}
+}
@@ -0,0 +1,177 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// ExecutorCustomVar is the host custom variable (_CHECK_EXECUTOR) that selects
+// which registered runner executes a host's checks and its services' checks.
+const ExecutorCustomVar = "CHECK_EXECUTOR"
+
+// CheckRunner executes check commands asynchronously and delivers each
+// result on the runner's result channel. Submit must never block the
+// scheduler's event loop.
+type CheckRunner interface {
+	Submit(hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64)
+	JobsRunning() int64
+	Stop()
+}
+
+var _ CheckRunner = (*Executor)(nil)
+
+// HealthReporter is implemented by runners that can tell when they cannot
+// take checks, such as a pool of remote workers that are all down.
+type HealthReporter interface {
+	Healthy() bool
+}
+
+// ExecutorRoute sends the checks of matching hosts to a named runner. It
+// matches members of HostGroup when that is set, and hosts whose custom
+// variables match Selector otherwise.
+type ExecutorRoute struct {
+	Runner    string
+	HostGroup string
+	Selector  objects.CustomVarSelector
+}
+
+// ParseExecutorRoute parses a check_executor_route value: a runner name and
+// either "hostgroup:<name>" or a custom variable selector, e.g.
+// "ssh hostgroup:dmz" or "ssh _SITE=eu-*".
+func ParseExecutorRoute(s string) (ExecutorRoute, error) {
+	runner, match, _ := strings.Cut(strings.TrimSpace(s), " ")
+	match = strings.TrimSpace(match)
+	if runner == "" || match == "" {
+		return ExecutorRoute{}, fmt.Errorf("check_executor_route: invalid route '%s' (want <runner> hostgroup:<name> or <runner> _NAME=value)", s)
+	}
+	rt := ExecutorRoute{Runner: strings.ToLower(runner)}
+	if group, ok := strings.CutPrefix(match, "hostgroup:"); ok {
+		if rt.HostGroup = strings.TrimSpace(group); rt.HostGroup == "" {
+			return ExecutorRoute{}, fmt.Errorf("check_executor_route: empty hostgroup in '%s'", s)
+		}
+		return rt, nil
+	}
+	sel, err := objects.ParseCustomVarSelector(match)
+	if err != nil {
+		return ExecutorRoute{}, fmt.Errorf("check_executor_route: %v", err)
+	}
+	rt.Selector = sel
+	return rt, nil
+}
+
+// Match reports whether h's checks take this route.
+func (rt ExecutorRoute) Match(h *objects.Host) bool {
+	if rt.HostGroup == "" {
+		return rt.Selector.MatchHost(h)
+	}
+	for _, hg := range h.HostGroups {
+		if hg.Name == rt.HostGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// Router picks a CheckRunner per host. Hosts select a runner by name through
+// the _CHECK_EXECUTOR custom variable, then through the first matching
+// route; other hosts, and those naming a runner that is not registered, use
+// the default runner. A chosen runner that reports itself unhealthy is
+// passed over for the central runner, the one the router was created with,
+// so checks keep running while remote workers are down.
+type Router struct {
+	defaultName string
+	centralName string
+	runners     map[string]CheckRunner
+	routes      []ExecutorRoute
+}
+
+// NewRouter creates a router whose default and central runner is registered
+// as name.
+func NewRouter(name string, def CheckRunner) *Router {
+	name = strings.ToLower(name)
+	return &Router{
+		defaultName: name,
+		centralName: name,
+		runners:     map[string]CheckRunner{name: def},
+	}
+}
+
+// AddRoute appends a route. Routes are tried in the order added.
+func (r *Router) AddRoute(rt ExecutorRoute) {
+	r.routes = append(r.routes, rt)
+}
+
+// Register adds a named runner. Registering an existing name replaces it.
+func (r *Router) Register(name string, runner CheckRunner) {
+	r.runners[strings.ToLower(name)] = runner
+}
+
+// SetDefault changes which registered runner is used for hosts that don't
+// choose one. Unknown names are ignored and the previous default is kept.
+func (r *Router) SetDefault(name string) bool {
+	name = strings.ToLower(name)
+	if _, ok := r.runners[name]; !ok {
+		return false
+	}
+	r.defaultName = name
+	return true
+}
+
+// Get returns the runner registered under name, or nil.
+func (r *Router) Get(name string) CheckRunner {
+	return r.runners[strings.ToLower(name)]
+}
+
+// For returns the runner that should execute checks for host h.
+func (r *Router) For(h *objects.Host) CheckRunner {
+	_, runner := r.Route(h)
+	return runner
+}
+
+// Route returns the runner that should execute checks for host h and the
+// name it is registered under.
+func (r *Router) Route(h *objects.Host) (string, CheckRunner) {
+	name := r.choose(h)
+	runner := r.runners[name]
+	if hr, ok := runner.(HealthReporter); ok && name != r.centralName && !hr.Healthy() {
+		return r.centralName, r.runners[r.centralName]
+	}
+	return name, runner
+}
+
+func (r *Router) choose(h *objects.Host) string {
+	if h == nil {
+		return r.defaultName
+	}
+	if name, ok := h.CustomVars[ExecutorCustomVar]; ok {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if r.runners[name] != nil {
+			return name
+		}
+	}
+	for _, rt := range r.routes {
+		if r.runners[rt.Runner] != nil && rt.Match(h) {
+			return rt.Runner
+		}
+	}
+	return r.defaultName
+}
+
+// JobsRunning returns the sum of executing checks across all runners.
+func (r *Router) JobsRunning() int64 {
+	var n int64
+	for _, runner := range r.runners {
+		n += runner.JobsRunning()
+	}
+	return n
+}
+
+// Stop stops every registered runner.
+func (r *Router) Stop() {
+	for _, runner := range r.runners {
+		runner.Stop()
+	}
+}
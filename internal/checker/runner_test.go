@@ -0,0 +1,95 @@
+package checker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+type countingRunner struct{ submitted int }
+
+func (r *countingRunner) Submit(hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64) {
+	r.submitted++
+}
+func (r *countingRunner) JobsRunning() int64 { return int64(r.submitted) }
+func (r *countingRunner) Stop()              {}
+
+func TestRouterSelectsRunnerByCustomVar(t *testing.T) {
+	local := &countingRunner{}
+	remote := &countingRunner{}
+	r := NewRouter("local", local)
+	r.Register("ssh", remote)
+
+	plain := &objects.Host{Name: "a"}
+	viaSSH := &objects.Host{Name: "b", CustomVars: map[string]string{ExecutorCustomVar: "SSH"}}
+	unknown := &objects.Host{Name: "c", CustomVars: map[string]string{ExecutorCustomVar: "agent"}}
+
+	r.For(plain).Submit("a", "", "true", time.Second, 0, 0, 0)
+	r.For(viaSSH).Submit("b", "", "true", time.Second, 0, 0, 0)
+	r.For(unknown).Submit("c", "", "true", time.Second, 0, 0, 0)
+
+	if local.submitted != 2 || remote.submitted != 1 {
+		t.Errorf("expected local=2 ssh=1, got local=%d ssh=%d", local.submitted, remote.submitted)
+	}
+	if r.JobsRunning() != 3 {
+		t.Errorf("expected JobsRunning to sum runners, got %d", r.JobsRunning())
+	}
+
+	if r.SetDefault("agent") {
+		t.Error("SetDefault should reject unregistered runner")
+	}
+	if !r.SetDefault("ssh") || r.For(plain) != remote {
+		t.Error("SetDefault(ssh) did not change the default runner")
+	}
+}
+
+type downRunner struct {
+	countingRunner
+	healthy bool
+}
+
+func (r *downRunner) Healthy() bool { return r.healthy }
+
+func TestRouterRoutesAndFailover(t *testing.T) {
+	local := &countingRunner{}
+	dmz := &downRunner{healthy: true}
+	r := NewRouter("local", local)
+	r.Register("dmz", dmz)
+	for _, s := range []string{"dmz hostgroup:dmz-servers", "dmz _SITE=edge-*", "agents _SITE=eu"} {
+		rt, err := ParseExecutorRoute(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.AddRoute(rt)
+	}
+	for _, s := range []string{"dmz", "dmz hostgroup:", "dmz SITE"} {
+		if _, err := ParseExecutorRoute(s); err == nil {
+			t.Errorf("ParseExecutorRoute(%q): expected an error", s)
+		}
+	}
+
+	inGroup := &objects.Host{Name: "fw1", HostGroups: []*objects.HostGroup{{Name: "dmz-servers"}}}
+	bySite := &objects.Host{Name: "edge1", CustomVars: map[string]string{"SITE": "edge-ams"}}
+	pinned := &objects.Host{Name: "edge2", CustomVars: map[string]string{"SITE": "edge-ams", ExecutorCustomVar: "local"}}
+	unrouted := &objects.Host{Name: "eu1", CustomVars: map[string]string{"SITE": "eu"}}
+
+	for _, tt := range []struct {
+		host *objects.Host
+		want string
+	}{
+		{inGroup, "dmz"},
+		{bySite, "dmz"},
+		{pinned, "local"},   // _CHECK_EXECUTOR beats routes
+		{unrouted, "local"}, // the route's runner is not registered
+	} {
+		if name, _ := r.Route(tt.host); name != tt.want {
+			t.Errorf("%s: routed to %q, want %q", tt.host.Name, name, tt.want)
+		}
+	}
+
+	dmz.healthy = false
+	if name, runner := r.Route(inGroup); name != "local" || runner != local {
+		t.Errorf("expected failover to the central runner, got %q", name)
+	}
+}
@@ -0,0 +1,99 @@
+package checker
+
+import (
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Output sanitization modes for check_output_sanitization.
+const (
+	SanitizeReplace = "replace" // invalid UTF-8 becomes U+FFFD, control characters become spaces
+	SanitizeStrip   = "strip"   // invalid UTF-8 and control characters are removed
+	SanitizeOff     = "off"
+)
+
+// Sanitizer normalizes check output before it reaches the object store, so
+// plugins that print binary garbage, ANSI colours or stray NULs cannot break
+// JSON API responses, status.dat or the log. Newlines and tabs are kept;
+// carriage returns are always removed so CRLF output reads as LF.
+type Sanitizer struct {
+	strip     bool
+	off       bool
+	sanitized atomic.Int64
+}
+
+// NewSanitizer creates a sanitizer for one of the Sanitize* modes. Unknown
+// modes behave as SanitizeReplace.
+func NewSanitizer(mode string) *Sanitizer {
+	return &Sanitizer{strip: mode == SanitizeStrip, off: mode == SanitizeOff}
+}
+
+// Apply sanitizes cr.Output in place and reports whether it changed.
+func (s *Sanitizer) Apply(cr *objects.CheckResult) bool {
+	if s.off {
+		return false
+	}
+	out, changed := SanitizeOutput(cr.Output, s.strip)
+	if changed {
+		cr.Output = out
+		s.sanitized.Add(1)
+	}
+	return changed
+}
+
+// Sanitized returns the number of results changed by Apply.
+func (s *Sanitizer) Sanitized() int64 {
+	return s.sanitized.Load()
+}
+
+// SanitizeOutput returns out with invalid UTF-8 and control characters
+// other than newline and tab replaced, or removed if strip is set.
+func SanitizeOutput(out string, strip bool) (string, bool) {
+	if clean(out) {
+		return out, false
+	}
+	var b strings.Builder
+	b.Grow(len(out))
+	for i := 0; i < len(out); {
+		r, size := utf8.DecodeRuneInString(out[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			if !strip {
+				b.WriteRune(utf8.RuneError)
+			}
+		case r == '\r':
+		case r != '\n' && r != '\t' && unicode.IsControl(r):
+			if !strip {
+				b.WriteByte(' ')
+			}
+		default:
+			b.WriteString(out[i : i+size])
+		}
+		i += size
+	}
+	return b.String(), true
+}
+
+// clean is the fast path: printable ASCII, newlines and tabs only.
+func clean(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x7f || (c < 0x20 && c != '\n' && c != '\t') {
+			return cleanUTF8(s[i:])
+		}
+	}
+	return true
+}
+
+func cleanUTF8(s string) bool {
+	for _, r := range s {
+		if r == utf8.RuneError || r == '\r' || (r != '\n' && r != '\t' && unicode.IsControl(r)) {
+			return false
+		}
+	}
+	return true
+}
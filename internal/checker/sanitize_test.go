@@ -0,0 +1,48 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestSanitizeOutput(t *testing.T) {
+	cases := []struct {
+		in, replace, strip string
+	}{
+		{"OK - all good\nline 2\tx", "OK - all good\nline 2\tx", "OK - all good\nline 2\tx"},
+		{"OK - ünïcode ✓", "OK - ünïcode ✓", "OK - ünïcode ✓"},
+		{"OK\r\nline 2\r\n", "OK\nline 2\n", "OK\nline 2\n"},
+		{"CRIT \x1b[31mred\x1b[0m", "CRIT  [31mred [0m", "CRIT [31mred[0m"},
+		{"bad \xff\xfe bytes\x00", "bad �� bytes ", "bad  bytes"},
+	}
+	for _, c := range cases {
+		if got, _ := SanitizeOutput(c.in, false); got != c.replace {
+			t.Errorf("replace %q: got %q, want %q", c.in, got, c.replace)
+		}
+		if got, _ := SanitizeOutput(c.in, true); got != c.strip {
+			t.Errorf("strip %q: got %q, want %q", c.in, got, c.strip)
+		}
+	}
+}
+
+func TestSanitizer_Counts(t *testing.T) {
+	s := NewSanitizer(SanitizeReplace)
+	cr := &objects.CheckResult{Output: "OK"}
+	if s.Apply(cr) {
+		t.Error("clean output reported as changed")
+	}
+	cr.Output = "OK\x00"
+	if !s.Apply(cr) || cr.Output != "OK " {
+		t.Errorf("unexpected output %q", cr.Output)
+	}
+	if s.Sanitized() != 1 {
+		t.Errorf("expected 1 sanitized result, got %d", s.Sanitized())
+	}
+
+	off := NewSanitizer(SanitizeOff)
+	cr.Output = "OK\x00"
+	if off.Apply(cr) || cr.Output != "OK\x00" {
+		t.Error("off mode changed output")
+	}
+}
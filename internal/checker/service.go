@@ -11,6 +11,9 @@ import (
 // ~700-line handle_async_service_check_result().
 type ServiceResultHandler struct {
 	Cfg *objects.Config
+	// ExitCodes maps out-of-bounds return codes to states; nil maps them
+	// all to CRITICAL.
+	ExitCodes *ExitCodeMap
 	// HostLookup finds a host by name. Set by the scheduler.
 	HostLookup func(name string) *objects.Host
 	// ScheduleHostCheck requests an immediate host check.
@@ -18,6 +21,9 @@ type ServiceResultHandler struct {
 	// OnStateChange is called when a service state change should trigger
 	// notifications/event handlers (provided by task #8).
 	OnStateChange func(svc *objects.Service, oldState, newState int, hardChange bool)
+	// OnHardStateChange is called after OnStateChange when the result
+	// changed the hard state, which LastHardStateCause now records.
+	OnHardStateChange func(svc *objects.Service)
 	// OnNotification is called when a notification should be sent.
 	OnNotification func(svc *objects.Service, notifType int)
 }
@@ -36,6 +42,7 @@ func (h *ServiceResultHandler) HandleResult(svc *objects.Service, cr *objects.Ch
 	svc.ExecutionTime = cr.ExecutionTime
 	svc.LastCheck = cr.StartTime
 	svc.HasBeenChecked = true
+	svc.CheckSource = cr.Source
 
 	// Clear freshness flag - race condition protection:
 	// if freshness triggered this check but a result arrived meanwhile, skip
@@ -43,15 +50,18 @@ func (h *ServiceResultHandler) HandleResult(svc *objects.Service, cr *objects.Ch
 		svc.IsBeingFreshened = false
 	}
 
-	// Parse output
+	// Parse output, after an out-of-bounds return code has replaced it
+	mappedState, outOfBounds := h.ExitCodes.Normalize(cr)
 	parsed := ParseCheckOutput(cr.Output)
-	cr.Output = AugmentReturnCodeOutput(cr)
 	svc.PluginOutput = parsed.ShortOutput
 	svc.LongPluginOutput = parsed.LongOutput
 	svc.PerfData = parsed.PerfData
 
 	// Determine new state
 	newState := GetServiceCheckReturnCode(cr, h.Cfg.ServiceCheckTimeoutState)
+	if outOfBounds {
+		newState = mappedState
+	}
 
 	// Record last time in each state
 	switch newState {
@@ -72,10 +82,9 @@ func (h *ServiceResultHandler) HandleResult(svc *objects.Service, cr *objects.Ch
 
 	// State change detection
 	stateChange := newState != lastState
-	hardChange := false
 
 	// Update current state early so notification callbacks see the correct state.
-	// All state machine branches below use local lastState, not svc.CurrentState.
+	// The state machine below uses local lastState, not svc.CurrentState.
 	svc.CurrentState = newState
 	svc.LastState = lastState
 
@@ -90,80 +99,37 @@ func (h *ServiceResultHandler) HandleResult(svc *objects.Service, cr *objects.Ch
 
 	// --- SOFT/HARD state machine ---
 
-	if newState == objects.ServiceOK {
-		// Recovery or continued OK
-		if lastState != objects.ServiceOK {
-			// Recovery from a problem - clear acknowledgement but preserve
-			// NotifiedOn until after the recovery notification is sent,
-			// because the viability check uses it to verify a prior PROBLEM.
-			svc.ProblemAcknowledged = false
-			svc.AckType = objects.AckNone
-			svc.LastNotification = time.Time{}
-			svc.NextNotification = time.Time{}
-			svc.NoMoreNotifications = false
-			svc.FirstProblemTime = time.Time{}
-			if lastStateType == objects.StateTypeHard {
-				// HARD recovery - send recovery notification
-				hardChange = true
-				svc.StateType = objects.StateTypeHard
-				svc.CurrentAttempt = 1
-				if h.OnNotification != nil {
-					h.OnNotification(svc, objects.NotificationNormal)
-				}
-			} else {
-				// SOFT recovery - no notification
-				svc.StateType = objects.StateTypeHard
-				svc.CurrentAttempt = 1
-			}
-			// Now safe to clear notification tracking state
-			svc.CurrentNotificationNumber = 0
-			svc.NotifiedOn = 0
-		} else {
-			// Continued OK
-			svc.StateType = objects.StateTypeHard
-			svc.CurrentAttempt = 1
-		}
-		svc.HostProblemAtLastCheck = false
-	} else if hostProblem {
-		// Host is DOWN - force service to HARD state immediately
-		svc.StateType = objects.StateTypeHard
-		svc.CurrentAttempt = svc.MaxCheckAttempts
-		svc.HostProblemAtLastCheck = true
-		// No notifications for service when host is down
-	} else if svc.MaxCheckAttempts <= 1 {
-		// max_check_attempts=1 means immediate HARD
-		svc.StateType = objects.StateTypeHard
-		svc.CurrentAttempt = 1
-		if stateChange || lastStateType == objects.StateTypeSoft {
-			hardChange = true
-			if h.OnNotification != nil {
-				h.OnNotification(svc, objects.NotificationNormal)
-			}
-		}
-		svc.HostProblemAtLastCheck = false
-	} else if lastState == objects.ServiceOK {
-		// First failure - transition to SOFT
-		svc.StateType = objects.StateTypeSoft
-		svc.CurrentAttempt = 1
-		svc.HostProblemAtLastCheck = false
-	} else if svc.StateType == objects.StateTypeSoft {
-		// Already in SOFT state
-		if svc.CurrentAttempt < svc.MaxCheckAttempts {
-			svc.CurrentAttempt++
-		}
-		if svc.CurrentAttempt >= svc.MaxCheckAttempts {
-			// Transition to HARD
-			svc.StateType = objects.StateTypeHard
-			hardChange = true
-			if h.OnNotification != nil {
-				h.OnNotification(svc, objects.NotificationNormal)
-			}
-		}
-		svc.HostProblemAtLastCheck = false
-	} else {
-		// HARD non-OK state, continued problem
-		svc.CurrentAttempt = svc.MaxCheckAttempts
-		svc.HostProblemAtLastCheck = false
+	t := ServiceTransition(ServiceAttempt{
+		LastState:        lastState,
+		LastStateType:    lastStateType,
+		CurrentAttempt:   svc.CurrentAttempt,
+		MaxCheckAttempts: svc.MaxCheckAttempts,
+		NewState:         newState,
+		HostProblem:      hostProblem,
+	})
+	hardChange := t.HardChange
+	if t.Recovery {
+		// Recovery from a problem - clear acknowledgement but preserve
+		// NotifiedOn until after the recovery notification is sent,
+		// because the viability check uses it to verify a prior PROBLEM.
+		svc.ProblemAcknowledged = false
+		svc.AckType = objects.AckNone
+		svc.LastNotification = time.Time{}
+		svc.NextNotification = time.Time{}
+		svc.NoMoreNotifications = false
+		svc.FirstProblemTime = time.Time{}
+	}
+	svc.StateType = t.StateType
+	svc.CurrentAttempt = t.CurrentAttempt
+	// No notifications for service when host is down
+	svc.HostProblemAtLastCheck = hostProblem
+	if t.Notify && h.OnNotification != nil {
+		h.OnNotification(svc, objects.NotificationNormal)
+	}
+	if t.Recovery {
+		// Now safe to clear notification tracking state
+		svc.CurrentNotificationNumber = 0
+		svc.NotifiedOn = 0
 	}
 
 	// Non-sticky ack: clear on any state change (including non-OK to different non-OK)
@@ -172,15 +138,26 @@ func (h *ServiceResultHandler) HandleResult(svc *objects.Service, cr *objects.Ch
 		svc.AckType = objects.AckNone
 	}
 
+	hardStateChanged := false
 	if hardChange || (svc.StateType == objects.StateTypeHard && lastStateType == objects.StateTypeHard && stateChange) {
 		svc.LastHardState = newState
 		svc.LastHardStateChange = now
+		svc.LastHardStateCause = objects.NewStateChangeCause(cr, svc.PluginOutput)
+		hardStateChanged = true
 	}
 
 	if stateChange {
 		svc.LastStateChange = now
 	}
 
+	objects.RecordCheck(&svc.CheckHistory, h.Cfg.CheckHistorySize, objects.CheckRecord{
+		Time:      now,
+		State:     newState,
+		StateType: svc.StateType,
+		Duration:  cr.ExecutionTime,
+		Output:    svc.PluginOutput,
+	})
+
 	// Flap detection
 	if svc.FlapDetectionEnabled {
 		if ShouldRecordServiceFlapState(newState, svc.StateType, lastState, lastHardState) {
@@ -206,10 +183,21 @@ func (h *ServiceResultHandler) HandleResult(svc *objects.Service, cr *objects.Ch
 	if h.OnStateChange != nil && (stateChange || hardChange) {
 		h.OnStateChange(svc, lastState, newState, hardChange)
 	}
+	if hardStateChanged && h.OnHardStateChange != nil {
+		h.OnHardStateChange(svc)
+	}
 
 	return hardChange
 }
 
+// SkipResult releases svc after its active check result was discarded
+// unprocessed, e.g. by result middleware, and schedules the next check at
+// the normal interval.
+func (h *ServiceResultHandler) SkipResult(svc *objects.Service, now time.Time) {
+	svc.IsExecuting = false
+	svc.NextCheck = now.Add(h.normalCheckWindow(svc))
+}
+
 func (h *ServiceResultHandler) normalCheckWindow(svc *objects.Service) time.Duration {
 	il := h.Cfg.IntervalLength
 	if il <= 0 {
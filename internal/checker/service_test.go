@@ -58,6 +58,70 @@ func TestServiceResultHandler_OKStaysOK(t *testing.T) {
 	}
 }
 
+func TestServiceResultHandler_CheckSource(t *testing.T) {
+	svc := newTestService()
+	h := &ServiceResultHandler{Cfg: newTestConfig()}
+
+	h.HandleResult(svc, &objects.CheckResult{
+		ReturnCode: 0,
+		ExitedOK:   true,
+		Output:     "OK",
+		Source:     "NRDP 10.0.0.5",
+		StartTime:  time.Now(),
+		FinishTime: time.Now(),
+	})
+	if svc.CheckSource != "NRDP 10.0.0.5" {
+		t.Errorf("expected check source from result, got %q", svc.CheckSource)
+	}
+}
+
+func TestServiceResultHandler_HardStateCause(t *testing.T) {
+	svc := newTestService()
+	var calls int
+	h := &ServiceResultHandler{Cfg: newTestConfig(), OnHardStateChange: func(*objects.Service) { calls++ }}
+	start := time.Unix(1700000000, 0)
+	result := func(rc, checkType int, source string) {
+		h.HandleResult(svc, &objects.CheckResult{
+			ReturnCode: rc,
+			ExitedOK:   true,
+			Output:     "state " + objects.ServiceStateName(rc),
+			CheckType:  checkType,
+			Source:     source,
+			StartTime:  start,
+			FinishTime: start.Add(time.Second),
+		})
+		start = start.Add(time.Minute)
+	}
+
+	// Soft results don't change the hard state; the third, passive one does
+	result(objects.ServiceCritical, objects.CheckTypeActive, "Core Worker 1")
+	result(objects.ServiceCritical, objects.CheckTypeActive, "Core Worker 1")
+	if calls != 0 || !svc.LastHardStateCause.Time.IsZero() {
+		t.Fatalf("soft results recorded a cause: %+v", svc.LastHardStateCause)
+	}
+	result(objects.ServiceCritical, objects.CheckTypePassive, "NRDP 10.0.0.5")
+	want := objects.StateChangeCause{
+		CheckType: objects.CheckTypePassive,
+		Source:    "NRDP 10.0.0.5",
+		Time:      time.Unix(1700000120, 0),
+		Output:    "state CRITICAL",
+	}
+	if calls != 1 || svc.LastHardStateCause != want {
+		t.Errorf("cause after HARD CRITICAL = %+v (%d calls), want %+v", svc.LastHardStateCause, calls, want)
+	}
+
+	// Results that keep the hard state keep its cause
+	result(objects.ServiceCritical, objects.CheckTypeActive, "Core Worker 1")
+	if calls != 1 || svc.LastHardStateCause != want {
+		t.Errorf("cause after an unchanged result = %+v, want %+v", svc.LastHardStateCause, want)
+	}
+	// A hard to hard change has a new cause
+	result(objects.ServiceWarning, objects.CheckTypeActive, "Core Worker 2")
+	if calls != 2 || svc.LastHardStateCause.Source != "Core Worker 2" || svc.LastHardStateCause.CheckType != objects.CheckTypeActive {
+		t.Errorf("cause after HARD WARNING = %+v", svc.LastHardStateCause)
+	}
+}
+
 func TestServiceResultHandler_SoftToHard(t *testing.T) {
 	cfg := newTestConfig()
 	svc := newTestService()
@@ -0,0 +1,171 @@
+package checker
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// SimConfig controls the synthetic results produced by SimExecutor.
+// Rates are per-check probabilities in [0,1].
+type SimConfig struct {
+	WarningRate  float64 // chance an OK service turns WARNING
+	CriticalRate float64 // chance an OK service turns CRITICAL
+	UnknownRate  float64 // chance an OK service turns UNKNOWN
+	HostDownRate float64 // chance an UP host turns DOWN
+	RecoveryRate float64 // chance a non-OK object recovers on its next check
+
+	// Latency is the simulated plugin execution time distribution:
+	// "fixed", "uniform" (0..2*mean), "normal" or "exponential".
+	Latency       string
+	LatencyMean   time.Duration
+	LatencyStdDev time.Duration // normal distribution only
+}
+
+// SimExecutor is a CheckRunner that never runs plugins. Each check produces
+// a synthetic result after a simulated execution time. States are sticky:
+// an object stays in its current state until a transition is drawn, so
+// load tests exercise realistic SOFT/HARD progressions and notifications
+// rather than constant flapping.
+type SimExecutor struct {
+	cfg         SimConfig
+	resultCh    chan *objects.CheckResult
+	jobsRunning atomic.Int64
+	stopped     atomic.Bool
+
+	mu     sync.Mutex
+	states map[string]int // "host\tsvc" -> last simulated return code
+}
+
+var _ CheckRunner = (*SimExecutor)(nil)
+
+// NewSimExecutor creates a simulation runner.
+func NewSimExecutor(cfg SimConfig, resultCh chan *objects.CheckResult) *SimExecutor {
+	if cfg.Latency == "" {
+		cfg.Latency = "fixed"
+	}
+	return &SimExecutor{
+		cfg:      cfg,
+		resultCh: resultCh,
+		states:   make(map[string]int),
+	}
+}
+
+// Submit schedules a synthetic result after a simulated execution time.
+// No goroutine is held while the check "runs".
+func (e *SimExecutor) Submit(hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64) {
+	if e.stopped.Load() {
+		return
+	}
+	e.jobsRunning.Add(1)
+	start := time.Now()
+	exec := e.latency()
+	timedOut := exec > timeout
+	if timedOut {
+		exec = timeout
+	}
+	time.AfterFunc(exec, func() {
+		cr := &objects.CheckResult{
+			HostName:           hostName,
+			ServiceDescription: svcDesc,
+			CheckType:          checkType,
+			CheckOptions:       checkOptions,
+			Latency:            latency,
+			ExitedOK:           true,
+			StartTime:          start,
+			FinishTime:         time.Now(),
+		}
+		cr.ExecutionTime = cr.FinishTime.Sub(start).Seconds()
+		if timedOut {
+			cr.EarlyTimeout = true
+			cr.ReturnCode = 2
+			cr.Output = fmt.Sprintf("(Check timed out after %.0f seconds)", timeout.Seconds())
+		} else {
+			cr.ReturnCode = e.nextState(hostName, svcDesc)
+			cr.Output = simOutput(svcDesc == "", cr.ReturnCode, cr.ExecutionTime)
+		}
+		e.jobsRunning.Add(-1)
+		e.resultCh <- cr
+	})
+}
+
+// JobsRunning returns the number of simulated checks in flight.
+func (e *SimExecutor) JobsRunning() int64 {
+	return e.jobsRunning.Load()
+}
+
+// Stop makes further submissions no-ops. In-flight results are still sent.
+func (e *SimExecutor) Stop() {
+	e.stopped.Store(true)
+}
+
+func (e *SimExecutor) nextState(hostName, svcDesc string) int {
+	key := hostName + "\t" + svcDesc
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cur := e.states[key]
+	next := cur
+	r := rand.Float64()
+	if cur != 0 {
+		if r < e.cfg.RecoveryRate {
+			next = 0
+		}
+	} else if svcDesc == "" {
+		if r < e.cfg.HostDownRate {
+			next = 2
+		}
+	} else {
+		switch {
+		case r < e.cfg.CriticalRate:
+			next = 2
+		case r < e.cfg.CriticalRate+e.cfg.WarningRate:
+			next = 1
+		case r < e.cfg.CriticalRate+e.cfg.WarningRate+e.cfg.UnknownRate:
+			next = 3
+		}
+	}
+	if next == 0 {
+		delete(e.states, key)
+	} else {
+		e.states[key] = next
+	}
+	return next
+}
+
+func (e *SimExecutor) latency() time.Duration {
+	mean := float64(e.cfg.LatencyMean)
+	var d float64
+	switch strings.ToLower(e.cfg.Latency) {
+	case "uniform":
+		d = rand.Float64() * 2 * mean
+	case "normal":
+		d = rand.NormFloat64()*float64(e.cfg.LatencyStdDev) + mean
+	case "exponential":
+		d = rand.ExpFloat64() * mean
+	default:
+		d = mean
+	}
+	if d < 0 || math.IsNaN(d) {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+func simOutput(isHost bool, rc int, execTime float64) string {
+	var state string
+	if isHost {
+		state = "UP"
+		if rc != 0 {
+			state = "DOWN"
+		}
+	} else {
+		state = objects.ServiceStateName(rc)
+	}
+	return fmt.Sprintf("SIMULATED %s - synthetic result|time=%.6fs;;;0", state, execTime)
+}
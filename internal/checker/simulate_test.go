@@ -0,0 +1,48 @@
+package checker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestSimExecutorStickyStates(t *testing.T) {
+	resultCh := make(chan *objects.CheckResult, 10)
+	e := NewSimExecutor(SimConfig{CriticalRate: 1, RecoveryRate: 0, LatencyMean: time.Millisecond}, resultCh)
+
+	for i := 0; i < 3; i++ {
+		e.Submit("h", "svc", "ignored", time.Second, 0, objects.CheckTypeActive, 0)
+		select {
+		case cr := <-resultCh:
+			if cr.ReturnCode != 2 || cr.ServiceDescription != "svc" {
+				t.Errorf("check %d: expected CRITICAL for svc, got rc=%d", i, cr.ReturnCode)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for simulated result")
+		}
+	}
+	if e.JobsRunning() != 0 {
+		t.Errorf("expected no jobs running, got %d", e.JobsRunning())
+	}
+
+	// Hosts only use the host down rate.
+	e.Submit("h", "", "ignored", time.Second, 0, objects.CheckTypeActive, 0)
+	if cr := <-resultCh; cr.ReturnCode != 0 {
+		t.Errorf("host with zero down rate should be UP, got rc=%d", cr.ReturnCode)
+	}
+}
+
+func TestSimExecutorTimeout(t *testing.T) {
+	resultCh := make(chan *objects.CheckResult, 1)
+	e := NewSimExecutor(SimConfig{LatencyMean: time.Hour}, resultCh)
+	e.Submit("h", "svc", "ignored", 10*time.Millisecond, 0, objects.CheckTypeActive, 0)
+	select {
+	case cr := <-resultCh:
+		if !cr.EarlyTimeout {
+			t.Error("expected simulated latency beyond the timeout to time out")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for simulated timeout")
+	}
+}
@@ -0,0 +1,389 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// SSHConfig configures the SSH check runner.
+type SSHConfig struct {
+	User           string
+	Port           int
+	KeyFile        string // private key used for public key auth
+	KnownHostsFile string // required unless InsecureIgnoreHostKey is set
+	// InsecureIgnoreHostKey accepts any host key when no KnownHostsFile is
+	// given. Anyone on the path can then impersonate the monitored hosts.
+	InsecureIgnoreHostKey bool
+	ConnectTimeout        time.Duration
+}
+
+// SSHExecutor runs check commands on the monitored host itself over SSH.
+// One client connection is kept per target address and every check opens a
+// new session on it, so the TCP and key-exchange cost is paid once per host
+// rather than once per check.
+type SSHExecutor struct {
+	jobCh       chan checkJob
+	jobsRunning atomic.Int64
+	resultCh    chan *objects.CheckResult
+	pool        *sshPool
+
+	// AddressLookup maps a host name to the address to connect to. When nil
+	// or when it returns "", the host name itself is dialed.
+	AddressLookup func(hostName string) string
+
+	// Concurrency, when set, limits checks per concurrency class. Share
+	// one with the local executor so a class's limit covers both.
+	Concurrency *ConcurrencyClasses
+}
+
+var _ CheckRunner = (*SSHExecutor)(nil)
+
+// NewSSHExecutor creates an SSH runner with maxConcurrent workers.
+func NewSSHExecutor(maxConcurrent int, resultCh chan *objects.CheckResult, cfg SSHConfig) (*SSHExecutor, error) {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 64
+	}
+	clientCfg, err := sshClientConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	port := cfg.Port
+	if port <= 0 {
+		port = 22
+	}
+	e := &SSHExecutor{
+		jobCh:    make(chan checkJob, maxConcurrent*4),
+		resultCh: resultCh,
+		pool:     newSSHPool(clientCfg, port),
+	}
+	for i := 0; i < maxConcurrent; i++ {
+		go e.worker()
+	}
+	return e, nil
+}
+
+func sshClientConfig(cfg SSHConfig) (*ssh.ClientConfig, error) {
+	if cfg.KeyFile == "" {
+		return nil, errors.New("ssh executor: no private key file configured")
+	}
+	keyData, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("ssh executor: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("ssh executor: parse %s: %w", cfg.KeyFile, err)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case cfg.KnownHostsFile != "":
+		hostKeyCallback, err = knownhosts.New(cfg.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("ssh executor: %w", err)
+		}
+	case cfg.InsecureIgnoreHostKey:
+		log.Printf("Warning: ssh executor has no known_hosts file, host keys will not be verified")
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, errors.New("ssh executor: no known_hosts file configured; set ssh_executor_known_hosts_file, or ssh_executor_insecure_ignore_host_key=1 to skip host key verification")
+	}
+
+	user := cfg.User
+	if user == "" {
+		user = "nagios"
+	}
+	timeout := cfg.ConnectTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, nil
+}
+
+// Submit queues a check for execution over SSH without blocking.
+func (e *SSHExecutor) Submit(hostName, svcDesc, command string, timeout time.Duration, checkOptions int, checkType int, latency float64) {
+	job := checkJob{
+		hostName:     hostName,
+		svcDesc:      svcDesc,
+		command:      command,
+		timeout:      timeout,
+		checkOptions: checkOptions,
+		checkType:    checkType,
+		latency:      latency,
+	}
+	select {
+	case e.jobCh <- job:
+	default:
+		go func() { e.jobCh <- job }()
+	}
+}
+
+// SubmitBySSH queues a check_by_ssh invocation for native execution. The
+// remote host, port and user come from the parsed command line rather than
+// from the monitored host.
+func (e *SSHExecutor) SubmitBySSH(bs *BySSHCommand, hostName, svcDesc string, timeout time.Duration, checkOptions int, checkType int, latency float64) {
+	if bs.Timeout > 0 && bs.Timeout < timeout {
+		timeout = bs.Timeout
+	}
+	job := checkJob{
+		hostName:     hostName,
+		svcDesc:      svcDesc,
+		command:      bs.Command,
+		timeout:      timeout,
+		checkOptions: checkOptions,
+		checkType:    checkType,
+		latency:      latency,
+		bySSH:        bs,
+	}
+	select {
+	case e.jobCh <- job:
+	default:
+		go func() { e.jobCh <- job }()
+	}
+}
+
+// JobsRunning returns the current number of executing checks.
+func (e *SSHExecutor) JobsRunning() int64 {
+	return e.jobsRunning.Load()
+}
+
+// Stop shuts down the workers and closes all pooled connections.
+func (e *SSHExecutor) Stop() {
+	close(e.jobCh)
+	e.pool.closeAll()
+}
+
+func (e *SSHExecutor) worker() {
+	runJob := func(job checkJob) {
+		e.jobsRunning.Add(1)
+		cr := e.run(job)
+		e.jobsRunning.Add(-1)
+		e.resultCh <- cr
+	}
+	for job := range e.jobCh {
+		e.Concurrency.run(job, runJob)
+	}
+}
+
+func (e *SSHExecutor) address(hostName string) string {
+	if e.AddressLookup != nil {
+		if addr := e.AddressLookup(hostName); addr != "" {
+			return addr
+		}
+	}
+	return hostName
+}
+
+func (e *SSHExecutor) run(job checkJob) *objects.CheckResult {
+	// Builtin checks probe from the monitoring server, never remotely.
+	if job.bySSH == nil {
+		if cr := runBuiltin(job); cr != nil {
+			return cr
+		}
+	}
+	cr := &objects.CheckResult{
+		HostName:           job.hostName,
+		ServiceDescription: job.svcDesc,
+		CheckType:          job.checkType,
+		CheckOptions:       job.checkOptions,
+		Latency:            job.latency,
+		ExitedOK:           true,
+	}
+	cr.StartTime = time.Now()
+	addr, user, port := e.address(job.hostName), "", 0
+	if job.bySSH != nil {
+		addr, user, port = job.bySSH.Host, job.bySSH.User, job.bySSH.Port
+	}
+	cr.Source = "SSH " + addr
+	if user != "" {
+		cr.Source = "SSH " + user + "@" + addr
+	}
+	output, stderr, code, err := e.pool.run(user, addr, port, job.command, job.timeout)
+	cr.FinishTime = time.Now()
+	cr.ExecutionTime = cr.FinishTime.Sub(cr.StartTime).Seconds()
+
+	switch {
+	case errors.Is(err, ErrCheckTimeout):
+		cr.EarlyTimeout = true
+		cr.ReturnCode = 2
+		cr.Output = fmt.Sprintf("(Check timed out after %.0f seconds)", job.timeout.Seconds())
+	case err != nil && job.bySSH != nil:
+		// check_by_ssh -U: a connection that fails is UNKNOWN.
+		cr.ReturnCode = 3
+		cr.ExitedOK = false
+		cr.Output = fmt.Sprintf("SSH connection failed: %v", err)
+	case err != nil:
+		cr.ReturnCode = 3
+		cr.ExitedOK = false
+		cr.Output = fmt.Sprintf("(SSH execution failed: %v)", err)
+	case job.bySSH != nil:
+		cr.Output, cr.ReturnCode = job.bySSH.result(output, stderr, code)
+	default:
+		cr.ReturnCode = code
+		cr.Output = output
+	}
+	return cr
+}
+
+// sshPool keeps one multiplexed client connection per user and target
+// address; each check runs in its own session on the shared connection.
+type sshPool struct {
+	cfg  *ssh.ClientConfig
+	port int
+
+	mu    sync.Mutex
+	conns map[string]*sshConn
+}
+
+// sshConn serializes dialing per target so a slow or dead host only blocks
+// checks against that host, not the whole pool.
+type sshConn struct {
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+func newSSHPool(cfg *ssh.ClientConfig, port int) *sshPool {
+	return &sshPool{cfg: cfg, port: port, conns: make(map[string]*sshConn)}
+}
+
+// key returns the pool key and dial target for a connection. Empty user and
+// zero port fall back to the pool's defaults.
+func (p *sshPool) key(user, addr string, port int) (string, string) {
+	if user == "" {
+		user = p.cfg.User
+	}
+	if port <= 0 {
+		port = p.port
+	}
+	target := addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		target = net.JoinHostPort(addr, strconv.Itoa(port))
+	}
+	return user + "@" + target, target
+}
+
+func (p *sshPool) get(user, key, target string) (*ssh.Client, error) {
+	p.mu.Lock()
+	conn := p.conns[key]
+	if conn == nil {
+		conn = &sshConn{}
+		p.conns[key] = conn
+	}
+	p.mu.Unlock()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.client != nil {
+		return conn.client, nil
+	}
+	cfg := p.cfg
+	if user != "" && user != cfg.User {
+		copied := *cfg
+		copied.User = user
+		cfg = &copied
+	}
+	c, err := ssh.Dial("tcp", target, cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn.client = c
+	return c, nil
+}
+
+// drop closes and forgets a connection, but only if it is still the pooled
+// one (another worker may already have replaced it).
+func (p *sshPool) drop(key string, c *ssh.Client) {
+	p.mu.Lock()
+	conn := p.conns[key]
+	p.mu.Unlock()
+	if conn != nil {
+		conn.mu.Lock()
+		if conn.client == c {
+			conn.client = nil
+		}
+		conn.mu.Unlock()
+	}
+	c.Close()
+}
+
+func (p *sshPool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, conn := range p.conns {
+		conn.mu.Lock()
+		if conn.client != nil {
+			conn.client.Close()
+			conn.client = nil
+		}
+		conn.mu.Unlock()
+		delete(p.conns, key)
+	}
+}
+
+// run executes command on addr as user and returns its stdout, stderr and
+// exit status. An error means the command did not run to completion over
+// the connection. A session that can't be opened on a pooled connection
+// means the connection went stale; it is redialed once.
+func (p *sshPool) run(user, addr string, port int, command string, timeout time.Duration) (string, string, int, error) {
+	key, target := p.key(user, addr, port)
+	var session *ssh.Session
+	for attempt := 0; attempt < 2; attempt++ {
+		c, err := p.get(user, key, target)
+		if err != nil {
+			return "", "", 0, err
+		}
+		session, err = c.NewSession()
+		if err == nil {
+			break
+		}
+		p.drop(key, c)
+		if attempt == 1 {
+			return "", "", 0, err
+		}
+	}
+	defer session.Close()
+
+	// The buffers go back to the pool only once Run has returned; after a
+	// timeout the session may still be writing to them.
+	stdout, stderr := getOutputBuffer(), getOutputBuffer()
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+
+	select {
+	case err := <-done:
+		out, errOut := outputString(stdout.Bytes()), outputString(stderr.Bytes())
+		putOutputBuffer(stdout)
+		putOutputBuffer(stderr)
+		if err == nil {
+			return out, errOut, 0, nil
+		}
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return out, errOut, exitErr.ExitStatus(), nil
+		}
+		return out, errOut, 0, err
+	case <-time.After(timeout):
+		session.Signal(ssh.SIGKILL)
+		return "", "", 0, ErrCheckTimeout
+	}
+}
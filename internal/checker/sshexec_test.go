@@ -0,0 +1,44 @@
+package checker
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// writeTestKey writes a new ed25519 private key and returns its path.
+func writeTestKey(t *testing.T) string {
+	t.Helper()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return keyFile
+}
+
+func TestSSHClientConfigRequiresKnownHosts(t *testing.T) {
+	keyFile := writeTestKey(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(knownHosts, nil, 0600)
+
+	if _, err := sshClientConfig(SSHConfig{KeyFile: keyFile}); err == nil || !strings.Contains(err.Error(), "known_hosts") {
+		t.Errorf("expected a missing known_hosts file to be refused, got %v", err)
+	}
+	if _, err := sshClientConfig(SSHConfig{KeyFile: keyFile, KnownHostsFile: knownHosts}); err != nil {
+		t.Errorf("known_hosts file: %v", err)
+	}
+	if _, err := sshClientConfig(SSHConfig{KeyFile: keyFile, InsecureIgnoreHostKey: true}); err != nil {
+		t.Errorf("explicit opt-out: %v", err)
+	}
+}
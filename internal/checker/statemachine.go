@@ -0,0 +1,131 @@
+package checker
+
+import "github.com/oceanplexian/gogios/internal/objects"
+
+// Transition is the outcome of running one check result through the
+// SOFT/HARD state machine. It is computed by ServiceTransition and
+// HostTransition without touching the object, so the rules can be tested
+// on their own; the result handlers apply it.
+type Transition struct {
+	StateType      int
+	CurrentAttempt int
+	// HardChange is set on a hard state change, including a hard recovery.
+	HardChange bool
+	// Notify is set when a NORMAL notification should be attempted.
+	Notify bool
+	// Recovery is set when a problem state returns to OK/UP. The handler
+	// resets acknowledgement and notification tracking.
+	Recovery bool
+}
+
+// ServiceAttempt is the state machine input for a service check result.
+type ServiceAttempt struct {
+	LastState        int
+	LastStateType    int
+	CurrentAttempt   int
+	MaxCheckAttempts int
+	NewState         int
+	// HostProblem is set when the service's host is not UP. A failing
+	// service on a failing host goes straight to HARD without notifying.
+	HostProblem bool
+}
+
+// ServiceTransition computes the SOFT/HARD transition for a service check
+// result, following handle_async_service_check_result() in Nagios.
+func ServiceTransition(in ServiceAttempt) Transition {
+	stateChange := in.NewState != in.LastState
+	switch {
+	case in.NewState == objects.ServiceOK:
+		t := Transition{StateType: objects.StateTypeHard, CurrentAttempt: 1}
+		if in.LastState != objects.ServiceOK {
+			t.Recovery = true
+			// Only a HARD problem was notified, so only its recovery is.
+			if in.LastStateType == objects.StateTypeHard {
+				t.HardChange = true
+				t.Notify = true
+			}
+		}
+		return t
+	case in.HostProblem:
+		return Transition{StateType: objects.StateTypeHard, CurrentAttempt: in.MaxCheckAttempts}
+	case in.MaxCheckAttempts <= 1:
+		t := Transition{StateType: objects.StateTypeHard, CurrentAttempt: 1, Notify: true}
+		if stateChange || in.LastStateType == objects.StateTypeSoft {
+			t.HardChange = true
+		}
+		return t
+	case in.LastState == objects.ServiceOK:
+		return Transition{StateType: objects.StateTypeSoft, CurrentAttempt: 1}
+	case in.LastStateType == objects.StateTypeSoft:
+		t := Transition{StateType: objects.StateTypeSoft, CurrentAttempt: in.CurrentAttempt}
+		if t.CurrentAttempt < in.MaxCheckAttempts {
+			t.CurrentAttempt++
+		}
+		if t.CurrentAttempt >= in.MaxCheckAttempts {
+			t.StateType = objects.StateTypeHard
+			t.HardChange = true
+			t.Notify = true
+		}
+		return t
+	default:
+		// Continued HARD problem. A change between problem states is not a
+		// hard change here; the handler records it as one because both
+		// the old and new state types are HARD. Every result attempts a
+		// notification, as in Nagios: the notification engine holds it
+		// back until notification_interval has passed, or the downtime
+		// that suppressed earlier ones has ended.
+		return Transition{StateType: objects.StateTypeHard, CurrentAttempt: in.MaxCheckAttempts, Notify: true}
+	}
+}
+
+// HostAttempt is the state machine input for a host check result.
+type HostAttempt struct {
+	LastState     int
+	LastStateType int
+	// CurrentAttempt has already been advanced by AdjustHostCheckAttempt
+	// when the active check was launched.
+	CurrentAttempt   int
+	MaxCheckAttempts int
+	NewState         int
+	// Passive results go straight to HARD.
+	Passive bool
+}
+
+// HostTransition computes the SOFT/HARD transition for a host check
+// result, following handle_host_state() in Nagios.
+func HostTransition(in HostAttempt) Transition {
+	stateChange := in.NewState != in.LastState
+	switch {
+	case in.NewState == objects.HostUp:
+		t := Transition{StateType: objects.StateTypeHard, CurrentAttempt: 1}
+		if in.LastState != objects.HostUp {
+			t.Recovery = true
+			if in.LastStateType == objects.StateTypeHard {
+				t.HardChange = true
+				t.Notify = true
+			}
+		}
+		return t
+	case in.MaxCheckAttempts <= 1:
+		t := Transition{StateType: objects.StateTypeHard, CurrentAttempt: 1, Notify: true}
+		if stateChange || in.LastStateType == objects.StateTypeSoft {
+			t.HardChange = true
+		}
+		return t
+	case in.LastState == objects.HostUp && in.Passive:
+		return Transition{StateType: objects.StateTypeHard, CurrentAttempt: in.MaxCheckAttempts, HardChange: true, Notify: true}
+	case in.LastState == objects.HostUp:
+		return Transition{StateType: objects.StateTypeSoft, CurrentAttempt: in.CurrentAttempt}
+	case in.LastStateType == objects.StateTypeSoft:
+		t := Transition{StateType: objects.StateTypeSoft, CurrentAttempt: in.CurrentAttempt}
+		if t.CurrentAttempt >= in.MaxCheckAttempts {
+			t.StateType = objects.StateTypeHard
+			t.HardChange = true
+			t.Notify = true
+		}
+		return t
+	default:
+		// Continued HARD problem; see ServiceTransition.
+		return Transition{StateType: objects.StateTypeHard, CurrentAttempt: in.MaxCheckAttempts, Notify: true}
+	}
+}
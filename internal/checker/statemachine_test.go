@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+const (
+	soft = objects.StateTypeSoft
+	hard = objects.StateTypeHard
+)
+
+func TestServiceTransition(t *testing.T) {
+	ok, warn, crit := objects.ServiceOK, objects.ServiceWarning, objects.ServiceCritical
+	tests := []struct {
+		name string
+		in   ServiceAttempt
+		want Transition
+	}{
+		{"continued OK",
+			ServiceAttempt{LastState: ok, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: ok},
+			Transition{StateType: hard, CurrentAttempt: 1}},
+		{"first failure goes SOFT",
+			ServiceAttempt{LastState: ok, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: crit},
+			Transition{StateType: soft, CurrentAttempt: 1}},
+		{"SOFT retry counts up",
+			ServiceAttempt{LastState: crit, LastStateType: soft, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: crit},
+			Transition{StateType: soft, CurrentAttempt: 2}},
+		{"SOFT change between problem states counts up",
+			ServiceAttempt{LastState: warn, LastStateType: soft, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: crit},
+			Transition{StateType: soft, CurrentAttempt: 2}},
+		{"last SOFT attempt goes HARD and notifies",
+			ServiceAttempt{LastState: crit, LastStateType: soft, CurrentAttempt: 2, MaxCheckAttempts: 3, NewState: crit},
+			Transition{StateType: hard, CurrentAttempt: 3, HardChange: true, Notify: true}},
+		{"attempt never exceeds max",
+			ServiceAttempt{LastState: crit, LastStateType: soft, CurrentAttempt: 5, MaxCheckAttempts: 3, NewState: crit},
+			Transition{StateType: hard, CurrentAttempt: 5, HardChange: true, Notify: true}},
+		{"continued HARD problem attempts a re-notification",
+			ServiceAttempt{LastState: crit, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: crit},
+			Transition{StateType: hard, CurrentAttempt: 3, Notify: true}},
+		{"HARD change between problem states notifies",
+			ServiceAttempt{LastState: warn, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: crit},
+			Transition{StateType: hard, CurrentAttempt: 3, Notify: true}},
+		{"recovery during SOFT does not notify",
+			ServiceAttempt{LastState: crit, LastStateType: soft, CurrentAttempt: 2, MaxCheckAttempts: 3, NewState: ok},
+			Transition{StateType: hard, CurrentAttempt: 1, Recovery: true}},
+		{"recovery from HARD notifies",
+			ServiceAttempt{LastState: crit, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: ok},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true, Recovery: true}},
+		{"max_check_attempts=1 goes HARD at once",
+			ServiceAttempt{LastState: ok, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 1, NewState: crit},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true}},
+		{"max_check_attempts=1 continued problem attempts a re-notification",
+			ServiceAttempt{LastState: crit, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 1, NewState: crit},
+			Transition{StateType: hard, CurrentAttempt: 1, Notify: true}},
+		{"max_check_attempts lowered while SOFT goes HARD",
+			ServiceAttempt{LastState: crit, LastStateType: soft, CurrentAttempt: 2, MaxCheckAttempts: 1, NewState: crit},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true}},
+		{"max_check_attempts=0 is treated as 1",
+			ServiceAttempt{LastState: ok, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 0, NewState: warn},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true}},
+		{"host problem forces HARD without notifying",
+			ServiceAttempt{LastState: ok, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: crit, HostProblem: true},
+			Transition{StateType: hard, CurrentAttempt: 3}},
+		{"host problem ignored on OK",
+			ServiceAttempt{LastState: crit, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: ok, HostProblem: true},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true, Recovery: true}},
+		{"continued problem behind a host problem is quiet",
+			ServiceAttempt{LastState: crit, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: crit, HostProblem: true},
+			Transition{StateType: hard, CurrentAttempt: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ServiceTransition(tt.in); got != tt.want {
+				t.Errorf("ServiceTransition(%+v)\n got %+v\nwant %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostTransition(t *testing.T) {
+	up, down, unreach := objects.HostUp, objects.HostDown, objects.HostUnreachable
+	tests := []struct {
+		name string
+		in   HostAttempt
+		want Transition
+	}{
+		{"continued UP",
+			HostAttempt{LastState: up, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: up},
+			Transition{StateType: hard, CurrentAttempt: 1}},
+		{"active first failure goes SOFT keeping the adjusted attempt",
+			HostAttempt{LastState: up, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: down},
+			Transition{StateType: soft, CurrentAttempt: 1}},
+		{"passive first failure goes HARD and notifies",
+			HostAttempt{LastState: up, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 3, NewState: down, Passive: true},
+			Transition{StateType: hard, CurrentAttempt: 3, HardChange: true, Notify: true}},
+		{"SOFT below max stays SOFT",
+			HostAttempt{LastState: down, LastStateType: soft, CurrentAttempt: 2, MaxCheckAttempts: 3, NewState: down},
+			Transition{StateType: soft, CurrentAttempt: 2}},
+		{"passive SOFT below max stays SOFT",
+			HostAttempt{LastState: down, LastStateType: soft, CurrentAttempt: 2, MaxCheckAttempts: 3, NewState: down, Passive: true},
+			Transition{StateType: soft, CurrentAttempt: 2}},
+		{"SOFT at max goes HARD and notifies",
+			HostAttempt{LastState: down, LastStateType: soft, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: down},
+			Transition{StateType: hard, CurrentAttempt: 3, HardChange: true, Notify: true}},
+		{"SOFT change to UNREACHABLE keeps counting",
+			HostAttempt{LastState: down, LastStateType: soft, CurrentAttempt: 2, MaxCheckAttempts: 3, NewState: unreach},
+			Transition{StateType: soft, CurrentAttempt: 2}},
+		{"continued HARD problem attempts a re-notification",
+			HostAttempt{LastState: down, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: down},
+			Transition{StateType: hard, CurrentAttempt: 3, Notify: true}},
+		{"HARD change between problem states notifies",
+			HostAttempt{LastState: down, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: unreach},
+			Transition{StateType: hard, CurrentAttempt: 3, Notify: true}},
+		{"recovery during SOFT does not notify",
+			HostAttempt{LastState: down, LastStateType: soft, CurrentAttempt: 2, MaxCheckAttempts: 3, NewState: up},
+			Transition{StateType: hard, CurrentAttempt: 1, Recovery: true}},
+		{"recovery from HARD notifies",
+			HostAttempt{LastState: down, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: up},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true, Recovery: true}},
+		{"passive recovery from HARD notifies",
+			HostAttempt{LastState: down, LastStateType: hard, CurrentAttempt: 3, MaxCheckAttempts: 3, NewState: up, Passive: true},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true, Recovery: true}},
+		{"max_check_attempts=1 goes HARD at once",
+			HostAttempt{LastState: up, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 1, NewState: down},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true}},
+		{"max_check_attempts=1 passive goes HARD at once",
+			HostAttempt{LastState: up, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 1, NewState: down, Passive: true},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true}},
+		{"max_check_attempts=1 continued problem attempts a re-notification",
+			HostAttempt{LastState: down, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 1, NewState: down},
+			Transition{StateType: hard, CurrentAttempt: 1, Notify: true}},
+		{"max_check_attempts=1 change between problem states notifies",
+			HostAttempt{LastState: down, LastStateType: hard, CurrentAttempt: 1, MaxCheckAttempts: 1, NewState: unreach},
+			Transition{StateType: hard, CurrentAttempt: 1, HardChange: true, Notify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HostTransition(tt.in); got != tt.want {
+				t.Errorf("HostTransition(%+v)\n got %+v\nwant %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func init() {
+	RegisterBuiltin("gogios_tcp", CheckTCP)
+}
+
+type tcpCheckOpts struct {
+	host          string
+	port          int
+	ssl           bool
+	send, quit    string
+	expect        []string
+	regex         *regexp.Regexp
+	maxBytes      int
+	refuseState   int
+	mismatchState int
+	warn, crit    float64 // response time thresholds in seconds; 0 = unset
+	certWarn      int     // days; -1 = unset
+	certCrit      int
+	timeout       string
+	network       string // "tcp", or "tcp4"/"tcp6" for -4/-6
+}
+
+// CheckTCP is the gogios_tcp builtin. It accepts a check_tcp-compatible
+// subset of options:
+//
+//	-H host  -p port  -S  -s send  -e expect  --regex regex  -q quit
+//	-E (escape \n \r \t \\ in -s and -q)  -m maxbytes  -r ok|warn|crit
+//	-M ok|warn|crit  -w secs  -c secs  -D warn_days[,crit_days]  -t secs
+//	-4  -6
+//
+// -e may be repeated, and is matched as a substring of what the server
+// sends; any one matching is enough. --regex matches the same data.
+func CheckTCP(ctx context.Context, args []string) (int, string) {
+	o, err := parseTCPCheckArgs(args)
+	if err != "" {
+		return objects.ServiceUnknown, "TCP UNKNOWN - " + err
+	}
+	ctx, cancel, err := withCheckTimeout(ctx, o.timeout)
+	if err != "" {
+		return objects.ServiceUnknown, "TCP UNKNOWN - " + err
+	}
+	defer cancel()
+
+	host := strings.TrimSuffix(strings.TrimPrefix(o.host, "["), "]")
+	start := time.Now()
+	conn, derr := builtinDialer.DialContext(ctx, o.network, net.JoinHostPort(host, strconv.Itoa(o.port)))
+	if derr != nil {
+		if errors.Is(derr, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return objects.ServiceCritical, "TCP CRITICAL - Socket timeout"
+		}
+		if errors.Is(derr, syscall.ECONNREFUSED) {
+			return o.refuseState, fmt.Sprintf("%s - Connection refused", tcpLabel(o.refuseState))
+		}
+		return objects.ServiceCritical, "TCP CRITICAL - " + derr.Error()
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	state := objects.ServiceOK
+	var problems []string
+	if o.ssl {
+		tc := tls.Client(conn, &tls.Config{
+			ServerName: host,
+			// Like check_tcp, the certificate is not verified; only its
+			// expiry is checked, with -D.
+			InsecureSkipVerify: true,
+		})
+		if herr := tc.HandshakeContext(ctx); herr != nil {
+			if errors.Is(herr, os.ErrDeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return objects.ServiceCritical, "TCP CRITICAL - Socket timeout"
+			}
+			return objects.ServiceCritical, "TCP CRITICAL - TLS handshake failed: " + herr.Error()
+		}
+		conn = tc
+		if o.certWarn >= 0 {
+			cert := tc.ConnectionState().PeerCertificates[0]
+			days := int(math.Floor(time.Until(cert.NotAfter).Hours() / 24))
+			certState := objects.ServiceOK
+			switch {
+			case days < 0:
+				certState = objects.ServiceCritical
+			case o.certCrit >= 0 && days < o.certCrit:
+				certState = objects.ServiceCritical
+			case days < o.certWarn:
+				certState = objects.ServiceWarning
+			}
+			if certState != objects.ServiceOK {
+				problems = append(problems, fmt.Sprintf("Certificate '%s' expires in %d day(s) (%s)",
+					cert.Subject.CommonName, days, cert.NotAfter.Format("2006-01-02 15:04 -0700")))
+			}
+			state = worstState(state, certState)
+		}
+	}
+
+	if o.send != "" {
+		if _, werr := conn.Write([]byte(o.send)); werr != nil {
+			return objects.ServiceCritical, "TCP CRITICAL - " + werr.Error()
+		}
+	}
+	var received []byte
+	if len(o.expect) > 0 || o.regex != nil {
+		var matched bool
+		var rerr error
+		received, matched, rerr = readUntilMatch(conn, o)
+		if !matched {
+			if errors.Is(rerr, os.ErrDeadlineExceeded) && len(received) == 0 {
+				return objects.ServiceCritical, "TCP CRITICAL - Socket timeout"
+			}
+			state = worstState(state, o.mismatchState)
+			problems = append(problems, "Unexpected response from host/socket")
+		}
+	}
+	if o.quit != "" {
+		conn.Write([]byte(o.quit))
+	}
+	elapsed := time.Since(start).Seconds()
+
+	switch {
+	case o.crit > 0 && elapsed > o.crit:
+		state = worstState(state, objects.ServiceCritical)
+	case o.warn > 0 && elapsed > o.warn:
+		state = worstState(state, objects.ServiceWarning)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s - %.3f second response time on %s port %d", tcpLabel(state), elapsed, o.host, o.port)
+	if banner, _, _ := strings.Cut(strings.TrimSpace(string(received)), "\n"); banner != "" {
+		fmt.Fprintf(&sb, " [%s]", strings.TrimSpace(banner))
+	}
+	for _, p := range problems {
+		sb.WriteString(" - ")
+		sb.WriteString(p)
+	}
+	fmt.Fprintf(&sb, "|time=%.6fs;%s;%s;0.000000", elapsed, thresholdString(o.warn), thresholdString(o.crit))
+	return state, sb.String()
+}
+
+// readUntilMatch reads from conn until what it has read matches o, the
+// server closes the connection, o.maxBytes have been read or the deadline
+// passes. It returns the read error that stopped it, if any.
+func readUntilMatch(conn net.Conn, o *tcpCheckOpts) ([]byte, bool, error) {
+	var buf []byte
+	chunk := make([]byte, 4096)
+	for len(buf) < o.maxBytes {
+		n, err := conn.Read(chunk[:min(len(chunk), o.maxBytes-len(buf))])
+		buf = append(buf, chunk[:n]...)
+		if tcpResponseMatches(buf, o) {
+			return buf, true, nil
+		}
+		if err != nil {
+			return buf, false, err
+		}
+	}
+	return buf, false, nil
+}
+
+func tcpResponseMatches(buf []byte, o *tcpCheckOpts) bool {
+	if o.regex != nil && o.regex.Match(buf) {
+		return true
+	}
+	for _, e := range o.expect {
+		if strings.Contains(string(buf), e) {
+			return true
+		}
+	}
+	return false
+}
+
+func tcpLabel(state int) string {
+	return map[int]string{
+		objects.ServiceOK:       "TCP OK",
+		objects.ServiceWarning:  "TCP WARNING",
+		objects.ServiceCritical: "TCP CRITICAL",
+		objects.ServiceUnknown:  "TCP UNKNOWN",
+	}[state]
+}
+
+// parseStateName parses the ok|warn|crit argument of -r and -M.
+func parseStateName(val string) (int, bool) {
+	switch strings.ToLower(val) {
+	case "ok":
+		return objects.ServiceOK, true
+	case "warn", "warning":
+		return objects.ServiceWarning, true
+	case "crit", "critical":
+		return objects.ServiceCritical, true
+	}
+	return 0, false
+}
+
+func parseTCPCheckArgs(args []string) (*tcpCheckOpts, string) {
+	o := &tcpCheckOpts{
+		maxBytes:      1024,
+		refuseState:   objects.ServiceCritical,
+		mismatchState: objects.ServiceWarning,
+		certWarn:      -1,
+		certCrit:      -1,
+		network:       "tcp",
+	}
+	noValue := map[string]bool{
+		"-S": true, "--ssl": true, "-E": true, "--escape": true,
+		"-4": true, "--use-ipv4": true, "-6": true, "--use-ipv6": true,
+	}
+	escape := false
+	f := &builtinFlags{args: args}
+	for {
+		name, val, ok, err := f.next(func(n string) bool { return !noValue[n] })
+		if err != "" {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		switch name {
+		case "-H", "--hostname":
+			o.host = val
+		case "-p", "--port":
+			p, perr := strconv.Atoi(val)
+			if perr != nil || p <= 0 || p > 65535 {
+				return nil, "invalid port '" + val + "'"
+			}
+			o.port = p
+		case "-S", "--ssl":
+			o.ssl = true
+		case "-E", "--escape":
+			escape = true
+		case "-4", "--use-ipv4":
+			o.network = "tcp4"
+		case "-6", "--use-ipv6":
+			o.network = "tcp6"
+		case "-s", "--send":
+			o.send = val
+		case "-q", "--quit":
+			o.quit = val
+		case "-e", "--expect":
+			o.expect = append(o.expect, val)
+		case "--regex":
+			re, rerr := regexp.Compile(val)
+			if rerr != nil {
+				return nil, "invalid regex: " + rerr.Error()
+			}
+			o.regex = re
+		case "-m", "--maxbytes":
+			n, nerr := strconv.Atoi(val)
+			if nerr != nil || n <= 0 {
+				return nil, "invalid maxbytes '" + val + "'"
+			}
+			o.maxBytes = n
+		case "-r", "--refuse":
+			s, sok := parseStateName(val)
+			if !sok {
+				return nil, "invalid refuse state '" + val + "'"
+			}
+			o.refuseState = s
+		case "-M", "--mismatch":
+			s, sok := parseStateName(val)
+			if !sok {
+				return nil, "invalid mismatch state '" + val + "'"
+			}
+			o.mismatchState = s
+		case "-w", "--warning":
+			v, verr := strconv.ParseFloat(val, 64)
+			if verr != nil {
+				return nil, "invalid warning threshold '" + val + "'"
+			}
+			o.warn = v
+		case "-c", "--critical":
+			v, verr := strconv.ParseFloat(val, 64)
+			if verr != nil {
+				return nil, "invalid critical threshold '" + val + "'"
+			}
+			o.crit = v
+		case "-D", "--certificate":
+			parts := strings.SplitN(val, ",", 2)
+			w, werr := strconv.Atoi(parts[0])
+			if werr != nil {
+				return nil, "invalid certificate threshold '" + val + "'"
+			}
+			o.certWarn = w
+			if len(parts) == 2 {
+				c, cerr := strconv.Atoi(parts[1])
+				if cerr != nil {
+					return nil, "invalid certificate threshold '" + val + "'"
+				}
+				o.certCrit = c
+			}
+		case "-t", "--timeout":
+			o.timeout = val
+		default:
+			return nil, "unknown option " + name
+		}
+	}
+	if o.host == "" {
+		return nil, "no host specified (-H)"
+	}
+	if o.port == 0 {
+		return nil, "no port specified (-p)"
+	}
+	if escape {
+		r := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\\`, `\`)
+		o.send, o.quit = r.Replace(o.send), r.Replace(o.quit)
+	}
+	if o.certWarn >= 0 && !o.ssl {
+		return nil, "-D requires -S"
+	}
+	return o, ""
+}
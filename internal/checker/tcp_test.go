@@ -0,0 +1,147 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// bannerServer greets with banner and answers "PING\n" with "PONG\n".
+func bannerServer(t *testing.T, banner string) (string, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				c.Write([]byte(banner))
+				line, _ := bufio.NewReader(c).ReadString('\n')
+				if line == "PING\n" {
+					c.Write([]byte("PONG\n"))
+				}
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+func TestCheckTCP(t *testing.T) {
+	host, port := bannerServer(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	ctx := context.Background()
+
+	rc, out := CheckTCP(ctx, []string{"-H", host, "-p", port})
+	if rc != objects.ServiceOK || !strings.HasPrefix(out, "TCP OK") || !strings.Contains(out, "|time=") {
+		t.Errorf("connect: rc=%d out=%q", rc, out)
+	}
+	rc, out = CheckTCP(ctx, []string{"-H", host, "-p", port, "--regex", `^SSH-2\.0-`})
+	if rc != objects.ServiceOK || !strings.Contains(out, "[SSH-2.0-OpenSSH_9.6]") {
+		t.Errorf("banner regex: rc=%d out=%q", rc, out)
+	}
+	rc, out = CheckTCP(ctx, []string{"-H", host, "-p", port, "-E", "-s", `PING\n`, "-e", "PONG"})
+	if rc != objects.ServiceOK {
+		t.Errorf("send/expect: rc=%d out=%q", rc, out)
+	}
+	rc, out = CheckTCP(ctx, []string{"-H", host, "-p", port, "-e", "SMTP", "-t", "0.2"})
+	if rc != objects.ServiceWarning || !strings.Contains(out, "Unexpected response") {
+		t.Errorf("mismatch: rc=%d out=%q", rc, out)
+	}
+	if rc, _ := CheckTCP(ctx, []string{"-H", host, "-p", port, "-e", "SMTP", "-M", "crit", "-t", "0.2"}); rc != objects.ServiceCritical {
+		t.Errorf("mismatch with -M crit: rc=%d", rc)
+	}
+
+	// A closed port is refused.
+	ln, _ := net.Listen("tcp4", "127.0.0.1:0")
+	closed := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	ln.Close()
+	rc, out = CheckTCP(ctx, []string{"-H", "127.0.0.1", "-p", closed})
+	if rc != objects.ServiceCritical || !strings.Contains(out, "Connection refused") {
+		t.Errorf("refused: rc=%d out=%q", rc, out)
+	}
+	if rc, _ := CheckTCP(ctx, []string{"-H", "127.0.0.1", "-p", closed, "-r", "ok"}); rc != objects.ServiceOK {
+		t.Errorf("refused with -r ok: rc=%d", rc)
+	}
+	if rc, out := CheckTCP(ctx, []string{"-H", host}); rc != objects.ServiceUnknown {
+		t.Errorf("missing port: rc=%d out=%q", rc, out)
+	}
+}
+
+func TestCheckTCPTimeout(t *testing.T) {
+	// The server never sends, so waiting for a banner runs into -t.
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	start := time.Now()
+	rc, out := CheckTCP(context.Background(), []string{"-H", "127.0.0.1", "-p", port, "-e", "220", "-t", "0.1"})
+	if rc != objects.ServiceCritical || !strings.Contains(out, "Socket timeout") {
+		t.Errorf("rc=%d out=%q", rc, out)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("-t 0.1 took %v", time.Since(start))
+	}
+}
+
+func TestCheckTCPCertificate(t *testing.T) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	host, port := splitHostPort(t, srv.URL)
+	days := int(time.Until(srv.Certificate().NotAfter).Hours() / 24)
+
+	if rc, out := CheckTCP(context.Background(), []string{"-H", host, "-p", port, "-S", "-D", "1"}); rc != objects.ServiceOK {
+		t.Errorf("rc=%d out=%q", rc, out)
+	}
+	rc, out := CheckTCP(context.Background(), []string{"-H", host, "-p", port, "-S", "-D", strconv.Itoa(days + 10)})
+	if rc != objects.ServiceWarning || !strings.Contains(out, "expires in") {
+		t.Errorf("rc=%d out=%q", rc, out)
+	}
+}
+
+func TestDialPoolSlots(t *testing.T) {
+	p := newDialPool(1)
+	release, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.acquire(ctx); err == nil {
+		t.Fatal("second acquire should wait for the slot")
+	}
+	release()
+	release() // idempotent
+	if _, err := p.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
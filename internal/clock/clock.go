@@ -0,0 +1,161 @@
+// Package clock abstracts the wall clock so the scheduler and downtime
+// timers can run on virtual time in tests. Production code uses Real; tests
+// use a Fake and move it forward with Advance instead of sleeping.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates timers.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer that sends the time on C after d.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc runs f in its own goroutine after d. The returned timer's
+	// C is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the subset of *time.Timer the scheduler and downtime manager use.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Real is the system clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+// Fake is a manually advanced clock. Timers fire only from Advance, or at
+// once when created or reset with a duration that is already due, and
+// AfterFunc callbacks run synchronously on the goroutine that fired them.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d, firing due timers in deadline
+// order with the clock set to each one's deadline as it fires.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(d)
+	for {
+		t := f.nextDue(target)
+		if t == nil {
+			break
+		}
+		f.now = t.when
+		f.mu.Unlock()
+		t.fire()
+		f.mu.Lock()
+	}
+	f.now = target
+	f.mu.Unlock()
+}
+
+// nextDue removes and returns the earliest timer due by target. Called
+// with f.mu held.
+func (f *Fake) nextDue(target time.Time) *fakeTimer {
+	sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].when.Before(f.timers[j].when) })
+	if len(f.timers) == 0 || f.timers[0].when.After(target) {
+		return nil
+	}
+	t := f.timers[0]
+	f.timers = f.timers[1:]
+	return t
+}
+
+// NewTimer returns a timer on the fake clock.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{f: f, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc returns a timer on the fake clock that calls fn when it fires.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{f: f, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+type fakeTimer struct {
+	f    *Fake
+	when time.Time
+	ch   chan time.Time
+	fn   func()
+}
+
+func (t *fakeTimer) fire() {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	select {
+	case t.ch <- t.when:
+	default:
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+// Stop reports whether the timer was pending.
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	for i, o := range t.f.timers {
+		if o == t {
+			t.f.timers = append(t.f.timers[:i], t.f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Reset reports whether the timer was pending. A duration of zero or less
+// fires the timer before Reset returns.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	active := t.Stop()
+	t.f.mu.Lock()
+	t.when = t.f.now.Add(d)
+	if d <= 0 {
+		t.f.mu.Unlock()
+		t.fire()
+		return active
+	}
+	t.f.timers = append(t.f.timers, t)
+	t.f.mu.Unlock()
+	return active
+}
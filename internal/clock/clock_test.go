@@ -0,0 +1,60 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_AdvanceFiresInOrder(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	f := NewFake(start)
+
+	var fired []time.Duration
+	f.AfterFunc(3*time.Second, func() { fired = append(fired, f.Now().Sub(start)) })
+	f.AfterFunc(time.Second, func() { fired = append(fired, f.Now().Sub(start)) })
+	stopped := f.AfterFunc(2*time.Second, func() { t.Error("stopped timer fired") })
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("expected only the first Stop to report a pending timer")
+	}
+	timer := f.NewTimer(2 * time.Second)
+
+	f.Advance(2500 * time.Millisecond)
+	if len(fired) != 1 || fired[0] != time.Second {
+		t.Errorf("after 2.5s got %v, want [1s]", fired)
+	}
+	select {
+	case at := <-timer.C():
+		if !at.Equal(start.Add(2 * time.Second)) {
+			t.Errorf("timer sent %v, want its deadline", at)
+		}
+	default:
+		t.Error("timer did not fire")
+	}
+	if got := f.Now(); !got.Equal(start.Add(2500 * time.Millisecond)) {
+		t.Errorf("Now = %v after Advance", got)
+	}
+
+	f.Advance(time.Second)
+	if len(fired) != 2 || fired[1] != 3*time.Second {
+		t.Errorf("after 3.5s got %v, want [1s 3s]", fired)
+	}
+}
+
+func TestFake_ResetDueFiresImmediately(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	timer := f.NewTimer(time.Hour)
+	if !timer.Reset(0) {
+		t.Error("expected Reset to report the pending timer")
+	}
+	select {
+	case <-timer.C():
+	default:
+		t.Error("Reset(0) did not fire")
+	}
+	f.Advance(2 * time.Hour)
+	select {
+	case <-timer.C():
+		t.Error("timer fired again at its old deadline")
+	default:
+	}
+}
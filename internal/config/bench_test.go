@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BenchmarkCheckTime compares timeperiod lookups that parse the range
+// strings on each call with lookups in the compiled minute bitmaps:
+//
+//	go test ./internal/config -run '^$' -bench CheckTime -benchmem
+func BenchmarkCheckTime(b *testing.B) {
+	at := time.Date(2024, 11, 28, 10, 30, 0, 0, time.UTC)
+	b.Run("parsed", func(b *testing.B) {
+		tp := newHolidayTimeperiod()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			CheckTime(tp, at)
+		}
+	})
+	b.Run("compiled", func(b *testing.B) {
+		tp := newHolidayTimeperiod()
+		CompileTimeperiod(tp)
+		CompileTimeperiod(tp.Exclusions[0])
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			CheckTime(tp, at)
+		}
+	})
+}
+
+// BenchmarkGetNextValidTime measures the minute-by-minute search for the
+// next valid time from a Saturday evening, which crosses the weekend.
+func BenchmarkGetNextValidTime(b *testing.B) {
+	at := time.Date(2024, 6, 15, 18, 0, 0, 0, time.UTC)
+	b.Run("parsed", func(b *testing.B) {
+		tp := newHolidayTimeperiod()
+		for i := 0; i < b.N; i++ {
+			GetNextValidTime(tp, at)
+		}
+	})
+	b.Run("compiled", func(b *testing.B) {
+		tp := newHolidayTimeperiod()
+		CompileTimeperiod(tp)
+		CompileTimeperiod(tp.Exclusions[0])
+		for i := 0; i < b.N; i++ {
+			GetNextValidTime(tp, at)
+		}
+	})
+}
+
+// BenchmarkLoadConfigCached compares a full load with a reload through a
+// warm ParseCache, which replays unchanged files instead of tokenizing them
+// again. Template resolution, expansion and registration run either way,
+// and they dominate a load:
+//
+//	go test ./internal/config -run '^$' -bench LoadConfigCached -benchmem
+func BenchmarkLoadConfigCached(b *testing.B) {
+	dir := b.TempDir()
+	mainCfg := writeBenchConfig(b, dir, 20, 100, 10)
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := LoadConfigCached(mainCfg, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		cache := NewParseCache()
+		if _, err := LoadConfigCached(mainCfg, cache); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := LoadConfigCached(mainCfg, cache); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkParseObjectFiles isolates the step ParseCache speeds up:
+// reading and tokenizing the object files.
+//
+//	go test ./internal/config -run '^$' -bench ParseObjectFiles -benchmem
+func BenchmarkParseObjectFiles(b *testing.B) {
+	dir := b.TempDir()
+	mainCfg, err := ReadMainConfig(writeBenchConfig(b, dir, 20, 100, 10))
+	if err != nil {
+		b.Fatal(err)
+	}
+	parse := func(b *testing.B, cache *ParseCache) {
+		parser := NewObjectParser()
+		parser.Cache = cache
+		if err := parseObjectFiles(mainCfg, parser, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			parse(b, nil)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		cache := NewParseCache()
+		parse(b, cache)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			parse(b, cache)
+		}
+	})
+}
+
+// writeBenchConfig writes files host files of hostsPerFile hosts with
+// svcsPerHost services each, all using templates, and returns the path of
+// the main config file.
+func writeBenchConfig(b *testing.B, dir string, files, hostsPerFile, svcsPerHost int) string {
+	b.Helper()
+	var main strings.Builder
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			b.Fatal(err)
+		}
+		fmt.Fprintf(&main, "cfg_file=%s\n", path)
+	}
+	write("templates.cfg", `define command {
+    command_name check_dummy
+    command_line /bin/true $ARG1$
+}
+define host {
+    name                 generic-host
+    register             0
+    check_command        check_dummy
+    max_check_attempts   3
+    check_interval       5
+    retry_interval       1
+}
+define service {
+    name                 generic-service
+    register             0
+    check_command        check_dummy!svc
+    max_check_attempts   3
+    check_interval       5
+    retry_interval       1
+}
+`)
+	for f := 0; f < files; f++ {
+		var sb strings.Builder
+		for h := 0; h < hostsPerFile; h++ {
+			name := fmt.Sprintf("host-%d-%d", f, h)
+			fmt.Fprintf(&sb, "define host {\n    use generic-host\n    host_name %s\n    alias %s\n    address 10.%d.%d.1\n    _SITE dc%d\n}\n", name, name, f, h, f%3)
+			for s := 0; s < svcsPerHost; s++ {
+				fmt.Fprintf(&sb, "define service {\n    use generic-service\n    host_name %s\n    service_description svc-%d\n    notes service %d on %s\n}\n", name, s, s, name)
+			}
+		}
+		write(fmt.Sprintf("hosts-%02d.cfg", f), sb.String())
+	}
+	path := filepath.Join(dir, "nagios.cfg")
+	if err := os.WriteFile(path, []byte(main.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
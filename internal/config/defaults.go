@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// ObjectDefaults holds the *_default directives: attributes given to hosts
+// and services whose definitions, templates included, omit them. Periods
+// name a timeperiod; contacts and contact groups are comma-separated names.
+// Empty fields set nothing.
+type ObjectDefaults struct {
+	HostCheckPeriod           string
+	HostNotificationPeriod    string
+	HostContacts              string
+	HostContactGroups         string
+	ServiceCheckPeriod        string
+	ServiceNotificationPeriod string
+	ServiceContacts           string
+	ServiceContactGroups      string
+}
+
+// objectDefaults is ObjectDefaults resolved against the store.
+type objectDefaults struct {
+	checkPeriod, notificationPeriod *objects.Timeperiod
+	contacts                        []*objects.Contact
+	contactGroups                   []*objects.ContactGroup
+}
+
+func (d ObjectDefaults) applyToHosts(store *objects.ObjectStore) error {
+	r, err := resolveDefaults(store, "host", d.HostCheckPeriod, d.HostNotificationPeriod, d.HostContacts, d.HostContactGroups)
+	if err != nil {
+		return err
+	}
+	for _, h := range store.Hosts {
+		if h.CheckPeriod == nil {
+			h.CheckPeriod = r.checkPeriod
+		}
+		if h.NotificationPeriod == nil {
+			h.NotificationPeriod = r.notificationPeriod
+		}
+		if len(h.Contacts) == 0 && len(h.ContactGroups) == 0 {
+			h.Contacts, h.ContactGroups = r.contacts, r.contactGroups
+		}
+	}
+	return nil
+}
+
+// applyToServices runs after services have inherited from their hosts, so
+// a host's contacts and notification period, defaults included, come
+// before the service defaults.
+func (d ObjectDefaults) applyToServices(store *objects.ObjectStore) error {
+	r, err := resolveDefaults(store, "service", d.ServiceCheckPeriod, d.ServiceNotificationPeriod, d.ServiceContacts, d.ServiceContactGroups)
+	if err != nil {
+		return err
+	}
+	for _, svc := range store.Services {
+		if svc.CheckPeriod == nil {
+			svc.CheckPeriod = r.checkPeriod
+		}
+		if svc.NotificationPeriod == nil {
+			svc.NotificationPeriod = r.notificationPeriod
+		}
+		if len(svc.Contacts) == 0 && len(svc.ContactGroups) == 0 {
+			svc.Contacts, svc.ContactGroups = r.contacts, r.contactGroups
+		}
+	}
+	return nil
+}
+
+func resolveDefaults(store *objects.ObjectStore, kind, checkPeriod, notificationPeriod, contacts, contactGroups string) (objectDefaults, error) {
+	var r objectDefaults
+	if checkPeriod != "" {
+		if r.checkPeriod = store.GetTimeperiod(checkPeriod); r.checkPeriod == nil {
+			return r, fmt.Errorf("%s_check_period_default: timeperiod '%s' not found", kind, checkPeriod)
+		}
+	}
+	if notificationPeriod != "" {
+		if r.notificationPeriod = store.GetTimeperiod(notificationPeriod); r.notificationPeriod == nil {
+			return r, fmt.Errorf("%s_notification_period_default: timeperiod '%s' not found", kind, notificationPeriod)
+		}
+	}
+	for _, name := range splitCSV(contacts) {
+		c := store.GetContact(name)
+		if c == nil {
+			return r, fmt.Errorf("%s_contacts_default: contact '%s' not found", kind, name)
+		}
+		r.contacts = append(r.contacts, c)
+	}
+	for _, name := range splitCSV(contactGroups) {
+		cg := store.GetContactGroup(name)
+		if cg == nil {
+			return r, fmt.Errorf("%s_contact_groups_default: contactgroup '%s' not found", kind, name)
+		}
+		r.contactGroups = append(r.contactGroups, cg)
+	}
+	return r, nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+const defaultsCfg = `define timeperiod {
+    timeperiod_name         24x7
+    monday                  00:00-24:00
+}
+
+define timeperiod {
+    timeperiod_name         workhours
+    monday                  09:00-17:00
+}
+
+define contact {
+    contact_name            admin
+}
+
+define contact {
+    contact_name            dba
+}
+
+define contactgroup {
+    contactgroup_name       ops
+    members                 admin
+}
+
+define host {
+    host_name               web01
+    max_check_attempts      1
+}
+
+define host {
+    host_name               db01
+    max_check_attempts      1
+    check_period            workhours
+    contacts                dba
+}
+
+define service {
+    host_name               web01
+    service_description     HTTP
+    check_command           check_dummy!0!OK
+    max_check_attempts      1
+}
+
+define service {
+    host_name               db01
+    service_description     PgSQL
+    check_command           check_dummy!0!OK
+    max_check_attempts      1
+}
+`
+
+func parseDefaultsCfg(t *testing.T) *ObjectParser {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "defaults.cfg")
+	if err := os.WriteFile(path, []byte(defaultsCfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveTemplates(parser); err != nil {
+		t.Fatal(err)
+	}
+	return parser
+}
+
+func TestObjectDefaults(t *testing.T) {
+	store := objects.NewObjectStore()
+	err := ExpandAndRegisterWithDefaults(parseDefaultsCfg(t), store, "", ObjectDefaults{
+		HostCheckPeriod:           "24x7",
+		HostContactGroups:         "ops",
+		ServiceNotificationPeriod: "workhours",
+		ServiceContacts:           "dba",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	web, db := store.GetHost("web01"), store.GetHost("db01")
+	if web.CheckPeriod == nil || web.CheckPeriod.Name != "24x7" {
+		t.Errorf("web01: expected the default check_period, got %v", web.CheckPeriod)
+	}
+	if db.CheckPeriod.Name != "workhours" {
+		t.Errorf("db01: the defined check_period should win, got %s", db.CheckPeriod.Name)
+	}
+	if len(web.ContactGroups) != 1 || web.ContactGroups[0].Name != "ops" || len(web.Contacts) != 0 {
+		t.Errorf("web01: expected the default contact group only, got %v %v", web.ContactGroups, web.Contacts)
+	}
+	if len(db.Contacts) != 1 || len(db.ContactGroups) != 0 {
+		t.Errorf("db01: defined contacts should win over the default contact groups")
+	}
+
+	// Services inherit contacts from their host before the service
+	// defaults apply.
+	http := store.GetService("web01", "HTTP")
+	if len(http.ContactGroups) != 1 || http.ContactGroups[0].Name != "ops" || len(http.Contacts) != 0 {
+		t.Errorf("HTTP: expected the host's contact group, got %v %v", http.ContactGroups, http.Contacts)
+	}
+	if http.NotificationPeriod == nil || http.NotificationPeriod.Name != "workhours" {
+		t.Errorf("HTTP: expected the default notification_period, got %v", http.NotificationPeriod)
+	}
+	if http.CheckPeriod != nil {
+		t.Errorf("HTTP: no service check_period default was set, got %v", http.CheckPeriod)
+	}
+}
+
+func TestObjectDefaultsUnknownName(t *testing.T) {
+	err := ExpandAndRegisterWithDefaults(parseDefaultsCfg(t), objects.NewObjectStore(), "", ObjectDefaults{ServiceContacts: "admin,nobody"})
+	if err == nil || !strings.Contains(err.Error(), "service_contacts_default: contact 'nobody' not found") {
+		t.Errorf("expected an unknown contact error, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// NewTemplateObject returns an empty definition of type typ, for building
+// configuration in code. Fill it in with Set and add it to a parser with
+// Define.
+func NewTemplateObject(typ string) *TemplateObject {
+	return &TemplateObject{
+		Type:       typ,
+		Attrs:      make(map[string]string),
+		CustomVars: make(map[string]string),
+	}
+}
+
+// Set sets an attribute, or a custom variable when key starts with _. As in
+// a file, aliases are stored under their canonical name and custom variable
+// names are upper-cased.
+func (t *TemplateObject) Set(key, val string) {
+	if strings.HasPrefix(key, "_") {
+		t.CustomVars[strings.ToUpper(key[1:])] = val
+		return
+	}
+	t.Attrs[normalizeAlias(t.Type, key)] = val
+}
+
+// Delete removes an attribute or custom variable.
+func (t *TemplateObject) Delete(key string) {
+	if strings.HasPrefix(key, "_") {
+		delete(t.CustomVars, strings.ToUpper(key[1:]))
+		return
+	}
+	delete(t.Attrs, normalizeAlias(t.Type, key))
+}
+
+// Define adds a definition built in code, as if it had been read from a
+// file. Unlike a file, it rejects unknown object types and attributes.
+// Templates resolve as usual, so Define must come before ResolveTemplates.
+func (p *ObjectParser) Define(obj *TemplateObject) error {
+	if bad := obj.unknown(); len(bad) > 0 {
+		return fmt.Errorf("define %s: %s", obj.Type, bad[0])
+	}
+	return p.addObject(obj)
+}
+
+// Remove drops a definition, reporting whether the parser had it.
+func (p *ObjectParser) Remove(obj *TemplateObject) bool {
+	for i, o := range p.Objects {
+		if o != obj {
+			continue
+		}
+		p.Objects = append(p.Objects[:i], p.Objects[i+1:]...)
+		if name := obj.Name(); name != "" && p.byTypeName[obj.Type+":"+name] == obj {
+			delete(p.byTypeName, obj.Type+":"+name)
+		}
+		return true
+	}
+	return false
+}
+
+// Files returns the files definitions came from, in the order they were
+// first read. Definitions added with Define and no File are under "".
+func (p *ObjectParser) Files() []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, obj := range p.Objects {
+		if !seen[obj.File] {
+			seen[obj.File] = true
+			files = append(files, obj.File)
+		}
+	}
+	return files
+}
+
+// Write writes the definitions from file, or all of them when file is "",
+// as canonical .cfg text. Inline ; comments are never kept; # comments are
+// written when comments is set and the parser kept them.
+//
+// Template resolution fills in inherited attributes in place, so write
+// before ResolveTemplates to get the definitions as written.
+func (p *ObjectParser) Write(w io.Writer, file string, comments bool) error {
+	first := true
+	for _, obj := range p.Objects {
+		if file != "" && obj.File != file {
+			continue
+		}
+		if !first {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		first = false
+		if _, err := io.WriteString(w, obj.Format(comments)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Format returns the definition as canonical .cfg text: use and name
+// first, then the type's attributes in schema order, then unknown
+// attributes and custom variables by name, and register last. Values are
+// aligned, and semicolons in them escaped.
+func (t *TemplateObject) Format(comments bool) string {
+	return t.format(comments, true)
+}
+
+// format is Format, leaving out use unless withUse is set.
+func (t *TemplateObject) format(comments, withUse bool) string {
+	keys := t.formatOrder()
+	if !withUse {
+		keys = slices.DeleteFunc(keys, func(k string) bool { return k == "use" })
+	}
+	width := 0
+	for _, k := range keys {
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+	var b strings.Builder
+	if comments {
+		for _, c := range t.Comments {
+			b.WriteString(c + "\n")
+		}
+	}
+	fmt.Fprintf(&b, "define %s {\n", t.Type)
+	for _, k := range keys {
+		if comments {
+			for _, c := range t.AttrComments[k] {
+				b.WriteString("    " + c + "\n")
+			}
+		}
+		val := t.Attrs[k]
+		if strings.HasPrefix(k, "_") {
+			val = t.CustomVars[k[1:]]
+		}
+		line := fmt.Sprintf("    %-*s  %s", width, k, escapeSemicolons(val))
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// formatOrder returns the attribute names of t, custom variables as
+// "_NAME", in the order Format writes them.
+func (t *TemplateObject) formatOrder() []string {
+	rank := map[string]int{"use": 0, "name": 1, "register": 1 << 20}
+	for i, a := range objectAttributes[t.Type] {
+		rank[a.Name] = 2 + i
+	}
+	if t.Type == "timeperiod" {
+		for i, d := range weekdays {
+			rank[d] = 1000 + i
+		}
+	}
+	keys := make([]string, 0, len(t.Attrs)+len(t.CustomVars))
+	for k := range t.Attrs {
+		keys = append(keys, k)
+	}
+	for k := range t.CustomVars {
+		keys = append(keys, "_"+k)
+	}
+	rankOf := func(k string) int {
+		if r, ok := rank[k]; ok {
+			return r
+		}
+		if strings.HasPrefix(k, "_") {
+			return 3000
+		}
+		return 2000
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := rankOf(keys[i]), rankOf(keys[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// escapeSemicolons escapes the semicolons of v that are not already, so
+// they are not read back as the start of a comment.
+func escapeSemicolons(v string) string {
+	if !strings.Contains(v, ";") {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			b.WriteByte(v[i])
+			i++
+		} else if v[i] == ';' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	src := `# Web servers
+define host {
+    # keep in sync with DNS
+    address        10.0.0.1
+    _owner         ops
+    host_name      web01
+    use            generic-host
+    obsess         0   ; alias of obsess_over_host
+    notes          a\;b
+}
+
+define timeperiod {
+    timeperiod_name  workhours
+    monday           09:00-17:00
+    2024-12-25       00:00-00:00
+    sunday           10:00-12:00
+}
+`
+	path := filepath.Join(t.TempDir(), "hosts.cfg")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewObjectParser()
+	p.KeepComments = true
+	if err := p.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := p.Write(&b, path, true); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Web servers
+define host {
+    use               generic-host
+    host_name         web01
+    # keep in sync with DNS
+    address           10.0.0.1
+    obsess_over_host  0
+    notes             a\;b
+    _OWNER            ops
+}
+
+define timeperiod {
+    timeperiod_name  workhours
+    sunday           10:00-12:00
+    monday           09:00-17:00
+    2024-12-25       00:00-00:00
+}
+`
+	if b.String() != want {
+		t.Errorf("Write =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	// Writing what was read back yields the same definitions.
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	again := NewObjectParser()
+	if err := again.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	for i, obj := range again.Objects {
+		if !reflect.DeepEqual(obj.Attrs, p.Objects[i].Attrs) || !reflect.DeepEqual(obj.CustomVars, p.Objects[i].CustomVars) {
+			t.Errorf("object %d changed: %v %v", i, obj.Attrs, obj.CustomVars)
+		}
+	}
+}
+
+func TestDefine(t *testing.T) {
+	p := NewObjectParser()
+	tmpl := NewTemplateObject("host")
+	tmpl.Set("name", "base")
+	tmpl.Set("register", "0")
+	tmpl.Set("max_check_attempts", "3")
+	if err := p.Define(tmpl); err != nil {
+		t.Fatal(err)
+	}
+	h := NewTemplateObject("host")
+	h.Set("use", "base")
+	h.Set("host_name", "web01")
+	h.Set("_rack", "b4")
+	if err := p.Define(h); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Format(false); got != "define host {\n    use        base\n    host_name  web01\n    _RACK      b4\n}\n" {
+		t.Errorf("Format = %q", got)
+	}
+
+	bad := NewTemplateObject("host")
+	bad.Set("host_nmae", "x")
+	if err := p.Define(bad); err == nil || err.Error() != "define host: unknown host attribute 'host_nmae' (did you mean 'host_name'?)" {
+		t.Errorf("Define with a typo: %v", err)
+	}
+	dup := NewTemplateObject("host")
+	dup.Set("name", "base")
+	if err := p.Define(dup); err == nil {
+		t.Error("Define accepted a duplicate template name")
+	}
+
+	if err := ResolveTemplates(p); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := h.Get("max_check_attempts"); v != "3" {
+		t.Errorf("template not applied, max_check_attempts = %q", v)
+	}
+	if !p.Remove(tmpl) || p.Remove(tmpl) || p.GetTemplate("host", "base") != nil {
+		t.Error("Remove did not drop the template")
+	}
+	if files := p.Files(); !reflect.DeepEqual(files, []string{""}) {
+		t.Errorf("Files = %v", files)
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Deprecated is a main config directive or object attribute that is
+// accepted but should not be used, for -v to warn about.
+type Deprecated struct {
+	File string
+	Line int // of the directive, or of the object definition
+	// Kind is "directive" or "<type> attribute".
+	Kind   string
+	Name   string
+	Reason string
+}
+
+func (d Deprecated) String() string {
+	return fmt.Sprintf("%s:%d: deprecated %s '%s': %s", d.File, d.Line, d.Kind, d.Name, d.Reason)
+}
+
+// deprecatedDirectives maps deprecated directive names to the reason.
+var deprecatedDirectives = func() map[string]string {
+	m := make(map[string]string)
+	for _, d := range mainDirectives {
+		if d.Deprecated != "" {
+			m[d.Name] = d.Deprecated
+		}
+	}
+	return m
+}()
+
+// deprecatedAttributes maps each object type to its deprecated attribute
+// names and the reason. Aliases are not deprecated here; Nagios accepts
+// both spellings.
+var deprecatedAttributes = func() map[string]map[string]string {
+	m := make(map[string]map[string]string)
+	for t, attrs := range objectAttributes {
+		for _, a := range append(append([]Attribute(nil), templateAttributes...), attrs...) {
+			if a.Deprecated == "" {
+				continue
+			}
+			if m[t] == nil {
+				m[t] = make(map[string]string)
+			}
+			m[t][a.Name] = a.Deprecated
+		}
+	}
+	return m
+}()
+
+// Deprecated returns the deprecated attributes of the parsed definitions,
+// in file order. Like Unknown, call it before ResolveTemplates.
+func (p *ObjectParser) Deprecated() []Deprecated {
+	var out []Deprecated
+	for _, obj := range p.Objects {
+		reasons := deprecatedAttributes[obj.Type]
+		var names []string
+		for key := range obj.Attrs {
+			if reasons[key] != "" {
+				names = append(names, key)
+			}
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			out = append(out, Deprecated{File: obj.File, Line: obj.Line, Kind: obj.Type + " attribute", Name: key, Reason: reasons[key]})
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeprecatedNames(t *testing.T) {
+	dir := t.TempDir()
+	objs := filepath.Join(dir, "objects.cfg")
+	if err := os.WriteFile(objs, []byte(`define host {
+    host_name       web01
+    obsess          1
+}
+define servicedependency {
+    host_name                   web01
+    service_description         HTTP
+    dependent_host_name         web01
+    dependent_service_description Disk
+    servicegroup_name           web
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "nagios.cfg")
+	if err := os.WriteFile(main, []byte("cfg_file=objects.cfg\ncheck_for_updates=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mainCfg, err := ReadMainConfig(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(objs); err != nil {
+		t.Fatal(err)
+	}
+	// The obsess alias is not deprecated.
+	got := append(mainCfg.Deprecated, parser.Deprecated()...)
+	want := []string{
+		main + ":2: deprecated directive 'check_for_updates': ignored: Gogios does not check for updates",
+		objs + ":5: deprecated servicedependency attribute 'servicegroup_name': ignored: servicegroup dependencies are not supported",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %d deprecated names", got, len(want))
+	}
+	for i, d := range got {
+		if d.String() != want[i] {
+			t.Errorf("got %q, want %q", d, want[i])
+		}
+	}
+}
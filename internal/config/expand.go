@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/oceanplexian/gogios/internal/objects"
 )
@@ -70,6 +72,12 @@ func staticServiceKeys(parser *ObjectParser, genCfgFile string) map[string]bool
 // instead of letting them erode across restarts, and an orphaned service in it is
 // skipped rather than aborting the whole load.
 func ExpandAndRegister(parser *ObjectParser, store *objects.ObjectStore, genCfgFile string) error {
+	return ExpandAndRegisterWithDefaults(parser, store, genCfgFile, ObjectDefaults{})
+}
+
+// ExpandAndRegisterWithDefaults is ExpandAndRegister, also giving hosts and
+// services the attributes in defaults that they omit.
+func ExpandAndRegisterWithDefaults(parser *ObjectParser, store *objects.ObjectStore, genCfgFile string, defaults ObjectDefaults) error {
 	// Step 1: Register commands first (needed by everything else)
 	if err := registerCommands(parser, store); err != nil {
 		return err
@@ -90,6 +98,9 @@ func ExpandAndRegister(parser *ObjectParser, store *objects.ObjectStore, genCfgF
 	if err := registerHosts(parser, store, genCfgFile); err != nil {
 		return err
 	}
+	if err := defaults.applyToHosts(store); err != nil {
+		return err
+	}
 	// Step 6: Register host groups (recombobulate)
 	if err := registerHostGroups(parser, store); err != nil {
 		return err
@@ -102,8 +113,12 @@ func ExpandAndRegister(parser *ObjectParser, store *objects.ObjectStore, genCfgF
 	if err := registerServiceGroups(parser, store); err != nil {
 		return err
 	}
-	// Step 9: Inter-object inheritance (service ← host)
-	inheritObjectProperties(store)
+	// Step 9: Inter-object inheritance (service ← host), then the service
+	// defaults for what is still unset
+	inheritObjectProperties(parser, store)
+	if err := defaults.applyToServices(store); err != nil {
+		return err
+	}
 	// Step 10: Register host dependencies (with expansion)
 	if err := registerHostDependencies(parser, store); err != nil {
 		return err
@@ -121,11 +136,19 @@ func ExpandAndRegister(parser *ObjectParser, store *objects.ObjectStore, genCfgF
 		return err
 	}
 	// Step 14: Resolve host parent/child relationships
-	if err := resolveHostParents(store); err != nil {
+	if err := resolveHostParents(parser, store, genCfgFile); err != nil {
 		return err
 	}
 	// Step 15: Wire up host/service group bidirectional refs
 	wireGroupReferences(store)
+	// Step 16: Register blackout windows (Gogios extension)
+	if err := registerBlackouts(parser, store); err != nil {
+		return err
+	}
+	// Step 17: Register inhibition rules (Gogios extension)
+	if err := registerInhibitRules(parser, store); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -140,7 +163,8 @@ func registerCommands(parser *ObjectParser, store *objects.ObjectStore) error {
 		if name == "" {
 			return fmt.Errorf("%s:%d: command missing command_name", obj.File, obj.Line)
 		}
-		cmd := &objects.Command{Name: name, CommandLine: line}
+		class, _ := obj.Get("concurrency_class")
+		cmd := &objects.Command{Name: name, CommandLine: line, ConfigSource: configSource(obj), ConcurrencyClass: class}
 		if err := store.AddCommand(cmd); err != nil {
 			return fmt.Errorf("%s:%d: %w", obj.File, obj.Line, err)
 		}
@@ -158,8 +182,9 @@ func registerTimeperiods(parser *ObjectParser, store *objects.ObjectStore) error
 			return fmt.Errorf("%s:%d: timeperiod missing timeperiod_name", obj.File, obj.Line)
 		}
 		tp := &objects.Timeperiod{
-			Name:  name,
-			Alias: attrOr(obj, "alias", name),
+			Name:         name,
+			Alias:        attrOr(obj, "alias", name),
+			ConfigSource: configSource(obj),
 		}
 		// Weekday ranges
 		days := [7]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
@@ -168,8 +193,7 @@ func registerTimeperiods(parser *ObjectParser, store *objects.ObjectStore) error
 				tp.Ranges[i] = v
 			}
 		}
-		// Date exceptions are handled by timeperiod.go parsing later
-		// Parse any date exception lines from remaining attrs
+		// Date exception lines are the remaining attrs
 		for key, val := range obj.Attrs {
 			exc := parseTimeDateException(key, val)
 			if exc != nil {
@@ -197,6 +221,9 @@ func registerTimeperiods(parser *ObjectParser, store *objects.ObjectStore) error
 			}
 		}
 	}
+	for _, tp := range store.Timeperiods {
+		CompileTimeperiod(tp)
+	}
 	return nil
 }
 
@@ -212,6 +239,7 @@ func registerContacts(parser *ObjectParser, store *objects.ObjectStore) error {
 		c := &objects.Contact{
 			Name:                       name,
 			Alias:                      attrOr(obj, "alias", name),
+			ConfigSource:               configSource(obj),
 			Email:                      attrOr(obj, "email", ""),
 			Pager:                      attrOr(obj, "pager", ""),
 			HostNotificationsEnabled:   attrBool(obj, "host_notifications_enabled", true),
@@ -232,6 +260,7 @@ func registerContacts(parser *ObjectParser, store *objects.ObjectStore) error {
 			n, _ := strconv.ParseUint(v, 10, 64)
 			c.MinimumImportance = uint(n)
 		}
+		c.NotificationDigest = time.Duration(attrInt(obj, "notification_digest", 0)) * time.Second
 		c.HostNotificationOptions = parseHostNotificationOptions(attrOr(obj, "host_notification_options", ""))
 		c.ServiceNotificationOptions = parseServiceNotificationOptions(attrOr(obj, "service_notification_options", ""))
 
@@ -258,10 +287,58 @@ func registerContacts(parser *ObjectParser, store *objects.ObjectStore) error {
 		if v, ok := obj.Get("service_notification_commands"); ok {
 			c.ServiceNotificationCommands = resolveCommands(store, v)
 		}
+		// Gogios extension: fallback chains run after *_notification_commands
+		// when every command of the previous step failed.
+		if v, ok := obj.Get("host_notification_fallback"); ok {
+			steps, err := parseNotificationFallback(store, v)
+			if err != nil {
+				return fmt.Errorf("%s:%d: contact '%s': host_notification_fallback: %w", obj.File, obj.Line, name, err)
+			}
+			c.HostNotificationFallback = steps
+		}
+		if v, ok := obj.Get("service_notification_fallback"); ok {
+			steps, err := parseNotificationFallback(store, v)
+			if err != nil {
+				return fmt.Errorf("%s:%d: contact '%s': service_notification_fallback: %w", obj.File, obj.Line, name, err)
+			}
+			c.ServiceNotificationFallback = steps
+		}
 	}
 	return nil
 }
 
+// parseNotificationFallback parses "cmd1,cmd2@30 | cmd3": steps separated by
+// '|', each a command list with an optional per-step timeout in seconds.
+func parseNotificationFallback(store *objects.ObjectStore, v string) ([]objects.NotificationStep, error) {
+	var steps []objects.NotificationStep
+	for _, part := range strings.Split(v, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var step objects.NotificationStep
+		if cmds, timeout, ok := strings.Cut(part, "@"); ok {
+			secs, err := strconv.ParseFloat(strings.TrimSpace(timeout), 64)
+			if err != nil || secs <= 0 {
+				return nil, fmt.Errorf("invalid timeout '%s'", timeout)
+			}
+			step.Timeout = time.Duration(secs * float64(time.Second))
+			part = cmds
+		}
+		for _, name := range splitCSV(part) {
+			cmd := store.GetCommand(name)
+			if cmd == nil {
+				return nil, fmt.Errorf("command '%s' not found", name)
+			}
+			step.Commands = append(step.Commands, cmd)
+		}
+		if len(step.Commands) > 0 {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
 func registerContactGroups(parser *ObjectParser, store *objects.ObjectStore) error {
 	// First pass: create all contactgroups
 	for _, obj := range parser.Objects {
@@ -273,8 +350,9 @@ func registerContactGroups(parser *ObjectParser, store *objects.ObjectStore) err
 			return fmt.Errorf("%s:%d: contactgroup missing contactgroup_name", obj.File, obj.Line)
 		}
 		cg := &objects.ContactGroup{
-			Name:  name,
-			Alias: attrOr(obj, "alias", name),
+			Name:         name,
+			Alias:        attrOr(obj, "alias", name),
+			ConfigSource: configSource(obj),
 		}
 		if err := store.AddContactGroup(cg); err != nil {
 			return fmt.Errorf("%s:%d: %w", obj.File, obj.Line, err)
@@ -330,6 +408,32 @@ func registerContactGroups(parser *ObjectParser, store *objects.ObjectStore) err
 			}
 		}
 	}
+	// A contactgroup's notification_digest applies to members without their
+	// own; a contact in several such groups gets the longest window.
+	ownDigest := make(map[string]bool)
+	for _, obj := range parser.Objects {
+		if obj.Type == "contact" && obj.Register() {
+			if _, ok := obj.Get("notification_digest"); ok {
+				name, _ := obj.Get("contact_name")
+				ownDigest[name] = true
+			}
+		}
+	}
+	for _, obj := range parser.Objects {
+		if obj.Type != "contactgroup" || !obj.Register() {
+			continue
+		}
+		window := time.Duration(attrInt(obj, "notification_digest", 0)) * time.Second
+		if window <= 0 {
+			continue
+		}
+		name, _ := obj.Get("contactgroup_name")
+		for _, c := range store.GetContactGroup(name).Members {
+			if !ownDigest[c.Name] && window > c.NotificationDigest {
+				c.NotificationDigest = window
+			}
+		}
+	}
 	return nil
 }
 
@@ -354,6 +458,7 @@ func registerHosts(parser *ObjectParser, store *objects.ObjectStore, genCfgFile
 			Name:                       name,
 			DisplayName:                attrOr(obj, "display_name", name),
 			Alias:                      attrOr(obj, "alias", name),
+			ConfigSource:               configSource(obj),
 			Address:                    attrOr(obj, "address", name),
 			CheckInterval:              attrFloat(obj, "check_interval", 5.0),
 			RetryInterval:              attrFloat(obj, "retry_interval", 1.0),
@@ -384,6 +489,7 @@ func registerHosts(parser *ObjectParser, store *objects.ObjectStore, genCfgFile
 			RetainStatusInformation:    attrBool(obj, "retain_status_information", true),
 			RetainNonstatusInformation: attrBool(obj, "retain_nonstatus_information", true),
 			CustomVars:                 copyMap(obj.CustomVars),
+			Tags:                       objectTags(obj),
 			ShouldBeScheduled:          true,
 		}
 		if v, ok := obj.Get("hourly_value"); ok {
@@ -427,7 +533,9 @@ func registerHosts(parser *ObjectParser, store *objects.ObjectStore, genCfgFile
 			h.NotificationPeriod = store.GetTimeperiod(v)
 		}
 		if v, ok := obj.Get("event_handler"); ok {
-			h.EventHandler = store.GetCommand(v)
+			cmdName, args := splitCommandArgs(v)
+			h.EventHandler = store.GetCommand(cmdName)
+			h.EventHandlerArgs = args
 		}
 		h.ContactGroups = resolveContactGroups(store, attrOr(obj, "contact_groups", ""))
 		h.Contacts = resolveContacts(store, attrOr(obj, "contacts", ""))
@@ -458,11 +566,12 @@ func registerHostGroups(parser *ObjectParser, store *objects.ObjectStore) error
 			return fmt.Errorf("%s:%d: hostgroup missing hostgroup_name", obj.File, obj.Line)
 		}
 		hg := &objects.HostGroup{
-			Name:      name,
-			Alias:     attrOr(obj, "alias", name),
-			Notes:     attrOr(obj, "notes", ""),
-			NotesURL:  attrOr(obj, "notes_url", ""),
-			ActionURL: attrOr(obj, "action_url", ""),
+			Name:         name,
+			Alias:        attrOr(obj, "alias", name),
+			ConfigSource: configSource(obj),
+			Notes:        attrOr(obj, "notes", ""),
+			NotesURL:     attrOr(obj, "notes_url", ""),
+			ActionURL:    attrOr(obj, "action_url", ""),
 		}
 		if v, ok := obj.Get("members"); ok {
 			for _, hName := range splitCSV(v) {
@@ -575,6 +684,7 @@ func registerServices(parser *ObjectParser, store *objects.ObjectStore, genCfgFi
 				Host:                       h,
 				Description:                desc,
 				DisplayName:                attrOr(obj, "display_name", desc),
+				ConfigSource:               configSource(obj),
 				CheckInterval:              attrFloat(obj, "check_interval", 5.0),
 				RetryInterval:              attrFloat(obj, "retry_interval", 1.0),
 				MaxCheckAttempts:           attrInt(obj, "max_check_attempts", -2),
@@ -604,6 +714,7 @@ func registerServices(parser *ObjectParser, store *objects.ObjectStore, genCfgFi
 				RetainNonstatusInformation: attrBool(obj, "retain_nonstatus_information", true),
 				ParallelizeCheck:           attrBool(obj, "parallelize_check", true),
 				CustomVars:                 copyMap(obj.CustomVars),
+				Tags:                       objectTags(obj),
 				ShouldBeScheduled:          true,
 			}
 			if v, ok := obj.Get("hourly_value"); ok {
@@ -628,7 +739,9 @@ func registerServices(parser *ObjectParser, store *objects.ObjectStore, genCfgFi
 				svc.NotificationPeriod = store.GetTimeperiod(v)
 			}
 			if v, ok := obj.Get("event_handler"); ok {
-				svc.EventHandler = store.GetCommand(v)
+				cmdName, args := splitCommandArgs(v)
+				svc.EventHandler = store.GetCommand(cmdName)
+				svc.EventHandlerArgs = args
 			}
 			svc.ContactGroups = resolveContactGroups(store, attrOr(obj, "contact_groups", ""))
 			svc.Contacts = resolveContacts(store, attrOr(obj, "contacts", ""))
@@ -658,11 +771,12 @@ func registerServiceGroups(parser *ObjectParser, store *objects.ObjectStore) err
 			return fmt.Errorf("%s:%d: servicegroup missing servicegroup_name", obj.File, obj.Line)
 		}
 		sg := &objects.ServiceGroup{
-			Name:      name,
-			Alias:     attrOr(obj, "alias", name),
-			Notes:     attrOr(obj, "notes", ""),
-			NotesURL:  attrOr(obj, "notes_url", ""),
-			ActionURL: attrOr(obj, "action_url", ""),
+			Name:         name,
+			Alias:        attrOr(obj, "alias", name),
+			ConfigSource: configSource(obj),
+			Notes:        attrOr(obj, "notes", ""),
+			NotesURL:     attrOr(obj, "notes_url", ""),
+			ActionURL:    attrOr(obj, "action_url", ""),
 		}
 		if v, ok := obj.Get("members"); ok {
 			members := splitCSV(v)
@@ -730,14 +844,24 @@ func registerServiceGroups(parser *ObjectParser, store *objects.ObjectStore) err
 
 // inheritObjectProperties applies inter-object inheritance:
 // services inherit contacts, notification_interval, notification_period from their host.
-func inheritObjectProperties(store *objects.ObjectStore) {
+// A service that sets contacts, contact_groups or notification_period to
+// null keeps them unset, as in Nagios.
+func inheritObjectProperties(parser *ObjectParser, store *objects.ObjectStore) {
+	nulled := make(map[string]*TemplateObject)
+	for _, obj := range parser.Objects {
+		if obj.Type == "service" && (obj.IsNull("contacts") || obj.IsNull("contact_groups") || obj.IsNull("notification_period")) {
+			nulled[configSource(obj)] = obj
+		}
+	}
 	for _, svc := range store.Services {
 		if svc.Host == nil {
 			continue
 		}
 		h := svc.Host
+		obj := nulled[svc.ConfigSource]
 		// Contacts: inherit from host only if service has neither contacts nor contact_groups
-		if len(svc.ContactGroups) == 0 && len(svc.Contacts) == 0 {
+		if len(svc.ContactGroups) == 0 && len(svc.Contacts) == 0 &&
+			(obj == nil || !obj.IsNull("contacts") && !obj.IsNull("contact_groups")) {
 			svc.ContactGroups = h.ContactGroups
 			svc.Contacts = h.Contacts
 		}
@@ -746,7 +870,8 @@ func inheritObjectProperties(store *objects.ObjectStore) {
 			// Only inherit if service still has default
 		}
 		// Notification period: inherit if not set
-		if svc.NotificationPeriod == nil && h.NotificationPeriod != nil {
+		if svc.NotificationPeriod == nil && h.NotificationPeriod != nil &&
+			(obj == nil || !obj.IsNull("notification_period")) {
 			svc.NotificationPeriod = h.NotificationPeriod
 		}
 	}
@@ -839,31 +964,41 @@ func registerHostEscalations(parser *ObjectParser, store *objects.ObjectStore) e
 		if obj.Type != "hostescalation" || !obj.Register() {
 			continue
 		}
-		hosts := resolveHostList(store, attrOr(obj, "host_name", ""), attrOr(obj, "hostgroup_name", ""))
-		cgs := resolveContactGroups(store, attrOr(obj, "contact_groups", ""))
-		cts := resolveContacts(store, attrOr(obj, "contacts", ""))
-		firstNotif := attrInt(obj, "first_notification", -2)
-		lastNotif := attrInt(obj, "last_notification", -2)
-		notifInterval := attrFloat(obj, "notification_interval", -1)
-		escOpts := parseHostEscalationOptions(attrOr(obj, "escalation_options", ""))
-
-		var escPeriod *objects.Timeperiod
+		// Gogios extension: with dynamic_groups 1, hostgroup_name binds the
+		// escalation to the group itself instead of its members at load time.
+		dynamic := attrInt(obj, "dynamic_groups", 0) == 1
+		hgNames := attrOr(obj, "hostgroup_name", "")
+		var hosts []*objects.Host
+		if dynamic {
+			hosts = resolveHostList(store, attrOr(obj, "host_name", ""), "")
+		} else {
+			hosts = resolveHostList(store, attrOr(obj, "host_name", ""), hgNames)
+		}
+		base := objects.HostEscalation{
+			ContactGroups:        resolveContactGroups(store, attrOr(obj, "contact_groups", "")),
+			Contacts:             resolveContacts(store, attrOr(obj, "contacts", "")),
+			FirstNotification:    attrInt(obj, "first_notification", -2),
+			LastNotification:     attrInt(obj, "last_notification", -2),
+			NotificationInterval: attrFloat(obj, "notification_interval", -1),
+			EscalationOptions:    parseHostEscalationOptions(attrOr(obj, "escalation_options", "")),
+		}
 		if v, ok := obj.Get("escalation_period"); ok {
-			escPeriod = store.GetTimeperiod(v)
+			base.EscalationPeriod = store.GetTimeperiod(v)
 		}
 
 		for _, h := range hosts {
-			he := &objects.HostEscalation{
-				Host:                 h,
-				ContactGroups:        cgs,
-				Contacts:             cts,
-				FirstNotification:    firstNotif,
-				LastNotification:     lastNotif,
-				NotificationInterval: notifInterval,
-				EscalationPeriod:     escPeriod,
-				EscalationOptions:    escOpts,
+			he := base
+			he.Host = h
+			store.AddHostEscalation(&he)
+		}
+		if dynamic {
+			for _, name := range splitCSV(hgNames) {
+				if hg := store.GetHostGroup(name); hg != nil {
+					he := base
+					he.HostGroup = hg
+					store.AddHostEscalation(&he)
+				}
 			}
-			store.AddHostEscalation(he)
 		}
 	}
 	return nil
@@ -874,47 +1009,238 @@ func registerServiceEscalations(parser *ObjectParser, store *objects.ObjectStore
 		if obj.Type != "serviceescalation" || !obj.Register() {
 			continue
 		}
-		hosts := resolveHostList(store, attrOr(obj, "host_name", ""), attrOr(obj, "hostgroup_name", ""))
+		dynamic := attrInt(obj, "dynamic_groups", 0) == 1
+		hgNames := attrOr(obj, "hostgroup_name", "")
+		sgNames := attrOr(obj, "servicegroup_name", "")
 		desc := attrOr(obj, "service_description", "")
-		cgs := resolveContactGroups(store, attrOr(obj, "contact_groups", ""))
-		cts := resolveContacts(store, attrOr(obj, "contacts", ""))
-		firstNotif := attrInt(obj, "first_notification", -2)
-		lastNotif := attrInt(obj, "last_notification", -2)
-		notifInterval := attrFloat(obj, "notification_interval", -1)
-		escOpts := parseServiceEscalationOptions(attrOr(obj, "escalation_options", ""))
-
-		var escPeriod *objects.Timeperiod
+		base := objects.ServiceEscalation{
+			ContactGroups:        resolveContactGroups(store, attrOr(obj, "contact_groups", "")),
+			Contacts:             resolveContacts(store, attrOr(obj, "contacts", "")),
+			FirstNotification:    attrInt(obj, "first_notification", -2),
+			LastNotification:     attrInt(obj, "last_notification", -2),
+			NotificationInterval: attrFloat(obj, "notification_interval", -1),
+			EscalationOptions:    parseServiceEscalationOptions(attrOr(obj, "escalation_options", "")),
+		}
 		if v, ok := obj.Get("escalation_period"); ok {
-			escPeriod = store.GetTimeperiod(v)
+			base.EscalationPeriod = store.GetTimeperiod(v)
 		}
 
-		for _, h := range hosts {
-			svc := store.GetService(h.Name, desc)
-			if svc == nil {
-				continue
+		// Expand host_name/hostgroup_name + service_description and the
+		// members of servicegroup_name into one escalation per service.
+		var services []*objects.Service
+		seen := make(map[*objects.Service]bool)
+		addService := func(svc *objects.Service) {
+			if svc != nil && !seen[svc] {
+				seen[svc] = true
+				services = append(services, svc)
+			}
+		}
+		hostGroups := hgNames
+		if dynamic {
+			hostGroups = ""
+		}
+		for _, h := range resolveHostList(store, attrOr(obj, "host_name", ""), hostGroups) {
+			addService(store.GetService(h.Name, desc))
+		}
+		if !dynamic {
+			for _, name := range splitCSV(sgNames) {
+				if sg := store.GetServiceGroup(name); sg != nil {
+					for _, svc := range sg.Members {
+						addService(svc)
+					}
+				}
+			}
+		}
+
+		for _, svc := range services {
+			se := base
+			se.Host = svc.Host
+			se.Service = svc
+			store.AddServiceEscalation(&se)
+		}
+		if dynamic {
+			for _, name := range splitCSV(hgNames) {
+				if hg := store.GetHostGroup(name); hg != nil && desc != "" {
+					se := base
+					se.HostGroup = hg
+					se.ServiceDescription = desc
+					store.AddServiceEscalation(&se)
+				}
 			}
-			se := &objects.ServiceEscalation{
-				Host:                 h,
-				Service:              svc,
-				ContactGroups:        cgs,
-				Contacts:             cts,
-				FirstNotification:    firstNotif,
-				LastNotification:     lastNotif,
-				NotificationInterval: notifInterval,
-				EscalationPeriod:     escPeriod,
-				EscalationOptions:    escOpts,
+			for _, name := range splitCSV(sgNames) {
+				if sg := store.GetServiceGroup(name); sg != nil {
+					se := base
+					se.ServiceGroup = sg
+					store.AddServiceEscalation(&se)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func registerBlackouts(parser *ObjectParser, store *objects.ObjectStore) error {
+	seen := make(map[string]bool)
+	for _, obj := range parser.Objects {
+		if obj.Type != "blackout" || !obj.Register() {
+			continue
+		}
+		name, _ := obj.Get("blackout_name")
+		if name == "" {
+			return fmt.Errorf("%s:%d: blackout missing blackout_name", obj.File, obj.Line)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s:%d: duplicate blackout '%s'", obj.File, obj.Line, name)
+		}
+		seen[name] = true
+		b := &objects.Blackout{
+			Name:                name,
+			HostNames:           splitCSV(attrOr(obj, "host_name", "")),
+			HostGroups:          splitCSV(attrOr(obj, "hostgroup_name", "")),
+			ServiceDescriptions: splitCSV(attrOr(obj, "service_description", "")),
+			ServiceGroups:       splitCSV(attrOr(obj, "servicegroup_name", "")),
+			Comment:             attrOr(obj, "comment", ""),
+		}
+		for _, f := range []struct {
+			key string
+			dst *time.Time
+		}{{"start_time", &b.StartTime}, {"end_time", &b.EndTime}} {
+			if v, ok := obj.Get(f.key); ok {
+				t, err := ParseBlackoutTime(v)
+				if err != nil {
+					return fmt.Errorf("%s:%d: blackout '%s': %s: %w", obj.File, obj.Line, name, f.key, err)
+				}
+				*f.dst = t
+			}
+		}
+		if !b.StartTime.IsZero() && !b.EndTime.IsZero() && !b.EndTime.After(b.StartTime) {
+			return fmt.Errorf("%s:%d: blackout '%s': end_time is not after start_time", obj.File, obj.Line, name)
+		}
+		if v, ok := obj.Get("blackout_period"); ok {
+			if b.Period = store.GetTimeperiod(v); b.Period == nil {
+				return fmt.Errorf("%s:%d: blackout '%s': timeperiod '%s' not found", obj.File, obj.Line, name, v)
 			}
-			store.AddServiceEscalation(se)
 		}
+		for _, hg := range b.HostGroups {
+			if store.GetHostGroup(hg) == nil {
+				return fmt.Errorf("%s:%d: blackout '%s': hostgroup '%s' not found", obj.File, obj.Line, name, hg)
+			}
+		}
+		for _, sg := range b.ServiceGroups {
+			if store.GetServiceGroup(sg) == nil {
+				return fmt.Errorf("%s:%d: blackout '%s': servicegroup '%s' not found", obj.File, obj.Line, name, sg)
+			}
+		}
+		store.Blackouts = append(store.Blackouts, b)
+	}
+	return nil
+}
+
+func registerInhibitRules(parser *ObjectParser, store *objects.ObjectStore) error {
+	seen := make(map[string]bool)
+	for _, obj := range parser.Objects {
+		if obj.Type != "inhibit" || !obj.Register() {
+			continue
+		}
+		name, _ := obj.Get("inhibit_name")
+		if name == "" {
+			return fmt.Errorf("%s:%d: inhibit missing inhibit_name", obj.File, obj.Line)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s:%d: duplicate inhibit '%s'", obj.File, obj.Line, name)
+		}
+		seen[name] = true
+		r := &objects.InhibitRule{
+			Name:          name,
+			SourceService: attrOr(obj, "source_service_description", ""),
+			SourceStates:  parseInhibitSourceStates(attrOr(obj, "source_states", "c")),
+			HostNames:     splitCSV(attrOr(obj, "host_name", "")),
+			HostGroups:    splitCSV(attrOr(obj, "hostgroup_name", "")),
+			Comment:       attrOr(obj, "comment", ""),
+		}
+		if r.SourceService == "" {
+			return fmt.Errorf("%s:%d: inhibit '%s' missing source_service_description", obj.File, obj.Line, name)
+		}
+		if r.SourceStates == 0 {
+			return fmt.Errorf("%s:%d: inhibit '%s': source_states matches no problem state", obj.File, obj.Line, name)
+		}
+		target, ok := obj.Get("target_service_description")
+		if !ok || target == "" {
+			return fmt.Errorf("%s:%d: inhibit '%s' missing target_service_description", obj.File, obj.Line, name)
+		}
+		re, err := regexp.Compile("^(?:" + target + ")$")
+		if err != nil {
+			return fmt.Errorf("%s:%d: inhibit '%s': target_service_description: %w", obj.File, obj.Line, name, err)
+		}
+		r.Target = re
+		for _, hg := range r.HostGroups {
+			if store.GetHostGroup(hg) == nil {
+				return fmt.Errorf("%s:%d: inhibit '%s': hostgroup '%s' not found", obj.File, obj.Line, name, hg)
+			}
+		}
+		store.InhibitRules = append(store.InhibitRules, r)
 	}
 	return nil
 }
 
-func resolveHostParents(store *objects.ObjectStore) error {
-	// This is done during registration; we iterate again to resolve parent string refs.
-	// Parents were stored as host_name refs during parsing; now build the pointer graph.
-	// Note: this is already partially handled, but we need to wire parent strings
-	// that weren't set during registerHosts. We'll do a dedicated pass.
+// parseInhibitSourceStates parses source_states. Only problem states can
+// inhibit, so "a" means warning, unknown and critical.
+func parseInhibitSourceStates(s string) uint32 {
+	return parseOptions(s, map[string]uint32{
+		"w": objects.OptWarning, "warning": objects.OptWarning,
+		"u": objects.OptUnknown, "unknown": objects.OptUnknown,
+		"c": objects.OptCritical, "critical": objects.OptCritical,
+	}) & (objects.OptWarning | objects.OptUnknown | objects.OptCritical)
+}
+
+// ParseBlackoutTime parses a blackout start or end time: a Unix timestamp,
+// or "YYYY-MM-DD HH:MM[:SS]" in local time. "0" means unbounded.
+func ParseBlackoutTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if n == 0 {
+			return time.Time{}, nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s'", v)
+}
+
+// resolveHostParents builds Host.Parents and Host.Children from each host's
+// parents directive. It runs after every host is registered so parents can
+// be defined in any order; hosts registerHosts skipped are skipped here too.
+func resolveHostParents(parser *ObjectParser, store *objects.ObjectStore, genCfgFile string) error {
+	staticHosts := staticHostNames(parser, genCfgFile)
+	for _, obj := range parser.Objects {
+		if obj.Type != "host" || !obj.Register() {
+			continue
+		}
+		name, _ := obj.Get("host_name")
+		if fromGeneratedCfg(obj, genCfgFile) && staticHosts[name] {
+			continue
+		}
+		v, ok := obj.Get("parents")
+		if !ok {
+			continue
+		}
+		h := store.GetHost(name)
+		if h == nil {
+			continue
+		}
+		for _, parentName := range splitCSV(v) {
+			parent := store.GetHost(parentName)
+			if parent == nil {
+				return fmt.Errorf("%s:%d: host '%s' has unknown parent host '%s'", obj.File, obj.Line, name, parentName)
+			}
+			h.Parents = append(h.Parents, parent)
+			parent.Children = append(parent.Children, h)
+		}
+	}
 	return nil
 }
 
@@ -1014,6 +1340,12 @@ func splitCommandArgs(s string) (string, string) {
 	return s[:idx], s[idx+1:]
 }
 
+// configSource returns where obj was defined, as "file:line". Every
+// service a definition expands to shares the one string.
+func configSource(obj *TemplateObject) string {
+	return objects.Intern(obj.File + ":" + strconv.Itoa(obj.Line))
+}
+
 func attrOr(obj *TemplateObject, key, def string) string {
 	v, ok := obj.Get(key)
 	if !ok || v == "null" {
@@ -1022,6 +1354,13 @@ func attrOr(obj *TemplateObject, key, def string) string {
 	return v
 }
 
+// objectTags returns the tags of a host or service: those of its tags
+// attribute and of its _TAGS custom variable.
+func objectTags(obj *TemplateObject) []string {
+	tags, _ := obj.Get("tags")
+	return objects.ParseTags(tags + "," + obj.CustomVars["TAGS"])
+}
+
 func clearNull(s string) string {
 	if s == "null" {
 		return ""
@@ -1282,8 +1621,8 @@ func parseTimeDateException(key, val string) *objects.TimeDateException {
 		"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday":
 		return nil
 	}
-	// This is a simplified parser. Full date exception parsing is in timeperiod.go.
-	// For now, store the raw key+value as a string exception.
+	// The key holds the date, split from the time ranges by the parser;
+	// timeperiod.go parses the whole line.
 	return &objects.TimeDateException{
 		Timerange: key + " " + val,
 	}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestRegisterBlackouts(t *testing.T) {
+	cfg := `define host {
+    host_name               web01
+    max_check_attempts      1
+}
+
+define hostgroup {
+    hostgroup_name          web
+    members                 web01
+}
+
+define blackout {
+    blackout_name           release
+    hostgroup_name          web
+    service_description     HTTP*
+    start_time              2024-06-15 22:00
+    end_time                1718496000
+    comment                 quarterly release
+}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blackout.cfg")
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	store := objects.NewObjectStore()
+	if err := ExpandAndRegister(parser, store, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Blackouts) != 1 {
+		t.Fatalf("expected 1 blackout, got %d", len(store.Blackouts))
+	}
+	b := store.Blackouts[0]
+	want := time.Date(2024, 6, 15, 22, 0, 0, 0, time.Local)
+	if b.Name != "release" || !b.StartTime.Equal(want) || b.EndTime.Unix() != 1718496000 ||
+		len(b.HostGroups) != 1 || b.ServiceDescriptions[0] != "HTTP*" || b.Runtime {
+		t.Errorf("unexpected blackout %+v", b)
+	}
+
+	bad := strings.Replace(cfg, "hostgroup_name          web\n    service", "hostgroup_name          nope\n    service", 1)
+	os.WriteFile(path, []byte(bad), 0644)
+	parser = NewObjectParser()
+	parser.ParseFile(path)
+	if err := ExpandAndRegister(parser, objects.NewObjectStore(), ""); err == nil || !strings.Contains(err.Error(), "hostgroup 'nope' not found") {
+		t.Errorf("expected unknown hostgroup error, got %v", err)
+	}
+}
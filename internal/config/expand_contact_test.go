@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestParseNotificationFallback(t *testing.T) {
+	store := objects.NewObjectStore()
+	for _, name := range []string{"notify-slack", "notify-email", "notify-email-backup", "notify-sms"} {
+		store.AddCommand(&objects.Command{Name: name})
+	}
+
+	steps, err := parseNotificationFallback(store, "notify-email, notify-email-backup@30 | notify-sms@2.5 |")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(steps))
+	}
+	if len(steps[0].Commands) != 2 || steps[0].Commands[1].Name != "notify-email-backup" || steps[0].Timeout != 30*time.Second {
+		t.Errorf("unexpected first step %+v", steps[0])
+	}
+	if len(steps[1].Commands) != 1 || steps[1].Timeout != 2500*time.Millisecond {
+		t.Errorf("unexpected second step %+v", steps[1])
+	}
+
+	for _, bad := range []string{"notify-slack@0", "notify-slack@soon", "notify-fax"} {
+		if _, err := parseNotificationFallback(store, bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
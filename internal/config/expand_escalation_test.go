@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+const escalationCfg = `define contact {
+    contact_name            oncall
+}
+
+define host {
+    host_name               web01
+    max_check_attempts      1
+}
+
+define host {
+    host_name               web02
+    max_check_attempts      1
+}
+
+define hostgroup {
+    hostgroup_name          web
+    members                 web01
+}
+
+define service {
+    host_name               web01,web02
+    service_description     HTTP
+    check_command           check_dummy!0!OK
+    max_check_attempts      1
+}
+
+define service {
+    host_name               web01
+    service_description     Disk
+    check_command           check_dummy!0!OK
+    max_check_attempts      1
+}
+
+define servicegroup {
+    servicegroup_name       storage
+    members                 web01,Disk
+}
+
+define serviceescalation {
+    servicegroup_name       storage
+    host_name               web02
+    service_description     HTTP
+    first_notification      2
+    contacts                oncall
+}
+
+define hostescalation {
+    hostgroup_name          web
+    first_notification      3
+    contacts                oncall
+    dynamic_groups          1
+}
+
+define serviceescalation {
+    hostgroup_name          web
+    service_description     HTTP
+    first_notification      4
+    contacts                oncall
+    dynamic_groups          1
+}
+`
+
+func loadEscalationCfg(t *testing.T) *objects.ObjectStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "escalations.cfg")
+	if err := os.WriteFile(path, []byte(escalationCfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	store := objects.NewObjectStore()
+	if err := ExpandAndRegister(parser, store, ""); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestServiceEscalation_ServicegroupName(t *testing.T) {
+	store := loadEscalationCfg(t)
+
+	if esc := store.GetService("web01", "Disk").Escalations; len(esc) != 1 || esc[0].FirstNotification != 2 {
+		t.Errorf("expected servicegroup member to get the escalation, got %+v", esc)
+	}
+	if esc := store.GetService("web02", "HTTP").Escalations; len(esc) != 1 || esc[0].FirstNotification != 2 {
+		t.Errorf("expected host_name target to get the escalation, got %+v", esc)
+	}
+	if esc := store.GetService("web01", "HTTP").Escalations; len(esc) != 0 {
+		t.Errorf("expected no expanded escalation on web01;HTTP, got %+v", esc)
+	}
+}
+
+func TestEscalation_DynamicGroups(t *testing.T) {
+	store := loadEscalationCfg(t)
+	web := store.GetHostGroup("web")
+	if len(web.Escalations) != 1 || len(web.ServiceEscalations) != 1 {
+		t.Fatalf("expected escalations bound to the group, got %d host and %d service",
+			len(web.Escalations), len(web.ServiceEscalations))
+	}
+
+	web01, web02 := store.GetHost("web01"), store.GetHost("web02")
+	if len(web01.Escalations) != 0 || len(web01.AllEscalations()) != 1 {
+		t.Errorf("expected web01 to inherit the group escalation, got %d own and %d total",
+			len(web01.Escalations), len(web01.AllEscalations()))
+	}
+	if len(web02.AllEscalations()) != 0 {
+		t.Error("web02 is not a member and should have no host escalations")
+	}
+	if esc := store.GetService("web01", "HTTP").AllEscalations(); len(esc) != 1 || esc[0].FirstNotification != 4 {
+		t.Errorf("expected web01;HTTP to match the hostgroup service escalation, got %+v", esc)
+	}
+	if esc := store.GetService("web01", "Disk").AllEscalations(); len(esc) != 1 {
+		t.Errorf("expected only the servicegroup escalation on web01;Disk, got %d", len(esc))
+	}
+
+	// A host joining the group later picks the escalations up without
+	// re-expanding the config.
+	web.Members = append(web.Members, web02)
+	web02.HostGroups = append(web02.HostGroups, web)
+	if len(web02.AllEscalations()) != 1 {
+		t.Error("expected new group member to inherit the host escalation")
+	}
+	if esc := store.GetService("web02", "HTTP").AllEscalations(); len(esc) != 2 {
+		t.Errorf("expected web02;HTTP to have its own and the group escalation, got %d", len(esc))
+	}
+}
+
+func TestWarnings_EscalationWithoutContacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "escalations.cfg")
+	cfg := escalationCfg + `
+define hostescalation {
+    host_name               web01,web02
+    first_notification      2
+    last_notification       5
+}
+`
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	store := objects.NewObjectStore()
+	if err := ExpandAndRegister(parser, store, ""); err != nil {
+		t.Fatal(err)
+	}
+	// web01 has a contact for the escalation to fall back on; web02 has none.
+	store.GetHost("web01").Contacts = []*objects.Contact{store.GetContact("oncall")}
+
+	var w []string
+	for _, s := range Warnings(store) {
+		if strings.Contains(s, "escalation") {
+			w = append(w, s)
+		}
+	}
+	if len(w) != 1 || w[0] != "host 'web02': escalation for notifications 2-5 notifies no contacts" {
+		t.Errorf("escalation warnings = %q", w)
+	}
+}
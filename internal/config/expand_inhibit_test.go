@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestRegisterInhibitRules(t *testing.T) {
+	cfg := `define host {
+    host_name               web01
+    max_check_attempts      1
+}
+
+define hostgroup {
+    hostgroup_name          web
+    members                 web01
+}
+
+define inhibit {
+    inhibit_name                ping-down
+    hostgroup_name              web
+    source_service_description  Ping
+    source_states               w,c
+    target_service_description  HTTP.*|SSH
+    comment                     host unreachable over the network
+}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "inhibit.cfg")
+	load := func(cfg string) (*objects.ObjectStore, error) {
+		if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		parser := NewObjectParser()
+		if err := parser.ParseFile(path); err != nil {
+			t.Fatal(err)
+		}
+		store := objects.NewObjectStore()
+		return store, ExpandAndRegister(parser, store, "")
+	}
+	store, err := load(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.InhibitRules) != 1 {
+		t.Fatalf("expected 1 inhibit rule, got %d", len(store.InhibitRules))
+	}
+	r := store.InhibitRules[0]
+	if r.Name != "ping-down" || r.SourceService != "Ping" || r.SourceStates != objects.OptWarning|objects.OptCritical ||
+		len(r.HostGroups) != 1 || r.Comment == "" {
+		t.Errorf("unexpected inhibit rule %+v", r)
+	}
+	for desc, want := range map[string]bool{"HTTP": true, "HTTPS": true, "SSH": true, "SSH2": false, "Ping": false} {
+		if got := r.Target.MatchString(desc); got != want {
+			t.Errorf("target match %q = %v, want %v", desc, got, want)
+		}
+	}
+
+	store, err = load(strings.Replace(cfg, "    source_states               w,c\n", "", 1))
+	if err != nil || store.InhibitRules[0].SourceStates != objects.OptCritical {
+		t.Errorf("expected source_states to default to c, got %v", err)
+	}
+
+	for _, c := range []struct{ old, new, want string }{
+		{"hostgroup_name              web", "hostgroup_name              nope", "hostgroup 'nope' not found"},
+		{"HTTP.*|SSH", "HTTP(", "target_service_description: error parsing regexp"},
+		{"source_service_description  Ping", "", "missing source_service_description"},
+		{"source_states               w,c", "source_states               o", "source_states matches no problem state"},
+	} {
+		if _, err := load(strings.Replace(cfg, c.old, c.new, 1)); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("expected %q error, got %v", c.want, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Explain returns the definition of an object as it is after template
+// inheritance, for gogios -v --explain. Each attribute carries a comment
+// naming the definitions its value came from, and the header shows the
+// templates it uses. name is the object's name, "host;service" for a
+// service, or the name of a template of type typ. store is the store the
+// configuration was loaded into; it finds objects expanded from a
+// definition, such as a service on a hostgroup.
+func Explain(mainConfigPath string, store *objects.ObjectStore, typ, name string) (string, error) {
+	mainCfg, err := ReadMainConfig(mainConfigPath)
+	if err != nil {
+		return "", fmt.Errorf("error reading main config: %w", err)
+	}
+	parser := NewObjectParser()
+	parser.origins = make(map[*TemplateObject]map[string][]*TemplateObject)
+	if err := parseObjectFiles(mainCfg, parser, false); err != nil {
+		return "", err
+	}
+	if err := ResolveTemplates(parser); err != nil {
+		return "", fmt.Errorf("error resolving templates: %w", err)
+	}
+
+	var target *TemplateObject
+	title := fmt.Sprintf("%s '%s'", typ, name)
+	if src := storeSource(store, typ, name); src != "" {
+		for _, obj := range parser.Objects {
+			if obj.Type == typ && obj.Register() && configSource(obj) == src {
+				target = obj
+				break
+			}
+		}
+		if host, desc, ok := strings.Cut(name, ";"); ok && typ == "service" {
+			title = fmt.Sprintf("service '%s' on host '%s'", desc, host)
+		}
+	} else if target = parser.GetTemplate(typ, name); target != nil {
+		title = fmt.Sprintf("%s template '%s'", typ, name)
+	}
+	if target == nil {
+		return "", fmt.Errorf("%s not found", title)
+	}
+	return explainObject(parser, target, title), nil
+}
+
+// storeSource returns the config_source of the named object in store, or
+// "" when there is none.
+func storeSource(store *objects.ObjectStore, typ, name string) string {
+	switch typ {
+	case "host":
+		if h := store.GetHost(name); h != nil {
+			return h.ConfigSource
+		}
+	case "service":
+		host, desc, _ := strings.Cut(name, ";")
+		if svc := store.GetService(host, desc); svc != nil {
+			return svc.ConfigSource
+		}
+	case "command":
+		if c := store.GetCommand(name); c != nil {
+			return c.ConfigSource
+		}
+	case "contact":
+		if c := store.GetContact(name); c != nil {
+			return c.ConfigSource
+		}
+	case "contactgroup":
+		if cg := store.GetContactGroup(name); cg != nil {
+			return cg.ConfigSource
+		}
+	case "hostgroup":
+		if hg := store.GetHostGroup(name); hg != nil {
+			return hg.ConfigSource
+		}
+	case "servicegroup":
+		if sg := store.GetServiceGroup(name); sg != nil {
+			return sg.ConfigSource
+		}
+	case "timeperiod":
+		if tp := store.GetTimeperiod(name); tp != nil {
+			return tp.ConfigSource
+		}
+	}
+	return ""
+}
+
+// explainObject renders obj, resolved by parser with origins tracked.
+func explainObject(parser *ObjectParser, obj *TemplateObject, title string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, defined at %s\n", title, configSource(obj))
+	if _, ok := obj.Attrs["use"]; ok {
+		b.WriteString("# using templates:\n")
+		explainChain(&b, parser, obj, 1)
+	}
+
+	// describe names where a value came from, the object's own
+	// definition as "own".
+	describe := func(from []*TemplateObject) string {
+		parts := make([]string, len(from))
+		for i, t := range from {
+			if t == obj {
+				parts[i] = "own"
+			} else {
+				parts[i] = fmt.Sprintf("%s (%s)", t.Name(), configSource(t))
+			}
+		}
+		return strings.Join(parts, " + ")
+	}
+
+	keys := slices.DeleteFunc(obj.formatOrder(), func(k string) bool { return k == "use" })
+	keyWidth, valWidth := 0, 0
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		if strings.HasPrefix(k, "_") {
+			values[i] = escapeSemicolons(obj.CustomVars[k[1:]])
+		} else {
+			values[i] = escapeSemicolons(obj.Attrs[k])
+		}
+		keyWidth = max(keyWidth, len(k))
+		// Long values such as command lines don't push every comment out.
+		if len(values[i]) <= 40 {
+			valWidth = max(valWidth, len(values[i]))
+		}
+	}
+	fmt.Fprintf(&b, "define %s {\n", obj.Type)
+	for i, k := range keys {
+		fmt.Fprintf(&b, "    %-*s  %-*s  ; %s\n", keyWidth, k, valWidth, values[i], describe(parser.origin(obj, k)))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// explainChain writes the templates obj uses, depth first in the order
+// they are applied, indented by depth.
+func explainChain(b *strings.Builder, parser *ObjectParser, obj *TemplateObject, depth int) {
+	for _, name := range splitCSV(obj.Attrs["use"]) {
+		tmpl := parser.GetTemplate(obj.Type, name)
+		if tmpl == nil {
+			continue
+		}
+		fmt.Fprintf(b, "#%s%s (%s)\n", strings.Repeat("  ", depth), name, configSource(tmpl))
+		explainChain(b, parser, tmpl, depth+1)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	dir := t.TempDir()
+	cfg := `define command {
+    command_name  check-ping
+    command_line  /bin/true
+}
+define host {
+    name                generic-host
+    register            0
+    max_check_attempts  3
+    check_command       check-ping
+    notes               generic\; see wiki
+    _OWNER              ops
+}
+define host {
+    name            linux-server
+    use             generic-host
+    register        0
+    contact_groups  admins
+}
+define host {
+    use             linux-server
+    host_name       web01
+    contact_groups  +web
+    notes           null
+}
+define hostgroup {
+    hostgroup_name  web
+    members         web01
+}
+define service {
+    hostgroup_name       web
+    service_description  HTTP
+    check_command        check-ping
+    max_check_attempts   1
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "objects.cfg"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mainPath := filepath.Join(dir, "nagios.cfg")
+	if err := os.WriteFile(mainPath, []byte("cfg_file=objects.cfg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := LoadConfig(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := filepath.Join(dir, "objects.cfg")
+
+	text, err := Explain(mainPath, result.Store, "host", "web01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# host 'web01', defined at " + objs + ":19\n",
+		"#  linux-server (" + objs + ":13)\n#    generic-host (" + objs + ":5)\n",
+		"max_check_attempts  3  ",
+		"; generic-host (" + objs + ":5)\n",
+		"admins,web  ; linux-server (" + objs + ":13) + own\n",
+		"notes               null  ",
+		"_OWNER              ops  ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("explain host: missing %q in\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "use ") {
+		t.Errorf("explain host: use attribute in\n%s", text)
+	}
+
+	// A service on a hostgroup is found through the host.
+	if text, err = Explain(mainPath, result.Store, "service", "web01;HTTP"); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(text, "# service 'HTTP' on host 'web01', defined at "+objs+":29\n") {
+		t.Errorf("explain service:\n%s", text)
+	}
+	if text, err = Explain(mainPath, result.Store, "host", "generic-host"); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(text, `generic\; see wiki`) {
+		t.Errorf("explain template: semicolon not escaped in\n%s", text)
+	}
+	if _, err := Explain(mainPath, result.Store, "host", "db01"); err == nil || err.Error() != "host 'db01' not found" {
+		t.Errorf("explain unknown host: err = %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/oceanplexian/gogios/internal/objects"
@@ -11,12 +12,51 @@ type LoadResult struct {
 	MainCfg    *MainConfig
 	UserMacros [MaxUserMacros]string
 	Store      *objects.ObjectStore
+
+	// Unknown lists the main config directives, object types and object
+	// attributes that were ignored.
+	Unknown []Unknown
+
+	// Deprecated lists the deprecated main config directives and object
+	// attributes that were set.
+	Deprecated []Deprecated
+
+	// ChangedFiles lists object config files that were re-parsed because they
+	// are new or their contents changed, plus files that disappeared since the
+	// previous load. Only populated by LoadConfigCached.
+	ChangedFiles []string
+
+	// Objects holds the object definitions after template resolution, for
+	// WriteObjectCache and WritePrecachedObjects. Set it to nil once
+	// written: it holds every definition.
+	Objects []*TemplateObject
 }
 
 // LoadConfig reads and processes all configuration starting from the main config file.
 // This follows the Nagios startup sequence: main config -> resource files -> object files ->
 // template resolution -> expansion -> registration -> validation.
 func LoadConfig(mainConfigPath string) (*LoadResult, error) {
+	return LoadConfigCached(mainConfigPath, nil)
+}
+
+// LoadConfigCached is LoadConfig with an optional tokenizer cache. When
+// cache is non-nil, object files whose contents are unchanged since the
+// previous call with the same cache are not tokenized again. The objects are
+// still resolved, expanded and registered from scratch.
+func LoadConfigCached(mainConfigPath string, cache *ParseCache) (*LoadResult, error) {
+	return loadConfig(mainConfigPath, cache, false)
+}
+
+// LoadPrecachedConfig is LoadConfig reading the object definitions from
+// precached_object_file, written by WritePrecachedObjects, instead of the
+// cfg_file and cfg_dir files. Its definitions are already resolved, which
+// saves the template resolution of very large configurations. Unknown and
+// deprecated names are not reported again.
+func LoadPrecachedConfig(mainConfigPath string) (*LoadResult, error) {
+	return loadConfig(mainConfigPath, nil, true)
+}
+
+func loadConfig(mainConfigPath string, cache *ParseCache, precached bool) (*LoadResult, error) {
 	// Step 1: Parse main config file
 	mainCfg, err := ReadMainConfig(mainConfigPath)
 	if err != nil {
@@ -33,25 +73,38 @@ func LoadConfig(mainConfigPath string) (*LoadResult, error) {
 
 	// Step 3: Parse all object config files
 	parser := NewObjectParser()
-	for _, cf := range mainCfg.CfgFiles {
-		if err := parser.ParseFile(cf); err != nil {
-			return nil, fmt.Errorf("error parsing config file: %w", err)
-		}
+	parser.Cache = cache
+	if err := parseObjectFiles(mainCfg, parser, precached); err != nil {
+		return nil, err
 	}
-	for _, cd := range mainCfg.CfgDirs {
-		if err := parser.ParseDir(cd); err != nil {
-			return nil, fmt.Errorf("error parsing config dir: %w", err)
-		}
+
+	changed := parser.ChangedFiles
+	if cache != nil {
+		changed = append(changed, cache.retain(parser.seenFiles)...)
 	}
 
-	// Step 4: Resolve templates
+	// Unknown names are collected before template resolution copies them
+	// into every object that inherits them. A precache holds them resolved
+	// into every object, and they were reported when it was written.
+	unknown := append([]Unknown(nil), mainCfg.Unknown...)
+	deprecated := append([]Deprecated(nil), mainCfg.Deprecated...)
+	if !precached {
+		unknown = append(unknown, parser.Unknown()...)
+		deprecated = append(deprecated, parser.Deprecated()...)
+	}
+	if mainCfg.StrictConfig && len(unknown) > 0 {
+		return nil, &UnknownError{Unknown: unknown}
+	}
+
+	// Step 4: Resolve templates. Precached definitions have none left, but
+	// the NRDP-generated config they include may use them.
 	if err := ResolveTemplates(parser); err != nil {
 		return nil, fmt.Errorf("error resolving templates: %w", err)
 	}
 
 	// Step 5: Expand, register, and wire up all objects
 	store := objects.NewObjectStore()
-	if err := ExpandAndRegister(parser, store, mainCfg.NRDPDynamicConfigFile); err != nil {
+	if err := ExpandAndRegisterWithDefaults(parser, store, mainCfg.NRDPDynamicConfigFile, mainCfg.ObjectDefaults); err != nil {
 		return nil, fmt.Errorf("error expanding objects: %w", err)
 	}
 
@@ -59,13 +112,51 @@ func LoadConfig(mainConfigPath string) (*LoadResult, error) {
 		MainCfg:    mainCfg,
 		UserMacros: macros,
 		Store:      store,
+		Unknown:    unknown,
+		Deprecated: deprecated,
+
+		ChangedFiles: changed,
+		Objects:      parser.Objects,
 	}, nil
 }
 
+// parseObjectFiles parses the object files of mainCfg, or its precached
+// object file, into parser.
+func parseObjectFiles(mainCfg *MainConfig, parser *ObjectParser, precached bool) error {
+	if precached {
+		if mainCfg.PrecachedObjectFile == "" {
+			return fmt.Errorf("precached_object_file is not set")
+		}
+		if err := parser.parsePrecached(mainCfg.PrecachedObjectFile); err != nil {
+			return fmt.Errorf("error parsing precached object file: %w", err)
+		}
+		return nil
+	}
+	for _, cf := range mainCfg.CfgFiles {
+		if err := parser.ParseFile(cf); err != nil {
+			return fmt.Errorf("error parsing config file: %w", err)
+		}
+	}
+	for _, cd := range mainCfg.CfgDirs {
+		if err := parser.ParseDir(cd); err != nil {
+			return fmt.Errorf("error parsing config dir: %w", err)
+		}
+	}
+	return nil
+}
+
 // VerifyConfig loads and validates configuration, returning errors found.
 func VerifyConfig(mainConfigPath string) (*LoadResult, []error) {
-	result, err := LoadConfig(mainConfigPath)
+	result, err := loadConfig(mainConfigPath, nil, false)
 	if err != nil {
+		var ue *UnknownError
+		if errors.As(err, &ue) {
+			errs := make([]error, len(ue.Unknown))
+			for i, u := range ue.Unknown {
+				errs[i] = errors.New(u.String())
+			}
+			return nil, errs
+		}
 		return nil, []error{err}
 	}
 	errs := Validate(result.Store)
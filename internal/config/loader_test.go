@@ -1,7 +1,13 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -68,6 +74,14 @@ func TestLoadConfig(t *testing.T) {
 		t.Error("SSH services on web-01/web-02 not found")
 	}
 
+	// Verify provenance is recorded for the expanded objects
+	if !strings.Contains(web01.ConfigSource, ".cfg:") {
+		t.Errorf("web-01 config_source: expected file:line, got %q", web01.ConfigSource)
+	}
+	if sshWeb01 != nil && sshWeb02 != nil && (sshWeb01.ConfigSource == "" || sshWeb01.ConfigSource != sshWeb02.ConfigSource) {
+		t.Errorf("SSH config_source: expected both to share the definition, got %q and %q", sshWeb01.ConfigSource, sshWeb02.ConfigSource)
+	}
+
 	// Verify contact group resolution
 	admins := store.GetContactGroup("admins")
 	if admins == nil {
@@ -160,6 +174,122 @@ func TestServiceInheritContactsFromHost(t *testing.T) {
 	}
 }
 
+func TestNullCancelsAttributes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "objects.cfg")
+	cfg := `define command {
+    command_name  check-ping
+    command_line  /bin/true
+}
+define contact {
+    contact_name  admin
+}
+define host {
+    host_name           h1
+    max_check_attempts  1
+    check_command       check-ping
+    contacts            admin
+}
+define service {
+    name                    base-svc
+    register                0
+    check_command           check-ping
+    flap_detection_enabled  0
+}
+define service {
+    use                     base-svc
+    host_name               h1
+    service_description     Quiet
+    contacts                null
+    flap_detection_enabled  null
+}
+define service {
+    use                  base-svc
+    host_name            h1
+    service_description  Loud
+}
+`
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveTemplates(parser); err != nil {
+		t.Fatal(err)
+	}
+	store := objects.NewObjectStore()
+	if err := ExpandAndRegister(parser, store, ""); err != nil {
+		t.Fatal(err)
+	}
+	quiet, loud := store.GetService("h1", "Quiet"), store.GetService("h1", "Loud")
+	if len(quiet.Contacts) != 0 {
+		t.Errorf("Quiet contacts = %v, want none (null stops inheritance from the host)", quiet.Contacts)
+	}
+	if len(loud.Contacts) != 1 {
+		t.Errorf("Loud contacts = %v, want the host's", loud.Contacts)
+	}
+	// null falls back to the built-in default, not the template's value
+	if !quiet.FlapDetectionEnabled || loud.FlapDetectionEnabled {
+		t.Errorf("flap_detection_enabled = %v/%v, want true/false", quiet.FlapDetectionEnabled, loud.FlapDetectionEnabled)
+	}
+}
+
+func TestTags(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "objects.cfg")
+	cfg := `define command {
+    command_name  check-ping
+    command_line  /bin/true
+}
+define host {
+    name                base-host
+    register            0
+    max_check_attempts  1
+    check_command       check-ping
+    tags                env=prod
+}
+define host {
+    use        base-host
+    host_name  db01
+    tags       +team=db, pci
+    _TAGS      tier=1
+}
+define service {
+    host_name            db01
+    service_description  MySQL
+    check_command        check-ping
+    _tags                team=dba
+}
+`
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveTemplates(parser); err != nil {
+		t.Fatal(err)
+	}
+	store := objects.NewObjectStore()
+	if err := ExpandAndRegister(parser, store, ""); err != nil {
+		t.Fatal(err)
+	}
+	h := store.GetHost("db01")
+	if want := []string{"env=prod", "pci", "team=db", "tier=1"}; !slices.Equal(h.Tags, want) {
+		t.Errorf("host tags = %q, want %q", h.Tags, want)
+	}
+	if svc := store.GetService("db01", "MySQL"); !slices.Equal(svc.Tags, []string{"team=dba"}) {
+		t.Errorf("service tags = %q, want [team=dba]", svc.Tags)
+	}
+	sel, _ := objects.ParseTagSelector("env=prod,pci")
+	if hosts := store.SelectHosts(sel); len(hosts) != 1 || hosts[0] != h {
+		t.Errorf("env=prod,pci selected %v, want [db01]", hosts)
+	}
+}
+
 func TestHostGroupBidirectionalRefs(t *testing.T) {
 	result, err := LoadConfig(testConfigPath("nagios.cfg"))
 	if err != nil {
@@ -173,3 +303,89 @@ func TestHostGroupBidirectionalRefs(t *testing.T) {
 		t.Error("web-01 should belong to at least one hostgroup")
 	}
 }
+
+func TestHostParentsWired(t *testing.T) {
+	result, err := LoadConfig(testConfigPath("nagios.cfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	web01 := result.Store.GetHost("web-01")
+	sw := result.Store.GetHost("dist-switch-01")
+	if web01 == nil || sw == nil {
+		t.Fatal("web-01 or dist-switch-01 not found")
+	}
+	if len(web01.Parents) != 1 || web01.Parents[0] != sw {
+		t.Errorf("web-01 parents = %v, want [dist-switch-01]", web01.Parents)
+	}
+	if len(sw.Children) != 4 {
+		t.Errorf("dist-switch-01 should have 4 children, got %d", len(sw.Children))
+	}
+	if core := result.Store.GetHost("core-router"); core == nil || len(core.Parents) != 0 {
+		t.Error("core-router should have no parents")
+	}
+}
+
+func TestHostParentsUnknown(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.cfg")
+	cfg := "define host {\n    host_name h1\n    parents nosuchhost\n    max_check_attempts 1\n}\n"
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExpandAndRegister(parser, objects.NewObjectStore(), ""); err == nil {
+		t.Error("expected an error for an unknown parent host")
+	}
+}
+
+func TestLoadConfigCachedSkipsUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	cmds := write("commands.cfg", "define command {\n command_name check_dummy\n command_line /bin/true\n}\n")
+	hosts := write("hosts.cfg", "define host {\n name tmpl\n register 0\n check_command check_dummy\n max_check_attempts 3\n}\n"+
+		"define host {\n use tmpl\n host_name h1\n alias h1\n address 127.0.0.1\n}\n")
+	mainCfg := write("nagios.cfg", "cfg_file="+cmds+"\ncfg_file="+hosts+"\n")
+
+	cache := NewParseCache()
+	first, err := LoadConfigCached(mainCfg, cache)
+	if err != nil {
+		t.Fatalf("first load: %v", err)
+	}
+	if len(first.ChangedFiles) != 2 {
+		t.Errorf("first load: expected 2 changed files, got %v", first.ChangedFiles)
+	}
+
+	second, err := LoadConfigCached(mainCfg, cache)
+	if err != nil {
+		t.Fatalf("second load: %v", err)
+	}
+	if len(second.ChangedFiles) != 0 {
+		t.Errorf("second load: expected no changed files, got %v", second.ChangedFiles)
+	}
+	// Replayed objects must still go through template resolution.
+	h1 := second.Store.GetHost("h1")
+	if h1 == nil || h1.CheckCommand == nil || h1.MaxCheckAttempts != 3 {
+		t.Fatalf("h1 not resolved from cached objects: %+v", h1)
+	}
+
+	write("hosts.cfg", "define host {\n host_name h2\n alias h2\n address 127.0.0.2\n check_command check_dummy\n max_check_attempts 1\n}\n")
+	third, err := LoadConfigCached(mainCfg, cache)
+	if err != nil {
+		t.Fatalf("third load: %v", err)
+	}
+	if len(third.ChangedFiles) != 1 || third.ChangedFiles[0] != hosts {
+		t.Errorf("third load: expected only %s changed, got %v", hosts, third.ChangedFiles)
+	}
+	if third.Store.GetHost("h1") != nil || third.Store.GetHost("h2") == nil {
+		t.Error("third load did not pick up the edited hosts file")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/oceanplexian/gogios/internal/listenaddr"
 )
 
 type MainConfig struct {
@@ -15,7 +17,7 @@ type MainConfig struct {
 	CfgFiles             []string
 	CfgDirs              []string
 	ResourceFiles        []string
-	StatusFile           string
+	StatusFile           string // empty for status_file=none
 	StateRetentionFile   string
 	ObjectCacheFile      string
 	PrecachedObjectFile  string
@@ -53,6 +55,7 @@ type MainConfig struct {
 	HostCheckTimeout         int
 	EventHandlerTimeout      int
 	NotificationTimeout      int
+	NotificationWorkers      int // notification commands run at once; 0 = no limit
 	OCSPTimeout              int
 	OCHPTimeout              int
 	PerfdataTimeout          int
@@ -76,6 +79,7 @@ type MainConfig struct {
 	// State management
 	RetainStateInformation                bool
 	RetentionUpdateInterval               int
+	RetentionShards                       int // split host/service state across this many files
 	UseRetainedProgramState               bool
 	UseRetainedSchedulingInfo             bool
 	RetentionSchedulingHorizon            int
@@ -173,7 +177,7 @@ type MainConfig struct {
 	LivestatusTCP                 string
 
 	// NRDP Relay (Gogios extension)
-	NRDPListen         string // listen address, e.g. ":5668"
+	NRDPListen         string // listen addresses, e.g. ":5668" or "0.0.0.0:5668,[::]:5668"
 	NRDPPath           string // URL path, default "/nrdp/"
 	NRDPTokenHash      string // bcrypt hash of accepted token
 	NRDPDynamicEnabled          bool   // auto-register hosts/services from NRDP submissions
@@ -183,6 +187,163 @@ type MainConfig struct {
 	NRDPDynamicConfigFile       string // persistent .cfg file with all dynamic hosts/services; empty=disabled (default /opt/nagios/etc/dynamic/nrdp_generated.cfg)
 	NRDPSSLCert        string // TLS certificate file
 	NRDPSSLKey         string // TLS key file
+	NRDPSenderStaleThreshold int      // seconds a sender may stay silent before it is reported stale; 0=not tracked
+	NRDPExpectedSenders      []string // sender addresses tracked from startup
+
+	// Check executors (Gogios extension)
+	CheckExecutor     string // default runner for hosts without _CHECK_EXECUTOR: "local", "ssh" or "agent"
+	// Routes from hostgroups or custom variables to runners, one
+	// check_executor_route per line, tried in order
+	CheckExecutorRoutes []string
+	SSHUser           string // remote user for the ssh runner (default "nagios")
+	SSHPort           int    // remote port for the ssh runner (default 22)
+	SSHKeyFile        string // private key for the ssh runner; empty disables it
+	SSHKnownHostsFile string // known_hosts file; required unless SSHInsecureIgnoreHostKey
+	SSHInsecureIgnoreHostKey bool // accept any host key without a known_hosts file (default 0)
+	SSHConnectTimeout int    // seconds (default 10)
+	NativeCheckBySSH  bool   // run check_by_ssh command lines over the ssh runner's pool (default 1)
+	AgentPort         int    // port of the agents for the agent runner (default 5670)
+	AgentTokenFile    string // bearer token for the agents; empty disables the agent runner
+	AgentTLS          bool   // dial the agents over TLS
+	AgentCAFile       string // CA bundle for agent TLS; empty uses the system roots
+
+	// Event bus publisher (Gogios extension)
+	EventPublisher       string // "" (disabled), "nats" or "kafka"
+	EventPublisherURL    string // e.g. nats://127.0.0.1:4222 or kafka://broker1:9092,broker2:9092
+	EventPublisherPrefix string // subject or topic prefix (default "gogios")
+	EventPublisherFormat string // "json" (default) or "protobuf"
+
+	// Alert forwarding (Gogios extension)
+	AlertForwardTarget string // "" (disabled), udp://host:port or tcp://host:port
+	AlertForwardFormat string // "syslog" (default) or "json"
+	AlertForwardBuffer int    // queued events before new ones are dropped (default 10000)
+
+	// Simulation mode (Gogios extension): synthetic check results for load testing
+	SimulationMode          bool
+	SimulationWarningRate   float64 // per-check probability an OK service turns WARNING
+	SimulationCriticalRate  float64 // per-check probability an OK service turns CRITICAL
+	SimulationUnknownRate   float64 // per-check probability an OK service turns UNKNOWN
+	SimulationHostDownRate  float64 // per-check probability an UP host turns DOWN
+	SimulationRecoveryRate  float64 // per-check probability a problem recovers (default 0.5)
+	SimulationLatency       string  // fixed, uniform, normal, exponential (default fixed)
+	SimulationLatencyMean   int     // milliseconds (default 50)
+	SimulationLatencyStdDev int     // milliseconds, normal distribution only
+
+	// gRPC admin API (Gogios extension)
+	GRPCAdminListen    string // listen address, e.g. "127.0.0.1:5669"; empty=disabled
+	GRPCAdminTokenHash string // bcrypt hash of accepted bearer token; empty=no auth
+	GRPCAdminSSLCert   string
+	GRPCAdminSSLKey    string
+
+	// REST/JSON API (Gogios extension)
+	RESTListen    string // listen address, e.g. "127.0.0.1:5672"; empty=disabled
+	RESTTokenHash string // bcrypt hash of accepted bearer token; empty=no auth
+	RESTSSLCert   string
+	RESTSSLKey    string
+
+	// Importance-weighted scheduling (Gogios extension): when the local
+	// executor is saturated, dispatch higher hourly_value checks first
+	ImportanceScheduling bool
+
+	// Debug listener (Gogios extension): pprof and runtime metrics
+	DebugListen string // default "127.0.0.1:6060"; empty=disabled
+
+	// Result queue (Gogios extension): overflow handling in front of the
+	// scheduler's result channel
+	ResultQueueSize    int    // channel capacity (default 65536)
+	ResultSpillFile    string // spill results here when the channel is full; empty=drop
+	ResultSpillMaxSize int64  // bytes (default 104857600); 0=unlimited
+
+	// Self-check (Gogios extension): a pseudo host whose services report
+	// the health of gogios' own subsystems
+	SelfCheck              bool
+	SelfCheckHostName      string // default "gogios"
+	SelfCheckInterval      int    // seconds between probes (default 60)
+	SelfCheckContactGroups []string // contact groups notified for the self-check objects
+
+	// Socket and pipe permissions (Gogios extension), applied when gogios
+	// creates query_socket and command_file
+	QuerySocketMode  uint32 // octal (default 0660)
+	QuerySocketGroup string // group name or GID; empty=leave as created
+	CommandFileMode  uint32 // octal (default 0660)
+	CommandFileGroup string // group name or GID; empty=leave as created
+
+	// Command intake (Gogios extension): sources besides command_file that
+	// feed the external command processor
+	CommandSocket     string // Unix datagram socket; uses command_file_mode/group
+	CommandTCPListen  string // e.g. "127.0.0.1:5670"; empty=disabled
+	CommandHTTPListen string // e.g. "127.0.0.1:5671"; empty=disabled
+	CommandTokenHash  string // bcrypt hash of the token for TCP and HTTP; empty=no auth
+	// Directory "@<name>" commands write their result to (Gogios
+	// extension); empty=response files disabled
+	CommandResponseDir string
+
+	// Check output sanitization (Gogios extension): "replace" (default),
+	// "strip" or "off"
+	CheckOutputSanitization string
+
+	// State of hosts without a check command (Gogios extension): "up"
+	// (default), "pending" or "services"; _NO_CHECK_STATE overrides it
+	HostNoCheckState string
+	// Seconds without a service result after which a host with no check
+	// command is DOWN (Gogios extension); 0=disabled
+	HostStateFromServices int
+
+	// Attributes given to hosts and services that omit them (Gogios
+	// extension); empty = no default
+	ObjectDefaults ObjectDefaults
+
+	// Check plugin sandboxing (Gogios extension): default rlimits for every
+	// check, overridable with _CHECK_RLIMIT_* custom variables (0=inherit),
+	// and a cgroup v2 directory all plugins run in (empty=none)
+	CheckRlimitCPU    int // CPU seconds
+	CheckRlimitMemory int // MB of address space
+	CheckRlimitNoFile int // open file descriptors
+	CheckCgroup       string
+
+	// Out-of-bounds return code mapping (Gogios extension): see
+	// checker.ParseExitCodeMap; empty maps every code to CRITICAL/DOWN
+	ExitCodeMap string
+
+	// Concurrency classes (Gogios extension): name:limit pairs, see
+	// checker.ParseConcurrencyClasses; empty = no limits
+	CheckConcurrencyClasses string
+
+	// Heartbeat (Gogios extension): a file rewritten as the event loop
+	// iterates, for external watchdogs
+	HeartbeatFile     string // empty=disabled
+	HeartbeatInterval int    // minimum seconds between rewrites (default 1)
+
+	// Downtime validation (Gogios extension)
+	MaxDowntimeDuration int  // seconds a scheduled downtime may last; 0=no cap
+	DowntimeNoOverlap   bool // refuse downtimes overlapping one on the same object
+
+	// Notification digests (Gogios extension): one summary line per alert,
+	// expanded with the alert's own macros; empty=built-in default
+	HostDigestLine    string
+	ServiceDigestLine string
+
+	// Acknowledgement notifications (Gogios extension): also notify every
+	// escalation level reached, not only the current one
+	AckNotifyAllEscalations bool
+
+	// Notification cap (Gogios extension): notifications a contact gets
+	// per hour before the rest go to the overflow contact group; 0=no cap
+	NotificationHourlyCap            int
+	NotificationOverflowContactGroup string
+
+	// Check history (Gogios extension): results kept in memory per host
+	// and service; 0=none
+	CheckHistorySize int
+
+	// Unknown directives, object types and attributes (Gogios extension):
+	// collected while loading and listed by -v; strict_config=1 makes
+	// loading fail on them
+	StrictConfig bool
+	Unknown      []Unknown
+
+	// Deprecated directives that were set, for -v to warn about
+	Deprecated []Deprecated
 
 	// For resolving relative paths
 	basedir string
@@ -206,8 +367,10 @@ func NewMainConfig() *MainConfig {
 		HostCheckTimeout:    30,
 		EventHandlerTimeout: 30,
 		NotificationTimeout: 30,
+		NotificationWorkers: 32,
 		OCSPTimeout:         15,
 		OCHPTimeout:         15,
+		PerfdataTimeout:     5,
 		IntervalLength:      60,
 		ServiceInterCheckDelayMethod: "s",
 		HostInterCheckDelayMethod:    "s",
@@ -254,6 +417,28 @@ func NewMainConfig() *MainConfig {
 		NRDPDynamicPrune:            600,
 		NRDPDynamicHostCheckCommand: "", // empty = passive only; avoids fping storms for NRDP-registered hosts
 		NRDPDynamicConfigFile:       "/opt/nagios/etc/dynamic/nrdp_generated.cfg",
+		CheckExecutor:               "local",
+		SSHPort:                     22,
+		SSHConnectTimeout:           10,
+		NativeCheckBySSH:            true,
+		EventPublisherPrefix:        "gogios",
+		EventPublisherFormat:        "json",
+		AlertForwardFormat:          "syslog",
+		AlertForwardBuffer:          10000,
+		SimulationRecoveryRate:      0.5,
+		SimulationLatency:           "fixed",
+		SimulationLatencyMean:       50,
+		DebugListen:                 "127.0.0.1:6060",
+		ResultQueueSize:             65536,
+		ResultSpillMaxSize:          104857600,
+		SelfCheckHostName:           "gogios",
+		SelfCheckInterval:           60,
+		QuerySocketMode:             0660,
+		CommandFileMode:             0660,
+		CheckOutputSanitization:     "replace",
+		HostNoCheckState:            "up",
+		HeartbeatInterval:           1,
+		CheckHistorySize:            10,
 	}
 }
 
@@ -288,6 +473,13 @@ func ReadMainConfig(path string) (*MainConfig, error) {
 		}
 		key := strings.TrimSpace(line[:eqIdx])
 		val := strings.TrimSpace(line[eqIdx+1:])
+		if u, ok := unknownDirective(key, path, lineNum); ok {
+			cfg.Unknown = append(cfg.Unknown, u)
+			continue
+		}
+		if reason, ok := deprecatedDirectives[key]; ok {
+			cfg.Deprecated = append(cfg.Deprecated, Deprecated{File: path, Line: lineNum, Kind: "directive", Name: key, Reason: reason})
+		}
 
 		if err := cfg.setDirective(key, val); err != nil {
 			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
@@ -322,7 +514,12 @@ func (c *MainConfig) setDirective(key, val string) error {
 	case "log_file":
 		c.LogFile = c.resolvePath(val)
 	case "status_file":
-		c.StatusFile = c.resolvePath(val)
+		// "none" turns status.dat off; consumers use Livestatus instead.
+		if val == "none" {
+			c.StatusFile = ""
+		} else {
+			c.StatusFile = c.resolvePath(val)
+		}
 	case "state_retention_file":
 		c.StateRetentionFile = c.resolvePath(val)
 	case "object_cache_file":
@@ -350,10 +547,16 @@ func (c *MainConfig) setDirective(key, val string) error {
 	case "query_socket":
 		c.QuerySocket = c.resolvePath(val)
 	case "livestatus_tcp":
+		if err := listenaddr.Validate(val); err != nil {
+			return fmt.Errorf("invalid livestatus_tcp: %w", err)
+		}
 		c.LivestatusTCP = val
 
 	// NRDP
 	case "nrdp_listen":
+		if err := listenaddr.Validate(val); err != nil {
+			return fmt.Errorf("invalid nrdp_listen: %w", err)
+		}
 		c.NRDPListen = val
 	case "nrdp_path":
 		c.NRDPPath = val
@@ -373,6 +576,198 @@ func (c *MainConfig) setDirective(key, val string) error {
 		c.NRDPSSLCert = c.resolvePath(val)
 	case "nrdp_ssl_key":
 		c.NRDPSSLKey = c.resolvePath(val)
+	case "nrdp_sender_stale_threshold":
+		return setInt(&c.NRDPSenderStaleThreshold, val)
+	case "nrdp_expected_senders":
+		c.NRDPExpectedSenders = splitCSV(val)
+
+	// Check executors
+	case "check_executor":
+		c.CheckExecutor = val
+	case "check_executor_route":
+		c.CheckExecutorRoutes = append(c.CheckExecutorRoutes, val)
+	case "ssh_executor_user":
+		c.SSHUser = val
+	case "ssh_executor_port":
+		return setInt(&c.SSHPort, val)
+	case "ssh_executor_key_file":
+		c.SSHKeyFile = c.resolvePath(val)
+	case "ssh_executor_known_hosts_file":
+		c.SSHKnownHostsFile = c.resolvePath(val)
+	case "ssh_executor_insecure_ignore_host_key":
+		c.SSHInsecureIgnoreHostKey = val == "1"
+	case "ssh_executor_connect_timeout":
+		return setInt(&c.SSHConnectTimeout, val)
+	case "native_check_by_ssh":
+		c.NativeCheckBySSH = val == "1"
+	case "agent_executor_port":
+		return setInt(&c.AgentPort, val)
+	case "agent_executor_token_file":
+		c.AgentTokenFile = c.resolvePath(val)
+	case "agent_executor_tls":
+		c.AgentTLS = val == "1"
+	case "agent_executor_ca_file":
+		c.AgentCAFile = c.resolvePath(val)
+
+	// Event bus publisher
+	case "event_publisher":
+		c.EventPublisher = val
+	case "event_publisher_url":
+		c.EventPublisherURL = val
+	case "event_publisher_subject_prefix":
+		c.EventPublisherPrefix = val
+	case "event_publisher_format":
+		c.EventPublisherFormat = val
+
+	// Alert forwarding
+	case "alert_forward_target":
+		c.AlertForwardTarget = val
+	case "alert_forward_format":
+		c.AlertForwardFormat = val
+	case "alert_forward_buffer":
+		return setInt(&c.AlertForwardBuffer, val)
+
+	// Simulation mode
+	case "simulation_mode":
+		c.SimulationMode = val == "1"
+	case "simulation_warning_rate":
+		return setFloat64(&c.SimulationWarningRate, val)
+	case "simulation_critical_rate":
+		return setFloat64(&c.SimulationCriticalRate, val)
+	case "simulation_unknown_rate":
+		return setFloat64(&c.SimulationUnknownRate, val)
+	case "simulation_host_down_rate":
+		return setFloat64(&c.SimulationHostDownRate, val)
+	case "simulation_recovery_rate":
+		return setFloat64(&c.SimulationRecoveryRate, val)
+	case "simulation_latency":
+		c.SimulationLatency = val
+	case "simulation_latency_mean":
+		return setInt(&c.SimulationLatencyMean, val)
+	case "simulation_latency_stddev":
+		return setInt(&c.SimulationLatencyStdDev, val)
+
+	// gRPC admin API
+	case "grpc_admin_listen":
+		c.GRPCAdminListen = val
+	case "grpc_admin_token_hash":
+		c.GRPCAdminTokenHash = val
+	case "grpc_admin_ssl_cert":
+		c.GRPCAdminSSLCert = c.resolvePath(val)
+	case "grpc_admin_ssl_key":
+		c.GRPCAdminSSLKey = c.resolvePath(val)
+
+	// REST/JSON API
+	case "rest_listen":
+		c.RESTListen = val
+	case "rest_token_hash":
+		c.RESTTokenHash = val
+	case "rest_ssl_cert":
+		c.RESTSSLCert = c.resolvePath(val)
+	case "rest_ssl_key":
+		c.RESTSSLKey = c.resolvePath(val)
+	case "importance_scheduling":
+		c.ImportanceScheduling = val == "1"
+	case "debug_listen":
+		c.DebugListen = val
+	case "result_queue_size":
+		return setInt(&c.ResultQueueSize, val)
+	case "result_spill_file":
+		c.ResultSpillFile = c.resolvePath(val)
+	case "result_spill_max_size":
+		return setInt64(&c.ResultSpillMaxSize, val)
+	case "self_check":
+		c.SelfCheck = val == "1"
+	case "self_check_host_name":
+		c.SelfCheckHostName = val
+	case "self_check_interval":
+		return setInt(&c.SelfCheckInterval, val)
+	case "self_check_contact_groups":
+		c.SelfCheckContactGroups = splitCSV(val)
+	case "query_socket_mode":
+		return setFileMode(&c.QuerySocketMode, val)
+	case "query_socket_group":
+		c.QuerySocketGroup = val
+	case "command_file_mode":
+		return setFileMode(&c.CommandFileMode, val)
+	case "command_file_group":
+		c.CommandFileGroup = val
+	case "command_socket":
+		c.CommandSocket = c.resolvePath(val)
+	case "command_tcp_listen":
+		c.CommandTCPListen = val
+	case "command_http_listen":
+		c.CommandHTTPListen = val
+	case "command_token_hash":
+		c.CommandTokenHash = val
+	case "command_response_dir":
+		c.CommandResponseDir = c.resolvePath(val)
+	case "check_output_sanitization":
+		switch val {
+		case "replace", "strip", "off":
+			c.CheckOutputSanitization = val
+		default:
+			return fmt.Errorf("invalid check_output_sanitization %q (want replace, strip or off)", val)
+		}
+	case "host_check_period_default":
+		c.ObjectDefaults.HostCheckPeriod = val
+	case "host_notification_period_default":
+		c.ObjectDefaults.HostNotificationPeriod = val
+	case "host_contacts_default":
+		c.ObjectDefaults.HostContacts = val
+	case "host_contact_groups_default":
+		c.ObjectDefaults.HostContactGroups = val
+	case "service_check_period_default":
+		c.ObjectDefaults.ServiceCheckPeriod = val
+	case "service_notification_period_default":
+		c.ObjectDefaults.ServiceNotificationPeriod = val
+	case "service_contacts_default":
+		c.ObjectDefaults.ServiceContacts = val
+	case "service_contact_groups_default":
+		c.ObjectDefaults.ServiceContactGroups = val
+	case "host_no_check_state":
+		switch val {
+		case "up", "pending", "services":
+			c.HostNoCheckState = val
+		default:
+			return fmt.Errorf("invalid host_no_check_state %q (want up, pending or services)", val)
+		}
+	case "host_state_from_services":
+		return setInt(&c.HostStateFromServices, val)
+	case "check_rlimit_cpu":
+		return setInt(&c.CheckRlimitCPU, val)
+	case "check_rlimit_memory":
+		return setInt(&c.CheckRlimitMemory, val)
+	case "check_rlimit_nofile":
+		return setInt(&c.CheckRlimitNoFile, val)
+	case "check_cgroup":
+		c.CheckCgroup = c.resolvePath(val)
+	case "exit_code_map":
+		c.ExitCodeMap = val
+	case "check_concurrency_classes":
+		c.CheckConcurrencyClasses = val
+	case "heartbeat_file":
+		c.HeartbeatFile = c.resolvePath(val)
+	case "heartbeat_interval":
+		return setInt(&c.HeartbeatInterval, val)
+	case "max_downtime_duration":
+		return setInt(&c.MaxDowntimeDuration, val)
+	case "downtime_no_overlap":
+		c.DowntimeNoOverlap = val == "1"
+	case "host_digest_line":
+		c.HostDigestLine = val
+	case "service_digest_line":
+		c.ServiceDigestLine = val
+	case "ack_notify_all_escalations":
+		c.AckNotifyAllEscalations = val == "1"
+	case "notification_hourly_cap":
+		return setInt(&c.NotificationHourlyCap, val)
+	case "notification_overflow_contactgroup":
+		c.NotificationOverflowContactGroup = val
+	case "check_history_size":
+		return setInt(&c.CheckHistorySize, val)
+	case "strict_config":
+		c.StrictConfig = val == "1"
 
 	// Permissions
 	case "nagios_user":
@@ -527,6 +922,8 @@ func (c *MainConfig) setDirective(key, val string) error {
 		return setInt(&c.OCHPTimeout, val)
 	case "perfdata_timeout":
 		return setInt(&c.PerfdataTimeout, val)
+	case "notification_workers":
+		return setInt(&c.NotificationWorkers, val)
 	case "max_concurrent_checks":
 		return setInt(&c.MaxConcurrentChecks, val)
 	case "check_workers":
@@ -547,6 +944,8 @@ func (c *MainConfig) setDirective(key, val string) error {
 		return setInt(&c.AutoReschedulingWindow, val)
 	case "retention_update_interval":
 		return setInt(&c.RetentionUpdateInterval, val)
+	case "retention_shards":
+		return setInt(&c.RetentionShards, val)
 	case "retention_scheduling_horizon":
 		return setInt(&c.RetentionSchedulingHorizon, val)
 	case "status_update_interval":
@@ -640,6 +1039,24 @@ func setInt(dst *int, val string) error {
 	return nil
 }
 
+func setInt64(dst *int64, val string) error {
+	v, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q: %w", val, err)
+	}
+	*dst = v
+	return nil
+}
+
+func setFileMode(dst *uint32, val string) error {
+	v, err := strconv.ParseUint(val, 8, 32)
+	if err != nil || v > 0777 {
+		return fmt.Errorf("invalid octal file mode %q", val)
+	}
+	*dst = uint32(v)
+	return nil
+}
+
 func setUint64(dst *uint64, val string) error {
 	v, err := strconv.ParseUint(val, 10, 64)
 	if err != nil {
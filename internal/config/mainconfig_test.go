@@ -87,3 +87,67 @@ func projectRoot() string {
 		dir = parent
 	}
 }
+
+func TestReadMainConfigSocketPermissions(t *testing.T) {
+	dir := t.TempDir()
+	content := "query_socket_mode=0666\nquery_socket_group=www-data\ncommand_file_group=100\n"
+	cfgPath := filepath.Join(dir, "nagios.cfg")
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadMainConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("ReadMainConfig failed: %v", err)
+	}
+	if cfg.QuerySocketMode != 0666 || cfg.QuerySocketGroup != "www-data" {
+		t.Errorf("unexpected query socket permissions %o %q", cfg.QuerySocketMode, cfg.QuerySocketGroup)
+	}
+	if cfg.CommandFileMode != 0660 || cfg.CommandFileGroup != "100" {
+		t.Errorf("unexpected command file permissions %o %q", cfg.CommandFileMode, cfg.CommandFileGroup)
+	}
+
+	if err := os.WriteFile(cfgPath, []byte("command_file_mode=0999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMainConfig(cfgPath); err == nil {
+		t.Error("expected an error for a non-octal mode")
+	}
+}
+
+func TestReadMainConfigStatusFileNone(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "nagios.cfg")
+	if err := os.WriteFile(cfgPath, []byte("status_file=var/status.dat\nstatus_file=none\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadMainConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("ReadMainConfig failed: %v", err)
+	}
+	if cfg.StatusFile != "" {
+		t.Errorf("expected status_file=none to disable status.dat, got %q", cfg.StatusFile)
+	}
+}
+
+func TestReadMainConfigListenAddresses(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "nagios.cfg")
+	content := "livestatus_tcp=127.0.0.1:6557,[::1]:6557\nnrdp_listen=:5668\n"
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadMainConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("ReadMainConfig failed: %v", err)
+	}
+	if cfg.LivestatusTCP != "127.0.0.1:6557,[::1]:6557" || cfg.NRDPListen != ":5668" {
+		t.Errorf("unexpected listen addresses %q %q", cfg.LivestatusTCP, cfg.NRDPListen)
+	}
+
+	if err := os.WriteFile(cfgPath, []byte("livestatus_tcp=::1:6557\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMainConfig(cfgPath); err == nil {
+		t.Error("expected an error for an unbracketed IPv6 address")
+	}
+}
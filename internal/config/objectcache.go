@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteObjectCache writes the registered definitions of objs to path, as
+// Nagios writes objects.cache: every object as it is after template
+// inheritance, so tools can read the configuration without resolving
+// templates. Each definition is preceded by a "# file:line" comment
+// naming where it was defined.
+func WriteObjectCache(path string, objs []*TemplateObject) error {
+	return writeObjectFile(path, "OBJECT CACHE FILE", objs, "", func(obj *TemplateObject) bool {
+		return obj.Register()
+	})
+}
+
+// WritePrecachedObjects writes objs to path for LoadPrecachedConfig: every
+// definition, templates included, as it is after template inheritance.
+// Definitions read from genCfgFile, the NRDP-generated config that changes
+// while the daemon runs, are not copied. An include_file line reads that
+// file afresh instead.
+func WritePrecachedObjects(path string, objs []*TemplateObject, genCfgFile string) error {
+	include := ""
+	for _, obj := range objs {
+		if fromGeneratedCfg(obj, genCfgFile) {
+			include = genCfgFile
+			break
+		}
+	}
+	return writeObjectFile(path, "PRECACHED OBJECT FILE", objs, include, func(obj *TemplateObject) bool {
+		return !fromGeneratedCfg(obj, genCfgFile)
+	})
+}
+
+// writeObjectFile atomically writes the definitions of objs that keep
+// accepts, without use, after a header and an optional include_file line.
+func writeObjectFile(path, title string, objs []*TemplateObject, include string, keep func(*TemplateObject) bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	fmt.Fprintf(w, "########################################\n")
+	fmt.Fprintf(w, "#       GOGIOS %s\n", title)
+	fmt.Fprintf(w, "#\n# THIS FILE IS AUTOMATICALLY GENERATED\n# BY GOGIOS.  DO NOT MODIFY THIS FILE!\n#\n")
+	fmt.Fprintf(w, "# Created: %s\n", time.Now().Format(time.ANSIC))
+	fmt.Fprintf(w, "########################################\n\n")
+	if include != "" {
+		fmt.Fprintf(w, "include_file=%s\n\n", include)
+	}
+	for _, obj := range objs {
+		if keep(obj) {
+			fmt.Fprintf(w, "# %s:%d\n%s\n", obj.File, obj.Line, obj.format(false, false))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	// CreateTemp makes the file private, but other tools read it.
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// parsePrecached reads a file written by WritePrecachedObjects. Its
+// definitions get back the file and line of their original definitions
+// from the comments above them.
+func (p *ObjectParser) parsePrecached(path string) error {
+	p.KeepComments = true
+	defer func() { p.KeepComments = false }()
+	if err := p.ParseFile(path); err != nil {
+		return err
+	}
+	for _, obj := range p.Objects {
+		if obj.File == path && len(obj.Comments) > 0 {
+			src := strings.TrimPrefix(obj.Comments[len(obj.Comments)-1], "# ")
+			if i := strings.LastIndexByte(src, ':'); i > 0 {
+				if line, err := strconv.Atoi(src[i+1:]); err == nil {
+					obj.File, obj.Line = src[:i], line
+				}
+			}
+		}
+		obj.Comments = nil
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestMainConfig writes a main config reading the test-configs
+// objects, with precached_object_file in dir.
+func writeTestMainConfig(t *testing.T, dir string) string {
+	t.Helper()
+	var b strings.Builder
+	for _, name := range []string{"commands", "timeperiods", "contacts", "templates", "hosts", "services", "hostgroups", "servicegroups", "dependencies", "escalations"} {
+		fmt.Fprintf(&b, "cfg_file=%s\n", testConfigPath(name+".cfg"))
+	}
+	fmt.Fprintf(&b, "cfg_dir=%s\n", testConfigPath("extra"))
+	fmt.Fprintf(&b, "resource_file=%s\n", testConfigPath("resource.cfg"))
+	fmt.Fprintf(&b, "precached_object_file=%s\n", filepath.Join(dir, "objects.precache"))
+	path := filepath.Join(dir, "nagios.cfg")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPrecachedObjects(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := writeTestMainConfig(t, dir)
+	want, errs := VerifyConfig(mainPath)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	// extra-services.cfg stands in for the NRDP-generated config, which
+	// is included rather than copied.
+	gen := filepath.Join(testConfigPath("extra"), "extra-services.cfg")
+	if err := WritePrecachedObjects(want.MainCfg.PrecachedObjectFile, want.Objects, gen); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(want.MainCfg.PrecachedObjectFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "include_file="+gen+"\n") || strings.Contains(string(data), "# "+gen+":") {
+		t.Errorf("precache copies %s instead of including it", gen)
+	}
+	got, err := LoadPrecachedConfig(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Store.Hosts) != len(want.Store.Hosts) || len(got.Store.Services) != len(want.Store.Services) {
+		t.Fatalf("precached config has %d hosts and %d services, want %d and %d",
+			len(got.Store.Hosts), len(got.Store.Services), len(want.Store.Hosts), len(want.Store.Services))
+	}
+	for _, w := range want.Store.Services {
+		g := got.Store.GetService(w.Host.Name, w.Description)
+		if g == nil {
+			t.Errorf("service %s/%s missing", w.Host.Name, w.Description)
+			continue
+		}
+		if g.ConfigSource != w.ConfigSource || g.CheckInterval != w.CheckInterval ||
+			len(g.Contacts) != len(w.Contacts) || len(g.ContactGroups) != len(w.ContactGroups) ||
+			g.NotificationOptions != w.NotificationOptions || g.CheckCommandArgs != w.CheckCommandArgs {
+			t.Errorf("service %s/%s differs when precached: %+v", w.Host.Name, w.Description, g)
+		}
+	}
+	for _, w := range want.Store.Hosts {
+		g := got.Store.GetHost(w.Name)
+		if g == nil || g.ConfigSource != w.ConfigSource || len(g.Parents) != len(w.Parents) ||
+			len(g.HostGroups) != len(w.HostGroups) || g.MaxCheckAttempts != w.MaxCheckAttempts {
+			t.Errorf("host %s differs when precached", w.Name)
+		}
+	}
+	if errs := Validate(got.Store); len(errs) > 0 {
+		t.Errorf("precached config does not validate: %v", errs)
+	}
+}
+
+func TestObjectCache(t *testing.T) {
+	dir := t.TempDir()
+	result, err := LoadConfig(writeTestMainConfig(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "objects.cache")
+	if err := WriteObjectCache(path, result.Objects); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	hosts := 0
+	for _, obj := range parser.Objects {
+		if !obj.Register() || obj.Attrs["use"] != "" {
+			t.Errorf("%s:%d: template or use in the object cache", obj.File, obj.Line)
+		}
+		if obj.Type == "host" {
+			hosts++
+		}
+	}
+	if hosts != len(result.Store.Hosts) {
+		t.Errorf("object cache has %d hosts, want %d", hosts, len(result.Store.Hosts))
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Errorf("object cache mode %v, want 0644", fi.Mode().Perm())
+	}
+}
@@ -2,10 +2,14 @@ package config
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/oceanplexian/gogios/internal/objects"
 )
 
 // TemplateObject is an intermediate representation before template resolution.
@@ -16,6 +20,13 @@ type TemplateObject struct {
 	File       string
 	Line       int
 	Resolved   bool
+
+	// Comments holds the # comment lines above the definition, and
+	// AttrComments those above each attribute (custom variables as
+	// "_NAME"). Both are only filled in when ObjectParser.KeepComments is
+	// set.
+	Comments     []string
+	AttrComments map[string][]string
 }
 
 func (t *TemplateObject) Name() string {
@@ -30,21 +41,47 @@ func (t *TemplateObject) Register() bool {
 	return v != "0"
 }
 
+// Get returns the value of an attribute. An attribute set to "null" is
+// unset: the value cancels what a template would give it.
 func (t *TemplateObject) Get(key string) (string, bool) {
 	v, ok := t.Attrs[key]
+	if v == "null" {
+		return "", false
+	}
 	return v, ok
 }
 
 func (t *TemplateObject) Has(key string) bool {
-	_, ok := t.Attrs[key]
+	_, ok := t.Get(key)
 	return ok
 }
 
+// IsNull reports whether an attribute was explicitly set to "null".
+func (t *TemplateObject) IsNull(key string) bool {
+	return t.Attrs[key] == "null"
+}
+
 // ObjectParser reads object definition files and produces TemplateObjects.
 type ObjectParser struct {
 	Objects []*TemplateObject
 	// byTypeName maps "type:name" to the template object for template lookups.
 	byTypeName map[string]*TemplateObject
+
+	// Cache, when set, lets ParseFile skip tokenizing files whose content
+	// hash is unchanged since the previous load.
+	Cache *ParseCache
+	// ChangedFiles lists files that were actually re-parsed (new or modified
+	// since the cached copy). Populated only when Cache is set.
+	ChangedFiles []string
+	seenFiles    map[string]bool
+
+	// KeepComments makes ParseFile record comment lines on the definitions
+	// they precede, so Format can write them back.
+	KeepComments bool
+
+	// origins, when not nil, records for each object the definitions its
+	// inherited values came from. Set by Explain.
+	origins map[*TemplateObject]map[string][]*TemplateObject
 }
 
 func NewObjectParser() *ObjectParser {
@@ -54,17 +91,37 @@ func NewObjectParser() *ObjectParser {
 }
 
 // ParseFile reads a single object config file, handling include_file/include_dir.
-func (p *ObjectParser) ParseFile(path string) error {
-	f, err := os.Open(path)
+func (p *ObjectParser) ParseFile(path string) (retErr error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("cannot open config file %s: %w", path, err)
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	var entry *cachedFile
+	if p.Cache != nil {
+		if p.seenFiles == nil {
+			p.seenFiles = make(map[string]bool)
+		}
+		p.seenFiles[path] = true
+		sum := sha256.Sum256(data)
+		if cached := p.Cache.lookup(path, sum); cached != nil {
+			return p.replay(cached)
+		}
+		entry = &cachedFile{sum: sum}
+		p.ChangedFiles = append(p.ChangedFiles, path)
+		defer func() {
+			// Only cache files that parsed cleanly in their entirety.
+			if retErr == nil {
+				p.Cache.store(path, entry)
+			}
+		}()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	lineNum := 0
 	var current *TemplateObject
 	inDefinition := false
+	var comments []string
 
 	for scanner.Scan() {
 		lineNum++
@@ -76,6 +133,9 @@ func (p *ObjectParser) ParseFile(path string) error {
 
 		// Skip blank lines and # comments
 		if line == "" || line[0] == '#' {
+			if p.KeepComments && line != "" {
+				comments = append(comments, line)
+			}
 			continue
 		}
 
@@ -86,6 +146,9 @@ func (p *ObjectParser) ParseFile(path string) error {
 				if !filepath.IsAbs(inclPath) {
 					inclPath = filepath.Join(filepath.Dir(path), inclPath)
 				}
+				if entry != nil {
+					entry.items = append(entry.items, cachedItem{includeFile: inclPath})
+				}
 				if err := p.ParseFile(inclPath); err != nil {
 					return err
 				}
@@ -96,6 +159,9 @@ func (p *ObjectParser) ParseFile(path string) error {
 				if !filepath.IsAbs(inclDir) {
 					inclDir = filepath.Join(filepath.Dir(path), inclDir)
 				}
+				if entry != nil {
+					entry.items = append(entry.items, cachedItem{includeDir: inclDir})
+				}
 				if err := p.ParseDir(inclDir); err != nil {
 					return err
 				}
@@ -116,6 +182,7 @@ func (p *ObjectParser) ParseFile(path string) error {
 				if rest == "hostgroupescalation" {
 					inDefinition = true
 					current = nil
+					comments = nil
 					continue
 				}
 				current = &TemplateObject{
@@ -124,19 +191,19 @@ func (p *ObjectParser) ParseFile(path string) error {
 					CustomVars: make(map[string]string),
 					File:       path,
 					Line:       lineNum,
+					Comments:   comments,
 				}
+				comments = nil
 				inDefinition = true
 			}
 		} else {
 			if line == "}" {
 				if current != nil {
-					p.Objects = append(p.Objects, current)
-					if name := current.Name(); name != "" {
-						key := current.Type + ":" + name
-						if _, exists := p.byTypeName[key]; exists {
-							return fmt.Errorf("%s:%d: duplicate template name '%s' for type '%s'", path, current.Line, name, current.Type)
-						}
-						p.byTypeName[key] = current
+					if entry != nil {
+						entry.items = append(entry.items, cachedItem{obj: current.clone()})
+					}
+					if err := p.addObject(current); err != nil {
+						return err
 					}
 				}
 				current = nil
@@ -155,14 +222,28 @@ func (p *ObjectParser) ParseFile(path string) error {
 			if key == "" {
 				continue
 			}
+			// Values repeat across definitions (check commands, contact
+			// groups, templates), so they are interned rather than left
+			// pinning the line they were cut from.
 			// Custom variables start with _
 			if strings.HasPrefix(key, "_") {
 				varName := strings.ToUpper(key[1:])
-				current.CustomVars[varName] = val
+				current.CustomVars[objects.Intern(varName)] = objects.Intern(val)
+				key = "_" + varName
 			} else {
+				if current.Type == "timeperiod" {
+					key, val = splitTimeperiodDirective(key, val)
+				}
 				// Normalize aliases
 				key = normalizeAlias(current.Type, key)
-				current.Attrs[key] = val
+				current.Attrs[objects.Intern(key)] = objects.Intern(val)
+			}
+			if comments != nil {
+				if current.AttrComments == nil {
+					current.AttrComments = make(map[string][]string)
+				}
+				current.AttrComments[key] = comments
+				comments = nil
 			}
 		}
 	}
@@ -172,6 +253,19 @@ func (p *ObjectParser) ParseFile(path string) error {
 	return scanner.Err()
 }
 
+// addObject appends a parsed object and indexes it by name for template lookups.
+func (p *ObjectParser) addObject(obj *TemplateObject) error {
+	p.Objects = append(p.Objects, obj)
+	if name := obj.Name(); name != "" {
+		key := obj.Type + ":" + name
+		if _, exists := p.byTypeName[key]; exists {
+			return fmt.Errorf("%s:%d: duplicate template name '%s' for type '%s'", obj.File, obj.Line, name, obj.Type)
+		}
+		p.byTypeName[key] = obj
+	}
+	return nil
+}
+
 // ParseDir recursively processes a directory of .cfg files.
 func (p *ObjectParser) ParseDir(dir string) error {
 	entries, err := os.ReadDir(dir)
@@ -223,89 +317,3 @@ func splitDirective(line string) (string, string) {
 	}
 	return line[:idx], strings.TrimSpace(line[idx+1:])
 }
-
-// normalizeAlias maps attribute aliases to their canonical form.
-func normalizeAlias(objType, key string) string {
-	switch objType {
-	case "host":
-		switch key {
-		case "obsess":
-			return "obsess_over_host"
-		case "importance":
-			return "hourly_value"
-		}
-	case "service":
-		switch key {
-		case "obsess":
-			return "obsess_over_service"
-		case "importance":
-			return "hourly_value"
-		case "description":
-			return "service_description"
-		}
-	case "contact":
-		switch key {
-		case "contact_groups":
-			return "contactgroups"
-		case "minimum_value":
-			return "minimum_importance"
-		}
-	case "hostdependency":
-		switch key {
-		case "host", "master_host", "master_host_name":
-			return "host_name"
-		case "dependent_host":
-			return "dependent_host_name"
-		case "hostgroup", "hostgroups":
-			return "hostgroup_name"
-		case "dependent_hostgroup", "dependent_hostgroups":
-			return "dependent_hostgroup_name"
-		case "execution_failure_criteria":
-			return "execution_failure_options"
-		case "notification_failure_criteria":
-			return "notification_failure_options"
-		}
-	case "servicedependency":
-		switch key {
-		case "host", "master_host", "master_host_name":
-			return "host_name"
-		case "description", "master_description", "master_service_description":
-			return "service_description"
-		case "hostgroup", "hostgroups":
-			return "hostgroup_name"
-		case "servicegroup", "servicegroups":
-			return "servicegroup_name"
-		case "dependent_host":
-			return "dependent_host_name"
-		case "dependent_description":
-			return "dependent_service_description"
-		case "dependent_hostgroup", "dependent_hostgroups":
-			return "dependent_hostgroup_name"
-		case "dependent_servicegroup", "dependent_servicegroups":
-			return "dependent_servicegroup_name"
-		case "execution_failure_criteria":
-			return "execution_failure_options"
-		case "notification_failure_criteria":
-			return "notification_failure_options"
-		}
-	case "hostescalation":
-		switch key {
-		case "host":
-			return "host_name"
-		case "hostgroup", "hostgroups":
-			return "hostgroup_name"
-		}
-	case "serviceescalation":
-		switch key {
-		case "host":
-			return "host_name"
-		case "description":
-			return "service_description"
-		case "hostgroup", "hostgroups":
-			return "hostgroup_name"
-		case "servicegroup", "servicegroups":
-			return "servicegroup_name"
-		}
-	}
-	return key
-}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"unsafe"
 )
 
 func TestParseObjectFile(t *testing.T) {
@@ -162,3 +163,27 @@ func TestNestedDefineError(t *testing.T) {
 		t.Error("expected error for nested define")
 	}
 }
+
+func TestParseFileInternsValues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "services.cfg")
+	cfg := "define service {\n    host_name web-01\n    service_description Load\n    check_command check_nrpe!check_load\n    _TEAM web\n}\n" +
+		"define service {\n    host_name web-02\n    service_description Load\n    check_command check_nrpe!check_load\n    _TEAM web\n}\n"
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(parser.Objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(parser.Objects))
+	}
+	a, b := parser.Objects[0], parser.Objects[1]
+	if unsafe.StringData(a.Attrs["check_command"]) != unsafe.StringData(b.Attrs["check_command"]) {
+		t.Error("check_command: expected both definitions to share one string")
+	}
+	if unsafe.StringData(a.CustomVars["TEAM"]) != unsafe.StringData(b.CustomVars["TEAM"]) {
+		t.Error("_TEAM: expected both definitions to share one string")
+	}
+}
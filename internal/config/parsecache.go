@@ -0,0 +1,113 @@
+package config
+
+import (
+	"sort"
+	"sync"
+)
+
+// ParseCache is a tokenizer cache. It remembers the raw
+// (pre-template-resolution) objects parsed from each config file, keyed by
+// path and SHA-256 of the file contents. A reload that passes the same cache
+// only tokenizes files whose contents changed; unchanged files are replayed
+// from memory. It does not make a reload incremental: template resolution,
+// expansion and registration still run over the full object set, because a
+// template edit in one file can change objects defined in any other, and
+// they are most of the cost of a load (see BenchmarkLoadConfigCached).
+type ParseCache struct {
+	mu    sync.Mutex
+	files map[string]*cachedFile
+}
+
+type cachedFile struct {
+	sum   [32]byte
+	items []cachedItem
+}
+
+// cachedItem is one top-level statement of a file, in source order: either an
+// object definition or an include directive that must be followed on replay.
+type cachedItem struct {
+	obj         *TemplateObject
+	includeFile string
+	includeDir  string
+}
+
+// NewParseCache creates an empty parse cache.
+func NewParseCache() *ParseCache {
+	return &ParseCache{files: make(map[string]*cachedFile)}
+}
+
+func (c *ParseCache) lookup(path string, sum [32]byte) *cachedFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f := c.files[path]; f != nil && f.sum == sum {
+		return f
+	}
+	return nil
+}
+
+func (c *ParseCache) store(path string, f *cachedFile) {
+	c.mu.Lock()
+	c.files[path] = f
+	c.mu.Unlock()
+}
+
+// Len returns the number of files held in the cache.
+func (c *ParseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.files)
+}
+
+// retain drops cache entries for files not in seen and returns their paths.
+func (c *ParseCache) retain(seen map[string]bool) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var removed []string
+	for path := range c.files {
+		if !seen[path] {
+			delete(c.files, path)
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// replay re-adds the cached objects of an unchanged file, following its
+// include directives so that changes in included files are still detected.
+func (p *ObjectParser) replay(f *cachedFile) error {
+	for _, item := range f.items {
+		switch {
+		case item.obj != nil:
+			if err := p.addObject(item.obj.clone()); err != nil {
+				return err
+			}
+		case item.includeFile != "":
+			if err := p.ParseFile(item.includeFile); err != nil {
+				return err
+			}
+		case item.includeDir != "":
+			if err := p.ParseDir(item.includeDir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy of an unresolved object. Template resolution
+// mutates Attrs and CustomVars in place, so cached objects are never handed
+// out directly.
+func (t *TemplateObject) clone() *TemplateObject {
+	return &TemplateObject{
+		Type:       t.Type,
+		Attrs:      copyMap(t.Attrs),
+		CustomVars: copyMap(t.CustomVars),
+		File:       t.File,
+		Line:       t.Line,
+		Resolved:   t.Resolved,
+
+		Comments:     t.Comments,
+		AttrComments: t.AttrComments,
+	}
+}
@@ -0,0 +1,742 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Attribute describes a main config directive or an object attribute. The
+// tables below list everything the parser reads; they back the schema
+// export and the checks for unknown names.
+type Attribute struct {
+	Name string
+	// Type is string, path, boolean, integer, number, char, mode (octal
+	// file mode) or list (comma-separated).
+	Type string
+	// Repeatable directives may be given more than once; every value is
+	// kept.
+	Repeatable bool
+	// Values lists the accepted values, when they are restricted.
+	Values []string
+	// Default is the value used when the directive or attribute is absent,
+	// or nil when there is none or it depends on other attributes.
+	Default interface{}
+	// Deprecated says why the name should not be used and what to use
+	// instead; empty for current names.
+	Deprecated string
+
+	field string // MainConfig field a main directive sets
+}
+
+// mainDirectives lists the nagios.cfg directives setDirective handles, in
+// the same order.
+var mainDirectives = []Attribute{
+	// Paths (multi-value)
+	{Name: "cfg_file", Type: "path", Repeatable: true, field: "CfgFiles"},
+	{Name: "cfg_dir", Type: "path", Repeatable: true, field: "CfgDirs"},
+	{Name: "resource_file", Type: "path", Repeatable: true, field: "ResourceFiles"},
+	{Name: "broker_module", Type: "string", Repeatable: true, Deprecated: "ignored: event broker modules are not supported", field: "BrokerModules"},
+	// Paths (single)
+	{Name: "log_file", Type: "path", field: "LogFile"},
+	{Name: "status_file", Type: "path", field: "StatusFile"},
+	{Name: "state_retention_file", Type: "path", field: "StateRetentionFile"},
+	{Name: "object_cache_file", Type: "path", field: "ObjectCacheFile"},
+	{Name: "precached_object_file", Type: "path", field: "PrecachedObjectFile"},
+	{Name: "temp_file", Type: "path", field: "TempFile"},
+	{Name: "temp_path", Type: "path", field: "TempPath"},
+	{Name: "check_result_path", Type: "path", field: "CheckResultPath"},
+	{Name: "lock_file", Type: "path", field: "LockFile"},
+	{Name: "log_archive_path", Type: "path", field: "LogArchivePath"},
+	{Name: "command_file", Type: "path", field: "CommandFile"},
+	{Name: "debug_file", Type: "path", field: "DebugFile"},
+	{Name: "host_perfdata_file", Type: "path", field: "HostPerfdataFile"},
+	{Name: "service_perfdata_file", Type: "path", field: "ServicePerfdataFile"},
+	{Name: "query_socket", Type: "path", field: "QuerySocket"},
+	{Name: "livestatus_tcp", Type: "string", field: "LivestatusTCP"},
+	// NRDP
+	{Name: "nrdp_listen", Type: "string", field: "NRDPListen"},
+	{Name: "nrdp_path", Type: "string", field: "NRDPPath"},
+	{Name: "nrdp_token_hash", Type: "string", field: "NRDPTokenHash"},
+	{Name: "nrdp_dynamic_enabled", Type: "boolean", field: "NRDPDynamicEnabled"},
+	{Name: "nrdp_dynamic_ttl", Type: "integer", field: "NRDPDynamicTTL"},
+	{Name: "nrdp_dynamic_prune_interval", Type: "integer", field: "NRDPDynamicPrune"},
+	{Name: "nrdp_dynamic_host_check_command", Type: "string", field: "NRDPDynamicHostCheckCommand"},
+	{Name: "nrdp_dynamic_config_file", Type: "path", field: "NRDPDynamicConfigFile"},
+	{Name: "nrdp_ssl_cert", Type: "path", field: "NRDPSSLCert"},
+	{Name: "nrdp_ssl_key", Type: "path", field: "NRDPSSLKey"},
+	{Name: "nrdp_sender_stale_threshold", Type: "integer", field: "NRDPSenderStaleThreshold"},
+	{Name: "nrdp_expected_senders", Type: "list", field: "NRDPExpectedSenders"},
+	// Check executors
+	{Name: "check_executor", Type: "string", Values: []string{"local", "ssh", "agent"}, field: "CheckExecutor"},
+	{Name: "check_executor_route", Type: "string", Repeatable: true, field: "CheckExecutorRoutes"},
+	{Name: "ssh_executor_user", Type: "string", field: "SSHUser"},
+	{Name: "ssh_executor_port", Type: "integer", field: "SSHPort"},
+	{Name: "ssh_executor_key_file", Type: "path", field: "SSHKeyFile"},
+	{Name: "ssh_executor_known_hosts_file", Type: "path", field: "SSHKnownHostsFile"},
+	{Name: "ssh_executor_insecure_ignore_host_key", Type: "boolean", field: "SSHInsecureIgnoreHostKey"},
+	{Name: "ssh_executor_connect_timeout", Type: "integer", field: "SSHConnectTimeout"},
+	{Name: "native_check_by_ssh", Type: "boolean", field: "NativeCheckBySSH"},
+	{Name: "agent_executor_port", Type: "integer", field: "AgentPort"},
+	{Name: "agent_executor_token_file", Type: "path", field: "AgentTokenFile"},
+	{Name: "agent_executor_tls", Type: "boolean", field: "AgentTLS"},
+	{Name: "agent_executor_ca_file", Type: "path", field: "AgentCAFile"},
+	// Event bus publisher
+	{Name: "event_publisher", Type: "string", Values: []string{"nats", "kafka"}, field: "EventPublisher"},
+	{Name: "event_publisher_url", Type: "string", field: "EventPublisherURL"},
+	{Name: "event_publisher_subject_prefix", Type: "string", field: "EventPublisherPrefix"},
+	{Name: "event_publisher_format", Type: "string", Values: []string{"json", "protobuf"}, field: "EventPublisherFormat"},
+	// Alert forwarding
+	{Name: "alert_forward_target", Type: "string", field: "AlertForwardTarget"},
+	{Name: "alert_forward_format", Type: "string", Values: []string{"syslog", "json"}, field: "AlertForwardFormat"},
+	{Name: "alert_forward_buffer", Type: "integer", field: "AlertForwardBuffer"},
+	// Simulation mode
+	{Name: "simulation_mode", Type: "boolean", field: "SimulationMode"},
+	{Name: "simulation_warning_rate", Type: "number", field: "SimulationWarningRate"},
+	{Name: "simulation_critical_rate", Type: "number", field: "SimulationCriticalRate"},
+	{Name: "simulation_unknown_rate", Type: "number", field: "SimulationUnknownRate"},
+	{Name: "simulation_host_down_rate", Type: "number", field: "SimulationHostDownRate"},
+	{Name: "simulation_recovery_rate", Type: "number", field: "SimulationRecoveryRate"},
+	{Name: "simulation_latency", Type: "string", Values: []string{"fixed", "uniform", "normal", "exponential"}, field: "SimulationLatency"},
+	{Name: "simulation_latency_mean", Type: "integer", field: "SimulationLatencyMean"},
+	{Name: "simulation_latency_stddev", Type: "integer", field: "SimulationLatencyStdDev"},
+	// gRPC admin API
+	{Name: "grpc_admin_listen", Type: "string", field: "GRPCAdminListen"},
+	{Name: "grpc_admin_token_hash", Type: "string", field: "GRPCAdminTokenHash"},
+	{Name: "grpc_admin_ssl_cert", Type: "path", field: "GRPCAdminSSLCert"},
+	{Name: "grpc_admin_ssl_key", Type: "path", field: "GRPCAdminSSLKey"},
+	// REST/JSON API
+	{Name: "rest_listen", Type: "string", field: "RESTListen"},
+	{Name: "rest_token_hash", Type: "string", field: "RESTTokenHash"},
+	{Name: "rest_ssl_cert", Type: "path", field: "RESTSSLCert"},
+	{Name: "rest_ssl_key", Type: "path", field: "RESTSSLKey"},
+	{Name: "importance_scheduling", Type: "boolean", field: "ImportanceScheduling"},
+	{Name: "debug_listen", Type: "string", field: "DebugListen"},
+	{Name: "result_queue_size", Type: "integer", field: "ResultQueueSize"},
+	{Name: "result_spill_file", Type: "path", field: "ResultSpillFile"},
+	{Name: "result_spill_max_size", Type: "integer", field: "ResultSpillMaxSize"},
+	{Name: "self_check", Type: "boolean", field: "SelfCheck"},
+	{Name: "self_check_host_name", Type: "string", field: "SelfCheckHostName"},
+	{Name: "self_check_interval", Type: "integer", field: "SelfCheckInterval"},
+	{Name: "self_check_contact_groups", Type: "list", field: "SelfCheckContactGroups"},
+	{Name: "query_socket_mode", Type: "mode", field: "QuerySocketMode"},
+	{Name: "query_socket_group", Type: "string", field: "QuerySocketGroup"},
+	{Name: "command_file_mode", Type: "mode", field: "CommandFileMode"},
+	{Name: "command_file_group", Type: "string", field: "CommandFileGroup"},
+	{Name: "command_socket", Type: "path", field: "CommandSocket"},
+	{Name: "command_tcp_listen", Type: "string", field: "CommandTCPListen"},
+	{Name: "command_http_listen", Type: "string", field: "CommandHTTPListen"},
+	{Name: "command_token_hash", Type: "string", field: "CommandTokenHash"},
+	{Name: "command_response_dir", Type: "path", field: "CommandResponseDir"},
+	{Name: "check_output_sanitization", Type: "string", Values: []string{"replace", "strip", "off"}, field: "CheckOutputSanitization"},
+	{Name: "host_check_period_default", Type: "string", field: "ObjectDefaults.HostCheckPeriod"},
+	{Name: "host_notification_period_default", Type: "string", field: "ObjectDefaults.HostNotificationPeriod"},
+	{Name: "host_contacts_default", Type: "string", field: "ObjectDefaults.HostContacts"},
+	{Name: "host_contact_groups_default", Type: "string", field: "ObjectDefaults.HostContactGroups"},
+	{Name: "service_check_period_default", Type: "string", field: "ObjectDefaults.ServiceCheckPeriod"},
+	{Name: "service_notification_period_default", Type: "string", field: "ObjectDefaults.ServiceNotificationPeriod"},
+	{Name: "service_contacts_default", Type: "string", field: "ObjectDefaults.ServiceContacts"},
+	{Name: "service_contact_groups_default", Type: "string", field: "ObjectDefaults.ServiceContactGroups"},
+	{Name: "host_no_check_state", Type: "string", Values: []string{"up", "pending", "services"}, field: "HostNoCheckState"},
+	{Name: "host_state_from_services", Type: "integer", field: "HostStateFromServices"},
+	{Name: "check_rlimit_cpu", Type: "integer", field: "CheckRlimitCPU"},
+	{Name: "check_rlimit_memory", Type: "integer", field: "CheckRlimitMemory"},
+	{Name: "check_rlimit_nofile", Type: "integer", field: "CheckRlimitNoFile"},
+	{Name: "check_cgroup", Type: "path", field: "CheckCgroup"},
+	{Name: "exit_code_map", Type: "string", field: "ExitCodeMap"},
+	{Name: "check_concurrency_classes", Type: "string", field: "CheckConcurrencyClasses"},
+	{Name: "heartbeat_file", Type: "path", field: "HeartbeatFile"},
+	{Name: "heartbeat_interval", Type: "integer", field: "HeartbeatInterval"},
+	{Name: "max_downtime_duration", Type: "integer", field: "MaxDowntimeDuration"},
+	{Name: "downtime_no_overlap", Type: "boolean", field: "DowntimeNoOverlap"},
+	{Name: "host_digest_line", Type: "string", field: "HostDigestLine"},
+	{Name: "service_digest_line", Type: "string", field: "ServiceDigestLine"},
+	{Name: "ack_notify_all_escalations", Type: "boolean", field: "AckNotifyAllEscalations"},
+	{Name: "notification_hourly_cap", Type: "integer", field: "NotificationHourlyCap"},
+	{Name: "notification_overflow_contactgroup", Type: "string", field: "NotificationOverflowContactGroup"},
+	{Name: "check_history_size", Type: "integer", field: "CheckHistorySize"},
+	{Name: "strict_config", Type: "boolean", field: "StrictConfig"},
+	// Permissions
+	{Name: "nagios_user", Type: "string", field: "NagiosUser"},
+	{Name: "nagios_group", Type: "string", field: "NagiosGroup"},
+	// Strings
+	{Name: "global_host_event_handler", Type: "string", field: "GlobalHostEventHandler"},
+	{Name: "global_service_event_handler", Type: "string", field: "GlobalServiceEventHandler"},
+	{Name: "ocsp_command", Type: "string", field: "OCSPCommand"},
+	{Name: "ochp_command", Type: "string", field: "OCHPCommand"},
+	{Name: "host_perfdata_command", Type: "string", field: "HostPerfdataCommand"},
+	{Name: "service_perfdata_command", Type: "string", field: "ServicePerfdataCommand"},
+	{Name: "host_perfdata_file_template", Type: "string", field: "HostPerfdataFileTemplate"},
+	{Name: "service_perfdata_file_template", Type: "string", field: "ServicePerfdataFileTemplate"},
+	{Name: "host_perfdata_file_processing_command", Type: "string", field: "HostPerfdataFileProcessingCommand"},
+	{Name: "service_perfdata_file_processing_command", Type: "string", field: "ServicePerfdataFileProcessingCommand"},
+	{Name: "date_format", Type: "string", field: "DateFormat"},
+	{Name: "use_timezone", Type: "string", field: "UseTimezone"},
+	{Name: "illegal_object_name_chars", Type: "string", field: "IllegalObjectNameChars"},
+	{Name: "illegal_macro_output_chars", Type: "string", field: "IllegalMacroOutputChars"},
+	{Name: "admin_email", Type: "string", field: "AdminEmail"},
+	{Name: "admin_pager", Type: "string", field: "AdminPager"},
+	{Name: "service_inter_check_delay_method", Type: "string", field: "ServiceInterCheckDelayMethod"},
+	{Name: "host_inter_check_delay_method", Type: "string", field: "HostInterCheckDelayMethod"},
+	{Name: "service_interleave_factor", Type: "string", field: "ServiceInterleaveFactor"},
+	{Name: "loadctl_options", Type: "string", field: "LoadctlOptions"},
+	// Booleans
+	{Name: "use_syslog", Type: "boolean", field: "UseSyslog"},
+	{Name: "log_notifications", Type: "boolean", field: "LogNotifications"},
+	{Name: "log_service_retries", Type: "boolean", field: "LogServiceRetries"},
+	{Name: "log_host_retries", Type: "boolean", field: "LogHostRetries"},
+	{Name: "log_event_handlers", Type: "boolean", field: "LogEventHandlers"},
+	{Name: "log_external_commands", Type: "boolean", field: "LogExternalCommands"},
+	{Name: "log_passive_checks", Type: "boolean", field: "LogPassiveChecks"},
+	{Name: "log_initial_states", Type: "boolean", field: "LogInitialStates"},
+	{Name: "log_current_states", Type: "boolean", field: "LogCurrentStates"},
+	{Name: "retain_state_information", Type: "boolean", field: "RetainStateInformation"},
+	{Name: "use_retained_program_state", Type: "boolean", field: "UseRetainedProgramState"},
+	{Name: "use_retained_scheduling_info", Type: "boolean", field: "UseRetainedSchedulingInfo"},
+	{Name: "execute_service_checks", Type: "boolean", field: "ExecuteServiceChecks"},
+	{Name: "accept_passive_service_checks", Type: "boolean", field: "AcceptPassiveServiceChecks"},
+	{Name: "execute_host_checks", Type: "boolean", field: "ExecuteHostChecks"},
+	{Name: "accept_passive_host_checks", Type: "boolean", field: "AcceptPassiveHostChecks"},
+	{Name: "enable_event_handlers", Type: "boolean", field: "EnableEventHandlers"},
+	{Name: "enable_notifications", Type: "boolean", field: "EnableNotifications"},
+	{Name: "enable_flap_detection", Type: "boolean", field: "EnableFlapDetection"},
+	{Name: "process_performance_data", Type: "boolean", field: "ProcessPerformanceData"},
+	{Name: "obsess_over_services", Type: "boolean", field: "ObsessOverServices"},
+	{Name: "obsess_over_hosts", Type: "boolean", field: "ObsessOverHosts"},
+	{Name: "check_for_orphaned_services", Type: "boolean", field: "CheckForOrphanedServices"},
+	{Name: "check_for_orphaned_hosts", Type: "boolean", field: "CheckForOrphanedHosts"},
+	{Name: "check_service_freshness", Type: "boolean", field: "CheckServiceFreshness"},
+	{Name: "check_host_freshness", Type: "boolean", field: "CheckHostFreshness"},
+	{Name: "check_external_commands", Type: "boolean", field: "CheckExternalCommands"},
+	{Name: "check_for_updates", Type: "boolean", Deprecated: "ignored: Gogios does not check for updates", field: "CheckForUpdates"},
+	{Name: "bare_update_check", Type: "boolean", Deprecated: "ignored: Gogios does not check for updates", field: "BareUpdateCheck"},
+	{Name: "auto_reschedule_checks", Type: "boolean", field: "AutoRescheduleChecks"},
+	{Name: "use_aggressive_host_checking", Type: "boolean", field: "UseAggressiveHostChecking"},
+	{Name: "soft_state_dependencies", Type: "boolean", field: "SoftStateDependencies"},
+	{Name: "translate_passive_host_checks", Type: "boolean", field: "TranslatePassiveHostChecks"},
+	{Name: "passive_host_checks_are_soft", Type: "boolean", field: "PassiveHostChecksAreSoft"},
+	{Name: "use_regexp_matching", Type: "boolean", field: "UseRegexpMatching"},
+	{Name: "use_true_regexp_matching", Type: "boolean", field: "UseTrueRegexpMatching"},
+	{Name: "daemon_dumps_core", Type: "boolean", field: "DaemonDumpsCore"},
+	{Name: "use_large_installation_tweaks", Type: "boolean", field: "UseLargeInstallationTweaks"},
+	{Name: "enable_environment_macros", Type: "boolean", field: "EnableEnvironmentMacros"},
+	{Name: "enable_predictive_host_dependency_checks", Type: "boolean", field: "EnablePredictiveHostDependencyChecks"},
+	{Name: "enable_predictive_service_dependency_checks", Type: "boolean", field: "EnablePredictiveServiceDependencyChecks"},
+	{Name: "allow_empty_hostgroup_assignment", Type: "boolean", field: "AllowEmptyHostgroupAssignment"},
+	{Name: "host_perfdata_process_empty_results", Type: "boolean", field: "HostPerfdataProcessEmptyResults"},
+	{Name: "service_perfdata_process_empty_results", Type: "boolean", field: "ServicePerfdataProcessEmptyResults"},
+	// Ints
+	{Name: "service_check_timeout", Type: "integer", field: "ServiceCheckTimeout"},
+	{Name: "host_check_timeout", Type: "integer", field: "HostCheckTimeout"},
+	{Name: "event_handler_timeout", Type: "integer", field: "EventHandlerTimeout"},
+	{Name: "notification_timeout", Type: "integer", field: "NotificationTimeout"},
+	{Name: "ocsp_timeout", Type: "integer", field: "OCSPTimeout"},
+	{Name: "ochp_timeout", Type: "integer", field: "OCHPTimeout"},
+	{Name: "perfdata_timeout", Type: "integer", field: "PerfdataTimeout"},
+	{Name: "notification_workers", Type: "integer", field: "NotificationWorkers"},
+	{Name: "max_concurrent_checks", Type: "integer", field: "MaxConcurrentChecks"},
+	{Name: "check_workers", Type: "integer", field: "CheckWorkers"},
+	{Name: "interval_length", Type: "integer", field: "IntervalLength"},
+	{Name: "max_service_check_spread", Type: "integer", field: "MaxServiceCheckSpread"},
+	{Name: "max_host_check_spread", Type: "integer", field: "MaxHostCheckSpread"},
+	{Name: "check_result_reaper_frequency", Type: "integer", field: "CheckResultReaperFrequency"},
+	{Name: "max_check_result_reaper_time", Type: "integer", field: "MaxCheckResultReaperTime"},
+	{Name: "auto_rescheduling_interval", Type: "integer", field: "AutoReschedulingInterval"},
+	{Name: "auto_rescheduling_window", Type: "integer", field: "AutoReschedulingWindow"},
+	{Name: "retention_update_interval", Type: "integer", field: "RetentionUpdateInterval"},
+	{Name: "retention_shards", Type: "integer", field: "RetentionShards"},
+	{Name: "retention_scheduling_horizon", Type: "integer", field: "RetentionSchedulingHorizon"},
+	{Name: "status_update_interval", Type: "integer", field: "StatusUpdateInterval"},
+	{Name: "additional_freshness_latency", Type: "integer", field: "AdditionalFreshnessLatency"},
+	{Name: "service_freshness_check_interval", Type: "integer", field: "ServiceFreshnessCheckInterval"},
+	{Name: "host_freshness_check_interval", Type: "integer", field: "HostFreshnessCheckInterval"},
+	{Name: "debug_level", Type: "integer", field: "DebugLevel"},
+	{Name: "debug_verbosity", Type: "integer", field: "DebugVerbosity"},
+	{Name: "event_broker_options", Type: "integer", Deprecated: "ignored: event broker modules are not supported", field: "EventBrokerOptions"},
+	{Name: "free_child_process_memory", Type: "integer", Deprecated: "ignored: checks do not fork the daemon", field: "FreeChildProcessMemory"},
+	{Name: "child_processes_fork_twice", Type: "integer", Deprecated: "ignored: checks do not fork the daemon", field: "ChildProcessesForkTwice"},
+	{Name: "time_change_threshold", Type: "integer", field: "TimeChangeThreshold"},
+	// Unsigned ints
+	{Name: "max_debug_file_size", Type: "integer", field: "MaxDebugFileSize"},
+	{Name: "max_log_file_size", Type: "integer", field: "MaxLogFileSize"},
+	{Name: "max_check_result_file_age", Type: "integer", field: "MaxCheckResultFileAge"},
+	{Name: "cached_host_check_horizon", Type: "integer", field: "CachedHostCheckHorizon"},
+	{Name: "cached_service_check_horizon", Type: "integer", field: "CachedServiceCheckHorizon"},
+	{Name: "retained_host_attribute_mask", Type: "integer", field: "RetainedHostAttributeMask"},
+	{Name: "retained_service_attribute_mask", Type: "integer", field: "RetainedServiceAttributeMask"},
+	{Name: "retained_process_host_attribute_mask", Type: "integer", field: "RetainedProcessHostAttributeMask"},
+	{Name: "retained_process_service_attribute_mask", Type: "integer", field: "RetainedProcessServiceAttributeMask"},
+	{Name: "retained_contact_host_attribute_mask", Type: "integer", field: "RetainedContactHostAttributeMask"},
+	{Name: "retained_contact_service_attribute_mask", Type: "integer", field: "RetainedContactServiceAttributeMask"},
+	{Name: "host_perfdata_file_processing_interval", Type: "integer", field: "HostPerfdataFileProcessingInterval"},
+	{Name: "service_perfdata_file_processing_interval", Type: "integer", field: "ServicePerfdataFileProcessingInterval"},
+	{Name: "host_down_disable_service_checks", Type: "integer", field: "HostDownDisableServiceChecks"},
+	// Floats
+	{Name: "low_service_flap_threshold", Type: "number", field: "LowServiceFlapThreshold"},
+	{Name: "high_service_flap_threshold", Type: "number", field: "HighServiceFlapThreshold"},
+	{Name: "low_host_flap_threshold", Type: "number", field: "LowHostFlapThreshold"},
+	{Name: "high_host_flap_threshold", Type: "number", field: "HighHostFlapThreshold"},
+	// Char
+	{Name: "log_rotation_method", Type: "char", Values: []string{"n", "h", "d", "w", "m"}, field: "LogRotationMethod"},
+	{Name: "service_check_timeout_state", Type: "char", Values: []string{"c", "w", "u", "o"}, field: "ServiceCheckTimeoutState"},
+	{Name: "host_perfdata_file_mode", Type: "char", Values: []string{"a", "w", "p"}, field: "HostPerfdataFileMode"},
+	{Name: "service_perfdata_file_mode", Type: "char", Values: []string{"a", "w", "p"}, field: "ServicePerfdataFileMode"},
+}
+
+// objectAttributes lists the attributes each object type reads, besides
+// use, name, register and custom variables.
+var objectAttributes = map[string][]Attribute{
+	"command": {
+		{Name: "command_name", Type: "string"},
+		{Name: "command_line", Type: "string"},
+		{Name: "concurrency_class", Type: "string"},
+	},
+	"timeperiod": {
+		{Name: "timeperiod_name", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "exclude", Type: "list"},
+	},
+	"contact": {
+		{Name: "contact_name", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "contactgroups", Type: "list"},
+		{Name: "email", Type: "string"},
+		{Name: "pager", Type: "string"},
+		{Name: "host_notifications_enabled", Type: "boolean", Default: true},
+		{Name: "service_notifications_enabled", Type: "boolean", Default: true},
+		{Name: "host_notification_period", Type: "string"},
+		{Name: "service_notification_period", Type: "string"},
+		{Name: "host_notification_options", Type: "list"},
+		{Name: "service_notification_options", Type: "list"},
+		{Name: "host_notification_commands", Type: "list"},
+		{Name: "service_notification_commands", Type: "list"},
+		{Name: "host_notification_fallback", Type: "string"},
+		{Name: "service_notification_fallback", Type: "string"},
+		{Name: "notification_digest", Type: "integer", Default: 0},
+		{Name: "minimum_importance", Type: "integer", Default: 0},
+		{Name: "can_submit_commands", Type: "boolean", Default: true},
+		{Name: "retain_status_information", Type: "boolean", Default: true},
+		{Name: "retain_nonstatus_information", Type: "boolean", Default: true},
+	},
+	"contactgroup": {
+		{Name: "contactgroup_name", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "members", Type: "list"},
+		{Name: "contactgroup_members", Type: "list"},
+		{Name: "notification_digest", Type: "integer", Default: 0},
+	},
+	"host": {
+		{Name: "host_name", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "display_name", Type: "string"},
+		{Name: "address", Type: "string"},
+		{Name: "parents", Type: "list"},
+		{Name: "hostgroups", Type: "list"},
+		{Name: "check_command", Type: "string"},
+		{Name: "initial_state", Type: "char", Values: []string{"o", "d", "u"}, Default: "o"},
+		{Name: "max_check_attempts", Type: "integer"},
+		{Name: "check_interval", Type: "number", Default: 5.0},
+		{Name: "retry_interval", Type: "number", Default: 1.0},
+		{Name: "active_checks_enabled", Type: "boolean", Default: true},
+		{Name: "passive_checks_enabled", Type: "boolean", Default: true},
+		{Name: "check_period", Type: "string"},
+		{Name: "obsess_over_host", Type: "boolean", Default: true},
+		{Name: "check_freshness", Type: "boolean", Default: false},
+		{Name: "freshness_threshold", Type: "integer", Default: 0},
+		{Name: "event_handler", Type: "string"},
+		{Name: "event_handler_enabled", Type: "boolean", Default: true},
+		{Name: "low_flap_threshold", Type: "number", Default: 0.0},
+		{Name: "high_flap_threshold", Type: "number", Default: 0.0},
+		{Name: "flap_detection_enabled", Type: "boolean", Default: true},
+		{Name: "flap_detection_options", Type: "list"},
+		{Name: "process_perf_data", Type: "boolean", Default: true},
+		{Name: "retain_status_information", Type: "boolean", Default: true},
+		{Name: "retain_nonstatus_information", Type: "boolean", Default: true},
+		{Name: "contacts", Type: "list"},
+		{Name: "contact_groups", Type: "list"},
+		{Name: "notification_interval", Type: "number", Default: 30.0},
+		{Name: "first_notification_delay", Type: "number", Default: 0.0},
+		{Name: "notification_period", Type: "string"},
+		{Name: "notification_options", Type: "list"},
+		{Name: "notifications_enabled", Type: "boolean", Default: true},
+		{Name: "stalking_options", Type: "list"},
+		{Name: "hourly_value", Type: "integer", Default: 0},
+		{Name: "notes", Type: "string"},
+		{Name: "notes_url", Type: "string"},
+		{Name: "action_url", Type: "string"},
+		{Name: "icon_image", Type: "string"},
+		{Name: "icon_image_alt", Type: "string"},
+		{Name: "vrml_image", Type: "string"},
+		{Name: "statusmap_image", Type: "string"},
+		{Name: "2d_coords", Type: "string"},
+		{Name: "3d_coords", Type: "string"},
+		{Name: "tags", Type: "list"},
+	},
+	"hostgroup": {
+		{Name: "hostgroup_name", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "members", Type: "list"},
+		{Name: "hostgroup_members", Type: "list"},
+		{Name: "notes", Type: "string"},
+		{Name: "notes_url", Type: "string"},
+		{Name: "action_url", Type: "string"},
+	},
+	"service": {
+		{Name: "host_name", Type: "list"},
+		{Name: "hostgroup_name", Type: "list"},
+		{Name: "service_description", Type: "string"},
+		{Name: "display_name", Type: "string"},
+		{Name: "servicegroups", Type: "list"},
+		{Name: "is_volatile", Type: "boolean", Default: false},
+		{Name: "check_command", Type: "string"},
+		{Name: "initial_state", Type: "char", Values: []string{"o", "w", "u", "c"}, Default: "o"},
+		{Name: "max_check_attempts", Type: "integer"},
+		{Name: "check_interval", Type: "number", Default: 5.0},
+		{Name: "retry_interval", Type: "number", Default: 1.0},
+		{Name: "active_checks_enabled", Type: "boolean", Default: true},
+		{Name: "passive_checks_enabled", Type: "boolean", Default: true},
+		{Name: "parallelize_check", Type: "boolean", Default: true},
+		{Name: "check_period", Type: "string"},
+		{Name: "obsess_over_service", Type: "boolean", Default: false},
+		{Name: "check_freshness", Type: "boolean", Default: false},
+		{Name: "freshness_threshold", Type: "integer", Default: 0},
+		{Name: "event_handler", Type: "string"},
+		{Name: "event_handler_enabled", Type: "boolean", Default: true},
+		{Name: "low_flap_threshold", Type: "number", Default: 0.0},
+		{Name: "high_flap_threshold", Type: "number", Default: 0.0},
+		{Name: "flap_detection_enabled", Type: "boolean", Default: true},
+		{Name: "flap_detection_options", Type: "list"},
+		{Name: "process_perf_data", Type: "boolean", Default: true},
+		{Name: "retain_status_information", Type: "boolean", Default: true},
+		{Name: "retain_nonstatus_information", Type: "boolean", Default: true},
+		{Name: "notification_interval", Type: "number", Default: 30.0},
+		{Name: "first_notification_delay", Type: "number", Default: 0.0},
+		{Name: "notification_period", Type: "string"},
+		{Name: "notification_options", Type: "list"},
+		{Name: "notifications_enabled", Type: "boolean", Default: true},
+		{Name: "contacts", Type: "list"},
+		{Name: "contact_groups", Type: "list"},
+		{Name: "stalking_options", Type: "list"},
+		{Name: "hourly_value", Type: "integer", Default: 0},
+		{Name: "notes", Type: "string"},
+		{Name: "notes_url", Type: "string"},
+		{Name: "action_url", Type: "string"},
+		{Name: "icon_image", Type: "string"},
+		{Name: "icon_image_alt", Type: "string"},
+		{Name: "tags", Type: "list"},
+	},
+	"servicegroup": {
+		{Name: "servicegroup_name", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "members", Type: "list"},
+		{Name: "servicegroup_members", Type: "list"},
+		{Name: "notes", Type: "string"},
+		{Name: "notes_url", Type: "string"},
+		{Name: "action_url", Type: "string"},
+	},
+	"hostdependency": {
+		{Name: "host_name", Type: "list"},
+		{Name: "hostgroup_name", Type: "list"},
+		{Name: "dependent_host_name", Type: "list"},
+		{Name: "dependent_hostgroup_name", Type: "list"},
+		{Name: "inherits_parent", Type: "boolean", Default: false},
+		{Name: "execution_failure_options", Type: "list"},
+		{Name: "notification_failure_options", Type: "list"},
+		{Name: "dependency_period", Type: "string"},
+	},
+	"servicedependency": {
+		{Name: "host_name", Type: "list"},
+		{Name: "hostgroup_name", Type: "list"},
+		{Name: "service_description", Type: "string"},
+		{Name: "servicegroup_name", Type: "list", Deprecated: "ignored: servicegroup dependencies are not supported"},
+		{Name: "dependent_host_name", Type: "list"},
+		{Name: "dependent_hostgroup_name", Type: "list"},
+		{Name: "dependent_service_description", Type: "string"},
+		{Name: "dependent_servicegroup_name", Type: "list", Deprecated: "ignored: servicegroup dependencies are not supported"},
+		{Name: "inherits_parent", Type: "boolean", Default: false},
+		{Name: "execution_failure_options", Type: "list"},
+		{Name: "notification_failure_options", Type: "list"},
+		{Name: "dependency_period", Type: "string"},
+	},
+	"hostescalation": {
+		{Name: "host_name", Type: "list"},
+		{Name: "hostgroup_name", Type: "list"},
+		{Name: "contacts", Type: "list"},
+		{Name: "contact_groups", Type: "list"},
+		{Name: "first_notification", Type: "integer"},
+		{Name: "last_notification", Type: "integer"},
+		{Name: "notification_interval", Type: "number"},
+		{Name: "escalation_period", Type: "string"},
+		{Name: "escalation_options", Type: "list"},
+		{Name: "dynamic_groups", Type: "boolean", Default: false},
+	},
+	"serviceescalation": {
+		{Name: "host_name", Type: "list"},
+		{Name: "hostgroup_name", Type: "list"},
+		{Name: "service_description", Type: "string"},
+		{Name: "servicegroup_name", Type: "list"},
+		{Name: "contacts", Type: "list"},
+		{Name: "contact_groups", Type: "list"},
+		{Name: "first_notification", Type: "integer"},
+		{Name: "last_notification", Type: "integer"},
+		{Name: "notification_interval", Type: "number"},
+		{Name: "escalation_period", Type: "string"},
+		{Name: "escalation_options", Type: "list"},
+		{Name: "dynamic_groups", Type: "boolean", Default: false},
+	},
+	"blackout": {
+		{Name: "blackout_name", Type: "string"},
+		{Name: "host_name", Type: "list"},
+		{Name: "hostgroup_name", Type: "list"},
+		{Name: "service_description", Type: "list"},
+		{Name: "servicegroup_name", Type: "list"},
+		{Name: "blackout_period", Type: "string"},
+		{Name: "comment", Type: "string"},
+	},
+	"inhibit": {
+		{Name: "inhibit_name", Type: "string"},
+		{Name: "source_service_description", Type: "string"},
+		{Name: "source_states", Type: "list"},
+		{Name: "target_service_description", Type: "string"},
+		{Name: "host_name", Type: "list"},
+		{Name: "hostgroup_name", Type: "list"},
+		{Name: "comment", Type: "string"},
+	},
+}
+
+// templateAttributes are read for every object type.
+var templateAttributes = []Attribute{
+	{Name: "use", Type: "list"},
+	{Name: "name", Type: "string"},
+	{Name: "register", Type: "boolean", Default: true},
+}
+
+// attributeAliases maps alternative attribute names to the canonical ones,
+// per object type. The parser stores aliased attributes under the
+// canonical name.
+var attributeAliases = map[string]map[string]string{
+	"host": {
+		"obsess":     "obsess_over_host",
+		"importance": "hourly_value",
+	},
+	"service": {
+		"obsess":      "obsess_over_service",
+		"importance":  "hourly_value",
+		"description": "service_description",
+	},
+	"contact": {
+		"contact_groups": "contactgroups",
+		"minimum_value":  "minimum_importance",
+	},
+	"hostdependency": {
+		"host":                          "host_name",
+		"master_host":                   "host_name",
+		"master_host_name":              "host_name",
+		"dependent_host":                "dependent_host_name",
+		"hostgroup":                     "hostgroup_name",
+		"hostgroups":                    "hostgroup_name",
+		"dependent_hostgroup":           "dependent_hostgroup_name",
+		"dependent_hostgroups":          "dependent_hostgroup_name",
+		"execution_failure_criteria":    "execution_failure_options",
+		"notification_failure_criteria": "notification_failure_options",
+	},
+	"servicedependency": {
+		"host":                          "host_name",
+		"master_host":                   "host_name",
+		"master_host_name":              "host_name",
+		"description":                   "service_description",
+		"master_description":            "service_description",
+		"master_service_description":    "service_description",
+		"hostgroup":                     "hostgroup_name",
+		"hostgroups":                    "hostgroup_name",
+		"servicegroup":                  "servicegroup_name",
+		"servicegroups":                 "servicegroup_name",
+		"dependent_host":                "dependent_host_name",
+		"dependent_description":         "dependent_service_description",
+		"dependent_hostgroup":           "dependent_hostgroup_name",
+		"dependent_hostgroups":          "dependent_hostgroup_name",
+		"dependent_servicegroup":        "dependent_servicegroup_name",
+		"dependent_servicegroups":       "dependent_servicegroup_name",
+		"execution_failure_criteria":    "execution_failure_options",
+		"notification_failure_criteria": "notification_failure_options",
+	},
+	"hostescalation": {
+		"host":       "host_name",
+		"hostgroup":  "hostgroup_name",
+		"hostgroups": "hostgroup_name",
+	},
+	"serviceescalation": {
+		"host":          "host_name",
+		"description":   "service_description",
+		"hostgroup":     "hostgroup_name",
+		"hostgroups":    "hostgroup_name",
+		"servicegroup":  "servicegroup_name",
+		"servicegroups": "servicegroup_name",
+	},
+}
+
+// normalizeAlias maps attribute aliases to their canonical form.
+func normalizeAlias(objType, key string) string {
+	if canonical, ok := attributeAliases[objType][key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// ObjectTypes returns the object types that can be defined, in the order
+// Nagios documents them.
+func ObjectTypes() []string {
+	return []string{"host", "hostgroup", "service", "servicegroup", "contact", "contactgroup",
+		"timeperiod", "command", "servicedependency", "serviceescalation",
+		"hostdependency", "hostescalation", "blackout", "inhibit"}
+}
+
+// MainDirectives returns the nagios.cfg directives, with their defaults.
+func MainDirectives() []Attribute {
+	defaults := reflect.ValueOf(NewMainConfig()).Elem()
+	out := make([]Attribute, len(mainDirectives))
+	for i, d := range mainDirectives {
+		v := defaults
+		for _, name := range strings.Split(d.field, ".") {
+			v = v.FieldByName(name)
+		}
+		d.Default = directiveDefault(d, v)
+		out[i] = d
+	}
+	return out
+}
+
+// directiveDefault converts the MainConfig field v to the JSON value of
+// d's default, or nil for an unset string or list.
+func directiveDefault(d Attribute, v reflect.Value) interface{} {
+	switch {
+	case d.Type == "char":
+		if v.Uint() == 0 {
+			return nil
+		}
+		return string(rune(v.Uint()))
+	case d.Type == "mode":
+		return fmt.Sprintf("%04o", v.Uint())
+	case v.Kind() == reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+		return v.Interface()
+	case v.Kind() == reflect.String:
+		if v.String() == "" {
+			return nil
+		}
+	}
+	return v.Interface()
+}
+
+// ObjectAttributes returns the attributes of objType, including use, name,
+// register and the aliases, or nil for an unknown type. Open reports
+// whether other names are accepted too, as the day and date ranges of a
+// timeperiod are.
+func ObjectAttributes(objType string) (attrs []Attribute, open bool) {
+	own, ok := objectAttributes[objType]
+	if !ok {
+		return nil, false
+	}
+	attrs = append(append(attrs, templateAttributes...), own...)
+	for alias, canonical := range attributeAliases[objType] {
+		for _, a := range own {
+			if a.Name == canonical {
+				a.Name = alias
+				a.Default = nil
+				a.Deprecated = "alias of " + canonical
+				attrs = append(attrs, a)
+			}
+		}
+	}
+	aliases := attrs[len(templateAttributes)+len(own):]
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+	return attrs, objType == "timeperiod"
+}
+
+// Schema returns a JSON Schema (draft 2020-12) of the configuration: the
+// main config as an object of directives, and each object type as an
+// array of definitions. Attribute values are typed by meaning (booleans,
+// numbers, lists as arrays); Gogios-specific details are in x-gogios-*
+// keywords.
+func Schema() map[string]interface{} {
+	defs := map[string]interface{}{}
+	props := map[string]interface{}{}
+
+	mainProps := map[string]interface{}{}
+	for _, d := range MainDirectives() {
+		mainProps[d.Name] = attributeSchema(d)
+	}
+	defs["main"] = map[string]interface{}{
+		"type":                 "object",
+		"description":          "nagios.cfg directives",
+		"properties":           mainProps,
+		"additionalProperties": false,
+	}
+	props["main"] = map[string]interface{}{"$ref": "#/$defs/main"}
+
+	for _, typ := range ObjectTypes() {
+		attrs, open := ObjectAttributes(typ)
+		objProps := map[string]interface{}{}
+		for _, a := range attrs {
+			objProps[a.Name] = attributeSchema(a)
+		}
+		def := map[string]interface{}{
+			"type":              "object",
+			"description":       "define " + typ + " { ... }",
+			"properties":        objProps,
+			"patternProperties": map[string]interface{}{"^_": map[string]interface{}{"type": "string", "description": "custom variable"}},
+		}
+		if open {
+			def["additionalProperties"] = map[string]interface{}{"type": "string", "description": "time range"}
+		} else {
+			def["additionalProperties"] = false
+		}
+		defs[typ] = def
+		props[typ] = map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/$defs/" + typ}}
+	}
+
+	return map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      "Gogios configuration",
+		"type":       "object",
+		"properties": props,
+		"$defs":      defs,
+	}
+}
+
+func attributeSchema(a Attribute) map[string]interface{} {
+	s := map[string]interface{}{}
+	switch a.Type {
+	case "boolean":
+		s["type"] = "boolean"
+	case "integer":
+		s["type"] = "integer"
+	case "number":
+		s["type"] = "number"
+	case "list":
+		s["type"] = "array"
+		s["items"] = map[string]interface{}{"type": "string"}
+	default:
+		s["type"] = "string"
+	}
+	if a.Type != s["type"] && a.Type != "list" {
+		s["x-gogios-type"] = a.Type
+	}
+	if len(a.Values) > 0 {
+		s["enum"] = a.Values
+	}
+	if a.Default != nil {
+		s["default"] = a.Default
+	}
+	if a.Deprecated != "" {
+		s["deprecated"] = true
+		s["description"] = a.Deprecated
+	}
+	if a.Repeatable {
+		s = map[string]interface{}{"type": "array", "items": s, "x-gogios-repeatable": true}
+	}
+	return s
+}
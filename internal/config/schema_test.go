@@ -0,0 +1,158 @@
+package config
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestMainDirectivesMatchParser checks the directive table against the
+// cases of setDirective, and that each entry names the field its directive
+// sets.
+func TestMainDirectivesMatchParser(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "mainconfig.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "setDirective" {
+			return true
+		}
+		sw := fn.Body.List[0].(*ast.SwitchStmt)
+		for _, stmt := range sw.Body.List {
+			for _, e := range stmt.(*ast.CaseClause).List {
+				name, _ := strconv.Unquote(e.(*ast.BasicLit).Value)
+				cases = append(cases, name)
+			}
+		}
+		return false
+	})
+	var names []string
+	for _, d := range mainDirectives {
+		names = append(names, d.Name)
+	}
+	if !reflect.DeepEqual(cases, names) {
+		t.Fatalf("mainDirectives is out of sync with setDirective:\n cases: %v\n table: %v", cases, names)
+	}
+
+	samples := map[string][2]string{
+		"boolean": {"0", "1"}, "integer": {"7", "8"}, "number": {"7.5", "8.5"},
+		"mode": {"0600", "0640"}, "char": {"a", "b"},
+	}
+	for _, d := range MainDirectives() {
+		vals, ok := samples[d.Type]
+		switch {
+		case d.Name == "livestatus_tcp" || d.Name == "nrdp_listen":
+			vals = [2]string{"127.0.0.1:1", "127.0.0.1:2"}
+		case len(d.Values) >= 2:
+			vals = [2]string{d.Values[0], d.Values[1]}
+		case len(d.Values) == 1:
+			vals = [2]string{"", d.Values[0]}
+		case !ok:
+			vals = [2]string{"a", "b"}
+		}
+		var got [2]interface{}
+		for i, v := range vals {
+			c := NewMainConfig()
+			if err := c.setDirective(d.Name, v); err != nil {
+				t.Fatalf("%s=%s: %v", d.Name, v, err)
+			}
+			fv := reflect.ValueOf(c).Elem()
+			for _, name := range strings.Split(d.field, ".") {
+				fv = fv.FieldByName(name)
+			}
+			got[i] = fv.Interface()
+		}
+		if reflect.DeepEqual(got[0], got[1]) {
+			t.Errorf("%s: field %s is not set by the directive", d.Name, d.field)
+		}
+	}
+}
+
+// TestObjectAttributesMatchParser checks that every attribute name the
+// register functions read is in the attribute tables.
+func TestObjectAttributesMatchParser(t *testing.T) {
+	known := map[string]bool{}
+	for _, typ := range ObjectTypes() {
+		attrs, _ := ObjectAttributes(typ)
+		for _, a := range attrs {
+			known[a.Name] = true
+		}
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "expand.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var arg ast.Expr
+		switch fn := call.Fun.(type) {
+		case *ast.SelectorExpr:
+			if fn.Sel.Name == "Get" && len(call.Args) == 1 {
+				if id, ok := fn.X.(*ast.Ident); ok && id.Name == "obj" {
+					arg = call.Args[0]
+				}
+			}
+		case *ast.Ident:
+			if strings.HasPrefix(fn.Name, "attr") && len(call.Args) >= 2 {
+				arg = call.Args[1]
+			}
+		}
+		if lit, ok := arg.(*ast.BasicLit); ok {
+			name, _ := strconv.Unquote(lit.Value)
+			if !known[name] {
+				t.Errorf("attribute %q is read by the parser but missing from objectAttributes", name)
+			}
+		}
+		return true
+	})
+}
+
+func TestSchema(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Defs map[string]struct {
+			Properties           map[string]map[string]interface{} `json:"properties"`
+			AdditionalProperties interface{}                       `json:"additionalProperties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	main := doc.Defs["main"].Properties
+	if p := main["interval_length"]; p["type"] != "integer" || p["default"] != 60.0 {
+		t.Errorf("interval_length = %v", p)
+	}
+	if p := main["cfg_file"]; p["type"] != "array" || p["x-gogios-repeatable"] != true {
+		t.Errorf("cfg_file = %v", p)
+	}
+	if p := main["log_rotation_method"]; p["default"] != "d" || p["x-gogios-type"] != "char" {
+		t.Errorf("log_rotation_method = %v", p)
+	}
+	if p := main["check_for_updates"]; p["deprecated"] != true {
+		t.Errorf("check_for_updates = %v", p)
+	}
+	host := doc.Defs["host"]
+	if p := host.Properties["obsess"]; p["deprecated"] != true || p["description"] != "alias of obsess_over_host" {
+		t.Errorf("host obsess = %v", p)
+	}
+	if p := host.Properties["check_interval"]; p["default"] != 5.0 {
+		t.Errorf("host check_interval = %v", p)
+	}
+	if host.AdditionalProperties != false || doc.Defs["timeperiod"].AdditionalProperties == false {
+		t.Errorf("additionalProperties: host %v, timeperiod %v", host.AdditionalProperties, doc.Defs["timeperiod"].AdditionalProperties)
+	}
+}
@@ -2,11 +2,14 @@ package config
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
 // ResolveTemplates processes the 'use' directive on all objects, applying
 // left-to-right template inheritance with additive (+prefix) support.
+// Templates may themselves use templates, to any depth. A value of "null"
+// is kept, so it stops inheritance, and Get then reports it as unset.
 func ResolveTemplates(parser *ObjectParser) error {
 	for _, obj := range parser.Objects {
 		if err := resolveObject(parser, obj, nil); err != nil {
@@ -41,6 +44,14 @@ func resolveObject(parser *ObjectParser, obj *TemplateObject, chain []*TemplateO
 	templates := splitCSV(useStr)
 	chain = append(chain, obj)
 
+	// have holds the attributes the object has a value for, its own or
+	// one inherited from an earlier template. As in Nagios, the leftmost
+	// template with a value wins, and an additive value is added to that
+	// one template's value only.
+	have := make(map[string]bool, len(obj.Attrs))
+	for key := range obj.Attrs {
+		have[key] = !strings.HasPrefix(obj.Attrs[key], "+")
+	}
 	for _, tmplName := range templates {
 		tmpl := parser.GetTemplate(obj.Type, tmplName)
 		if tmpl == nil {
@@ -51,23 +62,30 @@ func resolveObject(parser *ObjectParser, obj *TemplateObject, chain []*TemplateO
 		if err := resolveObject(parser, tmpl, chain); err != nil {
 			return err
 		}
-		// Inherit attributes: only if the child doesn't already have the attribute
 		for key, val := range tmpl.Attrs {
-			if key == "name" || key == "use" || key == "register" {
+			if key == "name" || key == "use" || key == "register" || have[key] {
 				continue
 			}
+			have[key] = true
 			childVal, childHas := obj.Attrs[key]
-			if !childHas {
+			switch {
+			case !childHas:
 				obj.Attrs[key] = val
-			} else if strings.HasPrefix(childVal, "+") {
+				parser.setOrigin(obj, key, parser.origin(tmpl, key))
+			case val == "null":
+				// The template cancelled the value: nothing to add to
+				obj.Attrs[key] = childVal[1:]
+			default:
 				// Additive inheritance: prepend template value
 				obj.Attrs[key] = val + "," + childVal[1:]
+				parser.setOrigin(obj, key, append(parser.origin(tmpl, key), obj))
 			}
 		}
 		// Inherit custom vars
 		for key, val := range tmpl.CustomVars {
 			if _, exists := obj.CustomVars[key]; !exists {
 				obj.CustomVars[key] = val
+				parser.setOrigin(obj, "_"+key, parser.origin(tmpl, "_"+key))
 			}
 		}
 	}
@@ -75,6 +93,27 @@ func resolveObject(parser *ObjectParser, obj *TemplateObject, chain []*TemplateO
 	return nil
 }
 
+// origin returns the definitions the value of key in obj came from: obj
+// itself, unless it was inherited. Custom variables are keyed "_NAME".
+func (p *ObjectParser) origin(obj *TemplateObject, key string) []*TemplateObject {
+	if o, ok := p.origins[obj][key]; ok {
+		return o
+	}
+	return []*TemplateObject{obj}
+}
+
+// setOrigin records where an inherited value came from, when the parser
+// tracks origins.
+func (p *ObjectParser) setOrigin(obj *TemplateObject, key string, from []*TemplateObject) {
+	if p.origins == nil {
+		return
+	}
+	if p.origins[obj] == nil {
+		p.origins[obj] = make(map[string][]*TemplateObject)
+	}
+	p.origins[obj][key] = slices.Clip(from)
+}
+
 func cleanAdditiveStrings(obj *TemplateObject) {
 	for key, val := range obj.Attrs {
 		if strings.HasPrefix(val, "+") {
@@ -143,10 +143,12 @@ define host {
 	if host == nil {
 		t.Fatal("test-host not found")
 	}
-	// notes should be "null" (cleared)
-	notes, _ := host.Get("notes")
-	if notes != "null" {
-		t.Errorf("expected notes='null', got %q", notes)
+	// notes should be cleared: kept as "null" but reported unset
+	if !host.IsNull("notes") {
+		t.Errorf("expected notes='null', got %q", host.Attrs["notes"])
+	}
+	if notes, ok := host.Get("notes"); ok {
+		t.Errorf("expected notes unset, got %q", notes)
 	}
 	// notes_url should be inherited from base
 	url, _ := host.Get("notes_url")
@@ -154,3 +156,92 @@ define host {
 		t.Errorf("expected notes_url='http://example.com', got %q", url)
 	}
 }
+
+func TestInheritanceChains(t *testing.T) {
+	dir := t.TempDir()
+	content := `define host {
+    name            generic
+    register        0
+    contacts        root
+    contact_groups  admins
+    notes           generic notes
+}
+define host {
+    name            linux
+    use             generic
+    register        0
+    contacts        +linux-admin
+    contact_groups  null
+}
+define host {
+    name            web
+    use             linux
+    register        0
+    contacts        +web-admin
+    contact_groups  +web-team
+}
+define host {
+    name            db
+    register        0
+    contacts        +dba
+    notes           db notes
+}
+define host {
+    host_name       web-db
+    address         10.0.0.1
+    use             web,db
+    contacts        +oncall
+}
+define host {
+    host_name       db-web
+    address         10.0.0.2
+    use             db,web
+    notes           null
+}
+`
+	path := filepath.Join(dir, "test.cfg")
+	os.WriteFile(path, []byte(content), 0644)
+
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveTemplates(parser); err != nil {
+		t.Fatal(err)
+	}
+	hosts := map[string]*TemplateObject{}
+	for _, obj := range parser.Objects {
+		if name, ok := obj.Get("host_name"); ok {
+			hosts[name] = obj
+		}
+	}
+
+	for _, c := range []struct {
+		obj       *TemplateObject
+		key, want string
+		wantUnset bool
+	}{
+		// Additive values accumulate down the chain
+		{parser.GetTemplate("host", "web"), "contacts", "root,linux-admin,web-admin", false},
+		// A null in the middle of the chain cancels what is above it
+		{parser.GetTemplate("host", "linux"), "contact_groups", "", true},
+		{parser.GetTemplate("host", "web"), "contact_groups", "web-team", false},
+		// Only the leftmost template with a value is added to
+		{hosts["web-db"], "contacts", "root,linux-admin,web-admin,oncall", false},
+		{hosts["web-db"], "notes", "generic notes", false},
+		{hosts["db-web"], "contacts", "dba", false},
+		{hosts["db-web"], "contact_groups", "web-team", false},
+		{hosts["db-web"], "notes", "", true},
+	} {
+		got, ok := c.obj.Get(c.key)
+		if c.wantUnset {
+			if ok {
+				t.Errorf("%s: %s = %q, want unset", c.obj.Name()+c.obj.Attrs["host_name"], c.key, got)
+			}
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: %s = %q, want %q", c.obj.Name()+c.obj.Attrs["host_name"], c.key, got, c.want)
+		}
+	}
+}
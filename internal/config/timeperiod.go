@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -69,6 +71,13 @@ func parseHHMM(s string) ([2]int, error) {
 }
 
 // CheckTime returns true if the given time falls within the timeperiod.
+// Timeperiods compiled by CompileTimeperiod are looked up in their minute
+// bitmaps; others have their range strings parsed on each call.
+//
+// As in Nagios, the date exceptions of the most specific kind that covers
+// t's date (calendar dates, then month dates, days of the month, weekdays of
+// a month and weekdays of every month) replace the weekday ranges for that
+// day, so an exception can also take time away.
 func CheckTime(tp *objects.Timeperiod, t time.Time) bool {
 	if tp == nil {
 		return true
@@ -79,12 +88,47 @@ func CheckTime(tp *objects.Timeperiod, t time.Time) bool {
 			return false
 		}
 	}
-	// Check date exceptions (higher priority than weekday ranges)
-	for _, exc := range tp.Exceptions {
-		if matchException(exc, t) {
-			return true
+	if c := tp.Compiled; c != nil {
+		minute := t.Hour()*60 + t.Minute()
+		kind := -1
+		for i := range c.Exceptions {
+			e := &c.Exceptions[i]
+			if kind >= 0 && e.Kind != kind {
+				break
+			}
+			if e.Matches(t) {
+				kind = e.Kind
+				if e.Minutes.Has(minute) {
+					return true
+				}
+			}
+		}
+		if kind >= 0 {
+			return false
+		}
+		return c.Week[t.Weekday()].Has(minute)
+	}
+	return checkTimeRanges(tp, t)
+}
+
+// checkTimeRanges is CheckTime for tp's own ranges, parsed from strings.
+func checkTimeRanges(tp *objects.Timeperiod, t time.Time) bool {
+	// Date exceptions take precedence over weekday ranges
+	kind := -1
+	for _, exc := range sortedExceptions(tp) {
+		if kind >= 0 && exc.kind != kind {
+			break
+		}
+		if exc.matches(t) {
+			kind = exc.kind
+			if ranges, err := ParseTimeRanges(exc.ranges); err == nil && timeInRanges(t, ranges) {
+				return true
+			}
 		}
 	}
+	if kind >= 0 {
+		return false
+	}
 	// Check weekday ranges
 	dow := int(t.Weekday()) // Sunday = 0
 	rangeStr := tp.Ranges[dow]
@@ -98,6 +142,47 @@ func CheckTime(tp *objects.Timeperiod, t time.Time) bool {
 	return timeInRanges(t, ranges)
 }
 
+// sortedExceptions parses tp's date exceptions, leaving out those that do
+// not parse, most specific kind first.
+func sortedExceptions(tp *objects.Timeperiod) []dateException {
+	var out []dateException
+	for _, raw := range tp.Exceptions {
+		if exc, ok := parseException(raw); ok {
+			out = append(out, exc)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].kind < out[j].kind })
+	return out
+}
+
+// CompileTimeperiod precompiles tp's weekday ranges and date exceptions
+// into minute bitmaps, which CheckTime uses from then on. Call it once the
+// timeperiod is complete; it must not change afterwards.
+func CompileTimeperiod(tp *objects.Timeperiod) {
+	c := &objects.CompiledTimeperiod{}
+	for day, rangeStr := range tp.Ranges {
+		c.Week[day] = rangeMinutes(rangeStr)
+	}
+	for _, exc := range sortedExceptions(tp) {
+		c.Exceptions = append(c.Exceptions, objects.CompiledException{Kind: exc.kind, Matches: exc.matches, Minutes: rangeMinutes(exc.ranges)})
+	}
+	tp.Compiled = c
+}
+
+// rangeMinutes returns the minutes of a "HH:MM-HH:MM,..." list. Like the
+// uncompiled lookup, a list that does not parse covers no minutes.
+func rangeMinutes(rangeStr string) objects.DayMinutes {
+	var d objects.DayMinutes
+	ranges, err := ParseTimeRanges(rangeStr)
+	if err != nil {
+		return d
+	}
+	for _, r := range ranges {
+		d.Add(r.StartHour*60+r.StartMin, r.EndHour*60+r.EndMin)
+	}
+	return d
+}
+
 // GetNextValidTime returns the next time >= t that is valid in the timeperiod.
 func GetNextValidTime(tp *objects.Timeperiod, t time.Time) time.Time {
 	if tp == nil {
@@ -118,6 +203,39 @@ func GetNextValidTime(tp *objects.Timeperiod, t time.Time) time.Time {
 	return t // fallback
 }
 
+// TimeperiodsOverlap reports whether a and b share at least one minute
+// within a year from from. A nil timeperiod is 24x7. Periods without date
+// exceptions repeat weekly, so one week is enough; otherwise every minute of
+// the following 366 days is tried, which is slow and meant for diagnostics.
+func TimeperiodsOverlap(a, b *objects.Timeperiod, from time.Time) bool {
+	span := 7 * 24 * time.Hour
+	if hasExceptions(a) || hasExceptions(b) {
+		span = 366 * 24 * time.Hour
+	}
+	start := from.Truncate(time.Minute)
+	for t := start; t.Before(start.Add(span)); t = t.Add(time.Minute) {
+		if CheckTime(a, t) && CheckTime(b, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExceptions(tp *objects.Timeperiod) bool {
+	if tp == nil {
+		return false
+	}
+	if len(tp.Exceptions) > 0 {
+		return true
+	}
+	for _, exc := range tp.Exclusions {
+		if hasExceptions(exc) {
+			return true
+		}
+	}
+	return false
+}
+
 func timeInRanges(t time.Time, ranges []TimeRange) bool {
 	minutes := t.Hour()*60 + t.Minute()
 	for _, r := range ranges {
@@ -130,102 +248,297 @@ func timeInRanges(t time.Time, ranges []TimeRange) bool {
 	return false
 }
 
-// matchException checks if time t matches a date exception.
-func matchException(exc objects.TimeDateException, t time.Time) bool {
-	// Parse the raw Timerange string which contains the full directive
-	// Format examples:
-	//   "january 1 00:00-24:00"
-	//   "july 4 00:00-24:00"
-	//   "december 25 00:00-24:00"
-	//   "monday -1 may 00:00-24:00"
-	//   "monday 1 september 00:00-24:00"
-	//   "thursday 4 november 00:00-24:00"
-	//   "2008-12-25 00:00-24:00"
-	//   "day 21 00:00-24:00"
-	raw := exc.Timerange
-	if raw == "" {
-		return false
+// Kinds of date exception, most specific first. Only the exceptions of the
+// first kind that covers a date apply to it.
+const (
+	exceptionCalendarDate = iota // 2008-12-25
+	exceptionMonthDate           // december 25
+	exceptionMonthDay            // day 25
+	exceptionMonthWeekday        // thursday 4 november
+	exceptionWeekday             // thursday 4 (of every month)
+)
+
+// dateException is a parsed date exception: its kind, a test of whether it
+// applies to a time's date, and its time ranges.
+type dateException struct {
+	kind    int
+	matches func(time.Time) bool
+	ranges  string
+}
+
+// dateSpec is a date, or one end of a date range, of a date exception.
+// Negative days and weekday counts are counted from the end of the month.
+type dateSpec struct {
+	kind             int
+	year, month, day int
+	weekday, n       int
+}
+
+// isTimeRange reports whether a field of a timeperiod line starts its time
+// ranges, as "00:00-09:00," does.
+func isTimeRange(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9' && strings.Contains(s, ":")
+}
+
+// splitTimeperiodDirective moves the date of a timeperiod date exception,
+// such as "day 1 - 15 / 5", from the value into the key, so exceptions
+// that start with the same word ("day 1", "day 15") are kept apart.
+// Attributes and weekday ranges are left as they are.
+func splitTimeperiodDirective(key, val string) (string, string) {
+	switch key {
+	case "timeperiod_name", "alias", "exclude", "use", "name", "register":
+		return key, val
+	}
+	fields := strings.Fields(val)
+	i := 0
+	for i < len(fields) && !isTimeRange(fields[i]) {
+		i++
+	}
+	if i == 0 {
+		return key, val
 	}
+	return key + " " + strings.Join(fields[:i], " "), strings.Join(fields[i:], " ")
+}
 
-	// Try to parse different formats
-	parts := strings.Fields(raw)
-	if len(parts) < 2 {
-		return false
+// parseException parses a date exception. It understands the Nagios
+// formats:
+//
+//	2008-12-25                      calendar date
+//	2008-12-25 - 2009-01-05         calendar date range
+//	2008-04-01 / 7                  every 7 days from a date, forever
+//	december 25                     month date; "february -1" is the last day
+//	april 10 - may 15, july 10 - 15 month date range
+//	day 1, day -1, day 1 - 15       day of every month, and ranges of them
+//	thursday 4 november             weekday of a month; -1 is the last
+//	tuesday 1 april - friday 2 october
+//	monday 3, monday 3 - thursday 4 weekday of every month, and ranges
+//
+// Any of them may be followed by "/ N" to apply only to every Nth day from
+// the start of the range, and then by the time ranges.
+func parseException(exc objects.TimeDateException) (dateException, bool) {
+	fields := strings.Fields(strings.ReplaceAll(exc.Timerange, "/", " / "))
+	i := 0
+	for i < len(fields) && !isTimeRange(fields[i]) {
+		i++
 	}
+	dateFields, ranges := fields[:i], strings.Join(fields[i:], "")
 
-	// Calendar date: "2008-12-25 HH:MM-HH:MM"
-	if len(parts) >= 2 && strings.Contains(parts[0], "-") && len(parts[0]) >= 8 {
-		dateStr := parts[0]
-		dateParts := strings.SplitN(dateStr, "-", 3)
-		if len(dateParts) == 3 {
-			yr, _ := strconv.Atoi(dateParts[0])
-			mo, _ := strconv.Atoi(dateParts[1])
-			dy, _ := strconv.Atoi(dateParts[2])
-			if t.Year() == yr && int(t.Month()) == mo && t.Day() == dy {
-				if len(parts) > 1 {
-					ranges, _ := ParseTimeRanges(parts[len(parts)-1])
-					return timeInRanges(t, ranges)
-				}
-				return true
-			}
+	skip := 1
+	if j := slices.Index(dateFields, "/"); j >= 0 {
+		if j != len(dateFields)-2 {
+			return dateException{}, false
 		}
-		return false
+		n, err := strconv.Atoi(dateFields[j+1])
+		if err != nil || n < 1 {
+			return dateException{}, false
+		}
+		skip, dateFields = n, dateFields[:j]
+	}
+	startFields, endFields := dateFields, []string(nil)
+	if j := slices.Index(dateFields, "-"); j >= 0 {
+		startFields, endFields = dateFields[:j], dateFields[j+1:]
+	}
+	start, ok := parseDateSpec(startFields)
+	if !ok {
+		return dateException{}, false
 	}
+	end, forever := start, false
+	switch {
+	case endFields != nil:
+		if end, ok = parseDateEnd(start, endFields); !ok {
+			return dateException{}, false
+		}
+	case skip > 1 && start.kind == exceptionCalendarDate:
+		forever = true
+	}
+	return dateException{kind: start.kind, matches: dateRangeMatcher(start, end, forever, skip), ranges: ranges}, true
+}
 
-	// Month date: "month day HH:MM-HH:MM" e.g. "january 1 00:00-24:00"
-	if mo := parseMonth(parts[0]); mo > 0 && len(parts) >= 3 {
-		day, err := strconv.Atoi(parts[1])
-		if err == nil && int(t.Month()) == mo && t.Day() == day {
-			ranges, _ := ParseTimeRanges(parts[2])
-			return timeInRanges(t, ranges)
+// parseDateSpec parses the start of a date exception.
+func parseDateSpec(f []string) (dateSpec, bool) {
+	var d dateSpec
+	switch {
+	case len(f) == 1:
+		parts := strings.Split(f[0], "-")
+		if len(parts) != 3 {
+			return d, false
 		}
+		var err [3]error
+		d.year, err[0] = strconv.Atoi(parts[0])
+		d.month, err[1] = strconv.Atoi(parts[1])
+		d.day, err[2] = strconv.Atoi(parts[2])
+		if err != [3]error{} || d.month < 1 || d.month > 12 || d.day < 1 || d.day > 31 {
+			return d, false
+		}
+		d.kind = exceptionCalendarDate
+		return d, true
+	case len(f) == 2 && f[0] == "day":
+		d.kind = exceptionMonthDay
+		d.day, _ = strconv.Atoi(f[1])
+		return d, validDay(d.day)
+	case len(f) == 2 && parseMonth(f[0]) > 0:
+		d.kind, d.month = exceptionMonthDate, parseMonth(f[0])
+		d.day, _ = strconv.Atoi(f[1])
+		return d, validDay(d.day)
+	case (len(f) == 2 || len(f) == 3) && parseWeekday(f[0]) >= 0:
+		d.kind, d.weekday = exceptionWeekday, parseWeekday(f[0])
+		d.n, _ = strconv.Atoi(f[1])
+		if len(f) == 3 {
+			if d.month = parseMonth(f[2]); d.month == 0 {
+				return d, false
+			}
+			d.kind = exceptionMonthWeekday
+		}
+		return d, d.n != 0 && d.n >= -5 && d.n <= 5
+	}
+	return d, false
+}
+
+// parseDateEnd parses the end of a date range starting at start. A month
+// date or day of the month may end with just the day ("july 10 - 15"), and
+// a weekday of a month with just the weekday and count.
+func parseDateEnd(start dateSpec, f []string) (dateSpec, bool) {
+	if len(f) == 1 && (start.kind == exceptionMonthDate || start.kind == exceptionMonthDay) {
+		end := start
+		end.day, _ = strconv.Atoi(f[0])
+		return end, validDay(end.day)
 	}
+	end, ok := parseDateSpec(f)
+	if ok && start.kind == exceptionMonthWeekday && end.kind == exceptionWeekday {
+		end.kind, end.month = exceptionMonthWeekday, start.month
+	}
+	return end, ok && end.kind == start.kind
+}
+
+func validDay(day int) bool {
+	return day != 0 && day >= -31 && day <= 31
+}
 
-	// Weekday of month: "weekday N month HH:MM-HH:MM" e.g. "monday 1 september 00:00-24:00"
-	if wd := parseWeekday(parts[0]); wd >= 0 && len(parts) >= 4 {
-		n, err := strconv.Atoi(parts[1])
-		if err == nil {
-			if mo := parseMonth(parts[2]); mo > 0 {
-				if matchWeekdayOfMonth(t, wd, n, mo) {
-					ranges, _ := ParseTimeRanges(parts[3])
-					return timeInRanges(t, ranges)
+// dateRangeMatcher returns a test of whether a time's date lies between
+// start and end, and is a multiple of skip days from start. Yearly ranges
+// (month dates and weekdays of a month) are tried from this year and the
+// last, monthly ones from this month and the last, so a range that ends
+// in the next year or month covers its start. forever extends a calendar
+// date to all later dates.
+func dateRangeMatcher(start, end dateSpec, forever bool, skip int) func(time.Time) bool {
+	if end == start && !forever {
+		return singleDateMatcher(start)
+	}
+	years, months := 1, 0
+	if start.kind == exceptionMonthDay || start.kind == exceptionWeekday {
+		years, months = 0, 1
+	}
+	return func(t time.Time) bool {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		period := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if years == 1 {
+			period = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		}
+		for _, p := range []time.Time{period.AddDate(-years, -months, 0), period} {
+			from, ok := start.resolve(p)
+			if !ok {
+				continue
+			}
+			until := from
+			if forever {
+				until = day
+			} else if end != start {
+				if until, ok = end.resolve(p); ok && until.Before(from) {
+					until, ok = end.resolve(p.AddDate(years, months, 0))
+				}
+				if !ok {
+					continue
 				}
 			}
+			if day.Before(from) || day.After(until) {
+				continue
+			}
+			if int(day.Sub(from).Hours()/24)%skip == 0 {
+				return true
+			}
 		}
+		return false
 	}
+}
 
-	// Day of month: "day N HH:MM-HH:MM"
-	if parts[0] == "day" && len(parts) >= 3 {
-		day, err := strconv.Atoi(parts[1])
-		if err == nil && t.Day() == day {
-			ranges, _ := ParseTimeRanges(parts[2])
-			return timeInRanges(t, ranges)
+// singleDateMatcher is dateRangeMatcher for a single date, which most
+// exceptions are. It is called for every lookup, so it compares the date
+// fields directly where it can.
+func singleDateMatcher(d dateSpec) func(time.Time) bool {
+	return func(t time.Time) bool {
+		year, month, day := t.Date()
+		switch {
+		case d.kind == exceptionCalendarDate:
+			return year == d.year && int(month) == d.month && day == d.day
+		case d.month != 0 && int(month) != d.month:
+			return false
+		case d.kind == exceptionMonthDate || d.kind == exceptionMonthDay:
+			if d.day < 0 {
+				return day == daysIn(month, year)+d.day+1
+			}
+			return day == d.day
+		case int(t.Weekday()) != d.weekday:
+			return false
+		case d.n > 0:
+			return (day-1)/7+1 == d.n
 		}
+		return (daysIn(month, year)-day)/7 == -d.n-1
 	}
+}
 
-	return false
+// resolve returns the date d falls on in the year or month starting at p.
+// ok is false when there is no such date, such as the fifth Monday of a
+// month with four.
+func (d dateSpec) resolve(p time.Time) (time.Time, bool) {
+	switch d.kind {
+	case exceptionCalendarDate:
+		return dayOfMonth(d.year, time.Month(d.month), d.day)
+	case exceptionMonthDate:
+		return dayOfMonth(p.Year(), time.Month(d.month), d.day)
+	case exceptionMonthDay:
+		return dayOfMonth(p.Year(), p.Month(), d.day)
+	case exceptionMonthWeekday:
+		return weekdayOfMonth(p.Year(), time.Month(d.month), d.weekday, d.n)
+	default:
+		return weekdayOfMonth(p.Year(), p.Month(), d.weekday, d.n)
+	}
 }
 
-func matchWeekdayOfMonth(t time.Time, weekday, n, month int) bool {
-	if int(t.Month()) != month {
-		return false
+// dayOfMonth returns the day'th day of a month, counting from its end when
+// day is negative.
+func dayOfMonth(year int, month time.Month, day int) (time.Time, bool) {
+	n := daysIn(month, year)
+	if day < 0 {
+		day = n + day + 1
 	}
-	if int(t.Weekday()) != weekday {
-		return false
+	if day < 1 || day > n {
+		return time.Time{}, false
 	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), true
+}
+
+// weekdayOfMonth returns the nth weekday of a month, counting from its end
+// when n is negative.
+func weekdayOfMonth(year int, month time.Month, weekday, n int) (time.Time, bool) {
+	days := daysIn(month, year)
+	var day int
 	if n > 0 {
-		// Nth weekday of month (1-based)
-		return (t.Day()-1)/7+1 == n
+		first := int(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday())
+		day = 1 + (weekday-first+7)%7 + (n-1)*7
+	} else {
+		last := int(time.Date(year, month, days, 0, 0, 0, 0, time.UTC).Weekday())
+		day = days - (last-weekday+7)%7 + (n+1)*7
 	}
-	// Negative: -1 = last, -2 = second to last
-	// Find the last occurrence
-	daysInMonth := daysIn(t.Month(), t.Year())
-	weekNum := (daysInMonth - t.Day()) / 7
-	return weekNum == (-n - 1)
+	return dayOfMonth(year, month, day)
 }
 
+var monthDays = [...]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
 func daysIn(m time.Month, year int) int {
-	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if m == time.February && year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+		return 29
+	}
+	return monthDays[m-1]
 }
 
 var monthNames = map[string]int{
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -132,3 +134,210 @@ func TestCheckTimeMultipleRanges(t *testing.T) {
 		t.Error("expected 6pm Monday to be valid in nonwork (17:00-24:00)")
 	}
 }
+
+func TestTimeperiodsOverlap(t *testing.T) {
+	work := &objects.Timeperiod{Name: "workhours"}
+	for d := 1; d <= 5; d++ {
+		work.Ranges[d] = "09:00-17:00"
+	}
+	nights := &objects.Timeperiod{Name: "nights"}
+	for d := range nights.Ranges {
+		nights.Ranges[d] = "00:00-06:00,22:00-24:00"
+	}
+	weekend := &objects.Timeperiod{Name: "weekend"}
+	weekend.Ranges[0] = "00:00-24:00"
+	weekend.Ranges[6] = "00:00-24:00"
+	from := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	if !TimeperiodsOverlap(work, nil, from) {
+		t.Error("expected workhours to overlap 24x7")
+	}
+	if TimeperiodsOverlap(work, nights, from) {
+		t.Error("expected workhours and nights not to overlap")
+	}
+	if TimeperiodsOverlap(work, weekend, from) {
+		t.Error("expected workhours and weekend not to overlap")
+	}
+	if !TimeperiodsOverlap(nights, weekend, from) {
+		t.Error("expected nights and weekend to overlap")
+	}
+	if TimeperiodsOverlap(&objects.Timeperiod{Name: "never"}, nil, from) {
+		t.Error("expected an empty timeperiod never to be valid")
+	}
+}
+
+// newHolidayTimeperiod returns a timeperiod exercising every lookup path:
+// several weekday ranges, each exception format and an exclusion.
+func newHolidayTimeperiod() *objects.Timeperiod {
+	lunch := &objects.Timeperiod{Name: "lunch"}
+	for d := range lunch.Ranges {
+		lunch.Ranges[d] = "12:00-12:30"
+	}
+	tp := &objects.Timeperiod{Name: "support", Exclusions: []*objects.Timeperiod{lunch}}
+	for d := 1; d <= 5; d++ {
+		tp.Ranges[d] = "08:00-12:45,13:15-18:00"
+	}
+	tp.Ranges[6] = "10:00-14:00"
+	tp.Ranges[0] = "bogus"
+	for _, raw := range []string{
+		"2024-12-24 09:00-12:00",
+		"january 1 00:00-24:00",
+		"monday -1 may 06:00-07:00",
+		"thursday 4 november 10:00-11:00",
+		"day 15 20:00-21:30",
+		"day x 00:00-24:00",
+	} {
+		tp.Exceptions = append(tp.Exceptions, objects.TimeDateException{Timerange: raw})
+	}
+	return tp
+}
+
+// A compiled timeperiod answers exactly as parsing its ranges does.
+func TestCompileTimeperiodMatchesRanges(t *testing.T) {
+	naive := newHolidayTimeperiod()
+	compiled := newHolidayTimeperiod()
+	CompileTimeperiod(compiled)
+	CompileTimeperiod(compiled.Exclusions[0])
+	if compiled.Compiled == nil || len(compiled.Compiled.Exceptions) != 5 {
+		t.Fatalf("expected 5 compiled exceptions, got %+v", compiled.Compiled)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := 0
+	for m := start; m.Year() == 2024; m = m.Add(7 * time.Minute) {
+		want := CheckTime(naive, m)
+		if got := CheckTime(compiled, m); got != want {
+			t.Fatalf("%s: compiled %v, parsed %v", m.Format(time.RFC1123), got, want)
+		}
+		if want {
+			valid++
+		}
+	}
+	if valid == 0 {
+		t.Fatal("expected some valid minutes")
+	}
+}
+
+func TestCheckTimeExceptions(t *testing.T) {
+	at := func(y int, m time.Month, d, hh, mm int) time.Time { return time.Date(y, m, d, hh, mm, 0, 0, time.UTC) }
+	tests := []struct {
+		exceptions []string
+		at         time.Time
+		want       bool
+	}{
+		// An exception replaces the weekday ranges of its days.
+		{[]string{"2024-12-25 00:00-00:00"}, at(2024, 12, 25, 12, 0), false},
+		{[]string{"december 24 09:00-12:00"}, at(2024, 12, 24, 10, 0), true},
+		{[]string{"december 24 09:00-12:00"}, at(2024, 12, 24, 14, 0), false},
+		{[]string{"day 24 10:00-11:00"}, at(2024, 1, 24, 14, 0), false},
+		// The most specific kind that covers a day wins.
+		{[]string{"day 24 10:00-11:00", "december 24 09:00-12:00"}, at(2024, 12, 24, 11, 30), true},
+		{[]string{"day 24 10:00-11:00", "december 24 09:00-12:00"}, at(2024, 1, 24, 10, 30), true},
+		// Skip intervals: days in between keep their weekday ranges.
+		{[]string{"2024-01-01 - 2024-01-10 / 3 06:00-07:00"}, at(2024, 1, 4, 6, 30), true},
+		{[]string{"2024-01-01 - 2024-01-10 / 3 06:00-07:00"}, at(2024, 1, 4, 10, 0), false},
+		{[]string{"2024-01-01 - 2024-01-10 / 3 06:00-07:00"}, at(2024, 1, 5, 6, 30), false},
+		{[]string{"2024-01-01 - 2024-01-10 / 3 06:00-07:00"}, at(2024, 1, 5, 10, 0), true},
+		{[]string{"2024-01-01 - 2024-01-10 / 3 06:00-07:00"}, at(2024, 1, 13, 6, 30), false},
+		{[]string{"2024-03-01 / 7 20:00-21:00"}, at(2025, 3, 14, 20, 30), true},
+		{[]string{"2024-03-01 / 7 20:00-21:00"}, at(2025, 3, 15, 20, 30), false},
+		{[]string{"july 10 - 15 / 2 08:00-09:00"}, at(2024, 7, 12, 8, 30), true},
+		{[]string{"july 10 - 15 / 2 08:00-09:00"}, at(2024, 7, 11, 8, 30), false},
+		{[]string{"july 10 - 15 /2 08:00-09:00"}, at(2024, 7, 11, 10, 0), true},
+		// Ranges that run into the next year or month.
+		{[]string{"november 25 - january 5 00:00-24:00"}, at(2025, 1, 4, 3, 0), true},
+		{[]string{"november 25 - january 5 00:00-24:00"}, at(2024, 12, 31, 3, 0), true},
+		{[]string{"november 25 - january 5 00:00-24:00"}, at(2025, 1, 6, 3, 0), false},
+		{[]string{"day 28 - 2 00:00-01:00"}, at(2024, 3, 1, 0, 30), true},
+		{[]string{"day 1 - 3 18:00-19:00"}, at(2024, 6, 3, 18, 30), true},
+		{[]string{"day 1 - 3 18:00-19:00"}, at(2024, 6, 3, 10, 0), false},
+		{[]string{"day 20 - -1 00:00-01:00"}, at(2024, 2, 29, 0, 30), true},
+		{[]string{"day -1 00:00-01:00"}, at(2024, 2, 29, 0, 30), true},
+		{[]string{"february -2 00:00-01:00"}, at(2024, 2, 28, 0, 30), true},
+		// Weekdays of a month, and of every month.
+		{[]string{"thursday 4 november 00:00-24:00"}, at(2024, 11, 28, 3, 0), true},
+		{[]string{"thursday -1 november 00:00-24:00"}, at(2024, 11, 28, 3, 0), true},
+		{[]string{"tuesday 1 april - friday 2 october 05:00-06:00"}, at(2024, 6, 15, 5, 30), true},
+		{[]string{"tuesday 1 april - friday 2 october 05:00-06:00"}, at(2024, 10, 14, 5, 30), false},
+		{[]string{"monday -1 05:00-06:00"}, at(2024, 6, 24, 5, 30), true},
+		{[]string{"monday -1 05:00-06:00"}, at(2024, 6, 17, 5, 30), false},
+		{[]string{"monday 3 - thursday 4 05:00-06:00"}, at(2024, 6, 20, 5, 30), true},
+		{[]string{"monday 3 - thursday 4 05:00-06:00"}, at(2024, 6, 28, 5, 30), false},
+		{[]string{"monday 5 05:00-06:00"}, at(2024, 6, 17, 10, 0), true},
+		{[]string{"saturday 1 09:00-17:00, 18:00-19:00"}, at(2024, 6, 1, 18, 30), true},
+	}
+	for _, tt := range tests {
+		for _, compile := range []bool{false, true} {
+			tp := &objects.Timeperiod{Name: "workhours"}
+			for d := 1; d <= 5; d++ {
+				tp.Ranges[d] = "09:00-17:00"
+			}
+			for _, raw := range tt.exceptions {
+				tp.Exceptions = append(tp.Exceptions, objects.TimeDateException{Timerange: raw})
+			}
+			if compile {
+				CompileTimeperiod(tp)
+			}
+			if got := CheckTime(tp, tt.at); got != tt.want {
+				t.Errorf("%q at %s (compiled %v): got %v, want %v", tt.exceptions, tt.at.Format("Mon 2006-01-02 15:04"), compile, got, tt.want)
+			}
+		}
+	}
+
+	for _, raw := range []string{"day 32 00:00-24:00", "monday 6 00:00-24:00", "july 10 - monday 3 00:00-24:00",
+		"2024-13-01 00:00-24:00", "day 1 / x 00:00-24:00", "someday 1 00:00-24:00"} {
+		if _, ok := parseException(objects.TimeDateException{Timerange: raw}); ok {
+			t.Errorf("%q should not parse", raw)
+		}
+	}
+}
+
+// Date exceptions that start with the same word are kept apart, and a
+// weekday of every month does not replace the weekday's ranges.
+func TestTimeperiodExceptionDirectives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeperiods.cfg")
+	cfg := `define timeperiod {
+    timeperiod_name  oncall
+    alias            On call hours
+    monday           09:00-17:00
+    monday 1         00:00-24:00
+    day 1            06:00-07:00
+    day 15 - 16      06:00-07:00
+}
+`
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewObjectParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveTemplates(parser); err != nil {
+		t.Fatal(err)
+	}
+	store := objects.NewObjectStore()
+	if err := ExpandAndRegister(parser, store, ""); err != nil {
+		t.Fatal(err)
+	}
+	tp := store.GetTimeperiod("oncall")
+	if tp.Alias != "On call hours" || tp.Ranges[1] != "09:00-17:00" || len(tp.Exceptions) != 3 {
+		t.Fatalf("unexpected timeperiod %+v", tp)
+	}
+	if !CheckTime(tp, time.Date(2024, 8, 5, 20, 0, 0, 0, time.UTC)) {
+		t.Error("expected the first monday of August to be valid all day")
+	}
+	if !CheckTime(tp, time.Date(2024, 7, 16, 6, 30, 0, 0, time.UTC)) || CheckTime(tp, time.Date(2024, 7, 16, 10, 0, 0, 0, time.UTC)) {
+		t.Error("expected July 16 to be valid only from 06:00 to 07:00")
+	}
+}
+
+func TestDayMinutes(t *testing.T) {
+	var d objects.DayMinutes
+	d.Add(-5, 2)
+	d.Add(1439, 2000)
+	for minute, want := range map[int]bool{-1: false, 0: true, 1: true, 2: false, 1438: false, 1439: true, 1440: false} {
+		if d.Has(minute) != want {
+			t.Errorf("minute %d: expected %v", minute, want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Unknown is a main config directive, object type or object attribute the
+// parser does not read. Loading ignores it; -v lists it as a warning, and
+// strict_config=1 makes it an error.
+type Unknown struct {
+	File string
+	Line int // of the directive, or of the object definition
+	// Kind is "directive", "object type", or "<type> attribute".
+	Kind string
+	Name string
+	// Suggestion is the known name closest to Name, or empty when none is
+	// close enough to be a likely typo.
+	Suggestion string
+}
+
+func (u Unknown) String() string {
+	s := fmt.Sprintf("unknown %s '%s'", u.Kind, u.Name)
+	if u.File != "" {
+		s = fmt.Sprintf("%s:%d: %s", u.File, u.Line, s)
+	}
+	if u.Suggestion != "" {
+		s += fmt.Sprintf(" (did you mean '%s'?)", u.Suggestion)
+	}
+	return s
+}
+
+// UnknownError is returned by LoadConfig when strict_config is set and the
+// configuration has unknown names.
+type UnknownError struct {
+	Unknown []Unknown
+}
+
+func (e *UnknownError) Error() string {
+	lines := make([]string, len(e.Unknown))
+	for i, u := range e.Unknown {
+		lines[i] = u.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ignoredNagiosDirectives are Nagios 3/4 directives that have no meaning in
+// Gogios. They are not reported, so a stock nagios.cfg loads cleanly in
+// strict mode.
+var ignoredNagiosDirectives = []string{
+	"command_check_interval",
+	"enable_embedded_perl",
+	"external_command_buffer_slots",
+	"host_skip_check_dependency_status",
+	"p1_file",
+	"service_skip_check_dependency_status",
+	"service_skip_check_host_down_status",
+	"service_skip_check_parent_status",
+	"sleep_time",
+	"use_embedded_perl_implicitly",
+}
+
+var directiveNames = func() map[string]bool {
+	m := make(map[string]bool, len(mainDirectives)+len(ignoredNagiosDirectives))
+	for _, d := range mainDirectives {
+		m[d.Name] = true
+	}
+	for _, name := range ignoredNagiosDirectives {
+		m[name] = true
+	}
+	return m
+}()
+
+// unknownDirective returns the Unknown for key, or false if setDirective
+// handles it.
+func unknownDirective(key, file string, line int) (Unknown, bool) {
+	if directiveNames[key] {
+		return Unknown{}, false
+	}
+	names := make([]string, len(mainDirectives))
+	for i, d := range mainDirectives {
+		names[i] = d.Name
+	}
+	return Unknown{File: file, Line: line, Kind: "directive", Name: key, Suggestion: suggest(key, names)}, true
+}
+
+// attributeNames maps each object type to the attribute names it accepts,
+// aliases included. Open types, which take any name, map to nil.
+var attributeNames = func() map[string][]string {
+	m := make(map[string][]string)
+	for _, t := range ObjectTypes() {
+		attrs, open := ObjectAttributes(t)
+		if open {
+			m[t] = nil
+			continue
+		}
+		for _, a := range attrs {
+			m[t] = append(m[t], a.Name)
+		}
+	}
+	return m
+}()
+
+// Unknown returns the unknown object types and attributes of the parsed
+// definitions, in file order. Call it before ResolveTemplates, so names a
+// template passes on are reported only once, against the template.
+func (p *ObjectParser) Unknown() []Unknown {
+	var out []Unknown
+	for _, obj := range p.Objects {
+		out = append(out, obj.unknown()...)
+	}
+	return out
+}
+
+// unknown returns obj's type if it is unknown, or else its unknown
+// attributes in name order.
+func (obj *TemplateObject) unknown() []Unknown {
+	names, ok := attributeNames[obj.Type]
+	if !ok {
+		return []Unknown{{File: obj.File, Line: obj.Line, Kind: "object type", Name: obj.Type, Suggestion: suggest(obj.Type, ObjectTypes())}}
+	}
+	if names == nil {
+		return nil
+	}
+	var bad []string
+	for key := range obj.Attrs {
+		if !contains(names, key) {
+			bad = append(bad, key)
+		}
+	}
+	sort.Strings(bad)
+	out := make([]Unknown, len(bad))
+	for i, key := range bad {
+		out[i] = Unknown{File: obj.File, Line: obj.Line, Kind: obj.Type + " attribute", Name: key, Suggestion: suggest(key, names)}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the candidate closest to name by edit distance, if it is
+// within a third of name's length (at least 2).
+func suggest(name string, candidates []string) string {
+	best, bestDist := "", len(name)/3
+	if bestDist < 2 {
+		bestDist = 2
+	}
+	for _, c := range candidates {
+		if d := editDistance(name, c); d <= bestDist && (best == "" || d < bestDist) {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnknownNames(t *testing.T) {
+	dir := t.TempDir()
+	objs := filepath.Join(dir, "objects.cfg")
+	if err := os.WriteFile(objs, []byte(`define command {
+    command_name    check_ping
+    command_line    /bin/true
+}
+define host {
+    name            base
+    chek_interval   5
+    register        0
+}
+define host {
+    use             base
+    host_name       web01
+    address         10.0.0.1
+    check_command   check_ping
+    max_check_attempts 3
+    obsess          1
+    _OWNER          ops
+}
+define hostgrup {
+    hostgroup_name  web
+}
+define timeperiod {
+    timeperiod_name always
+    monday          00:00-24:00
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "nagios.cfg")
+	cfg := "cfg_file=objects.cfg\ninterval_lenght=60\nsleep_time=0.25\nfrobnicate=1\n"
+	if err := os.WriteFile(main, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := LoadConfig(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		main + ":2: unknown directive 'interval_lenght' (did you mean 'interval_length'?)",
+		main + ":4: unknown directive 'frobnicate'",
+		objs + ":5: unknown host attribute 'chek_interval' (did you mean 'check_interval'?)",
+		objs + ":19: unknown object type 'hostgrup' (did you mean 'hostgroup'?)",
+	}
+	if len(result.Unknown) != len(want) {
+		t.Fatalf("got %v, want %d unknown names", result.Unknown, len(want))
+	}
+	for i, u := range result.Unknown {
+		if u.String() != want[i] {
+			t.Errorf("unknown[%d] = %q, want %q", i, u, want[i])
+		}
+	}
+	if h := result.Store.GetHost("web01"); h == nil || h.CheckInterval != 5 {
+		t.Errorf("web01 check_interval should fall back to the default, got %+v", h)
+	}
+
+	if err := os.WriteFile(main, []byte(cfg+"strict_config=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(main)
+	var ue *UnknownError
+	if !errors.As(err, &ue) || len(ue.Unknown) != len(want) {
+		t.Fatalf("strict_config: got %v", err)
+	}
+	if _, errs := VerifyConfig(main); len(errs) != len(want) || errs[0].Error() != want[0] {
+		t.Errorf("VerifyConfig errors = %v", errs)
+	}
+}
+
+func TestLoadConfigHasNoUnknownNames(t *testing.T) {
+	result, err := LoadConfig(testConfigPath("nagios.cfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range result.Unknown {
+		t.Errorf("%s", u)
+	}
+}
@@ -21,9 +21,6 @@ func Validate(store *objects.ObjectStore) []error {
 		if h.MaxCheckAttempts < 1 {
 			errs = append(errs, fmt.Errorf("host '%s': max_check_attempts must be >= 1 (got %d)", h.Name, h.MaxCheckAttempts))
 		}
-		if len(h.ContactGroups) == 0 && len(h.Contacts) == 0 {
-			errs = append(errs, fmt.Errorf("host '%s': has no contacts or contact_groups", h.Name))
-		}
 	}
 
 	// Validate services
@@ -43,10 +40,6 @@ func Validate(store *objects.ObjectStore) []error {
 			errs = append(errs, fmt.Errorf("service '%s/%s': missing check_command",
 				svc.Host.Name, svc.Description))
 		}
-		if len(svc.ContactGroups) == 0 && len(svc.Contacts) == 0 {
-			errs = append(errs, fmt.Errorf("service '%s/%s': has no contacts or contact_groups",
-				svc.Host.Name, svc.Description))
-		}
 	}
 
 	// Validate contacts
@@ -86,6 +79,76 @@ func Validate(store *objects.ObjectStore) []error {
 	return errs
 }
 
+// Warnings returns problems that do not stop the configuration from
+// loading but are likely mistakes, for -v to report. As in Nagios, a host
+// or service without contacts is a warning, not an error.
+func Warnings(store *objects.ObjectStore) []string {
+	var warnings []string
+	for _, h := range store.Hosts {
+		if len(h.ContactGroups) == 0 && len(h.Contacts) == 0 {
+			warnings = append(warnings, fmt.Sprintf("host '%s': has no contacts or contact_groups", h.Name))
+		}
+		for _, esc := range h.AllEscalations() {
+			if contacts, groups := esc.ContactsFor(h); !hasContacts(contacts, groups) {
+				warnings = append(warnings, fmt.Sprintf("host '%s': escalation for notifications %s notifies no contacts",
+					h.Name, notificationRange(esc.FirstNotification, esc.LastNotification)))
+			}
+		}
+	}
+	for _, svc := range store.Services {
+		if svc.Host == nil {
+			continue
+		}
+		if len(svc.ContactGroups) == 0 && len(svc.Contacts) == 0 {
+			warnings = append(warnings, fmt.Sprintf("service '%s/%s': has no contacts or contact_groups",
+				svc.Host.Name, svc.Description))
+		}
+		for _, esc := range svc.AllEscalations() {
+			if contacts, groups := esc.ContactsFor(svc); !hasContacts(contacts, groups) {
+				warnings = append(warnings, fmt.Sprintf("service '%s/%s': escalation for notifications %s notifies no contacts",
+					svc.Host.Name, svc.Description, notificationRange(esc.FirstNotification, esc.LastNotification)))
+			}
+		}
+	}
+	for _, hg := range store.HostGroups {
+		if len(hg.Members) == 0 {
+			warnings = append(warnings, fmt.Sprintf("hostgroup '%s': has no members", hg.Name))
+		}
+	}
+	for _, sg := range store.ServiceGroups {
+		if len(sg.Members) == 0 {
+			warnings = append(warnings, fmt.Sprintf("servicegroup '%s': has no members", sg.Name))
+		}
+	}
+	for _, cg := range store.ContactGroups {
+		if len(cg.Members) == 0 {
+			warnings = append(warnings, fmt.Sprintf("contactgroup '%s': has no members", cg.Name))
+		}
+	}
+	return warnings
+}
+
+func hasContacts(contacts []*objects.Contact, groups []*objects.ContactGroup) bool {
+	if len(contacts) > 0 {
+		return true
+	}
+	for _, cg := range groups {
+		if len(cg.Members) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationRange renders an escalation's notification numbers, with *
+// for an open end.
+func notificationRange(first, last int) string {
+	if last <= 0 {
+		return fmt.Sprintf("%d-*", first)
+	}
+	return fmt.Sprintf("%d-%d", first, last)
+}
+
 func checkCircularHostParents(store *objects.ObjectStore) error {
 	for _, h := range store.Hosts {
 		visited := make(map[string]bool)
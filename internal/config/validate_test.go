@@ -0,0 +1,35 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestWarnings_ContactsAndGroups(t *testing.T) {
+	store := objects.NewObjectStore()
+	oncall := &objects.Contact{Name: "oncall"}
+	store.AddContact(oncall)
+	store.AddContactGroup(&objects.ContactGroup{Name: "admins", Members: []*objects.Contact{oncall}})
+	store.AddContactGroup(&objects.ContactGroup{Name: "nobody"})
+	h := &objects.Host{Name: "web01", Alias: "web01", MaxCheckAttempts: 1}
+	store.AddHost(h)
+	store.AddService(&objects.Service{Host: h, Description: "HTTP", MaxCheckAttempts: 1,
+		CheckCommand: &objects.Command{Name: "check_http"}, Contacts: []*objects.Contact{oncall}})
+	store.AddHostGroup(&objects.HostGroup{Name: "web", Members: []*objects.Host{h}})
+	store.AddServiceGroup(&objects.ServiceGroup{Name: "empty"})
+
+	// Missing contacts are warnings, as in Nagios, and do not fail Validate.
+	if errs := Validate(store); len(errs) != 0 {
+		t.Errorf("Validate = %v, want no errors", errs)
+	}
+	want := []string{
+		"host 'web01': has no contacts or contact_groups",
+		"servicegroup 'empty': has no members",
+		"contactgroup 'nobody': has no members",
+	}
+	if got := Warnings(store); !reflect.DeepEqual(got, want) {
+		t.Errorf("Warnings = %q, want %q", got, want)
+	}
+}
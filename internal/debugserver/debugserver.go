@@ -0,0 +1,197 @@
+// Package debugserver serves net/http/pprof and a runtime metrics snapshot on
+// a separate, normally loopback-only, listener.
+package debugserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/logging"
+)
+
+// Server is the debug HTTP listener.
+type Server struct {
+	listen string
+	logger *logging.Logger
+	start  time.Time
+	server *http.Server
+
+	mu        sync.Mutex
+	gauges    map[string]func() float64
+	gaugeSets []func() map[string]float64
+	handlers  map[string]http.Handler
+}
+
+// New creates a debug server listening on addr, e.g. "127.0.0.1:6060".
+func New(addr string, logger *logging.Logger) *Server {
+	return &Server{
+		listen:   addr,
+		logger:   logger,
+		start:    time.Now(),
+		gauges:   make(map[string]func() float64),
+		handlers: make(map[string]http.Handler),
+	}
+}
+
+// AddGauge registers an application metric reported alongside the runtime
+// metrics. fn is called from the HTTP handler goroutine and must be safe for
+// concurrent use.
+func (s *Server) AddGauge(name string, fn func() float64) {
+	s.mu.Lock()
+	s.gauges[name] = fn
+	s.mu.Unlock()
+}
+
+// AddGauges registers a function reporting a variable set of metrics, e.g.
+// one per importance tier. The same rules as AddGauge apply.
+func (s *Server) AddGauges(fn func() map[string]float64) {
+	s.mu.Lock()
+	s.gaugeSets = append(s.gaugeSets, fn)
+	s.mu.Unlock()
+}
+
+// Handle registers an additional endpoint, e.g. a diagnostics report. Call
+// before Start.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mu.Lock()
+	s.handlers[pattern] = h
+	s.mu.Unlock()
+}
+
+// Handler returns the debug mux: /debug/pprof/*, /debug/runtime and any
+// endpoints added with Handle.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/runtime", s.handleRuntime)
+	s.mu.Lock()
+	for pattern, h := range s.handlers {
+		mux.Handle(pattern, h)
+	}
+	s.mu.Unlock()
+	return mux
+}
+
+// Start begins listening.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return fmt.Errorf("debug: listen %s: %w", s.listen, err)
+	}
+	// No WriteTimeout: CPU profiles and traces stream for ?seconds=N.
+	s.server = &http.Server{
+		Handler:     s.Handler(),
+		ReadTimeout: 30 * time.Second,
+		IdleTimeout: 60 * time.Second,
+	}
+	go func() {
+		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.Log("Debug server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the listener down.
+func (s *Server) Stop() {
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	}
+}
+
+// RuntimeStats is the /debug/runtime response.
+type RuntimeStats struct {
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	Goroutines    int     `json:"goroutines"`
+	GOMAXPROCS    int     `json:"gomaxprocs"`
+	NumCPU        int     `json:"num_cpu"`
+	HeapAlloc     uint64  `json:"heap_alloc_bytes"`
+	HeapInuse     uint64  `json:"heap_inuse_bytes"`
+	HeapSys       uint64  `json:"heap_sys_bytes"`
+	HeapObjects   uint64  `json:"heap_objects"`
+	TotalAlloc    uint64  `json:"total_alloc_bytes"`
+	Sys           uint64  `json:"sys_bytes"`
+	NextGC        uint64  `json:"next_gc_bytes"`
+	NumGC         uint32  `json:"num_gc"`
+	GCCPUFraction float64 `json:"gc_cpu_fraction"`
+	GCPauseTotal  float64 `json:"gc_pause_total_seconds"`
+	GCPauseLast   float64 `json:"gc_pause_last_seconds"`
+	GCPauseP50    float64 `json:"gc_pause_p50_seconds"` // over the last 256 cycles
+	GCPauseP99    float64 `json:"gc_pause_p99_seconds"`
+	GCPauseMax    float64 `json:"gc_pause_max_seconds"`
+	LastGC        int64   `json:"last_gc"`
+
+	Gauges map[string]float64 `json:"gauges,omitempty"`
+}
+
+// Snapshot collects the current runtime statistics. ReadMemStats briefly
+// stops the world, so this is meant for on-demand debugging, not polling at
+// high frequency.
+func (s *Server) Snapshot() RuntimeStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	st := RuntimeStats{
+		UptimeSeconds: time.Since(s.start).Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumCPU:        runtime.NumCPU(),
+		HeapAlloc:     ms.HeapAlloc,
+		HeapInuse:     ms.HeapInuse,
+		HeapSys:       ms.HeapSys,
+		HeapObjects:   ms.HeapObjects,
+		TotalAlloc:    ms.TotalAlloc,
+		Sys:           ms.Sys,
+		NextGC:        ms.NextGC,
+		NumGC:         ms.NumGC,
+		GCCPUFraction: ms.GCCPUFraction,
+		GCPauseTotal:  float64(ms.PauseTotalNs) / 1e9,
+	}
+	if ms.LastGC > 0 {
+		st.LastGC = int64(ms.LastGC / 1e9)
+	}
+	if n := min(int(ms.NumGC), len(ms.PauseNs)); n > 0 {
+		st.GCPauseLast = float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e9
+		pauses := slices.Clone(ms.PauseNs[:n])
+		slices.Sort(pauses)
+		st.GCPauseP50 = float64(pauses[n*50/100]) / 1e9
+		st.GCPauseP99 = float64(pauses[n*99/100]) / 1e9
+		st.GCPauseMax = float64(pauses[n-1]) / 1e9
+	}
+
+	s.mu.Lock()
+	if len(s.gauges)+len(s.gaugeSets) > 0 {
+		st.Gauges = make(map[string]float64, len(s.gauges))
+		for name, fn := range s.gauges {
+			st.Gauges[name] = fn()
+		}
+		for _, fn := range s.gaugeSets {
+			for name, v := range fn() {
+				st.Gauges[name] = v
+			}
+		}
+	}
+	s.mu.Unlock()
+	return st
+}
+
+func (s *Server) handleRuntime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(s.Snapshot())
+}
@@ -0,0 +1,69 @@
+package debugserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestRuntimeEndpoint(t *testing.T) {
+	s := New("127.0.0.1:0", nil)
+	s.AddGauge("checks_running", func() float64 { return 7 })
+	runtime.GC()
+
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/debug/runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st RuntimeStats
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Goroutines == 0 || st.HeapAlloc == 0 || st.NumGC == 0 {
+		t.Errorf("runtime stats not populated: %+v", st)
+	}
+	if st.GCPauseMax < st.GCPauseP50 {
+		t.Errorf("gc pause max %v < p50 %v", st.GCPauseMax, st.GCPauseP50)
+	}
+	if st.Gauges["checks_running"] != 7 {
+		t.Errorf("gauge checks_running = %v, want 7", st.Gauges["checks_running"])
+	}
+}
+
+func TestPprofEndpoint(t *testing.T) {
+	ts := httptest.NewServer(New("127.0.0.1:0", nil).Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("pprof goroutine status = %d", resp.StatusCode)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	s := New("127.0.0.1:0", nil)
+	s.Handle("/debug/stale", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/debug/stale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("custom handler status = %d", resp.StatusCode)
+	}
+}
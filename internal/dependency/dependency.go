@@ -4,6 +4,7 @@ package dependency
 import (
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/config"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -50,7 +51,7 @@ func checkServiceDeps(deps []*objects.ServiceDependency, depType int, softStateD
 		}
 
 		// Check dependency period
-		if dep.DependencyPeriod != nil && !objects.InTimeperiod(dep.DependencyPeriod, time.Now()) {
+		if dep.DependencyPeriod != nil && !config.CheckTime(dep.DependencyPeriod, time.Now()) {
 			continue
 		}
 
@@ -116,7 +117,7 @@ func checkHostDeps(deps []*objects.HostDependency, depType int, softStateDeps bo
 			continue
 		}
 
-		if dep.DependencyPeriod != nil && !objects.InTimeperiod(dep.DependencyPeriod, time.Now()) {
+		if dep.DependencyPeriod != nil && !config.CheckTime(dep.DependencyPeriod, time.Now()) {
 			continue
 		}
 
@@ -0,0 +1,226 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Edge types in a dependency Graph.
+const (
+	EdgeParent            = "parent"             // host parents
+	EdgeServiceParent     = "service_parent"     // service_parents
+	EdgeHostDependency    = "host_dependency"    // hostdependency objects
+	EdgeServiceDependency = "service_dependency" // servicedependency objects
+)
+
+// Graph is the dependency graph of the configuration: host parents,
+// service parents and host and service dependencies. Edges point from the
+// master to the dependent object, so everything reachable from a node is
+// its blast radius. Only objects with at least one edge are included.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Node is a host, or a service identified as "host;service".
+type Node struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"` // "host" or "service"
+	HostName           string `json:"host_name"`
+	ServiceDescription string `json:"service_description,omitempty"`
+	State              string `json:"state"`
+}
+
+// Edge is one dependency of To on From.
+type Edge struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Type         string `json:"type"`
+	Execution    bool   `json:"execution,omitempty"`    // dependencies: has execution_failure_options
+	Notification bool   `json:"notification,omitempty"` // dependencies: has notification_failure_options
+	Period       string `json:"dependency_period,omitempty"`
+}
+
+// BuildGraph builds the dependency graph of the store. Nodes and edges are
+// sorted so the output is stable between runs.
+func BuildGraph(store *objects.ObjectStore) *Graph {
+	g := &Graph{}
+	seen := make(map[string]bool)
+	addHost := func(h *objects.Host) string {
+		if !seen[h.Name] {
+			seen[h.Name] = true
+			g.Nodes = append(g.Nodes, Node{ID: h.Name, Type: "host", HostName: h.Name, State: hostState(h)})
+		}
+		return h.Name
+	}
+	addService := func(svc *objects.Service) string {
+		id := svc.Host.Name + ";" + svc.Description
+		if !seen[id] {
+			seen[id] = true
+			g.Nodes = append(g.Nodes, Node{ID: id, Type: "service", HostName: svc.Host.Name,
+				ServiceDescription: svc.Description, State: serviceState(svc)})
+		}
+		return id
+	}
+
+	for _, h := range store.Hosts {
+		for _, p := range h.Parents {
+			g.Edges = append(g.Edges, Edge{From: addHost(p), To: addHost(h), Type: EdgeParent})
+		}
+	}
+	for _, svc := range store.Services {
+		if svc.Host == nil {
+			continue
+		}
+		for _, p := range svc.ServiceParents {
+			if p.Host != nil {
+				g.Edges = append(g.Edges, Edge{From: addService(p), To: addService(svc), Type: EdgeServiceParent})
+			}
+		}
+	}
+	for _, d := range store.HostDependencies {
+		if d.Host == nil || d.DependentHost == nil {
+			continue
+		}
+		g.Edges = append(g.Edges, Edge{
+			From:         addHost(d.Host),
+			To:           addHost(d.DependentHost),
+			Type:         EdgeHostDependency,
+			Execution:    d.ExecutionFailureOptions != 0,
+			Notification: d.NotificationFailureOptions != 0,
+			Period:       periodName(d.DependencyPeriod),
+		})
+	}
+	for _, d := range store.ServiceDependencies {
+		if d.Service == nil || d.Service.Host == nil || d.DependentService == nil || d.DependentService.Host == nil {
+			continue
+		}
+		g.Edges = append(g.Edges, Edge{
+			From:         addService(d.Service),
+			To:           addService(d.DependentService),
+			Type:         EdgeServiceDependency,
+			Execution:    d.ExecutionFailureOptions != 0,
+			Notification: d.NotificationFailureOptions != 0,
+			Period:       periodName(d.DependencyPeriod),
+		})
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Type < b.Type
+	})
+	return g
+}
+
+func hostState(h *objects.Host) string {
+	if !h.HasBeenChecked {
+		return "PENDING"
+	}
+	return objects.HostStateName(h.CurrentState)
+}
+
+func serviceState(svc *objects.Service) string {
+	if !svc.HasBeenChecked {
+		return "PENDING"
+	}
+	return objects.ServiceStateName(svc.CurrentState)
+}
+
+func periodName(tp *objects.Timeperiod) string {
+	if tp == nil {
+		return ""
+	}
+	return tp.Name
+}
+
+// WriteJSON writes the graph as indented JSON.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// stateColors colors DOT nodes by state.
+var stateColors = map[string]string{
+	"UP":          "green",
+	"OK":          "green",
+	"WARNING":     "yellow",
+	"DOWN":        "red",
+	"CRITICAL":    "red",
+	"UNREACHABLE": "orange",
+	"UNKNOWN":     "orange",
+}
+
+// edgeStyles draws parent relations solid and dependencies dashed.
+var edgeStyles = map[string]string{
+	EdgeParent:            "solid",
+	EdgeServiceParent:     "solid",
+	EdgeHostDependency:    "dashed",
+	EdgeServiceDependency: "dashed",
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Hosts are boxes and
+// services ellipses, filled by state.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n\trankdir=LR;\n\tnode [style=filled];\n")
+	for _, n := range g.Nodes {
+		shape, label := "box", n.HostName
+		if n.Type == "service" {
+			shape, label = "ellipse", n.HostName+"\n"+n.ServiceDescription
+		}
+		color, ok := stateColors[n.State]
+		if !ok {
+			color = "lightgray"
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=%s, fillcolor=%s];\n", dotQuote(n.ID), dotQuote(label), shape, color)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s, style=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Type), edgeStyles[e.Type])
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes s as a DOT string, escaping quotes, backslashes and
+// newlines.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// GraphHandler serves the dependency graph as JSON, or as DOT with
+// ?format=dot.
+func GraphHandler(store *objects.ObjectStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store.Mu.RLock()
+		g := BuildGraph(store)
+		store.Mu.RUnlock()
+		switch r.URL.Query().Get("format") {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			g.WriteJSON(w)
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+			g.WriteDOT(w)
+		default:
+			http.Error(w, "format must be json or dot", http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package dependency
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestBuildGraph(t *testing.T) {
+	store := objects.NewObjectStore()
+	router := &objects.Host{Name: "router", HasBeenChecked: true, CurrentState: objects.HostDown}
+	web := &objects.Host{Name: "web", Parents: []*objects.Host{router}}
+	lonely := &objects.Host{Name: "lonely"}
+	for _, h := range []*objects.Host{router, web, lonely} {
+		store.AddHost(h)
+	}
+	db := &objects.Service{Host: router, Description: "DB", HasBeenChecked: true, CurrentState: objects.ServiceCritical}
+	app := &objects.Service{Host: web, Description: `App "v2"`}
+	store.AddService(db)
+	store.AddService(app)
+	store.AddServiceDependency(&objects.ServiceDependency{
+		Host: router, Service: db, DependentHost: web, DependentService: app,
+		NotificationFailureOptions: objects.OptCritical,
+	})
+
+	g := BuildGraph(store)
+	var ids []string
+	for _, n := range g.Nodes {
+		ids = append(ids, n.ID+"="+n.State)
+	}
+	if got, want := strings.Join(ids, " "), `router=DOWN router;DB=CRITICAL web=PENDING web;App "v2"=PENDING`; got != want {
+		t.Errorf("nodes: got %s, want %s", got, want)
+	}
+	want := []Edge{
+		{From: "router", To: "web", Type: EdgeParent},
+		{From: "router;DB", To: `web;App "v2"`, Type: EdgeServiceDependency, Notification: true},
+	}
+	if len(g.Edges) != len(want) {
+		t.Fatalf("edges: got %+v", g.Edges)
+	}
+	for i := range want {
+		if g.Edges[i] != want[i] {
+			t.Errorf("edge %d: got %+v, want %+v", i, g.Edges[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var back Graph
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil || len(back.Nodes) != 4 {
+		t.Errorf("JSON round trip: %v %+v", err, back)
+	}
+
+	buf.Reset()
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, s := range []string{
+		`"router" [label="router", shape=box, fillcolor=red];`,
+		`"router;DB" -> "web;App \"v2\"" [label="service_dependency", style=dashed];`,
+	} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("DOT output missing %s:\n%s", s, dot)
+		}
+	}
+}
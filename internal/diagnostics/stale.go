@@ -0,0 +1,204 @@
+// Package diagnostics reports configuration rot that only shows up at
+// runtime: objects that are never checked, hosts nobody attached services
+// to, contacts that can never receive a notification, and services whose
+// timeperiods rule out ever notifying. -v validates references; this catches
+// definitions that are valid but useless.
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/config"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// StaleReport is the result of BuildStaleReport.
+type StaleReport struct {
+	GeneratedAt          time.Time        `json:"generated_at"`
+	ProgramStart         time.Time        `json:"program_start"`
+	UncheckedServices    []StaleService   `json:"unchecked_services"`
+	HostsWithoutServices []string         `json:"hosts_without_services"`
+	UnnotifiableContacts []StaleContact   `json:"unnotifiable_contacts"`
+	PeriodMismatches     []PeriodMismatch `json:"period_mismatches"`
+}
+
+// StaleService is a service without a check result since program start.
+type StaleService struct {
+	Host         string `json:"host_name"`
+	Service      string `json:"service_description"`
+	Reason       string `json:"reason"`
+	ConfigSource string `json:"config_source,omitempty"`
+}
+
+// StaleContact is a contact that no notification can reach.
+type StaleContact struct {
+	Name         string   `json:"contact_name"`
+	Reasons      []string `json:"reasons"`
+	ConfigSource string   `json:"config_source,omitempty"`
+}
+
+// PeriodMismatch is a service whose check_period and notification_period
+// never overlap, so a state change detected by a check can never notify.
+type PeriodMismatch struct {
+	Host               string `json:"host_name"`
+	Service            string `json:"service_description"`
+	CheckPeriod        string `json:"check_period"`
+	NotificationPeriod string `json:"notification_period"`
+	ConfigSource       string `json:"config_source,omitempty"`
+}
+
+// BuildStaleReport inspects the object store. The caller must hold at least
+// a read lock on store.Mu. Timeperiod overlap is computed once per distinct
+// pair of periods.
+func BuildStaleReport(store *objects.ObjectStore, gs *objects.GlobalState, now time.Time) *StaleReport {
+	r := &StaleReport{
+		GeneratedAt:          now,
+		ProgramStart:         gs.ProgramStart,
+		UncheckedServices:    []StaleService{},
+		HostsWithoutServices: []string{},
+		UnnotifiableContacts: []StaleContact{},
+		PeriodMismatches:     []PeriodMismatch{},
+	}
+
+	for _, svc := range store.Services {
+		if svc.HasBeenChecked && !svc.LastCheck.Before(gs.ProgramStart) {
+			continue
+		}
+		reason := "no check result since program start"
+		switch {
+		case !svc.ActiveChecksEnabled && !svc.PassiveChecksEnabled:
+			reason = "active and passive checks are disabled"
+		case !svc.ActiveChecksEnabled:
+			reason = "passive only, no result submitted since program start"
+		case svc.CheckCommand == nil:
+			reason = "no check command"
+		}
+		r.UncheckedServices = append(r.UncheckedServices, StaleService{svc.Host.Name, svc.Description, reason, svc.ConfigSource})
+	}
+
+	for _, h := range store.Hosts {
+		if len(h.Services) == 0 {
+			r.HostsWithoutServices = append(r.HostsWithoutServices, h.Name)
+		}
+	}
+
+	overlap := make(map[[2]*objects.Timeperiod]bool)
+	overlaps := func(a, b *objects.Timeperiod) bool {
+		key := [2]*objects.Timeperiod{a, b}
+		v, ok := overlap[key]
+		if !ok {
+			v = config.TimeperiodsOverlap(a, b, now)
+			overlap[key] = v
+		}
+		return v
+	}
+
+	for _, c := range store.Contacts {
+		hostReasons := unnotifiable("host", c.HostNotificationsEnabled, len(c.HostNotificationCommands)+len(c.HostNotificationFallback), c.HostNotificationOptions, c.HostNotificationPeriod, overlaps)
+		svcReasons := unnotifiable("service", c.ServiceNotificationsEnabled, len(c.ServiceNotificationCommands)+len(c.ServiceNotificationFallback), c.ServiceNotificationOptions, c.ServiceNotificationPeriod, overlaps)
+		if len(hostReasons) > 0 && len(svcReasons) > 0 {
+			r.UnnotifiableContacts = append(r.UnnotifiableContacts, StaleContact{c.Name, append(hostReasons, svcReasons...), c.ConfigSource})
+		}
+	}
+
+	for _, svc := range store.Services {
+		if svc.CheckPeriod == nil || svc.NotificationPeriod == nil || !svc.NotificationsEnabled {
+			continue
+		}
+		if !overlaps(svc.CheckPeriod, svc.NotificationPeriod) {
+			r.PeriodMismatches = append(r.PeriodMismatches, PeriodMismatch{
+				svc.Host.Name, svc.Description, svc.CheckPeriod.Name, svc.NotificationPeriod.Name, svc.ConfigSource,
+			})
+		}
+	}
+
+	sort.Slice(r.UncheckedServices, func(i, j int) bool {
+		a, b := r.UncheckedServices[i], r.UncheckedServices[j]
+		return a.Host < b.Host || (a.Host == b.Host && a.Service < b.Service)
+	})
+	sort.Strings(r.HostsWithoutServices)
+	sort.Slice(r.UnnotifiableContacts, func(i, j int) bool {
+		return r.UnnotifiableContacts[i].Name < r.UnnotifiableContacts[j].Name
+	})
+	sort.Slice(r.PeriodMismatches, func(i, j int) bool {
+		a, b := r.PeriodMismatches[i], r.PeriodMismatches[j]
+		return a.Host < b.Host || (a.Host == b.Host && a.Service < b.Service)
+	})
+	return r
+}
+
+// unnotifiable lists why one side (host or service) of a contact can never
+// notify; empty means it can.
+func unnotifiable(kind string, enabled bool, commands int, options uint32, period *objects.Timeperiod, overlaps func(a, b *objects.Timeperiod) bool) []string {
+	var reasons []string
+	if !enabled {
+		reasons = append(reasons, kind+" notifications disabled")
+	}
+	if commands == 0 {
+		reasons = append(reasons, "no "+kind+" notification commands")
+	}
+	if options == 0 {
+		reasons = append(reasons, kind+"_notification_options is n")
+	}
+	if period != nil && !overlaps(period, nil) {
+		reasons = append(reasons, kind+"_notification_period "+period.Name+" is never valid")
+	}
+	return reasons
+}
+
+// String renders the report as text.
+func (r *StaleReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Stale object report at %s (program start %s)\n",
+		r.GeneratedAt.Format(time.RFC1123), r.ProgramStart.Format(time.RFC1123))
+	fmt.Fprintf(&b, "\nServices never checked since program start: %d\n", len(r.UncheckedServices))
+	for _, s := range r.UncheckedServices {
+		fmt.Fprintf(&b, "  %s;%s: %s%s\n", s.Host, s.Service, s.Reason, sourceSuffix(s.ConfigSource))
+	}
+	fmt.Fprintf(&b, "\nHosts with no services: %d\n", len(r.HostsWithoutServices))
+	for _, h := range r.HostsWithoutServices {
+		fmt.Fprintf(&b, "  %s\n", h)
+	}
+	fmt.Fprintf(&b, "\nContacts that can never be notified: %d\n", len(r.UnnotifiableContacts))
+	for _, c := range r.UnnotifiableContacts {
+		fmt.Fprintf(&b, "  %s: %s%s\n", c.Name, strings.Join(c.Reasons, ", "), sourceSuffix(c.ConfigSource))
+	}
+	fmt.Fprintf(&b, "\nServices whose check_period never overlaps their notification_period: %d\n", len(r.PeriodMismatches))
+	for _, m := range r.PeriodMismatches {
+		fmt.Fprintf(&b, "  %s;%s: check_period %s, notification_period %s%s\n", m.Host, m.Service, m.CheckPeriod, m.NotificationPeriod, sourceSuffix(m.ConfigSource))
+	}
+	return b.String()
+}
+
+// sourceSuffix formats where an object was defined for the text report.
+func sourceSuffix(source string) string {
+	if source == "" {
+		return ""
+	}
+	return " (" + source + ")"
+}
+
+// StaleHandler serves BuildStaleReport as JSON, or as text with
+// ?format=text. It takes the store read lock for the duration of the report.
+func StaleHandler(store *objects.ObjectStore, gs *objects.GlobalState) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store.Mu.RLock()
+		report := BuildStaleReport(store, gs, time.Now())
+		store.Mu.RUnlock()
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, report.String())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	})
+}
@@ -0,0 +1,119 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func staleFixture() (*objects.ObjectStore, *objects.GlobalState) {
+	start := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	store := objects.NewObjectStore()
+	gs := &objects.GlobalState{ProgramStart: start}
+
+	work := &objects.Timeperiod{Name: "workhours"}
+	for d := 1; d <= 5; d++ {
+		work.Ranges[d] = "09:00-17:00"
+	}
+	weekend := &objects.Timeperiod{Name: "weekend"}
+	weekend.Ranges[0] = "00:00-24:00"
+	weekend.Ranges[6] = "00:00-24:00"
+	never := &objects.Timeperiod{Name: "none"}
+	notify := &objects.Command{Name: "notify-by-email"}
+
+	web := &objects.Host{Name: "web01"}
+	empty := &objects.Host{Name: "empty01"}
+	store.AddHost(web)
+	store.AddHost(empty)
+	checkCmd := &objects.Command{Name: "check_http"}
+	add := func(svc *objects.Service) {
+		svc.Host = web
+		store.AddService(svc)
+		web.Services = append(web.Services, svc)
+	}
+	add(&objects.Service{Description: "HTTP", CheckCommand: checkCmd, ActiveChecksEnabled: true,
+		HasBeenChecked: true, LastCheck: start.Add(time.Minute)})
+	add(&objects.Service{Description: "Stale", CheckCommand: checkCmd, ActiveChecksEnabled: true,
+		HasBeenChecked: true, LastCheck: start.Add(-time.Hour)})
+	add(&objects.Service{Description: "Passive", PassiveChecksEnabled: true})
+	add(&objects.Service{Description: "Backup", CheckCommand: checkCmd, ActiveChecksEnabled: true,
+		HasBeenChecked: true, LastCheck: start.Add(time.Minute),
+		NotificationsEnabled: true, CheckPeriod: weekend, NotificationPeriod: work})
+
+	store.AddContact(&objects.Contact{Name: "oncall",
+		HostNotificationsEnabled: true, ServiceNotificationsEnabled: true,
+		HostNotificationCommands: []*objects.Command{notify}, ServiceNotificationCommands: []*objects.Command{notify},
+		HostNotificationOptions: objects.OptDown, ServiceNotificationOptions: objects.OptCritical})
+	store.AddContact(&objects.Contact{Name: "nobody",
+		HostNotificationsEnabled: true, ServiceNotificationsEnabled: true,
+		HostNotificationOptions: objects.OptDown, ServiceNotificationOptions: objects.OptCritical})
+	store.AddContact(&objects.Contact{Name: "asleep",
+		HostNotificationsEnabled: true, ServiceNotificationsEnabled: true,
+		HostNotificationCommands: []*objects.Command{notify}, ServiceNotificationCommands: []*objects.Command{notify},
+		HostNotificationOptions: objects.OptDown, ServiceNotificationOptions: objects.OptCritical,
+		HostNotificationPeriod: never, ServiceNotificationPeriod: never})
+	return store, gs
+}
+
+func TestBuildStaleReport(t *testing.T) {
+	store, gs := staleFixture()
+	r := BuildStaleReport(store, gs, gs.ProgramStart.Add(time.Hour))
+
+	if len(r.UncheckedServices) != 2 || r.UncheckedServices[0].Service != "Passive" || r.UncheckedServices[1].Service != "Stale" {
+		t.Fatalf("unexpected unchecked services: %+v", r.UncheckedServices)
+	}
+	if !strings.HasPrefix(r.UncheckedServices[0].Reason, "passive only") {
+		t.Errorf("unexpected reason for passive service: %s", r.UncheckedServices[0].Reason)
+	}
+	if len(r.HostsWithoutServices) != 1 || r.HostsWithoutServices[0] != "empty01" {
+		t.Errorf("unexpected hosts without services: %v", r.HostsWithoutServices)
+	}
+	if len(r.UnnotifiableContacts) != 2 || r.UnnotifiableContacts[0].Name != "asleep" || r.UnnotifiableContacts[1].Name != "nobody" {
+		t.Fatalf("unexpected unnotifiable contacts: %+v", r.UnnotifiableContacts)
+	}
+	if got := strings.Join(r.UnnotifiableContacts[0].Reasons, ", "); !strings.Contains(got, "host_notification_period none is never valid") {
+		t.Errorf("unexpected reasons for asleep: %s", got)
+	}
+	if len(r.PeriodMismatches) != 1 || r.PeriodMismatches[0].Service != "Backup" {
+		t.Errorf("unexpected period mismatches: %+v", r.PeriodMismatches)
+	}
+}
+
+func TestStaleHandler(t *testing.T) {
+	store, gs := staleFixture()
+	ts := httptest.NewServer(StaleHandler(store, gs))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r StaleReport
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.HostsWithoutServices) != 1 {
+		t.Errorf("unexpected JSON report: %+v", r)
+	}
+
+	resp, err = http.Get(ts.URL + "?format=text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "Hosts with no services: 1\n  empty01") {
+		t.Errorf("unexpected text report:\n%s", body)
+	}
+}
@@ -0,0 +1,202 @@
+package downtime
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/config"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// BlackoutManager holds the blackout windows in effect: those defined in
+// the object config and those added at runtime by ADD_BLACKOUT.
+type BlackoutManager struct {
+	mu        sync.RWMutex
+	blackouts map[string]*objects.Blackout
+}
+
+// NewBlackoutManager creates a manager seeded with the store's configured
+// blackouts.
+func NewBlackoutManager(store *objects.ObjectStore) *BlackoutManager {
+	bm := &BlackoutManager{
+		blackouts: make(map[string]*objects.Blackout),
+	}
+	for _, b := range store.Blackouts {
+		bm.blackouts[b.Name] = b
+	}
+	return bm
+}
+
+// Reload replaces the configured blackouts with those of a reloaded
+// store. Runtime blackouts are kept, unless the config now defines one of
+// the same name.
+func (bm *BlackoutManager) Reload(store *objects.ObjectStore) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	for name, b := range bm.blackouts {
+		if !b.Runtime {
+			delete(bm.blackouts, name)
+		}
+	}
+	for _, b := range store.Blackouts {
+		bm.blackouts[b.Name] = b
+	}
+}
+
+// Add adds b, replacing any blackout with the same name. It reports whether
+// one was replaced.
+func (bm *BlackoutManager) Add(b *objects.Blackout) bool {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	_, replaced := bm.blackouts[b.Name]
+	bm.blackouts[b.Name] = b
+	return replaced
+}
+
+// Remove deletes the named blackout and reports whether it existed.
+// Removing a configured blackout lasts until the next restart.
+func (bm *BlackoutManager) Remove(name string) bool {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	_, ok := bm.blackouts[name]
+	delete(bm.blackouts, name)
+	return ok
+}
+
+// All returns every blackout sorted by name, expired ones included.
+func (bm *BlackoutManager) All() []*objects.Blackout {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	out := make([]*objects.Blackout, 0, len(bm.blackouts))
+	for _, b := range bm.blackouts {
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Expire removes runtime blackouts whose end time has passed and returns
+// their names.
+func (bm *BlackoutManager) Expire(now time.Time) []string {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	var expired []string
+	for name, b := range bm.blackouts {
+		if b.Runtime && !b.EndTime.IsZero() && !now.Before(b.EndTime) {
+			delete(bm.blackouts, name)
+			expired = append(expired, name)
+		}
+	}
+	sort.Strings(expired)
+	return expired
+}
+
+// HostBlackout returns an active blackout suppressing notifications for
+// h, or nil.
+func (bm *BlackoutManager) HostBlackout(h *objects.Host, now time.Time) *objects.Blackout {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	for _, b := range bm.blackouts {
+		if len(b.ServiceDescriptions) == 0 && len(b.ServiceGroups) == 0 && blackoutActive(b, now) && matchHost(b, h) {
+			return b
+		}
+	}
+	return nil
+}
+
+// ServiceBlackout returns an active blackout suppressing notifications for
+// svc, or nil.
+func (bm *BlackoutManager) ServiceBlackout(svc *objects.Service, now time.Time) *objects.Blackout {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	for _, b := range bm.blackouts {
+		if blackoutActive(b, now) && (svc.Host == nil || matchHost(b, svc.Host)) && matchService(b, svc) {
+			return b
+		}
+	}
+	return nil
+}
+
+func blackoutActive(b *objects.Blackout, now time.Time) bool {
+	if !b.StartTime.IsZero() && now.Before(b.StartTime) {
+		return false
+	}
+	if !b.EndTime.IsZero() && !now.Before(b.EndTime) {
+		return false
+	}
+	return b.Period == nil || config.CheckTime(b.Period, now)
+}
+
+// matchHost checks the host filters. Groups are looked up at match time so
+// hosts added to a group later are covered.
+func matchHost(b *objects.Blackout, h *objects.Host) bool {
+	if len(b.HostNames) == 0 && len(b.HostGroups) == 0 {
+		return true
+	}
+	if matchPatterns(b.HostNames, h.Name) {
+		return true
+	}
+	for _, hg := range h.HostGroups {
+		for _, name := range b.HostGroups {
+			if hg.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchService(b *objects.Blackout, svc *objects.Service) bool {
+	if len(b.ServiceDescriptions) == 0 && len(b.ServiceGroups) == 0 {
+		return true
+	}
+	if matchPatterns(b.ServiceDescriptions, svc.Description) {
+		return true
+	}
+	for _, sg := range svc.ServiceGroups {
+		for _, name := range b.ServiceGroups {
+			if sg.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchPatterns(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if WildcardMatch(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WildcardMatch matches s against a pattern where '*' is any run of
+// characters and '?' any one character. Unlike path.Match, '*' also
+// matches '/', which service descriptions such as "Disk /var" contain.
+func WildcardMatch(p, s string) bool {
+	star, mark := -1, 0
+	i, j := 0, 0
+	for j < len(s) {
+		switch {
+		case i < len(p) && (p[i] == '?' || p[i] == s[j]):
+			i++
+			j++
+		case i < len(p) && p[i] == '*':
+			star, mark = i, j
+			i++
+		case star >= 0:
+			i = star + 1
+			mark++
+			j = mark
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
@@ -0,0 +1,67 @@
+package downtime
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestBlackoutManager_Match(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	web := &objects.HostGroup{Name: "web"}
+	db01 := &objects.Host{Name: "db01"}
+	web01 := &objects.Host{Name: "web01", HostGroups: []*objects.HostGroup{web}}
+	http := &objects.Service{Host: web01, Description: "HTTP"}
+	disk := &objects.Service{Host: db01, Description: "Disk /var"}
+
+	store := objects.NewObjectStore()
+	store.Blackouts = []*objects.Blackout{{Name: "web", HostGroups: []string{"web"}}}
+	bm := NewBlackoutManager(store)
+
+	if bm.HostBlackout(web01, now) == nil || bm.ServiceBlackout(http, now) == nil {
+		t.Error("expected hostgroup blackout to cover web01 and its services")
+	}
+	if bm.HostBlackout(db01, now) != nil || bm.ServiceBlackout(disk, now) != nil {
+		t.Error("db01 is not in the blackout")
+	}
+
+	bm.Add(&objects.Blackout{Name: "disks", ServiceDescriptions: []string{"Disk *"},
+		StartTime: now.Add(-time.Hour), EndTime: now.Add(time.Hour), Runtime: true})
+	if bm.ServiceBlackout(disk, now) == nil {
+		t.Error("expected service pattern to match")
+	}
+	if bm.HostBlackout(db01, now) != nil {
+		t.Error("a blackout with service filters must not suppress host notifications")
+	}
+	if bm.ServiceBlackout(disk, now.Add(2*time.Hour)) != nil {
+		t.Error("blackout should not apply after its end time")
+	}
+
+	if got := bm.Expire(now.Add(2 * time.Hour)); len(got) != 1 || got[0] != "disks" {
+		t.Errorf("expected the runtime blackout to expire, got %v", got)
+	}
+	if !bm.Remove("web") || bm.Remove("web") || len(bm.All()) != 0 {
+		t.Error("unexpected Remove result")
+	}
+}
+
+func TestBlackoutManager_Reload(t *testing.T) {
+	store := objects.NewObjectStore()
+	store.Blackouts = []*objects.Blackout{{Name: "old"}, {Name: "kept"}}
+	bm := NewBlackoutManager(store)
+	bm.Add(&objects.Blackout{Name: "runtime", Runtime: true})
+
+	next := objects.NewObjectStore()
+	next.Blackouts = []*objects.Blackout{{Name: "kept"}, {Name: "new"}}
+	bm.Reload(next)
+
+	var names []string
+	for _, b := range bm.All() {
+		names = append(names, b.Name)
+	}
+	if got := strings.Join(names, ","); got != "kept,new,runtime" {
+		t.Errorf("blackouts after reload = %s", got)
+	}
+}
@@ -2,10 +2,12 @@
 package downtime
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/clock"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -25,31 +27,92 @@ type Comment struct {
 	Data               string
 }
 
-// CommentManager manages all comments.
+// CommentManager manages all comments. Comments are indexed by object,
+// author and entry type so per-row livestatus lookups and bulk deletes
+// don't scan every comment.
 type CommentManager struct {
 	mu       sync.RWMutex
 	comments map[uint64]*Comment
 	nextID   atomic.Uint64
+	clock    clock.Clock
+
+	byObject    map[string]map[uint64]*Comment // commentKey -> comments
+	byAuthor    map[string]map[uint64]*Comment
+	byEntryType map[int]map[uint64]*Comment
 }
 
 // NewCommentManager creates a new comment manager.
 func NewCommentManager(startID uint64) *CommentManager {
 	cm := &CommentManager{
-		comments: make(map[uint64]*Comment),
+		comments:    make(map[uint64]*Comment),
+		byObject:    make(map[string]map[uint64]*Comment),
+		byAuthor:    make(map[string]map[uint64]*Comment),
+		byEntryType: make(map[int]map[uint64]*Comment),
+		clock:       clock.Real,
 	}
 	cm.nextID.Store(startID)
 	return cm
 }
 
+// SetClock replaces the wall clock used for entry times and expiry, for
+// tests on a clock.Fake.
+func (cm *CommentManager) SetClock(c clock.Clock) { cm.clock = c }
+
+// commentKey identifies the object a comment is attached to. Host comments
+// use an empty service description.
+func commentKey(commentType int, hostName, svcDesc string) string {
+	if commentType == objects.HostCommentType {
+		svcDesc = ""
+	}
+	return hostName + "\t" + svcDesc
+}
+
+func indexAdd[K comparable](idx map[K]map[uint64]*Comment, key K, c *Comment) {
+	m := idx[key]
+	if m == nil {
+		m = make(map[uint64]*Comment)
+		idx[key] = m
+	}
+	m[c.CommentID] = c
+}
+
+func indexRemove[K comparable](idx map[K]map[uint64]*Comment, key K, id uint64) {
+	if m := idx[key]; m != nil {
+		delete(m, id)
+		if len(m) == 0 {
+			delete(idx, key)
+		}
+	}
+}
+
+// insert stores c and indexes it. Caller holds cm.mu.
+func (cm *CommentManager) insert(c *Comment) {
+	if old := cm.comments[c.CommentID]; old != nil {
+		cm.remove(old)
+	}
+	cm.comments[c.CommentID] = c
+	indexAdd(cm.byObject, commentKey(c.CommentType, c.HostName, c.ServiceDescription), c)
+	indexAdd(cm.byAuthor, c.Author, c)
+	indexAdd(cm.byEntryType, c.EntryType, c)
+}
+
+// remove deletes c and its index entries. Caller holds cm.mu.
+func (cm *CommentManager) remove(c *Comment) {
+	delete(cm.comments, c.CommentID)
+	indexRemove(cm.byObject, commentKey(c.CommentType, c.HostName, c.ServiceDescription), c.CommentID)
+	indexRemove(cm.byAuthor, c.Author, c.CommentID)
+	indexRemove(cm.byEntryType, c.EntryType, c.CommentID)
+}
+
 // Add adds a comment and returns its ID.
 func (cm *CommentManager) Add(c *Comment) uint64 {
 	id := cm.nextID.Add(1) - 1
 	c.CommentID = id
 	if c.EntryTime.IsZero() {
-		c.EntryTime = time.Now()
+		c.EntryTime = cm.clock.Now()
 	}
 	cm.mu.Lock()
-	cm.comments[id] = c
+	cm.insert(c)
 	cm.mu.Unlock()
 	return id
 }
@@ -57,16 +120,24 @@ func (cm *CommentManager) Add(c *Comment) uint64 {
 // AddWithID adds a comment with a specific ID (for retention restore).
 func (cm *CommentManager) AddWithID(c *Comment) {
 	cm.mu.Lock()
-	cm.comments[c.CommentID] = c
+	cm.insert(c)
 	cm.mu.Unlock()
-	// Ensure nextID stays ahead
+	raiseNextID(&cm.nextID, c.CommentID+1)
+}
+
+// SetNextID raises the next ID Add allocates to at least id, e.g. to the
+// next_comment_id restored from retention, so the IDs of deleted comments
+// are not handed out again. It never lowers it.
+func (cm *CommentManager) SetNextID(id uint64) {
+	raiseNextID(&cm.nextID, id)
+}
+
+// raiseNextID sets next to id unless it is already at least id.
+func raiseNextID(next *atomic.Uint64, id uint64) {
 	for {
-		cur := cm.nextID.Load()
-		if c.CommentID < cur {
-			break
-		}
-		if cm.nextID.CompareAndSwap(cur, c.CommentID+1) {
-			break
+		cur := next.Load()
+		if id <= cur || next.CompareAndSwap(cur, id) {
+			return
 		}
 	}
 }
@@ -74,7 +145,9 @@ func (cm *CommentManager) AddWithID(c *Comment) {
 // Delete removes a comment by ID.
 func (cm *CommentManager) Delete(id uint64) {
 	cm.mu.Lock()
-	delete(cm.comments, id)
+	if c := cm.comments[id]; c != nil {
+		cm.remove(c)
+	}
 	cm.mu.Unlock()
 }
 
@@ -85,60 +158,53 @@ func (cm *CommentManager) Get(id uint64) *Comment {
 	return cm.comments[id]
 }
 
-// DeleteAllForHost deletes all comments for a host.
-func (cm *CommentManager) DeleteAllForHost(hostName string) {
+// deleteForObject removes the object's comments accepted by match (all of
+// them if match is nil) and returns how many were deleted.
+func (cm *CommentManager) deleteForObject(key string, match func(*Comment) bool) int {
 	cm.mu.Lock()
-	for id, c := range cm.comments {
-		if c.HostName == hostName && c.CommentType == objects.HostCommentType {
-			delete(cm.comments, id)
+	defer cm.mu.Unlock()
+	n := 0
+	for _, c := range cm.byObject[key] {
+		if match == nil || match(c) {
+			cm.remove(c)
+			n++
 		}
 	}
-	cm.mu.Unlock()
+	return n
 }
 
-// DeleteAllForService deletes all comments for a specific service.
-func (cm *CommentManager) DeleteAllForService(hostName, svcDesc string) {
-	cm.mu.Lock()
-	for id, c := range cm.comments {
-		if c.HostName == hostName && c.ServiceDescription == svcDesc && c.CommentType == objects.ServiceCommentType {
-			delete(cm.comments, id)
-		}
-	}
-	cm.mu.Unlock()
+// DeleteAllForHost deletes all comments for a host and returns the count.
+func (cm *CommentManager) DeleteAllForHost(hostName string) int {
+	return cm.deleteForObject(commentKey(objects.HostCommentType, hostName, ""), nil)
 }
 
-// DeleteAckComments deletes non-persistent acknowledgement comments for a host.
+// DeleteAllForService deletes all comments for a specific service and
+// returns the count.
+func (cm *CommentManager) DeleteAllForService(hostName, svcDesc string) int {
+	return cm.deleteForObject(commentKey(objects.ServiceCommentType, hostName, svcDesc), nil)
+}
+
+func isNonPersistentAck(c *Comment) bool {
+	return c.EntryType == objects.AcknowledgementCommentEntry && !c.Persistent
+}
+
+// DeleteHostAckComments deletes non-persistent acknowledgement comments for a host.
 func (cm *CommentManager) DeleteHostAckComments(hostName string) {
-	cm.mu.Lock()
-	for id, c := range cm.comments {
-		if c.HostName == hostName && c.CommentType == objects.HostCommentType &&
-			c.EntryType == objects.AcknowledgementCommentEntry && !c.Persistent {
-			delete(cm.comments, id)
-		}
-	}
-	cm.mu.Unlock()
+	cm.deleteForObject(commentKey(objects.HostCommentType, hostName, ""), isNonPersistentAck)
 }
 
 // DeleteServiceAckComments deletes non-persistent acknowledgement comments for a service.
 func (cm *CommentManager) DeleteServiceAckComments(hostName, svcDesc string) {
-	cm.mu.Lock()
-	for id, c := range cm.comments {
-		if c.HostName == hostName && c.ServiceDescription == svcDesc &&
-			c.CommentType == objects.ServiceCommentType &&
-			c.EntryType == objects.AcknowledgementCommentEntry && !c.Persistent {
-			delete(cm.comments, id)
-		}
-	}
-	cm.mu.Unlock()
+	cm.deleteForObject(commentKey(objects.ServiceCommentType, hostName, svcDesc), isNonPersistentAck)
 }
 
 // ExpireComments removes expired comments.
 func (cm *CommentManager) ExpireComments() {
-	now := time.Now()
+	now := cm.clock.Now()
 	cm.mu.Lock()
-	for id, c := range cm.comments {
+	for _, c := range cm.comments {
 		if c.Expires && !c.ExpireTime.IsZero() && c.ExpireTime.Before(now) {
-			delete(cm.comments, id)
+			cm.remove(c)
 		}
 	}
 	cm.mu.Unlock()
@@ -155,29 +221,40 @@ func (cm *CommentManager) All() []*Comment {
 	return result
 }
 
-// ForHost returns all comments for a host.
+// ForHost returns all comments for a host, ordered by ID.
 func (cm *CommentManager) ForHost(hostName string) []*Comment {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-	var result []*Comment
-	for _, c := range cm.comments {
-		if c.HostName == hostName && c.CommentType == objects.HostCommentType {
-			result = append(result, c)
-		}
-	}
-	return result
+	return lookupIndex(cm, cm.byObject, commentKey(objects.HostCommentType, hostName, ""))
 }
 
-// ForService returns all comments for a service.
+// ForService returns all comments for a service, ordered by ID.
 func (cm *CommentManager) ForService(hostName, svcDesc string) []*Comment {
+	return lookupIndex(cm, cm.byObject, commentKey(objects.ServiceCommentType, hostName, svcDesc))
+}
+
+// ByAuthor returns all comments written by author, ordered by ID.
+func (cm *CommentManager) ByAuthor(author string) []*Comment {
+	return lookupIndex(cm, cm.byAuthor, author)
+}
+
+// ByEntryType returns all comments of an entry type (UserCommentEntry,
+// DowntimeCommentEntry, ...), ordered by ID.
+func (cm *CommentManager) ByEntryType(entryType int) []*Comment {
+	return lookupIndex(cm, cm.byEntryType, entryType)
+}
+
+func lookupIndex[K comparable](cm *CommentManager, idx map[K]map[uint64]*Comment, key K) []*Comment {
 	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-	var result []*Comment
-	for _, c := range cm.comments {
-		if c.HostName == hostName && c.ServiceDescription == svcDesc && c.CommentType == objects.ServiceCommentType {
-			result = append(result, c)
-		}
+	m := idx[key]
+	if len(m) == 0 {
+		cm.mu.RUnlock()
+		return nil
+	}
+	result := make([]*Comment, 0, len(m))
+	for _, c := range m {
+		result = append(result, c)
 	}
+	cm.mu.RUnlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].CommentID < result[j].CommentID })
 	return result
 }
 
@@ -115,3 +115,33 @@ func TestCommentManager_ForService(t *testing.T) {
 		t.Errorf("expected 1 HTTP comment, got %d", len(svcComments))
 	}
 }
+
+func TestCommentManager_IndexedQueries(t *testing.T) {
+	cm := NewCommentManager(1)
+	h1 := cm.Add(&Comment{CommentType: objects.HostCommentType, EntryType: objects.UserCommentEntry, HostName: "host1", Author: "alice"})
+	s1 := cm.Add(&Comment{CommentType: objects.ServiceCommentType, EntryType: objects.UserCommentEntry, HostName: "host1", ServiceDescription: "HTTP", Author: "alice"})
+	cm.Add(&Comment{CommentType: objects.ServiceCommentType, EntryType: objects.AcknowledgementCommentEntry, HostName: "host1", ServiceDescription: "HTTP", Author: "bob"})
+
+	if got := cm.ByAuthor("alice"); len(got) != 2 || got[0].CommentID != h1 || got[1].CommentID != s1 {
+		t.Errorf("ByAuthor(alice) = %v", got)
+	}
+	if got := cm.ByEntryType(objects.AcknowledgementCommentEntry); len(got) != 1 || got[0].Author != "bob" {
+		t.Errorf("ByEntryType(ack) = %v", got)
+	}
+	if got := cm.ForHost("host1"); len(got) != 1 || got[0].CommentID != h1 {
+		t.Errorf("ForHost should only return host comments, got %v", got)
+	}
+
+	if n := cm.DeleteAllForService("host1", "HTTP"); n != 2 {
+		t.Errorf("DeleteAllForService deleted %d, want 2", n)
+	}
+	if got := cm.ByAuthor("bob"); len(got) != 0 {
+		t.Errorf("author index not updated on delete: %v", got)
+	}
+	if got := cm.ForHost("host1"); len(got) != 1 {
+		t.Error("host comment should survive service bulk delete")
+	}
+	if n := cm.DeleteAllForHost("host1"); n != 1 || len(cm.All()) != 0 {
+		t.Errorf("DeleteAllForHost deleted %d, %d left", n, len(cm.All()))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/clock"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -51,6 +52,9 @@ type DowntimeManager struct {
 	store     *objects.ObjectStore
 	logger    Logger
 	notifier  Notifier
+	maxLength time.Duration // 0 = no cap
+	noOverlap bool
+	clock     clock.Clock
 }
 
 // NewDowntimeManager creates a new downtime manager.
@@ -59,6 +63,7 @@ func NewDowntimeManager(startID uint64, comments *CommentManager, store *objects
 		downtimes: make(map[uint64]*Downtime),
 		comments:  comments,
 		store:     store,
+		clock:     clock.Real,
 	}
 	dm.nextID.Store(startID)
 	return dm
@@ -70,6 +75,22 @@ func (dm *DowntimeManager) SetLogger(l Logger) { dm.logger = l }
 // SetNotifier sets the notifier.
 func (dm *DowntimeManager) SetNotifier(n Notifier) { dm.notifier = n }
 
+// SetMaxDuration caps how long a downtime Validate accepts may last: the
+// window of a fixed downtime, the duration of a flexible one. 0 disables
+// the cap.
+func (dm *DowntimeManager) SetMaxDuration(d time.Duration) { dm.maxLength = d }
+
+// SetNoOverlap sets whether downtimes requested by external commands are
+// refused when they overlap one on the same object (downtime_no_overlap).
+func (dm *DowntimeManager) SetNoOverlap(on bool) { dm.noOverlap = on }
+
+// NoOverlap reports the SetNoOverlap setting.
+func (dm *DowntimeManager) NoOverlap() bool { return dm.noOverlap }
+
+// SetClock replaces the wall clock used for entry times, flexible starts,
+// expiry and ScheduleEnd timers, for tests on a clock.Fake.
+func (dm *DowntimeManager) SetClock(c clock.Clock) { dm.clock = c }
+
 func (dm *DowntimeManager) log(format string, args ...interface{}) {
 	if dm.logger != nil {
 		dm.logger.Log(format, args...)
@@ -78,13 +99,19 @@ func (dm *DowntimeManager) log(format string, args ...interface{}) {
 
 // Schedule adds a new downtime entry and returns its ID.
 func (dm *DowntimeManager) Schedule(d *Downtime) uint64 {
-	id := dm.nextID.Add(1) - 1
-	d.DowntimeID = id
+	id, _ := dm.schedule(d, false)
+	return id
+}
+
+// schedule adds d, refusing it with an *OverlapError when noOverlap is set
+// and d overlaps a downtime on the same object. The check and the insert
+// happen under one lock so concurrent requests cannot both pass.
+func (dm *DowntimeManager) schedule(d *Downtime, noOverlap bool) (uint64, error) {
 	if d.EntryTime.IsZero() {
-		d.EntryTime = time.Now()
+		d.EntryTime = dm.clock.Now()
 	}
 
-	// Add downtime comment
+	// Downtime comment, added with the downtime below
 	commentType := objects.HostCommentType
 	if d.Type == objects.ServiceDowntimeType {
 		commentType = objects.ServiceCommentType
@@ -105,9 +132,18 @@ func (dm *DowntimeManager) Schedule(d *Downtime) uint64 {
 		Author:             d.Author,
 		Data:               commentText,
 	}
-	d.CommentID = dm.comments.Add(c)
 
 	dm.mu.Lock()
+	if noOverlap {
+		start, end := d.Window()
+		if ids := dm.overlappingLocked(d.HostName, d.ServiceDescription, start, end); len(ids) > 0 {
+			dm.mu.Unlock()
+			return 0, &OverlapError{IDs: ids}
+		}
+	}
+	id := dm.nextID.Add(1) - 1
+	d.DowntimeID = id
+	d.CommentID = dm.comments.Add(c)
 	dm.downtimes[id] = d
 	dm.mu.Unlock()
 
@@ -116,7 +152,91 @@ func (dm *DowntimeManager) Schedule(d *Downtime) uint64 {
 		dm.incrementPending(d)
 	}
 
-	return id
+	return id, nil
+}
+
+// ValidationError is returned by Validate for a downtime that cannot be
+// scheduled as requested.
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string { return e.Reason }
+
+// Validate checks a downtime request before it is scheduled: the window must
+// end after it starts and not already be over, a flexible downtime needs a
+// positive duration, and neither may exceed the
+// SetMaxDuration cap. Schedule itself does not validate, so downtimes
+// restored from retention are accepted as saved.
+func (dm *DowntimeManager) Validate(d *Downtime, now time.Time) error {
+	if !d.EndTime.After(d.StartTime) {
+		return &ValidationError{fmt.Sprintf("end time %s is not after start time %s",
+			d.EndTime.Format(time.RFC3339), d.StartTime.Format(time.RFC3339))}
+	}
+	if !d.EndTime.After(now) {
+		return &ValidationError{fmt.Sprintf("end time %s is in the past", d.EndTime.Format(time.RFC3339))}
+	}
+	length := d.EndTime.Sub(d.StartTime)
+	if !d.Fixed {
+		if d.Duration <= 0 {
+			return &ValidationError{"flexible downtime needs a positive duration"}
+		}
+		length = d.Duration
+	}
+	if dm.maxLength > 0 && length > dm.maxLength {
+		return &ValidationError{fmt.Sprintf("downtime of %s exceeds max_downtime_duration of %s", length, dm.maxLength)}
+	}
+	return nil
+}
+
+// OverlapError is returned by ScheduleNoOverlap when the new downtime
+// overlaps existing downtimes on the same object.
+type OverlapError struct {
+	IDs []uint64
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("overlaps existing downtime %v", e.IDs)
+}
+
+// ScheduleNoOverlap schedules d unless its window overlaps a downtime
+// already scheduled on the same host or service.
+func (dm *DowntimeManager) ScheduleNoOverlap(d *Downtime) (uint64, error) {
+	return dm.schedule(d, true)
+}
+
+// ChildDowntimes returns a copy of the host downtime d for every host
+// below host in the parents tree, each host once, parents before their
+// children. The copies are triggered by triggeredBy, 0 for none. This is
+// what SCHEDULE_AND_PROPAGATE_HOST_DOWNTIME (0) and
+// SCHEDULE_AND_PROPAGATE_TRIGGERED_HOST_DOWNTIME (the parent's ID) schedule.
+func ChildDowntimes(host *objects.Host, d *Downtime, triggeredBy uint64) []*Downtime {
+	var out []*Downtime
+	seen := map[*objects.Host]bool{host: true}
+	var walk func(h *objects.Host)
+	walk = func(h *objects.Host) {
+		for _, child := range h.Children {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			out = append(out, &Downtime{
+				Type:        objects.HostDowntimeType,
+				HostName:    child.Name,
+				EntryTime:   d.EntryTime,
+				StartTime:   d.StartTime,
+				EndTime:     d.EndTime,
+				Fixed:       d.Fixed,
+				TriggeredBy: triggeredBy,
+				Duration:    d.Duration,
+				Author:      d.Author,
+				Comment:     d.Comment,
+			})
+			walk(child)
+		}
+	}
+	walk(host)
+	return out
 }
 
 // ScheduleWithID adds a downtime with a specific ID (for retention restore).
@@ -124,15 +244,13 @@ func (dm *DowntimeManager) ScheduleWithID(d *Downtime) {
 	dm.mu.Lock()
 	dm.downtimes[d.DowntimeID] = d
 	dm.mu.Unlock()
-	for {
-		cur := dm.nextID.Load()
-		if d.DowntimeID < cur {
-			break
-		}
-		if dm.nextID.CompareAndSwap(cur, d.DowntimeID+1) {
-			break
-		}
-	}
+	raiseNextID(&dm.nextID, d.DowntimeID+1)
+}
+
+// SetNextID raises the next ID Schedule allocates to at least id, e.g. to
+// the next_downtime_id restored from retention. It never lowers it.
+func (dm *DowntimeManager) SetNextID(id uint64) {
+	raiseNextID(&dm.nextID, id)
 }
 
 // Unschedule cancels a downtime.
@@ -232,6 +350,12 @@ func (dm *DowntimeManager) HandleStart(id uint64) {
 	}
 }
 
+// ScheduleEnd calls HandleEnd for the downtime at end, on the manager's
+// clock. Timers do not survive a restart; CheckExpired catches those.
+func (dm *DowntimeManager) ScheduleEnd(id uint64, end time.Time) {
+	dm.clock.AfterFunc(end.Sub(dm.clock.Now()), func() { dm.HandleEnd(id) })
+}
+
 // HandleEnd processes a downtime end event.
 func (dm *DowntimeManager) HandleEnd(id uint64) {
 	dm.mu.RLock()
@@ -240,6 +364,11 @@ func (dm *DowntimeManager) HandleEnd(id uint64) {
 	if !ok || !d.IsInEffect {
 		return
 	}
+	// The window's end timer does not end a flexible downtime that started
+	// late in the window; the timer set when it started does.
+	if !d.Fixed && !d.FlexDowntimeStart.IsZero() && d.FlexEndTime().After(dm.clock.Now()) {
+		return
+	}
 
 	dm.stopDowntime(d, false)
 
@@ -312,69 +441,53 @@ func (dm *DowntimeManager) stopDowntime(d *Downtime, cancelled bool) {
 	}
 }
 
-// CheckPendingFlexHostDowntime checks if a flexible downtime should start for a host.
+// CheckPendingFlexHostDowntime starts the pending flexible downtimes a host
+// problem triggers: those of the host itself and, as in Nagios, those of
+// its services, whose checks may be suppressed while the host is down.
 func (dm *DowntimeManager) CheckPendingFlexHostDowntime(hostName string, currentState int) {
 	if currentState == objects.HostUp {
 		return
 	}
-	now := time.Now()
-	dm.mu.RLock()
-	var toStart []uint64
-	for _, d := range dm.downtimes {
-		if d.Type != objects.HostDowntimeType || d.HostName != hostName {
-			continue
-		}
-		if d.Fixed || d.IsInEffect || d.TriggeredBy != 0 {
-			continue
-		}
-		if now.Before(d.StartTime) || now.After(d.EndTime) {
-			continue
-		}
-		toStart = append(toStart, d.DowntimeID)
-	}
-	dm.mu.RUnlock()
-
-	for _, id := range toStart {
-		dm.mu.RLock()
-		d := dm.downtimes[id]
-		dm.mu.RUnlock()
-		if d != nil {
-			d.FlexDowntimeStart = now
-			dm.HandleStart(id)
-		}
-	}
+	dm.startPendingFlex(func(d *Downtime) bool {
+		return d.HostName == hostName
+	})
 }
 
-// CheckPendingFlexServiceDowntime checks if a flexible downtime should start for a service.
+// CheckPendingFlexServiceDowntime starts the pending flexible downtimes of
+// a service when it has a problem or its host is not UP.
 func (dm *DowntimeManager) CheckPendingFlexServiceDowntime(hostName, svcDesc string, currentState int) {
 	if currentState == objects.ServiceOK {
-		return
+		if hst := dm.store.GetHost(hostName); hst == nil || hst.CurrentState == objects.HostUp {
+			return
+		}
 	}
-	now := time.Now()
+	dm.startPendingFlex(func(d *Downtime) bool {
+		return d.Type == objects.ServiceDowntimeType && d.HostName == hostName && d.ServiceDescription == svcDesc
+	})
+}
+
+// startPendingFlex starts the flexible downtimes selected by match whose
+// window is open now and that are not triggered by another downtime. Each
+// runs for its duration from now, which may reach past its window's end.
+func (dm *DowntimeManager) startPendingFlex(match func(*Downtime) bool) {
+	now := dm.clock.Now()
 	dm.mu.RLock()
-	var toStart []uint64
+	var toStart []*Downtime
 	for _, d := range dm.downtimes {
-		if d.Type != objects.ServiceDowntimeType || d.HostName != hostName || d.ServiceDescription != svcDesc {
-			continue
-		}
-		if d.Fixed || d.IsInEffect || d.TriggeredBy != 0 {
+		if d.Fixed || d.IsInEffect || d.TriggeredBy != 0 || !match(d) {
 			continue
 		}
 		if now.Before(d.StartTime) || now.After(d.EndTime) {
 			continue
 		}
-		toStart = append(toStart, d.DowntimeID)
+		toStart = append(toStart, d)
 	}
 	dm.mu.RUnlock()
 
-	for _, id := range toStart {
-		dm.mu.RLock()
-		d := dm.downtimes[id]
-		dm.mu.RUnlock()
-		if d != nil {
-			d.FlexDowntimeStart = now
-			dm.HandleStart(id)
-		}
+	for _, d := range toStart {
+		d.FlexDowntimeStart = now
+		dm.HandleStart(d.DowntimeID)
+		dm.ScheduleEnd(d.DowntimeID, d.FlexEndTime())
 	}
 }
 
@@ -382,14 +495,20 @@ func (dm *DowntimeManager) CheckPendingFlexServiceDowntime(hostName, svcDesc str
 // pending flex downtimes whose window passed) and active downtimes whose
 // EndTime has elapsed. For active downtimes this routes through HandleEnd so
 // scheduled_downtime_depth is decremented exactly once. This sweep is the
-// durable backstop for the goroutine-timer in cmd/gogios's SCHEDULE_*_DOWNTIME
+// durable backstop for the ScheduleEnd timers set by cmd/gogios's SCHEDULE_*_DOWNTIME
 // handlers, which is lost on process restart.
 func (dm *DowntimeManager) CheckExpired() {
-	now := time.Now()
+	now := dm.clock.Now()
 	dm.mu.RLock()
 	var expiredPending, expiredActive []uint64
 	for id, d := range dm.downtimes {
-		if d.EndTime.IsZero() || !d.EndTime.Before(now) {
+		// An active flexible downtime ends its duration after it started,
+		// which may be before or after the end of its window.
+		end := d.EndTime
+		if d.IsInEffect {
+			end = d.FlexEndTime()
+		}
+		if end.IsZero() || !end.Before(now) {
 			continue
 		}
 		if d.IsInEffect {
@@ -548,6 +667,84 @@ func (dm *DowntimeManager) All() []*Downtime {
 	return result
 }
 
+// Window returns the span d can cover. Fixed downtimes cover start to end.
+// A flexible downtime that has started covers its duration from the actual
+// start; one still pending could start as late as end, so its window runs
+// to end plus duration.
+func (d *Downtime) Window() (time.Time, time.Time) {
+	switch {
+	case d.Fixed:
+		return d.StartTime, d.EndTime
+	case d.IsInEffect && !d.FlexDowntimeStart.IsZero():
+		return d.FlexDowntimeStart, d.FlexDowntimeStart.Add(d.Duration)
+	default:
+		return d.StartTime, d.EndTime.Add(d.Duration)
+	}
+}
+
+// CoversAt reports whether d puts its object in downtime at t. A pending
+// flexible downtime covers nothing until a state change starts it.
+func (d *Downtime) CoversAt(t time.Time) bool {
+	if !d.Fixed && !d.IsInEffect {
+		return false
+	}
+	start, end := d.Window()
+	return !t.Before(start) && t.Before(end)
+}
+
+// ActiveAt returns the downtimes covering the given host (svcDesc empty) or
+// service at t. Host downtimes are not counted for services; callers that
+// want inherited coverage query the host as well.
+func (dm *DowntimeManager) ActiveAt(hostName, svcDesc string, t time.Time) []*Downtime {
+	var result []*Downtime
+	for _, d := range dm.All() {
+		if d.HostName == hostName && d.ServiceDescription == svcDesc && d.CoversAt(t) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// InDowntimeAt reports whether the host or service is in downtime at t.
+func (dm *DowntimeManager) InDowntimeAt(hostName, svcDesc string, t time.Time) bool {
+	return len(dm.ActiveAt(hostName, svcDesc, t)) > 0
+}
+
+// Overlapping returns downtimes whose window intersects [from, to), sorted
+// by start time. An empty hostName matches every object; otherwise only
+// downtimes on exactly that host (svcDesc empty) or service are returned.
+func (dm *DowntimeManager) Overlapping(hostName, svcDesc string, from, to time.Time) []*Downtime {
+	var result []*Downtime
+	for _, d := range dm.All() {
+		if hostName != "" && (d.HostName != hostName || d.ServiceDescription != svcDesc) {
+			continue
+		}
+		start, end := d.Window()
+		if start.Before(to) && end.After(from) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// overlappingLocked returns the IDs, in ascending order, of the downtimes on
+// exactly the given host or service whose window intersects [from, to).
+// Caller holds mu.
+func (dm *DowntimeManager) overlappingLocked(hostName, svcDesc string, from, to time.Time) []uint64 {
+	var ids []uint64
+	for id, d := range dm.downtimes {
+		if d.HostName != hostName || d.ServiceDescription != svcDesc {
+			continue
+		}
+		start, end := d.Window()
+		if start.Before(to) && end.After(from) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
 // NextID returns the next downtime ID value.
 func (dm *DowntimeManager) NextID() uint64 {
 	return dm.nextID.Load()
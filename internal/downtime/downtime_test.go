@@ -1,9 +1,13 @@
 package downtime
 
 import (
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/clock"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -14,13 +18,16 @@ func (m *mockLogger) Log(format string, args ...interface{}) {}
 type mockNotifier struct {
 	hostNotifs    int
 	serviceNotifs int
+	types         []int // notification types in the order sent
 }
 
 func (m *mockNotifier) SendHostNotification(hostName string, ntype int, author, data string, options int) {
 	m.hostNotifs++
+	m.types = append(m.types, ntype)
 }
 func (m *mockNotifier) SendServiceNotification(hostName, svcDesc string, ntype int, author, data string, options int) {
 	m.serviceNotifs++
+	m.types = append(m.types, ntype)
 }
 
 func newTestSetup() (*DowntimeManager, *CommentManager, *objects.ObjectStore, *mockNotifier) {
@@ -422,3 +429,367 @@ func TestScheduleDowntime_SortOrder(t *testing.T) {
 		t.Error("expected downtimes sorted by start time")
 	}
 }
+
+func TestDowntimeCoverageAndOverlap(t *testing.T) {
+	dm, _, _, _ := newTestSetup()
+
+	base := time.Unix(1700000000, 0)
+	fixedID := dm.Schedule(&Downtime{
+		Type:      objects.HostDowntimeType,
+		HostName:  "host1",
+		StartTime: base,
+		EndTime:   base.Add(time.Hour),
+		Fixed:     true,
+	})
+	flexID := dm.Schedule(&Downtime{
+		Type:      objects.HostDowntimeType,
+		HostName:  "host1",
+		StartTime: base.Add(2 * time.Hour),
+		EndTime:   base.Add(3 * time.Hour),
+		Duration:  30 * time.Minute,
+	})
+
+	if !dm.InDowntimeAt("host1", "", base.Add(30*time.Minute)) {
+		t.Error("expected host1 in fixed downtime at +30m")
+	}
+	if dm.InDowntimeAt("host1", "", base.Add(time.Hour)) {
+		t.Error("fixed downtime end should be exclusive")
+	}
+	if dm.InDowntimeAt("host1", "", base.Add(150*time.Minute)) {
+		t.Error("pending flexible downtime should not cover anything")
+	}
+
+	// A pending flexible downtime can still run until end+duration.
+	got := dm.Overlapping("host1", "", base.Add(200*time.Minute), base.Add(4*time.Hour))
+	if len(got) != 1 || got[0].DowntimeID != flexID {
+		t.Errorf("expected only the flexible downtime to overlap, got %v", got)
+	}
+	if got := dm.Overlapping("", "", base, base.Add(4*time.Hour)); len(got) != 2 {
+		t.Errorf("expected 2 downtimes across all objects, got %d", len(got))
+	}
+	if got := dm.Overlapping("host1", "svc", base, base.Add(4*time.Hour)); len(got) != 0 {
+		t.Errorf("host downtimes should not match a service query, got %d", len(got))
+	}
+
+	_, err := dm.ScheduleNoOverlap(&Downtime{
+		Type:      objects.HostDowntimeType,
+		HostName:  "host1",
+		StartTime: base.Add(30 * time.Minute),
+		EndTime:   base.Add(90 * time.Minute),
+		Fixed:     true,
+	})
+	oe, ok := err.(*OverlapError)
+	if !ok || len(oe.IDs) != 1 || oe.IDs[0] != fixedID {
+		t.Fatalf("expected overlap with downtime %d, got %v", fixedID, err)
+	}
+	if _, err := dm.ScheduleNoOverlap(&Downtime{
+		Type:      objects.HostDowntimeType,
+		HostName:  "host1",
+		StartTime: base.Add(time.Hour),
+		EndTime:   base.Add(2 * time.Hour),
+		Fixed:     true,
+	}); err != nil {
+		t.Errorf("adjacent downtime should be accepted: %v", err)
+	}
+}
+
+func TestScheduleNoOverlapConcurrent(t *testing.T) {
+	dm, _, _, _ := newTestSetup()
+	base := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	var wg sync.WaitGroup
+	var scheduled atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := dm.ScheduleNoOverlap(&Downtime{
+				Type:      objects.HostDowntimeType,
+				HostName:  "host1",
+				StartTime: base,
+				EndTime:   base.Add(time.Hour),
+				Fixed:     true,
+			}); err == nil {
+				scheduled.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := scheduled.Load(); n != 1 {
+		t.Errorf("%d overlapping downtimes scheduled, want 1", n)
+	}
+	if n := len(dm.All()); n != 1 {
+		t.Errorf("%d downtimes stored, want 1", n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dm, _, _, _ := newTestSetup()
+	dm.SetMaxDuration(24 * time.Hour)
+	now := time.Now()
+
+	cases := []struct {
+		name string
+		d    Downtime
+		ok   bool
+	}{
+		{"fixed", Downtime{StartTime: now, EndTime: now.Add(time.Hour), Fixed: true}, true},
+		{"end before start", Downtime{StartTime: now.Add(time.Hour), EndTime: now, Fixed: true}, false},
+		{"already over", Downtime{StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-time.Hour), Fixed: true}, false},
+		{"fixed too long", Downtime{StartTime: now, EndTime: now.Add(48 * time.Hour), Fixed: true}, false},
+		{"flexible", Downtime{StartTime: now, EndTime: now.Add(48 * time.Hour), Duration: time.Hour}, true},
+		{"flexible without duration", Downtime{StartTime: now, EndTime: now.Add(time.Hour)}, false},
+		{"flexible too long", Downtime{StartTime: now, EndTime: now.Add(time.Hour), Duration: 48 * time.Hour}, false},
+	}
+	for _, c := range cases {
+		err := dm.Validate(&c.d, now)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: got %v", c.name, err)
+		}
+	}
+
+	dm.SetMaxDuration(0)
+	long := Downtime{StartTime: now, EndTime: now.Add(365 * 24 * time.Hour), Fixed: true}
+	if err := dm.Validate(&long, now); err != nil {
+		t.Errorf("expected no cap with max duration 0, got %v", err)
+	}
+}
+
+// ScheduleEnd ends a downtime on the manager's clock, so a fake clock can
+// run a whole downtime window without sleeping.
+func TestScheduleEnd_FakeClock(t *testing.T) {
+	dm, cm, store, _ := newTestSetup()
+	fc := clock.NewFake(time.Unix(1700000000, 0))
+	dm.SetClock(fc)
+	cm.SetClock(fc)
+
+	now := fc.Now()
+	d := &Downtime{
+		Type:      objects.HostDowntimeType,
+		HostName:  "host1",
+		StartTime: now,
+		EndTime:   now.Add(time.Hour),
+		Fixed:     true,
+	}
+	id := dm.Schedule(d)
+	if !d.EntryTime.Equal(now) {
+		t.Errorf("entry time %v, want the fake clock's %v", d.EntryTime, now)
+	}
+	dm.HandleStart(id)
+	dm.ScheduleEnd(id, d.EndTime)
+
+	h := store.GetHost("host1")
+	fc.Advance(59 * time.Minute)
+	if h.ScheduledDowntimeDepth != 1 {
+		t.Fatalf("expected depth 1 before the end time, got %d", h.ScheduledDowntimeDepth)
+	}
+	fc.Advance(time.Minute)
+	if h.ScheduledDowntimeDepth != 0 {
+		t.Errorf("expected depth 0 at the end time, got %d", h.ScheduledDowntimeDepth)
+	}
+	if len(cm.All()) != 0 {
+		t.Errorf("expected the downtime comment to be deleted, got %d comments", len(cm.All()))
+	}
+}
+
+func TestChildDowntimes(t *testing.T) {
+	root := &objects.Host{Name: "router"}
+	sw1 := &objects.Host{Name: "sw1", Parents: []*objects.Host{root}}
+	sw2 := &objects.Host{Name: "sw2", Parents: []*objects.Host{root}}
+	// web has two parents and must only get one downtime.
+	web := &objects.Host{Name: "web", Parents: []*objects.Host{sw1, sw2}}
+	root.Children = []*objects.Host{sw1, sw2}
+	sw1.Children = []*objects.Host{web}
+	sw2.Children = []*objects.Host{web}
+
+	now := time.Now()
+	d := &Downtime{
+		Type:      objects.HostDowntimeType,
+		HostName:  "router",
+		StartTime: now,
+		EndTime:   now.Add(time.Hour),
+		Fixed:     true,
+		Author:    "admin",
+		Comment:   "core upgrade",
+	}
+	got := ChildDowntimes(root, d, 42)
+	var names []string
+	for _, c := range got {
+		names = append(names, c.HostName)
+		if c.TriggeredBy != 42 || !c.Fixed || !c.EndTime.Equal(d.EndTime) || c.Comment != d.Comment {
+			t.Errorf("unexpected copy for %s: %+v", c.HostName, c)
+		}
+	}
+	if want := "sw1 web sw2"; strings.Join(names, " ") != want {
+		t.Errorf("got %v, want %s", names, want)
+	}
+	if len(ChildDowntimes(web, d, 0)) != 0 {
+		t.Error("a leaf host should have no child downtimes")
+	}
+}
+
+// newFlexSetup adds service svc1 on host1 and a fake clock to the test setup.
+func newFlexSetup() (*DowntimeManager, *objects.ObjectStore, *mockNotifier, *clock.Fake) {
+	dm, cm, store, notifier := newTestSetup()
+	store.AddService(&objects.Service{Host: store.GetHost("host1"), Description: "svc1"})
+	fc := clock.NewFake(time.Unix(1700000000, 0))
+	dm.SetClock(fc)
+	cm.SetClock(fc)
+	return dm, store, notifier, fc
+}
+
+func flexServiceDowntime(now time.Time) *Downtime {
+	return &Downtime{
+		Type:               objects.ServiceDowntimeType,
+		HostName:           "host1",
+		ServiceDescription: "svc1",
+		StartTime:          now,
+		EndTime:            now.Add(time.Hour),
+		Duration:           30 * time.Minute,
+	}
+}
+
+func TestFlexServiceDowntime_StartsOnHostDown(t *testing.T) {
+	dm, store, notifier, fc := newFlexSetup()
+	dm.Schedule(flexServiceDowntime(fc.Now()))
+	svc := store.GetService("host1", "svc1")
+
+	dm.CheckPendingFlexHostDowntime("host1", objects.HostUp)
+	if svc.ScheduledDowntimeDepth != 0 {
+		t.Fatalf("expected no downtime while the host is up, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+
+	dm.CheckPendingFlexHostDowntime("host1", objects.HostDown)
+	if svc.ScheduledDowntimeDepth != 1 {
+		t.Fatalf("expected the host problem to start the service downtime, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+	if notifier.serviceNotifs != 1 || notifier.types[0] != objects.NotificationDowntimeStart {
+		t.Errorf("expected one DOWNTIMESTART service notification, got %v", notifier.types)
+	}
+	if h := store.GetHost("host1"); h.ScheduledDowntimeDepth != 0 {
+		t.Errorf("expected the host itself not to enter downtime, got depth %d", h.ScheduledDowntimeDepth)
+	}
+}
+
+func TestFlexServiceDowntime_StartsOnOKServiceWhenHostNotUp(t *testing.T) {
+	dm, store, _, fc := newFlexSetup()
+	dm.Schedule(flexServiceDowntime(fc.Now()))
+	svc := store.GetService("host1", "svc1")
+
+	dm.CheckPendingFlexServiceDowntime("host1", "svc1", objects.ServiceOK)
+	if svc.ScheduledDowntimeDepth != 0 {
+		t.Fatalf("expected an OK service on an UP host not to start downtime, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+
+	store.GetHost("host1").CurrentState = objects.HostUnreachable
+	dm.CheckPendingFlexServiceDowntime("host1", "svc1", objects.ServiceOK)
+	if svc.ScheduledDowntimeDepth != 1 {
+		t.Errorf("expected an unreachable host to start the service downtime, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+}
+
+// A flexible downtime lasts its duration from the first problem, even when
+// that runs past the end of the window it could start in.
+func TestFlexDowntime_DurationFromStart(t *testing.T) {
+	dm, store, notifier, fc := newFlexSetup()
+	d := flexServiceDowntime(fc.Now())
+	id := dm.Schedule(d)
+	dm.ScheduleEnd(id, d.EndTime) // as the SCHEDULE_SVC_DOWNTIME handler does
+	svc := store.GetService("host1", "svc1")
+
+	fc.Advance(50 * time.Minute)
+	dm.CheckPendingFlexServiceDowntime("host1", "svc1", objects.ServiceCritical)
+	if svc.ScheduledDowntimeDepth != 1 || !d.FlexDowntimeStart.Equal(fc.Now()) {
+		t.Fatalf("expected the downtime to start at the problem, got depth %d start %v", svc.ScheduledDowntimeDepth, d.FlexDowntimeStart)
+	}
+
+	fc.Advance(10 * time.Minute) // end of the window
+	dm.CheckExpired()
+	if svc.ScheduledDowntimeDepth != 1 || dm.Get(id) == nil {
+		t.Fatalf("expected the downtime to outlast its window, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+
+	fc.Advance(20 * time.Minute) // 30 minutes after the start
+	if svc.ScheduledDowntimeDepth != 0 || dm.Get(id) != nil {
+		t.Errorf("expected the downtime to end after its duration, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+	if svc.PendingFlexDowntime != 0 {
+		t.Errorf("expected no pending flexible downtime, got %d", svc.PendingFlexDowntime)
+	}
+	want := []int{objects.NotificationDowntimeStart, objects.NotificationDowntimeEnd}
+	if len(notifier.types) != 2 || notifier.types[0] != want[0] || notifier.types[1] != want[1] {
+		t.Errorf("expected DOWNTIMESTART then DOWNTIMEEND, got %v", notifier.types)
+	}
+}
+
+// A flexible downtime that starts early in its window ends after its
+// duration, before the window closes.
+func TestFlexDowntime_EndsBeforeWindow(t *testing.T) {
+	dm, store, _, fc := newFlexSetup()
+	d := flexServiceDowntime(fc.Now())
+	id := dm.Schedule(d)
+	svc := store.GetService("host1", "svc1")
+
+	fc.Advance(5 * time.Minute)
+	dm.CheckPendingFlexServiceDowntime("host1", "svc1", objects.ServiceWarning)
+	fc.Advance(30 * time.Minute)
+	if svc.ScheduledDowntimeDepth != 0 || dm.Get(id) != nil {
+		t.Errorf("expected the downtime to end 30 minutes after it started, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+}
+
+// Problems outside the window don't start the downtime, and a window that
+// closes without a problem ends it with a DOWNTIMEEND notification.
+func TestFlexDowntime_NoProblemInWindow(t *testing.T) {
+	dm, store, notifier, fc := newFlexSetup()
+	d := flexServiceDowntime(fc.Now().Add(time.Hour))
+	id := dm.Schedule(d)
+	svc := store.GetService("host1", "svc1")
+	if svc.PendingFlexDowntime != 1 {
+		t.Fatalf("expected 1 pending flexible downtime, got %d", svc.PendingFlexDowntime)
+	}
+
+	dm.CheckPendingFlexServiceDowntime("host1", "svc1", objects.ServiceCritical)
+	if d.IsInEffect {
+		t.Fatal("expected a problem before the window not to start the downtime")
+	}
+
+	fc.Advance(2*time.Hour + time.Minute)
+	dm.CheckPendingFlexServiceDowntime("host1", "svc1", objects.ServiceCritical)
+	if d.IsInEffect {
+		t.Fatal("expected a problem after the window not to start the downtime")
+	}
+	dm.CheckExpired()
+	if dm.Get(id) != nil || svc.PendingFlexDowntime != 0 {
+		t.Errorf("expected the expired downtime to be removed, pending %d", svc.PendingFlexDowntime)
+	}
+	if len(notifier.types) != 1 || notifier.types[0] != objects.NotificationDowntimeEnd {
+		t.Errorf("expected only DOWNTIMEEND, got %v", notifier.types)
+	}
+}
+
+// A flexible downtime triggered by another starts with it, not on a problem.
+func TestFlexDowntime_TriggeredWaitsForTrigger(t *testing.T) {
+	dm, store, _, fc := newFlexSetup()
+	now := fc.Now()
+	parentID := dm.Schedule(&Downtime{
+		Type:      objects.HostDowntimeType,
+		HostName:  "host1",
+		StartTime: now.Add(time.Hour),
+		EndTime:   now.Add(2 * time.Hour),
+		Fixed:     true,
+	})
+	d := flexServiceDowntime(now)
+	d.TriggeredBy = parentID
+	dm.Schedule(d)
+	svc := store.GetService("host1", "svc1")
+
+	dm.CheckPendingFlexHostDowntime("host1", objects.HostDown)
+	if svc.ScheduledDowntimeDepth != 0 {
+		t.Fatalf("expected the triggered downtime to wait, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+	dm.HandleStart(parentID)
+	if svc.ScheduledDowntimeDepth != 1 {
+		t.Errorf("expected the trigger to start the downtime, got depth %d", svc.ScheduledDowntimeDepth)
+	}
+}
@@ -0,0 +1,144 @@
+package eventbus
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+	"github.com/oceanplexian/gogios/internal/protowire"
+)
+
+// fakeNATS accepts one client, completes the handshake and forwards every
+// PUB it receives on the returned channel as "subject payload".
+func fakeNATS(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	msgs := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "PING":
+				conn.Write([]byte("PONG\r\n"))
+			case strings.HasPrefix(line, "PUB "):
+				parts := strings.Fields(line)
+				n, _ := strconv.Atoi(parts[len(parts)-1])
+				buf := make([]byte, n+2)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					return
+				}
+				msgs <- parts[1] + " " + string(buf[:n])
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String(), msgs
+}
+
+func TestPublisherNATS(t *testing.T) {
+	url, msgs := fakeNATS(t)
+	p, err := New(Config{Driver: "nats", URL: url, SubjectPrefix: "mon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Start()
+	defer p.Stop()
+
+	p.PublishCheckResult(&objects.CheckResult{
+		HostName: "web01", ServiceDescription: "HTTP", ReturnCode: 2,
+		Output: "CRITICAL", FinishTime: time.Unix(1700000000, 0),
+	})
+	host := &objects.Host{Name: "web01", CurrentAttempt: 1}
+	p.PublishHostStateChange(host, objects.HostUp, objects.HostDown, false)
+
+	for i, wantSubject := range []string{"mon.check_result", "mon.state_change"} {
+		select {
+		case m := <-msgs:
+			subject, payload, _ := strings.Cut(m, " ")
+			if subject != wantSubject {
+				t.Errorf("message %d: subject %q, want %q", i, subject, wantSubject)
+			}
+			var ev Event
+			if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+				t.Fatalf("message %d: bad json %q: %v", i, payload, err)
+			}
+			if ev.HostName != "web01" {
+				t.Errorf("message %d: host %q", i, ev.HostName)
+			}
+			if i == 1 && (ev.State != "DOWN" || ev.PreviousState != "UP") {
+				t.Errorf("state change: got %s -> %s", ev.PreviousState, ev.State)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for message %d", i)
+		}
+	}
+}
+
+func TestPublisherProtobuf(t *testing.T) {
+	url, msgs := fakeNATS(t)
+	p, err := New(Config{Driver: "nats", URL: url, Format: "protobuf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Start()
+	defer p.Stop()
+
+	p.PublishCheckResult(&objects.CheckResult{
+		HostName: "web01", ServiceDescription: "HTTP", ReturnCode: 2, Output: "CRITICAL",
+		ExecutionTime: 1.5, FinishTime: time.Unix(1700000000, 0), ExecutionID: 7,
+	})
+	select {
+	case m := <-msgs:
+		subject, payload, _ := strings.Cut(m, " ")
+		if subject != "gogios.check_result" {
+			t.Errorf("subject %q", subject)
+		}
+		fields, err := protowire.DecodeFields([]byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[int]protowire.Field{}
+		for _, f := range fields {
+			got[f.Number] = f
+		}
+		if string(got[1].Data) != "check_result" || got[2].Num != 1700000000 || string(got[3].Data) != "web01" ||
+			string(got[4].Data) != "HTTP" || got[6].Num != 2 || math.Float64frombits(got[8].Num) != 1.5 || got[16].Num != 7 {
+			t.Errorf("unexpected event %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event")
+	}
+}
+
+func TestPublisherRejectsUnsupported(t *testing.T) {
+	if _, err := New(Config{Driver: "kafka", URL: "localhost:9092"}); err == nil {
+		t.Error("expected a kafka url without the scheme to be rejected")
+	}
+	if _, err := New(Config{Driver: "nats", URL: "nats://localhost:4222", Format: "avro"}); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+	if _, err := New(Config{Driver: "amqp", URL: "amqp://localhost"}); err == nil {
+		t.Error("expected an unknown driver to be rejected")
+	}
+}
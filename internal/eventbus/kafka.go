@@ -0,0 +1,401 @@
+package eventbus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kafka API keys and the versions used. Metadata v4 and Produce v3 with
+// record batch v2 are the oldest versions current brokers still accept.
+const (
+	kafkaProduce         = 0
+	kafkaMetadata        = 3
+	kafkaProduceVersion  = 3
+	kafkaMetadataVersion = 4
+)
+
+const kafkaTimeout = 10 * time.Second
+
+var (
+	errKafkaShort = errors.New("kafka: short response")
+	crc32c        = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// kafkaTransport produces events with the Kafka binary protocol. It looks
+// up partition leaders with a Metadata request and routes each event to the
+// partition its key hashes to. A batch of events becomes one Produce request
+// per leader broker, with one record batch per partition, sent with acks=1.
+// TLS, SASL and compression are not supported.
+//
+// The transport is only used from the publisher's delivery goroutine, so
+// it needs no locking.
+type kafkaTransport struct {
+	brokers     []string // bootstrap addresses
+	bootstrap   *kafkaConn
+	correlation int32
+	nodes       map[int32]string   // broker node id -> host:port
+	leaders     map[string][]int32 // topic -> leader node id of each partition
+	conns       map[int32]*kafkaConn
+}
+
+type kafkaConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newKafkaTransport(brokers []string) *kafkaTransport {
+	return &kafkaTransport{brokers: brokers}
+}
+
+// parseKafkaURL parses kafka://host[:port][,host[:port]...] into bootstrap
+// addresses, defaulting the port to 9092.
+func parseKafkaURL(s string) ([]string, error) {
+	rest, ok := strings.CutPrefix(s, "kafka://")
+	if !ok {
+		return nil, fmt.Errorf("invalid kafka url %q (want kafka://host:port[,host:port...])", s)
+	}
+	rest, _, _ = strings.Cut(rest, "/")
+	var brokers []string
+	for _, b := range strings.Split(rest, ",") {
+		if b = strings.TrimSpace(b); b == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(b); err != nil {
+			b = net.JoinHostPort(b, "9092")
+		}
+		brokers = append(brokers, b)
+	}
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("invalid kafka url %q: no brokers", s)
+	}
+	return brokers, nil
+}
+
+func (k *kafkaTransport) connect() error {
+	var lastErr error
+	for _, addr := range k.brokers {
+		c, err := dialKafka(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		k.bootstrap = c
+		k.nodes = make(map[int32]string)
+		k.leaders = make(map[string][]int32)
+		k.conns = make(map[int32]*kafkaConn)
+		return nil
+	}
+	return lastErr
+}
+
+func dialKafka(addr string) (*kafkaConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, kafkaTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaConn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func (k *kafkaTransport) close() {
+	if k.bootstrap != nil {
+		k.bootstrap.conn.Close()
+		k.bootstrap = nil
+	}
+	for id, c := range k.conns {
+		c.conn.Close()
+		delete(k.conns, id)
+	}
+}
+
+func (k *kafkaTransport) publish(msgs []message) error {
+	if k.bootstrap == nil {
+		return errors.New("kafka: not connected")
+	}
+	// leader node id -> topic -> partition -> messages, in queue order.
+	requests := make(map[int32]map[string]map[int32][]message)
+	for _, m := range msgs {
+		leaders, ok := k.leaders[m.subject]
+		if !ok {
+			var err error
+			if leaders, err = k.metadata(m.subject); err != nil {
+				return err
+			}
+		}
+		partition := int32(0)
+		if m.key != "" {
+			h := fnv.New32a()
+			h.Write([]byte(m.key))
+			partition = int32(h.Sum32() % uint32(len(leaders)))
+		}
+		leader := leaders[partition]
+		if requests[leader] == nil {
+			requests[leader] = make(map[string]map[int32][]message)
+		}
+		if requests[leader][m.subject] == nil {
+			requests[leader][m.subject] = make(map[int32][]message)
+		}
+		requests[leader][m.subject][partition] = append(requests[leader][m.subject][partition], m)
+	}
+	now := time.Now()
+	for leader, topics := range requests {
+		if err := k.produce(leader, topics, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// produce sends one Produce request to the leader broker holding a record
+// batch for each of the given partitions.
+func (k *kafkaTransport) produce(leader int32, topics map[string]map[int32][]message, ts time.Time) error {
+	c, err := k.leader(leader)
+	if err != nil {
+		return err
+	}
+
+	var w kafkaWriter
+	w.int16(-1) // transactional_id
+	w.int16(1)  // acks
+	w.int32(int32(kafkaTimeout / time.Millisecond))
+	w.int32(int32(len(topics)))
+	for topic, partitions := range topics {
+		w.string(topic)
+		w.int32(int32(len(partitions)))
+		for partition, msgs := range partitions {
+			w.int32(partition)
+			batch := kafkaRecordBatch(msgs, ts)
+			w.int32(int32(len(batch)))
+			w.buf = append(w.buf, batch...)
+		}
+	}
+
+	resp, err := k.roundTrip(c, kafkaProduce, kafkaProduceVersion, w.buf)
+	if err != nil {
+		return err
+	}
+	r := kafkaReader{b: resp}
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		topic := r.string()
+		for parts := r.int32(); parts > 0 && r.err == nil; parts-- {
+			partition := r.int32()
+			code := r.int16()
+			r.int64()
+			r.int64()
+			if code != 0 && r.err == nil {
+				// Most produce errors mean the leader moved; look it up again.
+				delete(k.leaders, topic)
+				return fmt.Errorf("kafka: produce to %s/%d failed with error code %d", topic, partition, code)
+			}
+		}
+	}
+	return r.err
+}
+
+// metadata fetches the partition leaders of topic from the bootstrap
+// broker. Brokers with auto.create.topics.enable create it on first use.
+func (k *kafkaTransport) metadata(topic string) ([]int32, error) {
+	var w kafkaWriter
+	w.int32(1)
+	w.string(topic)
+	w.int8(1) // allow_auto_topic_creation
+	resp, err := k.roundTrip(k.bootstrap, kafkaMetadata, kafkaMetadataVersion, w.buf)
+	if err != nil {
+		return nil, err
+	}
+
+	r := kafkaReader{b: resp}
+	r.int32() // throttle_time_ms
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.string() // rack
+		k.nodes[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.string() // cluster_id
+	r.int32()  // controller_id
+	var leaders []int32
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		code := r.int16()
+		name := r.string()
+		r.int8() // is_internal
+		parts := r.int32()
+		found := name == topic
+		if found && code != 0 {
+			return nil, fmt.Errorf("kafka: metadata for %s failed with error code %d", topic, code)
+		}
+		if found && parts > 0 {
+			leaders = make([]int32, parts)
+		}
+		for ; parts > 0 && r.err == nil; parts-- {
+			r.int16() // error_code
+			index := r.int32()
+			leader := r.int32()
+			for replicas := r.int32(); replicas > 0 && r.err == nil; replicas-- {
+				r.int32()
+			}
+			for isr := r.int32(); isr > 0 && r.err == nil; isr-- {
+				r.int32()
+			}
+			if found && index >= 0 && int(index) < len(leaders) {
+				leaders[index] = leader
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(leaders) == 0 {
+		return nil, fmt.Errorf("kafka: no partitions for topic %s", topic)
+	}
+	for _, id := range leaders {
+		if id < 0 {
+			return nil, fmt.Errorf("kafka: topic %s has a partition without a leader", topic)
+		}
+	}
+	k.leaders[topic] = leaders
+	return leaders, nil
+}
+
+func (k *kafkaTransport) leader(id int32) (*kafkaConn, error) {
+	if c := k.conns[id]; c != nil {
+		return c, nil
+	}
+	addr, ok := k.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("kafka: unknown broker %d", id)
+	}
+	c, err := dialKafka(addr)
+	if err != nil {
+		return nil, err
+	}
+	k.conns[id] = c
+	return c, nil
+}
+
+// roundTrip sends one request and returns the response body after the
+// correlation id.
+func (k *kafkaTransport) roundTrip(c *kafkaConn, apiKey, version int16, body []byte) ([]byte, error) {
+	k.correlation++
+	var w kafkaWriter
+	w.int32(0) // size, filled in below
+	w.int16(apiKey)
+	w.int16(version)
+	w.int32(k.correlation)
+	w.string("gogios")
+	w.buf = append(w.buf, body...)
+	binary.BigEndian.PutUint32(w.buf, uint32(len(w.buf)-4))
+
+	c.conn.SetDeadline(time.Now().Add(2 * kafkaTimeout))
+	if _, err := c.conn.Write(w.buf); err != nil {
+		return nil, err
+	}
+	var hdr [8]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(hdr[:4])
+	if size < 4 || size > 64<<20 {
+		return nil, fmt.Errorf("kafka: bad response size %d", size)
+	}
+	if id := int32(binary.BigEndian.Uint32(hdr[4:])); id != k.correlation {
+		return nil, fmt.Errorf("kafka: response for request %d, want %d", id, k.correlation)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// kafkaRecordBatch encodes msgs as one v2 record batch, keyed by each
+// message's key.
+func kafkaRecordBatch(msgs []message, ts time.Time) []byte {
+	var records []byte
+	for i, m := range msgs {
+		var rec []byte
+		rec = append(rec, 0)                     // attributes
+		rec = binary.AppendVarint(rec, 0)        // timestamp delta
+		rec = binary.AppendVarint(rec, int64(i)) // offset delta
+		rec = binary.AppendVarint(rec, int64(len(m.key)))
+		rec = append(rec, m.key...)
+		rec = binary.AppendVarint(rec, int64(len(m.payload)))
+		rec = append(rec, m.payload...)
+		rec = binary.AppendVarint(rec, 0) // headers
+		records = binary.AppendVarint(records, int64(len(rec)))
+		records = append(records, rec...)
+	}
+
+	// The CRC covers everything from the attributes to the end.
+	be := binary.BigEndian
+	var body []byte
+	body = be.AppendUint16(body, 0)                   // attributes: no compression, create time
+	body = be.AppendUint32(body, uint32(len(msgs)-1)) // last offset delta
+	body = be.AppendUint64(body, uint64(ts.UnixMilli()))
+	body = be.AppendUint64(body, uint64(ts.UnixMilli()))
+	body = be.AppendUint64(body, ^uint64(0)) // producer id -1
+	body = be.AppendUint16(body, 0xffff)     // producer epoch -1
+	body = be.AppendUint32(body, 0xffffffff) // base sequence -1
+	body = be.AppendUint32(body, uint32(len(msgs)))
+	body = append(body, records...)
+
+	batch := be.AppendUint64(nil, 0) // base offset
+	batch = be.AppendUint32(batch, uint32(4+1+4+len(body)))
+	batch = be.AppendUint32(batch, 0xffffffff) // partition leader epoch -1
+	batch = append(batch, 2)                   // magic
+	batch = be.AppendUint32(batch, crc32.Checksum(body, crc32c))
+	return append(batch, body...)
+}
+
+type kafkaWriter struct {
+	buf []byte
+}
+
+func (w *kafkaWriter) int8(v int8)   { w.buf = append(w.buf, byte(v)) }
+func (w *kafkaWriter) int16(v int16) { w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v)) }
+func (w *kafkaWriter) int32(v int32) { w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v)) }
+
+func (w *kafkaWriter) string(s string) {
+	w.int16(int16(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// kafkaReader decodes a response. The first short read sets err and every
+// later read returns zero.
+type kafkaReader struct {
+	b   []byte
+	err error
+}
+
+func (r *kafkaReader) take(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = errKafkaShort
+		return make([]byte, n)
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *kafkaReader) int8() int8   { return int8(r.take(1)[0]) }
+func (r *kafkaReader) int16() int16 { return int16(binary.BigEndian.Uint16(r.take(2))) }
+func (r *kafkaReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.take(4))) }
+func (r *kafkaReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.take(8))) }
+
+// string reads a (nullable) string; null reads as "".
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n <= 0 {
+		return ""
+	}
+	return string(r.take(int(n)))
+}
@@ -0,0 +1,266 @@
+package eventbus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+type kafkaRecord struct {
+	topic     string
+	partition int32
+	key       string
+	value     []byte
+	request   int // sequence number of the Produce request that carried it
+}
+
+// fakeKafka answers Metadata with one broker (itself) leading two
+// partitions per topic, and forwards every produced record.
+func fakeKafka(t *testing.T) (string, <-chan kafkaRecord) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	records := make(chan kafkaRecord, 64)
+	var produced atomic.Int32
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(br, size[:]); err != nil {
+				return
+			}
+			req := make([]byte, binary.BigEndian.Uint32(size[:]))
+			if _, err := io.ReadFull(br, req); err != nil {
+				return
+			}
+			r := kafkaReader{b: req}
+			apiKey, version, correlation := r.int16(), r.int16(), r.int32()
+			r.string() // client id
+
+			var w kafkaWriter
+			w.int32(0)
+			w.int32(correlation)
+			switch {
+			case apiKey == kafkaMetadata && version == kafkaMetadataVersion:
+				r.int32()
+				topic := r.string()
+				w.int32(0) // throttle
+				w.int32(1)
+				w.int32(1)
+				w.string(host)
+				w.int32(int32(port))
+				w.int16(-1) // rack
+				w.int16(-1) // cluster id
+				w.int32(1)
+				w.int32(1)
+				w.int16(0)
+				w.string(topic)
+				w.int8(0)
+				w.int32(2)
+				for p := int32(0); p < 2; p++ {
+					w.int16(0)
+					w.int32(p)
+					w.int32(1) // leader
+					w.int32(1)
+					w.int32(1)
+					w.int32(1)
+					w.int32(1)
+				}
+			case apiKey == kafkaProduce && version == kafkaProduceVersion:
+				r.int16() // transactional id
+				r.int16() // acks
+				r.int32() // timeout
+				request := int(produced.Add(1))
+				topics := r.int32()
+				w.int32(topics)
+				for ; topics > 0; topics-- {
+					topic := r.string()
+					parts := r.int32()
+					w.string(topic)
+					w.int32(parts)
+					for ; parts > 0; parts-- {
+						partition := r.int32()
+						batch := r.take(int(r.int32()))
+						recs, ok := decodeRecordBatch(batch)
+						if !ok {
+							t.Errorf("bad record batch %x", batch)
+							return
+						}
+						for _, rec := range recs {
+							rec.topic, rec.partition, rec.request = topic, partition, request
+							records <- rec
+						}
+						w.int32(partition)
+						w.int16(0)
+						w.buf = binary.BigEndian.AppendUint64(w.buf, 0)
+						w.buf = binary.BigEndian.AppendUint64(w.buf, ^uint64(0))
+					}
+				}
+				w.int32(0) // throttle
+			default:
+				t.Errorf("unexpected request api %d v%d", apiKey, version)
+				return
+			}
+			binary.BigEndian.PutUint32(w.buf, uint32(len(w.buf)-4))
+			conn.Write(w.buf)
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return "kafka://" + ln.Addr().String(), records
+}
+
+// decodeRecordBatch checks the CRC and offset deltas of a v2 record batch
+// and returns the key and value of each record.
+func decodeRecordBatch(b []byte) ([]kafkaRecord, bool) {
+	if len(b) < 61 || b[16] != 2 {
+		return nil, false
+	}
+	if crc32.Checksum(b[21:], crc32.MakeTable(crc32.Castagnoli)) != binary.BigEndian.Uint32(b[17:21]) {
+		return nil, false
+	}
+	count := int(binary.BigEndian.Uint32(b[57:61]))
+	if count == 0 || int(binary.BigEndian.Uint32(b[23:27])) != count-1 {
+		return nil, false
+	}
+	rec := b[61:]
+	varint := func() int64 {
+		v, n := binary.Varint(rec)
+		rec = rec[n:]
+		return v
+	}
+	var recs []kafkaRecord
+	for i := 0; i < count; i++ {
+		varint()      // length
+		rec = rec[1:] // attributes
+		varint()      // timestamp delta
+		if varint() != int64(i) {
+			return nil, false
+		}
+		keyLen := varint()
+		key := string(rec[:keyLen])
+		rec = rec[keyLen:]
+		valueLen := varint()
+		recs = append(recs, kafkaRecord{key: key, value: rec[:valueLen]})
+		rec = rec[valueLen:]
+		varint() // headers
+	}
+	return recs, len(rec) == 0
+}
+
+func TestPublisherKafka(t *testing.T) {
+	url, records := fakeKafka(t)
+	p, err := New(Config{Driver: "kafka", URL: url, SubjectPrefix: "mon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Start()
+	defer p.Stop()
+
+	for i := 0; i < 2; i++ {
+		p.PublishCheckResult(&objects.CheckResult{
+			HostName: "web01", ServiceDescription: "HTTP", ReturnCode: 2,
+			Output: "CRITICAL", FinishTime: time.Unix(1700000000, 0),
+		})
+	}
+	var partitions []int32
+	for i := 0; i < 2; i++ {
+		select {
+		case rec := <-records:
+			if rec.topic != "mon.check_result" || rec.key != "web01" {
+				t.Errorf("record %d: topic %q key %q", i, rec.topic, rec.key)
+			}
+			var ev Event
+			if err := json.Unmarshal(rec.value, &ev); err != nil || ev.HostName != "web01" || ev.ReturnCode != 2 {
+				t.Errorf("record %d: bad event %q: %v", i, rec.value, err)
+			}
+			partitions = append(partitions, rec.partition)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for record %d", i)
+		}
+	}
+	if partitions[0] != partitions[1] {
+		t.Errorf("events of one host went to partitions %v", partitions)
+	}
+}
+
+func TestPublisherKafkaBatches(t *testing.T) {
+	url, records := fakeKafka(t)
+	p, err := New(Config{Driver: "kafka", URL: url, SubjectPrefix: "mon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Queue the events before delivery starts so they are all waiting.
+	hosts := []string{"web01", "web02", "db01"}
+	for i := 0; i < 6; i++ {
+		p.PublishCheckResult(&objects.CheckResult{
+			HostName: hosts[i%len(hosts)], ServiceDescription: "HTTP", ReturnCode: i % 3,
+			Output: strconv.Itoa(i), FinishTime: time.Unix(1700000000, 0),
+		})
+	}
+	p.Start()
+	defer p.Stop()
+
+	last := map[string]int{}
+	for i := 0; i < 6; i++ {
+		select {
+		case rec := <-records:
+			if rec.request != 1 {
+				t.Errorf("record %d went out in produce request %d, want all in 1", i, rec.request)
+			}
+			var ev Event
+			if err := json.Unmarshal(rec.value, &ev); err != nil || ev.HostName != rec.key {
+				t.Fatalf("record %d: bad event %q: %v", i, rec.value, err)
+			}
+			n, _ := strconv.Atoi(ev.Output)
+			if prev, ok := last[rec.key]; ok && n < prev {
+				t.Errorf("events of %s out of order: %d after %d", rec.key, n, prev)
+			}
+			last[rec.key] = n
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for record %d", i)
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); p.Sent() != 6; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Sent() = %d, want 6", p.Sent())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseKafkaURL(t *testing.T) {
+	brokers, err := parseKafkaURL("kafka://b1:9093, b2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brokers) != 2 || brokers[0] != "b1:9093" || brokers[1] != "b2:9092" {
+		t.Errorf("got %v", brokers)
+	}
+	if _, err := parseKafkaURL("kafka://"); err == nil {
+		t.Error("expected an error without brokers")
+	}
+}
@@ -0,0 +1,157 @@
+package eventbus
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsTransport speaks the core NATS text protocol (CONNECT/PUB/PING/PONG).
+// JetStream and TLS are not supported.
+type natsTransport struct {
+	url *url.URL
+
+	mu   sync.Mutex // serializes writes (publisher and PONG replies)
+	conn net.Conn
+	w    *bufio.Writer
+	err  chan error // first protocol error from the reader goroutine
+}
+
+func (n *natsTransport) connect() error {
+	host := n.url.Host
+	if n.url.Port() == "" {
+		host = net.JoinHostPort(n.url.Hostname(), "4222")
+	}
+	conn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+
+	opts := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "gogios",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if u := n.url.User; u != nil {
+		if pass, ok := u.Password(); ok {
+			opts["user"] = u.Username()
+			opts["pass"] = pass
+		} else {
+			opts["auth_token"] = u.Username()
+		}
+	}
+	connectJSON, _ := json.Marshal(opts)
+
+	w := bufio.NewWriter(conn)
+	w.WriteString("CONNECT ")
+	w.Write(connectJSON)
+	w.WriteString("\r\nPING\r\n")
+	if err := w.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+
+	// The server answers PING with PONG once CONNECT is accepted, or -ERR.
+	for {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return errors.New("nats: " + line)
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	n.mu.Lock()
+	n.conn = conn
+	n.w = w
+	n.err = make(chan error, 1)
+	n.mu.Unlock()
+	go n.readLoop(conn, r, n.err)
+	return nil
+}
+
+// readLoop answers server PINGs and surfaces -ERR or disconnects.
+func (n *natsTransport) readLoop(conn net.Conn, r *bufio.Reader, errCh chan error) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			errCh <- err
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			n.mu.Lock()
+			if n.conn == conn {
+				n.w.WriteString("PONG\r\n")
+				n.w.Flush()
+			}
+			n.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			errCh <- errors.New("nats: " + line)
+			return
+		}
+	}
+}
+
+func (n *natsTransport) publish(msgs []message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return errors.New("nats: not connected")
+	}
+	select {
+	case err := <-n.err:
+		return err
+	default:
+	}
+	for _, m := range msgs {
+		n.w.WriteString("PUB ")
+		n.w.WriteString(m.subject)
+		n.w.WriteByte(' ')
+		n.w.WriteString(strconv.Itoa(len(m.payload)))
+		n.w.WriteString("\r\n")
+		n.w.Write(m.payload)
+		n.w.WriteString("\r\n")
+	}
+	return n.w.Flush()
+}
+
+func (n *natsTransport) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+		n.w = nil
+	}
+}
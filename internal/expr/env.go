@@ -0,0 +1,92 @@
+package expr
+
+import (
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// ObjectVars are the variables HostEnv and ServiceEnv set, for Compile.
+var ObjectVars = []string{
+	"host_name", "service_description", "state", "state_name", "state_type",
+	"last_state", "attempt", "max_attempts", "duration", "problem_duration",
+	"notification_number", "acknowledged", "in_downtime", "flapping",
+	"output", "importance", "hour", "minute", "weekday", "weekend",
+}
+
+// HostEnv returns the variables describing h at now. Custom variables are
+// set as _NAME.
+func HostEnv(h *objects.Host, now time.Time) Env {
+	env := baseEnv(now)
+	env["host_name"] = h.Name
+	env["service_description"] = ""
+	env["state"] = h.CurrentState
+	env["state_name"] = objects.HostStateName(h.CurrentState)
+	env["state_type"] = objects.StateTypeName(h.StateType)
+	env["last_state"] = h.LastState
+	env["attempt"] = h.CurrentAttempt
+	env["max_attempts"] = h.MaxCheckAttempts
+	env["duration"] = secondsSince(h.LastStateChange, now)
+	env["problem_duration"] = secondsSince(h.FirstProblemTime, now)
+	env["notification_number"] = h.CurrentNotificationNumber
+	env["acknowledged"] = h.ProblemAcknowledged
+	env["in_downtime"] = h.ScheduledDowntimeDepth > 0
+	env["flapping"] = h.IsFlapping
+	env["output"] = h.PluginOutput
+	env["importance"] = h.HourlyValue
+	for k, v := range h.CustomVars {
+		env["_"+k] = v
+	}
+	return env
+}
+
+// ServiceEnv returns the variables describing svc at now. Custom
+// variables of the service, and of its host where the service does not set
+// them, are set as _NAME.
+func ServiceEnv(svc *objects.Service, now time.Time) Env {
+	env := baseEnv(now)
+	env["service_description"] = svc.Description
+	env["state"] = svc.CurrentState
+	env["state_name"] = objects.ServiceStateName(svc.CurrentState)
+	env["state_type"] = objects.StateTypeName(svc.StateType)
+	env["last_state"] = svc.LastState
+	env["attempt"] = svc.CurrentAttempt
+	env["max_attempts"] = svc.MaxCheckAttempts
+	env["duration"] = secondsSince(svc.LastStateChange, now)
+	env["problem_duration"] = secondsSince(svc.FirstProblemTime, now)
+	env["notification_number"] = svc.CurrentNotificationNumber
+	env["acknowledged"] = svc.ProblemAcknowledged
+	env["in_downtime"] = svc.ScheduledDowntimeDepth > 0
+	env["flapping"] = svc.IsFlapping
+	env["output"] = svc.PluginOutput
+	env["importance"] = svc.HourlyValue
+	env["host_name"] = ""
+	if svc.Host != nil {
+		env["host_name"] = svc.Host.Name
+		for k, v := range svc.Host.CustomVars {
+			env["_"+k] = v
+		}
+	}
+	for k, v := range svc.CustomVars {
+		env["_"+k] = v
+	}
+	return env
+}
+
+func baseEnv(now time.Time) Env {
+	wd := now.Weekday()
+	return Env{
+		"hour":    now.Hour(),
+		"minute":  now.Minute(),
+		"weekday": int(wd),
+		"weekend": wd == time.Saturday || wd == time.Sunday,
+	}
+}
+
+// secondsSince is the whole seconds from t to now, or 0 for a zero t.
+func secondsSince(t, now time.Time) int64 {
+	if t.IsZero() || now.Before(t) {
+		return 0
+	}
+	return int64(now.Sub(t) / time.Second)
+}
@@ -0,0 +1,557 @@
+// Package expr is a small expression language for filters and hooks written
+// inline in object definitions, so simple logic such as "only after the
+// third attempt and not at the weekend" needs no external script:
+//
+//	attempt >= 3 && problem_duration <= 600 && !weekend
+//
+// Expressions have numbers, strings in double or single quotes, true and
+// false, lists in brackets, the operators || && ! == != < <= > >= + - * / %
+// and in, parentheses, and the functions contains(s, sub), matches(s, re)
+// and lower(s). Identifiers are the variables of an Env. Identifiers
+// starting with an underscore are custom variables and are "" when unset.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Env holds the variables an expression is evaluated against. Values are
+// float64, string or bool; ints are accepted and converted.
+type Env map[string]interface{}
+
+// Program is a compiled expression.
+type Program struct {
+	src  string
+	root node
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string { return p.src }
+
+// Compile parses src. Identifiers not in vars and not starting with an
+// underscore are an error, so typos are caught before the expression runs.
+func Compile(src string, vars []string) (*Program, error) {
+	known := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		known[v] = true
+	}
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	ps := &parser{toks: toks, known: known}
+	root, err := ps.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := ps.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at offset %d", t.text, t.pos)
+	}
+	return &Program{src: src, root: root}, nil
+}
+
+// Eval evaluates the program against env.
+func (p *Program) Eval(env Env) (interface{}, error) {
+	return p.root.eval(env)
+}
+
+// EvalBool evaluates the program and requires a boolean result.
+func (p *Program) EvalBool(env Env) (bool, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression is %s, not a boolean", typeName(v))
+	}
+	return b, nil
+}
+
+// --- lexer ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokStr
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	num  float64
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%s' at offset %d", src[i:j], i)
+			}
+			toks = append(toks, token{kind: tokNum, text: src[i:j], num: n, pos: i})
+			i = j
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			toks = append(toks, token{kind: tokStr, text: sb.String(), pos: i})
+			i = j + 1
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character '%c' at offset %d", c, i)
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+// --- parser ---
+
+type parser struct {
+	toks  []token
+	i     int
+	known map[string]bool
+}
+
+func (ps *parser) peek() token { return ps.toks[ps.i] }
+
+func (ps *parser) next() token {
+	t := ps.toks[ps.i]
+	if t.kind != tokEOF {
+		ps.i++
+	}
+	return t
+}
+
+func (ps *parser) accept(op string) bool {
+	if t := ps.peek(); (t.kind == tokOp || t.kind == tokIdent) && t.text == op {
+		ps.i++
+		return true
+	}
+	return false
+}
+
+func (ps *parser) expect(op string) error {
+	if !ps.accept(op) {
+		t := ps.peek()
+		return fmt.Errorf("expected '%s' at offset %d, got '%s'", op, t.pos, t.text)
+	}
+	return nil
+}
+
+// binaryLevels lists the binary operators by increasing precedence.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (ps *parser) parseOr() (node, error) { return ps.parseLevel(0) }
+
+func (ps *parser) parseLevel(level int) (node, error) {
+	if level == len(binaryLevels) {
+		return ps.parseUnary()
+	}
+	left, err := ps.parseLevel(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, o := range binaryLevels[level] {
+			if ps.accept(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		right, err := ps.parseLevel(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (ps *parser) parseUnary() (node, error) {
+	if ps.accept("!") {
+		x, err := ps.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	if ps.accept("-") {
+		x, err := ps.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: "-", left: literal{0.0}, right: x}, nil
+	}
+	return ps.parsePrimary()
+}
+
+func (ps *parser) parsePrimary() (node, error) {
+	t := ps.next()
+	switch t.kind {
+	case tokNum:
+		return literal{t.num}, nil
+	case tokStr:
+		return literal{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		}
+		if ps.accept("(") {
+			return ps.parseCall(t)
+		}
+		if !strings.HasPrefix(t.text, "_") && !ps.known[t.text] {
+			return nil, fmt.Errorf("unknown variable '%s' at offset %d", t.text, t.pos)
+		}
+		return varNode(t.text), nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			x, err := ps.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, ps.expect(")")
+		case "[":
+			var l listNode
+			for !ps.accept("]") {
+				if len(l) > 0 {
+					if err := ps.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				x, err := ps.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				l = append(l, x)
+			}
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected '%s' at offset %d", t.text, t.pos)
+}
+
+// functions maps function names to their argument counts.
+var functions = map[string]int{"contains": 2, "matches": 2, "lower": 1}
+
+func (ps *parser) parseCall(name token) (node, error) {
+	want, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s' at offset %d", name.text, name.pos)
+	}
+	call := &callNode{name: name.text}
+	for !ps.accept(")") {
+		if len(call.args) > 0 {
+			if err := ps.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		x, err := ps.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, x)
+	}
+	if len(call.args) != want {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name.text, want, len(call.args))
+	}
+	if call.name == "matches" {
+		if pat, ok := call.args[1].(literal); ok {
+			s, _ := pat.v.(string)
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return nil, fmt.Errorf("matches: %v", err)
+			}
+			call.re = re
+		}
+	}
+	return call, nil
+}
+
+// --- evaluation ---
+
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literal struct{ v interface{} }
+
+func (l literal) eval(Env) (interface{}, error) { return l.v, nil }
+
+type varNode string
+
+func (v varNode) eval(env Env) (interface{}, error) {
+	val, ok := env[string(v)]
+	if !ok {
+		if strings.HasPrefix(string(v), "_") {
+			return "", nil
+		}
+		return nil, fmt.Errorf("variable '%s' is not set", string(v))
+	}
+	return normalize(val), nil
+}
+
+type listNode []node
+
+func (l listNode) eval(env Env) (interface{}, error) {
+	out := make([]interface{}, len(l))
+	for i, x := range l {
+		v, err := x.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+type notNode struct{ x node }
+
+func (n *notNode) eval(env Env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("'!' needs a boolean, got %s", typeName(v))
+	}
+	return !b, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'%s' needs booleans, got %s", n.op, typeName(l))
+		}
+		if lb == (n.op == "||") {
+			return lb, nil
+		}
+		r, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'%s' needs booleans, got %s", n.op, typeName(r))
+		}
+		return rb, nil
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		list, ok := r.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'in' needs a list, got %s", typeName(r))
+		}
+		for _, item := range list {
+			if equal(l, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("'%s' between string and %s", n.op, typeName(r))
+		}
+		switch n.op {
+		case "+":
+			return ls + rs, nil
+		case "<":
+			return ls < rs, nil
+		case "<=":
+			return ls <= rs, nil
+		case ">":
+			return ls > rs, nil
+		case ">=":
+			return ls >= rs, nil
+		}
+		return nil, fmt.Errorf("'%s' is not defined for strings", n.op)
+	}
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("'%s' between %s and %s", n.op, typeName(l), typeName(r))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/", "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if n.op == "/" {
+			return lf / rf, nil
+		}
+		return float64(int64(lf) % int64(rf)), nil
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", n.op)
+}
+
+type callNode struct {
+	name string
+	args []node
+	re   *regexp.Regexp // compiled pattern of matches() with a literal pattern
+}
+
+func (c *callNode) eval(env Env) (interface{}, error) {
+	args := make([]string, len(c.args))
+	for i, a := range c.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s needs strings, got %s", c.name, typeName(v))
+		}
+		args[i] = s
+	}
+	switch c.name {
+	case "contains":
+		return strings.Contains(args[0], args[1]), nil
+	case "lower":
+		return strings.ToLower(args[0]), nil
+	case "matches":
+		re := c.re
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(args[1]); err != nil {
+				return nil, fmt.Errorf("matches: %v", err)
+			}
+		}
+		return re.MatchString(args[0]), nil
+	}
+	return nil, fmt.Errorf("unknown function '%s'", c.name)
+}
+
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case uint:
+		return float64(x)
+	}
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case float64, string, bool:
+		return a == b
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,97 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestEval(t *testing.T) {
+	env := Env{
+		"attempt": 3, "state_name": "CRITICAL", "weekend": false,
+		"problem_duration": int64(420), "output": "DISK CRITICAL - /var 98%",
+		"_ENV": "prod",
+	}
+	vars := []string{"attempt", "state_name", "weekend", "problem_duration", "output"}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"attempt >= 3 && problem_duration <= 600 && !weekend", true},
+		{"attempt > 3 || state_name == 'WARNING'", false},
+		{`state_name in ["CRITICAL", "UNKNOWN"]`, true},
+		{"_ENV == 'prod' && _TEAM == ''", true},
+		{"contains(output, '/var') && matches(output, '9[0-9]%')", true},
+		{"lower(state_name) + '!'", "critical!"},
+		{"(attempt + 1) * 2 % 5", 3.0},
+		{"-attempt < 0", true},
+		{"false && attempt / 0 > 1", false}, // short-circuit skips the division
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.src, vars)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.src, err)
+			continue
+		}
+		got, err := p.Eval(env)
+		if err != nil || got != tt.want {
+			t.Errorf("%q = %v (%v), want %v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		"atempt >= 3",          // unknown variable
+		"attempt >=",           // missing operand
+		"attempt >= 3 )",       // trailing token
+		"'open",                // unterminated string
+		"attempt # 3",          // bad character
+		"contains(output)",     // wrong argument count
+		"sqrt(attempt)",        // unknown function
+		"matches(output, '[')", // bad literal pattern
+		"[attempt, ",           // unterminated list
+	} {
+		if _, err := Compile(src, []string{"attempt", "output"}); err == nil {
+			t.Errorf("Compile(%q): expected an error", src)
+		}
+	}
+}
+
+func TestEvalBoolErrors(t *testing.T) {
+	vars := []string{"attempt", "state_name"}
+	for _, src := range []string{"attempt", "attempt && true", "state_name < 3", "!state_name"} {
+		p, err := Compile(src, vars)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", src, err)
+		}
+		if _, err := p.EvalBool(Env{"attempt": 1, "state_name": "OK"}); err == nil {
+			t.Errorf("EvalBool(%q): expected an error", src)
+		}
+	}
+}
+
+func TestServiceEnv(t *testing.T) {
+	now := time.Date(2026, 10, 17, 14, 30, 0, 0, time.UTC) // a Saturday
+	h := &objects.Host{Name: "web01", CustomVars: map[string]string{"ENV": "prod", "TEAM": "web"}}
+	svc := &objects.Service{
+		Host: h, Description: "HTTP", CurrentState: objects.ServiceCritical,
+		StateType: objects.StateTypeHard, CurrentAttempt: 3,
+		FirstProblemTime: now.Add(-5 * time.Minute),
+		CustomVars:       map[string]string{"TEAM": "api"},
+	}
+	p, err := Compile(`host_name == "web01" && state_name == "CRITICAL" && state_type == "HARD" && `+
+		`problem_duration == 300 && weekend && hour == 14 && _ENV == "prod" && _TEAM == "api"`, ObjectVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := p.EvalBool(ServiceEnv(svc, now))
+	if err != nil || !ok {
+		t.Errorf("expected the service to match, got %v %v", ok, err)
+	}
+	if _, err := Compile("hostname == 'x'", ObjectVars); err == nil || !strings.Contains(err.Error(), "hostname") {
+		t.Errorf("expected an unknown variable error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package extcmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// maxBatchCommands caps the commands held for one batch, so a batch that is
+// never ended cannot grow without bound.
+const maxBatchCommands = 100000
+
+// batchCommand handles the gogios BEGIN_BATCH and END_BATCH commands, and
+// holds the commands that arrive from a source between the two. Commands
+// are held per source, so a batch on the command file does not take
+// commands arriving over HTTP. It reports whether it took cmd.
+func (p *Processor) batchCommand(cmd *Command) bool {
+	p.batchMu.Lock()
+	held, open := p.batches[cmd.Source]
+	switch {
+	case cmd.Name == "BEGIN_BATCH":
+		if open {
+			cmd.Fail("batch already open")
+		} else {
+			p.batches[cmd.Source] = nil
+		}
+	case cmd.Name == "END_BATCH":
+		if !open {
+			cmd.Fail("no batch open")
+		}
+		delete(p.batches, cmd.Source)
+	case !open:
+		p.batchMu.Unlock()
+		return false
+	case len(held) >= maxBatchCommands:
+		cmd.Fail("batch is full (%d commands)", maxBatchCommands)
+	default:
+		p.batches[cmd.Source] = append(held, cmd)
+		p.batchMu.Unlock()
+		return true
+	}
+	p.batchMu.Unlock()
+
+	if cmd.Name == "END_BATCH" && open {
+		p.applyBatch(cmd.Source, held)
+	}
+	if cmd.ResponseFile != "" {
+		p.writeResponse(cmd)
+	}
+	return true
+}
+
+// applyBatch carries out the commands of a batch from source under one
+// StateMu lock, so readers see all of them or none, and logs one summary
+// line in place of the commands' own.
+func (p *Processor) applyBatch(source string, cmds []*Command) {
+	p.applyMu.Lock()
+	if p.StateMu != nil {
+		p.StateMu.Lock()
+	}
+	if p.QuietBatch != nil {
+		p.QuietBatch(true)
+	}
+	for _, cmd := range cmds {
+		p.run(cmd)
+	}
+	if p.QuietBatch != nil {
+		p.QuietBatch(false)
+	}
+	if p.StateMu != nil {
+		p.StateMu.Unlock()
+	}
+	p.applyMu.Unlock()
+
+	counts := make(map[string]int)
+	failed := 0
+	for _, cmd := range cmds {
+		counts[cmd.Name]++
+		if cmd.Err != nil {
+			failed++
+		}
+		p.finish(cmd)
+	}
+	summary := fmt.Sprintf("%d commands from %s", len(cmds), source)
+	if len(counts) > 0 {
+		parts := make([]string, 0, len(counts))
+		for _, name := range slices.Sorted(maps.Keys(counts)) {
+			parts = append(parts, fmt.Sprintf("%s x%d", name, counts[name]))
+		}
+		summary += " (" + strings.Join(parts, ", ") + ")"
+	}
+	p.log("EXTERNAL COMMAND BATCH: %s, %d failed", summary, failed)
+}
+
+// HoldBatches waits for a batch being applied and keeps the next one from
+// being applied until the returned function is called. The status.dat
+// writer holds it, so the file never shows part of a batch.
+func (p *Processor) HoldBatches() (release func()) {
+	p.applyMu.RLock()
+	return p.applyMu.RUnlock
+}
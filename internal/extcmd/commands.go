@@ -0,0 +1,299 @@
+package extcmd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// commands lists the arguments of every known external command, named as
+// in the Nagios documentation. An argument in brackets may be left out.
+// The last argument takes the rest of the line, semicolons included, so
+// comments and plugin output need no escaping.
+var commands = map[string]string{
+	// Program-wide
+	"ENABLE_NOTIFICATIONS":                "",
+	"DISABLE_NOTIFICATIONS":               "",
+	"START_EXECUTING_SVC_CHECKS":          "",
+	"STOP_EXECUTING_SVC_CHECKS":           "",
+	"START_EXECUTING_HOST_CHECKS":         "",
+	"STOP_EXECUTING_HOST_CHECKS":          "",
+	"START_ACCEPTING_PASSIVE_SVC_CHECKS":  "",
+	"STOP_ACCEPTING_PASSIVE_SVC_CHECKS":   "",
+	"START_ACCEPTING_PASSIVE_HOST_CHECKS": "",
+	"STOP_ACCEPTING_PASSIVE_HOST_CHECKS":  "",
+	"ENABLE_EVENT_HANDLERS":               "",
+	"DISABLE_EVENT_HANDLERS":              "",
+	"ENABLE_FLAP_DETECTION":               "",
+	"DISABLE_FLAP_DETECTION":              "",
+	"ENABLE_PERFORMANCE_DATA":             "",
+	"DISABLE_PERFORMANCE_DATA":            "",
+	"START_OBSESSING_OVER_SVC_CHECKS":     "",
+	"STOP_OBSESSING_OVER_SVC_CHECKS":      "",
+	"START_OBSESSING_OVER_HOST_CHECKS":    "",
+	"STOP_OBSESSING_OVER_HOST_CHECKS":     "",
+	"ENABLE_SERVICE_FRESHNESS_CHECKS":     "",
+	"DISABLE_SERVICE_FRESHNESS_CHECKS":    "",
+	"ENABLE_HOST_FRESHNESS_CHECKS":        "",
+	"DISABLE_HOST_FRESHNESS_CHECKS":       "",
+	"CHANGE_GLOBAL_HOST_EVENT_HANDLER":    "event_handler_command",
+	"CHANGE_GLOBAL_SVC_EVENT_HANDLER":     "event_handler_command",
+	"SAVE_STATE_INFORMATION":              "",
+	"READ_STATE_INFORMATION":              "",
+	"PROCESS_FILE":                        "file_name;delete",
+	"RESTART_PROGRAM":                     "",
+	"RESTART_PROCESS":                     "",
+	"SHUTDOWN_PROGRAM":                    "",
+	"SHUTDOWN_PROCESS":                    "",
+
+	// Hosts
+	"ENABLE_HOST_CHECK":                              "host_name",
+	"DISABLE_HOST_CHECK":                             "host_name",
+	"ENABLE_HOST_NOTIFICATIONS":                      "host_name",
+	"DISABLE_HOST_NOTIFICATIONS":                     "host_name",
+	"ENABLE_HOST_EVENT_HANDLER":                      "host_name",
+	"DISABLE_HOST_EVENT_HANDLER":                     "host_name",
+	"ENABLE_HOST_FLAP_DETECTION":                     "host_name",
+	"DISABLE_HOST_FLAP_DETECTION":                    "host_name",
+	"ENABLE_PASSIVE_HOST_CHECKS":                     "host_name",
+	"DISABLE_PASSIVE_HOST_CHECKS":                    "host_name",
+	"START_OBSESSING_OVER_HOST":                      "host_name",
+	"STOP_OBSESSING_OVER_HOST":                       "host_name",
+	"ENABLE_HOST_SVC_CHECKS":                         "host_name",
+	"DISABLE_HOST_SVC_CHECKS":                        "host_name",
+	"ENABLE_HOST_SVC_NOTIFICATIONS":                  "host_name",
+	"DISABLE_HOST_SVC_NOTIFICATIONS":                 "host_name",
+	"ENABLE_HOST_AND_CHILD_NOTIFICATIONS":            "host_name",
+	"DISABLE_HOST_AND_CHILD_NOTIFICATIONS":           "host_name",
+	"ENABLE_ALL_NOTIFICATIONS_BEYOND_HOST":           "host_name",
+	"DISABLE_ALL_NOTIFICATIONS_BEYOND_HOST":          "host_name",
+	"SCHEDULE_HOST_CHECK":                            "host_name;check_time",
+	"SCHEDULE_FORCED_HOST_CHECK":                     "host_name;check_time",
+	"SCHEDULE_HOST_SVC_CHECKS":                       "host_name;check_time",
+	"SCHEDULE_FORCED_HOST_SVC_CHECKS":                "host_name;check_time",
+	"DELAY_HOST_NOTIFICATION":                        "host_name;notification_time",
+	"ACKNOWLEDGE_HOST_PROBLEM":                       "host_name;sticky;notify;persistent;author;comment",
+	"REMOVE_HOST_ACKNOWLEDGEMENT":                    "host_name",
+	"ADD_HOST_COMMENT":                               "host_name;persistent;author;comment",
+	"DEL_HOST_COMMENT":                               "comment_id",
+	"DEL_ALL_HOST_COMMENTS":                          "host_name",
+	"SCHEDULE_HOST_DOWNTIME":                         "host_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_HOST_SVC_DOWNTIME":                     "host_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_AND_PROPAGATE_HOST_DOWNTIME":           "host_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_AND_PROPAGATE_TRIGGERED_HOST_DOWNTIME": "host_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"DEL_HOST_DOWNTIME":                              "downtime_id",
+	"DEL_DOWNTIME_BY_HOST_NAME":                      "host_name;[service_description];[start_time];[comment]",
+	"DEL_DOWNTIME_BY_HOSTGROUP_NAME":                 "hostgroup_name;[host_name];[service_description];[start_time];[comment]",
+	"DEL_DOWNTIME_BY_START_TIME_COMMENT":             "start_time;[comment]",
+	"PROCESS_HOST_CHECK_RESULT":                      "host_name;status_code;plugin_output",
+	"SEND_CUSTOM_HOST_NOTIFICATION":                  "host_name;options;author;comment",
+	"SET_HOST_NOTIFICATION_NUMBER":                   "host_name;notification_number",
+	"CHANGE_HOST_CHECK_COMMAND":                      "host_name;check_command",
+	"CHANGE_HOST_EVENT_HANDLER":                      "host_name;event_handler_command",
+	"CHANGE_NORMAL_HOST_CHECK_INTERVAL":              "host_name;check_interval",
+	"CHANGE_RETRY_HOST_CHECK_INTERVAL":               "host_name;check_interval",
+	"CHANGE_MAX_HOST_CHECK_ATTEMPTS":                 "host_name;check_attempts",
+	"CHANGE_HOST_CHECK_TIMEPERIOD":                   "host_name;check_timeperiod",
+	"CHANGE_HOST_NOTIFICATION_TIMEPERIOD":            "host_name;notification_timeperiod",
+	"CHANGE_CUSTOM_HOST_VAR":                         "host_name;varname;varvalue",
+	"CHANGE_HOST_MODATTR":                            "host_name;value",
+
+	// Services
+	"ENABLE_SVC_CHECK":                   "host_name;service_description",
+	"DISABLE_SVC_CHECK":                  "host_name;service_description",
+	"ENABLE_SVC_NOTIFICATIONS":           "host_name;service_description",
+	"DISABLE_SVC_NOTIFICATIONS":          "host_name;service_description",
+	"ENABLE_SVC_EVENT_HANDLER":           "host_name;service_description",
+	"DISABLE_SVC_EVENT_HANDLER":          "host_name;service_description",
+	"ENABLE_SVC_FLAP_DETECTION":          "host_name;service_description",
+	"DISABLE_SVC_FLAP_DETECTION":         "host_name;service_description",
+	"ENABLE_PASSIVE_SVC_CHECKS":          "host_name;service_description",
+	"DISABLE_PASSIVE_SVC_CHECKS":         "host_name;service_description",
+	"START_OBSESSING_OVER_SVC":           "host_name;service_description",
+	"STOP_OBSESSING_OVER_SVC":            "host_name;service_description",
+	"SCHEDULE_SVC_CHECK":                 "host_name;service_description;check_time",
+	"SCHEDULE_FORCED_SVC_CHECK":          "host_name;service_description;check_time",
+	"DELAY_SVC_NOTIFICATION":             "host_name;service_description;notification_time",
+	"ACKNOWLEDGE_SVC_PROBLEM":            "host_name;service_description;sticky;notify;persistent;author;comment",
+	"REMOVE_SVC_ACKNOWLEDGEMENT":         "host_name;service_description",
+	"ADD_SVC_COMMENT":                    "host_name;service_description;persistent;author;comment",
+	"DEL_SVC_COMMENT":                    "comment_id",
+	"DEL_ALL_SVC_COMMENTS":               "host_name;service_description",
+	"SCHEDULE_SVC_DOWNTIME":              "host_name;service_description;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"DEL_SVC_DOWNTIME":                   "downtime_id",
+	"PROCESS_SERVICE_CHECK_RESULT":       "host_name;service_description;return_code;plugin_output",
+	"SEND_CUSTOM_SVC_NOTIFICATION":       "host_name;service_description;options;author;comment",
+	"SET_SVC_NOTIFICATION_NUMBER":        "host_name;service_description;notification_number",
+	"CHANGE_SVC_CHECK_COMMAND":           "host_name;service_description;check_command",
+	"CHANGE_SVC_EVENT_HANDLER":           "host_name;service_description;event_handler_command",
+	"CHANGE_NORMAL_SVC_CHECK_INTERVAL":   "host_name;service_description;check_interval",
+	"CHANGE_RETRY_SVC_CHECK_INTERVAL":    "host_name;service_description;check_interval",
+	"CHANGE_MAX_SVC_CHECK_ATTEMPTS":      "host_name;service_description;check_attempts",
+	"CHANGE_SVC_CHECK_TIMEPERIOD":        "host_name;service_description;check_timeperiod",
+	"CHANGE_SVC_NOTIFICATION_TIMEPERIOD": "host_name;service_description;notification_timeperiod",
+	"CHANGE_CUSTOM_SVC_VAR":              "host_name;service_description;varname;varvalue",
+	"CHANGE_SVC_MODATTR":                 "host_name;service_description;value",
+
+	// Host groups
+	"ENABLE_HOSTGROUP_HOST_NOTIFICATIONS":   "hostgroup_name",
+	"DISABLE_HOSTGROUP_HOST_NOTIFICATIONS":  "hostgroup_name",
+	"ENABLE_HOSTGROUP_SVC_NOTIFICATIONS":    "hostgroup_name",
+	"DISABLE_HOSTGROUP_SVC_NOTIFICATIONS":   "hostgroup_name",
+	"ENABLE_HOSTGROUP_HOST_CHECKS":          "hostgroup_name",
+	"DISABLE_HOSTGROUP_HOST_CHECKS":         "hostgroup_name",
+	"ENABLE_HOSTGROUP_SVC_CHECKS":           "hostgroup_name",
+	"DISABLE_HOSTGROUP_SVC_CHECKS":          "hostgroup_name",
+	"ENABLE_HOSTGROUP_PASSIVE_HOST_CHECKS":  "hostgroup_name",
+	"DISABLE_HOSTGROUP_PASSIVE_HOST_CHECKS": "hostgroup_name",
+	"ENABLE_HOSTGROUP_PASSIVE_SVC_CHECKS":   "hostgroup_name",
+	"DISABLE_HOSTGROUP_PASSIVE_SVC_CHECKS":  "hostgroup_name",
+	"SCHEDULE_HOSTGROUP_HOST_DOWNTIME":      "hostgroup_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_HOSTGROUP_SVC_DOWNTIME":       "hostgroup_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+
+	// Service groups
+	"ENABLE_SERVICEGROUP_HOST_NOTIFICATIONS":   "servicegroup_name",
+	"DISABLE_SERVICEGROUP_HOST_NOTIFICATIONS":  "servicegroup_name",
+	"ENABLE_SERVICEGROUP_SVC_NOTIFICATIONS":    "servicegroup_name",
+	"DISABLE_SERVICEGROUP_SVC_NOTIFICATIONS":   "servicegroup_name",
+	"ENABLE_SERVICEGROUP_HOST_CHECKS":          "servicegroup_name",
+	"DISABLE_SERVICEGROUP_HOST_CHECKS":         "servicegroup_name",
+	"ENABLE_SERVICEGROUP_SVC_CHECKS":           "servicegroup_name",
+	"DISABLE_SERVICEGROUP_SVC_CHECKS":          "servicegroup_name",
+	"ENABLE_SERVICEGROUP_PASSIVE_HOST_CHECKS":  "servicegroup_name",
+	"DISABLE_SERVICEGROUP_PASSIVE_HOST_CHECKS": "servicegroup_name",
+	"ENABLE_SERVICEGROUP_PASSIVE_SVC_CHECKS":   "servicegroup_name",
+	"DISABLE_SERVICEGROUP_PASSIVE_SVC_CHECKS":  "servicegroup_name",
+	"SCHEDULE_SERVICEGROUP_HOST_DOWNTIME":      "servicegroup_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_SERVICEGROUP_SVC_DOWNTIME":       "servicegroup_name;start_time;end_time;fixed;trigger_id;duration;author;comment",
+
+	// Contacts and contact groups
+	"ENABLE_CONTACT_HOST_NOTIFICATIONS":           "contact_name",
+	"DISABLE_CONTACT_HOST_NOTIFICATIONS":          "contact_name",
+	"ENABLE_CONTACT_SVC_NOTIFICATIONS":            "contact_name",
+	"DISABLE_CONTACT_SVC_NOTIFICATIONS":           "contact_name",
+	"CHANGE_CONTACT_HOST_NOTIFICATION_TIMEPERIOD": "contact_name;notification_timeperiod",
+	"CHANGE_CONTACT_SVC_NOTIFICATION_TIMEPERIOD":  "contact_name;notification_timeperiod",
+	"CHANGE_CUSTOM_CONTACT_VAR":                   "contact_name;varname;varvalue",
+	"CHANGE_CONTACT_MODATTR":                      "contact_name;value",
+	"CHANGE_CONTACT_MODHATTR":                     "contact_name;value",
+	"CHANGE_CONTACT_MODSATTR":                     "contact_name;value",
+	"ENABLE_CONTACTGROUP_HOST_NOTIFICATIONS":      "contactgroup_name",
+	"DISABLE_CONTACTGROUP_HOST_NOTIFICATIONS":     "contactgroup_name",
+	"ENABLE_CONTACTGROUP_SVC_NOTIFICATIONS":       "contactgroup_name",
+	"DISABLE_CONTACTGROUP_SVC_NOTIFICATIONS":      "contactgroup_name",
+
+	// Gogios extensions
+	"PAUSE_SCHEDULER":                    "",
+	"RESUME_SCHEDULER":                   "",
+	"BEGIN_BATCH":                        "",
+	"END_BATCH":                          "",
+	"ADD_BLACKOUT":                       "blackout_name;start_time;end_time;host_name;hostgroup_name;service_description;servicegroup_name;author;comment",
+	"DEL_BLACKOUT":                       "blackout_name",
+	"ACKNOWLEDGE_CUSTOMVAR_HOST_PROBLEM": "selector;sticky;notify;persistent;author;comment",
+	"ACKNOWLEDGE_CUSTOMVAR_SVC_PROBLEM":  "selector;sticky;notify;persistent;author;comment",
+	"ACKNOWLEDGE_TAG_HOST_PROBLEM":       "selector;sticky;notify;persistent;author;comment",
+	"ACKNOWLEDGE_TAG_SVC_PROBLEM":        "selector;sticky;notify;persistent;author;comment",
+	"SCHEDULE_CUSTOMVAR_HOST_DOWNTIME":   "selector;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_CUSTOMVAR_SVC_DOWNTIME":    "selector;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_TAG_HOST_DOWNTIME":         "selector;start_time;end_time;fixed;trigger_id;duration;author;comment",
+	"SCHEDULE_TAG_SVC_DOWNTIME":          "selector;start_time;end_time;fixed;trigger_id;duration;author;comment",
+}
+
+// commandSpec is a command's entry in commands, parsed.
+type commandSpec struct {
+	args     []string
+	required int // the arguments before the first optional one
+}
+
+var specs = func() map[string]commandSpec {
+	m := make(map[string]commandSpec, len(commands))
+	for name, sig := range commands {
+		var spec commandSpec
+		if sig != "" {
+			spec.args = strings.Split(sig, ";")
+		}
+		spec.required = len(spec.args)
+		for i, arg := range spec.args {
+			if opt, ok := strings.CutPrefix(arg, "["); ok {
+				spec.args[i] = strings.TrimSuffix(opt, "]")
+				spec.required = min(spec.required, i)
+			}
+		}
+		m[name] = spec
+	}
+	return m
+}()
+
+// intArgs are the arguments that must be whole numbers when given, and
+// floatArgs those that must be numbers. Empty values are left to the
+// handler, which reads them as 0 like Nagios does.
+var (
+	intArgs = map[string]bool{
+		"sticky": true, "notify": true, "persistent": true, "fixed": true, "delete": true,
+		"trigger_id": true, "duration": true, "start_time": true, "end_time": true,
+		"check_time": true, "notification_time": true, "downtime_id": true, "comment_id": true,
+		"return_code": true, "status_code": true, "options": true, "notification_number": true,
+		"check_attempts": true, "value": true,
+	}
+	floatArgs = map[string]bool{"check_interval": true}
+)
+
+// Known reports whether name is a known external command.
+func Known(name string) bool {
+	_, ok := specs[name]
+	return ok
+}
+
+// SplitArgs splits argStr, the part of a command line after the command
+// name and its semicolon, into cmdName's arguments. The last argument
+// keeps any further semicolons. For an unknown command every semicolon
+// separates arguments.
+func SplitArgs(cmdName, argStr string) []string {
+	if !Known(cmdName) && argStr != "" {
+		return strings.Split(argStr, ";")
+	}
+	return splitArgs(cmdName, argStr)
+}
+
+// checkArgs fails a known command that lacks arguments or has a number
+// argument that isn't one. Commands not in the table are left to their
+// handler.
+func checkArgs(cmd *Command) {
+	spec, ok := specs[cmd.Name]
+	if !ok {
+		return
+	}
+	if len(cmd.Args) < spec.required {
+		cmd.Fail("expected %d arguments, got %d", spec.required, len(cmd.Args))
+		return
+	}
+	for i, v := range cmd.Args {
+		if i >= len(spec.args) {
+			break
+		}
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		var err error
+		switch arg := spec.args[i]; {
+		case intArgs[arg]:
+			_, err = strconv.ParseInt(v, 10, 64)
+		case floatArgs[arg]:
+			_, err = strconv.ParseFloat(v, 64)
+		}
+		if err != nil {
+			cmd.Fail("invalid %s '%s'", spec.args[i], v)
+			return
+		}
+	}
+}
+
+// minArgCount is how many arguments cmdName needs to be carried out.
+func minArgCount(cmdName string) int {
+	return specs[cmdName].required
+}
+
+// expectedArgCount is how many arguments cmdName takes, 0 for an unknown
+// command.
+func expectedArgCount(cmdName string) int {
+	return len(specs[cmdName].args)
+}
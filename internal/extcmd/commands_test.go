@@ -0,0 +1,126 @@
+package extcmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommands_Table(t *testing.T) {
+	if len(specs) < 150 {
+		t.Errorf("only %d commands in the table", len(specs))
+	}
+	for name, spec := range specs {
+		for i, arg := range spec.args {
+			if arg == "" || strings.ContainsAny(arg, "[] ") {
+				t.Errorf("%s: bad argument name %q", name, arg)
+			}
+			if i >= spec.required && !strings.Contains(commands[name], "["+arg+"]") {
+				t.Errorf("%s: required argument %s after an optional one", name, arg)
+			}
+		}
+	}
+	if got := minArgCount("DEL_DOWNTIME_BY_HOSTGROUP_NAME"); got != 1 {
+		t.Errorf("minArgCount(DEL_DOWNTIME_BY_HOSTGROUP_NAME) = %d, want 1", got)
+	}
+	if got := expectedArgCount("DEL_DOWNTIME_BY_HOSTGROUP_NAME"); got != 5 {
+		t.Errorf("expectedArgCount(DEL_DOWNTIME_BY_HOSTGROUP_NAME) = %d, want 5", got)
+	}
+}
+
+func TestSplitArgs_Exported(t *testing.T) {
+	if got := SplitArgs("ADD_HOST_COMMENT", "web01;1;admin;see ticket;urgent"); !slices.Equal(got, []string{"web01", "1", "admin", "see ticket;urgent"}) {
+		t.Errorf("known command: %q", got)
+	}
+	if got := SplitArgs("DO_THING", "a;b;c"); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("unknown command: %q", got)
+	}
+	if got := SplitArgs("DO_THING", ""); got != nil {
+		t.Errorf("unknown command without args: %q", got)
+	}
+}
+
+func TestDispatchBatch_ChecksArgs(t *testing.T) {
+	p := NewProcessor("", 10)
+	var ran []string
+	for _, name := range []string{"ACKNOWLEDGE_HOST_PROBLEM", "CHANGE_NORMAL_SVC_CHECK_INTERVAL", "DEL_DOWNTIME_BY_HOST_NAME", "TEST_CMD"} {
+		p.RegisterHandler(name, func(cmd *Command) { ran = append(ran, cmd.Name) })
+	}
+	cmds := []Command{
+		{Name: "ACKNOWLEDGE_HOST_PROBLEM", Args: []string{"web01", "yes", "1", "1", "admin", "ack"}},
+		{Name: "ACKNOWLEDGE_HOST_PROBLEM", Args: []string{"web01", "2"}},
+		{Name: "ACKNOWLEDGE_HOST_PROBLEM", Args: []string{"web01", "", "1", "1", "admin", "ack"}},
+		{Name: "CHANGE_NORMAL_SVC_CHECK_INTERVAL", Args: []string{"web01", "HTTP", "2.5"}},
+		{Name: "DEL_DOWNTIME_BY_HOST_NAME", Args: []string{"web01"}},
+		{Name: "TEST_CMD", Args: []string{"anything"}},
+		{Name: "READ_STATE_INFORMATION"},
+		{Name: "NO_SUCH_COMMAND"},
+	}
+	p.DispatchBatch(cmds)
+	want := []string{
+		"invalid sticky 'yes'",
+		"expected 6 arguments, got 2",
+		"",
+		"",
+		"",
+		"",
+		"command not supported",
+		"unknown command",
+	}
+	for i, cmd := range cmds {
+		got := ""
+		if cmd.Err != nil {
+			got = cmd.Err.Error()
+		}
+		if got != want[i] {
+			t.Errorf("%s %q: err = %q, want %q", cmd.Name, cmd.Args, got, want[i])
+		}
+	}
+	if want := []string{"ACKNOWLEDGE_HOST_PROBLEM", "CHANGE_NORMAL_SVC_CHECK_INTERVAL", "DEL_DOWNTIME_BY_HOST_NAME", "TEST_CMD"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+}
+
+func TestProcessFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.txt")
+	lines := "[1609459200] DISABLE_HOST_CHECK;web01\n\n[1609459200] PROCESS_FILE;" + path + ";0\n" +
+		"not a command\n[1609459200] DISABLE_HOST_CHECK;db01\n"
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewProcessor("", 10)
+	var logged []string
+	p.SetLogger(func(format string, args ...interface{}) {
+		logged = append(logged, format)
+	})
+	var hosts []string
+	p.RegisterHandler("DISABLE_HOST_CHECK", func(cmd *Command) {
+		hosts = append(hosts, cmd.Args[0])
+		if cmd.Source != "file "+path {
+			t.Errorf("source = %q", cmd.Source)
+		}
+	})
+
+	p.Dispatch("PROCESS_FILE", []string{path, "0"})
+	if !slices.Equal(hosts, []string{"web01", "db01"}) {
+		t.Errorf("ran for %q", hosts)
+	}
+	if len(logged) != 3 {
+		t.Errorf("logged %q", logged)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("file removed with delete 0: %v", err)
+	}
+
+	p.Dispatch("PROCESS_FILE", []string{path, "1"})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file kept with delete 1: %v", err)
+	}
+	cmds := []Command{{Name: "PROCESS_FILE", Args: []string{path, "0"}}}
+	p.DispatchBatch(cmds)
+	if cmds[0].Err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -1,14 +1,16 @@
-// Package extcmd implements the Nagios external command pipe interface.
+// Package extcmd implements the Nagios external command interface. Commands
+// arrive through one or more intake sources (the classic named pipe, a Unix
+// datagram socket, TCP, HTTP) and are parsed and dispatched by a single
+// Processor.
 package extcmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 )
 
@@ -18,37 +20,80 @@ type Command struct {
 	Name      string
 	Args      []string
 	Raw       string
+	Source    string // intake the command arrived on, e.g. "command file", "Livestatus"
+	// ResponseFile names the file in command_response_dir the command's
+	// result is written to, from an "@<name>" token after the timestamp.
+	ResponseFile string
+	// Err is why the command was not carried out, set by the handler with
+	// Fail.
+	Err error
+}
+
+// Fail records why the command was not carried out. Only the first failure
+// is kept.
+func (c *Command) Fail(format string, args ...interface{}) {
+	if c.Err == nil {
+		c.Err = fmt.Errorf(format, args...)
+	}
 }
 
 // Handler is a function that processes an external command.
 type Handler func(cmd *Command)
 
-// Processor reads external commands from a named pipe and dispatches them.
+// Processor reads external commands from its intake sources and dispatches
+// them.
 type Processor struct {
-	pipePath string
+	pipe     *PipeSource // nil when no command_file is configured
+	sources  []Source
+	started  []Source
 	handlers map[string]Handler
 	cmdChan  chan *Command
-	stopChan chan struct{}
-	wg       sync.WaitGroup
 	mu       sync.RWMutex
 	logger   func(string, ...interface{})
+	// responseDir is where response files are written; empty disables them.
+	responseDir string
 	// StateMu is an optional mutex held during handler invocation to
 	// synchronize state mutations with concurrent readers (e.g. livestatus).
 	// Set by the caller after construction.
 	StateMu *sync.RWMutex
+	// QuietBatch, when set, is called with true before the commands of a
+	// batch are applied and with false after, so their own log lines can
+	// be left out in favour of the batch summary.
+	QuietBatch func(quiet bool)
+
+	batchMu sync.Mutex
+	batches map[string][]*Command // open batches by source
+	applyMu sync.RWMutex          // held for writing while a batch is applied
 }
 
-// NewProcessor creates a new command processor.
+// NewProcessor creates a new command processor reading from the named pipe
+// at pipePath. An empty pipePath creates a processor with no pipe; add
+// other sources with AddSource.
 func NewProcessor(pipePath string, bufSize int) *Processor {
 	if bufSize <= 0 {
 		bufSize = 256
 	}
-	return &Processor{
-		pipePath: pipePath,
+	p := &Processor{
 		handlers: make(map[string]Handler),
 		cmdChan:  make(chan *Command, bufSize),
-		stopChan: make(chan struct{}),
+		batches:  make(map[string][]*Command),
 	}
+	p.handlers["PROCESS_FILE"] = p.processFile
+	if pipePath != "" {
+		p.pipe = NewPipeSource(pipePath)
+		p.sources = append(p.sources, p.pipe)
+	}
+	return p
+}
+
+// AddSource adds a command intake. Sources must be added before Start.
+func (p *Processor) AddSource(src Source) {
+	p.sources = append(p.sources, src)
+}
+
+// Sources returns the configured intake sources.
+func (p *Processor) Sources() []Source {
+	return p.sources
 }
 
 // SetLogger sets the logging function.
@@ -62,6 +107,22 @@ func (p *Processor) log(format string, args ...interface{}) {
 	}
 }
 
+// SetResponseDir sets the directory commands carrying an "@<name>" token
+// have their result written to. Response files are only ever written inside
+// dir; an empty dir ignores the tokens.
+func (p *Processor) SetResponseDir(dir string) {
+	p.responseDir = dir
+}
+
+// SetPipePermissions sets the mode and group (name or GID, empty to leave
+// unchanged) applied to the command pipe when Start creates it. An existing
+// pipe is left as it is.
+func (p *Processor) SetPipePermissions(mode os.FileMode, group string) {
+	if p.pipe != nil {
+		p.pipe.SetPermissions(mode, group)
+	}
+}
+
 // RegisterHandler registers a handler for a command name.
 func (p *Processor) RegisterHandler(name string, h Handler) {
 	p.mu.Lock()
@@ -73,20 +134,26 @@ func (p *Processor) RegisterHandler(name string, h Handler) {
 // This allows external APIs (like Livestatus) to route commands
 // through the same handler infrastructure as the pipe interface.
 func (p *Processor) Dispatch(name string, args []string) {
-	p.mu.RLock()
-	handler, ok := p.handlers[name]
-	p.mu.RUnlock()
-	if ok {
-		if p.StateMu != nil {
-			p.StateMu.Lock()
-			defer p.StateMu.Unlock()
-		}
-		handler(&Command{
-			Timestamp: time.Now().Unix(),
-			Name:      name,
-			Args:      args,
-		})
+	p.DispatchFrom("", name, args)
+}
+
+// DispatchFrom is Dispatch with the intake recorded in Command.Source, so
+// handlers such as PROCESS_SERVICE_CHECK_RESULT can report a check_source.
+func (p *Processor) DispatchFrom(source, name string, args []string) {
+	cmd := &Command{
+		Timestamp: time.Now().Unix(),
+		Name:      name,
+		Args:      args,
+		Source:    source,
+	}
+	if p.StateMu != nil {
+		p.StateMu.Lock()
 	}
+	p.run(cmd)
+	if p.StateMu != nil {
+		p.StateMu.Unlock()
+	}
+	p.logFailure(cmd)
 }
 
 // DispatchBatch invokes multiple command handlers under a single StateMu
@@ -98,31 +165,31 @@ func (p *Processor) DispatchBatch(cmds []Command) {
 		return
 	}
 
-	// Resolve all handlers up front under a single read-lock.
-	type resolved struct {
-		cmd     Command
-		handler Handler
+	// Take the write lock once for the entire batch.
+	if p.StateMu != nil {
+		p.StateMu.Lock()
 	}
-	p.mu.RLock()
-	batch := make([]resolved, 0, len(cmds))
-	for _, c := range cmds {
-		if h, ok := p.handlers[c.Name]; ok {
-			batch = append(batch, resolved{cmd: c, handler: h})
-		}
+	for i := range cmds {
+		p.run(&cmds[i])
 	}
-	p.mu.RUnlock()
-
-	if len(batch) == 0 {
-		return
+	if p.StateMu != nil {
+		p.StateMu.Unlock()
 	}
+	for i := range cmds {
+		p.logFailure(&cmds[i])
+	}
+}
 
-	// Take the write lock once for the entire batch.
-	if p.StateMu != nil {
-		p.StateMu.Lock()
-		defer p.StateMu.Unlock()
+// logFailure logs why a dispatched command was not carried out. Commands
+// from intake sources report this in their response file instead.
+func (p *Processor) logFailure(cmd *Command) {
+	if cmd.Err == nil {
+		return
 	}
-	for i := range batch {
-		batch[i].handler(&batch[i].cmd)
+	if cmd.Source != "" {
+		p.log("Warning: External command %s from %s failed: %s", cmd.Name, cmd.Source, cmd.Err)
+	} else {
+		p.log("Warning: External command %s failed: %s", cmd.Name, cmd.Err)
 	}
 }
 
@@ -141,100 +208,131 @@ func (p *Processor) CommandChan() <-chan *Command {
 	return p.cmdChan
 }
 
-// Start begins reading from the command pipe in a goroutine.
+// Start starts every intake source. If one fails, the sources already
+// started are stopped and the error is returned.
 func (p *Processor) Start() error {
-	// Create FIFO if it doesn't exist
-	if _, err := os.Stat(p.pipePath); os.IsNotExist(err) {
-		if err := mkfifo(p.pipePath); err != nil {
-			return fmt.Errorf("failed to create command pipe %s: %w", p.pipePath, err)
+	for _, src := range p.sources {
+		if err := src.Start(p.submit); err != nil {
+			p.Stop()
+			return fmt.Errorf("%s: %w", src, err)
 		}
+		p.started = append(p.started, src)
 	}
-
-	p.wg.Add(1)
-	go p.readLoop()
 	return nil
 }
 
-// Stop stops the command processor.
+// Stop stops the intake sources.
 func (p *Processor) Stop() {
-	close(p.stopChan)
-	// Unblock the readLoop if it's stuck in os.Open() on the FIFO.
-	// Use O_WRONLY|O_NONBLOCK so this open doesn't block itself,
-	// and the write-side open wakes up the blocking read-side open.
-	fd, err := syscall.Open(p.pipePath, syscall.O_WRONLY|syscall.O_NONBLOCK, 0)
-	if err == nil {
-		syscall.Close(fd)
+	for _, src := range p.started {
+		src.Stop()
 	}
-	p.wg.Wait()
+	p.started = nil
 }
 
-func (p *Processor) readLoop() {
-	defer p.wg.Done()
-	for {
-		select {
-		case <-p.stopChan:
-			return
-		default:
-		}
+// submit parses one command line received by a source and dispatches it.
+// It is the SubmitFunc passed to every source.
+func (p *Processor) submit(source, line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
 
-		f, err := os.Open(p.pipePath)
-		if err != nil {
-			select {
-			case <-p.stopChan:
-				return
-			default:
-				continue
-			}
+	cmd, err := Parse(line)
+	if err != nil {
+		p.log("Error parsing external command: %s", err)
+		return err
+	}
+	cmd.Source = source
+	if p.batchCommand(cmd) {
+		return nil
+	}
+
+	if p.StateMu != nil {
+		p.StateMu.Lock()
+	}
+	p.run(cmd)
+	if p.StateMu != nil {
+		p.StateMu.Unlock()
+	}
+	p.finish(cmd)
+	return nil
+}
+
+// run carries out cmd with its handler, or fails it when the command is
+// unknown, not supported, or has missing or malformed arguments. The
+// caller holds StateMu.
+func (p *Processor) run(cmd *Command) {
+	p.mu.RLock()
+	handler, ok := p.handlers[cmd.Name]
+	p.mu.RUnlock()
+
+	switch {
+	case !ok && Known(cmd.Name):
+		cmd.Fail("command not supported")
+	case !ok:
+		cmd.Fail("unknown command")
+	default:
+		if checkArgs(cmd); cmd.Err == nil {
+			handler(cmd)
 		}
+	}
+}
+
+// finish writes the response file of a command that has been run and
+// passes it on to the main loop.
+func (p *Processor) finish(cmd *Command) {
+	if cmd.ResponseFile != "" {
+		p.writeResponse(cmd)
+	}
+
+	// Also send to channel for main loop processing
+	select {
+	case p.cmdChan <- cmd:
+	default:
+		p.log("External command channel full, dropping: %s", cmd.Name)
+	}
+}
 
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			select {
-			case <-p.stopChan:
-				f.Close()
-				return
-			default:
-			}
-
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" {
-				continue
-			}
-
-			cmd, err := Parse(line)
-			if err != nil {
-				p.log("Error parsing external command: %s", err)
-				continue
-			}
-
-			// Try direct dispatch first
-			p.mu.RLock()
-			handler, ok := p.handlers[cmd.Name]
-			p.mu.RUnlock()
-
-			if ok {
-				if p.StateMu != nil {
-					p.StateMu.Lock()
-				}
-				handler(cmd)
-				if p.StateMu != nil {
-					p.StateMu.Unlock()
-				}
-			}
-
-			// Also send to channel for main loop processing
-			select {
-			case p.cmdChan <- cmd:
-			default:
-				p.log("External command channel full, dropping: %s", cmd.Name)
-			}
+// writeResponse writes "OK" or "ERROR: <reason>" to cmd's response file.
+// The file is written under a temporary name and renamed, so a script
+// waiting for it to appear never reads a partial result.
+func (p *Processor) writeResponse(cmd *Command) {
+	if p.responseDir == "" {
+		p.log("Warning: Ignoring response file '%s' for %s: command_response_dir is not set", cmd.ResponseFile, cmd.Name)
+		return
+	}
+	result := "OK\n"
+	if cmd.Err != nil {
+		result = "ERROR: " + cmd.Err.Error() + "\n"
+	}
+	path := filepath.Join(p.responseDir, cmd.ResponseFile)
+	tmp, err := os.CreateTemp(p.responseDir, "."+cmd.ResponseFile+".tmp*")
+	if err == nil {
+		_, err = tmp.WriteString(result)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
 		}
-		f.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		p.log("Warning: Could not write response file '%s' for %s: %s", path, cmd.Name, err)
 	}
 }
 
+// validResponseName reports whether name may be used as a response file
+// name: a single path element, so a command cannot write outside the
+// response directory.
+func validResponseName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\x00")
+}
+
 // Parse parses a single external command line.
-// Format: [<timestamp>] <COMMAND_NAME>;<arg1>;<arg2>;...
+// Format: [<timestamp>] [@<response_file>] <COMMAND_NAME>;<arg1>;<arg2>;...
 func Parse(line string) (*Command, error) {
 	line = strings.TrimSpace(line)
 	if len(line) == 0 {
@@ -265,6 +363,18 @@ func Parse(line string) (*Command, error) {
 		Raw:       line,
 	}
 
+	if strings.HasPrefix(rest, "@") {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return nil, fmt.Errorf("missing command after response file")
+		}
+		cmd.ResponseFile = rest[1:end]
+		if !validResponseName(cmd.ResponseFile) {
+			return nil, fmt.Errorf("invalid response file name '%s'", cmd.ResponseFile)
+		}
+		rest = strings.TrimSpace(rest[end:])
+	}
+
 	semiIdx := strings.IndexByte(rest, ';')
 	if semiIdx < 0 {
 		cmd.Name = rest
@@ -310,163 +420,6 @@ func splitArgs(cmdName, argStr string) []string {
 	return args
 }
 
-func expectedArgCount(cmdName string) int {
-	switch cmdName {
-	case "ACKNOWLEDGE_HOST_PROBLEM":
-		return 6 // host;sticky;notify;persistent;author;comment
-	case "ACKNOWLEDGE_SVC_PROBLEM":
-		return 7 // host;svc;sticky;notify;persistent;author;comment
-	case "ADD_HOST_COMMENT":
-		return 4 // host;persistent;author;comment
-	case "ADD_SVC_COMMENT":
-		return 5 // host;svc;persistent;author;comment
-	case "DEL_HOST_COMMENT", "DEL_SVC_COMMENT":
-		return 1
-	case "DEL_ALL_HOST_COMMENTS":
-		return 1
-	case "DEL_ALL_SVC_COMMENTS":
-		return 2
-	case "SCHEDULE_HOST_DOWNTIME":
-		return 8 // host;start;end;fixed;trigger_id;duration;author;comment
-	case "SCHEDULE_SVC_DOWNTIME":
-		return 9 // host;svc;start;end;fixed;trigger_id;duration;author;comment
-	case "SCHEDULE_HOST_SVC_DOWNTIME":
-		return 8
-	case "DEL_HOST_DOWNTIME", "DEL_SVC_DOWNTIME":
-		return 1
-	case "REMOVE_HOST_ACKNOWLEDGEMENT":
-		return 1
-	case "REMOVE_SVC_ACKNOWLEDGEMENT":
-		return 2
-	case "ENABLE_HOST_NOTIFICATIONS", "DISABLE_HOST_NOTIFICATIONS":
-		return 1
-	case "ENABLE_SVC_NOTIFICATIONS", "DISABLE_SVC_NOTIFICATIONS":
-		return 2
-	case "ENABLE_HOST_SVC_NOTIFICATIONS", "DISABLE_HOST_SVC_NOTIFICATIONS":
-		return 1
-	case "SCHEDULE_HOST_CHECK", "SCHEDULE_FORCED_HOST_CHECK":
-		return 2
-	case "SCHEDULE_SVC_CHECK", "SCHEDULE_FORCED_SVC_CHECK":
-		return 3
-	case "PROCESS_SERVICE_CHECK_RESULT":
-		return 4 // host;svc;return_code;output
-	case "PROCESS_HOST_CHECK_RESULT":
-		return 3 // host;return_code;output
-	case "SEND_CUSTOM_HOST_NOTIFICATION":
-		return 4 // host;options;author;comment
-	case "SEND_CUSTOM_SVC_NOTIFICATION":
-		return 5 // host;svc;options;author;comment
-	case "DELAY_HOST_NOTIFICATION":
-		return 2
-	case "DELAY_SVC_NOTIFICATION":
-		return 3
-	case "SCHEDULE_HOST_SVC_CHECKS", "SCHEDULE_FORCED_HOST_SVC_CHECKS":
-		return 2
-	case "ENABLE_HOST_CHECK", "DISABLE_HOST_CHECK":
-		return 1
-	case "ENABLE_SVC_CHECK", "DISABLE_SVC_CHECK":
-		return 2
-	case "ENABLE_PASSIVE_HOST_CHECKS", "DISABLE_PASSIVE_HOST_CHECKS":
-		return 1
-	case "ENABLE_PASSIVE_SVC_CHECKS", "DISABLE_PASSIVE_SVC_CHECKS":
-		return 2
-	case "ENABLE_HOST_EVENT_HANDLER", "DISABLE_HOST_EVENT_HANDLER":
-		return 1
-	case "ENABLE_SVC_EVENT_HANDLER", "DISABLE_SVC_EVENT_HANDLER":
-		return 2
-	case "ENABLE_HOST_FLAP_DETECTION", "DISABLE_HOST_FLAP_DETECTION":
-		return 1
-	case "ENABLE_SVC_FLAP_DETECTION", "DISABLE_SVC_FLAP_DETECTION":
-		return 2
-	case "SET_HOST_NOTIFICATION_NUMBER":
-		return 2
-	case "SET_SVC_NOTIFICATION_NUMBER":
-		return 3
-	case "CHANGE_NORMAL_HOST_CHECK_INTERVAL", "CHANGE_RETRY_HOST_CHECK_INTERVAL":
-		return 2
-	case "CHANGE_NORMAL_SVC_CHECK_INTERVAL", "CHANGE_RETRY_SVC_CHECK_INTERVAL":
-		return 3
-	case "CHANGE_MAX_HOST_CHECK_ATTEMPTS":
-		return 2
-	case "CHANGE_MAX_SVC_CHECK_ATTEMPTS":
-		return 3
-	case "CHANGE_HOST_EVENT_HANDLER", "CHANGE_HOST_CHECK_COMMAND":
-		return 2
-	case "CHANGE_SVC_EVENT_HANDLER", "CHANGE_SVC_CHECK_COMMAND":
-		return 3
-	case "CHANGE_HOST_CHECK_TIMEPERIOD", "CHANGE_HOST_NOTIFICATION_TIMEPERIOD":
-		return 2
-	case "CHANGE_SVC_CHECK_TIMEPERIOD", "CHANGE_SVC_NOTIFICATION_TIMEPERIOD":
-		return 3
-	case "CHANGE_CUSTOM_HOST_VAR":
-		return 3
-	case "CHANGE_CUSTOM_SVC_VAR":
-		return 4
-	case "CHANGE_CUSTOM_CONTACT_VAR":
-		return 3
-	case "CHANGE_GLOBAL_HOST_EVENT_HANDLER", "CHANGE_GLOBAL_SVC_EVENT_HANDLER":
-		return 1
-	case "ENABLE_HOSTGROUP_HOST_NOTIFICATIONS", "DISABLE_HOSTGROUP_HOST_NOTIFICATIONS",
-		"ENABLE_HOSTGROUP_SVC_NOTIFICATIONS", "DISABLE_HOSTGROUP_SVC_NOTIFICATIONS",
-		"ENABLE_HOSTGROUP_HOST_CHECKS", "DISABLE_HOSTGROUP_HOST_CHECKS",
-		"ENABLE_HOSTGROUP_PASSIVE_HOST_CHECKS", "DISABLE_HOSTGROUP_PASSIVE_HOST_CHECKS",
-		"ENABLE_HOSTGROUP_SVC_CHECKS", "DISABLE_HOSTGROUP_SVC_CHECKS",
-		"ENABLE_HOSTGROUP_PASSIVE_SVC_CHECKS", "DISABLE_HOSTGROUP_PASSIVE_SVC_CHECKS":
-		return 1
-	case "SCHEDULE_HOSTGROUP_HOST_DOWNTIME":
-		return 8
-	case "SCHEDULE_HOSTGROUP_SVC_DOWNTIME":
-		return 8
-	case "ENABLE_SERVICEGROUP_HOST_NOTIFICATIONS", "DISABLE_SERVICEGROUP_HOST_NOTIFICATIONS",
-		"ENABLE_SERVICEGROUP_SVC_NOTIFICATIONS", "DISABLE_SERVICEGROUP_SVC_NOTIFICATIONS",
-		"ENABLE_SERVICEGROUP_HOST_CHECKS", "DISABLE_SERVICEGROUP_HOST_CHECKS",
-		"ENABLE_SERVICEGROUP_PASSIVE_HOST_CHECKS", "DISABLE_SERVICEGROUP_PASSIVE_HOST_CHECKS",
-		"ENABLE_SERVICEGROUP_SVC_CHECKS", "DISABLE_SERVICEGROUP_SVC_CHECKS",
-		"ENABLE_SERVICEGROUP_PASSIVE_SVC_CHECKS", "DISABLE_SERVICEGROUP_PASSIVE_SVC_CHECKS":
-		return 1
-	case "SCHEDULE_SERVICEGROUP_HOST_DOWNTIME", "SCHEDULE_SERVICEGROUP_SVC_DOWNTIME":
-		return 8
-	case "ENABLE_CONTACT_HOST_NOTIFICATIONS", "DISABLE_CONTACT_HOST_NOTIFICATIONS":
-		return 1
-	case "ENABLE_CONTACT_SVC_NOTIFICATIONS", "DISABLE_CONTACT_SVC_NOTIFICATIONS":
-		return 1
-	case "CHANGE_CONTACT_HOST_NOTIFICATION_TIMEPERIOD", "CHANGE_CONTACT_SVC_NOTIFICATION_TIMEPERIOD":
-		return 2
-	case "CHANGE_CONTACT_MODATTR", "CHANGE_CONTACT_MODHATTR", "CHANGE_CONTACT_MODSATTR":
-		return 2
-	case "ENABLE_CONTACTGROUP_HOST_NOTIFICATIONS", "DISABLE_CONTACTGROUP_HOST_NOTIFICATIONS":
-		return 1
-	case "ENABLE_CONTACTGROUP_SVC_NOTIFICATIONS", "DISABLE_CONTACTGROUP_SVC_NOTIFICATIONS":
-		return 1
-	case "DEL_DOWNTIME_BY_HOST_NAME":
-		return 4 // host;svc;start;comment (all optional except host)
-	case "DEL_DOWNTIME_BY_HOSTGROUP_NAME":
-		return 4
-	case "DEL_DOWNTIME_BY_START_TIME_COMMENT":
-		return 2
-	case "SCHEDULE_AND_PROPAGATE_TRIGGERED_HOST_DOWNTIME":
-		return 8
-	case "SCHEDULE_AND_PROPAGATE_HOST_DOWNTIME":
-		return 8
-	case "ENABLE_HOST_AND_CHILD_NOTIFICATIONS", "DISABLE_HOST_AND_CHILD_NOTIFICATIONS":
-		return 1
-	case "ENABLE_ALL_NOTIFICATIONS_BEYOND_HOST", "DISABLE_ALL_NOTIFICATIONS_BEYOND_HOST":
-		return 1
-	case "START_OBSESSING_OVER_HOST", "STOP_OBSESSING_OVER_HOST":
-		return 1
-	case "START_OBSESSING_OVER_SVC", "STOP_OBSESSING_OVER_SVC":
-		return 2
-	case "CHANGE_HOST_MODATTR":
-		return 2
-	case "CHANGE_SVC_MODATTR":
-		return 3
-	case "PROCESS_FILE":
-		return 2
-	default:
-		return 0
-	}
-}
-
 // mkfifo creates a named pipe. On Unix systems this uses syscall.
 func mkfifo(path string) error {
 	return mkfifoImpl(path)
@@ -1,6 +1,11 @@
 package extcmd
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 )
 
@@ -119,6 +124,22 @@ func TestDispatch_RegisteredHandler(t *testing.T) {
 	}
 }
 
+func TestDispatchFrom_SetsSource(t *testing.T) {
+	p := NewProcessor("/dev/null", 10)
+	var got string
+	p.RegisterHandler("TEST_CMD", func(cmd *Command) {
+		got = cmd.Source
+	})
+	p.DispatchFrom("Livestatus", "TEST_CMD", nil)
+	if got != "Livestatus" {
+		t.Errorf("expected source Livestatus, got %q", got)
+	}
+	p.Dispatch("TEST_CMD", nil)
+	if got != "" {
+		t.Errorf("expected empty source from Dispatch, got %q", got)
+	}
+}
+
 func TestDispatch_UnregisteredHandler(t *testing.T) {
 	p := NewProcessor("/dev/null", 10)
 	// Should not panic
@@ -251,3 +272,151 @@ func TestExpectedArgCount_UnknownCommand(t *testing.T) {
 		t.Errorf("expected 0, got %d", got)
 	}
 }
+
+func TestStart_AppliesPipePermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nagios.cmd")
+	p := NewProcessor(path, 1)
+	p.SetPipePermissions(0620, "")
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 || fi.Mode().Perm() != 0620 {
+		t.Errorf("unexpected pipe mode %v", fi.Mode())
+	}
+}
+
+func TestParse_ResponseFile(t *testing.T) {
+	cmd, err := Parse("[1609459200] @job-4711 DISABLE_HOST_CHECK;web01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.ResponseFile != "job-4711" || cmd.Name != "DISABLE_HOST_CHECK" || len(cmd.Args) != 1 || cmd.Args[0] != "web01" {
+		t.Errorf("unexpected parse: %+v", cmd)
+	}
+	for _, line := range []string{
+		"[1609459200] @../etc/passwd DISABLE_HOST_CHECK;web01",
+		"[1609459200] @.. DISABLE_HOST_CHECK;web01",
+		"[1609459200] @ DISABLE_HOST_CHECK;web01",
+		"[1609459200] @job-4711",
+	} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("Parse(%q): expected an error", line)
+		}
+	}
+}
+
+func TestSubmit_WritesResponseFile(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProcessor("", 10)
+	p.SetResponseDir(dir)
+	p.RegisterHandler("DISABLE_HOST_CHECK", func(cmd *Command) {
+		if cmd.Args[0] != "web01" {
+			cmd.Fail("unknown host '%s'", cmd.Args[0])
+		}
+	})
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"[1] @ok DISABLE_HOST_CHECK;web01", "OK\n"},
+		{"[1] @nohost DISABLE_HOST_CHECK;db99", "ERROR: unknown host 'db99'\n"},
+		{"[1] @noargs DISABLE_HOST_CHECK", "ERROR: expected 1 arguments, got 0\n"},
+		{"[1] @unknown FROBNICATE_HOST;web01", "ERROR: unknown command\n"},
+	}
+	for _, tt := range tests {
+		if err := p.submit("command file", tt.line); err != nil {
+			t.Fatal(err)
+		}
+		cmd, _ := Parse(tt.line)
+		data, err := os.ReadFile(filepath.Join(dir, cmd.ResponseFile))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.line, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: response %q, want %q", tt.line, data, tt.want)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != len(tests) {
+		t.Errorf("expected only the %d response files, got %d entries", len(tests), len(entries))
+	}
+
+	// Without a response directory nothing is written.
+	p.SetResponseDir("")
+	p.submit("command file", "[1] @elsewhere DISABLE_HOST_CHECK;web01")
+	if _, err := os.Stat(filepath.Join(dir, "elsewhere")); err == nil {
+		t.Error("expected no response file without command_response_dir")
+	}
+}
+
+func TestSubmit_Batch(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProcessor("", 10)
+	p.SetResponseDir(dir)
+	p.StateMu = &sync.RWMutex{}
+	var applied []string
+	p.RegisterHandler("DISABLE_HOST_CHECK", func(cmd *Command) {
+		applied = append(applied, cmd.Args[0])
+	})
+	var quiet []bool
+	p.QuietBatch = func(q bool) { quiet = append(quiet, q) }
+	var logged []string
+	p.SetLogger(func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+	response := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+
+	for _, line := range []string{
+		"[1] @begin BEGIN_BATCH",
+		"[1] DISABLE_HOST_CHECK;web01",
+		"[1] @bad FROBNICATE_HOST;web01",
+		"[1] @web02 DISABLE_HOST_CHECK;web02",
+		"[1] @nested BEGIN_BATCH",
+	} {
+		p.submit("command file", line)
+	}
+	// Commands from another source are not held.
+	p.submit("command HTTP 10.0.0.1", "[1] DISABLE_HOST_CHECK;db01")
+	if len(applied) != 1 || applied[0] != "db01" {
+		t.Fatalf("applied before END_BATCH: %v", applied)
+	}
+	if response("begin") != "OK\n" || response("nested") != "ERROR: batch already open\n" {
+		t.Errorf("begin responses %q, %q", response("begin"), response("nested"))
+	}
+	if response("web02") != "" {
+		t.Error("response written before the batch was applied")
+	}
+
+	p.submit("command file", "[1] @end END_BATCH")
+	if want := []string{"db01", "web01", "web02"}; !slices.Equal(applied, want) {
+		t.Errorf("applied %v, want %v", applied, want)
+	}
+	if !slices.Equal(quiet, []bool{true, false}) {
+		t.Errorf("QuietBatch calls %v", quiet)
+	}
+	if want := "EXTERNAL COMMAND BATCH: 3 commands from command file (DISABLE_HOST_CHECK x2, FROBNICATE_HOST x1), 1 failed"; len(logged) != 1 || logged[0] != want {
+		t.Errorf("logged %q, want %q", logged, want)
+	}
+	for name, want := range map[string]string{"end": "OK\n", "web02": "OK\n", "bad": "ERROR: unknown command\n"} {
+		if got := response(name); got != want {
+			t.Errorf("response %s: %q, want %q", name, got, want)
+		}
+	}
+
+	p.submit("command file", "[1] @again END_BATCH")
+	if got := response("again"); got != "ERROR: no batch open\n" {
+		t.Errorf("END_BATCH without a batch: %q", got)
+	}
+	p.submit("command file", "[1] DISABLE_HOST_CHECK;web03")
+	if applied[len(applied)-1] != "web03" {
+		t.Error("command after the batch was held")
+	}
+}
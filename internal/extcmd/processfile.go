@@ -0,0 +1,53 @@
+package extcmd
+
+import (
+	"os"
+	"strings"
+)
+
+// processFile is the default PROCESS_FILE handler. It runs each command
+// line of the named file, as the command file would, under the StateMu lock
+// the caller already holds, and removes the file afterwards when the
+// delete argument is non-zero. Lines that fail are logged; the command
+// itself only fails when the file cannot be read.
+func (p *Processor) processFile(cmd *Command) {
+	path := cmd.Args[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		cmd.Fail("%v", err)
+		return
+	}
+	source := "file " + path
+	n, failed := 0, 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		n++
+		c, err := Parse(line)
+		if err != nil {
+			p.log("Error parsing external command in %s: %s", path, err)
+			failed++
+			continue
+		}
+		c.Source = source
+		if c.Name == "PROCESS_FILE" {
+			c.Fail("PROCESS_FILE cannot be nested")
+		} else {
+			p.run(c)
+		}
+		if c.ResponseFile != "" {
+			p.writeResponse(c)
+		}
+		p.logFailure(c)
+		if c.Err != nil {
+			failed++
+		}
+	}
+	p.log("EXTERNAL COMMAND: PROCESS_FILE;%s;%s (%d commands, %d failed)", path, cmd.Args[1], n, failed)
+	if del := strings.TrimSpace(cmd.Args[1]); del != "" && del != "0" {
+		if err := os.Remove(path); err != nil {
+			p.log("Warning: Could not remove %s after PROCESS_FILE: %v", path, err)
+		}
+	}
+}
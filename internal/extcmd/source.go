@@ -0,0 +1,111 @@
+package extcmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/oceanplexian/gogios/internal/fileperm"
+)
+
+// SubmitFunc receives one raw command line from a source. source is recorded
+// in Command.Source. It returns an error if the line could not be parsed.
+type SubmitFunc func(source, line string) error
+
+// Source is an external command intake. Start begins delivering command
+// lines to submit in the background; Stop halts delivery and releases the
+// source's listener or file.
+type Source interface {
+	Start(submit SubmitFunc) error
+	Stop()
+	String() string // for log messages, e.g. "command file /var/lib/nagios/rw/nagios.cmd"
+}
+
+// PipeSource reads commands from the classic Nagios named pipe.
+type PipeSource struct {
+	path  string
+	mode  os.FileMode
+	group string
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewPipeSource creates a source reading the FIFO at path, creating it on
+// Start if it does not exist.
+func NewPipeSource(path string) *PipeSource {
+	return &PipeSource{path: path, mode: 0660}
+}
+
+// SetPermissions sets the mode and group (name or GID, empty to leave
+// unchanged) applied to the pipe when Start creates it.
+func (s *PipeSource) SetPermissions(mode os.FileMode, group string) {
+	s.mode = mode
+	s.group = group
+}
+
+func (s *PipeSource) String() string { return "command file " + s.path }
+
+// Start creates the FIFO if needed and begins reading it.
+func (s *PipeSource) Start(submit SubmitFunc) error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		if err := mkfifo(s.path); err != nil {
+			return fmt.Errorf("failed to create command pipe %s: %w", s.path, err)
+		}
+		// mkfifo honours the umask, so set the mode explicitly.
+		if err := fileperm.Apply(s.path, s.mode, s.group); err != nil {
+			return fmt.Errorf("failed to set command pipe permissions %s: %w", s.path, err)
+		}
+	}
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go s.readLoop(submit)
+	return nil
+}
+
+// Stop stops reading the pipe.
+func (s *PipeSource) Stop() {
+	close(s.stop)
+	// Unblock the readLoop if it's stuck in os.Open() on the FIFO.
+	// Use O_WRONLY|O_NONBLOCK so this open doesn't block itself,
+	// and the write-side open wakes up the blocking read-side open.
+	fd, err := syscall.Open(s.path, syscall.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err == nil {
+		syscall.Close(fd)
+	}
+	s.wg.Wait()
+}
+
+func (s *PipeSource) readLoop(submit SubmitFunc) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		f, err := os.Open(s.path)
+		if err != nil {
+			select {
+			case <-s.stop:
+				return
+			default:
+				continue
+			}
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			select {
+			case <-s.stop:
+				f.Close()
+				return
+			default:
+			}
+			submit("command file", scanner.Text())
+		}
+		f.Close()
+	}
+}
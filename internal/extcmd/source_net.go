@@ -0,0 +1,268 @@
+package extcmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/oceanplexian/gogios/internal/fileperm"
+)
+
+// maxCommandBody caps a single datagram or HTTP request body.
+const maxCommandBody = 1 << 20
+
+// validToken reports whether token matches the bcrypt hash. An empty hash
+// disables authentication.
+func validToken(hash, token string) bool {
+	if hash == "" {
+		return true
+	}
+	return token != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(token)) == nil
+}
+
+// submitLines submits each newline-separated command in data and returns
+// the parse errors.
+func submitLines(submit SubmitFunc, source, data string) []error {
+	var errs []error
+	for _, line := range strings.Split(data, "\n") {
+		if err := submit(source, line); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// DatagramSource accepts commands on a Unix datagram socket, one or more
+// newline-separated commands per datagram. Unlike a FIFO it needs no
+// mkfifo support from the filesystem and never blocks the writer while
+// gogios is not reading.
+type DatagramSource struct {
+	path  string
+	mode  os.FileMode
+	group string
+	conn  *net.UnixConn
+	wg    sync.WaitGroup
+}
+
+// NewDatagramSource creates a source listening on the socket at path.
+func NewDatagramSource(path string) *DatagramSource {
+	return &DatagramSource{path: path, mode: 0660}
+}
+
+// SetPermissions sets the mode and group (name or GID, empty to leave
+// unchanged) applied to the socket when Start creates it.
+func (s *DatagramSource) SetPermissions(mode os.FileMode, group string) {
+	s.mode = mode
+	s.group = group
+}
+
+func (s *DatagramSource) String() string { return "command socket " + s.path }
+
+// Start binds the socket, replacing a stale one, and begins reading.
+func (s *DatagramSource) Start(submit SubmitFunc) error {
+	os.Remove(s.path)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: s.path, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("unixgram listen %s: %w", s.path, err)
+	}
+	if err := fileperm.Apply(s.path, s.mode, s.group); err != nil {
+		conn.Close()
+		return fmt.Errorf("command socket permissions %s: %w", s.path, err)
+	}
+	s.conn = conn
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		buf := make([]byte, maxCommandBody)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				continue
+			}
+			submitLines(submit, "command socket", string(buf[:n]))
+		}
+	}()
+	return nil
+}
+
+// Stop closes and removes the socket.
+func (s *DatagramSource) Stop() {
+	s.conn.Close()
+	s.wg.Wait()
+	os.Remove(s.path)
+}
+
+// TCPSource accepts newline-separated commands on a TCP listener. When a
+// token hash is set, the first line of each connection must be
+// "AUTH <token>". Lines that fail to parse are answered with "ERROR: ...".
+type TCPSource struct {
+	addr      string
+	tokenHash string
+	ln        net.Listener
+	conns     map[net.Conn]struct{}
+	mu        sync.Mutex
+	wg        sync.WaitGroup
+}
+
+// NewTCPSource creates a source listening on addr. tokenHash is a bcrypt
+// hash of the accepted token; empty disables authentication.
+func NewTCPSource(addr, tokenHash string) *TCPSource {
+	return &TCPSource{addr: addr, tokenHash: tokenHash, conns: make(map[net.Conn]struct{})}
+}
+
+func (s *TCPSource) String() string { return "command TCP " + s.addr }
+
+// Addr returns the listening address once started.
+func (s *TCPSource) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Start begins accepting connections.
+func (s *TCPSource) Start(submit SubmitFunc) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("tcp listen %s: %w", s.addr, err)
+	}
+	s.ln = ln
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = struct{}{}
+			s.mu.Unlock()
+			s.wg.Add(1)
+			go s.serve(conn, submit)
+		}
+	}()
+	return nil
+}
+
+func (s *TCPSource) serve(conn net.Conn, submit SubmitFunc) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	source := "command TCP " + host
+	scanner := bufio.NewScanner(conn)
+	if s.tokenHash != "" {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		token, ok := "", scanner.Scan()
+		if ok {
+			token, ok = strings.CutPrefix(strings.TrimSpace(scanner.Text()), "AUTH ")
+		}
+		if !ok || !validToken(s.tokenHash, token) {
+			io.WriteString(conn, "ERROR: authentication failed\n")
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+	}
+	for scanner.Scan() {
+		if err := submit(source, scanner.Text()); err != nil {
+			fmt.Fprintf(conn, "ERROR: %v\n", err)
+		}
+	}
+}
+
+// Stop closes the listener and open connections.
+func (s *TCPSource) Stop() {
+	s.ln.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// HTTPSource accepts commands as the body of POST requests, one command
+// per line. When a token hash is set, requests must carry
+// "Authorization: Bearer <token>". It answers 202 when every line parsed
+// and 400 listing the lines that did not; the lines that parsed are
+// dispatched either way.
+type HTTPSource struct {
+	addr      string
+	tokenHash string
+	ln        net.Listener
+	srv       *http.Server
+}
+
+// NewHTTPSource creates a source listening on addr. tokenHash is a bcrypt
+// hash of the accepted bearer token; empty disables authentication.
+func NewHTTPSource(addr, tokenHash string) *HTTPSource {
+	return &HTTPSource{addr: addr, tokenHash: tokenHash}
+}
+
+func (s *HTTPSource) String() string { return "command HTTP " + s.addr }
+
+// Addr returns the listening address once started.
+func (s *HTTPSource) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Start begins serving.
+func (s *HTTPSource) Start(submit SubmitFunc) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("http listen %s: %w", s.addr, err)
+	}
+	s.ln = ln
+	s.srv = &http.Server{Handler: s.handler(submit), ReadHeaderTimeout: 10 * time.Second}
+	go s.srv.Serve(ln)
+	return nil
+}
+
+func (s *HTTPSource) handler(submit SubmitFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "POST command lines", http.StatusMethodNotAllowed)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !validToken(s.tokenHash, token) {
+			http.Error(w, "authentication failed", http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxCommandBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		if errs := submitLines(submit, "command HTTP "+host, string(body)); len(errs) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			for _, err := range errs {
+				fmt.Fprintf(w, "ERROR: %v\n", err)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Stop shuts down the HTTP server.
+func (s *HTTPSource) Stop() {
+	s.srv.Close()
+}
@@ -0,0 +1,136 @@
+package extcmd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func receiveCommand(t *testing.T, p *Processor) *Command {
+	t.Helper()
+	select {
+	case cmd := <-p.CommandChan():
+		return cmd
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a command")
+		return nil
+	}
+}
+
+func tokenHash(t *testing.T, token string) string {
+	t.Helper()
+	h, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(h)
+}
+
+func TestDatagramSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cmd.sock")
+	p := NewProcessor("", 4)
+	p.AddSource(NewDatagramSource(path))
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	conn, err := net.Dial("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "[1700000000] DISABLE_NOTIFICATIONS\n[1700000000] ENABLE_NOTIFICATIONS\n")
+
+	if cmd := receiveCommand(t, p); cmd.Name != "DISABLE_NOTIFICATIONS" || cmd.Source != "command socket" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+	if cmd := receiveCommand(t, p); cmd.Name != "ENABLE_NOTIFICATIONS" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+}
+
+func TestTCPSource_Auth(t *testing.T) {
+	src := NewTCPSource("127.0.0.1:0", tokenHash(t, "secret"))
+	p := NewProcessor("", 4)
+	p.AddSource(src)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	conn, err := net.Dial("tcp", src.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(conn, "AUTH wrong\n[1700000000] DISABLE_NOTIFICATIONS\n")
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	conn.Close()
+	if line != "ERROR: authentication failed\n" {
+		t.Errorf("expected authentication failure, got %q", line)
+	}
+
+	conn, err = net.Dial("tcp", src.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "AUTH secret\nnot a command\n[1700000000] ENABLE_NOTIFICATIONS\n")
+	line, _ = bufio.NewReader(conn).ReadString('\n')
+	if !strings.HasPrefix(line, "ERROR: ") {
+		t.Errorf("expected a parse error reply, got %q", line)
+	}
+	cmd := receiveCommand(t, p)
+	if cmd.Name != "ENABLE_NOTIFICATIONS" || cmd.Source != "command TCP 127.0.0.1" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+	select {
+	case cmd := <-p.CommandChan():
+		t.Errorf("unauthenticated command dispatched: %+v", cmd)
+	default:
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	src := NewHTTPSource("127.0.0.1:0", tokenHash(t, "secret"))
+	p := NewProcessor("", 4)
+	p.AddSource(src)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	url := "http://" + src.Addr().String() + "/"
+
+	post := func(token, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("", "[1700000000] DISABLE_NOTIFICATIONS\n"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", code)
+	}
+	if code := post("secret", "[1700000000] DISABLE_NOTIFICATIONS\n"); code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", code)
+	}
+	if cmd := receiveCommand(t, p); cmd.Name != "DISABLE_NOTIFICATIONS" || cmd.Source != "command HTTP 127.0.0.1" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+	if code := post("secret", "garbage\n"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unparsable line, got %d", code)
+	}
+}
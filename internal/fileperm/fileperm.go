@@ -0,0 +1,42 @@
+// Package fileperm applies the configured mode and group to files gogios
+// creates for other processes to use, such as the Livestatus socket and the
+// external command pipe.
+package fileperm
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// Apply sets mode on path and, if group is non-empty, changes its group.
+// group may be a group name or a numeric GID. The owner is left unchanged.
+func Apply(path string, mode os.FileMode, group string) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	if group == "" {
+		return nil
+	}
+	gid, err := LookupGID(group)
+	if err != nil {
+		return err
+	}
+	if err := os.Chown(path, -1, gid); err != nil {
+		return fmt.Errorf("chgrp %s %s: %w", group, path, err)
+	}
+	return nil
+}
+
+// LookupGID resolves a group name or numeric GID.
+func LookupGID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
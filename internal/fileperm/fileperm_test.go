@@ -0,0 +1,35 @@
+package fileperm
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	gid := strconv.Itoa(os.Getgid())
+	if err := Apply(path, 0664, gid); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0664 {
+		t.Errorf("mode = %o, want 664", fi.Mode().Perm())
+	}
+}
+
+func TestLookupGID(t *testing.T) {
+	if gid, err := LookupGID("42"); err != nil || gid != 42 {
+		t.Errorf("LookupGID(42) = %d, %v", gid, err)
+	}
+	if _, err := LookupGID("no-such-group-gogios"); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+}
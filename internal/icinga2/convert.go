@@ -0,0 +1,531 @@
+// Package icinga2 converts a subset of the Icinga2 configuration DSL into
+// Nagios object definitions, for migrations from Icinga2 to gogios.
+//
+// Supported: Host, Service, User, TimePeriod, HostGroup, ServiceGroup and
+// UserGroup objects and templates with literal attribute values, and
+// apply Service rules whose assign where clauses match host groups or host
+// names. Everything else (CheckCommand and Notification objects, ignore
+// where, apply for, functions, non-literal expressions) is skipped with a
+// warning, so the output is a starting point rather than a finished config.
+package icinga2
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is the output of Convert.
+type Result struct {
+	Config   string         // Nagios object definitions
+	Counts   map[string]int // converted definitions by Nagios object type
+	Warnings []string       // "file:line: message"
+}
+
+// definition is one Nagios "define <typ> { ... }" block.
+type definition struct {
+	typ    string
+	fields [][2]string
+}
+
+func (d *definition) set(key, value string) {
+	for i := range d.fields {
+		if d.fields[i][0] == key {
+			d.fields[i][1] = value
+			return
+		}
+	}
+	d.fields = append(d.fields, [2]string{key, value})
+}
+
+// nagiosTypes maps Icinga2 object types to Nagios ones, in output order.
+var nagiosTypes = []struct{ icinga, nagios string }{
+	{"TimePeriod", "timeperiod"},
+	{"UserGroup", "contactgroup"},
+	{"User", "contact"},
+	{"HostGroup", "hostgroup"},
+	{"Host", "host"},
+	{"ServiceGroup", "servicegroup"},
+	{"Service", "service"},
+}
+
+func nagiosType(icingaType string) string {
+	for _, t := range nagiosTypes {
+		if t.icinga == icingaType {
+			return t.nagios
+		}
+	}
+	return ""
+}
+
+type converter struct {
+	file     string
+	warnings []warning
+}
+
+type warning struct {
+	line int
+	msg  string
+}
+
+func (c *converter) warn(line int, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, warning{line, fmt.Sprintf(format, args...)})
+}
+
+// Convert translates the Icinga2 configuration in src. file is only used in
+// warnings. An error is returned only when src cannot be tokenized.
+func Convert(file, src string) (*Result, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	c := &converter{file: file}
+	p := &parser{toks: toks, warn: c.warn}
+	p.parseFile()
+
+	byType := make(map[string][]*definition)
+	for _, obj := range p.objs {
+		if def := c.convert(obj); def != nil {
+			byType[def.typ] = append(byType[def.typ], def)
+		}
+	}
+
+	res := &Result{Counts: make(map[string]int)}
+	var b strings.Builder
+	for _, t := range nagiosTypes {
+		for _, def := range byType[t.nagios] {
+			writeDefinition(&b, def)
+			res.Counts[def.typ]++
+		}
+	}
+	res.Config = b.String()
+	sort.SliceStable(c.warnings, func(i, j int) bool { return c.warnings[i].line < c.warnings[j].line })
+	for _, w := range c.warnings {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%s:%d: %s", c.file, w.line, w.msg))
+	}
+	return res, nil
+}
+
+func (c *converter) convert(obj *object) *definition {
+	typ := nagiosType(obj.typ)
+	if typ == "" {
+		c.warn(obj.line, "skipped %s %s '%s': type not supported", obj.kind, obj.typ, obj.name)
+		return nil
+	}
+	def := &definition{typ: typ}
+
+	switch obj.kind {
+	case "template":
+		def.set("name", obj.name)
+	case "apply":
+		if obj.typ != "Service" || obj.applyTo != "" {
+			c.warn(obj.line, "skipped apply %s '%s': only apply Service is supported", obj.typ, obj.name)
+			return nil
+		}
+		if obj.loop {
+			c.warn(obj.line, "skipped apply Service '%s': apply for is not supported", obj.name)
+			return nil
+		}
+		if len(obj.ignore) > 0 {
+			c.warn(obj.line, "skipped apply Service '%s': ignore where is not supported", obj.name)
+			return nil
+		}
+		groups, hosts, ok := c.assignTargets(obj)
+		if !ok {
+			return nil
+		}
+		def.set("service_description", obj.name)
+		if len(hosts) > 0 {
+			def.set("host_name", strings.Join(hosts, ","))
+		}
+		if len(groups) > 0 {
+			def.set("hostgroup_name", strings.Join(groups, ","))
+		}
+	default:
+		def.set(nameField(typ), obj.name)
+	}
+
+	if len(obj.imports) > 0 {
+		// Later Icinga2 imports override earlier ones; Nagios gives the
+		// first template in use precedence.
+		use := make([]string, len(obj.imports))
+		for i, imp := range obj.imports {
+			use[len(use)-1-i] = imp
+		}
+		def.set("use", strings.Join(use, ","))
+	}
+
+	var states, types []string
+	for _, a := range obj.attrs {
+		if strings.HasPrefix(a.path, "vars.") || a.path == "vars" {
+			c.convertVars(obj, def, a)
+			continue
+		}
+		if a.add && a.path != "groups" {
+			c.warn(a.line, "%s '%s': += on %s treated as =", obj.typ, obj.name, a.path)
+		}
+		switch {
+		case typ == "contact" && a.path == "states":
+			states = identList(a.value)
+		case typ == "contact" && a.path == "types":
+			types = identList(a.value)
+		case typ == "timeperiod" && a.path == "ranges":
+			c.convertRanges(obj, def, a)
+		default:
+			c.convertAttr(obj, def, a)
+		}
+	}
+	if typ == "contact" {
+		def.set("host_notification_options", notificationOptions(states, types, hostStateOptions))
+		def.set("service_notification_options", notificationOptions(states, types, serviceStateOptions))
+	}
+
+	if obj.kind == "template" {
+		def.set("register", "0")
+	} else if typ != "service" && typ != "contact" {
+		if _, ok := def.get("alias"); !ok {
+			def.set("alias", obj.name)
+		}
+	}
+	if obj.kind != "template" && typ == "service" {
+		if _, ok := def.get("host_name"); !ok {
+			if _, ok := def.get("hostgroup_name"); !ok {
+				c.warn(obj.line, "Service '%s' has no host_name", obj.name)
+			}
+		}
+	}
+	return def
+}
+
+func (d *definition) get(key string) (string, bool) {
+	for _, f := range d.fields {
+		if f[0] == key {
+			return f[1], true
+		}
+	}
+	return "", false
+}
+
+func nameField(typ string) string {
+	if typ == "service" {
+		return "service_description"
+	}
+	return typ + "_name"
+}
+
+// attrMap maps Icinga2 attributes to Nagios directives. Durations are
+// converted to minutes, the default interval_length.
+var attrMap = map[string]map[string]string{
+	"common": {
+		"display_name":          "alias",
+		"check_command":         "check_command",
+		"max_check_attempts":    "max_check_attempts",
+		"check_period":          "check_period",
+		"check_interval":        "check_interval",
+		"retry_interval":        "retry_interval",
+		"enable_active_checks":  "active_checks_enabled",
+		"enable_passive_checks": "passive_checks_enabled",
+		"enable_notifications":  "notifications_enabled",
+		"enable_flapping":       "flap_detection_enabled",
+		"enable_perfdata":       "process_perf_data",
+		"enable_event_handler":  "event_handler_enabled",
+		"event_command":         "event_handler",
+		"notes":                 "notes",
+		"notes_url":             "notes_url",
+		"action_url":            "action_url",
+		"icon_image":            "icon_image",
+		"icon_image_alt":        "icon_image_alt",
+	},
+	"host": {
+		"address":  "address",
+		"address6": "address6",
+		"groups":   "hostgroups",
+	},
+	"service": {
+		"display_name": "display_name",
+		"host_name":    "host_name",
+		"groups":       "servicegroups",
+		"volatile":     "is_volatile",
+	},
+	"contact": {
+		"display_name":         "alias",
+		"email":                "email",
+		"pager":                "pager",
+		"groups":               "contactgroups",
+		"period":               "host_notification_period,service_notification_period",
+		"enable_notifications": "host_notifications_enabled,service_notifications_enabled",
+	},
+	"timeperiod": {
+		"display_name": "alias",
+		"excludes":     "exclude",
+	},
+	"hostgroup":    {"display_name": "alias"},
+	"servicegroup": {"display_name": "alias"},
+	"contactgroup": {"display_name": "alias"},
+}
+
+func (c *converter) convertAttr(obj *object, def *definition, a attr) {
+	target, ok := attrMap[def.typ][a.path]
+	if !ok && (def.typ == "host" || def.typ == "service") {
+		target, ok = attrMap["common"][a.path]
+	}
+	if !ok {
+		c.warn(a.line, "%s '%s': attribute %s not supported", obj.typ, obj.name, a.path)
+		return
+	}
+	value, ok := formatValue(a.value, strings.HasSuffix(a.path, "_interval"))
+	if !ok {
+		c.warn(a.line, "%s '%s': %s value cannot be converted", obj.typ, obj.name, a.path)
+		return
+	}
+	for _, key := range strings.Split(target, ",") {
+		if a.add {
+			if prev, ok := def.get(key); ok && prev != "" {
+				value = prev + "," + value
+			}
+		}
+		def.set(key, value)
+	}
+}
+
+// formatValue renders a literal as a Nagios directive value. Intervals
+// given as plain numbers are seconds in Icinga2.
+func formatValue(v interface{}, interval bool) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case ident:
+		return string(v), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	case float64:
+		if interval {
+			return formatFloat(v / 60), true
+		}
+		return formatFloat(v), true
+	case duration:
+		if interval {
+			return formatFloat(float64(v) / 60), true
+		}
+		return formatFloat(float64(v)), true
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, e := range v {
+			s, ok := formatValue(e, false)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), true
+	}
+	return "", false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// convertVars turns custom variables into Nagios _VARNAME directives.
+// Only scalar values have a Nagios equivalent.
+func (c *converter) convertVars(obj *object, def *definition, a attr) {
+	if a.path == "vars" {
+		d, ok := a.value.(dict)
+		if !ok {
+			c.warn(a.line, "%s '%s': vars is not a dictionary", obj.typ, obj.name)
+			return
+		}
+		for _, e := range d {
+			c.convertVars(obj, def, attr{path: "vars." + e.key, value: e.value, line: a.line})
+		}
+		return
+	}
+	name := strings.TrimPrefix(a.path, "vars.")
+	value, ok := formatValue(a.value, false)
+	if _, isList := a.value.([]interface{}); !ok || isList || strings.Contains(name, ".") {
+		c.warn(a.line, "%s '%s': custom variable %s is not a scalar", obj.typ, obj.name, name)
+		return
+	}
+	def.set("_"+strings.ToUpper(name), value)
+}
+
+func (c *converter) convertRanges(obj *object, def *definition, a attr) {
+	d, ok := a.value.(dict)
+	if !ok {
+		c.warn(a.line, "TimePeriod '%s': ranges is not a dictionary", obj.name)
+		return
+	}
+	for _, e := range d {
+		s, ok := e.value.(string)
+		if !ok {
+			c.warn(a.line, "TimePeriod '%s': range %s is not a string", obj.name, e.key)
+			continue
+		}
+		def.set(e.key, s)
+	}
+}
+
+// assignTargets extracts host groups and host names from assign where
+// clauses of the forms `"group" in host.groups` and `host.name == "name"`,
+// joined with ||. Multiple assign where clauses are alternatives too.
+func (c *converter) assignTargets(obj *object) (groups, hosts []string, ok bool) {
+	if len(obj.assign) == 0 {
+		c.warn(obj.line, "skipped apply Service '%s': no assign where", obj.name)
+		return nil, nil, false
+	}
+	for _, expr := range obj.assign {
+		for _, term := range splitOr(expr) {
+			switch {
+			case matchTokens(term, tString, "", tIdent, "in", tIdent, "host", tPunct, ".", tIdent, "groups"):
+				groups = appendUnique(groups, term[0].text)
+			case matchTokens(term, tIdent, "host", tPunct, ".", tIdent, "name", tPunct, "==", tString, ""):
+				hosts = appendUnique(hosts, term[4].text)
+			case matchTokens(term, tString, "", tPunct, "==", tIdent, "host", tPunct, ".", tIdent, "name"):
+				hosts = appendUnique(hosts, term[0].text)
+			default:
+				c.warn(obj.line, "skipped apply Service '%s': assign where %s is not a host group or host name match",
+					obj.name, tokenText(expr))
+				return nil, nil, false
+			}
+		}
+	}
+	return groups, hosts, true
+}
+
+// splitOr splits an expression at top-level || operators.
+func splitOr(expr []token) [][]token {
+	var terms [][]token
+	depth, start := 0, 0
+	for i, t := range expr {
+		switch t.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case "||":
+			if depth == 0 {
+				terms = append(terms, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, expr[start:])
+}
+
+// matchTokens reports whether toks matches the kind/text pairs in pattern.
+// An empty text matches any token of that kind.
+func matchTokens(toks []token, pattern ...interface{}) bool {
+	if len(toks)*2 != len(pattern) {
+		return false
+	}
+	for i, t := range toks {
+		kind, text := pattern[2*i].(tokKind), pattern[2*i+1].(string)
+		if t.kind != kind || (text != "" && t.text != text) {
+			return false
+		}
+	}
+	return true
+}
+
+func tokenText(toks []token) string {
+	parts := make([]string, len(toks))
+	for i, t := range toks {
+		if t.kind == tString {
+			parts[i] = strconv.Quote(t.text)
+		} else {
+			parts[i] = t.text
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func identList(v interface{}) []string {
+	arr, _ := v.([]interface{})
+	var out []string
+	for _, e := range arr {
+		if id, ok := e.(ident); ok {
+			out = append(out, string(id))
+		}
+	}
+	return out
+}
+
+var hostStateOptions = map[string]string{"Down": "d"}
+
+var serviceStateOptions = map[string]string{"Warning": "w", "Critical": "c", "Unknown": "u"}
+
+// notificationOptions maps Icinga2 User states and types filters to Nagios
+// notification options. A missing filter means everything, as in Icinga2.
+func notificationOptions(states, types []string, stateOpts map[string]string) string {
+	var opts []string
+	if states == nil {
+		for _, o := range stateOpts {
+			opts = append(opts, o)
+		}
+		sort.Strings(opts)
+	} else {
+		for _, s := range states {
+			if o, ok := stateOpts[s]; ok {
+				opts = append(opts, o)
+			}
+		}
+	}
+	if len(opts) == 0 {
+		return "n"
+	}
+	has := func(prefix string) bool {
+		if types == nil {
+			return true
+		}
+		for _, t := range types {
+			if strings.HasPrefix(t, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	if !has("Problem") {
+		// Only recovery, flapping and downtime notifications.
+		opts = opts[:0]
+	}
+	if has("Recovery") {
+		opts = append(opts, "r")
+	}
+	if has("Flapping") {
+		opts = append(opts, "f")
+	}
+	if has("Downtime") {
+		opts = append(opts, "s")
+	}
+	if len(opts) == 0 {
+		return "n"
+	}
+	return strings.Join(opts, ",")
+}
+
+func writeDefinition(b *strings.Builder, def *definition) {
+	width := 0
+	for _, f := range def.fields {
+		if len(f[0]) > width {
+			width = len(f[0])
+		}
+	}
+	fmt.Fprintf(b, "define %s {\n", def.typ)
+	for _, f := range def.fields {
+		fmt.Fprintf(b, "    %-*s  %s\n", width, f[0], f[1])
+	}
+	b.WriteString("}\n\n")
+}
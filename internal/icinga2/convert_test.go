@@ -0,0 +1,181 @@
+package icinga2
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/config"
+)
+
+const sample = `/* Icinga2 sample */
+const PluginDir = "/usr/lib/nagios/plugins"
+
+template Host "generic-host" {
+  max_check_attempts = 3
+  check_interval = 1m
+  retry_interval = 30s
+  check_command = "check-host-alive"
+}
+
+object Host "web01" {
+  import "generic-host"
+  import "linux-host"
+  display_name = "Web Server 01"
+  address = "10.0.0.1"
+  groups = [ "linux-servers" ]
+  vars.os = "Linux"
+  vars.disks["disk /"] = { disk_partitions = "/" }
+  notes = "rack " + "12"
+}
+
+object HostGroup "linux-servers" {
+  display_name = "Linux Servers"
+}
+
+apply Service "ssh" {
+  check_command = "check_ssh"
+  check_interval = 300
+  assign where "linux-servers" in host.groups || host.name == "bastion"
+}
+
+apply Service "http" {
+  check_command = "check_http"
+  assign where host.vars.os == "Linux"
+}
+
+apply Service "disk" for (disk => config in host.vars.disks) {
+  check_command = "check_disk"
+}
+
+object Service "ping" {
+  host_name = "web01"; check_command = "check_ping"
+  enable_notifications = false
+}
+
+object User "alice" {
+  display_name = "Alice"
+  email = "alice@example.com"
+  groups = [ "admins" ]
+  period = "workhours"
+  states = [ OK, Warning, Critical, Down ]
+  types = [ Problem, Recovery ]
+}
+
+object UserGroup "admins" {
+}
+
+object TimePeriod "workhours" {
+  display_name = "Work hours"
+  ranges = {
+    "monday" = "09:00-17:00"
+    "friday" = "09:00-15:00"
+  }
+}
+
+object CheckCommand "check_ssh" {
+  command = [ PluginDir + "/check_ssh", "$address$" ]
+}
+`
+
+func convertSample(t *testing.T) *Result {
+	t.Helper()
+	res, err := Convert("sample.conf", sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// parseDefs parses the converted output with the gogios object parser.
+func parseDefs(t *testing.T, cfg string) map[string]*config.TemplateObject {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "converted.cfg")
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := config.NewObjectParser()
+	if err := p.ParseFile(path); err != nil {
+		t.Fatalf("converted config does not parse: %v\n%s", err, cfg)
+	}
+	defs := make(map[string]*config.TemplateObject)
+	for _, obj := range p.Objects {
+		name := obj.Type + ":"
+		for _, key := range []string{"name", obj.Type + "_name", "service_description"} {
+			if v, ok := obj.Get(key); ok {
+				name += v
+				break
+			}
+		}
+		defs[name] = obj
+	}
+	return defs
+}
+
+func TestConvert_Objects(t *testing.T) {
+	res := convertSample(t)
+	defs := parseDefs(t, res.Config)
+
+	want := map[string]map[string]string{
+		"host:generic-host": {"register": "0", "check_interval": "1", "retry_interval": "0.5"},
+		"host:web01": {"use": "linux-host,generic-host", "alias": "Web Server 01", "address": "10.0.0.1",
+			"hostgroups": "linux-servers"},
+		"hostgroup:linux-servers": {"alias": "Linux Servers"},
+		"service:ssh":             {"hostgroup_name": "linux-servers", "host_name": "bastion", "check_interval": "5"},
+		"service:ping":            {"host_name": "web01", "check_command": "check_ping", "notifications_enabled": "0"},
+		"contact:alice": {"alias": "Alice", "contactgroups": "admins", "host_notification_period": "workhours",
+			"service_notification_options": "w,c,r", "host_notification_options": "d,r"},
+		"contactgroup:admins":  {"alias": "admins"},
+		"timeperiod:workhours": {"alias": "Work hours", "monday": "09:00-17:00", "friday": "09:00-15:00"},
+	}
+	for name, fields := range want {
+		obj := defs[name]
+		if obj == nil {
+			t.Errorf("missing %s in output:\n%s", name, res.Config)
+			continue
+		}
+		for k, v := range fields {
+			if got, _ := obj.Get(k); got != v {
+				t.Errorf("%s: %s = %q, want %q", name, k, got, v)
+			}
+		}
+	}
+	if got := defs["host:web01"].CustomVars["OS"]; got != "Linux" {
+		t.Errorf("custom variable _OS = %q, want Linux", got)
+	}
+	if _, ok := defs["host:web01"].Get("notes"); ok {
+		t.Error("non-literal notes should not be converted")
+	}
+	for _, skipped := range []string{"service:http", "service:disk"} {
+		if defs[skipped] != nil {
+			t.Errorf("%s should have been skipped", skipped)
+		}
+	}
+	if res.Counts["service"] != 2 || res.Counts["host"] != 2 {
+		t.Errorf("unexpected counts %v", res.Counts)
+	}
+}
+
+func TestConvert_Warnings(t *testing.T) {
+	res := convertSample(t)
+	all := strings.Join(res.Warnings, "\n")
+	for _, want := range []string{
+		"sample.conf:2: skipped unsupported statement starting with 'const'",
+		"custom variable disks.disk / is not a scalar",
+		"skipped notes, value is not a literal",
+		`assign where host . vars . os == "Linux" is not a host group or host name match`,
+		"apply for is not supported",
+		"skipped object CheckCommand 'check_ssh'",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing warning %q in:\n%s", want, all)
+		}
+	}
+}
+
+func TestConvert_LexErrors(t *testing.T) {
+	if _, err := Convert("bad.conf", `object Host "web01 {`); err == nil {
+		t.Error("expected error for unterminated string")
+	}
+}
@@ -0,0 +1,425 @@
+package icinga2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tNewline
+	tIdent
+	tString
+	tNumber
+	tPunct
+)
+
+type token struct {
+	kind tokKind
+	text string
+	line int
+}
+
+// lex splits Icinga2 DSL source into tokens. Semicolons are folded into
+// newline tokens since both end a statement.
+func lex(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n' || c == ';':
+			toks = append(toks, token{tNewline, "\n", line})
+			if c == '\n' {
+				line++
+			}
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "{{{"):
+			end := strings.Index(src[i+3:], "}}}")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated {{{ string", line)
+			}
+			text := src[i+3 : i+3+end]
+			toks = append(toks, token{tString, text, line})
+			line += strings.Count(text, "\n")
+			i += end + 6
+		case c == '"':
+			var b strings.Builder
+			start := line
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\n' {
+					line++
+				}
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+					switch src[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(src[j])
+					}
+					continue
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			toks = append(toks, token{tString, b.String(), start})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (isIdentByte(src[j]) || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tNumber, src[i:j], line})
+			i = j
+		case isIdentByte(c):
+			j := i
+			for j < len(src) && isIdentByte(src[j]) {
+				j++
+			}
+			toks = append(toks, token{tIdent, src[i:j], line})
+			i = j
+		default:
+			n := 1
+			if i+1 < len(src) {
+				switch src[i : i+2] {
+				case "==", "!=", "&&", "||", "+=", "-=", "<=", ">=":
+					n = 2
+				}
+			}
+			toks = append(toks, token{tPunct, src[i : i+n], line})
+			i += n
+		}
+	}
+	return append(toks, token{tEOF, "", line}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// object is one object, template or apply rule.
+type object struct {
+	kind    string // "object", "template" or "apply"
+	typ     string
+	name    string
+	line    int
+	imports []string
+	attrs   []attr
+	assign  [][]token
+	ignore  [][]token
+	applyTo string
+	loop    bool // apply ... for (...)
+}
+
+type attr struct {
+	path  string // "address", "vars.os"
+	add   bool   // += instead of =
+	value interface{}
+	line  int
+}
+
+// Values are string, float64, duration, bool, ident, nil, []interface{} or
+// dict.
+type duration float64 // seconds
+
+type ident string
+
+type dict []dictEntry
+
+type dictEntry struct {
+	key   string
+	value interface{}
+}
+
+type parser struct {
+	toks []token
+	pos  int
+	objs []*object
+	warn func(line int, format string, args ...interface{})
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(kind tokKind, text string) bool {
+	t := p.peek()
+	return t.kind == kind && t.text == text
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tNewline {
+		p.pos++
+	}
+}
+
+// skipStatement discards tokens up to the end of the current statement: a
+// newline outside brackets, or an unmatched closing brace, which is left
+// for the enclosing body.
+func (p *parser) skipStatement() {
+	depth := 0
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tEOF:
+			return
+		case t.kind == tNewline && depth == 0:
+			return
+		case t.kind == tPunct && (t.text == "{" || t.text == "[" || t.text == "("):
+			depth++
+		case t.kind == tPunct && (t.text == "}" || t.text == "]" || t.text == ")"):
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) parseFile() {
+	for {
+		p.skipNewlines()
+		t := p.peek()
+		if t.kind == tEOF {
+			return
+		}
+		if t.kind == tIdent && (t.text == "object" || t.text == "template" || t.text == "apply") {
+			if obj := p.parseObject(); obj != nil {
+				p.objs = append(p.objs, obj)
+			}
+			continue
+		}
+		p.warn(t.line, "skipped unsupported statement starting with '%s'", t.text)
+		p.next()
+		p.skipStatement()
+	}
+}
+
+func (p *parser) parseObject() *object {
+	kw := p.next()
+	obj := &object{kind: kw.text, line: kw.line}
+	typ, name := p.next(), p.next()
+	if typ.kind != tIdent || name.kind != tString {
+		p.warn(kw.line, "expected %s <Type> \"<name>\"", kw.text)
+		p.skipStatement()
+		return nil
+	}
+	obj.typ, obj.name = typ.text, name.text
+	if nagiosType(obj.typ) == "" {
+		// The whole object is reported as skipped; its contents are not.
+		warn := p.warn
+		p.warn = func(int, string, ...interface{}) {}
+		defer func() { p.warn = warn }()
+	}
+	for !p.is(tPunct, "{") {
+		t := p.next()
+		switch {
+		case t.kind == tIdent && t.text == "to" && obj.kind == "apply":
+			obj.applyTo = p.next().text
+		case t.kind == tIdent && t.text == "for" && obj.kind == "apply":
+			obj.loop = true
+			for depth := 0; ; {
+				t := p.next()
+				if t.kind == tEOF {
+					return obj
+				}
+				if t.text == "(" {
+					depth++
+				} else if t.text == ")" {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+		case t.kind == tNewline || t.kind == tEOF:
+			// Body-less object.
+			return obj
+		}
+	}
+	p.parseBody(obj)
+	return obj
+}
+
+func (p *parser) parseBody(obj *object) {
+	p.next() // {
+	for {
+		p.skipNewlines()
+		t := p.peek()
+		switch {
+		case t.kind == tEOF:
+			p.warn(obj.line, "%s %s '%s' is not closed", obj.kind, obj.typ, obj.name)
+			return
+		case t.kind == tPunct && t.text == "}":
+			p.next()
+			return
+		case t.kind == tIdent && t.text == "import":
+			p.next()
+			if s := p.next(); s.kind == tString {
+				obj.imports = append(obj.imports, s.text)
+			}
+			p.skipStatement()
+		case t.kind == tIdent && (t.text == "assign" || t.text == "ignore") && p.toks[p.pos+1].text == "where":
+			p.pos += 2
+			start := p.pos
+			p.skipStatement()
+			expr := p.toks[start:p.pos]
+			if t.text == "assign" {
+				obj.assign = append(obj.assign, expr)
+			} else {
+				obj.ignore = append(obj.ignore, expr)
+			}
+		case t.kind == tIdent:
+			p.parseAttr(obj)
+		default:
+			p.warn(t.line, "%s '%s': skipped unsupported statement", obj.typ, obj.name)
+			p.next()
+			p.skipStatement()
+		}
+	}
+}
+
+func (p *parser) parseAttr(obj *object) {
+	start := p.pos
+	line := p.peek().line
+	path := p.next().text
+	for {
+		if p.is(tPunct, ".") {
+			p.next()
+			path += "." + p.next().text
+		} else if p.is(tPunct, "[") && p.toks[p.pos+1].kind == tString && p.toks[p.pos+2].text == "]" {
+			path += "." + p.toks[p.pos+1].text
+			p.pos += 3
+		} else {
+			break
+		}
+	}
+	op := p.next()
+	if op.kind == tPunct && (op.text == "=" || op.text == "+=") {
+		vstart := p.pos
+		if v, ok := p.parseValue(); ok {
+			if end := p.peek(); end.kind == tNewline || end.kind == tEOF || end.text == "}" {
+				obj.attrs = append(obj.attrs, attr{path: path, add: op.text == "+=", value: v, line: line})
+				return
+			}
+		}
+		p.pos = vstart
+		p.warn(line, "%s '%s': skipped %s, value is not a literal", obj.typ, obj.name, path)
+		p.skipStatement()
+		return
+	}
+	p.pos = start
+	p.warn(line, "%s '%s': skipped unsupported statement", obj.typ, obj.name)
+	p.skipStatement()
+}
+
+func (p *parser) parseValue() (interface{}, bool) {
+	t := p.next()
+	switch t.kind {
+	case tString:
+		return t.text, true
+	case tNumber:
+		return parseNumber(t.text)
+	case tIdent:
+		switch t.text {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		case "null":
+			return nil, true
+		}
+		if p.is(tPunct, ".") || p.is(tPunct, "(") {
+			return nil, false
+		}
+		return ident(t.text), true
+	case tPunct:
+		switch t.text {
+		case "[":
+			var arr []interface{}
+			for {
+				p.skipNewlines()
+				if p.is(tPunct, "]") {
+					p.next()
+					return arr, true
+				}
+				v, ok := p.parseValue()
+				if !ok {
+					return nil, false
+				}
+				arr = append(arr, v)
+				p.skipNewlines()
+				if p.is(tPunct, ",") {
+					p.next()
+				}
+			}
+		case "{":
+			var d dict
+			for {
+				p.skipNewlines()
+				if p.is(tPunct, "}") {
+					p.next()
+					return d, true
+				}
+				k := p.next()
+				if (k.kind != tString && k.kind != tIdent) || !p.is(tPunct, "=") {
+					return nil, false
+				}
+				p.next()
+				v, ok := p.parseValue()
+				if !ok {
+					return nil, false
+				}
+				d = append(d, dictEntry{k.text, v})
+				if p.is(tPunct, ",") {
+					p.next()
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseNumber parses a number with an optional duration suffix.
+func parseNumber(s string) (interface{}, bool) {
+	units := []struct {
+		suffix string
+		secs   float64
+	}{{"ms", 0.001}, {"s", 1}, {"m", 60}, {"h", 3600}, {"d", 86400}}
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 64)
+			return duration(v * u.secs), err == nil
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
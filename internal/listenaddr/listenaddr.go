@@ -0,0 +1,62 @@
+// Package listenaddr parses and binds the TCP listen addresses of gogios's
+// network listeners, such as livestatus_tcp and nrdp_listen. A directive may
+// name several addresses separated by commas, each in host:port form with
+// IPv6 literals in brackets:
+//
+//	livestatus_tcp=127.0.0.1:6557,[::1]:6557
+//
+// A bare ":port" binds every IPv4 and IPv6 address (dual-stack).
+package listenaddr
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Split returns the addresses in a comma-separated directive value,
+// dropping empty entries.
+func Split(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Validate checks that every address in s is a host:port pair. An
+// unbracketed IPv6 literal gets an error saying how to write it.
+func Validate(s string) error {
+	for _, addr := range Split(s) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			if strings.Count(addr, ":") > 1 && !strings.HasPrefix(addr, "[") {
+				return fmt.Errorf("address '%s': IPv6 addresses must be in brackets, e.g. [::1]:6557", addr)
+			}
+			return fmt.Errorf("address '%s': %w", addr, err)
+		}
+		if port == "" {
+			return fmt.Errorf("address '%s': missing port", addr)
+		}
+	}
+	return nil
+}
+
+// Listen binds every address in s. If any bind fails, those already made
+// are closed and the error names the address.
+func Listen(s string) ([]net.Listener, error) {
+	var lns []net.Listener
+	for _, addr := range Split(s) {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range lns {
+				l.Close()
+			}
+			return nil, fmt.Errorf("tcp listen %s: %w", addr, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
@@ -0,0 +1,57 @@
+package listenaddr
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, s := range []string{":6557", "127.0.0.1:6557", "[::1]:6557", "127.0.0.1:6557, [::]:6557", "[fe80::1%eth0]:6557"} {
+		if err := Validate(s); err != nil {
+			t.Errorf("Validate(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"127.0.0.1", "::1:6557", "[::1]", "127.0.0.1:"} {
+		if err := Validate(s); err == nil {
+			t.Errorf("Validate(%q): expected an error", s)
+		}
+	}
+	if err := Validate("::1:6557"); err == nil || !strings.Contains(err.Error(), "brackets") {
+		t.Errorf("expected a hint about brackets, got %v", err)
+	}
+}
+
+func TestListen(t *testing.T) {
+	addrs := "127.0.0.1:0"
+	if ln, err := net.Listen("tcp", "[::1]:0"); err == nil {
+		ln.Close()
+		addrs += ",[::1]:0"
+	}
+	lns, err := Listen(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}()
+	if len(lns) != len(Split(addrs)) {
+		t.Fatalf("expected %d listeners, got %d", len(Split(addrs)), len(lns))
+	}
+	for _, ln := range lns {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Errorf("dial %s: %v", ln.Addr(), err)
+			continue
+		}
+		conn.Close()
+	}
+
+	// A failed bind releases the ones already made.
+	taken := lns[0].Addr().String()
+	if _, err := Listen("127.0.0.1:0," + taken); err == nil || !strings.Contains(err.Error(), taken) {
+		t.Errorf("expected an error naming %s, got %v", taken, err)
+	}
+}
@@ -0,0 +1,282 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AlertEvent is the structured form of a HOST/SERVICE ALERT or NOTIFICATION
+// log line, as sent by a Forwarder.
+type AlertEvent struct {
+	Type               string `json:"type"` // host_alert, service_alert, host_notification, service_notification
+	Timestamp          int64  `json:"timestamp"`
+	HostName           string `json:"host_name"`
+	ServiceDescription string `json:"service_description,omitempty"`
+	State              string `json:"state,omitempty"`
+	StateType          string `json:"state_type,omitempty"`
+	Attempt            int    `json:"attempt,omitempty"`
+	ContactName        string `json:"contact_name,omitempty"`
+	NotificationType   string `json:"notification_type,omitempty"`
+	Command            string `json:"command,omitempty"`
+	Output             string `json:"output"`
+}
+
+// Forwarder sends structured copies of alert and notification log lines to
+// a syslog-ng or Fluentd endpoint over UDP or TCP, independently of the
+// main log. Forwarding never blocks logging: events are queued and sent
+// from a background goroutine, and dropped and counted when the queue is
+// full.
+type Forwarder struct {
+	network  string // "udp" or "tcp"
+	addr     string
+	format   string // "json" or "syslog"
+	hostname string
+	queue    chan []byte
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	dropped  atomic.Uint64
+	sent     atomic.Uint64
+	logf     func(format string, args ...interface{})
+	dial     func(network, addr string) (net.Conn, error)
+}
+
+// NewForwarder validates target, a udp://host:port or tcp://host:port URL,
+// and format, "json" (one JSON object per line) or "syslog" (RFC 5424 with
+// the JSON object as the message). bufSize events are queued at most. Call
+// Start to begin sending.
+func NewForwarder(target, format string, bufSize int) (*Forwarder, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" || u.Port() == "" {
+		return nil, fmt.Errorf("alert forwarder: invalid target %q (expected udp://host:port or tcp://host:port)", target)
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("alert forwarder: unsupported protocol %q (use udp or tcp)", u.Scheme)
+	}
+	if format == "" {
+		format = "syslog"
+	}
+	if format != "json" && format != "syslog" {
+		return nil, fmt.Errorf("alert forwarder: unknown format %q (use json or syslog)", format)
+	}
+	if bufSize <= 0 {
+		bufSize = 10000
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	return &Forwarder{
+		network:  u.Scheme,
+		addr:     u.Host,
+		format:   format,
+		hostname: hostname,
+		queue:    make(chan []byte, bufSize),
+		stop:     make(chan struct{}),
+		dial:     net.Dial,
+	}, nil
+}
+
+// SetLogger sets the function send failures are logged with.
+func (f *Forwarder) SetLogger(fn func(format string, args ...interface{})) {
+	f.logf = fn
+}
+
+func (f *Forwarder) log(format string, args ...interface{}) {
+	if f.logf != nil {
+		f.logf(format, args...)
+	}
+}
+
+// Start launches the sending goroutine.
+func (f *Forwarder) Start() {
+	f.wg.Add(1)
+	go f.run()
+}
+
+// Stop sends what is still queued, giving up after a few seconds, and closes
+// the connection.
+func (f *Forwarder) Stop() {
+	close(f.stop)
+	f.wg.Wait()
+}
+
+// Dropped returns the number of events discarded because the queue was full.
+func (f *Forwarder) Dropped() uint64 { return f.dropped.Load() }
+
+// Sent returns the number of events sent.
+func (f *Forwarder) Sent() uint64 { return f.sent.Load() }
+
+// Forward queues a structured copy of msg, logged at ts, if it is an alert
+// or notification line.
+func (f *Forwarder) Forward(ts time.Time, msg string) {
+	ev := ParseAlert(msg)
+	if ev == nil {
+		return
+	}
+	ev.Timestamp = ts.Unix()
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	if f.format == "syslog" {
+		payload = f.syslogFrame(ts, ev, payload)
+	}
+	payload = append(payload, '\n')
+	select {
+	case f.queue <- payload:
+	default:
+		f.dropped.Add(1)
+	}
+}
+
+// syslogFrame wraps payload in an RFC 5424 header with facility user and a
+// severity derived from the event's state.
+func (f *Forwarder) syslogFrame(ts time.Time, ev *AlertEvent, payload []byte) []byte {
+	severity := 6 // info
+	switch ev.State {
+	case "WARNING", "UNKNOWN":
+		severity = 4
+	case "CRITICAL", "DOWN", "UNREACHABLE":
+		severity = 3
+	}
+	if ev.ContactName != "" {
+		severity = 5 // notice
+	}
+	header := fmt.Sprintf("<%d>1 %s %s gogios %d %s - ", 8+severity,
+		ts.UTC().Format(time.RFC3339), f.hostname, os.Getpid(), ev.Type)
+	return append([]byte(header), payload...)
+}
+
+// ParseAlert returns the structured form of a HOST/SERVICE ALERT or
+// NOTIFICATION log message (without the timestamp), or nil for any other
+// message.
+func ParseAlert(msg string) *AlertEvent {
+	kind, detail, ok := strings.Cut(msg, ": ")
+	if !ok {
+		return nil
+	}
+	switch kind {
+	case "SERVICE ALERT":
+		p := strings.SplitN(detail, ";", 6)
+		if len(p) < 6 {
+			return nil
+		}
+		attempt, _ := strconv.Atoi(p[4])
+		return &AlertEvent{Type: "service_alert", HostName: p[0], ServiceDescription: p[1],
+			State: p[2], StateType: p[3], Attempt: attempt, Output: p[5]}
+	case "HOST ALERT":
+		p := strings.SplitN(detail, ";", 5)
+		if len(p) < 5 {
+			return nil
+		}
+		attempt, _ := strconv.Atoi(p[3])
+		return &AlertEvent{Type: "host_alert", HostName: p[0],
+			State: p[1], StateType: p[2], Attempt: attempt, Output: p[4]}
+	case "SERVICE NOTIFICATION":
+		p := strings.SplitN(detail, ";", 6)
+		if len(p) < 6 {
+			return nil
+		}
+		return &AlertEvent{Type: "service_notification", ContactName: p[0], HostName: p[1],
+			ServiceDescription: p[2], NotificationType: p[3], State: notificationState(p[3]),
+			Command: p[4], Output: p[5]}
+	case "HOST NOTIFICATION":
+		p := strings.SplitN(detail, ";", 5)
+		if len(p) < 5 {
+			return nil
+		}
+		return &AlertEvent{Type: "host_notification", ContactName: p[0], HostName: p[1],
+			NotificationType: p[2], State: notificationState(p[2]), Command: p[3], Output: p[4]}
+	}
+	return nil
+}
+
+// notificationState extracts the state from a notification type such as
+// "CRITICAL" or "ACKNOWLEDGEMENT (CRITICAL)".
+func notificationState(typ string) string {
+	if i := strings.IndexByte(typ, '('); i >= 0 && strings.HasSuffix(typ, ")") {
+		return typ[i+1 : len(typ)-1]
+	}
+	if strings.Contains(typ, " ") {
+		return ""
+	}
+	return typ
+}
+
+// run sends queued events, reconnecting with backoff on failure. An event
+// that fails to send is kept and retried after reconnecting.
+func (f *Forwarder) run() {
+	defer f.wg.Done()
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	backoff := time.Second
+	var pending []byte
+	var deadline <-chan time.Time // set once stopping
+
+	for {
+		select {
+		case <-deadline:
+			return
+		default:
+		}
+		if pending == nil {
+			select {
+			case pending = <-f.queue:
+			default:
+				if deadline != nil {
+					return // drained
+				}
+				select {
+				case pending = <-f.queue:
+				case <-f.stop:
+					deadline = time.After(5 * time.Second)
+					continue
+				}
+			}
+		}
+
+		if conn == nil {
+			c, err := f.dial(f.network, f.addr)
+			if err != nil {
+				if deadline != nil {
+					return
+				}
+				f.log("Warning: alert forwarder connect to %s failed: %v (retrying in %s)", f.addr, err, backoff)
+				select {
+				case <-f.stop:
+					deadline = time.After(5 * time.Second)
+				case <-time.After(backoff):
+				}
+				if backoff < 30*time.Second {
+					backoff *= 2
+				}
+				continue
+			}
+			conn = c
+			backoff = time.Second
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if _, err := conn.Write(pending); err != nil {
+			f.log("Warning: alert forwarder send to %s failed: %v", f.addr, err)
+			conn.Close()
+			conn = nil
+			continue
+		}
+		f.sent.Add(1)
+		pending = nil
+	}
+}
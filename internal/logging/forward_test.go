@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestParseAlert(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want *AlertEvent
+	}{
+		{"SERVICE ALERT: web01;HTTP;CRITICAL;HARD;3;Connection refused; port 80",
+			&AlertEvent{Type: "service_alert", HostName: "web01", ServiceDescription: "HTTP", State: "CRITICAL",
+				StateType: "HARD", Attempt: 3, Output: "Connection refused; port 80"}},
+		{"HOST ALERT: web01;DOWN;SOFT;1;PING CRITICAL",
+			&AlertEvent{Type: "host_alert", HostName: "web01", State: "DOWN", StateType: "SOFT", Attempt: 1, Output: "PING CRITICAL"}},
+		{"SERVICE NOTIFICATION: admin;web01;HTTP;ACKNOWLEDGEMENT (CRITICAL);notify-email;down;jdoe;on it",
+			&AlertEvent{Type: "service_notification", ContactName: "admin", HostName: "web01", ServiceDescription: "HTTP",
+				NotificationType: "ACKNOWLEDGEMENT (CRITICAL)", State: "CRITICAL", Command: "notify-email", Output: "down;jdoe;on it"}},
+		{"HOST NOTIFICATION: admin;web01;DOWN;notify-host;PING CRITICAL",
+			&AlertEvent{Type: "host_notification", ContactName: "admin", HostName: "web01",
+				NotificationType: "DOWN", State: "DOWN", Command: "notify-host", Output: "PING CRITICAL"}},
+		{"HOST ALERT: web01;DOWN", nil},
+		{"EXTERNAL COMMAND: DISABLE_NOTIFICATIONS", nil},
+	}
+	for _, tt := range tests {
+		got := ParseAlert(tt.msg)
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("ParseAlert(%q) = %+v, want %+v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestNewForwarderErrors(t *testing.T) {
+	for _, target := range []string{"127.0.0.1:514", "http://127.0.0.1:514", "udp://127.0.0.1"} {
+		if _, err := NewForwarder(target, "json", 0); err == nil {
+			t.Errorf("NewForwarder(%q): expected an error", target)
+		}
+	}
+	if _, err := NewForwarder("udp://127.0.0.1:514", "gelf", 0); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestForwarderTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sc := bufio.NewScanner(conn)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+
+	f, err := NewForwarder("tcp://"+ln.Addr().String(), "syslog", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpDir := t.TempDir()
+	l, err := NewLogger(tmpDir+"/test.log", tmpDir, objects.LogRotationNone, false, &objects.GlobalState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetForwarder(f)
+	f.Start()
+
+	l.Log("Caught SIGHUP, restarting...")
+	l.LogServiceAlert("web01", "HTTP", objects.ServiceCritical, objects.StateTypeHard, 3, "Connection refused")
+	f.Stop() // sends what is queued before returning
+
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "<11>1 ") || !strings.Contains(line, " gogios ") {
+			t.Errorf("unexpected syslog header: %q", line)
+		}
+		var ev AlertEvent
+		if err := json.Unmarshal([]byte(line[strings.IndexByte(line, '{'):]), &ev); err != nil {
+			t.Fatalf("bad payload %q: %v", line, err)
+		}
+		if ev.Type != "service_alert" || ev.HostName != "web01" || ev.State != "CRITICAL" || ev.Timestamp == 0 {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+	if f.Sent() != 1 || f.Dropped() != 0 {
+		t.Errorf("sent %d, dropped %d; want 1, 0", f.Sent(), f.Dropped())
+	}
+}
+
+func TestForwarderUDPDropsWhenFull(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	f, err := NewForwarder("udp://"+pc.LocalAddr().String(), "json", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Forward(time.Unix(1700000000, 0), "HOST ALERT: web01;DOWN;HARD;3;PING CRITICAL")
+	f.Forward(time.Unix(1700000000, 0), "HOST ALERT: web02;DOWN;HARD;3;PING CRITICAL")
+	if f.Dropped() != 1 {
+		t.Errorf("dropped %d, want 1", f.Dropped())
+	}
+	f.Start()
+	buf := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Stop()
+	want := `{"type":"host_alert","timestamp":1700000000,"host_name":"web01","state":"DOWN","state_type":"HARD","attempt":3,"output":"PING CRITICAL"}` + "\n"
+	if string(buf[:n]) != want {
+		t.Errorf("got %q, want %q", buf[:n], want)
+	}
+}
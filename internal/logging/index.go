@@ -0,0 +1,280 @@
+package logging
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexMarkEvery is how many lines apart the index marks a file.
+const indexMarkEvery = 1024
+
+// LogIndex indexes the log file and the archives in the log archive path
+// by time, so a query for a time range reads only the files, and the parts
+// of files, that can hold it. An archive is indexed once. The current log
+// is indexed as it grows, and from the start again after it is rotated;
+// an archive that was the current log keeps its index.
+type LogIndex struct {
+	logFile     string
+	archivePath string
+
+	mu    sync.Mutex
+	files map[string]*indexedFile
+}
+
+// indexedFile is what the index knows about one file.
+type indexedFile struct {
+	info  os.FileInfo
+	size  int64     // bytes indexed, up to the end of the last full line
+	lines int       // lines indexed
+	first time.Time // earliest timestamp; zero when there are none
+	last  time.Time // latest timestamp
+	marks []indexMark
+}
+
+// indexMark is the offset of a line, its line number, and the latest
+// timestamp before it.
+type indexMark struct {
+	offset int64
+	lineno int
+	before time.Time
+}
+
+// NewLogIndex returns an index of logFile and the *.log archives in
+// archivePath. Files are indexed on first use.
+func NewLogIndex(logFile, archivePath string) *LogIndex {
+	return &LogIndex{
+		logFile:     logFile,
+		archivePath: archivePath,
+		files:       make(map[string]*indexedFile),
+	}
+}
+
+// Scan calls fn, oldest file first, for each timestamped line between
+// minTime and maxTime inclusive, with its 1-based line number in its file.
+// A zero minTime or maxTime leaves that end open. fn returns false to stop.
+func (x *LogIndex) Scan(minTime, maxTime time.Time, fn func(line string, lineno int) bool) error {
+	type span struct {
+		path string
+		from indexMark
+	}
+	x.mu.Lock()
+	paths, err := x.refresh()
+	var spans []span
+	for _, path := range paths {
+		f := x.files[path]
+		if f.first.IsZero() ||
+			(!minTime.IsZero() && f.last.Before(minTime)) ||
+			(!maxTime.IsZero() && f.first.After(maxTime)) {
+			continue
+		}
+		spans = append(spans, span{path, f.seek(minTime)})
+	}
+	x.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range spans {
+		more, err := scanFile(s.path, s.from, minTime, maxTime, fn)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// refresh brings the index up to date with the files on disk and returns
+// their paths, archives by first timestamp and then the current log.
+func (x *LogIndex) refresh() ([]string, error) {
+	var archives []string
+	if x.archivePath != "" {
+		matches, err := filepath.Glob(filepath.Join(x.archivePath, "*.log"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if m != x.logFile {
+				archives = append(archives, m)
+			}
+		}
+	}
+
+	current := x.files[x.logFile]
+	seen := make(map[string]bool)
+	var paths []string
+	for _, path := range append(archives, x.logFile) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		f := x.files[path]
+		if f == nil && path != x.logFile && current != nil && os.SameFile(current.info, fi) {
+			// The current log was rotated into the archive.
+			f = current
+		}
+		if f, err = indexFile(path, fi, f); err != nil {
+			return nil, err
+		}
+		x.files[path] = f
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	for path := range x.files {
+		if !seen[path] {
+			delete(x.files, path)
+		}
+	}
+
+	n := len(paths)
+	if n > 0 && paths[n-1] == x.logFile {
+		n--
+	}
+	sort.SliceStable(paths[:n], func(i, j int) bool {
+		return x.files[paths[i]].first.Before(x.files[paths[j]].first)
+	})
+	return paths, nil
+}
+
+// seek returns the last mark before which every line is older than
+// minTime.
+func (f *indexedFile) seek(minTime time.Time) indexMark {
+	i := sort.Search(len(f.marks), func(i int) bool {
+		return !f.marks[i].before.Before(minTime)
+	})
+	if i == 0 {
+		return indexMark{}
+	}
+	return f.marks[i-1]
+}
+
+// indexFile brings prev, the index of path so far or nil, up to date with
+// fi. A file that was replaced or shrank is indexed from the start.
+func indexFile(path string, fi os.FileInfo, prev *indexedFile) (*indexedFile, error) {
+	f := prev
+	if f == nil || !os.SameFile(f.info, fi) || fi.Size() < f.size {
+		f = &indexedFile{}
+	} else if fi.Size() == f.size {
+		f.info = fi
+		return f, nil
+	} else {
+		// Extend a copy, so an index handed to a rotated archive is not
+		// changed under it.
+		c := *f
+		c.marks = append([]indexMark(nil), f.marks...)
+		f = &c
+	}
+	f.info = fi
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(f.size, io.SeekStart); err != nil {
+		return nil, err
+	}
+	err = eachLine(file, func(line []byte, size int64) bool {
+		if f.lines%indexMarkEvery == 0 {
+			f.marks = append(f.marks, indexMark{offset: f.size, lineno: f.lines, before: f.last})
+		}
+		if ts, ok := lineTime(line); ok {
+			if f.first.IsZero() || ts.Before(f.first) {
+				f.first = ts
+			}
+			if ts.After(f.last) {
+				f.last = ts
+			}
+		}
+		f.size += size
+		f.lines++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// scanFile reads path from mark on, calling fn for the lines in range. It
+// reports whether fn wants more.
+func scanFile(path string, from indexMark, minTime, maxTime time.Time, fn func(string, int) bool) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return true, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(from.offset, io.SeekStart); err != nil {
+		return true, err
+	}
+	lineno := from.lineno
+	more := true
+	err = eachLine(file, func(line []byte, _ int64) bool {
+		lineno++
+		ts, ok := lineTime(line)
+		if !ok || (!minTime.IsZero() && ts.Before(minTime)) || (!maxTime.IsZero() && ts.After(maxTime)) {
+			return true
+		}
+		more = fn(strings.TrimRight(string(line), "\r\n"), lineno)
+		return more
+	})
+	return more, err
+}
+
+// eachLine calls fn with each full line of r and its size in bytes, until
+// fn returns false. A line too long to buffer is passed as nil. A partial
+// last line, one still being written, is left out.
+func eachLine(r io.Reader, fn func(line []byte, size int64) bool) error {
+	br := bufio.NewReaderSize(r, 256*1024)
+	for {
+		line, err := br.ReadSlice('\n')
+		size := int64(len(line))
+		if err == bufio.ErrBufferFull {
+			for err == bufio.ErrBufferFull {
+				line, err = br.ReadSlice('\n')
+				size += int64(len(line))
+			}
+			line = nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(line, size) {
+			return nil
+		}
+	}
+}
+
+// lineTime returns the timestamp of a "[1700000000] ..." log line.
+func lineTime(line []byte) (time.Time, bool) {
+	if len(line) < 3 || line[0] != '[' {
+		return time.Time{}, false
+	}
+	end := 1
+	for end < len(line) && line[end] != ']' {
+		end++
+	}
+	if end == len(line) {
+		return time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(string(line[1:end]), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLogLines(t *testing.T, path string, from, n int, tail string) {
+	t.Helper()
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "[%d] SERVICE ALERT: web01;HTTP;OK;HARD;1;line %d\n", from+i, i)
+	}
+	b.WriteString(tail)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(b.String()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogIndex(t *testing.T) {
+	dir := t.TempDir()
+	archives := filepath.Join(dir, "archives")
+	if err := os.Mkdir(archives, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// By name, the newer archive sorts first.
+	writeLogLines(t, filepath.Join(archives, "nagios-12-31-2023-00.log"), 1000, 3000, "")
+	writeLogLines(t, filepath.Join(archives, "nagios-01-01-2024-00.log"), 5000, 10, "")
+	logFile := filepath.Join(dir, "nagios.log")
+	writeLogLines(t, logFile, 6000, 5, "Warning: not a log line\n[6005] partial")
+
+	x := NewLogIndex(logFile, archives)
+	scan := func(min, max int64) (times []int64, linenos []int) {
+		t.Helper()
+		var minTime, maxTime time.Time
+		if min > 0 {
+			minTime = time.Unix(min, 0)
+		}
+		if max > 0 {
+			maxTime = time.Unix(max, 0)
+		}
+		err := x.Scan(minTime, maxTime, func(line string, lineno int) bool {
+			ts, _ := lineTime([]byte(line))
+			times = append(times, ts.Unix())
+			linenos = append(linenos, lineno)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return times, linenos
+	}
+
+	times, _ := scan(0, 0)
+	if len(times) != 3015 {
+		t.Fatalf("full scan: got %d lines, want 3015", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if times[i] < times[i-1] {
+			t.Fatalf("full scan out of order at %d: %d after %d", i, times[i], times[i-1])
+		}
+	}
+
+	times, linenos := scan(3500, 5005)
+	if len(times) != 506 || times[0] != 3500 || linenos[0] != 2501 || times[505] != 5005 {
+		t.Errorf("range scan: %d lines, first %v at line %v", len(times), times[:1], linenos[:1])
+	}
+
+	// The partial line is picked up once it is complete.
+	writeLogLines(t, logFile, 0, 0, "\n")
+	writeLogLines(t, logFile, 6006, 2, "")
+	if times, _ := scan(6000, 0); len(times) != 8 {
+		t.Errorf("after growth: got %d lines, want 8", len(times))
+	}
+
+	// Rotation: the current log moves into the archives and a new one
+	// starts.
+	if err := os.Rename(logFile, filepath.Join(archives, "nagios-01-02-2024-00.log")); err != nil {
+		t.Fatal(err)
+	}
+	writeLogLines(t, logFile, 7000, 1, "")
+	times, linenos = scan(6000, 0)
+	if len(times) != 9 || times[8] != 7000 || linenos[8] != 1 {
+		t.Errorf("after rotation: %v at lines %v", times, linenos)
+	}
+}
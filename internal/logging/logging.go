@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -37,6 +38,8 @@ type Logger struct {
 	global         *objects.GlobalState
 	Verbosity      int
 	OnSizeRotate   func() // called after size-triggered rotation (to reschedule timed event)
+	forwarder      *Forwarder
+	quietExtCmds   atomic.Bool // see QuietExternalCommands
 }
 
 // NewLogger creates a new Nagios logger.
@@ -93,6 +96,13 @@ func (l *Logger) SetStdout(enabled bool) {
 	l.mu.Unlock()
 }
 
+// QuietExternalCommands leaves "EXTERNAL COMMAND:" lines out of the log
+// while quiet is true. It is set while a batch of external commands is
+// applied, which is logged as one line instead.
+func (l *Logger) QuietExternalCommands(quiet bool) {
+	l.quietExtCmds.Store(quiet)
+}
+
 // SetMaxFileSize sets the maximum log file size in bytes. When exceeded,
 // the log is rotated automatically regardless of the time-based schedule.
 func (l *Logger) SetMaxFileSize(size uint64) {
@@ -101,10 +111,20 @@ func (l *Logger) SetMaxFileSize(size uint64) {
 	l.mu.Unlock()
 }
 
+// SetForwarder sends structured copies of alert and notification lines to
+// f. Set it before logging starts.
+func (l *Logger) SetForwarder(f *Forwarder) {
+	l.forwarder = f
+}
+
 // Log writes a timestamped message to the log file.
 func (l *Logger) Log(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	line := fmt.Sprintf("[%d] %s\n", time.Now().Unix(), msg)
+	if l.quietExtCmds.Load() && strings.HasPrefix(msg, "EXTERNAL COMMAND: ") {
+		return
+	}
+	now := time.Now()
+	line := fmt.Sprintf("[%d] %s\n", now.Unix(), msg)
 
 	l.mu.Lock()
 	if l.logFile != nil {
@@ -120,6 +140,9 @@ func (l *Logger) Log(format string, args ...interface{}) {
 	if l.useSyslog && l.syslogWriter != nil {
 		l.syslogWriter.Info(msg)
 	}
+	if l.forwarder != nil {
+		l.forwarder.Forward(now, msg)
+	}
 
 	if needsRotate {
 		l.Rotate()
@@ -155,6 +178,30 @@ func (l *Logger) LogHostAlert(hostName string, state, stateType, attempt int, ou
 		attempt, output)
 }
 
+// LogServiceStateCause logs the check result that caused a service's hard
+// state change, after its SERVICE ALERT (Gogios extension).
+func (l *Logger) LogServiceStateCause(hostName, svcDesc string, state int, cause objects.StateChangeCause) {
+	l.Log("SERVICE STATE CAUSE: %s;%s;%s;%s;%s;%d",
+		hostName, svcDesc,
+		objects.ServiceStateName(state),
+		checkTypeName(cause.CheckType), cause.Source, cause.Time.Unix())
+}
+
+// LogHostStateCause is LogServiceStateCause for hosts.
+func (l *Logger) LogHostStateCause(hostName string, state int, cause objects.StateChangeCause) {
+	l.Log("HOST STATE CAUSE: %s;%s;%s;%s;%d",
+		hostName,
+		objects.HostStateName(state),
+		checkTypeName(cause.CheckType), cause.Source, cause.Time.Unix())
+}
+
+func checkTypeName(checkType int) string {
+	if checkType == objects.CheckTypePassive {
+		return "PASSIVE"
+	}
+	return "ACTIVE"
+}
+
 // LogServiceNotification logs a service notification event.
 func (l *Logger) LogServiceNotification(contactName, hostName, svcDesc, notifType, cmdName, output, author, comment string) {
 	if l.global != nil && !l.global.LogNotifications {
@@ -99,6 +99,32 @@ func TestLogger_ConditionalLogging(t *testing.T) {
 	}
 }
 
+func TestLogger_QuietExternalCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := tmpDir + "/test.log"
+
+	l, err := NewLogger(logPath, tmpDir, objects.LogRotationNone, false, &objects.GlobalState{LogExternalCommands: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	l.QuietExternalCommands(true)
+	l.LogExternalCommand("DISABLE_HOST_CHECK", []string{"web01"})
+	l.Log("EXTERNAL COMMAND BATCH: 1 commands from command file (DISABLE_HOST_CHECK x1), 0 failed")
+	l.QuietExternalCommands(false)
+	l.LogExternalCommand("DISABLE_HOST_CHECK", []string{"web02"})
+
+	data, _ := os.ReadFile(logPath)
+	content := string(data)
+	if strings.Contains(content, "web01") {
+		t.Error("expected the command to be left out while quiet")
+	}
+	if !strings.Contains(content, "EXTERNAL COMMAND BATCH:") || !strings.Contains(content, "DISABLE_HOST_CHECK;web02") {
+		t.Errorf("expected the summary and the later command in log, got: %s", content)
+	}
+}
+
 func TestLogger_NextRotationTime(t *testing.T) {
 	logPath := "/dev/null"
 	gs := &objects.GlobalState{}
@@ -0,0 +1,107 @@
+package macros
+
+import (
+	"strings"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// hostGroupMacro resolves a $HOSTGROUP...$ macro against hg. Member lists
+// are built on demand since large groups make them expensive.
+func hostGroupMacro(name string, hg *objects.HostGroup) (string, bool) {
+	switch name {
+	case "HOSTGROUPNAME":
+		return hg.Name, true
+	case "HOSTGROUPALIAS":
+		return hg.Alias, true
+	case "HOSTGROUPMEMBERS":
+		names := make([]string, len(hg.Members))
+		for i, h := range hg.Members {
+			names[i] = h.Name
+		}
+		return strings.Join(names, ","), true
+	case "HOSTGROUPNOTES":
+		return hg.Notes, true
+	case "HOSTGROUPNOTESURL":
+		return hg.NotesURL, true
+	case "HOSTGROUPACTIONURL":
+		return hg.ActionURL, true
+	}
+	return "", false
+}
+
+// serviceGroupMacro resolves a $SERVICEGROUP...$ macro against sg. Members
+// are listed as host,service pairs, as Nagios does.
+func serviceGroupMacro(name string, sg *objects.ServiceGroup) (string, bool) {
+	switch name {
+	case "SERVICEGROUPNAME":
+		return sg.Name, true
+	case "SERVICEGROUPALIAS":
+		return sg.Alias, true
+	case "SERVICEGROUPMEMBERS":
+		var b strings.Builder
+		for i, svc := range sg.Members {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if svc.Host != nil {
+				b.WriteString(svc.Host.Name)
+			}
+			b.WriteByte(',')
+			b.WriteString(svc.Description)
+		}
+		return b.String(), true
+	case "SERVICEGROUPNOTES":
+		return sg.Notes, true
+	case "SERVICEGROUPNOTESURL":
+		return sg.NotesURL, true
+	case "SERVICEGROUPACTIONURL":
+		return sg.ActionURL, true
+	}
+	return "", false
+}
+
+// resolveGroup resolves group macros for the first group of host or svc,
+// and the $HOSTGROUPNAMES$ and $SERVICEGROUPNAMES$ lists of all of them.
+// A host or service without groups gets empty values.
+func resolveGroup(name string, host *objects.Host, svc *objects.Service) (string, bool) {
+	switch {
+	case name == "HOSTGROUPNAMES":
+		if host == nil {
+			return "", false
+		}
+		names := make([]string, len(host.HostGroups))
+		for i, hg := range host.HostGroups {
+			names[i] = hg.Name
+		}
+		return strings.Join(names, ","), true
+	case name == "SERVICEGROUPNAMES":
+		if svc == nil {
+			return "", false
+		}
+		names := make([]string, len(svc.ServiceGroups))
+		for i, sg := range svc.ServiceGroups {
+			names[i] = sg.Name
+		}
+		return strings.Join(names, ","), true
+	case strings.HasPrefix(name, "HOSTGROUP"):
+		if host == nil {
+			return "", false
+		}
+		if len(host.HostGroups) == 0 {
+			_, ok := hostGroupMacro(name, &objects.HostGroup{})
+			return "", ok
+		}
+		return hostGroupMacro(name, host.HostGroups[0])
+	case strings.HasPrefix(name, "SERVICEGROUP"):
+		if svc == nil {
+			return "", false
+		}
+		if len(svc.ServiceGroups) == 0 {
+			_, ok := serviceGroupMacro(name, &objects.ServiceGroup{})
+			return "", ok
+		}
+		return serviceGroupMacro(name, svc.ServiceGroups[0])
+	}
+	return "", false
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/config"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -14,6 +15,16 @@ type Expander struct {
 	Cfg        *objects.Config
 	HostLookup func(name string) *objects.Host
 	SvcLookup  func(hostName, svcDesc string) *objects.Service
+	// TimeperiodLookup finds a timeperiod for $ISVALIDTIME:...$ and
+	// $NEXTVALIDTIME:...$.
+	TimeperiodLookup func(name string) *objects.Timeperiod
+	// HostGroupLookup and ServiceGroupLookup find groups for on-demand
+	// group macros such as $HOSTGROUPMEMBERS:groupname$.
+	HostGroupLookup    func(name string) *objects.HostGroup
+	ServiceGroupLookup func(name string) *objects.ServiceGroup
+	// Summary backs the $TOTALHOSTS...$ and $TOTALSERVICES...$ macros.
+	// They resolve to nothing without it.
+	Summary *SummaryCache
 }
 
 // Expand replaces all $MACRO$ references in the input string.
@@ -61,6 +72,12 @@ func (e *Expander) Expand(input string, host *objects.Host, svc *objects.Service
 	return result.String()
 }
 
+// Resolve returns the value of one macro, named without its $ signs. It
+// reports false for macros it does not know.
+func (e *Expander) Resolve(name string, host *objects.Host, svc *objects.Service) (string, bool) {
+	return e.resolveMacro(name, host, svc, nil)
+}
+
 func (e *Expander) resolveMacro(name string, host *objects.Host, svc *objects.Service, args []string) (string, bool) {
 	// $ARGn$ macros (1-32)
 	if strings.HasPrefix(name, "ARG") {
@@ -121,6 +138,20 @@ func (e *Expander) resolveMacro(name string, host *objects.Host, svc *objects.Se
 		return e.resolveOnDemand(name)
 	}
 
+	// Group macros for the host's or service's first group
+	if strings.HasPrefix(name, "HOSTGROUP") || strings.HasPrefix(name, "SERVICEGROUP") {
+		if v, ok := resolveGroup(name, host, svc); ok {
+			return v, true
+		}
+	}
+
+	// Summary macros, shared by every expansion in a scheduler iteration
+	if strings.HasPrefix(name, "TOTAL") && e.Summary != nil {
+		if v, ok := e.Summary.Get().Value(name); ok {
+			return v, true
+		}
+	}
+
 	// Standard macros
 	now := time.Now()
 	switch name {
@@ -181,6 +212,10 @@ func (e *Expander) resolveMacro(name string, host *objects.Host, svc *objects.Se
 		if host != nil {
 			return fmt.Sprintf("%.3f", host.ExecutionTime), true
 		}
+	case "HOSTEXECUTIONID":
+		if host != nil {
+			return strconv.FormatUint(host.ExecutionID, 10), true
+		}
 	case "HOSTDURATION":
 		if host != nil {
 			return formatDuration(now.Sub(host.LastStateChange)), true
@@ -321,6 +356,10 @@ func (e *Expander) resolveMacro(name string, host *objects.Host, svc *objects.Se
 		if svc != nil {
 			return fmt.Sprintf("%.3f", svc.ExecutionTime), true
 		}
+	case "SERVICEEXECUTIONID":
+		if svc != nil {
+			return strconv.FormatUint(svc.ExecutionID, 10), true
+		}
 	case "SERVICEDURATION":
 		if svc != nil {
 			return formatDuration(now.Sub(svc.LastStateChange)), true
@@ -416,6 +455,29 @@ func (e *Expander) resolveOnDemand(name string) (string, bool) {
 	macroBase := parts[0]
 	target := parts[1]
 
+	// $ISVALIDTIME:timeperiod[:timestamp]$ style
+	if macroBase == "ISVALIDTIME" || macroBase == "NEXTVALIDTIME" {
+		return e.resolveTimeperiod(macroBase, target)
+	}
+
+	// $HOSTGROUPMEMBERS:groupname$ style
+	if strings.HasPrefix(macroBase, "HOSTGROUP") && e.HostGroupLookup != nil {
+		hg := e.HostGroupLookup(target)
+		if hg == nil {
+			return "", false
+		}
+		return hostGroupMacro(macroBase, hg)
+	}
+
+	// $SERVICEGROUPMEMBERS:groupname$ style
+	if strings.HasPrefix(macroBase, "SERVICEGROUP") && e.ServiceGroupLookup != nil {
+		sg := e.ServiceGroupLookup(target)
+		if sg == nil {
+			return "", false
+		}
+		return serviceGroupMacro(macroBase, sg)
+	}
+
 	// $HOSTSTATE:hostname$ style
 	if strings.HasPrefix(macroBase, "HOST") && e.HostLookup != nil {
 		host := e.HostLookup(target)
@@ -449,6 +511,40 @@ func (e *Expander) resolveOnDemand(name string) (string, bool) {
 	return "", false
 }
 
+// resolveTimeperiod answers $ISVALIDTIME$ with 1 or 0 and $NEXTVALIDTIME$
+// with the Unix time of the next valid minute, or an empty string if there
+// is none within a year. The time checked is now unless a timestamp follows
+// the timeperiod name.
+func (e *Expander) resolveTimeperiod(macroBase, target string) (string, bool) {
+	name, ts, hasTS := strings.Cut(target, ":")
+	if e.TimeperiodLookup == nil {
+		return "", false
+	}
+	tp := e.TimeperiodLookup(name)
+	if tp == nil {
+		return "", false
+	}
+	t := time.Now()
+	if hasTS {
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		t = time.Unix(sec, 0)
+	}
+	if macroBase == "ISVALIDTIME" {
+		if config.CheckTime(tp, t) {
+			return "1", true
+		}
+		return "0", true
+	}
+	next := config.GetNextValidTime(tp, t)
+	if !config.CheckTime(tp, next) {
+		return "", true
+	}
+	return strconv.FormatInt(next.Unix(), 10), true
+}
+
 // SplitCommandArgs splits "command_name!arg1!arg2!arg3" into command name and args.
 func SplitCommandArgs(checkCommand string) (string, []string) {
 	parts := strings.Split(checkCommand, "!")
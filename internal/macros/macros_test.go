@@ -1,7 +1,9 @@
 package macros
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/oceanplexian/gogios/internal/objects"
 )
@@ -156,3 +158,108 @@ func TestExpander_HostStateMacros(t *testing.T) {
 		}
 	}
 }
+
+func TestExpander_TimeperiodMacros(t *testing.T) {
+	work := &objects.Timeperiod{Name: "workhours"}
+	for d := 1; d <= 5; d++ {
+		work.Ranges[d] = "09:00-17:00"
+	}
+	never := &objects.Timeperiod{Name: "none"}
+	periods := map[string]*objects.Timeperiod{"workhours": work, "none": never}
+	e := &Expander{Cfg: objects.DefaultConfig(), TimeperiodLookup: func(name string) *objects.Timeperiod { return periods[name] }}
+
+	monday := time.Date(2024, 6, 17, 10, 0, 0, 0, time.Local).Unix()
+	saturday := time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local).Unix()
+	nextMonday := time.Date(2024, 6, 17, 9, 0, 0, 0, time.Local).Unix()
+	tests := []struct{ in, want string }{
+		{fmt.Sprintf("$ISVALIDTIME:workhours:%d$", monday), "1"},
+		{fmt.Sprintf("$ISVALIDTIME:workhours:%d$", saturday), "0"},
+		{fmt.Sprintf("$NEXTVALIDTIME:workhours:%d$", monday), fmt.Sprint(monday)},
+		{fmt.Sprintf("$NEXTVALIDTIME:workhours:%d$", saturday), fmt.Sprint(nextMonday)},
+		{"$NEXTVALIDTIME:none$", ""},
+		{"$ISVALIDTIME:missing$", "$ISVALIDTIME:missing$"},
+		{"$ISVALIDTIME:workhours:soon$", "$ISVALIDTIME:workhours:soon$"},
+	}
+	for _, tt := range tests {
+		if got := e.Expand(tt.in, nil, nil, nil); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpander_GroupMacros(t *testing.T) {
+	web1 := &objects.Host{Name: "web1"}
+	web2 := &objects.Host{Name: "web2"}
+	hg := &objects.HostGroup{Name: "web", Alias: "Web servers", Members: []*objects.Host{web1, web2}, Notes: "rack 4"}
+	web1.HostGroups = []*objects.HostGroup{hg, {Name: "linux"}}
+	http1 := &objects.Service{Host: web1, Description: "HTTP"}
+	http2 := &objects.Service{Host: web2, Description: "HTTP"}
+	sg := &objects.ServiceGroup{Name: "frontends", Members: []*objects.Service{http1, http2}, NotesURL: "http://wiki/frontends"}
+	http1.ServiceGroups = []*objects.ServiceGroup{sg}
+
+	e := &Expander{
+		Cfg:                objects.DefaultConfig(),
+		HostGroupLookup:    func(name string) *objects.HostGroup { return map[string]*objects.HostGroup{"web": hg}[name] },
+		ServiceGroupLookup: func(name string) *objects.ServiceGroup { return map[string]*objects.ServiceGroup{"frontends": sg}[name] },
+	}
+	tests := []struct {
+		in   string
+		host *objects.Host
+		svc  *objects.Service
+		want string
+	}{
+		{"$HOSTGROUPNAME$|$HOSTGROUPALIAS$|$HOSTGROUPNOTES$", web1, nil, "web|Web servers|rack 4"},
+		{"$HOSTGROUPNAMES$", web1, nil, "web,linux"},
+		{"$HOSTGROUPMEMBERS$", web1, nil, "web1,web2"},
+		{"[$HOSTGROUPNAME$][$HOSTGROUPNAMES$]", web2, nil, "[][]"},
+		{"$SERVICEGROUPNAME$ $SERVICEGROUPNOTESURL$", web1, http1, "frontends http://wiki/frontends"},
+		{"$SERVICEGROUPMEMBERS$", web1, http1, "web1,HTTP,web2,HTTP"},
+		{"$HOSTGROUPMEMBERS:web$", nil, nil, "web1,web2"},
+		{"$SERVICEGROUPMEMBERS:frontends$", nil, nil, "web1,HTTP,web2,HTTP"},
+		{"$HOSTGROUPMEMBERS:missing$", nil, nil, "$HOSTGROUPMEMBERS:missing$"},
+	}
+	for _, tt := range tests {
+		if got := e.Expand(tt.in, tt.host, tt.svc, nil); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpander_SummaryMacros(t *testing.T) {
+	store := objects.NewObjectStore()
+	up := &objects.Host{Name: "up", CurrentState: objects.HostUp, ActiveChecksEnabled: true}
+	down := &objects.Host{Name: "down", CurrentState: objects.HostDown, ActiveChecksEnabled: true}
+	acked := &objects.Host{Name: "acked", CurrentState: objects.HostDown, ActiveChecksEnabled: true, ProblemAcknowledged: true}
+	for _, h := range []*objects.Host{up, down, acked} {
+		store.AddHost(h)
+	}
+	store.AddService(&objects.Service{Host: up, Description: "ok", CurrentState: objects.ServiceOK, ActiveChecksEnabled: true})
+	crit := &objects.Service{Host: up, Description: "crit", CurrentState: objects.ServiceCritical, ActiveChecksEnabled: true}
+	store.AddService(crit)
+	store.AddService(&objects.Service{Host: down, Description: "behind", CurrentState: objects.ServiceCritical, ActiveChecksEnabled: true})
+	store.AddService(&objects.Service{Host: up, Description: "maint", CurrentState: objects.ServiceWarning, ActiveChecksEnabled: true, ScheduledDowntimeDepth: 1})
+
+	cache := NewSummaryCache(store)
+	e := &Expander{Cfg: objects.DefaultConfig(), Summary: cache}
+	in := "$TOTALHOSTSUP$ $TOTALHOSTSDOWN$ $TOTALHOSTSDOWNUNHANDLED$ $TOTALHOSTPROBLEMS$ " +
+		"$TOTALSERVICESCRITICAL$ $TOTALSERVICESCRITICALUNHANDLED$ $TOTALSERVICEPROBLEMS$ $TOTALSERVICEPROBLEMSUNHANDLED$"
+	if got, want := e.Expand(in, nil, nil, nil), "1 2 1 2 2 1 3 1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Totals are cached until invalidated.
+	crit.CurrentState = objects.ServiceOK
+	if got := e.Expand("$TOTALSERVICESCRITICAL$", nil, nil, nil); got != "2" {
+		t.Errorf("expected cached total 2, got %s", got)
+	}
+	cache.Invalidate()
+	if got := e.Expand("$TOTALSERVICESCRITICAL$", nil, nil, nil); got != "1" {
+		t.Errorf("expected recomputed total 1, got %s", got)
+	}
+
+	// Without a cache the summary macros are left alone.
+	e.Summary = nil
+	if got := e.Expand("$TOTALHOSTSUP$", nil, nil, nil); got != "$TOTALHOSTSUP$" {
+		t.Errorf("expected macro left as-is, got %s", got)
+	}
+}
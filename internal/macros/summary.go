@@ -0,0 +1,154 @@
+package macros
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Summary holds the totals behind the $TOTALHOSTS...$ and $TOTALSERVICES...$
+// summary macros. A problem is unhandled when it is not acknowledged, not
+// in downtime and still actively checked; a service problem on a host
+// that is not UP is handled by the host.
+type Summary struct {
+	HostsUp                   int
+	HostsDown                 int
+	HostsUnreachable          int
+	HostsDownUnhandled        int
+	HostsUnreachableUnhandled int
+
+	ServicesOK                int
+	ServicesWarning           int
+	ServicesCritical          int
+	ServicesUnknown           int
+	ServicesWarningUnhandled  int
+	ServicesCriticalUnhandled int
+	ServicesUnknownUnhandled  int
+}
+
+// ComputeSummary counts hosts and services by state.
+func ComputeSummary(hosts []*objects.Host, services []*objects.Service) Summary {
+	var s Summary
+	for _, h := range hosts {
+		unhandled := !h.ProblemAcknowledged && h.ScheduledDowntimeDepth == 0 && h.ActiveChecksEnabled
+		switch h.CurrentState {
+		case objects.HostUp:
+			s.HostsUp++
+		case objects.HostDown:
+			s.HostsDown++
+			if unhandled {
+				s.HostsDownUnhandled++
+			}
+		case objects.HostUnreachable:
+			s.HostsUnreachable++
+			if unhandled {
+				s.HostsUnreachableUnhandled++
+			}
+		}
+	}
+	for _, svc := range services {
+		unhandled := !svc.ProblemAcknowledged && svc.ScheduledDowntimeDepth == 0 && svc.ActiveChecksEnabled &&
+			(svc.Host == nil || svc.Host.CurrentState == objects.HostUp)
+		switch svc.CurrentState {
+		case objects.ServiceOK:
+			s.ServicesOK++
+		case objects.ServiceWarning:
+			s.ServicesWarning++
+			if unhandled {
+				s.ServicesWarningUnhandled++
+			}
+		case objects.ServiceCritical:
+			s.ServicesCritical++
+			if unhandled {
+				s.ServicesCriticalUnhandled++
+			}
+		case objects.ServiceUnknown:
+			s.ServicesUnknown++
+			if unhandled {
+				s.ServicesUnknownUnhandled++
+			}
+		}
+	}
+	return s
+}
+
+// Value returns the named summary macro.
+func (s Summary) Value(name string) (string, bool) {
+	var n int
+	switch name {
+	case "TOTALHOSTSUP":
+		n = s.HostsUp
+	case "TOTALHOSTSDOWN":
+		n = s.HostsDown
+	case "TOTALHOSTSUNREACHABLE":
+		n = s.HostsUnreachable
+	case "TOTALHOSTSDOWNUNHANDLED":
+		n = s.HostsDownUnhandled
+	case "TOTALHOSTSUNREACHABLEUNHANDLED":
+		n = s.HostsUnreachableUnhandled
+	case "TOTALHOSTPROBLEMS":
+		n = s.HostsDown + s.HostsUnreachable
+	case "TOTALHOSTPROBLEMSUNHANDLED":
+		n = s.HostsDownUnhandled + s.HostsUnreachableUnhandled
+	case "TOTALSERVICESOK":
+		n = s.ServicesOK
+	case "TOTALSERVICESWARNING":
+		n = s.ServicesWarning
+	case "TOTALSERVICESCRITICAL":
+		n = s.ServicesCritical
+	case "TOTALSERVICESUNKNOWN":
+		n = s.ServicesUnknown
+	case "TOTALSERVICESWARNINGUNHANDLED":
+		n = s.ServicesWarningUnhandled
+	case "TOTALSERVICESCRITICALUNHANDLED":
+		n = s.ServicesCriticalUnhandled
+	case "TOTALSERVICESUNKNOWNUNHANDLED":
+		n = s.ServicesUnknownUnhandled
+	case "TOTALSERVICEPROBLEMS":
+		n = s.ServicesWarning + s.ServicesCritical + s.ServicesUnknown
+	case "TOTALSERVICEPROBLEMSUNHANDLED":
+		n = s.ServicesWarningUnhandled + s.ServicesCriticalUnhandled + s.ServicesUnknownUnhandled
+	default:
+		return "", false
+	}
+	return strconv.Itoa(n), true
+}
+
+// SummaryCache computes the Summary at most once between invalidations.
+// The scheduler invalidates it every loop iteration, so expanding summary
+// macros for many notifications in one iteration walks the objects once,
+// and not at all when no command uses them.
+type SummaryCache struct {
+	store *objects.ObjectStore
+	gen   atomic.Uint64
+
+	mu       sync.Mutex
+	valid    bool
+	validGen uint64
+	sum      Summary
+}
+
+// NewSummaryCache creates a cache over the store's hosts and services.
+func NewSummaryCache(store *objects.ObjectStore) *SummaryCache {
+	return &SummaryCache{store: store}
+}
+
+// Invalidate marks the cached totals stale. It is cheap enough to call on
+// every scheduler iteration.
+func (c *SummaryCache) Invalidate() {
+	c.gen.Add(1)
+}
+
+// Get returns the totals, recomputing them if they were invalidated.
+func (c *SummaryCache) Get() Summary {
+	gen := c.gen.Load()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.valid || c.validGen != gen {
+		c.sum = ComputeSummary(c.store.Hosts, c.store.Services)
+		c.valid, c.validGen = true, gen
+	}
+	return c.sum
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// HourlyCapCustomVar on a contact overrides notification_hourly_cap for
+// that contact; 0 leaves the contact uncapped.
+const HourlyCapCustomVar = "NOTIFICATION_HOURLY_CAP"
+
+// countNotification records a notification sent to contact about the
+// object whose counts are at obj.
+func countNotification(contact *objects.Contact, obj **objects.NotificationCounts, now time.Time) {
+	if contact.NotificationCounts == nil {
+		contact.NotificationCounts = &objects.NotificationCounts{}
+	}
+	contact.NotificationCounts.Add(now)
+	if *obj == nil {
+		*obj = &objects.NotificationCounts{}
+	}
+	(*obj).Add(now)
+}
+
+// hourlyCap returns how many notifications contact may get in an hour, or
+// 0 for no cap.
+func (ne *NotificationEngine) hourlyCap(contact *objects.Contact) int {
+	if v, ok := contact.CustomVars[HourlyCapCustomVar]; ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return ne.HourlyCap
+}
+
+// overCap reports whether contact has had its hourly cap of notifications
+// in the last hour.
+func (ne *NotificationEngine) overCap(contact *objects.Contact, now time.Time) bool {
+	limit := ne.hourlyCap(contact)
+	return limit > 0 && contact.NotificationCounts.Since(now, time.Hour) >= limit
+}
+
+// routeOverflow handles a notification about what held back from the
+// contacts in capped by their hourly cap. It goes instead to the members of
+// the overflow contact group that were not among contacts, the ones the
+// notification was meant for, and that viable accepts. Overflow contacts are
+// not capped. routeOverflow returns how many it sent.
+func (ne *NotificationEngine) routeOverflow(capped, contacts []*objects.Contact, what string, now time.Time, viable func(*objects.Contact) bool, send func(*objects.Contact)) int {
+	if len(capped) == 0 {
+		return 0
+	}
+	var group *objects.ContactGroup
+	if ne.OverflowContactGroup != "" {
+		group = ne.Store.GetContactGroup(ne.OverflowContactGroup)
+	}
+	for _, c := range capped {
+		if c.CappedCounts.Since(now, time.Hour) == 0 {
+			// Log once as the contact goes over its cap, not for every
+			// notification held back in a storm.
+			if group != nil {
+				ne.log("Warning: Contact '%s' reached its cap of %d notifications per hour; routing notifications to contact group '%s', starting with %s",
+					c.Name, ne.hourlyCap(c), group.Name, what)
+			} else {
+				ne.log("Warning: Contact '%s' reached its cap of %d notifications per hour; holding back notifications, starting with %s",
+					c.Name, ne.hourlyCap(c), what)
+			}
+		}
+		if c.CappedCounts == nil {
+			c.CappedCounts = &objects.NotificationCounts{}
+		}
+		c.CappedCounts.Add(now)
+	}
+	if group == nil {
+		return 0
+	}
+	meant := make(map[*objects.Contact]bool, len(contacts))
+	for _, c := range contacts {
+		meant[c] = true
+	}
+	sent := 0
+	for _, c := range group.Members {
+		if meant[c] || !viable(c) {
+			continue
+		}
+		meant[c] = true
+		send(c)
+		sent++
+	}
+	return sent
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestHourlyCap_Overflow(t *testing.T) {
+	ne := newTestEngine()
+	ne.HourlyCap = 2
+	ne.OverflowContactGroup = "overflow"
+	cmds := []*objects.Command{{Name: "notify", CommandLine: "true"}}
+	admin := &objects.Contact{Name: "admin", ServiceNotificationsEnabled: true, ServiceNotificationOptions: objects.OptAll, ServiceNotificationCommands: cmds}
+	backup := &objects.Contact{Name: "backup", ServiceNotificationsEnabled: true, ServiceNotificationOptions: objects.OptAll, ServiceNotificationCommands: cmds}
+	lead := &objects.Contact{Name: "lead", ServiceNotificationsEnabled: true, ServiceNotificationOptions: objects.OptAll, ServiceNotificationCommands: cmds,
+		CustomVars: map[string]string{HourlyCapCustomVar: "0"}}
+	ne.Store.AddContactGroup(&objects.ContactGroup{Name: "overflow", Members: []*objects.Contact{backup, lead}})
+	svc := &objects.Service{
+		Host:                 &objects.Host{Name: "web01"},
+		Description:          "HTTP",
+		CurrentState:         objects.ServiceCritical,
+		StateType:            objects.StateTypeHard,
+		NotificationsEnabled: true,
+		Contacts:             []*objects.Contact{admin, lead},
+	}
+
+	for i := 0; i < 3; i++ {
+		ne.ServiceNotification(svc, objects.NotificationCustom, "ops", "test", objects.NotificationOptionForced)
+	}
+	now := time.Now()
+	// admin gets two, then the third goes to backup; lead is uncapped and
+	// already had it, so the overflow group does not send it again.
+	for _, tc := range []struct {
+		contact      *objects.Contact
+		sent, capped int
+	}{{admin, 2, 1}, {backup, 1, 0}, {lead, 3, 0}} {
+		if got := tc.contact.NotificationCounts.Since(now, time.Hour); got != tc.sent {
+			t.Errorf("%s: %d sent, want %d", tc.contact.Name, got, tc.sent)
+		}
+		if got := tc.contact.CappedCounts.Since(now, time.Hour); got != tc.capped {
+			t.Errorf("%s: %d capped, want %d", tc.contact.Name, got, tc.capped)
+		}
+	}
+	if got := svc.NotificationCounts.Since(now, 24*time.Hour); got != 6 {
+		t.Errorf("service: %d notifications, want 6", got)
+	}
+}
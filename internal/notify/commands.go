@@ -1,43 +1,274 @@
 package notify
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
 	"os/exec"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 // CommandExecutor runs notification commands.
 type CommandExecutor struct {
 	Timeout time.Duration
+	// DryRun skips running commands (simulation mode). Notifications are
+	// still evaluated and logged.
+	DryRun bool
+	// Kind names the commands in log messages, e.g. "Notification" or
+	// "Event handler".
+	Kind string
+
+	logFunc func(format string, args ...interface{})
+	mu      sync.Mutex
+	stats   map[string]*CommandStats
+	running sync.WaitGroup
+	slots   chan struct{} // one per worker; nil runs every command at once
+	queued  atomic.Int64
+}
+
+// CommandStats is the execution audit for one command definition.
+type CommandStats struct {
+	Name         string        `json:"command_name"`
+	Runs         int64         `json:"runs"`
+	Failures     int64         `json:"failures"` // non-zero exits, timeouts and exec errors
+	Timeouts     int64         `json:"timeouts"`
+	LastExitCode int           `json:"last_exit_code"` // -1 for timeouts and exec errors
+	LastDuration time.Duration `json:"last_duration"`
+	LastRun      time.Time     `json:"last_run"`
+	LastFailure  time.Time     `json:"last_failure"`
+	LastOutput   string        `json:"last_failure_output"` // first line of output of the last failure
 }
 
 // NewCommandExecutor creates a new executor with the given timeout.
 func NewCommandExecutor(timeout time.Duration) *CommandExecutor {
-	return &CommandExecutor{Timeout: timeout}
+	return &CommandExecutor{Timeout: timeout, Kind: "Notification"}
+}
+
+// SetLogger sets the function failed commands are logged with.
+func (e *CommandExecutor) SetLogger(fn func(string, ...interface{})) {
+	e.logFunc = fn
+}
+
+// SetWorkers limits how many commands run at once to n; 0 removes the
+// limit. Commands beyond it wait for a worker, so a burst of notifications
+// queues instead of forking hundreds of shells alongside the checks. Call
+// it before the first command runs.
+func (e *CommandExecutor) SetWorkers(n int) {
+	e.slots = nil
+	if n > 0 {
+		e.slots = make(chan struct{}, n)
+	}
+}
+
+// Queued returns the number of commands waiting for a worker.
+func (e *CommandExecutor) Queued() int {
+	return int(e.queued.Load())
+}
+
+// Execute runs a command asynchronously and returns immediately. The
+// command is run via /bin/sh -c; name is the command definition it was
+// expanded from and keys the execution audit.
+func (e *CommandExecutor) Execute(name, cmdLine string) {
+	if e.DryRun {
+		return
+	}
+	e.running.Add(1)
+	go func() {
+		defer e.running.Done()
+		e.run(name, cmdLine, 0)
+	}()
+}
+
+// Wait blocks until every command started by Execute or ExecuteChain has
+// finished.
+func (e *CommandExecutor) Wait() {
+	e.running.Wait()
+}
+
+// ExecuteSync runs a command synchronously. Used for testing.
+func (e *CommandExecutor) ExecuteSync(name, cmdLine string) error {
+	return e.run(name, cmdLine, 0)
+}
+
+// ChainStep is one step of a fallback chain: its commands, already
+// expanded, run concurrently and the step succeeds if any of them does.
+type ChainStep struct {
+	Commands []ChainCommand
+	Timeout  time.Duration // 0 means the executor timeout
+}
+
+// ChainCommand is an expanded command in a ChainStep. LogMsg, if set, is
+// logged when the command runs as a fallback.
+type ChainCommand struct {
+	Name    string
+	CmdLine string
+	LogMsg  string
 }
 
-// Execute runs a notification command asynchronously and returns immediately.
-// The command is run via /bin/sh -c.
-func (e *CommandExecutor) Execute(cmdLine string) {
-	go e.run(cmdLine)
+// ExecuteChain runs steps in order in the background, moving on to the
+// next step only when every command of the current one failed. desc names
+// the chain in log messages.
+func (e *CommandExecutor) ExecuteChain(desc string, steps []ChainStep) {
+	if e.DryRun || len(steps) == 0 {
+		return
+	}
+	e.running.Add(1)
+	go func() {
+		defer e.running.Done()
+		e.runChain(desc, steps)
+	}()
 }
 
-// ExecuteSync runs a notification command synchronously. Used for testing.
-func (e *CommandExecutor) ExecuteSync(cmdLine string) error {
-	return e.run(cmdLine)
+func (e *CommandExecutor) runChain(desc string, steps []ChainStep) bool {
+	for i, step := range steps {
+		if i > 0 {
+			e.log("Warning: %s step %d of %d for %s failed, falling back to step %d", e.Kind, i, len(steps), desc, i+1)
+			for _, c := range step.Commands {
+				if c.LogMsg != "" {
+					e.log("%s", c.LogMsg)
+				}
+			}
+		}
+		if e.runStep(step) {
+			return true
+		}
+	}
+	e.log("Warning: All %d %s steps for %s failed", len(steps), strings.ToLower(e.Kind), desc)
+	return false
 }
 
-func (e *CommandExecutor) run(cmdLine string) error {
-	timeout := e.Timeout
+func (e *CommandExecutor) runStep(step ChainStep) bool {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ok := false
+	for _, c := range step.Commands {
+		wg.Add(1)
+		go func(c ChainCommand) {
+			defer wg.Done()
+			if e.run(c.Name, c.CmdLine, step.Timeout) == nil {
+				mu.Lock()
+				ok = true
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+	return ok
+}
+
+func (e *CommandExecutor) run(name, cmdLine string, timeout time.Duration) error {
+	if e.slots != nil {
+		// The timeout starts once a worker is free.
+		e.queued.Add(1)
+		e.slots <- struct{}{}
+		e.queued.Add(-1)
+		defer func() { <-e.slots }()
+	}
+	if timeout == 0 {
+		timeout = e.Timeout
+	}
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	var out bytes.Buffer
 	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", cmdLine)
-	return cmd.Run()
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Kill the whole process group on timeout; otherwise a grandchild
+	// holding the output pipe keeps Run waiting.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+
+	exitCode := 0
+	timedOut := ctx.Err() == context.DeadlineExceeded
+	var exitErr *exec.ExitError
+	switch {
+	case timedOut:
+		exitCode = -1
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		exitCode = -1
+	}
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(out.String()), "\n")
+	e.record(name, start, duration, exitCode, timedOut, err != nil, firstLine)
+
+	switch {
+	case timedOut:
+		e.log("Warning: %s command '%s' timed out after %.0f seconds", e.Kind, name, timeout.Seconds())
+	case err != nil && exitCode >= 0:
+		e.log("Warning: %s command '%s' exited with code %d after %.2fs: %s", e.Kind, name, exitCode, duration.Seconds(), firstLine)
+	case err != nil:
+		e.log("Warning: %s command '%s' could not be executed: %v", e.Kind, name, err)
+	}
+	return err
+}
+
+func (e *CommandExecutor) record(name string, start time.Time, d time.Duration, exitCode int, timedOut, failed bool, output string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stats == nil {
+		e.stats = make(map[string]*CommandStats)
+	}
+	st := e.stats[name]
+	if st == nil {
+		st = &CommandStats{Name: name}
+		e.stats[name] = st
+	}
+	st.Runs++
+	st.LastExitCode = exitCode
+	st.LastDuration = d
+	st.LastRun = start
+	if failed {
+		st.Failures++
+		st.LastFailure = start
+		st.LastOutput = output
+	}
+	if timedOut {
+		st.Timeouts++
+	}
+}
+
+func (e *CommandExecutor) log(format string, args ...interface{}) {
+	if e.logFunc != nil {
+		e.logFunc(format, args...)
+	}
+}
+
+// Stats returns the execution audit for every command run so far, sorted
+// by command name.
+func (e *CommandExecutor) Stats() []CommandStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]CommandStats, 0, len(e.stats))
+	for _, st := range e.stats {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// StatsHandler serves Stats as JSON.
+func (e *CommandExecutor) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(e.Stats())
+	})
 }
 
 // ExpandMacros does simple macro substitution in a command line.
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandExecutor_Audit(t *testing.T) {
+	e := NewCommandExecutor(time.Second)
+	var logs []string
+	e.SetLogger(func(format string, args ...interface{}) {
+		logs = append(logs, format)
+	})
+
+	if err := e.ExecuteSync("notify-ok", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ExecuteSync("notify-broken", "echo 'smtp: connection refused'; exit 2"); err == nil {
+		t.Fatal("expected an error for a non-zero exit")
+	}
+	e.ExecuteSync("notify-broken", "exit 1")
+
+	st := e.Stats()
+	if len(st) != 2 || st[0].Name != "notify-broken" || st[1].Name != "notify-ok" {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	if st[0].Runs != 2 || st[0].Failures != 2 || st[0].LastExitCode != 1 || st[0].LastOutput != "" {
+		t.Errorf("unexpected broken command stats %+v", st[0])
+	}
+	if st[1].Runs != 1 || st[1].Failures != 0 || st[1].LastExitCode != 0 {
+		t.Errorf("unexpected ok command stats %+v", st[1])
+	}
+	if len(logs) != 2 || !strings.Contains(logs[0], "exited with code") {
+		t.Errorf("expected a warning per failed run, got %v", logs)
+	}
+}
+
+func TestCommandExecutor_Timeout(t *testing.T) {
+	e := NewCommandExecutor(50 * time.Millisecond)
+	var logged string
+	e.SetLogger(func(format string, args ...interface{}) { logged = format })
+	e.ExecuteSync("notify-slow", "sleep 5")
+
+	st := e.Stats()
+	if len(st) != 1 || st[0].Timeouts != 1 || st[0].Failures != 1 || st[0].LastExitCode != -1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if !strings.Contains(logged, "timed out") {
+		t.Errorf("expected a timeout warning, got %q", logged)
+	}
+}
+
+func TestCommandExecutor_Chain(t *testing.T) {
+	e := NewCommandExecutor(time.Second)
+	var logs []string
+	e.SetLogger(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})
+
+	steps := []ChainStep{
+		{Commands: []ChainCommand{{Name: "notify-slack", CmdLine: "exit 1"}}},
+		{Commands: []ChainCommand{{Name: "notify-email", CmdLine: "sleep 5"}}, Timeout: 50 * time.Millisecond},
+		{Commands: []ChainCommand{
+			{Name: "notify-sms", CmdLine: "true", LogMsg: "SERVICE NOTIFICATION: sms"},
+			{Name: "notify-pager", CmdLine: "exit 2"},
+		}},
+		{Commands: []ChainCommand{{Name: "notify-never", CmdLine: "true"}}},
+	}
+	if !e.runChain("contact 'oncall'", steps) {
+		t.Fatal("expected the chain to succeed on step 3")
+	}
+	for _, st := range e.Stats() {
+		if st.Name == "notify-never" {
+			t.Error("chain should stop at the first successful step")
+		}
+		if st.Name == "notify-email" && st.Timeouts != 1 {
+			t.Errorf("expected the per-step timeout to apply, got %+v", st)
+		}
+	}
+	joined := strings.Join(logs, "\n")
+	if !strings.Contains(joined, "step 2 of 4 for contact 'oncall' failed, falling back to step 3") ||
+		!strings.Contains(joined, "SERVICE NOTIFICATION: sms") {
+		t.Errorf("unexpected log:\n%s", joined)
+	}
+
+	if e.runChain("contact 'oncall'", steps[:2]) {
+		t.Error("expected a chain of failing steps to fail")
+	}
+}
+
+func TestCommandExecutor_Workers(t *testing.T) {
+	e := NewCommandExecutor(time.Second)
+	e.SetWorkers(2)
+	dir := t.TempDir()
+	// Each command records how many ran at the same time as itself.
+	cmd := fmt.Sprintf(`touch %[1]s/$$; ls %[1]s | wc -l >> %[1]s.max; sleep 0.2; rm %[1]s/$$`, dir)
+	start := time.Now()
+	for range 5 {
+		e.Execute("notify-slow", cmd)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := e.Queued(); n != 3 {
+		t.Errorf("expected 3 queued commands, got %d", n)
+	}
+	e.Wait()
+	if d := time.Since(start); d < 500*time.Millisecond {
+		t.Errorf("5 commands of 0.2s on 2 workers took %v", d)
+	}
+	if e.Queued() != 0 || e.Stats()[0].Runs != 5 {
+		t.Errorf("unexpected queue %d, stats %+v", e.Queued(), e.Stats())
+	}
+	out, err := os.ReadFile(dir + ".max")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range strings.Fields(string(out)) {
+		if n != "1" && n != "2" {
+			t.Errorf("%s commands ran at once", n)
+		}
+	}
+}
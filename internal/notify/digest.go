@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Default per-alert digest lines; see HostDigestLine and ServiceDigestLine.
+const (
+	DefaultHostDigestLine    = "$NOTIFICATIONTYPE$ $HOSTNAME$ is $HOSTSTATE$: $HOSTOUTPUT$"
+	DefaultServiceDigestLine = "$NOTIFICATIONTYPE$ $HOSTNAME$/$SERVICEDESC$ is $SERVICESTATE$: $SERVICEOUTPUT$"
+)
+
+// digestEntry is one notification held back for a contact's digest, with
+// its macros captured when it was raised.
+type digestEntry struct {
+	service  bool
+	cmds     []*objects.Command
+	fallback []objects.NotificationStep
+	macros   map[string]string
+	logMsg   func(*objects.Command) string
+}
+
+type pendingDigest struct {
+	contact *objects.Contact
+	entries []digestEntry
+	timer   *time.Timer
+}
+
+// queueDigest holds e back until the contact's digest window, started by
+// the first queued notification, closes.
+func (ne *NotificationEngine) queueDigest(contact *objects.Contact, e digestEntry) {
+	ne.digestMu.Lock()
+	defer ne.digestMu.Unlock()
+	if ne.digests == nil {
+		ne.digests = make(map[string]*pendingDigest)
+	}
+	d := ne.digests[contact.Name]
+	if d == nil {
+		d = &pendingDigest{contact: contact}
+		d.timer = time.AfterFunc(contact.NotificationDigest, func() { ne.flushDigest(contact.Name) })
+		ne.digests[contact.Name] = d
+	}
+	d.entries = append(d.entries, e)
+}
+
+// FlushDigests sends every pending digest without waiting for its window
+// to close. Called at shutdown.
+func (ne *NotificationEngine) FlushDigests() {
+	ne.digestMu.Lock()
+	names := make([]string, 0, len(ne.digests))
+	for name, d := range ne.digests {
+		d.timer.Stop()
+		names = append(names, name)
+	}
+	ne.digestMu.Unlock()
+	for _, name := range names {
+		ne.flushDigest(name)
+	}
+}
+
+// flushDigest sends a contact's pending notifications: a lone notification
+// goes out as it would have without a digest, several go out as one
+// DIGEST notification through the contact's service commands, or its host
+// commands if only hosts are involved.
+func (ne *NotificationEngine) flushDigest(name string) {
+	ne.digestMu.Lock()
+	d := ne.digests[name]
+	delete(ne.digests, name)
+	ne.digestMu.Unlock()
+	if d == nil || len(d.entries) == 0 {
+		return
+	}
+	if len(d.entries) == 1 {
+		e := d.entries[0]
+		ne.runContactCommands(d.contact, e.cmds, e.fallback, e.macros, e.logMsg)
+		return
+	}
+
+	hostLine, svcLine := ne.HostDigestLine, ne.ServiceDigestLine
+	if hostLine == "" {
+		hostLine = DefaultHostDigestLine
+	}
+	if svcLine == "" {
+		svcLine = DefaultServiceDigestLine
+	}
+	macros := make(map[string]string)
+	lines := make([]string, 0, len(d.entries))
+	var via *digestEntry
+	for i := range d.entries {
+		e := &d.entries[i]
+		for k := range e.macros {
+			macros[k] = ""
+		}
+		cmds := e.cmds
+		if len(cmds) == 0 && len(e.fallback) > 0 {
+			cmds = e.fallback[0].Commands
+		}
+		for _, cmd := range cmds {
+			ne.log(e.logMsg(cmd))
+		}
+		if e.service {
+			lines = append(lines, ExpandMacros(svcLine, e.macros))
+			if via == nil || !via.service {
+				via = e
+			}
+		} else {
+			lines = append(lines, ExpandMacros(hostLine, e.macros))
+			if via == nil {
+				via = e
+			}
+		}
+	}
+
+	count := strconv.Itoa(len(d.entries))
+	summary := count + " notifications in " + d.contact.NotificationDigest.String()
+	detail := strings.Join(lines, `\n`)
+	for _, k := range []string{"CONTACTNAME", "CONTACTEMAIL", "CONTACTPAGER"} {
+		macros[k] = via.macros[k]
+	}
+	macros["NOTIFICATIONTYPE"] = "DIGEST"
+	macros["DIGESTCOUNT"] = count
+	macros["DIGESTSUMMARY"] = detail
+	macros["HOSTOUTPUT"], macros["SERVICEOUTPUT"] = summary, summary
+	macros["LONGHOSTOUTPUT"], macros["LONGSERVICEOUTPUT"] = detail, detail
+
+	ne.runContactCommands(d.contact, via.cmds, via.fallback, macros, func(cmd *objects.Command) string {
+		return "NOTIFICATION DIGEST: " + d.contact.Name + ";" + cmd.Name + ";" + summary
+	})
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestNotificationDigest(t *testing.T) {
+	ne := newTestEngine()
+	out := filepath.Join(t.TempDir(), "sent")
+	cmd := &objects.Command{Name: "notify", CommandLine: "printf '%s\\n' '$NOTIFICATIONTYPE$|$DIGESTCOUNT$|$LONGSERVICEOUTPUT$' >> " + out}
+	contact := &objects.Contact{
+		Name:                        "oncall",
+		NotificationDigest:          time.Hour,
+		HostNotificationCommands:    []*objects.Command{cmd},
+		ServiceNotificationCommands: []*objects.Command{cmd},
+	}
+	host := &objects.Host{Name: "web01", CurrentState: objects.HostDown, PluginOutput: "timeout"}
+	svc := &objects.Service{Host: host, Description: "HTTP", CurrentState: objects.ServiceCritical, PluginOutput: "refused"}
+
+	ne.notifyContactOfHost(contact, host, objects.NotificationNormal, "PROBLEM", "", "")
+	ne.notifyContactOfService(contact, svc, objects.NotificationNormal, "PROBLEM", "", "")
+	if _, err := os.Stat(out); err == nil {
+		t.Fatal("expected notifications to be held until the digest window closes")
+	}
+	ne.FlushDigests()
+	ne.CmdExecutor.Wait()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `DIGEST|2|PROBLEM web01 is DOWN: timeout\nPROBLEM web01/HTTP is CRITICAL: refused` + "\n"
+	if string(data) != want {
+		t.Errorf("unexpected digest:\n got %q\nwant %q", data, want)
+	}
+
+	// A window with a single notification sends it unchanged.
+	os.Remove(out)
+	ne.notifyContactOfService(contact, svc, objects.NotificationNormal, "RECOVERY", "", "")
+	ne.FlushDigests()
+	ne.CmdExecutor.Wait()
+	data, _ = os.ReadFile(out)
+	if !strings.HasPrefix(string(data), "RECOVERY|$DIGESTCOUNT$|") {
+		t.Errorf("expected a plain notification, got %q", data)
+	}
+}
@@ -2,90 +2,167 @@
 package notify
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/config"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
 // IsValidServiceEscalation checks if an escalation entry is valid for the current notification.
 func IsValidServiceEscalation(svc *objects.Service, esc *objects.ServiceEscalation, notifNum int, options int) bool {
+	return serviceEscalationReason(svc.CurrentState, esc, notifNum, options, time.Now()) == ""
+}
+
+// serviceEscalationReason returns why esc does not apply to notification
+// notifNum for a service in state at now, or "" if it does. As in Nagios:
+//
+//   - a recovery is matched with the number of the last problem
+//     notification, notifNum-1, so the escalation that carried the problem
+//     also carries its recovery;
+//   - first_notification 0 matches from the first notification on, and
+//     last_notification 0 never ends the escalation;
+//   - escalation_options must include the state (r for recoveries);
+//     unset options match every state;
+//   - escalation_period must include now;
+//   - a broadcast notification skips all of the above.
+func serviceEscalationReason(state int, esc *objects.ServiceEscalation, notifNum int, options int, now time.Time) string {
 	// BROADCAST overrides all checks
 	if options&objects.NotificationOptionBroadcast != 0 {
-		return true
+		return ""
 	}
 
 	// Check notification number range
 	num := notifNum
-	if svc.CurrentState == objects.ServiceOK {
+	if state == objects.ServiceOK {
 		// For recovery, use previous notification number
 		num = notifNum - 1
 	}
-	if esc.FirstNotification > 0 && num < esc.FirstNotification {
-		return false
-	}
-	if esc.LastNotification > 0 && num > esc.LastNotification {
-		return false
+	if reason := escalationRangeReason(num, esc.FirstNotification, esc.LastNotification); reason != "" {
+		return reason
 	}
 
 	// Check escalation options match current state
-	if esc.EscalationOptions != 0 && !objects.StateMatchesSvcOptions(svc.CurrentState, esc.EscalationOptions) {
-		return false
+	if esc.EscalationOptions != 0 && !objects.StateMatchesSvcOptions(state, esc.EscalationOptions) {
+		return "escalation_options exclude " + objects.ServiceStateName(state)
 	}
 
 	// Check escalation period
-	if esc.EscalationPeriod != nil && !objects.InTimeperiod(esc.EscalationPeriod, time.Now()) {
-		return false
+	if !config.CheckTime(esc.EscalationPeriod, now) {
+		return "outside escalation_period " + esc.EscalationPeriod.Name
 	}
 
-	return true
+	return ""
 }
 
 // IsValidHostEscalation checks if a host escalation entry is valid.
 func IsValidHostEscalation(hst *objects.Host, esc *objects.HostEscalation, notifNum int, options int) bool {
+	return hostEscalationReason(hst.CurrentState, esc, notifNum, options, time.Now()) == ""
+}
+
+// hostEscalationReason is the host counterpart of serviceEscalationReason.
+func hostEscalationReason(state int, esc *objects.HostEscalation, notifNum int, options int, now time.Time) string {
 	if options&objects.NotificationOptionBroadcast != 0 {
-		return true
+		return ""
 	}
 
 	num := notifNum
-	if hst.CurrentState == objects.HostUp {
+	if state == objects.HostUp {
 		num = notifNum - 1
 	}
-	if esc.FirstNotification > 0 && num < esc.FirstNotification {
-		return false
-	}
-	if esc.LastNotification > 0 && num > esc.LastNotification {
-		return false
+	if reason := escalationRangeReason(num, esc.FirstNotification, esc.LastNotification); reason != "" {
+		return reason
 	}
 
-	if esc.EscalationOptions != 0 && !objects.StateMatchesHostOptions(hst.CurrentState, esc.EscalationOptions) {
-		return false
+	if esc.EscalationOptions != 0 && !objects.StateMatchesHostOptions(state, esc.EscalationOptions) {
+		return "escalation_options exclude " + objects.HostStateName(state)
 	}
 
-	if esc.EscalationPeriod != nil && !objects.InTimeperiod(esc.EscalationPeriod, time.Now()) {
-		return false
+	if !config.CheckTime(esc.EscalationPeriod, now) {
+		return "outside escalation_period " + esc.EscalationPeriod.Name
 	}
 
-	return true
+	return ""
+}
+
+func escalationRangeReason(num, first, last int) string {
+	if first > 0 && num < first {
+		return fmt.Sprintf("notification %d is before first_notification %d", num, first)
+	}
+	if last > 0 && num > last {
+		return fmt.Sprintf("notification %d is after last_notification %d", num, last)
+	}
+	return ""
 }
 
 // ShouldServiceNotificationBeEscalated checks if any escalation is valid.
 func ShouldServiceNotificationBeEscalated(svc *objects.Service, options int) bool {
-	for _, esc := range svc.Escalations {
-		if IsValidServiceEscalation(svc, esc, svc.CurrentNotificationNumber, options) {
-			return true
-		}
-	}
-	return false
+	return len(validServiceEscalations(svc, options, time.Now())) > 0
 }
 
 // ShouldHostNotificationBeEscalated checks if any host escalation is valid.
 func ShouldHostNotificationBeEscalated(hst *objects.Host, options int) bool {
-	for _, esc := range hst.Escalations {
-		if IsValidHostEscalation(hst, esc, hst.CurrentNotificationNumber, options) {
-			return true
+	return len(validHostEscalations(hst, options, time.Now())) > 0
+}
+
+// validServiceEscalations returns the escalations of svc that apply to its
+// current notification at now. Deciding whether a notification escalates
+// and to whom from one list, for one instant, keeps a notification sent as
+// an escalation_period ends from being escalated to nobody.
+func validServiceEscalations(svc *objects.Service, options int, now time.Time) []*objects.ServiceEscalation {
+	var valid []*objects.ServiceEscalation
+	for _, esc := range svc.AllEscalations() {
+		if serviceEscalationReason(svc.CurrentState, esc, svc.CurrentNotificationNumber, options, now) == "" {
+			valid = append(valid, esc)
+		}
+	}
+	return valid
+}
+
+// validHostEscalations is validServiceEscalations for hosts.
+func validHostEscalations(hst *objects.Host, options int, now time.Time) []*objects.HostEscalation {
+	var valid []*objects.HostEscalation
+	for _, esc := range hst.AllEscalations() {
+		if hostEscalationReason(hst.CurrentState, esc, hst.CurrentNotificationNumber, options, now) == "" {
+			valid = append(valid, esc)
+		}
+	}
+	return valid
+}
+
+// reachedServiceEscalations returns the escalations of svc that its
+// notifications for the current problem have reached: first_notification
+// is at most the current notification number and escalation_options
+// include the state. last_notification and escalation_period are not
+// checked, so contacts paged at a level that has since ended still hear of
+// an acknowledgement.
+func reachedServiceEscalations(svc *objects.Service) []*objects.ServiceEscalation {
+	var reached []*objects.ServiceEscalation
+	for _, esc := range svc.AllEscalations() {
+		if esc.FirstNotification > svc.CurrentNotificationNumber {
+			continue
+		}
+		if esc.EscalationOptions != 0 && !objects.StateMatchesSvcOptions(svc.CurrentState, esc.EscalationOptions) {
+			continue
+		}
+		reached = append(reached, esc)
+	}
+	return reached
+}
+
+// reachedHostEscalations is reachedServiceEscalations for hosts.
+func reachedHostEscalations(hst *objects.Host) []*objects.HostEscalation {
+	var reached []*objects.HostEscalation
+	for _, esc := range hst.AllEscalations() {
+		if esc.FirstNotification > hst.CurrentNotificationNumber {
+			continue
+		}
+		if esc.EscalationOptions != 0 && !objects.StateMatchesHostOptions(hst.CurrentState, esc.EscalationOptions) {
+			continue
 		}
+		reached = append(reached, esc)
 	}
-	return false
+	return reached
 }
 
 // GetNextServiceNotificationTime calculates when the next notification should be sent.
@@ -94,7 +171,7 @@ func GetNextServiceNotificationTime(svc *objects.Service, offset time.Time, inte
 
 	// Check escalations for shortest interval
 	hasEscInterval := false
-	for _, esc := range svc.Escalations {
+	for _, esc := range svc.AllEscalations() {
 		if !IsValidServiceEscalation(svc, esc, svc.CurrentNotificationNumber, 0) {
 			continue
 		}
@@ -118,7 +195,7 @@ func GetNextHostNotificationTime(hst *objects.Host, offset time.Time, intervalLe
 	interval := hst.NotificationInterval
 
 	hasEscInterval := false
-	for _, esc := range hst.Escalations {
+	for _, esc := range hst.AllEscalations() {
 		if !IsValidHostEscalation(hst, esc, hst.CurrentNotificationNumber, 0) {
 			continue
 		}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/config"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func businessHours() *objects.Timeperiod {
+	tp := &objects.Timeperiod{Name: "business-hours"}
+	for day := time.Monday; day <= time.Friday; day++ {
+		tp.Ranges[day] = "09:00-17:00"
+	}
+	return tp
+}
+
+func TestEscalationPeriod_BusinessHours(t *testing.T) {
+	parsed := businessHours()
+	compiled := businessHours()
+	config.CompileTimeperiod(compiled)
+	// Wednesday 2024-06-12 and Saturday 2024-06-15.
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 6, day, hour, min, 0, 0, time.Local) }
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"start of day", at(12, 9, 0), true},
+		{"before start", at(12, 8, 59), false},
+		{"midday", at(12, 12, 30), true},
+		{"last minute", at(12, 16, 59), true},
+		{"end is exclusive", at(12, 17, 0), false},
+		{"weekend", at(15, 12, 0), false},
+	}
+	for _, tp := range []*objects.Timeperiod{parsed, compiled} {
+		for _, tt := range tests {
+			esc := &objects.ServiceEscalation{EscalationOptions: objects.OptAll, EscalationPeriod: tp}
+			reason := serviceEscalationReason(objects.ServiceCritical, esc, 1, 0, tt.now)
+			if got := reason == ""; got != tt.want {
+				t.Errorf("%s (compiled=%v): applies=%v, want %v (%s)", tt.name, tp.Compiled != nil, got, tt.want, reason)
+			}
+			hesc := &objects.HostEscalation{EscalationOptions: objects.OptAll, EscalationPeriod: tp}
+			if got := hostEscalationReason(objects.HostDown, hesc, 1, 0, tt.now) == ""; got != tt.want {
+				t.Errorf("%s (compiled=%v): host escalation applies=%v, want %v", tt.name, tp.Compiled != nil, got, tt.want)
+			}
+		}
+	}
+
+	// Broadcasts reach escalation contacts outside the period too.
+	esc := &objects.ServiceEscalation{EscalationOptions: objects.OptAll, EscalationPeriod: parsed}
+	if reason := serviceEscalationReason(objects.ServiceCritical, esc, 1, objects.NotificationOptionBroadcast, at(15, 3, 0)); reason != "" {
+		t.Errorf("broadcast outside escalation_period: expected to apply, got %q", reason)
+	}
+}
+
+func TestEscalationRange_Boundaries(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		first, last int
+		state       int
+		notifNum    int
+		want        bool
+	}{
+		{2, 4, objects.ServiceCritical, 1, false},
+		{2, 4, objects.ServiceCritical, 2, true},
+		{2, 4, objects.ServiceCritical, 4, true},
+		{2, 4, objects.ServiceCritical, 5, false},
+		// 0 leaves that end of the range open.
+		{0, 0, objects.ServiceCritical, 1, true},
+		{0, 0, objects.ServiceCritical, 1000, true},
+		{0, 2, objects.ServiceCritical, 1, true},
+		{0, 2, objects.ServiceCritical, 3, false},
+		{3, 0, objects.ServiceCritical, 2, false},
+		{3, 0, objects.ServiceCritical, 100, true},
+		{1, 1, objects.ServiceCritical, 1, true},
+		{1, 1, objects.ServiceCritical, 2, false},
+		// A recovery is matched with the last problem notification.
+		{3, 0, objects.ServiceOK, 3, false},
+		{3, 0, objects.ServiceOK, 4, true},
+		{0, 2, objects.ServiceOK, 3, true},
+		{0, 2, objects.ServiceOK, 4, false},
+		{1, 0, objects.ServiceOK, 1, false},
+	}
+	for _, tt := range tests {
+		esc := &objects.ServiceEscalation{FirstNotification: tt.first, LastNotification: tt.last, EscalationOptions: objects.OptAll}
+		reason := serviceEscalationReason(tt.state, esc, tt.notifNum, 0, now)
+		if got := reason == ""; got != tt.want {
+			t.Errorf("first=%d last=%d state=%d notification %d: applies=%v, want %v (%s)",
+				tt.first, tt.last, tt.state, tt.notifNum, got, tt.want, reason)
+		}
+		hostState := objects.HostDown
+		if tt.state == objects.ServiceOK {
+			hostState = objects.HostUp
+		}
+		hesc := &objects.HostEscalation{FirstNotification: tt.first, LastNotification: tt.last, EscalationOptions: objects.OptAll}
+		if got := hostEscalationReason(hostState, hesc, tt.notifNum, 0, now) == ""; got != tt.want {
+			t.Errorf("host first=%d last=%d state=%d notification %d: applies=%v, want %v",
+				tt.first, tt.last, hostState, tt.notifNum, got, tt.want)
+		}
+	}
+}
+
+func TestEscalationOptions(t *testing.T) {
+	now := time.Now()
+	svcEsc := &objects.ServiceEscalation{EscalationOptions: objects.OptCritical | objects.OptRecovery}
+	for state, want := range map[int]bool{
+		objects.ServiceWarning:  false,
+		objects.ServiceCritical: true,
+		objects.ServiceUnknown:  false,
+		objects.ServiceOK:       true,
+	} {
+		if got := serviceEscalationReason(state, svcEsc, 2, 0, now) == ""; got != want {
+			t.Errorf("escalation_options c,r for service state %d: applies=%v, want %v", state, got, want)
+		}
+	}
+	hostEsc := &objects.HostEscalation{EscalationOptions: objects.OptDown}
+	for state, want := range map[int]bool{
+		objects.HostDown:        true,
+		objects.HostUnreachable: false,
+		objects.HostUp:          false,
+	} {
+		if got := hostEscalationReason(state, hostEsc, 2, 0, now) == ""; got != want {
+			t.Errorf("escalation_options d for host state %d: applies=%v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestNotificationList_EscalationPeriod(t *testing.T) {
+	oncall := &objects.Contact{Name: "oncall", ServiceNotificationsEnabled: true}
+	owner := &objects.Contact{Name: "owner", ServiceNotificationsEnabled: true}
+	closed := &objects.Timeperiod{Name: "never"}
+	svc := &objects.Service{
+		Description:               "HTTP",
+		Host:                      &objects.Host{Name: "web01"},
+		CurrentState:              objects.ServiceCritical,
+		CurrentNotificationNumber: 3,
+		Contacts:                  []*objects.Contact{owner},
+	}
+	svc.Escalations = []*objects.ServiceEscalation{{
+		FirstNotification: 2,
+		EscalationOptions: objects.OptAll,
+		EscalationPeriod:  closed,
+		Contacts:          []*objects.Contact{oncall},
+	}}
+	ne := &NotificationEngine{}
+	got := ne.createServiceNotificationList(svc, 0)
+	if len(got) != 1 || got[0] != owner {
+		t.Errorf("outside escalation_period: expected the service's own contact, got %v", got)
+	}
+	closed.Ranges[time.Now().Weekday()] = "00:00-24:00"
+	got = ne.createServiceNotificationList(svc, 0)
+	if len(got) != 1 || got[0] != oncall {
+		t.Errorf("inside escalation_period: expected the escalation contact, got %v", got)
+	}
+}
+
+func TestNotificationList_EscalationInheritsContacts(t *testing.T) {
+	owner := &objects.Contact{Name: "owner", HostNotificationsEnabled: true}
+	oncall := &objects.Contact{Name: "oncall", HostNotificationsEnabled: true}
+	hst := &objects.Host{
+		Name:                      "web01",
+		CurrentState:              objects.HostDown,
+		CurrentNotificationNumber: 3,
+		Contacts:                  []*objects.Contact{owner},
+	}
+	// The first escalation names no contacts, so it notifies the host's
+	// own; the second names its own instead.
+	hst.Escalations = []*objects.HostEscalation{
+		{FirstNotification: 2, EscalationOptions: objects.OptAll},
+		{FirstNotification: 3, EscalationOptions: objects.OptAll, Contacts: []*objects.Contact{oncall}},
+	}
+	ne := &NotificationEngine{}
+	got := ne.createHostNotificationList(hst, 0)
+	if len(got) != 2 || got[0] != owner || got[1] != oncall {
+		t.Errorf("expected owner and oncall, got %v", got)
+	}
+	if p := PreviewHostEscalation(hst, 2, objects.HostDown, time.Now()); len(p.Contacts) != 1 || p.Contacts[0].Name != "owner" {
+		t.Errorf("preview of notification 2: %+v", p.Contacts)
+	}
+}
+
+func TestNotificationList_AllEscalations(t *testing.T) {
+	owner := &objects.Contact{Name: "owner", ServiceNotificationsEnabled: true}
+	level2 := &objects.Contact{Name: "level2", ServiceNotificationsEnabled: true}
+	level3 := &objects.Contact{Name: "level3", ServiceNotificationsEnabled: true}
+	later := &objects.Contact{Name: "later", ServiceNotificationsEnabled: true}
+	svc := &objects.Service{
+		Description:               "HTTP",
+		Host:                      &objects.Host{Name: "web01"},
+		CurrentState:              objects.ServiceCritical,
+		CurrentNotificationNumber: 4,
+		Contacts:                  []*objects.Contact{owner},
+	}
+	svc.Escalations = []*objects.ServiceEscalation{
+		{FirstNotification: 2, LastNotification: 3, EscalationOptions: objects.OptAll, Contacts: []*objects.Contact{level2}},
+		{FirstNotification: 4, EscalationOptions: objects.OptAll, Contacts: []*objects.Contact{level3}},
+		{FirstNotification: 6, EscalationOptions: objects.OptAll, Contacts: []*objects.Contact{later}},
+	}
+	ne := &NotificationEngine{}
+	got := ne.createServiceNotificationList(svc, 0)
+	if len(got) != 1 || got[0] != level3 {
+		t.Errorf("current level only: expected level3, got %v", got)
+	}
+	// Every level reached so far, including one that has ended, and the
+	// service's own contacts; not the level not yet reached.
+	got = ne.createServiceNotificationList(svc, objects.NotificationOptionAllEscalations)
+	if len(got) != 3 || got[0] != level2 || got[1] != level3 || got[2] != owner {
+		t.Errorf("all levels: expected level2, level3 and owner, got %v", got)
+	}
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/expr"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// NotificationFilterCustomVar holds an expression (see package expr) that
+// must be true for a problem notification to go out, e.g.
+// "attempt >= 3 && !weekend". On a host or service it gates the
+// notification; a service without one uses its host's. On a contact it
+// gates that contact only, and the expression can also use contact_name.
+// Recoveries and other notification types are not filtered.
+const NotificationFilterCustomVar = "NOTIFICATION_FILTER"
+
+var contactFilterVars = append(append([]string{}, expr.ObjectVars...), "contact_name")
+
+// filterAllows evaluates the filter src against env for what, e.g.
+// "service 'HTTP' on host 'web01'". A filter that does not compile or
+// fails to evaluate is logged and lets the notification through, so a typo
+// never silences alerts.
+func (ne *NotificationEngine) filterAllows(src string, vars []string, env expr.Env, what string) bool {
+	ne.filterMu.Lock()
+	prog, ok := ne.filters[src]
+	if !ok {
+		var err error
+		prog, err = expr.Compile(src, vars)
+		if err != nil {
+			ne.log("Warning: Ignoring _%s of %s: %v", NotificationFilterCustomVar, what, err)
+		}
+		if ne.filters == nil {
+			ne.filters = make(map[string]*expr.Program)
+		}
+		ne.filters[src] = prog // nil for a filter that does not compile
+	}
+	ne.filterMu.Unlock()
+	if prog == nil {
+		return true
+	}
+	pass, err := prog.EvalBool(env)
+	if err != nil {
+		ne.log("Warning: Ignoring _%s of %s: %v", NotificationFilterCustomVar, what, err)
+		return true
+	}
+	return pass
+}
+
+// serviceFilterAllows applies the service's notification filter, or its
+// host's.
+func (ne *NotificationEngine) serviceFilterAllows(svc *objects.Service, now time.Time) bool {
+	src, ok := svc.CustomVars[NotificationFilterCustomVar]
+	if !ok && svc.Host != nil {
+		src, ok = svc.Host.CustomVars[NotificationFilterCustomVar]
+	}
+	if !ok {
+		return true
+	}
+	return ne.filterAllows(src, expr.ObjectVars, expr.ServiceEnv(svc, now), serviceName(svc))
+}
+
+// hostFilterAllows applies the host's notification filter.
+func (ne *NotificationEngine) hostFilterAllows(hst *objects.Host, now time.Time) bool {
+	src, ok := hst.CustomVars[NotificationFilterCustomVar]
+	if !ok {
+		return true
+	}
+	return ne.filterAllows(src, expr.ObjectVars, expr.HostEnv(hst, now), "host '"+hst.Name+"'")
+}
+
+// contactFilterAllows applies contact's notification filter to a problem of
+// svc, or of hst when svc is nil.
+func (ne *NotificationEngine) contactFilterAllows(contact *objects.Contact, hst *objects.Host, svc *objects.Service, now time.Time) bool {
+	src, ok := contact.CustomVars[NotificationFilterCustomVar]
+	if !ok {
+		return true
+	}
+	var env expr.Env
+	if svc != nil {
+		env = expr.ServiceEnv(svc, now)
+	} else {
+		env = expr.HostEnv(hst, now)
+	}
+	env["contact_name"] = contact.Name
+	return ne.filterAllows(src, contactFilterVars, env, "contact '"+contact.Name+"'")
+}
+
+func serviceName(svc *objects.Service) string {
+	if svc.Host == nil {
+		return "service '" + svc.Description + "'"
+	}
+	return "service '" + svc.Description + "' on host '" + svc.Host.Name + "'"
+}
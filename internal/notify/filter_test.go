@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestNotificationFilter(t *testing.T) {
+	ne := newTestEngine()
+	host := &objects.Host{Name: "web01", CurrentState: objects.HostUp,
+		CustomVars: map[string]string{NotificationFilterCustomVar: "attempt >= 3"}}
+	svc := &objects.Service{
+		Host:                 host,
+		Description:          "HTTP",
+		NotificationsEnabled: true,
+		CurrentState:         objects.ServiceCritical,
+		StateType:            objects.StateTypeHard,
+		CurrentAttempt:       2,
+		NotificationOptions:  objects.OptCritical,
+	}
+
+	// The service has no filter of its own, so the host's applies.
+	if ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) == 0 {
+		t.Error("expected the host's filter to block attempt 2")
+	}
+	svc.CurrentAttempt = 3
+	if ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) != 0 {
+		t.Error("expected attempt 3 to pass the filter")
+	}
+	svc.CustomVars = map[string]string{NotificationFilterCustomVar: "_TEAM == 'db'"}
+	if ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) == 0 {
+		t.Error("expected the service's own filter to replace the host's")
+	}
+
+	// A broken filter lets notifications through.
+	svc.CustomVars[NotificationFilterCustomVar] = "atempt >= 3"
+	if ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) != 0 {
+		t.Error("expected an invalid filter to be ignored")
+	}
+
+	contact := &objects.Contact{
+		Name:                        "night-shift",
+		ServiceNotificationsEnabled: true,
+		ServiceNotificationOptions:  objects.OptCritical | objects.OptRecovery,
+		CustomVars:                  map[string]string{NotificationFilterCustomVar: `contact_name == "night-shift" && state_name == "UNKNOWN"`},
+	}
+	if ne.checkContactServiceViability(contact, svc, objects.NotificationNormal, 0) == 0 {
+		t.Error("expected the contact's filter to block CRITICAL")
+	}
+	if ne.checkContactServiceViability(contact, svc, objects.NotificationAcknowledgement, 0) != 0 {
+		t.Error("expected acknowledgements not to be filtered")
+	}
+	svc.CurrentState = objects.ServiceOK
+	if ne.checkContactServiceViability(contact, svc, objects.NotificationNormal, 0) != 0 {
+		t.Error("expected recoveries not to be filtered")
+	}
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// serviceInhibitor returns the first inhibition rule that suppresses
+// problem notifications for svc, with the source service in a problem
+// state on the same host, or nil. A service never inhibits itself, so a
+// target pattern may cover the source.
+func (ne *NotificationEngine) serviceInhibitor(svc *objects.Service) (*objects.InhibitRule, *objects.Service) {
+	if ne.Store == nil || svc.Host == nil {
+		return nil, nil
+	}
+	for _, r := range ne.Store.InhibitRules {
+		if r.SourceService == svc.Description || !r.Target.MatchString(svc.Description) || !inhibitHostMatch(r, svc.Host) {
+			continue
+		}
+		src := ne.Store.GetService(svc.Host.Name, r.SourceService)
+		if src == nil || src.CurrentState == objects.ServiceOK || !objects.StateMatchesSvcOptions(src.CurrentState, r.SourceStates) {
+			continue
+		}
+		return r, src
+	}
+	return nil, nil
+}
+
+// inhibitHostMatch checks the rule's host filters. Groups are looked up at
+// match time, as for blackouts.
+func inhibitHostMatch(r *objects.InhibitRule, h *objects.Host) bool {
+	if len(r.HostNames) == 0 && len(r.HostGroups) == 0 {
+		return true
+	}
+	for _, p := range r.HostNames {
+		if downtime.WildcardMatch(p, h.Name) {
+			return true
+		}
+	}
+	for _, hg := range h.HostGroups {
+		for _, name := range r.HostGroups {
+			if hg.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestServiceNotification_Inhibited(t *testing.T) {
+	ne := newTestEngine()
+	web := &objects.HostGroup{Name: "web"}
+	host := &objects.Host{Name: "web01", CurrentState: objects.HostUp, HostGroups: []*objects.HostGroup{web}}
+	ne.Store.AddHost(host)
+	newService := func(desc string, state int) *objects.Service {
+		svc := &objects.Service{
+			Host:                 host,
+			Description:          desc,
+			NotificationsEnabled: true,
+			CurrentState:         state,
+			StateType:            objects.StateTypeHard,
+			NotificationOptions:  objects.OptCritical | objects.OptRecovery,
+		}
+		ne.Store.AddService(svc)
+		return svc
+	}
+	ping := newService("Ping", objects.ServiceCritical)
+	http := newService("HTTP", objects.ServiceCritical)
+	disk := newService("Disk /var", objects.ServiceCritical)
+	rule := &objects.InhibitRule{
+		Name:          "ping-down",
+		SourceService: "Ping",
+		SourceStates:  objects.OptCritical,
+		Target:        regexp.MustCompile("^(?:.*)$"),
+		HostGroups:    []string{"web"},
+	}
+	ne.Store.InhibitRules = []*objects.InhibitRule{rule}
+
+	viable := func(svc *objects.Service) bool {
+		return ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) == 0
+	}
+	if viable(http) || viable(disk) {
+		t.Error("expected services inhibited while Ping is CRITICAL")
+	}
+	if !viable(ping) {
+		t.Error("the source must not inhibit itself")
+	}
+	if ne.checkServiceNotificationViability(http, objects.NotificationNormal, objects.NotificationOptionForced) != 0 {
+		t.Error("expected forced notification to pass")
+	}
+
+	rule.Target = regexp.MustCompile("^(?:HTTP)$")
+	if !viable(disk) || viable(http) {
+		t.Error("expected only HTTP inhibited")
+	}
+
+	// Recoveries of problems notified before the source failed still go out.
+	http.CurrentState, http.NotifiedOn = objects.ServiceOK, objects.OptCritical
+	if !viable(http) {
+		t.Error("expected recovery to pass")
+	}
+	http.CurrentState = objects.ServiceCritical
+
+	ping.CurrentState = objects.ServiceWarning
+	if !viable(http) {
+		t.Error("WARNING is not a source state")
+	}
+	rule.SourceStates |= objects.OptWarning
+	if viable(http) {
+		t.Error("expected inhibited with w in source_states")
+	}
+
+	rule.HostGroups, rule.HostNames = nil, []string{"db*"}
+	if !viable(http) {
+		t.Error("expected rule limited to db* hosts not to apply")
+	}
+}
@@ -1,10 +1,17 @@
 package notify
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/config"
 	"github.com/oceanplexian/gogios/internal/dependency"
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/expr"
+	"github.com/oceanplexian/gogios/internal/macros"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -19,17 +26,48 @@ type NotificationEngine struct {
 	Store          *objects.ObjectStore
 	Logger         Logger
 	CmdExecutor    *CommandExecutor
+	Blackouts      *downtime.BlackoutManager // nil = no blackout windows
+	// Macros resolves macros a notification command uses beyond the
+	// built-in set, such as group and summary macros. nil = built-ins only.
+	Macros         *macros.Expander
+	// AckAllEscalations sends every acknowledgement notification to all
+	// escalation levels reached, as NotificationOptionAllEscalations.
+	AckAllEscalations bool
+	// HourlyCap is how many notifications a contact gets in an hour
+	// before the rest go to OverflowContactGroup, or are held back
+	// without one; 0 = no cap. _NOTIFICATION_HOURLY_CAP overrides it.
+	HourlyCap            int
+	OverflowContactGroup string
+	// OnNextNotification is called when a problem notification has set a
+	// host's or service's NextNotification (svc is nil for a host), so
+	// the caller can schedule the renotification. It is not called once
+	// no_more_notifications is set.
+	OnNextNotification func(h *objects.Host, svc *objects.Service)
 	nextNotifID    atomic.Uint64
+
+	// Digest line templates for contacts with notification_digest; empty
+	// means DefaultHostDigestLine / DefaultServiceDigestLine.
+	HostDigestLine    string
+	ServiceDigestLine string
+	digestMu          sync.Mutex
+	digests           map[string]*pendingDigest
+
+	// Compiled _NOTIFICATION_FILTER expressions by source; nil for ones
+	// that do not compile.
+	filterMu sync.Mutex
+	filters  map[string]*expr.Program
 }
 
 // NewNotificationEngine creates a new notification engine.
 func NewNotificationEngine(gs *objects.GlobalState, store *objects.ObjectStore, logger Logger) *NotificationEngine {
-	return &NotificationEngine{
+	ne := &NotificationEngine{
 		GlobalState: gs,
 		Store:       store,
 		Logger:      logger,
 		CmdExecutor: NewCommandExecutor(30 * time.Second),
 	}
+	ne.CmdExecutor.SetLogger(ne.log)
+	return ne
 }
 
 // SetNextNotificationID sets the next notification ID (from retention).
@@ -63,6 +101,10 @@ func (ne *NotificationEngine) ServiceNotification(svc *objects.Service, ntype in
 	// Assign notification ID
 	svc.CurrentNotificationID = ne.nextNotifID.Add(1) - 1
 
+	if ntype == objects.NotificationAcknowledgement && ne.AckAllEscalations {
+		options |= objects.NotificationOptionAllEscalations
+	}
+
 	// Build contact list
 	contacts := ne.createServiceNotificationList(svc, options)
 
@@ -70,13 +112,21 @@ func (ne *NotificationEngine) ServiceNotification(svc *objects.Service, ntype in
 	now := time.Now()
 	typeName := objects.NotificationTypeName(ntype, svc.CurrentState, false)
 
+	var capped []*objects.Contact
 	for _, contact := range contacts {
 		if ne.checkContactServiceViability(contact, svc, ntype, options) != 0 {
 			continue
 		}
+		if ne.overCap(contact, now) {
+			capped = append(capped, contact)
+			continue
+		}
 		ne.notifyContactOfService(contact, svc, ntype, typeName, author, data)
 		contactsNotified++
 	}
+	contactsNotified += ne.routeOverflow(capped, contacts, serviceName(svc), now,
+		func(c *objects.Contact) bool { return ne.checkContactServiceViability(c, svc, ntype, options) == 0 },
+		func(c *objects.Contact) { ne.notifyContactOfService(c, svc, ntype, typeName, author, data) })
 
 	if ntype == objects.NotificationNormal && contactsNotified > 0 {
 		svc.NextNotification = GetNextServiceNotificationTime(svc, now, ne.intervalLength())
@@ -94,6 +144,9 @@ func (ne *NotificationEngine) ServiceNotification(svc *objects.Service, ntype in
 			svc.CurrentNotificationNumber = 0
 			svc.NoMoreNotifications = false
 		}
+		if svc.CurrentState != objects.ServiceOK && !svc.NoMoreNotifications && ne.OnNextNotification != nil {
+			ne.OnNextNotification(svc.Host, svc)
+		}
 	}
 
 	// Decrement if no contacts notified
@@ -119,19 +172,31 @@ func (ne *NotificationEngine) HostNotification(hst *objects.Host, ntype int, aut
 
 	hst.CurrentNotificationID = ne.nextNotifID.Add(1) - 1
 
+	if ntype == objects.NotificationAcknowledgement && ne.AckAllEscalations {
+		options |= objects.NotificationOptionAllEscalations
+	}
+
 	contacts := ne.createHostNotificationList(hst, options)
 
 	contactsNotified := 0
 	now := time.Now()
 	typeName := objects.NotificationTypeName(ntype, hst.CurrentState, true)
 
+	var capped []*objects.Contact
 	for _, contact := range contacts {
 		if ne.checkContactHostViability(contact, hst, ntype, options) != 0 {
 			continue
 		}
+		if ne.overCap(contact, now) {
+			capped = append(capped, contact)
+			continue
+		}
 		ne.notifyContactOfHost(contact, hst, ntype, typeName, author, data)
 		contactsNotified++
 	}
+	contactsNotified += ne.routeOverflow(capped, contacts, "host '"+hst.Name+"'", now,
+		func(c *objects.Contact) bool { return ne.checkContactHostViability(c, hst, ntype, options) == 0 },
+		func(c *objects.Contact) { ne.notifyContactOfHost(c, hst, ntype, typeName, author, data) })
 
 	if ntype == objects.NotificationNormal && contactsNotified > 0 {
 		hst.NextNotification = GetNextHostNotificationTime(hst, now, ne.intervalLength())
@@ -146,6 +211,9 @@ func (ne *NotificationEngine) HostNotification(hst *objects.Host, ntype int, aut
 			hst.CurrentNotificationNumber = 0
 			hst.NoMoreNotifications = false
 		}
+		if hst.CurrentState != objects.HostUp && !hst.NoMoreNotifications && ne.OnNextNotification != nil {
+			ne.OnNextNotification(hst, nil)
+		}
 	}
 
 	if contactsNotified == 0 && (ntype == objects.NotificationNormal || options&objects.NotificationOptionIncrement != 0) {
@@ -158,6 +226,28 @@ func (ne *NotificationEngine) HostNotification(hst *objects.Host, ntype int, aut
 	return 0
 }
 
+// RenotifyService sends svc's next problem notification once its
+// NextNotification time has come. The scheduler's renotification event
+// calls it, so repeats go out on time even when the service is checked
+// less often than its notification_interval. It does nothing if svc has
+// recovered, or was notified again since the event was scheduled.
+func (ne *NotificationEngine) RenotifyService(svc *objects.Service) int {
+	if svc.StateType != objects.StateTypeHard || svc.CurrentState == objects.ServiceOK ||
+		svc.NextNotification.IsZero() || time.Now().Before(svc.NextNotification) {
+		return 1
+	}
+	return ne.ServiceNotification(svc, objects.NotificationNormal, "", "", 0)
+}
+
+// RenotifyHost is RenotifyService for a host.
+func (ne *NotificationEngine) RenotifyHost(hst *objects.Host) int {
+	if hst.StateType != objects.StateTypeHard || hst.CurrentState == objects.HostUp ||
+		hst.NextNotification.IsZero() || time.Now().Before(hst.NextNotification) {
+		return 1
+	}
+	return ne.HostNotification(hst, objects.NotificationNormal, "", "", 0)
+}
+
 func (ne *NotificationEngine) intervalLength() int {
 	if ne.GlobalState != nil && ne.GlobalState.IntervalLength > 0 {
 		return ne.GlobalState.IntervalLength
@@ -182,6 +272,11 @@ func (ne *NotificationEngine) checkServiceNotificationViability(svc *objects.Ser
 		return 1
 	}
 
+	// 3a. Blackout window (Gogios extension)
+	if ne.Blackouts != nil && ne.Blackouts.ServiceBlackout(svc, time.Now()) != nil {
+		return 1
+	}
+
 	// 4. Service parents all bad
 	if len(svc.ServiceParents) > 0 {
 		allBad := true
@@ -197,7 +292,7 @@ func (ne *NotificationEngine) checkServiceNotificationViability(svc *objects.Ser
 	}
 
 	// 5. Notification period
-	if svc.NotificationPeriod != nil && !objects.InTimeperiod(svc.NotificationPeriod, time.Now()) {
+	if svc.NotificationPeriod != nil && !config.CheckTime(svc.NotificationPeriod, time.Now()) {
 		return 1
 	}
 
@@ -290,8 +385,9 @@ func (ne *NotificationEngine) checkServiceNotificationViability(svc *objects.Ser
 		return 0
 	}
 
-	// notification_interval==0 and no_more_notifications
-	if svc.NotificationInterval == 0 && svc.NoMoreNotifications {
+	// no_more_notifications: the interval for the last notification,
+	// the object's own or an escalation's, was 0
+	if svc.NoMoreNotifications {
 		return 1
 	}
 
@@ -300,7 +396,13 @@ func (ne *NotificationEngine) checkServiceNotificationViability(svc *objects.Ser
 		return 1
 	}
 
-	// Not enough time elapsed (unless volatile)
+	// Inhibition rules (Gogios extension)
+	if r, _ := ne.serviceInhibitor(svc); r != nil {
+		return 1
+	}
+
+	// Not enough time elapsed. Volatile services notify on every problem
+	// result, as in Nagios.
 	now := time.Now()
 	if !svc.IsVolatile && !svc.NextNotification.IsZero() && now.Before(svc.NextNotification) {
 		return 1
@@ -316,6 +418,11 @@ func (ne *NotificationEngine) checkServiceNotificationViability(svc *objects.Ser
 		return 1
 	}
 
+	// _NOTIFICATION_FILTER (Gogios extension)
+	if !ne.serviceFilterAllows(svc, now) {
+		return 1
+	}
+
 	return 0
 }
 
@@ -329,7 +436,11 @@ func (ne *NotificationEngine) checkHostNotificationViability(hst *objects.Host,
 		return 1
 	}
 
-	if hst.NotificationPeriod != nil && !objects.InTimeperiod(hst.NotificationPeriod, time.Now()) {
+	if ne.Blackouts != nil && ne.Blackouts.HostBlackout(hst, time.Now()) != nil {
+		return 1
+	}
+
+	if hst.NotificationPeriod != nil && !config.CheckTime(hst.NotificationPeriod, time.Now()) {
 		return 1
 	}
 
@@ -414,7 +525,7 @@ func (ne *NotificationEngine) checkHostNotificationViability(hst *objects.Host,
 		return 1
 	}
 
-	if hst.NotificationInterval == 0 && hst.NoMoreNotifications {
+	if hst.NoMoreNotifications {
 		return 1
 	}
 
@@ -423,6 +534,11 @@ func (ne *NotificationEngine) checkHostNotificationViability(hst *objects.Host,
 		return 1
 	}
 
+	// _NOTIFICATION_FILTER (Gogios extension)
+	if !ne.hostFilterAllows(hst, now) {
+		return 1
+	}
+
 	return 0
 }
 
@@ -432,16 +548,7 @@ func (ne *NotificationEngine) checkContactServiceViability(contact *objects.Cont
 		return 0
 	}
 
-	// minimum_value check
-	if contact.MinimumImportance > 0 && svc.HourlyValue < contact.MinimumImportance {
-		return 1
-	}
-
-	if !contact.ServiceNotificationsEnabled {
-		return 1
-	}
-
-	if contact.ServiceNotificationPeriod != nil && !objects.InTimeperiod(contact.ServiceNotificationPeriod, time.Now()) {
+	if contactServiceFilterReason(contact, svc.HourlyValue, time.Now()) != "" {
 		return 1
 	}
 
@@ -463,19 +570,51 @@ func (ne *NotificationEngine) checkContactServiceViability(contact *objects.Cont
 		return 0
 	}
 
-	// State match
-	if !objects.StateMatchesSvcOptions(svc.CurrentState, contact.ServiceNotificationOptions) {
+	if contactServiceStateReason(contact, svc.CurrentState) != "" {
+		return 1
+	}
+
+	if ntype == objects.NotificationNormal && svc.CurrentState != objects.ServiceOK &&
+		!ne.contactFilterAllows(contact, svc.Host, svc, time.Now()) {
 		return 1
 	}
 
+	return 0
+}
+
+// contactServiceFilterReason returns why contact gets no service
+// notifications at now regardless of type or state, or "".
+func contactServiceFilterReason(contact *objects.Contact, hourlyValue uint, now time.Time) string {
+	// minimum_value check
+	if contact.MinimumImportance > 0 && hourlyValue < contact.MinimumImportance {
+		return fmt.Sprintf("service importance %d is below minimum_value %d", hourlyValue, contact.MinimumImportance)
+	}
+
+	if !contact.ServiceNotificationsEnabled {
+		return "service notifications disabled"
+	}
+
+	if contact.ServiceNotificationPeriod != nil && !config.CheckTime(contact.ServiceNotificationPeriod, now) {
+		return "outside service_notification_period " + contact.ServiceNotificationPeriod.Name
+	}
+	return ""
+}
+
+// contactServiceStateReason returns why contact is not notified of a
+// problem or recovery in state, or "".
+func contactServiceStateReason(contact *objects.Contact, state int) string {
+	// State match
+	if !objects.StateMatchesSvcOptions(state, contact.ServiceNotificationOptions) {
+		return "service_notification_options exclude " + objects.ServiceStateName(state)
+	}
+
 	// Recovery: contact must have OPT_RECOVERY
-	if svc.CurrentState == objects.ServiceOK {
+	if state == objects.ServiceOK {
 		if contact.ServiceNotificationOptions&objects.OptRecovery == 0 {
-			return 1
+			return "service_notification_options exclude recoveries"
 		}
 	}
-
-	return 0
+	return ""
 }
 
 // checkContactHostViability checks per-contact host notification filters.
@@ -484,15 +623,7 @@ func (ne *NotificationEngine) checkContactHostViability(contact *objects.Contact
 		return 0
 	}
 
-	if contact.MinimumImportance > 0 && hst.HourlyValue < contact.MinimumImportance {
-		return 1
-	}
-
-	if !contact.HostNotificationsEnabled {
-		return 1
-	}
-
-	if contact.HostNotificationPeriod != nil && !objects.InTimeperiod(contact.HostNotificationPeriod, time.Now()) {
+	if contactHostFilterReason(contact, hst.HourlyValue, time.Now()) != "" {
 		return 1
 	}
 
@@ -514,19 +645,48 @@ func (ne *NotificationEngine) checkContactHostViability(contact *objects.Contact
 		return 0
 	}
 
-	if !objects.StateMatchesHostOptions(hst.CurrentState, contact.HostNotificationOptions) {
+	if contactHostStateReason(contact, hst.CurrentState) != "" {
 		return 1
 	}
 
-	if hst.CurrentState == objects.HostUp {
-		if contact.HostNotificationOptions&objects.OptRecovery == 0 {
-			return 1
-		}
+	if ntype == objects.NotificationNormal && hst.CurrentState != objects.HostUp &&
+		!ne.contactFilterAllows(contact, hst, nil, time.Now()) {
+		return 1
 	}
 
 	return 0
 }
 
+// contactHostFilterReason is the host counterpart of contactServiceFilterReason.
+func contactHostFilterReason(contact *objects.Contact, hourlyValue uint, now time.Time) string {
+	if contact.MinimumImportance > 0 && hourlyValue < contact.MinimumImportance {
+		return fmt.Sprintf("host importance %d is below minimum_value %d", hourlyValue, contact.MinimumImportance)
+	}
+
+	if !contact.HostNotificationsEnabled {
+		return "host notifications disabled"
+	}
+
+	if contact.HostNotificationPeriod != nil && !config.CheckTime(contact.HostNotificationPeriod, now) {
+		return "outside host_notification_period " + contact.HostNotificationPeriod.Name
+	}
+	return ""
+}
+
+// contactHostStateReason is the host counterpart of contactServiceStateReason.
+func contactHostStateReason(contact *objects.Contact, state int) string {
+	if !objects.StateMatchesHostOptions(state, contact.HostNotificationOptions) {
+		return "host_notification_options exclude " + objects.HostStateName(state)
+	}
+
+	if state == objects.HostUp {
+		if contact.HostNotificationOptions&objects.OptRecovery == 0 {
+			return "host_notification_options exclude recoveries"
+		}
+	}
+	return ""
+}
+
 // createServiceNotificationList builds the deduplicated contact list.
 func (ne *NotificationEngine) createServiceNotificationList(svc *objects.Service, options int) []*objects.Contact {
 	seen := make(map[string]bool)
@@ -538,18 +698,21 @@ func (ne *NotificationEngine) createServiceNotificationList(svc *objects.Service
 		}
 	}
 
-	escalated := ShouldServiceNotificationBeEscalated(svc, options)
+	escalations := validServiceEscalations(svc, options, time.Now())
+	escalated := len(escalations) > 0
 	broadcast := options&objects.NotificationOptionBroadcast != 0
+	allLevels := options&objects.NotificationOptionAllEscalations != 0
+	if allLevels && !broadcast {
+		escalations = reachedServiceEscalations(svc)
+	}
 
-	if escalated || broadcast {
-		for _, esc := range svc.Escalations {
-			if !IsValidServiceEscalation(svc, esc, svc.CurrentNotificationNumber, options) {
-				continue
-			}
-			for _, c := range esc.Contacts {
+	if escalated || broadcast || allLevels {
+		for _, esc := range escalations {
+			escContacts, escGroups := esc.ContactsFor(svc)
+			for _, c := range escContacts {
 				addContact(c)
 			}
-			for _, cg := range esc.ContactGroups {
+			for _, cg := range escGroups {
 				for _, c := range cg.Members {
 					addContact(c)
 				}
@@ -557,7 +720,7 @@ func (ne *NotificationEngine) createServiceNotificationList(svc *objects.Service
 		}
 	}
 
-	if !escalated || broadcast {
+	if !escalated || broadcast || allLevels {
 		for _, c := range svc.Contacts {
 			addContact(c)
 		}
@@ -582,18 +745,21 @@ func (ne *NotificationEngine) createHostNotificationList(hst *objects.Host, opti
 		}
 	}
 
-	escalated := ShouldHostNotificationBeEscalated(hst, options)
+	escalations := validHostEscalations(hst, options, time.Now())
+	escalated := len(escalations) > 0
 	broadcast := options&objects.NotificationOptionBroadcast != 0
+	allLevels := options&objects.NotificationOptionAllEscalations != 0
+	if allLevels && !broadcast {
+		escalations = reachedHostEscalations(hst)
+	}
 
-	if escalated || broadcast {
-		for _, esc := range hst.Escalations {
-			if !IsValidHostEscalation(hst, esc, hst.CurrentNotificationNumber, options) {
-				continue
-			}
-			for _, c := range esc.Contacts {
+	if escalated || broadcast || allLevels {
+		for _, esc := range escalations {
+			escContacts, escGroups := esc.ContactsFor(hst)
+			for _, c := range escContacts {
 				addContact(c)
 			}
-			for _, cg := range esc.ContactGroups {
+			for _, cg := range escGroups {
 				for _, c := range cg.Members {
 					addContact(c)
 				}
@@ -601,7 +767,7 @@ func (ne *NotificationEngine) createHostNotificationList(hst *objects.Host, opti
 		}
 	}
 
-	if !escalated || broadcast {
+	if !escalated || broadcast || allLevels {
 		for _, c := range hst.Contacts {
 			addContact(c)
 		}
@@ -616,67 +782,158 @@ func (ne *NotificationEngine) createHostNotificationList(hst *objects.Host, opti
 }
 
 func (ne *NotificationEngine) notifyContactOfService(contact *objects.Contact, svc *objects.Service, ntype int, typeName, author, data string) {
-	for _, cmd := range contact.ServiceNotificationCommands {
-		macros := map[string]string{
-			"NOTIFICATIONTYPE":    typeName,
-			"CONTACTNAME":        contact.Name,
-			"CONTACTEMAIL":       contact.Email,
-			"CONTACTPAGER":       contact.Pager,
-			"HOSTNAME":           svc.Host.Name,
-			"HOSTALIAS":          svc.Host.Alias,
-			"HOSTADDRESS":        svc.Host.Address,
-			"SERVICEDESC":        svc.Description,
-			"SERVICESTATE":       objects.ServiceStateName(svc.CurrentState),
-			"SERVICESTATETYPE":   objects.StateTypeName(svc.StateType),
-			"SERVICEATTEMPT":     itoa(svc.CurrentAttempt),
-			"MAXSERVICEATTEMPTS": itoa(svc.MaxCheckAttempts),
-			"SERVICEOUTPUT":      svc.PluginOutput,
-			"LONGSERVICEOUTPUT":  svc.LongPluginOutput,
-			"NOTIFICATIONAUTHOR":  author,
-			"NOTIFICATIONCOMMENT": data,
-		}
-		cmdLine := ExpandMacros(cmd.CommandLine, macros)
-		// Log notification
-		logMsg := "SERVICE NOTIFICATION: " + contact.Name + ";" + svc.Host.Name + ";" + svc.Description + ";" + typeName + ";" + cmd.Name + ";" + svc.PluginOutput
+	macros := map[string]string{
+		"NOTIFICATIONTYPE":    typeName,
+		"CONTACTNAME":        contact.Name,
+		"CONTACTEMAIL":       contact.Email,
+		"CONTACTPAGER":       contact.Pager,
+		"HOSTNAME":           svc.Host.Name,
+		"HOSTALIAS":          svc.Host.Alias,
+		"HOSTADDRESS":        svc.Host.Address,
+		"SERVICEDESC":        svc.Description,
+		"SERVICESTATE":       objects.ServiceStateName(svc.CurrentState),
+		"SERVICESTATETYPE":   objects.StateTypeName(svc.StateType),
+		"SERVICEATTEMPT":     itoa(svc.CurrentAttempt),
+		"MAXSERVICEATTEMPTS": itoa(svc.MaxCheckAttempts),
+		"SERVICEOUTPUT":      svc.PluginOutput,
+		"LONGSERVICEOUTPUT":  svc.LongPluginOutput,
+		"SERVICEEXECUTIONID": fmt.Sprint(svc.ExecutionID),
+		"NOTIFICATIONAUTHOR":  author,
+		"NOTIFICATIONCOMMENT": data,
+	}
+	ne.addReferencedMacros(macros, contact.ServiceNotificationCommands, contact.ServiceNotificationFallback, svc.Host, svc)
+	logMsg := func(cmd *objects.Command) string {
+		msg := "SERVICE NOTIFICATION: " + contact.Name + ";" + svc.Host.Name + ";" + svc.Description + ";" + typeName + ";" + cmd.Name + ";" + svc.PluginOutput
 		if ntype == objects.NotificationCustom || ntype == objects.NotificationAcknowledgement {
-			logMsg += ";" + author + ";" + data
+			msg += ";" + author + ";" + data
 		}
-		ne.log(logMsg)
-
-		ne.CmdExecutor.Execute(cmdLine)
+		return msg
 	}
-	contact.LastServiceNotification = time.Now()
+	if contact.NotificationDigest > 0 {
+		ne.queueDigest(contact, digestEntry{service: true, cmds: contact.ServiceNotificationCommands,
+			fallback: contact.ServiceNotificationFallback, macros: macros, logMsg: logMsg})
+	} else {
+		ne.runContactCommands(contact, contact.ServiceNotificationCommands, contact.ServiceNotificationFallback, macros, logMsg)
+	}
+	now := time.Now()
+	contact.LastServiceNotification = now
+	countNotification(contact, &svc.NotificationCounts, now)
 }
 
 func (ne *NotificationEngine) notifyContactOfHost(contact *objects.Contact, hst *objects.Host, ntype int, typeName, author, data string) {
-	for _, cmd := range contact.HostNotificationCommands {
-		macros := map[string]string{
-			"NOTIFICATIONTYPE":    typeName,
-			"CONTACTNAME":        contact.Name,
-			"CONTACTEMAIL":       contact.Email,
-			"CONTACTPAGER":       contact.Pager,
-			"HOSTNAME":           hst.Name,
-			"HOSTALIAS":          hst.Alias,
-			"HOSTADDRESS":        hst.Address,
-			"HOSTSTATE":          objects.HostStateName(hst.CurrentState),
-			"HOSTSTATETYPE":      objects.StateTypeName(hst.StateType),
-			"HOSTATTEMPT":        itoa(hst.CurrentAttempt),
-			"MAXHOSTATTEMPTS":    itoa(hst.MaxCheckAttempts),
-			"HOSTOUTPUT":         hst.PluginOutput,
-			"LONGHOSTOUTPUT":     hst.LongPluginOutput,
-			"NOTIFICATIONAUTHOR":  author,
-			"NOTIFICATIONCOMMENT": data,
-		}
-		cmdLine := ExpandMacros(cmd.CommandLine, macros)
-		logMsg := "HOST NOTIFICATION: " + contact.Name + ";" + hst.Name + ";" + typeName + ";" + cmd.Name + ";" + hst.PluginOutput
+	macros := map[string]string{
+		"NOTIFICATIONTYPE":    typeName,
+		"CONTACTNAME":        contact.Name,
+		"CONTACTEMAIL":       contact.Email,
+		"CONTACTPAGER":       contact.Pager,
+		"HOSTNAME":           hst.Name,
+		"HOSTALIAS":          hst.Alias,
+		"HOSTADDRESS":        hst.Address,
+		"HOSTSTATE":          objects.HostStateName(hst.CurrentState),
+		"HOSTSTATETYPE":      objects.StateTypeName(hst.StateType),
+		"HOSTATTEMPT":        itoa(hst.CurrentAttempt),
+		"MAXHOSTATTEMPTS":    itoa(hst.MaxCheckAttempts),
+		"HOSTOUTPUT":         hst.PluginOutput,
+		"LONGHOSTOUTPUT":     hst.LongPluginOutput,
+		"HOSTEXECUTIONID":    fmt.Sprint(hst.ExecutionID),
+		"NOTIFICATIONAUTHOR":  author,
+		"NOTIFICATIONCOMMENT": data,
+	}
+	ne.addReferencedMacros(macros, contact.HostNotificationCommands, contact.HostNotificationFallback, hst, nil)
+	logMsg := func(cmd *objects.Command) string {
+		msg := "HOST NOTIFICATION: " + contact.Name + ";" + hst.Name + ";" + typeName + ";" + cmd.Name + ";" + hst.PluginOutput
 		if ntype == objects.NotificationCustom || ntype == objects.NotificationAcknowledgement {
-			logMsg += ";" + author + ";" + data
+			msg += ";" + author + ";" + data
+		}
+		return msg
+	}
+	if contact.NotificationDigest > 0 {
+		ne.queueDigest(contact, digestEntry{cmds: contact.HostNotificationCommands,
+			fallback: contact.HostNotificationFallback, macros: macros, logMsg: logMsg})
+	} else {
+		ne.runContactCommands(contact, contact.HostNotificationCommands, contact.HostNotificationFallback, macros, logMsg)
+	}
+	now := time.Now()
+	contact.LastHostNotification = now
+	countNotification(contact, &hst.NotificationCounts, now)
+}
+
+// runContactCommands logs and runs a contact's notification commands. With
+// a fallback chain the commands form its first step and the fallback steps
+// are only logged if they end up running.
+func (ne *NotificationEngine) runContactCommands(contact *objects.Contact, cmds []*objects.Command, fallback []objects.NotificationStep, macros map[string]string, logMsg func(*objects.Command) string) {
+	if len(fallback) == 0 {
+		for _, cmd := range cmds {
+			ne.log(logMsg(cmd))
+			ne.CmdExecutor.Execute(cmd.Name, ExpandMacros(cmd.CommandLine, macros))
+		}
+		return
+	}
+	steps := make([]ChainStep, 0, len(fallback)+1)
+	if len(cmds) > 0 {
+		var first ChainStep
+		for _, cmd := range cmds {
+			ne.log(logMsg(cmd))
+			first.Commands = append(first.Commands, ChainCommand{Name: cmd.Name, CmdLine: ExpandMacros(cmd.CommandLine, macros)})
+		}
+		steps = append(steps, first)
+	} else {
+		for _, cmd := range fallback[0].Commands {
+			ne.log(logMsg(cmd))
+		}
+	}
+	for i, fs := range fallback {
+		step := ChainStep{Timeout: fs.Timeout}
+		for _, cmd := range fs.Commands {
+			cc := ChainCommand{Name: cmd.Name, CmdLine: ExpandMacros(cmd.CommandLine, macros)}
+			if len(steps) > 0 || i > 0 {
+				cc.LogMsg = logMsg(cmd)
+			}
+			step.Commands = append(step.Commands, cc)
 		}
-		ne.log(logMsg)
+		steps = append(steps, step)
+	}
+	ne.CmdExecutor.ExecuteChain("contact '"+contact.Name+"'", steps)
+}
 
-		ne.CmdExecutor.Execute(cmdLine)
+// addReferencedMacros adds to m the macros the commands use that m lacks,
+// resolved through ne.Macros. Costly macros such as $HOSTGROUPMEMBERS$ or
+// $TOTALSERVICESCRITICAL$ are only computed when a command asks for them.
+func (ne *NotificationEngine) addReferencedMacros(m map[string]string, cmds []*objects.Command, fallback []objects.NotificationStep, host *objects.Host, svc *objects.Service) {
+	if ne.Macros == nil {
+		return
+	}
+	add := func(cmdLine string) {
+		for rest := cmdLine; ; {
+			start := strings.IndexByte(rest, '$')
+			if start < 0 {
+				return
+			}
+			end := strings.IndexByte(rest[start+1:], '$')
+			if end < 0 {
+				return
+			}
+			name := rest[start+1 : start+1+end]
+			rest = rest[start+end+2:]
+			if name == "" {
+				continue
+			}
+			if _, ok := m[name]; ok {
+				continue
+			}
+			if v, ok := ne.Macros.Resolve(name, host, svc); ok {
+				m[name] = v
+			}
+		}
+	}
+	for _, cmd := range cmds {
+		add(cmd.CommandLine)
+	}
+	for _, step := range fallback {
+		for _, cmd := range step.Commands {
+			add(cmd.CommandLine)
+		}
 	}
-	contact.LastHostNotification = time.Now()
 }
 
 func (ne *NotificationEngine) softStateDeps() bool {
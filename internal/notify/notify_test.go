@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/macros"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -211,6 +212,229 @@ func TestHostNotification_BasicFlow(t *testing.T) {
 	}
 }
 
+// TestServiceNotification_ResumesAfterDowntime follows a problem through a
+// downtime spanning several notification intervals. Each check result
+// attempts a notification, as the result handler does for a continued HARD
+// problem. The attempts made during the downtime are suppressed without
+// using up notification numbers, so the first notification after it ends is
+// the next number, goes out at once, and reaches the escalation that number
+// is due for.
+func TestServiceNotification_ResumesAfterDowntime(t *testing.T) {
+	ne := newTestEngine()
+	newContact := func(name string) *objects.Contact {
+		return &objects.Contact{
+			Name:                        name,
+			ServiceNotificationsEnabled: true,
+			ServiceNotificationOptions:  objects.OptCritical | objects.OptRecovery,
+			ServiceNotificationCommands: []*objects.Command{{Name: "notify", CommandLine: "true"}},
+		}
+	}
+	admin, oncall := newContact("admin"), newContact("oncall")
+	svc := &objects.Service{
+		Host:                 &objects.Host{Name: "h1", CurrentState: objects.HostUp},
+		Description:          "HTTP",
+		CurrentState:         objects.ServiceCritical,
+		StateType:            objects.StateTypeHard,
+		NotificationsEnabled: true,
+		NotificationOptions:  objects.OptCritical | objects.OptRecovery,
+		NotificationInterval: 5,
+		Contacts:             []*objects.Contact{admin},
+	}
+	svc.Escalations = []*objects.ServiceEscalation{{
+		FirstNotification:    2,
+		Contacts:             []*objects.Contact{oncall},
+		NotificationInterval: -1,
+	}}
+	// notifyResult attempts a notification and reports who received it.
+	notifyResult := func() []string {
+		admin.LastServiceNotification, oncall.LastServiceNotification = time.Time{}, time.Time{}
+		ne.ServiceNotification(svc, objects.NotificationNormal, "", "", 0)
+		var got []string
+		for _, c := range []*objects.Contact{admin, oncall} {
+			if !c.LastServiceNotification.IsZero() {
+				got = append(got, c.Name)
+			}
+		}
+		return got
+	}
+	// intervalPasses moves the next notification time into the past, as
+	// if notification_interval had elapsed.
+	intervalPasses := func() { svc.NextNotification = time.Now().Add(-time.Second) }
+
+	if got := notifyResult(); len(got) != 1 || got[0] != "admin" || svc.CurrentNotificationNumber != 1 {
+		t.Fatalf("first notification: sent to %v, number %d", got, svc.CurrentNotificationNumber)
+	}
+
+	svc.ScheduledDowntimeDepth = 1
+	for i := 0; i < 3; i++ {
+		intervalPasses()
+		if got := notifyResult(); len(got) != 0 {
+			t.Fatalf("notification sent to %v during downtime", got)
+		}
+	}
+	if svc.CurrentNotificationNumber != 1 {
+		t.Fatalf("notification number %d after downtime, want 1", svc.CurrentNotificationNumber)
+	}
+
+	svc.ScheduledDowntimeDepth = 0
+	if got := notifyResult(); len(got) != 1 || got[0] != "oncall" || svc.CurrentNotificationNumber != 2 {
+		t.Fatalf("first notification after downtime: sent to %v, number %d", got, svc.CurrentNotificationNumber)
+	}
+	if want := time.Now().Add(5 * time.Minute); svc.NextNotification.Before(want.Add(-time.Minute)) || svc.NextNotification.After(want) {
+		t.Errorf("next notification at %v, want about %v", svc.NextNotification, want)
+	}
+	if got := notifyResult(); len(got) != 0 {
+		t.Errorf("notification sent to %v before the interval passed", got)
+	}
+	intervalPasses()
+	if got := notifyResult(); len(got) != 1 || svc.CurrentNotificationNumber != 3 {
+		t.Errorf("re-notification: sent to %v, number %d", got, svc.CurrentNotificationNumber)
+	}
+}
+
+func TestHostNotification_ResumesAfterDowntime(t *testing.T) {
+	ne := newTestEngine()
+	hst := &objects.Host{
+		Name:                 "h1",
+		CurrentState:         objects.HostDown,
+		StateType:            objects.StateTypeHard,
+		NotificationsEnabled: true,
+		NotificationOptions:  objects.OptDown | objects.OptRecovery,
+		NotificationInterval: 5,
+		Contacts: []*objects.Contact{{
+			Name:                     "admin",
+			HostNotificationsEnabled: true,
+			HostNotificationOptions:  objects.OptDown | objects.OptRecovery,
+			HostNotificationCommands: []*objects.Command{{Name: "notify", CommandLine: "true"}},
+		}},
+	}
+
+	ne.HostNotification(hst, objects.NotificationNormal, "", "", 0)
+	hst.ScheduledDowntimeDepth = 1
+	for i := 0; i < 3; i++ {
+		hst.NextNotification = time.Now().Add(-time.Second)
+		ne.HostNotification(hst, objects.NotificationNormal, "", "", 0)
+	}
+	if hst.CurrentNotificationNumber != 1 {
+		t.Fatalf("notification number %d after downtime, want 1", hst.CurrentNotificationNumber)
+	}
+	hst.ScheduledDowntimeDepth = 0
+	ne.HostNotification(hst, objects.NotificationNormal, "", "", 0)
+	if hst.CurrentNotificationNumber != 2 || !hst.NextNotification.After(time.Now()) {
+		t.Errorf("after downtime: number %d, next notification %v", hst.CurrentNotificationNumber, hst.NextNotification)
+	}
+}
+
+func TestRenotifyService(t *testing.T) {
+	ne := newTestEngine()
+	var scheduled []time.Time
+	ne.OnNextNotification = func(h *objects.Host, svc *objects.Service) {
+		scheduled = append(scheduled, svc.NextNotification)
+	}
+	contact := &objects.Contact{
+		Name:                        "admin",
+		ServiceNotificationsEnabled: true,
+		ServiceNotificationOptions:  objects.OptCritical | objects.OptRecovery,
+		ServiceNotificationCommands: []*objects.Command{{Name: "notify", CommandLine: "true"}},
+	}
+	svc := &objects.Service{
+		Host:                 &objects.Host{Name: "h1", CurrentState: objects.HostUp},
+		Description:          "HTTP",
+		CurrentState:         objects.ServiceCritical,
+		StateType:            objects.StateTypeHard,
+		NotificationsEnabled: true,
+		NotificationOptions:  objects.OptCritical | objects.OptRecovery,
+		NotificationInterval: 30,
+		Contacts:             []*objects.Contact{contact},
+	}
+	// Notifications 2-3 escalate to every 10 minutes; from 4 on there are
+	// no more.
+	svc.Escalations = []*objects.ServiceEscalation{
+		{FirstNotification: 2, LastNotification: 3, NotificationInterval: 10},
+		{FirstNotification: 4, NotificationInterval: 0},
+	}
+	// about reports whether at is about d from now.
+	about := func(at time.Time, d time.Duration) bool {
+		return at.After(time.Now().Add(d-time.Minute)) && !at.After(time.Now().Add(d))
+	}
+
+	ne.ServiceNotification(svc, objects.NotificationNormal, "", "", 0)
+	if len(scheduled) != 1 || !about(scheduled[0], 30*time.Minute) {
+		t.Fatalf("after notification 1, scheduled %v", scheduled)
+	}
+	if ne.RenotifyService(svc) == 0 || svc.CurrentNotificationNumber != 1 {
+		t.Fatal("renotified before next_notification")
+	}
+
+	svc.NextNotification = time.Now().Add(-time.Second)
+	if ne.RenotifyService(svc) != 0 || svc.CurrentNotificationNumber != 2 {
+		t.Fatalf("renotification not sent: number %d", svc.CurrentNotificationNumber)
+	}
+	if len(scheduled) != 2 || !about(scheduled[1], 10*time.Minute) {
+		t.Fatalf("after notification 2, scheduled %v; want the escalation's 10 minutes", scheduled)
+	}
+
+	svc.NextNotification = time.Now().Add(-time.Second)
+	ne.RenotifyService(svc)
+	svc.NextNotification = time.Now().Add(-time.Second)
+	ne.RenotifyService(svc)
+	if svc.CurrentNotificationNumber != 4 || !svc.NoMoreNotifications || len(scheduled) != 3 {
+		t.Fatalf("notification 4: no_more_notifications=%v, %d scheduled", svc.NoMoreNotifications, len(scheduled))
+	}
+	svc.NextNotification = time.Now().Add(-time.Second)
+	if ne.RenotifyService(svc) == 0 || svc.CurrentNotificationNumber != 4 {
+		t.Error("renotified after no_more_notifications")
+	}
+
+	// A recovered service is not renotified.
+	svc.CurrentState = objects.ServiceOK
+	svc.NoMoreNotifications = false
+	if ne.RenotifyService(svc) == 0 {
+		t.Error("renotified a recovered service")
+	}
+}
+
+func TestRenotifyHost(t *testing.T) {
+	ne := newTestEngine()
+	scheduled := 0
+	ne.OnNextNotification = func(h *objects.Host, svc *objects.Service) {
+		if svc != nil {
+			t.Errorf("host renotification scheduled for service %q", svc.Description)
+		}
+		scheduled++
+	}
+	hst := &objects.Host{
+		Name:                 "h1",
+		CurrentState:         objects.HostDown,
+		StateType:            objects.StateTypeHard,
+		NotificationsEnabled: true,
+		NotificationOptions:  objects.OptDown | objects.OptRecovery,
+		NotificationInterval: 0,
+		Contacts: []*objects.Contact{{
+			Name:                     "admin",
+			HostNotificationsEnabled: true,
+			HostNotificationOptions:  objects.OptDown | objects.OptRecovery,
+			HostNotificationCommands: []*objects.Command{{Name: "notify", CommandLine: "true"}},
+		}},
+	}
+
+	// notification_interval 0 notifies once.
+	ne.HostNotification(hst, objects.NotificationNormal, "", "", 0)
+	if scheduled != 0 || !hst.NoMoreNotifications {
+		t.Fatalf("interval 0: %d scheduled, no_more_notifications=%v", scheduled, hst.NoMoreNotifications)
+	}
+	if ne.RenotifyHost(hst) == 0 {
+		t.Error("renotified with notification_interval 0")
+	}
+
+	hst.NotificationInterval = 5
+	hst.NoMoreNotifications = false
+	hst.NextNotification = time.Now().Add(-time.Second)
+	if ne.RenotifyHost(hst) != 0 || hst.CurrentNotificationNumber != 2 || scheduled != 1 {
+		t.Errorf("renotification: number %d, %d scheduled", hst.CurrentNotificationNumber, scheduled)
+	}
+}
+
 func TestEscalation_ValidRange(t *testing.T) {
 	svc := &objects.Service{
 		CurrentState:              objects.ServiceCritical,
@@ -351,3 +575,54 @@ func TestCreateNotificationList_Broadcast(t *testing.T) {
 		t.Errorf("expected 2 contacts with broadcast, got %d", len(list))
 	}
 }
+
+func TestAddReferencedMacros(t *testing.T) {
+	ne := newTestEngine()
+	host := &objects.Host{Name: "web1", HostGroups: []*objects.HostGroup{{Name: "web"}}}
+	m := map[string]string{"HOSTNAME": "web1"}
+	cmds := []*objects.Command{{Name: "notify", CommandLine: "notify $HOSTNAME$ $HOSTGROUPNAME$"}}
+	fallback := []objects.NotificationStep{{Commands: []*objects.Command{{Name: "page", CommandLine: "page $$ $HOSTGROUPNAMES$ $NOSUCHMACRO$"}}}}
+
+	ne.addReferencedMacros(m, cmds, fallback, host, nil)
+	if len(m) != 1 {
+		t.Fatalf("expected no macros added without an expander, got %v", m)
+	}
+
+	ne.Macros = &macros.Expander{Cfg: objects.DefaultConfig()}
+	ne.addReferencedMacros(m, cmds, fallback, host, nil)
+	if m["HOSTGROUPNAME"] != "web" || m["HOSTGROUPNAMES"] != "web" {
+		t.Errorf("group macros not added: %v", m)
+	}
+	if _, ok := m["NOSUCHMACRO"]; ok || len(m) != 3 {
+		t.Errorf("unexpected macros added: %v", m)
+	}
+}
+
+// TestServiceNotification_VolatileIgnoresInterval checks that a volatile
+// service in a HARD problem state notifies on every result, while another
+// service waits for notification_interval.
+func TestServiceNotification_VolatileIgnoresInterval(t *testing.T) {
+	ne := newTestEngine()
+	svc := &objects.Service{
+		Host:                      &objects.Host{Name: "h1", CurrentState: objects.HostUp},
+		Description:               "Security",
+		NotificationsEnabled:      true,
+		CurrentState:              objects.ServiceCritical,
+		StateType:                 objects.StateTypeHard,
+		NotificationOptions:       objects.OptCritical,
+		NotificationInterval:      30,
+		CurrentNotificationNumber: 1,
+		NextNotification:          time.Now().Add(30 * time.Minute),
+	}
+	if ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) == 0 {
+		t.Error("expected a non-volatile service to wait for notification_interval")
+	}
+	svc.IsVolatile = true
+	if ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) != 0 {
+		t.Error("expected a volatile service to notify before notification_interval")
+	}
+	svc.StateType = objects.StateTypeSoft
+	if ne.checkServiceNotificationViability(svc, objects.NotificationNormal, 0) == 0 {
+		t.Error("expected a volatile service in a SOFT state not to notify")
+	}
+}
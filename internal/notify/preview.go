@@ -0,0 +1,251 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/config"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// EscalationPreview explains who a hypothetical problem notification would
+// reach, so on-call chains can be validated before an outage. It evaluates
+// the same escalation and contact filters as the notification engine, but
+// for a given notification number, state and time instead of the object's
+// current ones. Object-level viability (downtime, acknowledgement,
+// notification interval, dependencies) is not simulated.
+type EscalationPreview struct {
+	Host               string
+	Service            string // empty for host previews
+	NotificationNumber int
+	State              int
+	At                 time.Time
+
+	// Notes lists object-level settings that would suppress the
+	// notification before contacts are considered.
+	Notes []string
+
+	// Escalated is true when at least one escalation applies, in which case
+	// escalation contacts replace the object's own contacts.
+	Escalated bool
+	Steps     []EscalationStep
+	Contacts  []PreviewContact
+}
+
+// EscalationStep is the verdict for one escalation definition.
+type EscalationStep struct {
+	FirstNotification int
+	LastNotification  int // 0 = open-ended
+	Interval          float64
+	Period            string
+	Applies           bool
+	Reason            string // why the step does not apply
+}
+
+// PreviewContact is one candidate recipient.
+type PreviewContact struct {
+	Name     string
+	Via      string // e.g. "escalation 2 (3-5), contact group oncall"
+	Notified bool
+	Reason   string // why the contact is filtered out
+}
+
+// PreviewServiceEscalation previews notification notifNum for svc in state
+// at the given time.
+func PreviewServiceEscalation(svc *objects.Service, notifNum, state int, at time.Time) *EscalationPreview {
+	p := &EscalationPreview{
+		Service:            svc.Description,
+		NotificationNumber: notifNum,
+		State:              state,
+		At:                 at,
+	}
+	if svc.Host != nil {
+		p.Host = svc.Host.Name
+	}
+	if !svc.NotificationsEnabled {
+		p.Notes = append(p.Notes, "notifications are disabled for this service")
+	}
+	if svc.NotificationOptions != 0 && !objects.StateMatchesSvcOptions(state, svc.NotificationOptions) {
+		p.Notes = append(p.Notes, "notification_options exclude "+objects.ServiceStateName(state))
+	}
+	if svc.NotificationPeriod != nil && !config.CheckTime(svc.NotificationPeriod, at) {
+		p.Notes = append(p.Notes, "outside notification_period "+svc.NotificationPeriod.Name)
+	}
+
+	var escContacts [][]viaContact
+	for i, esc := range svc.AllEscalations() {
+		step := newEscalationStep(esc.FirstNotification, esc.LastNotification, esc.NotificationInterval, esc.EscalationPeriod)
+		step.Reason = serviceEscalationReason(state, esc, notifNum, 0, at)
+		step.Applies = step.Reason == ""
+		p.Steps = append(p.Steps, step)
+		contacts, groups := esc.ContactsFor(svc)
+		escContacts = append(escContacts, collectContacts(step.label(i+1), contacts, groups))
+	}
+	own := collectContacts("service", svc.Contacts, svc.ContactGroups)
+
+	p.resolveContacts(escContacts, own, func(c *objects.Contact) string {
+		if reason := contactServiceFilterReason(c, svc.HourlyValue, at); reason != "" {
+			return reason
+		}
+		return contactServiceStateReason(c, state)
+	})
+	return p
+}
+
+// PreviewHostEscalation previews notification notifNum for hst in state at
+// the given time.
+func PreviewHostEscalation(hst *objects.Host, notifNum, state int, at time.Time) *EscalationPreview {
+	p := &EscalationPreview{
+		Host:               hst.Name,
+		NotificationNumber: notifNum,
+		State:              state,
+		At:                 at,
+	}
+	if !hst.NotificationsEnabled {
+		p.Notes = append(p.Notes, "notifications are disabled for this host")
+	}
+	if hst.NotificationOptions != 0 && !objects.StateMatchesHostOptions(state, hst.NotificationOptions) {
+		p.Notes = append(p.Notes, "notification_options exclude "+objects.HostStateName(state))
+	}
+	if hst.NotificationPeriod != nil && !config.CheckTime(hst.NotificationPeriod, at) {
+		p.Notes = append(p.Notes, "outside notification_period "+hst.NotificationPeriod.Name)
+	}
+
+	var escContacts [][]viaContact
+	for i, esc := range hst.AllEscalations() {
+		step := newEscalationStep(esc.FirstNotification, esc.LastNotification, esc.NotificationInterval, esc.EscalationPeriod)
+		step.Reason = hostEscalationReason(state, esc, notifNum, 0, at)
+		step.Applies = step.Reason == ""
+		p.Steps = append(p.Steps, step)
+		contacts, groups := esc.ContactsFor(hst)
+		escContacts = append(escContacts, collectContacts(step.label(i+1), contacts, groups))
+	}
+	own := collectContacts("host", hst.Contacts, hst.ContactGroups)
+
+	p.resolveContacts(escContacts, own, func(c *objects.Contact) string {
+		if reason := contactHostFilterReason(c, hst.HourlyValue, at); reason != "" {
+			return reason
+		}
+		return contactHostStateReason(c, state)
+	})
+	return p
+}
+
+// LastEscalationNotification returns the highest notification number named
+// by any step, useful for walking a whole escalation chain.
+func LastEscalationNotification(steps []EscalationStep) int {
+	last := 0
+	for _, s := range steps {
+		last = max(last, s.FirstNotification, s.LastNotification)
+	}
+	return last
+}
+
+type viaContact struct {
+	contact *objects.Contact
+	via     string
+}
+
+func collectContacts(source string, contacts []*objects.Contact, groups []*objects.ContactGroup) []viaContact {
+	var out []viaContact
+	for _, c := range contacts {
+		out = append(out, viaContact{c, source + " contacts"})
+	}
+	for _, cg := range groups {
+		for _, c := range cg.Members {
+			out = append(out, viaContact{c, source + ", contact group " + cg.Name})
+		}
+	}
+	return out
+}
+
+// resolveContacts mirrors createServiceNotificationList: applicable
+// escalations replace the object's contacts, and each contact appears once.
+func (p *EscalationPreview) resolveContacts(escContacts [][]viaContact, own []viaContact, filter func(*objects.Contact) string) {
+	var candidates []viaContact
+	for i, step := range p.Steps {
+		if step.Applies {
+			p.Escalated = true
+			candidates = append(candidates, escContacts[i]...)
+		}
+	}
+	if !p.Escalated {
+		candidates = own
+	}
+	seen := make(map[string]bool)
+	for _, vc := range candidates {
+		if seen[vc.contact.Name] {
+			continue
+		}
+		seen[vc.contact.Name] = true
+		reason := filter(vc.contact)
+		p.Contacts = append(p.Contacts, PreviewContact{
+			Name:     vc.contact.Name,
+			Via:      vc.via,
+			Notified: reason == "",
+			Reason:   reason,
+		})
+	}
+}
+
+func newEscalationStep(first, last int, interval float64, period *objects.Timeperiod) EscalationStep {
+	step := EscalationStep{FirstNotification: first, LastNotification: last, Interval: interval}
+	if period != nil {
+		step.Period = period.Name
+	}
+	return step
+}
+
+func (s EscalationStep) label(n int) string {
+	last := "*"
+	if s.LastNotification > 0 {
+		last = itoa(s.LastNotification)
+	}
+	return fmt.Sprintf("escalation %d (%d-%s)", n, s.FirstNotification, last)
+}
+
+// String renders the preview as a human-readable report.
+func (p *EscalationPreview) String() string {
+	var b strings.Builder
+	target, state := "Host '"+p.Host+"'", objects.HostStateName(p.State)
+	if p.Service != "" {
+		target = "Service '" + p.Service + "' on host '" + p.Host + "'"
+		state = objects.ServiceStateName(p.State)
+	}
+	fmt.Fprintf(&b, "%s, notification #%d, state %s, at %s\n", target, p.NotificationNumber, state, p.At.Format(time.RFC1123))
+	for _, n := range p.Notes {
+		fmt.Fprintf(&b, "  Warning: %s\n", n)
+	}
+	if len(p.Steps) == 0 {
+		b.WriteString("  No escalations defined\n")
+	}
+	for i, s := range p.Steps {
+		verdict := "applies"
+		if !s.Applies {
+			verdict = "skipped: " + s.Reason
+		}
+		fmt.Fprintf(&b, "  %s: %s", s.label(i+1), verdict)
+		if s.Period != "" {
+			fmt.Fprintf(&b, " [period %s]", s.Period)
+		}
+		if s.Interval >= 0 {
+			fmt.Fprintf(&b, " [interval %g]", s.Interval)
+		}
+		b.WriteByte('\n')
+	}
+	if !p.Escalated {
+		b.WriteString("  Not escalated, using the object's own contacts\n")
+	}
+	if len(p.Contacts) == 0 {
+		b.WriteString("  No contacts would be notified\n")
+	}
+	for _, c := range p.Contacts {
+		if c.Notified {
+			fmt.Fprintf(&b, "  NOTIFY %s (via %s)\n", c.Name, c.Via)
+		} else {
+			fmt.Fprintf(&b, "  SKIP   %s (via %s): %s\n", c.Name, c.Via, c.Reason)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestPreviewServiceEscalation(t *testing.T) {
+	oncall := &objects.Contact{Name: "oncall", ServiceNotificationsEnabled: true, ServiceNotificationOptions: objects.OptCritical | objects.OptRecovery}
+	lead := &objects.Contact{Name: "lead", ServiceNotificationsEnabled: true, ServiceNotificationOptions: objects.OptCritical}
+	quiet := &objects.Contact{Name: "quiet", ServiceNotificationOptions: objects.OptCritical}
+	svc := &objects.Service{
+		Host:                 &objects.Host{Name: "web01"},
+		Description:          "HTTP",
+		NotificationsEnabled: true,
+		Contacts:             []*objects.Contact{oncall},
+		Escalations: []*objects.ServiceEscalation{{
+			FirstNotification:    3,
+			LastNotification:     5,
+			NotificationInterval: -1,
+			ContactGroups:        []*objects.ContactGroup{{Name: "leads", Members: []*objects.Contact{lead, quiet}}},
+		}},
+	}
+
+	p := PreviewServiceEscalation(svc, 1, objects.ServiceCritical, time.Now())
+	if p.Escalated || len(p.Contacts) != 1 || p.Contacts[0].Name != "oncall" || !p.Contacts[0].Notified {
+		t.Errorf("notification 1: expected only oncall, got %+v", p.Contacts)
+	}
+	if !strings.Contains(p.Steps[0].Reason, "before first_notification 3") {
+		t.Errorf("unexpected step reason %q", p.Steps[0].Reason)
+	}
+
+	p = PreviewServiceEscalation(svc, 3, objects.ServiceCritical, time.Now())
+	if !p.Escalated || len(p.Contacts) != 2 {
+		t.Fatalf("notification 3: expected 2 escalation contacts, got %+v", p.Contacts)
+	}
+	if !p.Contacts[0].Notified || p.Contacts[0].Via != "escalation 1 (3-5), contact group leads" {
+		t.Errorf("unexpected lead verdict %+v", p.Contacts[0])
+	}
+	if p.Contacts[1].Notified || p.Contacts[1].Reason != "service notifications disabled" {
+		t.Errorf("unexpected quiet verdict %+v", p.Contacts[1])
+	}
+
+	if got := LastEscalationNotification(p.Steps); got != 5 {
+		t.Errorf("LastEscalationNotification = %d, want 5", got)
+	}
+	if out := p.String(); !strings.Contains(out, "SKIP   quiet") || !strings.Contains(out, "NOTIFY lead") {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+}
+
+func TestPreviewHostEscalation_StateFilter(t *testing.T) {
+	c := &objects.Contact{Name: "admin", HostNotificationsEnabled: true, HostNotificationOptions: objects.OptDown}
+	hst := &objects.Host{
+		Name:                 "db01",
+		NotificationsEnabled: true,
+		NotificationOptions:  objects.OptDown,
+		Contacts:             []*objects.Contact{c},
+	}
+	p := PreviewHostEscalation(hst, 1, objects.HostUnreachable, time.Now())
+	if len(p.Notes) != 1 || len(p.Contacts) != 1 || p.Contacts[0].Notified {
+		t.Errorf("expected unreachable to be filtered, got notes=%v contacts=%+v", p.Notes, p.Contacts)
+	}
+}
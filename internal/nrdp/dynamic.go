@@ -100,6 +100,7 @@ func (d *DynamicTracker) EnsureHost(hostname string) {
 				if cmd := d.store.GetCommand("check_dummy"); cmd != nil {
 					existing.CheckCommand = cmd
 					existing.CheckCommandArgs = "0!OK"
+					existing.InvalidateChecks()
 				}
 			}
 			if !existing.HasBeenChecked {
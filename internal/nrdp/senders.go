@@ -0,0 +1,178 @@
+package nrdp
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SenderStatus describes one NRDP sender, identified by its IP address.
+type SenderStatus struct {
+	Sender   string    `json:"sender"`
+	Expected bool      `json:"expected"`  // listed in nrdp_expected_senders
+	LastSeen time.Time `json:"last_seen"` // zero if it has not submitted since start
+	Results  int64     `json:"results"`   // results submitted by it since start
+	Stale    bool      `json:"stale"`     // silent for longer than the threshold
+}
+
+// SenderTracker records when each sender last submitted results, so a
+// sender that stops submitting altogether can be noticed even when the
+// objects it feeds have no freshness checks. Senders listed as expected are
+// tracked from startup; any other sender is tracked from its first
+// submission.
+type SenderTracker struct {
+	threshold time.Duration
+	mu        sync.Mutex
+	senders   map[string]*senderState
+	start     time.Time
+	now       func() time.Time
+	logFunc   func(string, ...interface{})
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+type senderState struct {
+	expected bool
+	lastSeen time.Time
+	results  int64
+	stale    bool // as last logged
+}
+
+// NewSenderTracker tracks senders, flagging those silent for longer than
+// threshold. expected lists sender addresses that should be submitting.
+func NewSenderTracker(threshold time.Duration, expected []string) *SenderTracker {
+	t := &SenderTracker{
+		threshold: threshold,
+		senders:   make(map[string]*senderState),
+		start:     time.Now(),
+		now:       time.Now,
+		logFunc:   func(string, ...interface{}) {},
+		stopCh:    make(chan struct{}),
+	}
+	for _, s := range expected {
+		t.senders[s] = &senderState{expected: true}
+	}
+	return t
+}
+
+// SetLogger sets the function stale and resumed senders are logged with.
+func (t *SenderTracker) SetLogger(fn func(string, ...interface{})) {
+	t.logFunc = fn
+}
+
+// Record notes that sender submitted n results.
+func (t *SenderTracker) Record(sender string, n int) {
+	t.mu.Lock()
+	st, ok := t.senders[sender]
+	if !ok {
+		st = &senderState{}
+		t.senders[sender] = st
+	}
+	st.lastSeen = t.now()
+	st.results += int64(n)
+	resumed := st.stale
+	st.stale = false
+	t.mu.Unlock()
+	if resumed {
+		t.logFunc("NRDP sender %s resumed submitting results", sender)
+	}
+}
+
+// Senders returns every tracked sender, by address.
+func (t *SenderTracker) Senders() []SenderStatus {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]SenderStatus, 0, len(t.senders))
+	for name, st := range t.senders {
+		out = append(out, SenderStatus{
+			Sender:   name,
+			Expected: st.expected,
+			LastSeen: st.lastSeen,
+			Results:  st.results,
+			Stale:    t.isStale(st, now),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Sender < out[j].Sender })
+	return out
+}
+
+// Threshold returns how long a sender may stay silent.
+func (t *SenderTracker) Threshold() time.Duration {
+	return t.threshold
+}
+
+// isStale reports whether st has been silent too long. An expected sender
+// that has not submitted yet counts from startup. The caller holds t.mu.
+func (t *SenderTracker) isStale(st *senderState, now time.Time) bool {
+	last := st.lastSeen
+	if last.IsZero() {
+		last = t.start
+	}
+	return now.Sub(last) > t.threshold
+}
+
+// check logs senders that have gone stale since the last check.
+func (t *SenderTracker) check() {
+	now := t.now()
+	type silent struct {
+		sender string
+		since  time.Duration
+	}
+	var newlyStale []silent
+	t.mu.Lock()
+	for name, st := range t.senders {
+		if st.stale || !t.isStale(st, now) {
+			continue
+		}
+		st.stale = true
+		last := st.lastSeen
+		if last.IsZero() {
+			last = t.start
+		}
+		newlyStale = append(newlyStale, silent{name, now.Sub(last)})
+	}
+	t.mu.Unlock()
+	sort.Slice(newlyStale, func(i, j int) bool { return newlyStale[i].sender < newlyStale[j].sender })
+	for _, s := range newlyStale {
+		t.logFunc("Warning: NRDP sender %s has not submitted results for %.0fs", s.sender, s.since.Seconds())
+	}
+}
+
+// Start checks for stale senders in the background, a few times per
+// threshold and at least once a minute.
+func (t *SenderTracker) Start() {
+	interval := t.threshold / 4
+	if interval > time.Minute || interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.check()
+			case <-t.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Handler serves Senders as JSON, for the debug listener.
+func (t *SenderTracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(t.Senders())
+	})
+}
+
+// Stop ends the background check.
+func (t *SenderTracker) Stop() {
+	t.stopOnce.Do(func() { close(t.stopCh) })
+}
@@ -0,0 +1,76 @@
+package nrdp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestSenderTrackerStaleAndResumed(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tr := NewSenderTracker(10*time.Minute, []string{"10.0.0.5"})
+	tr.start = now
+	tr.now = func() time.Time { return now }
+	var logged []string
+	tr.SetLogger(func(format string, args ...interface{}) { logged = append(logged, fmt.Sprintf(format, args...)) })
+
+	tr.Record("10.0.0.9", 3)
+	now = now.Add(5 * time.Minute)
+	tr.Record("10.0.0.9", 2)
+
+	now = now.Add(6 * time.Minute)
+	tr.check()
+	senders := tr.Senders()
+	if len(senders) != 2 {
+		t.Fatalf("expected 2 senders, got %+v", senders)
+	}
+	// The expected sender never submitted and counts from startup.
+	if s := senders[0]; s.Sender != "10.0.0.5" || !s.Expected || !s.Stale || !s.LastSeen.IsZero() {
+		t.Errorf("expected 10.0.0.5 to be stale, got %+v", s)
+	}
+	if s := senders[1]; s.Sender != "10.0.0.9" || s.Stale || s.Results != 5 {
+		t.Errorf("expected 10.0.0.9 to be fresh with 5 results, got %+v", s)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "NRDP sender 10.0.0.5 has not submitted results for 660s") {
+		t.Errorf("expected one stale warning, got %q", logged)
+	}
+
+	tr.check()
+	if len(logged) != 1 {
+		t.Errorf("expected a stale sender to be logged once, got %q", logged)
+	}
+
+	tr.Record("10.0.0.5", 1)
+	if len(logged) != 2 || !strings.Contains(logged[1], "NRDP sender 10.0.0.5 resumed") {
+		t.Errorf("expected a resumed message, got %q", logged)
+	}
+	if s := tr.Senders()[0]; s.Stale {
+		t.Errorf("expected 10.0.0.5 to be fresh after submitting, got %+v", s)
+	}
+}
+
+func TestServerRecordsSenders(t *testing.T) {
+	s := New(Config{Path: "/nrdp/", SenderStaleThreshold: time.Minute}, objects.NewObjectStore(),
+		make(chan *objects.CheckResult, 10), testLogger(t))
+	body := strings.NewReader(`{"checkresults":[{"type":"service","hostname":"h","servicename":"s","status":0,"output":"ok"},` +
+		`{"type":"host","hostname":"h","status":0,"output":"ok"}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/nrdp/", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "127.0.0.1:12345"
+	w := httptest.NewRecorder()
+	s.handleNRDP(w, req)
+
+	senders := s.Senders().Senders()
+	if len(senders) != 1 || senders[0].Sender != "127.0.0.1" || senders[0].Results != 2 {
+		t.Errorf("expected 2 results from 127.0.0.1, got %+v", senders)
+	}
+
+	if New(Config{}, objects.NewObjectStore(), nil, testLogger(t)).Senders() != nil {
+		t.Error("expected no sender tracking without a threshold")
+	}
+}
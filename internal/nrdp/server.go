@@ -3,13 +3,16 @@ package nrdp
 import (
 	"context"
 	"crypto/subtle"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/listenaddr"
 	"github.com/oceanplexian/gogios/internal/logging"
 	"github.com/oceanplexian/gogios/internal/objects"
 
@@ -18,7 +21,7 @@ import (
 
 // Config holds the NRDP server configuration.
 type Config struct {
-	Listen         string // e.g. ":5668"
+	Listen         string // e.g. ":5668"; several may be comma-separated
 	Path           string // URL path, e.g. "/nrdp/"
 	TokenHash      string // bcrypt hash of accepted token
 	DynamicEnabled bool   // auto-register unknown hosts/services
@@ -26,6 +29,12 @@ type Config struct {
 	DynamicPrune   time.Duration
 	SSLCert        string
 	SSLKey         string
+
+	// Sender health: a sender silent for longer than SenderStaleThreshold
+	// is logged and reported stale (0 = not tracked). ExpectedSenders are
+	// addresses tracked from startup, before they first submit.
+	SenderStaleThreshold time.Duration
+	ExpectedSenders      []string
 }
 
 // Server is the NRDP HTTP relay endpoint.
@@ -33,9 +42,15 @@ type Server struct {
 	cfg      Config
 	store    *objects.ObjectStore
 	resultCh chan<- *objects.CheckResult
+	sink     func(*objects.CheckResult) bool
 	logger   *logging.Logger
 	tracker  *DynamicTracker
+	senders  *SenderTracker
 	server   *http.Server
+	lns      []net.Listener
+
+	received atomic.Int64 // results accepted into the pipeline
+	dropped  atomic.Int64 // results dropped because the result channel was full
 }
 
 // New creates a new NRDP server.
@@ -52,14 +67,32 @@ func New(cfg Config, store *objects.ObjectStore, resultCh chan<- *objects.CheckR
 			logger.Log(format, args...)
 		})
 	}
+	if cfg.SenderStaleThreshold > 0 {
+		s.senders = NewSenderTracker(cfg.SenderStaleThreshold, cfg.ExpectedSenders)
+		s.senders.SetLogger(func(format string, args ...interface{}) {
+			logger.Log(format, args...)
+		})
+	}
 	return s
 }
 
+// SetResultSink routes accepted results through fn instead of a
+// non-blocking send on the result channel, e.g. to spill to disk when the
+// channel is full. fn must not block and returns false if it dropped the
+// result.
+func (s *Server) SetResultSink(fn func(*objects.CheckResult) bool) {
+	s.sink = fn
+}
+
 // Tracker returns the dynamic host/service tracker, or nil if dynamic
 // registration is disabled. Used by the scheduler to register objects
 // under its existing store lock.
 func (s *Server) Tracker() *DynamicTracker { return s.tracker }
 
+// Senders returns the sender health tracker, or nil if senders are not
+// tracked.
+func (s *Server) Senders() *SenderTracker { return s.senders }
+
 // Start begins listening for NRDP requests.
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
@@ -80,23 +113,29 @@ func (s *Server) Start() error {
 	if s.tracker != nil {
 		s.tracker.StartPruner()
 	}
+	if s.senders != nil {
+		s.senders.Start()
+	}
 
-	ln, err := net.Listen("tcp", s.cfg.Listen)
+	lns, err := listenaddr.Listen(s.cfg.Listen)
 	if err != nil {
-		return fmt.Errorf("nrdp: listen %s: %w", s.cfg.Listen, err)
+		return fmt.Errorf("nrdp: %w", err)
+	}
+	s.lns = lns
+
+	for _, ln := range lns {
+		go func(ln net.Listener) {
+			var serveErr error
+			if s.cfg.SSLCert != "" && s.cfg.SSLKey != "" {
+				serveErr = s.server.ServeTLS(ln, s.cfg.SSLCert, s.cfg.SSLKey)
+			} else {
+				serveErr = s.server.Serve(ln)
+			}
+			if serveErr != nil && serveErr != http.ErrServerClosed {
+				s.logger.Log("NRDP server error on %s: %v", ln.Addr(), serveErr)
+			}
+		}(ln)
 	}
-
-	go func() {
-		var serveErr error
-		if s.cfg.SSLCert != "" && s.cfg.SSLKey != "" {
-			serveErr = s.server.ServeTLS(ln, s.cfg.SSLCert, s.cfg.SSLKey)
-		} else {
-			serveErr = s.server.Serve(ln)
-		}
-		if serveErr != nil && serveErr != http.ErrServerClosed {
-			s.logger.Log("NRDP server error: %v", serveErr)
-		}
-	}()
 	return nil
 }
 
@@ -105,6 +144,9 @@ func (s *Server) Stop() {
 	if s.tracker != nil {
 		s.tracker.Stop()
 	}
+	if s.senders != nil {
+		s.senders.Stop()
+	}
 	if s.server != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -112,6 +154,43 @@ func (s *Server) Stop() {
 	}
 }
 
+// Stats returns the number of results accepted and dropped since start.
+func (s *Server) Stats() (received, dropped int64) {
+	return s.received.Load(), s.dropped.Load()
+}
+
+// Ping sends a GET to the NRDP endpoint over every listener and returns the
+// slowest round trip. Any HTTP response (normally 405) counts as alive.
+func (s *Server) Ping(timeout time.Duration) (time.Duration, error) {
+	if len(s.lns) == 0 {
+		return 0, fmt.Errorf("not listening")
+	}
+	scheme := "http"
+	client := &http.Client{Timeout: timeout}
+	if s.cfg.SSLCert != "" && s.cfg.SSLKey != "" {
+		scheme = "https"
+		// Loopback probe of our own listener; the certificate names the
+		// public hostname, not the address we dial.
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	path := s.cfg.Path
+	if path == "" {
+		path = "/nrdp/"
+	}
+	var slowest time.Duration
+	for _, ln := range s.lns {
+		start := time.Now()
+		resp, err := client.Get(scheme + "://" + ln.Addr().String() + path)
+		if err != nil {
+			return 0, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		slowest = max(slowest, time.Since(start))
+	}
+	return slowest, nil
+}
+
 // handleNRDP is the main request handler for POST /nrdp/.
 func (s *Server) handleNRDP(w http.ResponseWriter, r *http.Request) {
 	reqID := GenerateRequestID()
@@ -161,6 +240,11 @@ func (s *Server) handleNRDP(w http.ResponseWriter, r *http.Request) {
 
 	// Process results
 	source := BuildSource(format, r.RemoteAddr)
+	sender := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		sender = host
+	}
+	checkSource := "NRDP " + sender
 	processed := 0
 
 	for _, result := range results {
@@ -185,17 +269,25 @@ func (s *Server) handleNRDP(w http.ResponseWriter, r *http.Request) {
 			FinishTime:         now,
 			ExitedOK:           true,
 			DynamicRegister:    s.tracker != nil && s.cfg.DynamicEnabled,
+			Source:             checkSource,
 		}
 
-		select {
-		case s.resultCh <- cr:
+		if s.submit(cr) {
 			processed++
-		default:
+			s.received.Add(1)
+		} else {
+			s.dropped.Add(1)
 			s.logger.Log("NRDP [%s] result channel full, dropping result for %s/%s",
 				reqID, result.Hostname, result.Servicename)
 		}
 	}
 
+	// A sender is alive if it submits at all, even when its results were
+	// dropped on our side.
+	if s.senders != nil && len(results) > 0 {
+		s.senders.Record(sender, len(results))
+	}
+
 	msg := fmt.Sprintf("Processing %d Results", processed)
 	s.logger.Log("NRDP [%s] %s from %s (%s)", reqID, msg, r.RemoteAddr, format)
 
@@ -205,6 +297,18 @@ func (s *Server) handleNRDP(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+func (s *Server) submit(cr *objects.CheckResult) bool {
+	if s.sink != nil {
+		return s.sink(cr)
+	}
+	select {
+	case s.resultCh <- cr:
+		return true
+	default:
+		return false
+	}
+}
+
 // authenticate checks the request token against the configured bcrypt hash.
 // Localhost requests bypass authentication.
 func (s *Server) authenticate(r *http.Request) bool {
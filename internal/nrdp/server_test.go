@@ -193,9 +193,29 @@ func TestJSONPost(t *testing.T) {
 		if cr.HostName != "app01" || cr.ServiceDescription != "CPU" || cr.ReturnCode != 1 {
 			t.Errorf("result = %+v", cr)
 		}
+		if cr.Source != "NRDP 127.0.0.1" {
+			t.Errorf("source = %q", cr.Source)
+		}
 	case <-time.After(time.Second):
 		t.Fatal("no result")
 	}
+	if received, dropped := s.Stats(); received != 1 || dropped != 0 {
+		t.Errorf("stats = %d received, %d dropped", received, dropped)
+	}
+}
+
+func TestPing(t *testing.T) {
+	s, _, _ := testServer(t, "", false)
+	if _, err := s.Ping(time.Second); err == nil {
+		t.Fatal("expected error before Start")
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if _, err := s.Ping(5 * time.Second); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
 }
 
 func TestBatchResults(t *testing.T) {
@@ -0,0 +1,55 @@
+package objects
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	countBucket  = 5 * time.Minute
+	countBuckets = int64(24 * time.Hour / countBucket)
+)
+
+// NotificationCounts counts notifications over the last 24 hours in
+// five-minute buckets, for alert fatigue metrics. Counts are not retained
+// across restarts. Methods on a nil *NotificationCounts report zero.
+type NotificationCounts struct {
+	mu      sync.Mutex
+	buckets [countBuckets]uint32
+	stamps  [countBuckets]int64 // bucket number each slot counts
+}
+
+// Add counts one notification at now.
+func (c *NotificationCounts) Add(now time.Time) {
+	n := now.Unix() / int64(countBucket/time.Second)
+	i := n % countBuckets
+	c.mu.Lock()
+	if c.stamps[i] != n {
+		c.stamps[i] = n
+		c.buckets[i] = 0
+	}
+	c.buckets[i]++
+	c.mu.Unlock()
+}
+
+// Since returns the notifications counted in the window d before now, to
+// five-minute resolution. Windows longer than 24 hours count 24 hours.
+func (c *NotificationCounts) Since(now time.Time, d time.Duration) int {
+	if c == nil {
+		return 0
+	}
+	n := now.Unix() / int64(countBucket/time.Second)
+	k := int64((d + countBucket - 1) / countBucket)
+	if k > countBuckets {
+		k = countBuckets
+	}
+	total := 0
+	c.mu.Lock()
+	for b := n - k + 1; b <= n; b++ {
+		if i := b % countBuckets; b >= 0 && c.stamps[i] == b {
+			total += int(c.buckets[i])
+		}
+	}
+	c.mu.Unlock()
+	return total
+}
@@ -0,0 +1,32 @@
+package objects
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationCounts(t *testing.T) {
+	var nilCounts *NotificationCounts
+	if nilCounts.Since(time.Now(), time.Hour) != 0 {
+		t.Error("nil counts should be zero")
+	}
+
+	c := &NotificationCounts{}
+	start := time.Unix(1700000000, 0)
+	c.Add(start)
+	c.Add(start.Add(30 * time.Minute))
+	c.Add(start.Add(2 * time.Hour))
+	now := start.Add(2 * time.Hour)
+	if got := c.Since(now, time.Hour); got != 1 {
+		t.Errorf("last hour = %d, want 1", got)
+	}
+	if got := c.Since(now, 24*time.Hour); got != 3 {
+		t.Errorf("last 24 hours = %d, want 3", got)
+	}
+	// A day later the slots are reused, and the old counts are gone.
+	later := start.Add(24*time.Hour + 10*time.Minute)
+	c.Add(later)
+	if got := c.Since(later, 24*time.Hour); got != 3 {
+		t.Errorf("after a day = %d, want 3", got)
+	}
+}
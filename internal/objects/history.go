@@ -0,0 +1,87 @@
+package objects
+
+import (
+	"sync"
+	"time"
+)
+
+// CheckRecord is one check result kept in a CheckHistory.
+type CheckRecord struct {
+	Time      time.Time
+	State     int
+	StateType int
+	Duration  float64 // seconds
+	Output    string  // short plugin output
+}
+
+// CheckHistory keeps the last results of a host or service in a ring, so
+// the recent pattern can be seen without reading the log. It is not
+// retained across restarts. Methods on a nil *CheckHistory report nothing.
+type CheckHistory struct {
+	mu      sync.Mutex
+	records []CheckRecord
+	next    int
+	full    bool
+}
+
+// NewCheckHistory returns a history of the last size results.
+func NewCheckHistory(size int) *CheckHistory {
+	return &CheckHistory{records: make([]CheckRecord, size)}
+}
+
+// Size returns how many results the history keeps.
+func (h *CheckHistory) Size() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.records)
+}
+
+// Add records r, dropping the oldest result when the history is full.
+func (h *CheckHistory) Add(r CheckRecord) {
+	h.mu.Lock()
+	h.records[h.next] = r
+	h.next++
+	if h.next == len(h.records) {
+		h.next = 0
+		h.full = true
+	}
+	h.mu.Unlock()
+}
+
+// Records returns the results kept, oldest first.
+func (h *CheckHistory) Records() []CheckRecord {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]CheckRecord(nil), h.records[:h.next]...)
+	}
+	out := make([]CheckRecord, 0, len(h.records))
+	out = append(out, h.records[h.next:]...)
+	return append(out, h.records[:h.next]...)
+}
+
+// RecordCheck adds r to the history at *hist, creating it, or resizing it
+// keeping the latest results, when its size is not size. A size of 0 drops
+// the history.
+func RecordCheck(hist **CheckHistory, size int, r CheckRecord) {
+	if size <= 0 {
+		*hist = nil
+		return
+	}
+	if h := *hist; h == nil || h.Size() != size {
+		n := NewCheckHistory(size)
+		old := h.Records()
+		if len(old) > size {
+			old = old[len(old)-size:]
+		}
+		for _, o := range old {
+			n.Add(o)
+		}
+		*hist = n
+	}
+	(*hist).Add(r)
+}
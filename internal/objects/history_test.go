@@ -0,0 +1,38 @@
+package objects
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckHistory(t *testing.T) {
+	var hist *CheckHistory
+	if hist.Records() != nil {
+		t.Error("nil history should have no records")
+	}
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 5; i++ {
+		RecordCheck(&hist, 3, CheckRecord{Time: start.Add(time.Duration(i) * time.Minute), State: i})
+	}
+	states := func() []int {
+		var s []int
+		for _, r := range hist.Records() {
+			s = append(s, r.State)
+		}
+		return s
+	}
+	if got := states(); len(got) != 3 || got[0] != 2 || got[2] != 4 {
+		t.Errorf("states = %v, want [2 3 4]", got)
+	}
+
+	// Shrinking keeps the latest results; 0 drops the history.
+	RecordCheck(&hist, 2, CheckRecord{State: 5})
+	if got := states(); len(got) != 2 || got[0] != 4 || got[1] != 5 {
+		t.Errorf("after shrinking, states = %v, want [4 5]", got)
+	}
+	RecordCheck(&hist, 0, CheckRecord{State: 6})
+	if hist != nil {
+		t.Error("size 0 should drop the history")
+	}
+}
@@ -0,0 +1,26 @@
+package objects
+
+import "unique"
+
+// Intern returns a canonical copy of s. Equal strings interned anywhere
+// share one backing array, so the command arguments, contact lists, custom
+// variables and plugin outputs repeated across thousands of objects are
+// stored once. The copy never points into a larger string s was cut from.
+// Interned strings no longer referenced are reclaimed by the garbage
+// collector, so interning values that change, like plugin output, does not
+// leak.
+func Intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	return unique.Make(s).Value()
+}
+
+// InternMap interns the keys and values of m in place. Assigning to an
+// existing string key stores the new key too, so the map ends up holding
+// the shared copies.
+func InternMap(m map[string]string) {
+	for k, v := range m {
+		m[Intern(k)] = Intern(v)
+	}
+}
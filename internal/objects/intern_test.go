@@ -0,0 +1,76 @@
+package objects
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestInternSharesBacking(t *testing.T) {
+	line := "    check_command   check_nrpe!check_load"
+	a := Intern(strings.Clone(line[20:]))
+	b := Intern(strings.Clone(line[20:]))
+	if a != "check_nrpe!check_load" || unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Errorf("Intern: expected one shared copy, got %q and %q", a, b)
+	}
+
+	m := map[string]string{strings.Clone("ENV"): strings.Clone("staging")}
+	InternMap(m)
+	for k, v := range m {
+		if unsafe.StringData(k) != unsafe.StringData(Intern("ENV")) || unsafe.StringData(v) != unsafe.StringData(Intern("staging")) {
+			t.Error("InternMap: expected the map to hold the interned key and value")
+		}
+	}
+	if Intern("") != "" {
+		t.Error("Intern: empty string changed")
+	}
+}
+
+// heapAfter reports the live heap held by whatever build returns.
+func heapAfter(build func() interface{}) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	keep := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(keep)
+	if after.HeapAlloc < before.HeapAlloc {
+		return 0
+	}
+	return after.HeapAlloc - before.HeapAlloc
+}
+
+// TestInternHeap compares the heap held by 100k services' worth of
+// repeated attribute values with and without interning.
+func TestInternHeap(t *testing.T) {
+	values := []string{
+		"check_nrpe!check_load!-w 5,4,3 -c 10,8,6",
+		"/etc/gogios/objects/services/linux-base.cfg:42",
+		"PING OK - Packet loss = 0%",
+		"linux-admins,oncall-primary",
+	}
+	const n = 100000
+	build := func(intern bool) func() interface{} {
+		return func() interface{} {
+			out := make([]string, n)
+			for i := range out {
+				s := strings.Clone(values[i%len(values)])
+				if intern {
+					s = Intern(s)
+				}
+				out[i] = s
+			}
+			return out
+		}
+	}
+	plain := heapAfter(build(false))
+	interned := heapAfter(build(true))
+	t.Logf("heap for %d values: %d bytes plain, %d bytes interned", n, plain, interned)
+	// Both hold the slice of string headers (16 bytes each); only the plain
+	// copies hold the contents too.
+	if interned*2 > plain {
+		t.Errorf("expected interning to at least halve the heap, got %d vs %d bytes", interned, plain)
+	}
+}
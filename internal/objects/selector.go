@@ -0,0 +1,118 @@
+package objects
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CustomVarSelector matches hosts and services by custom variables. It is
+// written as comma-separated "_NAME=value" terms, all of which must match,
+// e.g. "_ENV=staging,_TEAM=web". The leading underscore is optional and
+// names are case-insensitive like custom variable names in object
+// definitions. Values are compared exactly unless they contain a shell
+// glob ("_ENV=stag*").
+type CustomVarSelector []customVarTerm
+
+type customVarTerm struct {
+	name, value string
+}
+
+// ParseCustomVarSelector parses a selector such as "_ENV=staging".
+func ParseCustomVarSelector(s string) (CustomVarSelector, error) {
+	var sel CustomVarSelector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(term, "=")
+		name = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(name), "_"))
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid selector term '%s' (want _NAME=value)", term)
+		}
+		value = strings.TrimSpace(value)
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in selector term '%s'", term)
+		}
+		sel = append(sel, customVarTerm{name: name, value: value})
+	}
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	return sel, nil
+}
+
+// MatchHost reports whether h's custom variables match every term.
+func (sel CustomVarSelector) MatchHost(h *Host) bool {
+	return sel.match(func(name string) (string, bool) {
+		v, ok := h.CustomVars[name]
+		return v, ok
+	})
+}
+
+// MatchService reports whether svc matches every term. A variable the
+// service does not define is looked up on its host, so "_ENV=staging"
+// set on a host selects its services too.
+func (sel CustomVarSelector) MatchService(svc *Service) bool {
+	return sel.match(func(name string) (string, bool) {
+		if v, ok := svc.CustomVars[name]; ok {
+			return v, true
+		}
+		if svc.Host != nil {
+			v, ok := svc.Host.CustomVars[name]
+			return v, ok
+		}
+		return "", false
+	})
+}
+
+func (sel CustomVarSelector) match(lookup func(string) (string, bool)) bool {
+	for _, t := range sel {
+		v, ok := lookup(t.name)
+		if !ok {
+			return false
+		}
+		if matched, _ := path.Match(t.value, v); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Selector matches hosts and services, such as a CustomVarSelector or a
+// TagSelector.
+type Selector interface {
+	MatchHost(h *Host) bool
+	MatchService(svc *Service) bool
+}
+
+// SelectHosts returns the hosts matching sel, in definition order. A
+// TagSelector is looked up in the tag index instead, in no particular
+// order.
+func (s *ObjectStore) SelectHosts(sel Selector) []*Host {
+	if ts, ok := sel.(TagSelector); ok {
+		return s.hostsWithTags(ts)
+	}
+	var result []*Host
+	for _, h := range s.Hosts {
+		if sel.MatchHost(h) {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// SelectServices is SelectHosts for services.
+func (s *ObjectStore) SelectServices(sel Selector) []*Service {
+	if ts, ok := sel.(TagSelector); ok {
+		return s.servicesWithTags(ts)
+	}
+	var result []*Service
+	for _, svc := range s.Services {
+		if sel.MatchService(svc) {
+			result = append(result, svc)
+		}
+	}
+	return result
+}
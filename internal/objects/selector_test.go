@@ -0,0 +1,104 @@
+package objects
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCustomVarSelector(t *testing.T) {
+	store := NewObjectStore()
+	staging := &Host{Name: "stg-web", CustomVars: map[string]string{"ENV": "staging", "TEAM": "web"}}
+	stagingDB := &Host{Name: "stg-db", CustomVars: map[string]string{"ENV": "staging-eu", "TEAM": "db"}}
+	prod := &Host{Name: "prod-web", CustomVars: map[string]string{"ENV": "production", "TEAM": "web"}}
+	for _, h := range []*Host{staging, stagingDB, prod} {
+		store.AddHost(h)
+	}
+	store.AddService(&Service{Host: staging, Description: "HTTP", CustomVars: map[string]string{}})
+	store.AddService(&Service{Host: prod, Description: "HTTP", CustomVars: map[string]string{"ENV": "staging"}})
+	store.AddService(&Service{Host: stagingDB, Description: "MySQL", CustomVars: map[string]string{"ENV": "production"}})
+
+	sel, err := ParseCustomVarSelector("_ENV=staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := store.SelectHosts(sel); len(hosts) != 1 || hosts[0] != staging {
+		t.Errorf("_ENV=staging: expected [stg-web], got %v", hosts)
+	}
+	// The service's own variable wins over its host's.
+	svcs := store.SelectServices(sel)
+	if len(svcs) != 2 || svcs[0].Host != staging || svcs[1].Host != prod {
+		t.Errorf("_ENV=staging services: expected HTTP on stg-web and prod-web, got %d", len(svcs))
+	}
+
+	sel, err = ParseCustomVarSelector("env=staging*, _team=web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := store.SelectHosts(sel); len(hosts) != 1 || hosts[0] != staging {
+		t.Errorf("glob with two terms: expected [stg-web], got %v", hosts)
+	}
+
+	for _, bad := range []string{"", "_ENV", "=staging", "_ENV=[stag"} {
+		if _, err := ParseCustomVarSelector(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestTagSelector(t *testing.T) {
+	if got := ParseTags(" team=web, pci,env = prod,,pci,=x "); !slices.Equal(got, []string{"env=prod", "pci", "team=web"}) {
+		t.Errorf("ParseTags: got %q", got)
+	}
+
+	store := NewObjectStore()
+	web := &Host{Name: "web01", Tags: ParseTags("env=prod,team=web")}
+	db := &Host{Name: "db01", Tags: ParseTags("env=staging")}
+	store.AddHost(web)
+	store.AddHost(db)
+	http := &Service{Host: web, Description: "HTTP"}
+	mysql := &Service{Host: db, Description: "MySQL", Tags: ParseTags("team=db,pci")}
+	store.AddService(http)
+	store.AddService(mysql)
+	web.Services = []*Service{http}
+	db.Services = []*Service{mysql}
+
+	sel, err := ParseTagSelector("env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := store.SelectHosts(sel); len(hosts) != 2 {
+		t.Errorf("env: expected both hosts, got %d", len(hosts))
+	}
+	// A service is selected by its own tags and its host's.
+	sel, _ = ParseTagSelector("env=staging,team=db")
+	if svcs := store.SelectServices(sel); len(svcs) != 1 || svcs[0] != mysql {
+		t.Errorf("env=staging,team=db: expected [MySQL], got %v", svcs)
+	}
+	sel, _ = ParseTagSelector("team=web")
+	if svcs := store.SelectServices(sel); len(svcs) != 1 || svcs[0] != http {
+		t.Errorf("team=web: expected [HTTP], got %v", svcs)
+	}
+	if hosts := store.SelectHosts(TagSelector{"team=w"}); len(hosts) != 0 {
+		t.Errorf("team=w: expected no hosts, got %v", hosts)
+	}
+
+	// Removed objects and replaced tags leave the index.
+	store.RemoveService("web01", "HTTP")
+	if svcs := store.SelectServices(sel); len(svcs) != 0 {
+		t.Errorf("team=web after RemoveService: expected none, got %v", svcs)
+	}
+	store.SetHostTags(web, ParseTags("env=dev"))
+	sel, _ = ParseTagSelector("env=prod")
+	if hosts := store.SelectHosts(sel); len(hosts) != 0 {
+		t.Errorf("env=prod after SetHostTags: expected none, got %v", hosts)
+	}
+	store.RemoveHost("db01")
+	sel, _ = ParseTagSelector("pci")
+	if svcs := store.SelectServices(sel); len(svcs) != 0 {
+		t.Errorf("pci after RemoveHost: expected none, got %v", svcs)
+	}
+
+	if _, err := ParseTagSelector(" , "); err == nil {
+		t.Error("empty selector: expected an error")
+	}
+}
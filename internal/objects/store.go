@@ -3,8 +3,35 @@ package objects
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
+// StaleCheckEpoch marks a check result from a launch that a later launch
+// of the same check has superseded. No object ever has this epoch.
+const StaleCheckEpoch = ^uint64(0)
+
+var checkEpochs atomic.Uint64
+
+// NextCheckEpoch returns a check epoch that has never been handed out.
+// Every host and service gets one when added to a store and a new one
+// when its active checks are disabled or its check command changes, so a
+// result launched under an older epoch can be recognised as stale.
+func NextCheckEpoch() uint64 {
+	return checkEpochs.Add(1)
+}
+
+// InvalidateChecks gives h a new check epoch; results of active checks
+// already in flight are discarded when they arrive.
+func (h *Host) InvalidateChecks() {
+	h.CheckEpoch = NextCheckEpoch()
+}
+
+// InvalidateChecks gives svc a new check epoch; results of active checks
+// already in flight are discarded when they arrive.
+func (svc *Service) InvalidateChecks() {
+	svc.CheckEpoch = NextCheckEpoch()
+}
+
 type ObjectStore struct {
 	// Mu protects mutable runtime state on Host/Service objects.
 	// The scheduler takes a write lock when processing check results;
@@ -22,6 +49,8 @@ type ObjectStore struct {
 	ServiceDependencies []*ServiceDependency
 	HostEscalations    []*HostEscalation
 	ServiceEscalations []*ServiceEscalation
+	Blackouts          []*Blackout
+	InhibitRules       []*InhibitRule
 
 	hostsByName         map[string]*Host
 	servicesByHostDesc  map[string]*Service // "hostname\tsvc_description"
@@ -31,6 +60,8 @@ type ObjectStore struct {
 	timeperiodsByName   map[string]*Timeperiod
 	hostGroupsByName    map[string]*HostGroup
 	serviceGroupsByName map[string]*ServiceGroup
+	hostsByTag          map[string][]*Host // by tag and by label name; see SelectHosts
+	servicesByTag       map[string][]*Service
 }
 
 func NewObjectStore() *ObjectStore {
@@ -43,6 +74,8 @@ func NewObjectStore() *ObjectStore {
 		timeperiodsByName:   make(map[string]*Timeperiod),
 		hostGroupsByName:    make(map[string]*HostGroup),
 		serviceGroupsByName: make(map[string]*ServiceGroup),
+		hostsByTag:          make(map[string][]*Host),
+		servicesByTag:       make(map[string][]*Service),
 	}
 }
 
@@ -54,8 +87,10 @@ func (s *ObjectStore) AddHost(h *Host) error {
 	if _, exists := s.hostsByName[h.Name]; exists {
 		return fmt.Errorf("duplicate host: %s", h.Name)
 	}
+	h.CheckEpoch = NextCheckEpoch()
 	s.Hosts = append(s.Hosts, h)
 	s.hostsByName[h.Name] = h
+	s.indexHostTags(h)
 	return nil
 }
 
@@ -68,8 +103,10 @@ func (s *ObjectStore) AddService(svc *Service) error {
 	if _, exists := s.servicesByHostDesc[key]; exists {
 		return fmt.Errorf("duplicate service: %s/%s", svc.Host.Name, svc.Description)
 	}
+	svc.CheckEpoch = NextCheckEpoch()
 	s.Services = append(s.Services, svc)
 	s.servicesByHostDesc[key] = svc
+	s.indexServiceTags(svc)
 	return nil
 }
 
@@ -186,14 +223,21 @@ func (s *ObjectStore) AddHostEscalation(he *HostEscalation) {
 	// Wire up to the host's escalation list
 	if he.Host != nil {
 		he.Host.Escalations = append(he.Host.Escalations, he)
+	} else if he.HostGroup != nil {
+		he.HostGroup.Escalations = append(he.HostGroup.Escalations, he)
 	}
 }
 
 func (s *ObjectStore) AddServiceEscalation(se *ServiceEscalation) {
 	s.ServiceEscalations = append(s.ServiceEscalations, se)
 	// Wire up to the service's escalation list
-	if se.Service != nil {
+	switch {
+	case se.Service != nil:
 		se.Service.Escalations = append(se.Service.Escalations, se)
+	case se.ServiceGroup != nil:
+		se.ServiceGroup.Escalations = append(se.ServiceGroup.Escalations, se)
+	case se.HostGroup != nil:
+		se.HostGroup.ServiceEscalations = append(se.HostGroup.ServiceEscalations, se)
 	}
 }
 
@@ -220,6 +264,7 @@ func (s *ObjectStore) RemoveHost(name string) {
 	for _, svc := range s.Services {
 		if svc.Host != nil && svc.Host.Name == name {
 			delete(s.servicesByHostDesc, svcKey(name, svc.Description))
+			s.unindexServiceTags(svc)
 		} else {
 			keptServices = append(keptServices, svc)
 		}
@@ -228,6 +273,7 @@ func (s *ObjectStore) RemoveHost(name string) {
 
 	// Remove the host
 	delete(s.hostsByName, name)
+	s.unindexHostTags(host)
 	for i, h := range s.Hosts {
 		if h.Name == name {
 			s.Hosts = append(s.Hosts[:i], s.Hosts[i+1:]...)
@@ -243,6 +289,7 @@ func (s *ObjectStore) RemoveService(hostName, desc string) {
 	if _, exists := s.servicesByHostDesc[key]; !exists {
 		return
 	}
+	s.unindexServiceTags(s.servicesByHostDesc[key])
 	delete(s.servicesByHostDesc, key)
 	for i, svc := range s.Services {
 		if svc.Host != nil && svc.Host.Name == hostName && svc.Description == desc {
@@ -251,3 +298,61 @@ func (s *ObjectStore) RemoveService(hostName, desc string) {
 		}
 	}
 }
+
+// Replace swaps the contents of s for those of next, a freshly loaded
+// store, and returns a store holding what s had. Everything holding s
+// sees the new objects from then on. next must not be used afterwards.
+// Caller must hold the write lock.
+func (s *ObjectStore) Replace(next *ObjectStore) *ObjectStore {
+	old := &ObjectStore{
+		Hosts:               s.Hosts,
+		Services:            s.Services,
+		Commands:            s.Commands,
+		Contacts:            s.Contacts,
+		ContactGroups:       s.ContactGroups,
+		Timeperiods:         s.Timeperiods,
+		HostGroups:          s.HostGroups,
+		ServiceGroups:       s.ServiceGroups,
+		HostDependencies:    s.HostDependencies,
+		ServiceDependencies: s.ServiceDependencies,
+		HostEscalations:     s.HostEscalations,
+		ServiceEscalations:  s.ServiceEscalations,
+		Blackouts:           s.Blackouts,
+		InhibitRules:        s.InhibitRules,
+		hostsByName:         s.hostsByName,
+		servicesByHostDesc:  s.servicesByHostDesc,
+		commandsByName:      s.commandsByName,
+		contactsByName:      s.contactsByName,
+		contactGroupsByName: s.contactGroupsByName,
+		timeperiodsByName:   s.timeperiodsByName,
+		hostGroupsByName:    s.hostGroupsByName,
+		serviceGroupsByName: s.serviceGroupsByName,
+		hostsByTag:          s.hostsByTag,
+		servicesByTag:       s.servicesByTag,
+	}
+	s.Hosts = next.Hosts
+	s.Services = next.Services
+	s.Commands = next.Commands
+	s.Contacts = next.Contacts
+	s.ContactGroups = next.ContactGroups
+	s.Timeperiods = next.Timeperiods
+	s.HostGroups = next.HostGroups
+	s.ServiceGroups = next.ServiceGroups
+	s.HostDependencies = next.HostDependencies
+	s.ServiceDependencies = next.ServiceDependencies
+	s.HostEscalations = next.HostEscalations
+	s.ServiceEscalations = next.ServiceEscalations
+	s.Blackouts = next.Blackouts
+	s.InhibitRules = next.InhibitRules
+	s.hostsByName = next.hostsByName
+	s.servicesByHostDesc = next.servicesByHostDesc
+	s.commandsByName = next.commandsByName
+	s.contactsByName = next.contactsByName
+	s.contactGroupsByName = next.contactGroupsByName
+	s.timeperiodsByName = next.timeperiodsByName
+	s.hostGroupsByName = next.hostGroupsByName
+	s.serviceGroupsByName = next.serviceGroupsByName
+	s.hostsByTag = next.hostsByTag
+	s.servicesByTag = next.servicesByTag
+	return old
+}
@@ -102,3 +102,44 @@ func TestObjectStoreAllTypes(t *testing.T) {
 		t.Error("servicegroup not found")
 	}
 }
+
+func TestObjectStoreCheckEpochs(t *testing.T) {
+	s := NewObjectStore()
+	h := &Host{Name: "web01"}
+	s.AddHost(h)
+	if h.CheckEpoch == 0 {
+		t.Fatal("expected a check epoch on add")
+	}
+	before := h.CheckEpoch
+	h.InvalidateChecks()
+	if h.CheckEpoch == before {
+		t.Error("InvalidateChecks should change the epoch")
+	}
+
+	// A host removed and added again under the same name gets a new epoch.
+	s.RemoveHost("web01")
+	readded := &Host{Name: "web01"}
+	s.AddHost(readded)
+	if readded.CheckEpoch == h.CheckEpoch || readded.CheckEpoch == StaleCheckEpoch {
+		t.Errorf("unexpected epoch %d for re-added host", readded.CheckEpoch)
+	}
+}
+
+func TestObjectStoreReplace(t *testing.T) {
+	s := NewObjectStore()
+	s.AddHost(&Host{Name: "old"})
+	lookup := s.GetHost
+
+	next := NewObjectStore()
+	h := &Host{Name: "new"}
+	next.AddHost(h)
+	next.AddService(&Service{Host: h, Description: "PING"})
+
+	old := s.Replace(next)
+	if lookup("new") != h || lookup("old") != nil || s.GetService("new", "PING") == nil {
+		t.Error("store does not hold the replacement objects")
+	}
+	if old.GetHost("old") == nil || len(old.Hosts) != 1 || old.GetHost("new") != nil {
+		t.Error("returned store does not hold the previous objects")
+	}
+}
@@ -0,0 +1,192 @@
+package objects
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParseTags parses a comma-separated tag list such as
+// "env=prod,team=db,pci". A tag is a bare name or a name=value label.
+// Tags are case-sensitive; the result is sorted and has no duplicates.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(t, "=")
+		t = strings.TrimSpace(name)
+		if ok {
+			t += "=" + strings.TrimSpace(value)
+		}
+		if t != "" && t[0] != '=' {
+			tags = append(tags, Intern(t))
+		}
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// tagKeys returns the index keys of tag: the tag itself and, for a
+// name=value label, its name.
+func tagKeys(tag string) []string {
+	if name, _, ok := strings.Cut(tag, "="); ok {
+		return []string{tag, name}
+	}
+	return []string{tag}
+}
+
+// TagSelector matches hosts and services by tags. It is written as
+// comma-separated terms, all of which must match: "env=prod" matches that
+// label, and a bare "env" matches the tag env or an env label of any
+// value.
+type TagSelector []string
+
+// ParseTagSelector parses a selector such as "env=prod,team=db".
+func ParseTagSelector(s string) (TagSelector, error) {
+	sel := TagSelector(ParseTags(s))
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("empty tag selector")
+	}
+	return sel, nil
+}
+
+func hasTag(tags []string, term string) bool {
+	for _, t := range tags {
+		if t == term || strings.HasPrefix(t, term) && t[len(term)] == '=' && !strings.Contains(term, "=") {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether tags has every tag of sel.
+func (sel TagSelector) Match(tags []string) bool {
+	for _, term := range sel {
+		if !hasTag(tags, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchHost reports whether h has every tag of sel.
+func (sel TagSelector) MatchHost(h *Host) bool {
+	return sel.Match(h.Tags)
+}
+
+// MatchService reports whether svc, or its host, has every tag of sel.
+// Tags set on a host select its services too.
+func (sel TagSelector) MatchService(svc *Service) bool {
+	for _, term := range sel {
+		if !hasTag(svc.Tags, term) && (svc.Host == nil || !hasTag(svc.Host.Tags, term)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *ObjectStore) indexHostTags(h *Host) {
+	for _, tag := range h.Tags {
+		for _, k := range tagKeys(tag) {
+			if !slices.Contains(s.hostsByTag[k], h) {
+				s.hostsByTag[k] = append(s.hostsByTag[k], h)
+			}
+		}
+	}
+}
+
+func (s *ObjectStore) unindexHostTags(h *Host) {
+	for _, tag := range h.Tags {
+		for _, k := range tagKeys(tag) {
+			s.hostsByTag[k] = slices.DeleteFunc(s.hostsByTag[k], func(x *Host) bool { return x == h })
+			if len(s.hostsByTag[k]) == 0 {
+				delete(s.hostsByTag, k)
+			}
+		}
+	}
+}
+
+func (s *ObjectStore) indexServiceTags(svc *Service) {
+	for _, tag := range svc.Tags {
+		for _, k := range tagKeys(tag) {
+			if !slices.Contains(s.servicesByTag[k], svc) {
+				s.servicesByTag[k] = append(s.servicesByTag[k], svc)
+			}
+		}
+	}
+}
+
+func (s *ObjectStore) unindexServiceTags(svc *Service) {
+	for _, tag := range svc.Tags {
+		for _, k := range tagKeys(tag) {
+			s.servicesByTag[k] = slices.DeleteFunc(s.servicesByTag[k], func(x *Service) bool { return x == svc })
+			if len(s.servicesByTag[k]) == 0 {
+				delete(s.servicesByTag, k)
+			}
+		}
+	}
+}
+
+// SetHostTags replaces the tags of h, a host in s.
+// Caller must hold the write lock.
+func (s *ObjectStore) SetHostTags(h *Host, tags []string) {
+	s.unindexHostTags(h)
+	h.Tags = tags
+	s.indexHostTags(h)
+}
+
+// SetServiceTags replaces the tags of svc, a service in s.
+// Caller must hold the write lock.
+func (s *ObjectStore) SetServiceTags(svc *Service, tags []string) {
+	s.unindexServiceTags(svc)
+	svc.Tags = tags
+	s.indexServiceTags(svc)
+}
+
+// rarestTag returns the term of sel that the fewest hosts and services are
+// indexed under, to look the candidates up by.
+func (s *ObjectStore) rarestTag(sel TagSelector) string {
+	best, n := sel[0], -1
+	for _, term := range sel {
+		if c := len(s.hostsByTag[term]) + len(s.servicesByTag[term]); n < 0 || c < n {
+			best, n = term, c
+		}
+	}
+	return best
+}
+
+// hostsWithTags returns the hosts matching sel, looked up in the tag index.
+func (s *ObjectStore) hostsWithTags(sel TagSelector) []*Host {
+	var result []*Host
+	for _, h := range s.hostsByTag[s.rarestTag(sel)] {
+		if sel.MatchHost(h) {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// servicesWithTags returns the services matching sel, looked up in the tag
+// index: those tagged themselves, then those of tagged hosts.
+func (s *ObjectStore) servicesWithTags(sel TagSelector) []*Service {
+	term := s.rarestTag(sel)
+	var result []*Service
+	seen := make(map[*Service]bool)
+	add := func(svc *Service) {
+		if !seen[svc] && sel.MatchService(svc) {
+			seen[svc] = true
+			result = append(result, svc)
+		}
+	}
+	for _, svc := range s.servicesByTag[term] {
+		add(svc)
+	}
+	for _, h := range s.hostsByTag[term] {
+		for _, svc := range h.Services {
+			// h.Services may still hold a service removed from s
+			if s.servicesByHostDesc[svcKey(h.Name, svc.Description)] == svc {
+				add(svc)
+			}
+		}
+	}
+	return result
+}
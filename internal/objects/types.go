@@ -1,6 +1,9 @@
 package objects
 
-import "time"
+import (
+	"regexp"
+	"time"
+)
 
 // State constants
 const (
@@ -43,6 +46,10 @@ const (
 	NotificationOptionBroadcast = 1
 	NotificationOptionForced    = 2
 	NotificationOptionIncrement = 4
+	// NotificationOptionAllEscalations (Gogios extension) sends the
+	// notification to the object's own contacts and to every escalation
+	// level its notifications have reached.
+	NotificationOptionAllEscalations = 8
 )
 
 // Dependency types
@@ -104,17 +111,21 @@ const (
 )
 
 type Command struct {
-	Name        string
-	CommandLine string
+	Name             string
+	CommandLine      string
+	ConfigSource     string // "file:line" of the definition; empty for objects created at runtime
+	ConcurrencyClass string // concurrency_class (Gogios extension); see checker.ConcurrencyClasses
 }
 
 type Timeperiod struct {
-	Name       string
-	Alias      string
-	Ranges     [7]string // sunday=0 through saturday=6
-	Exceptions []TimeDateException
-	Exclusions []*Timeperiod
-	CustomVars map[string]string
+	Name         string
+	Alias        string
+	ConfigSource string // "file:line" of the definition; empty for objects created at runtime
+	Ranges       [7]string // sunday=0 through saturday=6
+	Exceptions   []TimeDateException
+	Exclusions   []*Timeperiod
+	CustomVars   map[string]string
+	Compiled     *CompiledTimeperiod // minute bitmaps set by the config loader; nil = parse Ranges on each lookup
 }
 
 type TimeDateException struct {
@@ -131,9 +142,49 @@ type TimeDateException struct {
 	Timerange string
 }
 
+// MinutesPerDay is the number of minutes a DayMinutes covers.
+const MinutesPerDay = 24 * 60
+
+// DayMinutes is a bitmap of the minutes of a day, minute 0 being 00:00.
+type DayMinutes [(MinutesPerDay + 63) / 64]uint64
+
+// Add sets the minutes from start up to but not including end, clamped to
+// the day.
+func (d *DayMinutes) Add(start, end int) {
+	start = max(start, 0)
+	end = min(end, MinutesPerDay)
+	for m := start; m < end; m++ {
+		d[m/64] |= 1 << (m % 64)
+	}
+}
+
+// Has reports whether minute is set.
+func (d *DayMinutes) Has(minute int) bool {
+	if minute < 0 || minute >= MinutesPerDay {
+		return false
+	}
+	return d[minute/64]&(1<<(minute%64)) != 0
+}
+
+// CompiledTimeperiod is a timeperiod's weekday ranges and date exceptions
+// as minute bitmaps, so a lookup does not parse range strings.
+type CompiledTimeperiod struct {
+	Week       [7]DayMinutes // sunday=0 through saturday=6
+	Exceptions []CompiledException
+}
+
+// CompiledException is a date exception: the dates it applies to and its
+// minutes on those dates.
+type CompiledException struct {
+	Kind    int // precedence; exceptions are sorted by it, most specific first
+	Matches func(t time.Time) bool
+	Minutes DayMinutes
+}
+
 type Contact struct {
 	Name                          string
 	Alias                         string
+	ConfigSource                  string // "file:line" of the definition; empty for objects created at runtime
 	Email                         string
 	Pager                         string
 	Addresses                     [MaxContactAddresses]string
@@ -141,6 +192,8 @@ type Contact struct {
 	ServiceNotificationPeriod     *Timeperiod
 	HostNotificationCommands      []*Command
 	ServiceNotificationCommands   []*Command
+	HostNotificationFallback      []NotificationStep
+	ServiceNotificationFallback   []NotificationStep
 	HostNotificationOptions       uint32
 	ServiceNotificationOptions    uint32
 	HostNotificationsEnabled      bool
@@ -149,16 +202,26 @@ type Contact struct {
 	RetainStatusInformation       bool
 	RetainNonstatusInformation    bool
 	MinimumImportance             uint
+	NotificationDigest            time.Duration // batch window; 0 sends each notification immediately
 	ContactGroups                 []*ContactGroup
 	CustomVars                    map[string]string
 	// Runtime
 	LastHostNotification          time.Time
 	LastServiceNotification       time.Time
+	NotificationCounts            *NotificationCounts // notifications sent; nil before the first
+	CappedCounts                  *NotificationCounts // notifications held back by the hourly cap
 	ModifiedAttributes            uint64
 	ModifiedHostAttributes        uint64
 	ModifiedServiceAttributes     uint64
 }
 
+// NotificationStep is one step of a contact's notification fallback chain.
+// The step fails when none of its commands succeed, and the next one runs.
+type NotificationStep struct {
+	Commands []*Command
+	Timeout  time.Duration // 0 means notification_timeout
+}
+
 // GlobalState holds global runtime state for the Nagios process.
 type GlobalState struct {
 	EnableNotifications            bool
@@ -173,6 +236,8 @@ type GlobalState struct {
 	CheckHostFreshness             bool
 	EnableFlapDetection            bool
 	ProcessPerformanceData         bool
+	SchedulerPaused                bool // PAUSE_SCHEDULER; not retained
+	SchedulerPausedSince           time.Time
 	GlobalHostEventHandler         string
 	GlobalServiceEventHandler      string
 	NextCommentID                  uint64
@@ -218,9 +283,10 @@ type GlobalState struct {
 }
 
 type ContactGroup struct {
-	Name    string
-	Alias   string
-	Members []*Contact
+	Name         string
+	Alias        string
+	ConfigSource string // "file:line" of the definition; empty for objects created at runtime
+	Members      []*Contact
 }
 
 type Host struct {
@@ -228,6 +294,7 @@ type Host struct {
 	Name                       string
 	DisplayName                string
 	Alias                      string
+	ConfigSource               string // "file:line" of the definition; empty for objects created at runtime
 	Address                    string
 	Parents                    []*Host
 	Children                   []*Host
@@ -244,6 +311,7 @@ type Host struct {
 	PassiveChecksEnabled       bool
 	ObsessOver                 bool
 	EventHandler               *Command
+	EventHandlerArgs           string
 	EventHandlerEnabled        bool
 	CheckFreshness             bool
 	FreshnessThreshold         int
@@ -275,6 +343,7 @@ type Host struct {
 	RetainNonstatusInformation bool
 	HourlyValue                uint
 	CustomVars                 map[string]string
+	Tags                       []string // sorted "name" and "name=value" tags (Gogios extension); see ParseTags
 
 	// Runtime state
 	CurrentState        int
@@ -284,14 +353,19 @@ type Host struct {
 	CurrentAttempt      int
 	HasBeenChecked      bool
 	IsExecuting         bool
+	CheckEpoch          uint64 // see NextCheckEpoch
+	ExecutionID         uint64 // ID of the running or last processed active check; 0 after a passive result
 	IsFlapping          bool
 	PluginOutput        string
 	LongPluginOutput    string
 	PerfData            string
+	CheckSource         string
+	CheckExecutor       string // runner the last active check was sent to
 	LastCheck           time.Time
 	NextCheck           time.Time
 	LastStateChange     time.Time
 	LastHardStateChange time.Time
+	LastHardStateCause  StateChangeCause
 	LastTimeUp          time.Time
 	LastTimeDown        time.Time
 	LastTimeUnreachable time.Time
@@ -336,18 +410,30 @@ type Host struct {
 	// Notification-related config
 	CheckType int
 
+	// Contact notifications sent about the host; nil before the first
+	NotificationCounts *NotificationCounts
+
+	// Latest check results, up to check_history_size; nil before the first
+	CheckHistory *CheckHistory
+
 	// Dynamic NRDP objects
 	Dynamic  bool      // true if auto-created via NRDP, eligible for TTL pruning
 	LastSeen time.Time // last time a passive check was received (for TTL pruning)
 }
 
 type HostGroup struct {
-	Name      string
-	Alias     string
-	Members   []*Host
-	Notes     string
-	NotesURL  string
-	ActionURL string
+	Name         string
+	Alias        string
+	ConfigSource string // "file:line" of the definition; empty for objects created at runtime
+	Members      []*Host
+	Notes        string
+	NotesURL     string
+	ActionURL    string
+
+	// Group-level escalations (dynamic_groups 1), applied to whoever is a
+	// member when a notification goes out.
+	Escalations        []*HostEscalation
+	ServiceEscalations []*ServiceEscalation
 }
 
 type Service struct {
@@ -355,6 +441,7 @@ type Service struct {
 	Host                       *Host
 	Description                string
 	DisplayName                string
+	ConfigSource               string // "file:line" of the definition; empty for objects created at runtime
 	ServiceGroups              []*ServiceGroup
 	CheckCommand               *Command
 	CheckCommandArgs           string
@@ -368,6 +455,7 @@ type Service struct {
 	PassiveChecksEnabled       bool
 	ObsessOver                 bool
 	EventHandler               *Command
+	EventHandlerArgs           string
 	EventHandlerEnabled        bool
 	CheckFreshness             bool
 	FreshnessThreshold         int
@@ -394,6 +482,7 @@ type Service struct {
 	HourlyValue                uint
 	ParallelizeCheck           bool
 	CustomVars                 map[string]string
+	Tags                       []string // sorted "name" and "name=value" tags (Gogios extension); see ParseTags
 
 	// Runtime state
 	CurrentState        int
@@ -403,14 +492,19 @@ type Service struct {
 	CurrentAttempt      int
 	HasBeenChecked      bool
 	IsExecuting         bool
+	CheckEpoch          uint64 // see NextCheckEpoch
+	ExecutionID         uint64 // ID of the running or last processed active check; 0 after a passive result
 	IsFlapping          bool
 	PluginOutput        string
 	LongPluginOutput    string
 	PerfData            string
+	CheckSource         string
+	CheckExecutor       string // runner the last active check was sent to
 	LastCheck           time.Time
 	NextCheck           time.Time
 	LastStateChange     time.Time
 	LastHardStateChange time.Time
+	LastHardStateCause  StateChangeCause
 	LastTimeOK          time.Time
 	LastTimeWarning     time.Time
 	LastTimeCritical    time.Time
@@ -457,6 +551,12 @@ type Service struct {
 
 	CheckType int
 
+	// Contact notifications sent about the service; nil before the first
+	NotificationCounts *NotificationCounts
+
+	// Latest check results, up to check_history_size; nil before the first
+	CheckHistory *CheckHistory
+
 	// Dynamic NRDP objects
 	Dynamic  bool      // true if auto-created via NRDP, eligible for TTL pruning
 	LastSeen time.Time // last time a passive check was received (for TTL pruning)
@@ -477,6 +577,9 @@ type CheckResult struct {
 	ExecutionTime      float64
 	CheckOptions       int
 	DynamicRegister    bool // NRDP: auto-create host/service in scheduler goroutine
+	Source             string // where the result came from, e.g. "Core Worker 1234", "NRDP 10.0.0.5"
+	CheckEpoch         uint64 // object's CheckEpoch at launch, stamped by the scheduler; 0 = untracked
+	ExecutionID        uint64 // unique ID of the check run, stamped by the scheduler; 0 = passive or untracked
 }
 
 // Check option flags
@@ -488,6 +591,47 @@ const (
 	CheckOptionDependencyCheck  = 1 << 3
 )
 
+// Modified attribute bits, recorded in ModifiedAttributes when an external
+// command changes an object's setting (Nagios's MODATTR_*).
+const (
+	ModAttrNone                    uint64 = 0
+	ModAttrNotificationsEnabled    uint64 = 1
+	ModAttrActiveChecksEnabled     uint64 = 2
+	ModAttrPassiveChecksEnabled    uint64 = 4
+	ModAttrEventHandlerEnabled     uint64 = 8
+	ModAttrFlapDetectionEnabled    uint64 = 16
+	ModAttrPerformanceDataEnabled  uint64 = 64
+	ModAttrObsessiveHandlerEnabled uint64 = 128
+	ModAttrEventHandlerCommand     uint64 = 256
+	ModAttrCheckCommand            uint64 = 512
+	ModAttrNormalCheckInterval     uint64 = 1024
+	ModAttrRetryCheckInterval      uint64 = 2048
+	ModAttrMaxCheckAttempts        uint64 = 4096
+	ModAttrFreshnessChecksEnabled  uint64 = 8192
+	ModAttrCheckTimeperiod         uint64 = 16384
+	ModAttrCustomVariable          uint64 = 32768
+	ModAttrNotificationTimeperiod  uint64 = 65536
+)
+
+// StateChangeCause is the check result that caused a hard state change.
+// It is not retained across restarts; the log keeps it.
+type StateChangeCause struct {
+	CheckType int       // CheckTypeActive or CheckTypePassive
+	Source    string    // CheckResult.Source
+	Time      time.Time // when the check ran
+	Output    string    // short plugin output
+}
+
+// NewStateChangeCause returns the cause recorded for cr, whose short
+// output is output.
+func NewStateChangeCause(cr *CheckResult, output string) StateChangeCause {
+	t := cr.StartTime
+	if t.IsZero() {
+		t = cr.FinishTime
+	}
+	return StateChangeCause{CheckType: cr.CheckType, Source: cr.Source, Time: t, Output: output}
+}
+
 // CheckTypeActive / CheckTypePassive
 const (
 	CheckTypeActive  = 0
@@ -528,6 +672,7 @@ type Config struct {
 	AvgServiceExecutionTime       float64
 	UserMacros                    [256]string
 	OrphanCheckInterval           int // default 60
+	CheckHistorySize              int // check results kept per object; 0 keeps none
 }
 
 // DefaultConfig returns a Config with Nagios 4.1.1 defaults.
@@ -556,12 +701,16 @@ func DefaultConfig() *Config {
 }
 
 type ServiceGroup struct {
-	Name      string
-	Alias     string
-	Members   []*Service
-	Notes     string
-	NotesURL  string
-	ActionURL string
+	Name         string
+	Alias        string
+	ConfigSource string // "file:line" of the definition; empty for objects created at runtime
+	Members      []*Service
+	Notes        string
+	NotesURL     string
+	ActionURL    string
+
+	// Group-level escalations (dynamic_groups 1).
+	Escalations []*ServiceEscalation
 }
 
 type HostDependency struct {
@@ -586,6 +735,7 @@ type ServiceDependency struct {
 
 type HostEscalation struct {
 	Host                 *Host
+	HostGroup            *HostGroup // group-level escalation; Host is nil
 	ContactGroups        []*ContactGroup
 	Contacts             []*Contact
 	FirstNotification    int
@@ -598,6 +748,9 @@ type HostEscalation struct {
 type ServiceEscalation struct {
 	Host                 *Host
 	Service              *Service
+	HostGroup            *HostGroup    // group-level: matches ServiceDescription on member hosts
+	ServiceGroup         *ServiceGroup // group-level: matches every member service
+	ServiceDescription   string
 	ContactGroups        []*ContactGroup
 	Contacts             []*Contact
 	FirstNotification    int
@@ -607,6 +760,99 @@ type ServiceEscalation struct {
 	EscalationOptions    uint32
 }
 
+// Blackout is a window during which notifications for matching objects
+// are suppressed. Checks, state changes and logging are unaffected. Empty
+// filters match everything; host notifications are only suppressed by
+// blackouts without service filters.
+type Blackout struct {
+	Name                string
+	StartTime           time.Time   // zero = already started
+	EndTime             time.Time   // zero = open-ended
+	Period              *Timeperiod // optional recurring window within Start/End
+	HostNames           []string    // shell patterns
+	HostGroups          []string
+	ServiceDescriptions []string // shell patterns
+	ServiceGroups       []string
+	Author              string
+	Comment             string
+	Runtime             bool // added by external command; kept in retention
+}
+
+// InhibitRule suppresses problem notifications for services on a host
+// while another service on the same host, the source, is in one of the
+// source states. It complements dependencies the way Alertmanager
+// inhibition rules do, without a dependency per service pair. Empty host
+// filters match every host.
+type InhibitRule struct {
+	Name          string
+	SourceService string         // service_description of the source
+	SourceStates  uint32         // OptWarning, OptUnknown, OptCritical
+	Target        *regexp.Regexp // matches whole target service descriptions
+	HostNames     []string       // shell patterns
+	HostGroups    []string
+	Comment       string
+}
+
+// AllEscalations returns the host's own escalations followed by those
+// attached to its host groups, so group membership is evaluated at the time
+// of the call rather than when the config was expanded.
+func (h *Host) AllEscalations() []*HostEscalation {
+	n := 0
+	for _, hg := range h.HostGroups {
+		n += len(hg.Escalations)
+	}
+	if n == 0 {
+		return h.Escalations
+	}
+	all := make([]*HostEscalation, 0, len(h.Escalations)+n)
+	all = append(all, h.Escalations...)
+	for _, hg := range h.HostGroups {
+		all = append(all, hg.Escalations...)
+	}
+	return all
+}
+
+// ContactsFor returns the contacts and contact groups e notifies for h.
+// As in Nagios, an escalation that names neither notifies h's own.
+func (e *HostEscalation) ContactsFor(h *Host) ([]*Contact, []*ContactGroup) {
+	if len(e.Contacts) == 0 && len(e.ContactGroups) == 0 {
+		return h.Contacts, h.ContactGroups
+	}
+	return e.Contacts, e.ContactGroups
+}
+
+// ContactsFor returns the contacts and contact groups e notifies for svc,
+// falling back to svc's own like HostEscalation.ContactsFor.
+func (e *ServiceEscalation) ContactsFor(svc *Service) ([]*Contact, []*ContactGroup) {
+	if len(e.Contacts) == 0 && len(e.ContactGroups) == 0 {
+		return svc.Contacts, svc.ContactGroups
+	}
+	return e.Contacts, e.ContactGroups
+}
+
+// AllEscalations returns the service's own escalations followed by those
+// attached to its service groups and, matched by description, to its host's
+// host groups.
+func (svc *Service) AllEscalations() []*ServiceEscalation {
+	var group []*ServiceEscalation
+	for _, sg := range svc.ServiceGroups {
+		group = append(group, sg.Escalations...)
+	}
+	if svc.Host != nil {
+		for _, hg := range svc.Host.HostGroups {
+			for _, se := range hg.ServiceEscalations {
+				if se.ServiceDescription == svc.Description {
+					group = append(group, se)
+				}
+			}
+		}
+	}
+	if len(group) == 0 {
+		return svc.Escalations
+	}
+	return append(append(make([]*ServiceEscalation, 0, len(svc.Escalations)+len(group)), svc.Escalations...), group...)
+}
+
 // NotificationTypeName returns the $NOTIFICATIONTYPE$ macro string.
 func NotificationTypeName(ntype, state int, isHost bool) string {
 	switch ntype {
@@ -705,13 +951,3 @@ func StateMatchesSvcOptions(state int, opts uint32) bool {
 	}
 	return false
 }
-
-// InTimeperiod checks if a time falls within a timeperiod. Returns true if tp is nil.
-func InTimeperiod(tp *Timeperiod, t time.Time) bool {
-	if tp == nil {
-		return true
-	}
-	// Timeperiod logic will be implemented by config parser.
-	// For now, all times are valid if ranges are empty (24x7 default).
-	return true
-}
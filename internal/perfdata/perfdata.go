@@ -3,55 +3,209 @@ package perfdata
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
-// Processor handles performance data output.
+// Default file templates, as in Nagios.
+const (
+	DefaultHostFileTemplate    = `[HOSTPERFDATA]\t$TIMET$\t$HOSTNAME$\t$HOSTEXECUTIONTIME$\t$HOSTOUTPUT$\t$HOSTPERFDATA$`
+	DefaultServiceFileTemplate = `[SERVICEPERFDATA]\t$TIMET$\t$HOSTNAME$\t$SERVICEDESC$\t$SERVICEEXECUTIONTIME$\t$SERVICELATENCY$\t$SERVICEOUTPUT$\t$SERVICEPERFDATA$`
+)
+
+// pipeWriteTimeout bounds how long a line waits for room in a pipe whose
+// reader has fallen behind. Results are not held up longer than that.
+const pipeWriteTimeout = 100 * time.Millisecond
+
+// Processor handles performance data output: it runs the host and service
+// perfdata commands, writes the perfdata files from their templates, and
+// runs the file processing commands every processing interval.
 type Processor struct {
 	Global *objects.GlobalState
+	// Expand expands the macros of a template or command line. The caller
+	// holds the store lock when UpdateHostPerfdata or UpdateServicePerfdata
+	// is called. When nil, a basic set of host and service macros is used.
+	Expand func(cmdLine string, h *objects.Host, svc *objects.Service, args []string) string
+	// Timeout bounds the perfdata and file processing commands
+	// (perfdata_timeout).
+	Timeout time.Duration
+	// DryRun skips running the perfdata and file processing commands
+	// (simulation mode). The perfdata files are still written.
+	DryRun bool
+
+	hostFile, serviceFile   *perfdataFile
+	hostCmd, svcCmd         *objects.Command
+	hostArgs, svcArgs       []string
+	hostProcCmd, svcProcCmd *objects.Command
+	hostProcArgs            []string
+	svcProcArgs             []string
+
+	logFunc func(string, ...interface{})
+	run     func(cmdLine string, timeout time.Duration) error
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
 
-	// File handles (opened once, reused)
-	hostFile    *os.File
-	serviceFile *os.File
+// perfdataFile is one of the perfdata files. Its lock is held while the
+// file is being processed, so no lines are written to it meanwhile.
+type perfdataFile struct {
+	mu       sync.Mutex
+	kind     string // "host" or "service"
+	path     string
+	mode     int
+	template string
+	f        *os.File
+	// failed is set once an open or write error has been logged, so a
+	// missing reader on a pipe is not logged for every result.
+	failed bool
 }
 
 // NewProcessor creates a new perfdata processor.
 func NewProcessor(gs *objects.GlobalState) *Processor {
-	return &Processor{Global: gs}
+	return &Processor{
+		Global:  gs,
+		Timeout: 5 * time.Second,
+		logFunc: func(string, ...interface{}) {},
+		run:     runCommand,
+	}
 }
 
-// OpenFiles opens the perfdata files for writing.
-func (p *Processor) OpenFiles() error {
+// ParseFileMode converts a host_perfdata_file_mode or
+// service_perfdata_file_mode value (a, w or p) to a PerfdataFile* mode.
+func ParseFileMode(c byte) int {
+	switch c {
+	case 'w':
+		return objects.PerfdataFileWrite
+	case 'p':
+		return objects.PerfdataFilePipe
+	}
+	return objects.PerfdataFileAppend
+}
+
+// SetLogger sets the function failures are logged with.
+func (p *Processor) SetLogger(fn func(string, ...interface{})) {
+	p.logFunc = fn
+}
+
+// Start resolves the perfdata and file processing command names against
+// the store's commands, opens the perfdata files and starts running the
+// file processing commands. Commands that are not defined are logged and
+// left out.
+func (p *Processor) Start(store *objects.ObjectStore) {
+	gs := p.Global
 	var err error
-	if p.Global.HostPerfdataFile != "" {
-		p.hostFile, err = openPerfdataFile(p.Global.HostPerfdataFile, p.Global.HostPerfdataFileMode)
-		if err != nil {
-			return err
+	if p.hostCmd, p.hostArgs, err = lookupCommand(store, gs.HostPerfdataCommand); err != nil {
+		p.logFunc("Warning: Host performance data command %v, it will not be run", err)
+	}
+	if p.svcCmd, p.svcArgs, err = lookupCommand(store, gs.ServicePerfdataCommand); err != nil {
+		p.logFunc("Warning: Service performance data command %v, it will not be run", err)
+	}
+	if p.hostProcCmd, p.hostProcArgs, err = lookupCommand(store, gs.HostPerfdataFileProcessingCommand); err != nil {
+		p.logFunc("Warning: Host performance data file processing command %v, it will not be run", err)
+	}
+	if p.svcProcCmd, p.svcProcArgs, err = lookupCommand(store, gs.ServicePerfdataFileProcessingCommand); err != nil {
+		p.logFunc("Warning: Service performance data file processing command %v, it will not be run", err)
+	}
+	if err := p.OpenFiles(); err != nil {
+		p.logFunc("Warning: %v", err)
+	}
+
+	p.stop = make(chan struct{})
+	if p.hostProcCmd != nil && gs.HostPerfdataFileProcessingInterval > 0 {
+		p.every(time.Duration(gs.HostPerfdataFileProcessingInterval)*time.Second, p.RunHostFileProcessingCommand)
+	}
+	if p.svcProcCmd != nil && gs.ServicePerfdataFileProcessingInterval > 0 {
+		p.every(time.Duration(gs.ServicePerfdataFileProcessingInterval)*time.Second, p.RunServiceFileProcessingCommand)
+	}
+}
+
+func (p *Processor) every(interval time.Duration, fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				fn()
+			case <-p.stop:
+				return
+			}
 		}
+	}()
+}
+
+func lookupCommand(store *objects.ObjectStore, spec string) (*objects.Command, []string, error) {
+	if spec == "" {
+		return nil, nil, nil
+	}
+	parts := strings.Split(spec, "!")
+	cmd := store.GetCommand(parts[0])
+	if cmd == nil {
+		return nil, nil, fmt.Errorf("'%s' is not defined anywhere", parts[0])
 	}
-	if p.Global.ServicePerfdataFile != "" {
-		p.serviceFile, err = openPerfdataFile(p.Global.ServicePerfdataFile, p.Global.ServicePerfdataFileMode)
-		if err != nil {
-			return err
+	return cmd, parts[1:], nil
+}
+
+// OpenFiles opens the perfdata files for writing. A pipe is opened without
+// waiting for a reader; while there is none, writing is retried with each
+// result.
+func (p *Processor) OpenFiles() error {
+	gs := p.Global
+	if gs.HostPerfdataFile != "" {
+		p.hostFile = &perfdataFile{kind: "host", path: gs.HostPerfdataFile, mode: gs.HostPerfdataFileMode,
+			template: fileTemplate(gs.HostPerfdataFileTemplate, DefaultHostFileTemplate)}
+	}
+	if gs.ServicePerfdataFile != "" {
+		p.serviceFile = &perfdataFile{kind: "service", path: gs.ServicePerfdataFile, mode: gs.ServicePerfdataFileMode,
+			template: fileTemplate(gs.ServicePerfdataFileTemplate, DefaultServiceFileTemplate)}
+	}
+	var errs []error
+	for _, pf := range []*perfdataFile{p.hostFile, p.serviceFile} {
+		if pf == nil {
+			continue
 		}
+		pf.mu.Lock()
+		if err := pf.open(); err != nil {
+			pf.failed = true
+			errs = append(errs, fmt.Errorf("unable to open %s performance data file '%s': %w", pf.kind, pf.path, err))
+		}
+		pf.mu.Unlock()
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-// Close closes any open perfdata files.
+// fileTemplate returns template, or def if it is empty, with \t and \n
+// turned into tabs and newlines.
+func fileTemplate(template, def string) string {
+	if template == "" {
+		template = def
+	}
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(template)
+}
+
+// Close stops the file processing commands and closes the perfdata files.
 func (p *Processor) Close() {
-	if p.hostFile != nil {
-		p.hostFile.Close()
-		p.hostFile = nil
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+		p.stop = nil
 	}
-	if p.serviceFile != nil {
-		p.serviceFile.Close()
-		p.serviceFile = nil
+	for _, pf := range []*perfdataFile{p.hostFile, p.serviceFile} {
+		if pf != nil {
+			pf.mu.Lock()
+			pf.close()
+			pf.mu.Unlock()
+		}
 	}
 }
 
@@ -64,18 +218,13 @@ func (p *Processor) UpdateHostPerfdata(h *objects.Host) {
 		return
 	}
 
-	macros := hostMacros(h)
-
-	// Perfdata command
-	if p.Global.HostPerfdataCommand != "" {
-		cmdLine := expandMacros(p.Global.HostPerfdataCommand, macros)
-		go runCommand(cmdLine, 30*time.Second)
+	if p.hostCmd != nil {
+		cmdLine := p.expand(p.hostCmd.CommandLine, h, nil, p.hostArgs)
+		what := fmt.Sprintf("Host performance data command '%s' for host '%s'", p.hostCmd.Name, h.Name)
+		go p.exec(cmdLine, what)
 	}
-
-	// Perfdata file
-	if p.hostFile != nil && p.Global.HostPerfdataFileTemplate != "" {
-		line := expandMacros(p.Global.HostPerfdataFileTemplate, macros)
-		p.hostFile.WriteString(line + "\n")
+	if p.hostFile != nil {
+		p.write(p.hostFile, p.expand(p.hostFile.template, h, nil, nil))
 	}
 }
 
@@ -88,56 +237,125 @@ func (p *Processor) UpdateServicePerfdata(s *objects.Service) {
 		return
 	}
 
-	macros := serviceMacros(s)
-
-	if p.Global.ServicePerfdataCommand != "" {
-		cmdLine := expandMacros(p.Global.ServicePerfdataCommand, macros)
-		go runCommand(cmdLine, 30*time.Second)
+	if p.svcCmd != nil {
+		cmdLine := p.expand(p.svcCmd.CommandLine, s.Host, s, p.svcArgs)
+		what := fmt.Sprintf("Service performance data command '%s' for service '%s'", p.svcCmd.Name, s.Description)
+		go p.exec(cmdLine, what)
 	}
-
-	if p.serviceFile != nil && p.Global.ServicePerfdataFileTemplate != "" {
-		line := expandMacros(p.Global.ServicePerfdataFileTemplate, macros)
-		p.serviceFile.WriteString(line + "\n")
+	if p.serviceFile != nil {
+		p.write(p.serviceFile, p.expand(p.serviceFile.template, s.Host, s, nil))
 	}
 }
 
-// RunHostFileProcessingCommand runs the host perfdata file processing command.
+// RunHostFileProcessingCommand runs the host perfdata file processing
+// command. The file is closed while it runs, so the command may move it
+// away, and reopened afterwards.
 func (p *Processor) RunHostFileProcessingCommand() {
-	if p.Global.HostPerfdataFileProcessingCommand == "" {
+	p.processFile(p.hostFile, p.hostProcCmd, p.hostProcArgs, "Host")
+}
+
+// RunServiceFileProcessingCommand runs the service perfdata file processing
+// command, like RunHostFileProcessingCommand.
+func (p *Processor) RunServiceFileProcessingCommand() {
+	p.processFile(p.serviceFile, p.svcProcCmd, p.svcProcArgs, "Service")
+}
+
+func (p *Processor) processFile(pf *perfdataFile, cmd *objects.Command, args []string, kind string) {
+	if cmd == nil {
 		return
 	}
-	// Close and reopen if in write mode
-	if p.hostFile != nil && p.Global.HostPerfdataFileMode == objects.PerfdataFileWrite {
-		p.hostFile.Close()
-		p.hostFile = nil
-	}
-	runCommand(p.Global.HostPerfdataFileProcessingCommand, 60*time.Second)
-	if p.Global.HostPerfdataFile != "" && p.Global.HostPerfdataFileMode == objects.PerfdataFileWrite {
-		var err error
-		p.hostFile, err = openPerfdataFile(p.Global.HostPerfdataFile, p.Global.HostPerfdataFileMode)
-		if err != nil {
-			p.hostFile = nil
-		}
+	cmdLine := p.expand(cmd.CommandLine, nil, nil, args)
+	what := fmt.Sprintf("%s performance data file processing command '%s'", kind, cmd.Name)
+	if pf == nil {
+		p.exec(cmdLine, what)
+		return
+	}
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	pf.close()
+	p.exec(cmdLine, what)
+	if err := pf.open(); err != nil && !pf.failed {
+		pf.failed = true
+		p.logFunc("Warning: Unable to reopen %s performance data file '%s': %v", pf.kind, pf.path, err)
 	}
 }
 
-// RunServiceFileProcessingCommand runs the service perfdata file processing command.
-func (p *Processor) RunServiceFileProcessingCommand() {
-	if p.Global.ServicePerfdataFileProcessingCommand == "" {
+func (p *Processor) expand(template string, h *objects.Host, s *objects.Service, args []string) string {
+	if p.Expand != nil {
+		return p.Expand(template, h, s, args)
+	}
+	macros := map[string]string{}
+	switch {
+	case s != nil:
+		macros = serviceMacros(s)
+	case h != nil:
+		macros = hostMacros(h)
+	}
+	for i, arg := range args {
+		macros[fmt.Sprintf("ARG%d", i+1)] = arg
+	}
+	return expandMacros(template, macros)
+}
+
+func (p *Processor) exec(cmdLine, what string) {
+	if p.DryRun {
 		return
 	}
-	if p.serviceFile != nil && p.Global.ServicePerfdataFileMode == objects.PerfdataFileWrite {
-		p.serviceFile.Close()
-		p.serviceFile = nil
+	err := p.run(cmdLine, p.Timeout)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		p.logFunc("Warning: %s timed out after %.0f seconds", what, p.Timeout.Seconds())
+	case err != nil:
+		p.logFunc("Warning: %s failed: %v", what, err)
 	}
-	runCommand(p.Global.ServicePerfdataFileProcessingCommand, 60*time.Second)
-	if p.Global.ServicePerfdataFile != "" && p.Global.ServicePerfdataFileMode == objects.PerfdataFileWrite {
-		var err error
-		p.serviceFile, err = openPerfdataFile(p.Global.ServicePerfdataFile, p.Global.ServicePerfdataFileMode)
-		if err != nil {
-			p.serviceFile = nil
+}
+
+// write appends line to pf, opening it first if an earlier open failed.
+// A line that cannot be written, such as to a pipe that is full, is dropped.
+func (p *Processor) write(pf *perfdataFile, line string) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	if pf.f == nil {
+		if err := pf.open(); err != nil {
+			if !pf.failed {
+				pf.failed = true
+				p.logFunc("Warning: Unable to open %s performance data file '%s': %v", pf.kind, pf.path, err)
+			}
+			return
 		}
 	}
+	if pf.mode == objects.PerfdataFilePipe {
+		pf.f.SetWriteDeadline(time.Now().Add(pipeWriteTimeout))
+	}
+	if _, err := pf.f.WriteString(line + "\n"); err != nil {
+		if !pf.failed {
+			pf.failed = true
+			p.logFunc("Warning: Unable to write to %s performance data file '%s': %v", pf.kind, pf.path, err)
+		}
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			// Reopen with the next line, e.g. once a pipe has a reader again.
+			pf.close()
+		}
+		return
+	}
+	pf.failed = false
+}
+
+func (pf *perfdataFile) open() error {
+	f, err := openPerfdataFile(pf.path, pf.mode)
+	if err != nil {
+		return err
+	}
+	pf.f = f
+	pf.failed = false
+	return nil
+}
+
+func (pf *perfdataFile) close() {
+	if pf.f != nil {
+		pf.f.Close()
+		pf.f = nil
+	}
 }
 
 func openPerfdataFile(path string, mode int) (*os.File, error) {
@@ -145,7 +363,8 @@ func openPerfdataFile(path string, mode int) (*os.File, error) {
 	case objects.PerfdataFileWrite:
 		return os.Create(path)
 	case objects.PerfdataFilePipe:
-		return os.OpenFile(path, os.O_WRONLY, 0)
+		// Opening a pipe for writing would otherwise wait for a reader.
+		return os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
 	default: // append
 		return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	}
@@ -154,7 +373,11 @@ func openPerfdataFile(path string, mode int) (*os.File, error) {
 func runCommand(cmdLine string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	return exec.CommandContext(ctx, "/bin/sh", "-c", cmdLine).Run()
+	err := exec.CommandContext(ctx, "/bin/sh", "-c", cmdLine).Run()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 func expandMacros(template string, macros map[string]string) string {
@@ -3,7 +3,11 @@ package perfdata
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/oceanplexian/gogios/internal/objects"
 )
@@ -166,3 +170,139 @@ func TestOpenPerfdataFile_Write(t *testing.T) {
 		t.Error("expected file to exist")
 	}
 }
+
+func TestProcessorFilesAndCommands(t *testing.T) {
+	dir := t.TempDir()
+	store := objects.NewObjectStore()
+	store.AddCommand(&objects.Command{Name: "process-service-perfdata", CommandLine: "send $SERVICEDESC$ $ARG1$"})
+	gs := &objects.GlobalState{
+		ProcessPerformanceData:      true,
+		ServicePerfdataCommand:      "process-service-perfdata!graphios",
+		HostPerfdataFile:            filepath.Join(dir, "host-perfdata"),
+		ServicePerfdataFile:         filepath.Join(dir, "service-perfdata"),
+		ServicePerfdataFileTemplate: `$HOSTNAME$\t$SERVICEDESC$\t$SERVICEPERFDATA$`,
+	}
+	p := NewProcessor(gs)
+	var mu sync.Mutex
+	var ran []string
+	p.run = func(cmdLine string, _ time.Duration) error {
+		mu.Lock()
+		ran = append(ran, cmdLine)
+		mu.Unlock()
+		return nil
+	}
+	p.Start(store)
+	defer p.Close()
+
+	h := &objects.Host{Name: "web1", ProcessPerfData: true, PluginOutput: "PING OK", PerfData: "rta=1ms"}
+	s := &objects.Service{Host: h, Description: "HTTP", ProcessPerfData: true, PerfData: "time=0.1s"}
+	p.UpdateHostPerfdata(h)
+	p.UpdateServicePerfdata(s)
+	p.UpdateServicePerfdata(&objects.Service{Host: h, Description: "Empty", ProcessPerfData: true})
+
+	data, _ := os.ReadFile(gs.ServicePerfdataFile)
+	if string(data) != "web1\tHTTP\ttime=0.1s\n" {
+		t.Errorf("service perfdata file: %q", data)
+	}
+	// The default template is used without one; $TIMET$ is left to the
+	// macro expander.
+	data, _ = os.ReadFile(gs.HostPerfdataFile)
+	if !strings.HasPrefix(string(data), "[HOSTPERFDATA]\t$TIMET$\tweb1\t") || !strings.HasSuffix(string(data), "\tPING OK\trta=1ms\n") {
+		t.Errorf("host perfdata file: %q", data)
+	}
+	time.Sleep(20 * time.Millisecond) // the command runs in the background
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ran) != 1 || ran[0] != "send HTTP graphios" {
+		t.Errorf("perfdata commands: %q", ran)
+	}
+	mu.Unlock()
+
+	// Simulation mode writes the file but runs no command.
+	p.DryRun = true
+	p.UpdateServicePerfdata(s)
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	if len(ran) != 1 {
+		t.Errorf("perfdata command ran in dry run: %q", ran)
+	}
+}
+
+func TestProcessorFileProcessingCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service-perfdata")
+	store := objects.NewObjectStore()
+	store.AddCommand(&objects.Command{Name: "move-perfdata", CommandLine: "mv " + path + " " + path + ".$ARG1$"})
+	gs := &objects.GlobalState{
+		ProcessPerformanceData:               true,
+		ServicePerfdataFile:                  path,
+		ServicePerfdataFileTemplate:          "$SERVICEPERFDATA$",
+		ServicePerfdataFileProcessingCommand: "move-perfdata!spool",
+	}
+	p := NewProcessor(gs)
+	p.Start(store)
+	defer p.Close()
+
+	s := &objects.Service{Description: "HTTP", ProcessPerfData: true, PerfData: "time=1s"}
+	p.UpdateServicePerfdata(s)
+	p.RunServiceFileProcessingCommand()
+	s.PerfData = "time=2s"
+	p.UpdateServicePerfdata(s)
+
+	// The file is closed while the command moves it, so the next line goes
+	// to a new file rather than to the moved one.
+	if data, _ := os.ReadFile(path + ".spool"); string(data) != "time=1s\n" {
+		t.Errorf("moved file: %q", data)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "time=2s\n" {
+		t.Errorf("new file: %q", data)
+	}
+}
+
+func TestProcessorPipeWithoutReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "perfdata.pipe")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Skip("mkfifo:", err)
+	}
+	gs := &objects.GlobalState{
+		ProcessPerformanceData:  true,
+		ServicePerfdataFile:     path,
+		ServicePerfdataFileMode: objects.PerfdataFilePipe,
+	}
+	p := NewProcessor(gs)
+	var logged []string
+	p.SetLogger(func(format string, args ...interface{}) {
+		logged = append(logged, format)
+	})
+	p.Start(objects.NewObjectStore())
+	defer p.Close()
+
+	// Without a reader, lines are dropped rather than blocking, and the
+	// failure is logged once.
+	s := &objects.Service{Description: "HTTP", ProcessPerfData: true, PerfData: "time=1s"}
+	p.UpdateServicePerfdata(s)
+	p.UpdateServicePerfdata(s)
+	if len(logged) != 1 {
+		t.Errorf("logged %d warnings: %q", len(logged), logged)
+	}
+
+	r, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	p.UpdateServicePerfdata(s)
+	buf := make([]byte, 512)
+	n, _ := r.Read(buf)
+	if !strings.Contains(string(buf[:n]), "[SERVICEPERFDATA]") {
+		t.Errorf("pipe read %q", buf[:n])
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	for c, want := range map[byte]int{'a': objects.PerfdataFileAppend, 'w': objects.PerfdataFileWrite, 'p': objects.PerfdataFilePipe} {
+		if got := ParseFileMode(c); got != want {
+			t.Errorf("ParseFileMode(%q) = %d, want %d", c, got, want)
+		}
+	}
+}
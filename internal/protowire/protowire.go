@@ -0,0 +1,154 @@
+// Package protowire implements the subset of the protobuf wire format that
+// gogios speaks without the protobuf runtime: scalar, string and nested
+// message fields, enough for the hand-written .proto schemas in the tree.
+package protowire
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+	wire32     = 5
+)
+
+// ErrTruncated is returned for a message that ends inside a field.
+var ErrTruncated = errors.New("protobuf: truncated message")
+
+// Encoder appends fields to a message. Zero scalars and empty strings are
+// omitted, as proto3 does.
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes returns the encoded message.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+// Int64 encodes an int64 field.
+func (e *Encoder) Int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, uint64(v))
+}
+
+// Uint64 encodes a uint64 field.
+func (e *Encoder) Uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// Int32 encodes an int32 field.
+func (e *Encoder) Int32(field int, v int) {
+	e.Int64(field, int64(v))
+}
+
+// Bool encodes a bool field.
+func (e *Encoder) Bool(field int, v bool) {
+	if v {
+		e.tag(field, wireVarint)
+		e.buf = append(e.buf, 1)
+	}
+}
+
+// Double encodes a double field.
+func (e *Encoder) Double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wire64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// String encodes a string field.
+func (e *Encoder) String(field int, v string) {
+	if v == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// RepeatedString encodes every element, including empty strings.
+func (e *Encoder) RepeatedString(field int, vs []string) {
+	for _, v := range vs {
+		e.tag(field, wireBytes)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(v)))
+		e.buf = append(e.buf, v...)
+	}
+}
+
+// Message encodes an already encoded nested message.
+func (e *Encoder) Message(field int, m []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(m)))
+	e.buf = append(e.buf, m...)
+}
+
+// Field is one decoded field. For varints and fixed64 fields only Num is
+// set; for length-delimited fields Data holds the payload.
+type Field struct {
+	Number int
+	Num    uint64
+	Data   []byte
+}
+
+// DecodeFields splits a message into its fields, skipping fixed32 ones.
+func DecodeFields(b []byte) ([]Field, error) {
+	var fields []Field
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, ErrTruncated
+		}
+		b = b[n:]
+		f := Field{Number: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, ErrTruncated
+			}
+			f.Num = v
+			b = b[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, ErrTruncated
+			}
+			f.Data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case wire64:
+			if len(b) < 8 {
+				return nil, ErrTruncated
+			}
+			f.Num = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wire32:
+			if len(b) < 4 {
+				return nil, ErrTruncated
+			}
+			b = b[4:]
+			continue
+		default:
+			return nil, errors.New("protobuf: unsupported wire type")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
@@ -0,0 +1,242 @@
+// Package replay feeds HOST and SERVICE ALERT lines from Nagios/Gogios logs
+// back through the check result state machine, to reconstruct object state
+// from archived logs and to find where the current configuration and
+// engine disagree with what was logged.
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/checker"
+	"github.com/oceanplexian/gogios/internal/logging"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Event is a logged alert or state line.
+type Event struct {
+	Time      time.Time
+	Line      int // line number in its file
+	File      string
+	HostName  string
+	Service   string // empty for host events
+	State     string
+	StateType string
+	Attempt   int
+	Output    string
+	// Seed is set for INITIAL and CURRENT STATE lines, which set the state
+	// directly instead of being replayed.
+	Seed bool
+}
+
+// ReadEvents returns the alert and state lines of r, a log named file.
+func ReadEvents(r io.Reader, file string) ([]Event, error) {
+	var events []Event
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := sc.Text()
+		if len(line) < 3 || line[0] != '[' {
+			continue
+		}
+		end := strings.IndexByte(line, ']')
+		if end < 0 {
+			continue
+		}
+		ts, err := strconv.ParseInt(line[1:end], 10, 64)
+		if err != nil {
+			continue
+		}
+		msg := strings.TrimSpace(line[end+1:])
+		seed := false
+		for _, prefix := range []string{"INITIAL ", "CURRENT "} {
+			if strings.HasPrefix(msg, prefix+"HOST STATE: ") || strings.HasPrefix(msg, prefix+"SERVICE STATE: ") {
+				msg = strings.Replace(strings.TrimPrefix(msg, prefix), " STATE: ", " ALERT: ", 1)
+				seed = true
+			}
+		}
+		a := logging.ParseAlert(msg)
+		if a == nil || (a.Type != "host_alert" && a.Type != "service_alert") {
+			continue
+		}
+		events = append(events, Event{
+			Time: time.Unix(ts, 0), Line: n, File: file,
+			HostName: a.HostName, Service: a.ServiceDescription,
+			State: a.State, StateType: a.StateType, Attempt: a.Attempt,
+			Output: a.Output, Seed: seed,
+		})
+	}
+	return events, sc.Err()
+}
+
+// Mismatch is a replayed alert after which the object's state differs from
+// the logged one.
+type Mismatch struct {
+	Event
+	GotState     string
+	GotStateType string
+	GotAttempt   int
+}
+
+func (m Mismatch) String() string {
+	name := m.HostName
+	if m.Service != "" {
+		name += ";" + m.Service
+	}
+	return fmt.Sprintf("%s:%d: %s: logged %s;%s;%d, replayed %s;%s;%d", m.File, m.Line, name,
+		m.State, m.StateType, m.Attempt, m.GotState, m.GotStateType, m.GotAttempt)
+}
+
+// Report summarizes a replay.
+type Report struct {
+	Events     int
+	Replayed   int // alerts fed through the state machine, excluding seeds
+	Synthetic  int // results added for retries the log does not show
+	Unknown    int // events for hosts or services not in the configuration
+	Mismatches []Mismatch
+}
+
+// Replayer replays events against the objects of Store, which it modifies.
+type Replayer struct {
+	Store   *objects.ObjectStore
+	Service *checker.ServiceResultHandler
+	Host    *checker.HostResultHandler
+}
+
+// New returns a Replayer whose result handlers use cfg and exitCodes and
+// have no callbacks, so nothing is notified or scheduled.
+func New(store *objects.ObjectStore, cfg *objects.Config, exitCodes *checker.ExitCodeMap) *Replayer {
+	return &Replayer{
+		Store:   store,
+		Service: &checker.ServiceResultHandler{Cfg: cfg, ExitCodes: exitCodes, HostLookup: store.GetHost},
+		Host:    &checker.HostResultHandler{Cfg: cfg, ExitCodes: exitCodes},
+	}
+}
+
+// Replay sorts events by time, keeping the order of events logged in the
+// same second, and replays them.
+func (r *Replayer) Replay(events []Event) *Report {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	rep := &Report{Events: len(events)}
+	for _, ev := range events {
+		if ev.Service != "" {
+			svc := r.Store.GetService(ev.HostName, ev.Service)
+			if svc == nil {
+				rep.Unknown++
+				continue
+			}
+			r.replayService(svc, ev, rep)
+		} else {
+			h := r.Store.GetHost(ev.HostName)
+			if h == nil {
+				rep.Unknown++
+				continue
+			}
+			r.replayHost(h, ev, rep)
+		}
+	}
+	return rep
+}
+
+func (r *Replayer) replayService(svc *objects.Service, ev Event, rep *Report) {
+	state := serviceState(ev.State)
+	if ev.Seed {
+		svc.CurrentState, svc.LastState = state, state
+		svc.StateType = stateType(ev.StateType)
+		svc.CurrentAttempt = ev.Attempt
+		svc.PluginOutput = ev.Output
+		svc.HasBeenChecked = true
+		svc.LastCheck, svc.LastStateChange = ev.Time, ev.Time
+		return
+	}
+	cr := &objects.CheckResult{
+		HostName: ev.HostName, ServiceDescription: ev.Service,
+		CheckType: objects.CheckTypeActive, ReturnCode: state, ExitedOK: true,
+		Output: ev.Output, StartTime: ev.Time, FinishTime: ev.Time,
+	}
+	// Without log_service_retries only the first SOFT alert and the HARD
+	// one are logged; supply the retries in between.
+	for svc.CurrentState == state && svc.StateType == objects.StateTypeSoft && svc.CurrentAttempt < ev.Attempt-1 {
+		r.Service.HandleResult(svc, cr)
+		rep.Synthetic++
+	}
+	r.Service.HandleResult(svc, cr)
+	rep.Replayed++
+	got := objects.ServiceStateName(svc.CurrentState)
+	r.compare(ev, got, svc.StateType, svc.CurrentAttempt, rep)
+}
+
+func (r *Replayer) replayHost(h *objects.Host, ev Event, rep *Report) {
+	state := hostState(ev.State)
+	if ev.Seed {
+		h.CurrentState, h.LastState = state, state
+		h.StateType = stateType(ev.StateType)
+		h.CurrentAttempt = ev.Attempt
+		h.PluginOutput = ev.Output
+		h.HasBeenChecked = true
+		h.LastCheck, h.LastStateChange = ev.Time, ev.Time
+		return
+	}
+	rc := 0
+	if state != objects.HostUp {
+		rc = 2 // DOWN; UNREACHABLE is derived from the parents' states
+	}
+	cr := &objects.CheckResult{
+		HostName: ev.HostName, CheckType: objects.CheckTypeActive,
+		ReturnCode: rc, ExitedOK: true,
+		Output: ev.Output, StartTime: ev.Time, FinishTime: ev.Time,
+	}
+	for h.CurrentState == state && h.StateType == objects.StateTypeSoft && h.CurrentAttempt < ev.Attempt-1 {
+		checker.AdjustHostCheckAttempt(h)
+		r.Host.HandleResult(h, cr)
+		rep.Synthetic++
+	}
+	checker.AdjustHostCheckAttempt(h)
+	r.Host.HandleResult(h, cr)
+	rep.Replayed++
+	r.compare(ev, objects.HostStateName(h.CurrentState), h.StateType, h.CurrentAttempt, rep)
+}
+
+func (r *Replayer) compare(ev Event, state string, typ, attempt int, rep *Report) {
+	gotType := objects.StateTypeName(typ)
+	if state == ev.State && gotType == ev.StateType && attempt == ev.Attempt {
+		return
+	}
+	rep.Mismatches = append(rep.Mismatches, Mismatch{Event: ev, GotState: state, GotStateType: gotType, GotAttempt: attempt})
+}
+
+func serviceState(name string) int {
+	switch name {
+	case "OK":
+		return objects.ServiceOK
+	case "WARNING":
+		return objects.ServiceWarning
+	case "CRITICAL":
+		return objects.ServiceCritical
+	}
+	return objects.ServiceUnknown
+}
+
+func hostState(name string) int {
+	switch name {
+	case "UP":
+		return objects.HostUp
+	case "UNREACHABLE":
+		return objects.HostUnreachable
+	}
+	return objects.HostDown
+}
+
+func stateType(name string) int {
+	if name == "SOFT" {
+		return objects.StateTypeSoft
+	}
+	return objects.StateTypeHard
+}
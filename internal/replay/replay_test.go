@@ -0,0 +1,69 @@
+package replay
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+const testLog = `[1700000000] Gogios 1.0.0 starting... (PID=1)
+[1700000000] INITIAL HOST STATE: web01;UP;HARD;1;PING OK
+[1700000000] INITIAL SERVICE STATE: web01;HTTP;OK;HARD;1;HTTP OK
+[1700000060] SERVICE ALERT: web01;HTTP;CRITICAL;SOFT;1;Connection refused
+[1700000180] SERVICE ALERT: web01;HTTP;CRITICAL;HARD;3;Connection refused
+[1700000240] SERVICE ALERT: web01;HTTP;OK;HARD;1;HTTP OK
+[1700000300] SERVICE ALERT: db01;PgSQL;CRITICAL;SOFT;1;down
+[1700000360] HOST ALERT: web01;DOWN;SOFT;1;PING CRITICAL
+[1700000420] HOST ALERT: web01;DOWN;HARD;5;PING CRITICAL
+`
+
+func testStore(t *testing.T) *objects.ObjectStore {
+	t.Helper()
+	store := objects.NewObjectStore()
+	h := &objects.Host{Name: "web01", MaxCheckAttempts: 3}
+	if err := store.AddHost(h); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddService(&objects.Service{Host: h, Description: "HTTP", MaxCheckAttempts: 3}); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestReplay(t *testing.T) {
+	events, err := ReadEvents(strings.NewReader(testLog), "nagios.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 8 {
+		t.Fatalf("read %d events, want 8", len(events))
+	}
+	store := testStore(t)
+	rep := New(store, objects.DefaultConfig(), nil).Replay(events)
+
+	// The host's max_check_attempts was 5 when the log was written and is
+	// 3 now, so the HARD alert comes at a different attempt.
+	if rep.Replayed != 5 || rep.Synthetic != 3 || rep.Unknown != 1 || len(rep.Mismatches) != 1 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if got := rep.Mismatches[0].String(); got != "nagios.log:9: web01: logged DOWN;HARD;5, replayed DOWN;HARD;3" {
+		t.Errorf("mismatch = %q", got)
+	}
+	svc := store.GetService("web01", "HTTP")
+	if svc.CurrentState != objects.ServiceOK || svc.StateType != objects.StateTypeHard || svc.PluginOutput != "HTTP OK" {
+		t.Errorf("service ended %d/%d %q", svc.CurrentState, svc.StateType, svc.PluginOutput)
+	}
+	if h := store.GetHost("web01"); h.CurrentState != objects.HostDown || h.LastStateChange.Unix() != 1700000360 {
+		t.Errorf("host ended %d, changed at %v", h.CurrentState, h.LastStateChange)
+	}
+}
+
+func TestReplaySortsAcrossFiles(t *testing.T) {
+	older, _ := ReadEvents(strings.NewReader("[1700000060] SERVICE ALERT: web01;HTTP;CRITICAL;SOFT;1;refused\n"), "a.log")
+	newer, _ := ReadEvents(strings.NewReader("[1700000120] SERVICE ALERT: web01;HTTP;CRITICAL;SOFT;2;refused\n"), "b.log")
+	rep := New(testStore(t), objects.DefaultConfig(), nil).Replay(append(newer, older...))
+	if len(rep.Mismatches) != 0 {
+		t.Errorf("unexpected mismatches %v", rep.Mismatches)
+	}
+}
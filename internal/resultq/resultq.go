@@ -0,0 +1,326 @@
+// Package resultq puts an overflow strategy in front of the scheduler's
+// result channel. Producers that must not block (NRDP handlers, external
+// command handlers) submit through a Queue: results go straight to the
+// channel while it has room, spill to an append-only file on disk when it is
+// full, and are only dropped, and counted, when no spill file is configured
+// or the undrained results have reached the size limit. A drainer feeds
+// spilled results back into the channel in order as the scheduler catches
+// up.
+package resultq
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// compactMinBytes is how much of the spill file must have been drained
+// before it is compacted while results are still pending.
+var compactMinBytes int64 = 1 << 20
+
+// Stats is a snapshot of queue depth and overflow counters.
+type Stats struct {
+	Depth      int   // results waiting in the channel
+	Capacity   int   // channel capacity
+	SpillDepth int64 // results waiting in the spill file
+	Spilled    int64 // results written to the spill file since start
+	Dropped    int64 // results dropped since start
+}
+
+// Queue is a non-blocking front for a result channel.
+type Queue struct {
+	ch      chan *objects.CheckResult
+	spilled atomic.Int64
+	dropped atomic.Int64
+	logFunc func(format string, args ...interface{})
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	path     string
+	maxBytes int64
+	w        *os.File // append handle, nil when spilling is disabled
+	r        *os.File
+	br       *bufio.Reader
+	size     int64 // bytes of results in the spill file not yet drained
+	drained  int64 // bytes at the head of the spill file already drained
+	pending  int64 // results in the spill file not yet drained
+	closed   bool
+	started  bool
+	stopCh   chan struct{}
+	done     chan struct{}
+	unsent   []byte // line read by the drainer but not delivered at Stop
+}
+
+// New creates a queue in front of ch. spillPath enables the disk spill
+// queue; an existing file is replayed when the drainer starts, so results
+// spilled before a restart are not lost. maxBytes caps the size of the
+// undrained results in the spill file (0 = unlimited).
+func New(ch chan *objects.CheckResult, spillPath string, maxBytes int64) (*Queue, error) {
+	q := &Queue{
+		ch:       ch,
+		path:     spillPath,
+		maxBytes: maxBytes,
+		logFunc:  log.Printf,
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	if spillPath == "" {
+		return q, nil
+	}
+	w, err := os.OpenFile(spillPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("result spill file: %w", err)
+	}
+	r, err := os.Open(spillPath)
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("result spill file: %w", err)
+	}
+	q.w, q.r, q.br = w, r, bufio.NewReader(r)
+	// Count results left over from a previous run.
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		q.pending++
+		q.size += int64(len(sc.Bytes())) + 1
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		w.Close()
+		r.Close()
+		return nil, fmt.Errorf("result spill file: %w", err)
+	}
+	return q, nil
+}
+
+// SetLogger overrides the default log function.
+func (q *Queue) SetLogger(fn func(string, ...interface{})) {
+	q.logFunc = fn
+}
+
+// Submit queues cr without blocking. It returns false if the result was
+// dropped. Once results are spilling, later results spill too so the
+// scheduler still sees them in submission order.
+func (q *Queue) Submit(cr *objects.CheckResult) bool {
+	q.mu.Lock()
+	spilling := q.pending > 0
+	q.mu.Unlock()
+	if !spilling {
+		select {
+		case q.ch <- cr:
+			return true
+		default:
+		}
+	}
+	if q.spill(cr) {
+		return true
+	}
+	q.dropped.Add(1)
+	return false
+}
+
+func (q *Queue) spill(cr *objects.CheckResult) bool {
+	if q.w == nil {
+		return false
+	}
+	line, err := json.Marshal(cr)
+	if err != nil {
+		return false
+	}
+	line = append(line, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || (q.maxBytes > 0 && q.size+int64(len(line)) > q.maxBytes) {
+		return false
+	}
+	if _, err := q.w.Write(line); err != nil {
+		q.logFunc("Error: Failed to spill check result to %s: %v", q.path, err)
+		return false
+	}
+	q.size += int64(len(line))
+	q.pending++
+	q.spilled.Add(1)
+	q.cond.Signal()
+	return true
+}
+
+// Start launches the drainer that moves spilled results into the channel.
+// It is a no-op without a spill file.
+func (q *Queue) Start() {
+	q.started = true
+	if q.w == nil {
+		close(q.done)
+		return
+	}
+	go q.drain()
+}
+
+func (q *Queue) drain() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for q.pending == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		line, err := q.br.ReadBytes('\n')
+		q.mu.Unlock()
+		if err != nil {
+			// The writer appends whole lines under mu, so a short read
+			// means the file was damaged; start over with an empty file.
+			q.logFunc("Error: Result spill file %s is unreadable, discarding it: %v", q.path, err)
+			q.mu.Lock()
+			q.dropped.Add(q.pending)
+			q.resetLocked()
+			q.mu.Unlock()
+			continue
+		}
+
+		var cr objects.CheckResult
+		if err := json.Unmarshal(line, &cr); err != nil {
+			q.dropped.Add(1)
+		} else {
+			// Blocking: this is where the backpressure belongs.
+			select {
+			case q.ch <- &cr:
+			case <-q.stopCh:
+				q.unsent = line
+				return
+			}
+		}
+
+		q.mu.Lock()
+		q.pending--
+		q.size -= int64(len(line))
+		q.drained += int64(len(line))
+		if q.pending == 0 {
+			q.resetLocked()
+		} else if q.drained > q.size && q.drained >= compactMinBytes {
+			// Under sustained overflow the file never empties; drop the
+			// drained head once it is most of the file.
+			if err := q.rotateLocked(); err != nil {
+				q.logFunc("Error: Failed to compact result spill file %s: %v", q.path, err)
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+// resetLocked truncates the spill file once everything has been drained so
+// it does not grow without bound. Caller holds mu.
+func (q *Queue) resetLocked() {
+	q.pending = 0
+	q.size = 0
+	q.drained = 0
+	if err := q.w.Truncate(0); err != nil {
+		q.logFunc("Error: Failed to truncate result spill file %s: %v", q.path, err)
+	}
+	q.r.Seek(0, io.SeekStart)
+	q.br.Reset(q.r)
+}
+
+// rotateLocked rewrites the spill file with only the undrained results
+// while the drainer runs. On failure the drainer carries on reading the
+// old file where it left off. Caller holds mu.
+func (q *Queue) rotateLocked() error {
+	w, r, err := q.rewriteLocked()
+	if err != nil {
+		q.r.Seek(q.drained, io.SeekStart)
+		q.br.Reset(q.r)
+		return err
+	}
+	q.w.Close()
+	q.r.Close()
+	q.w, q.r = w, r
+	q.br.Reset(r)
+	q.drained = 0
+	return nil
+}
+
+func (q *Queue) rewriteLocked() (w, r *os.File, err error) {
+	rest, err := io.ReadAll(q.br)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, rest, 0644); err != nil {
+		return nil, nil, err
+	}
+	if w, err = os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return nil, nil, err
+	}
+	if r, err = os.Open(tmp); err != nil {
+		w.Close()
+		return nil, nil, err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		w.Close()
+		r.Close()
+		return nil, nil, err
+	}
+	return w, r, nil
+}
+
+// Stop halts the drainer. Results not yet drained are compacted into the
+// spill file and replayed by the next New on the same path.
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	close(q.stopCh)
+	if q.started {
+		<-q.done
+	}
+	if q.w == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending > 0 {
+		if err := q.compactLocked(); err != nil {
+			q.logFunc("Error: Failed to save %d spilled check results to %s: %v", q.pending, q.path, err)
+		}
+	}
+	q.w.Close()
+	q.r.Close()
+}
+
+// compactLocked rewrites the spill file with only the undrained results.
+// Caller holds mu.
+func (q *Queue) compactLocked() error {
+	rest, err := io.ReadAll(q.br)
+	if err != nil {
+		return err
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, append(q.unsent, rest...), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
+
+// Stats returns current depth and overflow counters.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	pending := q.pending
+	q.mu.Unlock()
+	return Stats{
+		Depth:      len(q.ch),
+		Capacity:   cap(q.ch),
+		SpillDepth: pending,
+		Spilled:    q.spilled.Load(),
+		Dropped:    q.dropped.Load(),
+	}
+}
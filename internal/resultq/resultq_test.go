@@ -0,0 +1,216 @@
+package resultq
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func result(i int) *objects.CheckResult {
+	return &objects.CheckResult{
+		HostName:           "host1",
+		ServiceDescription: fmt.Sprintf("svc%d", i),
+		CheckType:          objects.CheckTypePassive,
+		Output:             "OK",
+		StartTime:          time.Unix(1700000000, 0),
+		FinishTime:         time.Unix(1700000001, 0),
+	}
+}
+
+func receive(t *testing.T, ch chan *objects.CheckResult) *objects.CheckResult {
+	t.Helper()
+	select {
+	case cr := <-ch:
+		return cr
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a result")
+		return nil
+	}
+}
+
+func TestSubmit_DropsWithoutSpill(t *testing.T) {
+	ch := make(chan *objects.CheckResult, 2)
+	q, err := New(ch, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		q.Submit(result(i))
+	}
+	st := q.Stats()
+	if st.Depth != 2 || st.Capacity != 2 || st.Dropped != 1 || st.Spilled != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestSubmit_SpillsAndDrainsInOrder(t *testing.T) {
+	ch := make(chan *objects.CheckResult, 2)
+	path := filepath.Join(t.TempDir(), "spill.jsonl")
+	q, err := New(ch, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SetLogger(func(string, ...interface{}) {})
+	for i := 0; i < 10; i++ {
+		if !q.Submit(result(i)) {
+			t.Fatalf("result %d dropped", i)
+		}
+	}
+	if st := q.Stats(); st.Spilled != 8 || st.SpillDepth != 8 || st.Dropped != 0 {
+		t.Fatalf("unexpected stats after flood %+v", st)
+	}
+
+	q.Start()
+	defer q.Stop()
+	for i := 0; i < 10; i++ {
+		cr := receive(t, ch)
+		if want := fmt.Sprintf("svc%d", i); cr.ServiceDescription != want {
+			t.Fatalf("result %d: got %s, want %s", i, cr.ServiceDescription, want)
+		}
+		if !cr.StartTime.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("start time not preserved: %v", cr.StartTime)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Stats().SpillDepth != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("expected spill file truncated after drain, got %v %v", fi, err)
+	}
+}
+
+func TestSubmit_SpillLimit(t *testing.T) {
+	ch := make(chan *objects.CheckResult)
+	q, err := New(ch, filepath.Join(t.TempDir(), "spill.jsonl"), 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accepted int
+	for i := 0; i < 10; i++ {
+		if q.Submit(result(i)) {
+			accepted++
+		}
+	}
+	st := q.Stats()
+	if accepted == 0 || accepted == 10 || st.Dropped != int64(10-accepted) {
+		t.Errorf("expected the size limit to drop some results: accepted %d, stats %+v", accepted, st)
+	}
+}
+
+// waitSpillDepth waits for the drainer to bring the spill depth to n.
+func waitSpillDepth(t *testing.T, q *Queue, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Stats().SpillDepth != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("spill depth %d, want %d", q.Stats().SpillDepth, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubmit_SpillLimitCountsUndrainedOnly(t *testing.T) {
+	line, _ := json.Marshal(result(0))
+	ch := make(chan *objects.CheckResult)
+	q, err := New(ch, filepath.Join(t.TempDir(), "spill.jsonl"), int64(3*(len(line)+1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if !q.Submit(result(i)) {
+			t.Fatalf("result %d dropped", i)
+		}
+	}
+	if q.Submit(result(9)) {
+		t.Fatal("expected a full spill file to drop")
+	}
+	q.Start()
+	defer q.Stop()
+	// The spill file never empties, but drained results free their room.
+	receive(t, ch)
+	receive(t, ch)
+	waitSpillDepth(t, q, 1)
+	for i := 3; i < 5; i++ {
+		if !q.Submit(result(i)) {
+			t.Fatalf("result %d dropped after the drainer made room", i)
+		}
+	}
+	for i := 2; i < 5; i++ {
+		if cr := receive(t, ch); cr.ServiceDescription != fmt.Sprintf("svc%d", i) {
+			t.Errorf("got %s, want svc%d", cr.ServiceDescription, i)
+		}
+	}
+}
+
+func TestDrain_CompactsWhileSpilling(t *testing.T) {
+	defer func(n int64) { compactMinBytes = n }(compactMinBytes)
+	compactMinBytes = 1
+	line, _ := json.Marshal(result(0))
+	ch := make(chan *objects.CheckResult)
+	path := filepath.Join(t.TempDir(), "spill.jsonl")
+	q, err := New(ch, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		q.Submit(result(i))
+	}
+	q.Start()
+	defer q.Stop()
+	for i := 0; i < 3; i++ {
+		receive(t, ch)
+	}
+	waitSpillDepth(t, q, 1)
+	// svc3 is in the drainer's hands; only it may be left in the file.
+	if fi, err := os.Stat(path); err != nil || fi.Size() != int64(len(line)+1) {
+		t.Errorf("expected the drained head compacted away, got %v %v", fi, err)
+	}
+	q.Submit(result(4))
+	for i := 3; i < 5; i++ {
+		if cr := receive(t, ch); cr.ServiceDescription != fmt.Sprintf("svc%d", i) {
+			t.Errorf("got %s, want svc%d", cr.ServiceDescription, i)
+		}
+	}
+}
+
+func TestStop_PersistsUndrainedResults(t *testing.T) {
+	ch := make(chan *objects.CheckResult, 1)
+	path := filepath.Join(t.TempDir(), "spill.jsonl")
+	q, err := New(ch, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		q.Submit(result(i))
+	}
+	q.Start()
+	// Take the channelled result plus one drained result. The drainer then
+	// refills the channel with svc2 and blocks on svc3 until Stop.
+	receive(t, ch)
+	receive(t, ch)
+	time.Sleep(20 * time.Millisecond)
+	q.Stop()
+
+	ch2 := make(chan *objects.CheckResult, 10)
+	q2, err := New(ch2, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q2.Stats().SpillDepth; got != 2 {
+		t.Fatalf("expected 2 results replayed from disk, got %d", got)
+	}
+	q2.Start()
+	defer q2.Stop()
+	for i := 3; i < 5; i++ {
+		if cr := receive(t, ch2); cr.ServiceDescription != fmt.Sprintf("svc%d", i) {
+			t.Errorf("replayed %s, want svc%d", cr.ServiceDescription, i)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"container/heap"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// BenchmarkFireReadyEvents measures scheduler dispatch throughput: each
+// iteration queues one due check event per service and fires them all.
+// The run callback is a no-op, so this isolates queue and bookkeeping cost
+// from plugin execution:
+//
+//	go test ./internal/scheduler -run '^$' -bench FireReadyEvents -benchmem
+func BenchmarkFireReadyEvents(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("services=%d", n), func(b *testing.B) {
+			cfg := objects.DefaultConfig()
+			var hosts []*objects.Host
+			var svcs []*objects.Service
+			for i := 0; i < n/10; i++ {
+				h := &objects.Host{Name: fmt.Sprintf("host%05d", i), ActiveChecksEnabled: true}
+				hosts = append(hosts, h)
+				for j := 0; j < 10; j++ {
+					svcs = append(svcs, &objects.Service{
+						Host:                h,
+						Description:         fmt.Sprintf("svc%d", j),
+						CheckInterval:       5,
+						ActiveChecksEnabled: true,
+						MaxCheckAttempts:    3,
+					})
+				}
+			}
+			s := New(cfg, hosts, svcs, make(chan *objects.CheckResult, 1))
+			s.lastTimeChange = time.Now()
+			s.OnRunServiceCheck = func(svc *objects.Service, options int) {}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				now := time.Now()
+				s.queue = s.queue[:0]
+				for _, svc := range svcs {
+					svc.IsExecuting = false
+					heap.Push(&s.queue, &Event{
+						Type:               EventServiceCheck,
+						RunTime:            now,
+						HostName:           svc.Host.Name,
+						ServiceDescription: svc.Description,
+					})
+				}
+				s.currentlyRunningServiceChecks = 0
+				b.StartTimer()
+				s.fireReadyEvents()
+			}
+			b.ReportMetric(float64(n)*float64(b.N)/b.Elapsed().Seconds(), "checks/s")
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/config"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -134,6 +135,7 @@ func InitTimingLoop(cfg *objects.Config, services []*objects.Service, hosts []*o
 	}
 
 	var events []*Event
+	periods := newPeriodStarts(now)
 
 	// Schedule service checks with interleaving
 	if params.TotalScheduledSvcs > 0 && params.InterleaveFactor > 0 {
@@ -154,7 +156,7 @@ func InitTimingLoop(cfg *objects.Config, services []*objects.Service, hosts []*o
 				checkDelay = rand.Float64() * window
 			}
 
-			svc.NextCheck = now.Add(time.Duration(checkDelay * float64(time.Second)))
+			svc.NextCheck = periods.place(svc.CheckPeriod, time.Duration(checkDelay*float64(time.Second)))
 
 			events = append(events, &Event{
 				Type:               EventServiceCheck,
@@ -182,7 +184,7 @@ func InitTimingLoop(cfg *objects.Config, services []*objects.Service, hosts []*o
 			checkDelay = rand.Float64() * window
 		}
 
-		h.NextCheck = now.Add(time.Duration(checkDelay * float64(time.Second)))
+		h.NextCheck = periods.place(h.CheckPeriod, time.Duration(checkDelay*float64(time.Second)))
 
 		events = append(events, &Event{
 			Type:     EventHostCheck,
@@ -195,6 +197,46 @@ func InitTimingLoop(cfg *objects.Config, services []*objects.Service, hosts []*o
 	return events, params
 }
 
+// periodStarts places initial checks inside their check_period. It
+// caches, for each period, when it is next valid from now.
+type periodStarts struct {
+	now    time.Time
+	starts map[*objects.Timeperiod]time.Time // zero when never valid
+}
+
+func newPeriodStarts(now time.Time) *periodStarts {
+	return &periodStarts{now: now, starts: make(map[*objects.Timeperiod]time.Time)}
+}
+
+// place returns when a check spread delay after now should first run. If
+// its check_period is not valid then, the check runs delay after the period
+// next opens instead, so checks held for a period keep their spread rather
+// than all firing when it opens. A period that is never valid within a year
+// leaves the time as it was.
+func (p *periodStarts) place(tp *objects.Timeperiod, delay time.Duration) time.Time {
+	next := p.now.Add(delay)
+	if tp == nil || config.CheckTime(tp, next) {
+		return next
+	}
+	start, ok := p.starts[tp]
+	if !ok {
+		start = config.GetNextValidTime(tp, p.now)
+		if !config.CheckTime(tp, start) {
+			start = time.Time{}
+		}
+		p.starts[tp] = start
+	}
+	if start.IsZero() {
+		return next
+	}
+	next = start.Add(delay)
+	if config.CheckTime(tp, next) {
+		return next
+	}
+	// The period closes within delay of opening; take its next opening.
+	return config.GetNextValidTime(tp, next)
+}
+
 // checkWindow returns the appropriate check window in seconds based on state.
 func checkWindow(currentState, stateType int, checkInterval, retryInterval float64, intervalLength int) float64 {
 	if currentState != 0 && stateType == objects.StateTypeSoft {
@@ -21,6 +21,7 @@ const (
 	EventRescheduleChecks   = 14
 	EventExpireComment      = 15
 	EventCheckProgramUpdate = 16
+	EventRenotification     = 17 // Gogios extension: resend a problem notification at next_notification
 	EventSleep              = 98
 	EventUserFunction       = 99
 )
@@ -146,7 +147,7 @@ func RecurringEvents(now time.Time, reaperInterval, orphanInterval, sfreshnessIn
 	}
 
 	// Periodic downtime expiry sweep. Catches downtimes whose end-time
-	// passed while the in-process goroutine timer (set up in cmd/gogios's
+	// passed while the in-process ScheduleEnd timer (set up in cmd/gogios's
 	// SCHEDULE_*_DOWNTIME handler) was killed by a restart. Without this
 	// sweep scheduled_downtime_depth never gets decremented and the
 	// service stays "in downtime" forever (KANB-109).
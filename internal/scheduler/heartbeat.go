@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat records event loop iterations for external watchdogs, which
+// can restart gogios when the loop wedges while the process stays alive.
+// Beat is called from the loop. The heartbeat file, if any, is rewritten at
+// most once per interval, so a busy loop does not write to disk for every
+// result batch. The loop wakes at least every check_reaper_interval.
+type Heartbeat struct {
+	path     string
+	interval time.Duration
+	pid      int
+	logFunc  func(string, ...interface{})
+
+	// Loop goroutine only.
+	lastWrite time.Time
+	failing   bool
+	buf       []byte
+
+	last       atomic.Int64 // unix nanoseconds of the last Beat
+	queueDepth atomic.Int64
+	iterations atomic.Uint64
+}
+
+// NewHeartbeat returns a heartbeat that writes path at most once per
+// interval. An empty path only keeps the in-memory values for Handler.
+func NewHeartbeat(path string, interval time.Duration) *Heartbeat {
+	return &Heartbeat{
+		path:     path,
+		interval: interval,
+		pid:      os.Getpid(),
+		logFunc:  func(string, ...interface{}) {},
+	}
+}
+
+// SetLogger sets the function write failures are logged with.
+func (h *Heartbeat) SetLogger(fn func(string, ...interface{})) {
+	h.logFunc = fn
+}
+
+// Beat records one loop iteration with the event queue depth at that time.
+func (h *Heartbeat) Beat(now time.Time, queueDepth int) {
+	h.last.Store(now.UnixNano())
+	h.queueDepth.Store(int64(queueDepth))
+	n := h.iterations.Add(1)
+	if h.path == "" || now.Sub(h.lastWrite) < h.interval {
+		return
+	}
+	h.lastWrite = now
+	if err := h.write(now, queueDepth, n); err != nil {
+		// Log the first failure only, until a write succeeds again.
+		if !h.failing {
+			h.logFunc("Warning: Failed to write heartbeat file '%s': %v", h.path, err)
+		}
+		h.failing = true
+		return
+	}
+	h.failing = false
+}
+
+// write replaces the heartbeat file with key=value lines. The file's mtime
+// doubles as the timestamp for watchdogs that only check file age.
+func (h *Heartbeat) write(now time.Time, queueDepth int, iterations uint64) error {
+	b := h.buf[:0]
+	b = append(b, "last_iteration="...)
+	b = strconv.AppendInt(b, now.Unix(), 10)
+	b = append(b, "\nqueue_depth="...)
+	b = strconv.AppendInt(b, int64(queueDepth), 10)
+	b = append(b, "\niterations="...)
+	b = strconv.AppendUint(b, iterations, 10)
+	b = append(b, "\npid="...)
+	b = strconv.AppendInt(b, int64(h.pid), 10)
+	b = append(b, '\n')
+	h.buf = b
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), ".heartbeat.tmp.*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), h.path)
+}
+
+// LastBeat returns the time of the last Beat, zero before the first.
+func (h *Heartbeat) LastBeat() time.Time {
+	ns := h.last.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+type heartbeatStatus struct {
+	LastIteration int64   `json:"last_iteration"`
+	AgeSeconds    float64 `json:"age_seconds"`
+	QueueDepth    int64   `json:"queue_depth"`
+	Iterations    uint64  `json:"iterations"`
+	PID           int     `json:"pid"`
+}
+
+// Handler serves the heartbeat as JSON. With ?max_age=<seconds> it answers
+// 503 when the last iteration is older than that, or there has been none,
+// so HTTP health checks can act on the status code alone.
+func (h *Heartbeat) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last := h.LastBeat()
+		st := heartbeatStatus{
+			QueueDepth: h.queueDepth.Load(),
+			Iterations: h.iterations.Load(),
+			PID:        h.pid,
+		}
+		if !last.IsZero() {
+			st.LastIteration = last.Unix()
+			st.AgeSeconds = time.Since(last).Seconds()
+		}
+		code := http.StatusOK
+		if v := r.URL.Query().Get("max_age"); v != "" {
+			maxAge, err := strconv.ParseFloat(v, 64)
+			if err != nil || maxAge <= 0 {
+				http.Error(w, "max_age must be a positive number of seconds", http.StatusBadRequest)
+				return
+			}
+			if last.IsZero() || st.AgeSeconds > maxAge {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(st)
+	})
+}
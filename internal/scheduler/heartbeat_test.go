@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat_WritesFileThrottled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heartbeat")
+	hb := NewHeartbeat(path, time.Second)
+	now := time.Unix(1700000000, 0)
+
+	hb.Beat(now, 42)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "last_iteration=1700000000\nqueue_depth=42\niterations=1\npid=" + strconv.Itoa(os.Getpid()) + "\n"
+	if string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+
+	// Within the interval only the in-memory values move.
+	hb.Beat(now.Add(500*time.Millisecond), 7)
+	if data, _ = os.ReadFile(path); !strings.Contains(string(data), "queue_depth=42") {
+		t.Errorf("file rewritten within the interval: %q", data)
+	}
+	if hb.queueDepth.Load() != 7 || hb.iterations.Load() != 2 {
+		t.Errorf("in-memory values not updated: depth %d, iterations %d", hb.queueDepth.Load(), hb.iterations.Load())
+	}
+
+	hb.Beat(now.Add(time.Second), 9)
+	if data, _ = os.ReadFile(path); !strings.Contains(string(data), "queue_depth=9\niterations=3") {
+		t.Errorf("file not rewritten after the interval: %q", data)
+	}
+}
+
+func TestHeartbeat_WriteFailureLoggedOnce(t *testing.T) {
+	hb := NewHeartbeat(filepath.Join(t.TempDir(), "missing", "heartbeat"), 0)
+	var logged int
+	hb.SetLogger(func(string, ...interface{}) { logged++ })
+	hb.Beat(time.Now(), 0)
+	hb.Beat(time.Now(), 0)
+	if logged != 1 {
+		t.Errorf("expected one warning, got %d", logged)
+	}
+}
+
+func TestHeartbeat_Handler(t *testing.T) {
+	hb := NewHeartbeat("", time.Second)
+	get := func(url string) int {
+		rec := httptest.NewRecorder()
+		hb.Handler().ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		return rec.Code
+	}
+
+	if code := get("/debug/heartbeat?max_age=30"); code != http.StatusServiceUnavailable {
+		t.Errorf("before the first beat: got %d, want 503", code)
+	}
+	hb.Beat(time.Now().Add(-time.Minute), 3)
+	if code := get("/debug/heartbeat"); code != http.StatusOK {
+		t.Errorf("without max_age: got %d, want 200", code)
+	}
+	if code := get("/debug/heartbeat?max_age=30"); code != http.StatusServiceUnavailable {
+		t.Errorf("stale beat: got %d, want 503", code)
+	}
+	hb.Beat(time.Now(), 3)
+	if code := get("/debug/heartbeat?max_age=30"); code != http.StatusOK {
+		t.Errorf("fresh beat: got %d, want 200", code)
+	}
+	if code := get("/debug/heartbeat?max_age=soon"); code != http.StatusBadRequest {
+		t.Errorf("invalid max_age: got %d, want 400", code)
+	}
+}
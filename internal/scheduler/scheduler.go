@@ -3,20 +3,25 @@ package scheduler
 import (
 	"container/heap"
 	"log"
+	"math/rand"
+	"sync/atomic"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/clock"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
 // Scheduler is the main event loop that dispatches checks and processes results.
 type Scheduler struct {
 	cfg      *objects.Config
+	clock    clock.Clock
 	queue    EventQueue
 	hosts    map[string]*objects.Host
 	services map[string]map[string]*objects.Service // host -> svc desc -> *Service
 
 	resultCh  chan *objects.CheckResult
 	commandCh chan Command
+	funcCh    chan func()
 	stopCh    chan struct{}
 
 	// Callbacks set by the application
@@ -26,15 +31,42 @@ type Scheduler struct {
 	OnRetentionSave   func()
 	OnLogRotation     func()
 	OnExpireDowntime  func()
+	// OnRenotifyService and OnRenotifyHost run an EventRenotification.
+	OnRenotifyService func(svc *objects.Service)
+	OnRenotifyHost    func(host *objects.Host)
 	OnProcessResult   func(cr *objects.CheckResult)
 	OnProcessResults  func(results []*objects.CheckResult) // batch version — preferred over OnProcessResult
+	OnIteration       func()                               // start of every event loop iteration; must be cheap
 
 	// Counters
 	currentlyRunningServiceChecks int
 	lastTimeChange                time.Time
+	lastIteration                 atomic.Int64 // unix nanoseconds, read by health probes
+	paused                        atomic.Bool
 
 	// Reusable batch buffer for result draining.
 	resultBatch []*objects.CheckResult
+
+	// Active checks dispatched whose results have not arrived yet. Only
+	// touched from the event loop goroutine.
+	launched map[launchKey]launch
+
+	// Next check execution ID, seeded from the start time so IDs stay
+	// unique across restarts.
+	nextExecutionID uint64
+}
+
+type launchKey struct {
+	host, service string
+}
+
+// launch records the object's check epoch and the execution ID assigned
+// when an active check was dispatched, so its result can be stamped with
+// them.
+type launch struct {
+	epoch uint64
+	id    uint64
+	at    time.Time
 }
 
 // Command represents an external command sent to the scheduler.
@@ -47,14 +79,23 @@ type Command struct {
 func New(cfg *objects.Config, hosts []*objects.Host, services []*objects.Service, resultCh chan *objects.CheckResult) *Scheduler {
 	s := &Scheduler{
 		cfg:         cfg,
+		clock:       clock.Real,
 		hosts:       make(map[string]*objects.Host, len(hosts)),
 		services:    make(map[string]map[string]*objects.Service),
 		resultCh:    resultCh,
 		commandCh:   make(chan Command, 100),
+		funcCh:      make(chan func()),
 		stopCh:      make(chan struct{}),
 		resultBatch: make([]*objects.CheckResult, 0, 1024),
+		launched:    make(map[launchKey]launch),
 	}
 
+	s.setObjects(hosts, services)
+	return s
+}
+
+// setObjects fills the lookup maps check events are resolved through.
+func (s *Scheduler) setObjects(hosts []*objects.Host, services []*objects.Service) {
 	for _, h := range hosts {
 		s.hosts[h.Name] = h
 	}
@@ -67,13 +108,19 @@ func New(cfg *objects.Config, hosts []*objects.Host, services []*objects.Service
 		}
 		s.services[svc.Host.Name][svc.Description] = svc
 	}
+}
 
-	return s
+// SetClock replaces the wall clock, for tests that run the scheduler on a
+// clock.Fake. Call it before Init.
+func (s *Scheduler) SetClock(c clock.Clock) {
+	s.clock = c
 }
 
 // Init schedules all initial checks and recurring events, then returns.
 func (s *Scheduler) Init(hosts []*objects.Host, services []*objects.Service) {
-	now := time.Now()
+	now := s.clock.Now()
+	s.lastTimeChange = now
+	s.nextExecutionID = uint64(now.UnixNano())
 	heap.Init(&s.queue)
 
 	// Schedule initial checks
@@ -100,11 +147,59 @@ func (s *Scheduler) Init(hosts []*objects.Host, services []*objects.Service) {
 	}
 }
 
+// LastIteration returns when the main loop last woke up. The loop wakes at
+// least for every recurring event (check reaper, status save), so a stale
+// value means the loop is wedged. Zero before Run starts.
+func (s *Scheduler) LastIteration() time.Time {
+	ns := s.lastIteration.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// Pause stops dispatching active host and service checks, forced ones
+// included, until Resume. Results, status saves and the other recurring
+// events keep running. It reports whether the scheduler was running.
+func (s *Scheduler) Pause() bool {
+	return s.paused.CompareAndSwap(false, true)
+}
+
+// Resume undoes Pause. Checks that came due while paused run as they are
+// nudged back into the queue. It reports whether the scheduler was paused.
+func (s *Scheduler) Resume() bool {
+	return s.paused.CompareAndSwap(true, false)
+}
+
+// Paused reports whether check dispatching is paused.
+func (s *Scheduler) Paused() bool {
+	return s.paused.Load()
+}
+
 // SendCommand sends an external command to the scheduler.
 func (s *Scheduler) SendCommand(cmd Command) {
 	s.commandCh <- cmd
 }
 
+// Do runs fn on the event loop and waits for it to return, so fn can
+// change scheduler and object state without racing result processing. It
+// reports false, without running fn, if the scheduler has stopped.
+func (s *Scheduler) Do(fn func()) bool {
+	select {
+	case <-s.stopCh:
+		return false
+	default:
+	}
+	done := make(chan struct{})
+	select {
+	case s.funcCh <- func() { fn(); close(done) }:
+	case <-s.stopCh:
+		return false
+	}
+	<-done
+	return true
+}
+
 // Stop signals the scheduler to shut down. Safe to call multiple times.
 func (s *Scheduler) Stop() {
 	select {
@@ -117,19 +212,24 @@ func (s *Scheduler) Stop() {
 
 // Run is the main event loop. It blocks until Stop() is called.
 func (s *Scheduler) Run() {
-	s.lastTimeChange = time.Now()
-	timer := time.NewTimer(time.Second)
+	s.lastTimeChange = s.clock.Now()
+	timer := s.clock.NewTimer(time.Second)
 
 	for {
+		s.lastIteration.Store(s.clock.Now().UnixNano())
+		if s.OnIteration != nil {
+			s.OnIteration()
+		}
+
 		// Calculate wait time for next event.
 		if s.queue.Len() > 0 {
-			wait := time.Until(s.queue[0].RunTime)
+			wait := s.queue[0].RunTime.Sub(s.clock.Now())
 			if wait < 0 {
 				wait = 0
 			}
 			if !timer.Stop() {
 				select {
-				case <-timer.C:
+				case <-timer.C():
 				default:
 				}
 			}
@@ -137,7 +237,7 @@ func (s *Scheduler) Run() {
 		} else {
 			if !timer.Stop() {
 				select {
-				case <-timer.C:
+				case <-timer.C():
 				default:
 				}
 			}
@@ -166,7 +266,10 @@ func (s *Scheduler) Run() {
 		case cmd := <-s.commandCh:
 			s.handleCommand(cmd)
 
-		case <-timer.C:
+		case fn := <-s.funcCh:
+			fn()
+
+		case <-timer.C():
 			s.fireReadyEvents()
 		}
 	}
@@ -175,6 +278,7 @@ func (s *Scheduler) Run() {
 // processResultBatch dispatches a batch of results using the batch callback
 // if available, otherwise falls back to individual processing.
 func (s *Scheduler) processResultBatch(batch []*objects.CheckResult) {
+	s.stampEpochs(batch)
 	if s.OnProcessResults != nil {
 		s.OnProcessResults(batch)
 		return
@@ -186,6 +290,35 @@ func (s *Scheduler) processResultBatch(batch []*objects.CheckResult) {
 	}
 }
 
+// stampEpochs sets CheckEpoch and ExecutionID on active results from the
+// check epoch their object had at dispatch and the ID the dispatch was
+// given. A result that started before the latest dispatch
+// of its check belongs to a superseded launch, e.g. one an orphan check
+// gave up on or one for an object since removed and re-added, and gets
+// objects.StaleCheckEpoch. Results of checks the scheduler did not
+// dispatch are left untracked.
+func (s *Scheduler) stampEpochs(batch []*objects.CheckResult) {
+	for _, cr := range batch {
+		if cr.CheckType != objects.CheckTypeActive || cr.CheckEpoch != 0 {
+			continue
+		}
+		key := launchKey{cr.HostName, cr.ServiceDescription}
+		l, ok := s.launched[key]
+		if !ok {
+			continue
+		}
+		if cr.StartTime.Before(l.at) {
+			cr.CheckEpoch = objects.StaleCheckEpoch
+			continue
+		}
+		cr.CheckEpoch = l.epoch
+		if cr.ExecutionID == 0 {
+			cr.ExecutionID = l.id
+		}
+		delete(s.launched, key)
+	}
+}
+
 // drainResults non-blocking drains all pending results from resultCh and
 // processes them. Called from within fireReadyEvents to keep workers flowing
 // during long dispatch bursts.
@@ -214,7 +347,7 @@ const drainInterval = 2048
 // Every drainInterval events, it pauses to process pending results so that
 // workers don't stall waiting for resultCh buffer space during large bursts.
 func (s *Scheduler) fireReadyEvents() {
-	now := time.Now()
+	now := s.clock.Now()
 	tolerance := 100 * time.Millisecond
 
 	// Detect time change
@@ -310,6 +443,9 @@ func (s *Scheduler) isExecutingNonForced(e *Event) bool {
 // shouldRunEvent gates check events based on parallel limits and enabled flags.
 func (s *Scheduler) shouldRunEvent(e *Event) bool {
 	forced := e.CheckOptions&objects.CheckOptionForceExecution != 0
+	if (e.Type == EventServiceCheck || e.Type == EventHostCheck) && s.paused.Load() {
+		return false
+	}
 
 	switch e.Type {
 	case EventServiceCheck:
@@ -349,6 +485,12 @@ func (s *Scheduler) shouldRunEvent(e *Event) bool {
 	}
 }
 
+// newExecutionID returns the ID for the next active check dispatched.
+func (s *Scheduler) newExecutionID() uint64 {
+	s.nextExecutionID++
+	return s.nextExecutionID
+}
+
 func (s *Scheduler) handleEvent(e *Event, now time.Time) {
 	switch e.Type {
 	case EventServiceCheck:
@@ -366,6 +508,8 @@ func (s *Scheduler) handleEvent(e *Event, now time.Time) {
 		}
 		s.currentlyRunningServiceChecks++
 		svc.IsExecuting = true
+		svc.ExecutionID = s.newExecutionID()
+		s.launched[launchKey{e.HostName, e.ServiceDescription}] = launch{svc.CheckEpoch, svc.ExecutionID, now}
 		if s.OnRunServiceCheck != nil {
 			s.OnRunServiceCheck(svc, e.CheckOptions)
 		}
@@ -380,6 +524,8 @@ func (s *Scheduler) handleEvent(e *Event, now time.Time) {
 			host.Latency = 0
 		}
 		host.IsExecuting = true
+		host.ExecutionID = s.newExecutionID()
+		s.launched[launchKey{host: e.HostName}] = launch{host.CheckEpoch, host.ExecutionID, now}
 		if s.OnRunHostCheck != nil {
 			s.OnRunHostCheck(host, e.CheckOptions)
 		}
@@ -410,6 +556,15 @@ func (s *Scheduler) handleEvent(e *Event, now time.Time) {
 			s.OnExpireDowntime()
 		}
 
+	case EventRenotification:
+		if e.ServiceDescription != "" {
+			if svc := s.services[e.HostName][e.ServiceDescription]; svc != nil && s.OnRenotifyService != nil {
+				s.OnRenotifyService(svc)
+			}
+		} else if host := s.hosts[e.HostName]; host != nil && s.OnRenotifyHost != nil {
+			s.OnRenotifyHost(host)
+		}
+
 	case EventCheckReaper:
 		// In Go, results come via channel, so this is mostly a no-op.
 		// Could be used to check for external check result files.
@@ -538,6 +693,107 @@ func (s *Scheduler) RegisterHost(h *objects.Host) {
 	s.hosts[h.Name] = h
 }
 
+// Reload switches the scheduler to the hosts and services of a reloaded
+// configuration. Call it on the event loop, through Do. Queued checks of
+// objects that are gone are dropped. Objects that are new, or whose next
+// check is now further off than their check window, are scheduled at a
+// random point within the window; the rest keep their queued check. Checks
+// in flight are left to finish: their results are matched by name, and the
+// result handler schedules the next check as usual.
+func (s *Scheduler) Reload(hosts []*objects.Host, services []*objects.Service) {
+	s.hosts = make(map[string]*objects.Host, len(hosts))
+	s.services = make(map[string]map[string]*objects.Service)
+	s.setObjects(hosts, services)
+
+	// Regular checks still queued for each object. Forced checks may be
+	// scheduled for any time, so they don't count.
+	queued := make(map[launchKey]*Event)
+	kept := s.queue[:0]
+	for _, e := range s.queue {
+		switch e.Type {
+		case EventServiceCheck:
+			if s.services[e.HostName][e.ServiceDescription] == nil {
+				continue
+			}
+		case EventHostCheck:
+			if s.hosts[e.HostName] == nil {
+				continue
+			}
+		}
+		kept = append(kept, e)
+		if (e.Type == EventServiceCheck || e.Type == EventHostCheck) && e.CheckOptions&objects.CheckOptionForceExecution == 0 {
+			key := launchKey{e.HostName, e.ServiceDescription}
+			if q := queued[key]; q == nil || e.RunTime.Before(q.RunTime) {
+				queued[key] = e
+			}
+		}
+	}
+	for i := len(kept); i < len(s.queue); i++ {
+		s.queue[i] = nil
+	}
+	s.queue = kept
+
+	now := s.clock.Now()
+	il := s.cfg.IntervalLength
+	if il <= 0 {
+		il = 60
+	}
+	// schedule returns when the object's next check should run, or false
+	// if its queued check is soon enough.
+	periods := newPeriodStarts(now)
+	schedule := func(key launchKey, window float64, tp *objects.Timeperiod) (time.Time, bool) {
+		e := queued[key]
+		if e != nil && !e.RunTime.After(now.Add(time.Duration(window*float64(time.Second)))) {
+			return time.Time{}, false
+		}
+		next := periods.place(tp, time.Duration(rand.Float64()*window*float64(time.Second)))
+		if e != nil {
+			e.RunTime = next
+			return next, false
+		}
+		return next, true
+	}
+	CalculateSchedulingParams(s.cfg, services, hosts)
+	for _, svc := range services {
+		if svc.Host == nil || !svc.ShouldBeScheduled || svc.IsExecuting {
+			continue
+		}
+		key := launchKey{svc.Host.Name, svc.Description}
+		next, add := schedule(key, checkWindow(svc.CurrentState, svc.StateType, svc.CheckInterval, svc.RetryInterval, il), svc.CheckPeriod)
+		if !next.IsZero() {
+			svc.NextCheck = next
+		}
+		if add {
+			s.queue = append(s.queue, &Event{
+				Type:               EventServiceCheck,
+				RunTime:            next,
+				HostName:           svc.Host.Name,
+				ServiceDescription: svc.Description,
+			})
+		}
+	}
+	for _, h := range hosts {
+		if !h.ShouldBeScheduled || h.IsExecuting {
+			continue
+		}
+		next, add := schedule(launchKey{host: h.Name}, checkWindow(h.CurrentState, h.StateType, h.CheckInterval, h.RetryInterval, il), h.CheckPeriod)
+		if !next.IsZero() {
+			h.NextCheck = next
+		}
+		if add {
+			s.queue = append(s.queue, &Event{
+				Type:     EventHostCheck,
+				RunTime:  next,
+				HostName: h.Name,
+			})
+		}
+	}
+	for i, e := range s.queue {
+		e.index = i
+	}
+	heap.Init(&s.queue)
+}
+
 // AddEvent adds an event to the queue.
 func (s *Scheduler) AddEvent(e *Event) {
 	heap.Push(&s.queue, e)
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/oceanplexian/gogios/internal/clock"
+	"github.com/oceanplexian/gogios/internal/config"
 	"github.com/oceanplexian/gogios/internal/objects"
 )
 
@@ -102,6 +104,50 @@ func TestInitTimingLoop_SpreadChecks(t *testing.T) {
 	}
 }
 
+func TestInitTimingLoop_CheckPeriod(t *testing.T) {
+	cfg := objects.DefaultConfig()
+	workhours := &objects.Timeperiod{Name: "workhours"}
+	for d := 1; d <= 5; d++ {
+		workhours.Ranges[d] = "09:00-17:00"
+	}
+	config.CompileTimeperiod(workhours)
+
+	host := &objects.Host{Name: "h1", CheckInterval: 5, ActiveChecksEnabled: true, MaxCheckAttempts: 3}
+	var svcs []*objects.Service
+	for i := 0; i < 10; i++ {
+		svcs = append(svcs, &objects.Service{
+			Host:                host,
+			Description:         "svc" + string(rune('0'+i)),
+			CheckInterval:       5,
+			RetryInterval:       1,
+			ActiveChecksEnabled: true,
+			MaxCheckAttempts:    3,
+			CheckPeriod:         workhours,
+		})
+	}
+	always := &objects.Service{Host: host, Description: "always", CheckInterval: 5, RetryInterval: 1, ActiveChecksEnabled: true, MaxCheckAttempts: 3}
+	svcs = append(svcs, always)
+
+	// Monday 03:00: workhours checks wait for 09:00, keeping their spread.
+	now := time.Date(2024, 6, 3, 3, 0, 0, 0, time.Local)
+	opens := time.Date(2024, 6, 3, 9, 0, 0, 0, time.Local)
+	InitTimingLoop(cfg, svcs, []*objects.Host{host}, now)
+
+	distinct := make(map[time.Time]bool)
+	for _, svc := range svcs[:10] {
+		if svc.NextCheck.Before(opens) || !config.CheckTime(workhours, svc.NextCheck) {
+			t.Errorf("%s: next check %v outside workhours", svc.Description, svc.NextCheck)
+		}
+		distinct[svc.NextCheck] = true
+	}
+	if len(distinct) < 2 {
+		t.Error("workhours checks should be spread, not all at 09:00")
+	}
+	if always.NextCheck.After(now.Add(5 * time.Minute)) {
+		t.Errorf("service without a check_period delayed to %v", always.NextCheck)
+	}
+}
+
 func TestScheduleServiceCheck_Deconfliction(t *testing.T) {
 	now := time.Now()
 	earlier := now.Add(-time.Second)
@@ -363,3 +409,225 @@ func TestRecurringEvents(t *testing.T) {
 		}
 	}
 }
+
+// A paused scheduler holds even forced checks, keeping the event queued,
+// and dispatches it once resumed.
+func TestFireReadyEvents_PausedHoldsChecks(t *testing.T) {
+	s, _, runs := dueServiceCheckScheduler(t, false, objects.CheckOptionForceExecution)
+	if !s.Pause() || s.Pause() {
+		t.Fatal("expected only the first Pause to report a state change")
+	}
+
+	s.fireReadyEvents()
+	if *runs != 0 {
+		t.Fatalf("expected no dispatch while paused, got %d", *runs)
+	}
+	if s.queue.Len() != 1 {
+		t.Fatalf("expected the held check to stay queued, got %d events", s.queue.Len())
+	}
+
+	if !s.Resume() || s.Paused() {
+		t.Fatal("expected Resume to unpause")
+	}
+	s.queue[0].RunTime = time.Now()
+	s.fireReadyEvents()
+	if *runs != 1 {
+		t.Errorf("expected the check to run after Resume, got %d dispatches", *runs)
+	}
+}
+
+// Results are stamped with the service's check epoch at dispatch; a result
+// that started before the latest dispatch is marked stale, and checks the
+// scheduler never dispatched stay untracked.
+func TestProcessResultBatch_StampsEpochs(t *testing.T) {
+	s, svc, _ := dueServiceCheckScheduler(t, false, 0)
+	svc.CheckEpoch = 7
+	var got []*objects.CheckResult
+	s.OnProcessResults = func(results []*objects.CheckResult) { got = append(got, results...) }
+
+	early := time.Now()
+	s.fireReadyEvents()
+	svc.CheckEpoch = 8 // checks disabled while the check runs
+
+	stale := &objects.CheckResult{HostName: "h1", ServiceDescription: "SSH", StartTime: early.Add(-time.Minute)}
+	current := &objects.CheckResult{HostName: "h1", ServiceDescription: "SSH", StartTime: time.Now()}
+	passive := &objects.CheckResult{HostName: "h1", ServiceDescription: "SSH", CheckType: objects.CheckTypePassive, StartTime: time.Now()}
+	other := &objects.CheckResult{HostName: "h1", ServiceDescription: "Other", StartTime: time.Now()}
+	s.processResultBatch([]*objects.CheckResult{stale, current, passive, other})
+
+	if len(got) != 4 {
+		t.Fatalf("expected 4 results passed on, got %d", len(got))
+	}
+	if stale.CheckEpoch != objects.StaleCheckEpoch {
+		t.Errorf("superseded result: expected stale epoch, got %d", stale.CheckEpoch)
+	}
+	if current.CheckEpoch != 7 {
+		t.Errorf("expected the dispatch epoch 7, got %d", current.CheckEpoch)
+	}
+	if passive.CheckEpoch != 0 || other.CheckEpoch != 0 {
+		t.Errorf("untracked results should keep epoch 0, got %d and %d", passive.CheckEpoch, other.CheckEpoch)
+	}
+	if len(s.launched) != 0 {
+		t.Errorf("launch record should be consumed, %d left", len(s.launched))
+	}
+}
+
+// Each dispatch gets a fresh execution ID, set on the service before the
+// check runs and stamped onto its result.
+func TestProcessResultBatch_StampsExecutionIDs(t *testing.T) {
+	s, svc, _ := dueServiceCheckScheduler(t, false, 0)
+	s.nextExecutionID = 41
+	var dispatched uint64
+	s.OnRunServiceCheck = func(svc *objects.Service, _ int) { dispatched = svc.ExecutionID }
+	s.OnProcessResults = func([]*objects.CheckResult) {}
+
+	s.fireReadyEvents()
+	if dispatched != 42 || svc.ExecutionID != 42 {
+		t.Fatalf("expected execution ID 42 at dispatch, got %d (service %d)", dispatched, svc.ExecutionID)
+	}
+	cr := &objects.CheckResult{HostName: "h1", ServiceDescription: "SSH", StartTime: time.Now()}
+	passive := &objects.CheckResult{HostName: "h1", ServiceDescription: "SSH", CheckType: objects.CheckTypePassive, StartTime: time.Now()}
+	s.processResultBatch([]*objects.CheckResult{passive, cr})
+	if cr.ExecutionID != 42 {
+		t.Errorf("expected the result stamped with 42, got %d", cr.ExecutionID)
+	}
+	if passive.ExecutionID != 0 {
+		t.Errorf("passive results should have no execution ID, got %d", passive.ExecutionID)
+	}
+
+	svc.IsExecuting = false
+	heap.Push(&s.queue, &Event{Type: EventServiceCheck, RunTime: time.Now(), HostName: "h1", ServiceDescription: "SSH"})
+	s.fireReadyEvents()
+	if svc.ExecutionID != 43 {
+		t.Errorf("expected the next dispatch to get 43, got %d", svc.ExecutionID)
+	}
+}
+
+// The event loop runs on the injected clock: advancing a fake clock past
+// the status update interval fires the status save without waiting.
+func TestRun_FakeClock(t *testing.T) {
+	cfg := objects.DefaultConfig()
+	fc := clock.NewFake(time.Unix(1700000000, 0))
+	s := New(cfg, nil, nil, make(chan *objects.CheckResult, 1))
+	s.SetClock(fc)
+	s.Init(nil, nil)
+
+	saved := make(chan time.Time, 10)
+	s.OnStatusSave = func() { saved <- fc.Now() }
+	go s.Run()
+	defer s.Stop()
+
+	fc.Advance(time.Duration(cfg.StatusUpdateInterval) * time.Second)
+	select {
+	case at := <-saved:
+		if want := time.Unix(1700000000, 0).Add(time.Duration(cfg.StatusUpdateInterval) * time.Second); at.Before(want) {
+			t.Errorf("status saved at %v, before its due time %v", at, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("status save did not fire after advancing the clock")
+	}
+	if got := s.LastIteration(); got.Before(time.Unix(1700000000, 0)) || got.After(fc.Now()) {
+		t.Errorf("LastIteration %v is not on the fake clock", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	cfg := objects.DefaultConfig()
+	fc := clock.NewFake(time.Unix(1700000000, 0))
+	now := fc.Now()
+	h := &objects.Host{Name: "h1", CheckInterval: 5, ActiveChecksEnabled: true}
+	kept := &objects.Service{Host: h, Description: "kept", CheckInterval: 5, ActiveChecksEnabled: true}
+	slowed := &objects.Service{Host: h, Description: "sped-up", CheckInterval: 60, ActiveChecksEnabled: true}
+	running := &objects.Service{Host: h, Description: "running", CheckInterval: 5, ActiveChecksEnabled: true}
+	gone := &objects.Service{Host: h, Description: "gone", CheckInterval: 5, ActiveChecksEnabled: true}
+	s := New(cfg, []*objects.Host{h}, []*objects.Service{kept, slowed, running, gone}, make(chan *objects.CheckResult, 1))
+	s.SetClock(fc)
+	s.Init([]*objects.Host{h}, []*objects.Service{kept, slowed, running, gone})
+	keptAt := now.Add(2 * time.Minute)
+	for _, e := range s.queue {
+		switch e.ServiceDescription {
+		case "kept":
+			e.RunTime = keptAt
+		case "sped-up":
+			e.RunTime = now.Add(50 * time.Minute)
+		}
+	}
+	heap.Init(&s.queue)
+	// A check in flight has no queued event; its result queues the next.
+	for i, e := range s.queue {
+		if e.ServiceDescription == "running" {
+			heap.Remove(&s.queue, i)
+			break
+		}
+	}
+	running.IsExecuting = true
+	s.currentlyRunningServiceChecks = 1
+
+	slowed.CheckInterval = 5
+	added := &objects.Service{Host: h, Description: "added", CheckInterval: 5, ActiveChecksEnabled: true}
+	s.Reload([]*objects.Host{h}, []*objects.Service{kept, slowed, running, added})
+
+	events := make(map[string][]time.Time)
+	for _, e := range s.queue {
+		if e.Type == EventServiceCheck {
+			events[e.ServiceDescription] = append(events[e.ServiceDescription], e.RunTime)
+		}
+	}
+	window := now.Add(5 * time.Minute)
+	if len(events["gone"]) != 0 || len(events["running"]) != 0 {
+		t.Errorf("unexpected events %v", events)
+	}
+	if got := events["kept"]; len(got) != 1 || !got[0].Equal(keptAt) {
+		t.Errorf("kept service rescheduled: %v", got)
+	}
+	for _, desc := range []string{"sped-up", "added"} {
+		if got := events[desc]; len(got) != 1 || got[0].After(window) {
+			t.Errorf("%s: events %v, want one within the check window", desc, got)
+		}
+	}
+	if !added.ShouldBeScheduled || !added.NextCheck.Equal(events["added"][0]) {
+		t.Error("new service not set up for scheduling")
+	}
+	if s.services["h1"]["gone"] != nil || s.services["h1"]["added"] != added {
+		t.Error("lookup maps not switched")
+	}
+	for i := 1; i < len(s.queue); i++ {
+		if s.queue.Less(i, (i-1)/2) {
+			t.Fatal("queue is not a heap")
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	s := New(objects.DefaultConfig(), nil, nil, make(chan *objects.CheckResult, 1))
+	s.Init(nil, nil)
+	go s.Run()
+	ran := false
+	if !s.Do(func() { ran = true }) || !ran {
+		t.Error("Do did not run the function")
+	}
+	s.Stop()
+	if s.Do(func() {}) {
+		t.Error("Do ran after Stop")
+	}
+}
+
+func TestHandleEvent_Renotification(t *testing.T) {
+	cfg := objects.DefaultConfig()
+	h := &objects.Host{Name: "h1"}
+	svc := &objects.Service{Host: h, Description: "HTTP"}
+	s := New(cfg, []*objects.Host{h}, []*objects.Service{svc}, make(chan *objects.CheckResult, 1))
+	var got []string
+	s.OnRenotifyService = func(svc *objects.Service) { got = append(got, svc.Description) }
+	s.OnRenotifyHost = func(h *objects.Host) { got = append(got, h.Name) }
+
+	now := time.Now()
+	s.handleEvent(&Event{Type: EventRenotification, HostName: "h1", ServiceDescription: "HTTP"}, now)
+	s.handleEvent(&Event{Type: EventRenotification, HostName: "h1"}, now)
+	// Objects removed by a reload are skipped.
+	s.handleEvent(&Event{Type: EventRenotification, HostName: "h1", ServiceDescription: "gone"}, now)
+	s.handleEvent(&Event{Type: EventRenotification, HostName: "gone"}, now)
+	if len(got) != 2 || got[0] != "HTTP" || got[1] != "h1" {
+		t.Errorf("renotified %v", got)
+	}
+}
@@ -0,0 +1,245 @@
+// Package selfcheck registers a pseudo host whose services report the health
+// of gogios' own subsystems. Results are submitted as passive check results,
+// so they go through the normal state machine and notification paths and
+// gogios can alert about itself.
+package selfcheck
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// Source is the check_source reported for self-check results.
+const Source = "gogios self-check"
+
+// Probe reports the health of one subsystem as a plugin return code and
+// output. Output may carry performance data after a '|'. Probes run on the
+// monitor goroutine without any store lock held.
+type Probe func() (int, string)
+
+type probeService struct {
+	description string
+	probe       Probe
+}
+
+// Monitor owns the pseudo host and runs its probes on an interval.
+type Monitor struct {
+	hostName      string
+	store         *objects.ObjectStore
+	interval      time.Duration
+	contactGroups []string
+	mu            sync.Mutex
+	services      []*probeService
+	start         time.Time
+	stopCh        chan struct{}
+	stopOnce      sync.Once
+	logFunc       func(format string, args ...interface{})
+}
+
+// New creates a monitor for the pseudo host hostName that probes every
+// interval.
+func New(hostName string, store *objects.ObjectStore, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Monitor{
+		hostName: hostName,
+		store:    store,
+		interval: interval,
+		start:    time.Now(),
+		stopCh:   make(chan struct{}),
+		logFunc:  log.Printf,
+	}
+}
+
+// SetLogger overrides the default log function.
+func (m *Monitor) SetLogger(fn func(string, ...interface{})) {
+	m.logFunc = fn
+}
+
+// SetContactGroups names the contact groups notified about the pseudo host
+// and its services. Unknown groups are logged and skipped by Register.
+func (m *Monitor) SetContactGroups(names []string) {
+	m.contactGroups = names
+}
+
+// HostName returns the pseudo host's name.
+func (m *Monitor) HostName() string {
+	return m.hostName
+}
+
+// AddService adds a service. Call before Register. probe may be nil when
+// the subsystem does not exist yet; attach it later with SetProbe.
+func (m *Monitor) AddService(description string, probe Probe) {
+	m.mu.Lock()
+	m.services = append(m.services, &probeService{description, probe})
+	m.mu.Unlock()
+}
+
+// SetProbe attaches or replaces the probe of a service added with
+// AddService.
+func (m *Monitor) SetProbe(description string, probe Probe) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ps := range m.services {
+		if ps.description == description {
+			ps.probe = probe
+			return
+		}
+	}
+}
+
+// Register creates the pseudo host and its services in the store. A host or
+// service already defined in the object configuration is kept as is, so
+// operators can override intervals, contacts or notification options with a
+// regular definition. Call at startup before retention data is read, so the
+// objects keep their state across restarts.
+func (m *Monitor) Register() {
+	var groups []*objects.ContactGroup
+	for _, name := range m.contactGroups {
+		if cg := m.store.GetContactGroup(name); cg != nil {
+			groups = append(groups, cg)
+		} else {
+			m.logFunc("Warning: self_check_contact_groups: contact group '%s' not found", name)
+		}
+	}
+	// Passive results count as check attempts, so a problem must persist
+	// for three probes before it turns HARD and notifies.
+	interval := m.interval.Minutes()
+
+	host := m.store.GetHost(m.hostName)
+	if host == nil {
+		host = &objects.Host{
+			Name:                 m.hostName,
+			DisplayName:          m.hostName,
+			Alias:                "gogios self-check",
+			Address:              "127.0.0.1",
+			MaxCheckAttempts:     3,
+			CheckInterval:        interval,
+			RetryInterval:        interval,
+			PassiveChecksEnabled: true,
+			NotificationsEnabled: true,
+			NotificationOptions:  objects.OptDown | objects.OptUnreachable | objects.OptRecovery,
+			NotificationInterval: 60,
+			ContactGroups:        groups,
+			CurrentState:         objects.HostUp,
+			StateType:            objects.StateTypeHard,
+		}
+		m.store.AddHost(host)
+	}
+
+	for _, ps := range m.services {
+		if m.store.GetService(m.hostName, ps.description) != nil {
+			continue
+		}
+		svc := &objects.Service{
+			Host:                 host,
+			Description:          ps.description,
+			DisplayName:          ps.description,
+			MaxCheckAttempts:     3,
+			CheckInterval:        interval,
+			RetryInterval:        interval,
+			PassiveChecksEnabled: true,
+			NotificationsEnabled: true,
+			NotificationOptions:  objects.OptWarning | objects.OptCritical | objects.OptUnknown | objects.OptRecovery,
+			NotificationInterval: 60,
+			ContactGroups:        groups,
+			CurrentState:         objects.ServiceOK,
+			StateType:            objects.StateTypeHard,
+			CurrentAttempt:       1,
+		}
+		m.store.AddService(svc)
+		host.Services = append(host.Services, svc)
+	}
+}
+
+// Start runs the probes every interval until Stop, handing results to
+// submit, which must not block and returns false if it dropped the result.
+func (m *Monitor) Start(submit func(*objects.CheckResult) bool) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+				m.RunOnce(submit)
+			}
+		}
+	}()
+}
+
+// Stop halts the probe loop. Safe to call multiple times.
+func (m *Monitor) Stop() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+}
+
+// RunOnce probes every service and submits one result per service plus a
+// host result. A full result queue is itself a symptom, so results that
+// cannot be queued are dropped rather than blocking the monitor.
+func (m *Monitor) RunOnce(submit func(*objects.CheckResult) bool) {
+	now := time.Now()
+	m.send(submit, &objects.CheckResult{
+		HostName:   m.hostName,
+		ReturnCode: 0,
+		Output:     fmt.Sprintf("gogios UP - pid %d, uptime %s", os.Getpid(), now.Sub(m.start).Truncate(time.Second)),
+	})
+	m.mu.Lock()
+	services := make([]probeService, len(m.services))
+	for i, ps := range m.services {
+		services[i] = *ps
+	}
+	m.mu.Unlock()
+	for _, ps := range services {
+		start := time.Now()
+		rc, output := 3, "UNKNOWN - subsystem not started"
+		if ps.probe != nil {
+			rc, output = ps.probe()
+		}
+		m.send(submit, &objects.CheckResult{
+			HostName:           m.hostName,
+			ServiceDescription: ps.description,
+			ReturnCode:         rc,
+			Output:             output,
+			StartTime:          start,
+			ExecutionTime:      time.Since(start).Seconds(),
+		})
+	}
+}
+
+func (m *Monitor) send(submit func(*objects.CheckResult) bool, cr *objects.CheckResult) {
+	now := time.Now()
+	if cr.StartTime.IsZero() {
+		cr.StartTime = now
+	}
+	cr.FinishTime = now
+	cr.CheckType = objects.CheckTypePassive
+	cr.ExitedOK = true
+	cr.Source = Source
+	if !submit(cr) {
+		m.logFunc("Warning: self-check result for %s;%s dropped, result queue full", cr.HostName, cr.ServiceDescription)
+	}
+}
+
+// Threshold maps a measured value onto a return code: CRITICAL at or above
+// crit, WARNING at or above warn, OK otherwise.
+func Threshold(value, warn, crit float64) int {
+	switch {
+	case value >= crit:
+		return 2
+	case value >= warn:
+		return 1
+	}
+	return 0
+}
+
+// StateLabel returns the plugin output prefix for a return code.
+func StateLabel(rc int) string {
+	return objects.ServiceStateName(rc)
+}
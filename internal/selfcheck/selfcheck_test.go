@@ -0,0 +1,136 @@
+package selfcheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func chanSink(ch chan *objects.CheckResult) func(*objects.CheckResult) bool {
+	return func(cr *objects.CheckResult) bool {
+		select {
+		case ch <- cr:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func TestRegister_CreatesPseudoObjects(t *testing.T) {
+	store := objects.NewObjectStore()
+	store.AddContactGroup(&objects.ContactGroup{Name: "admins"})
+
+	m := New("gogios", store, time.Minute)
+	m.SetLogger(func(string, ...interface{}) {})
+	m.SetContactGroups([]string{"admins", "missing"})
+	m.AddService("Scheduler", nil)
+	m.AddService("Result Queue", nil)
+	m.Register()
+
+	host := store.GetHost("gogios")
+	if host == nil {
+		t.Fatal("expected pseudo host to be registered")
+	}
+	if host.ActiveChecksEnabled || !host.PassiveChecksEnabled {
+		t.Error("expected passive-only pseudo host")
+	}
+	if len(host.Services) != 2 {
+		t.Fatalf("expected 2 services on pseudo host, got %d", len(host.Services))
+	}
+	svc := store.GetService("gogios", "Result Queue")
+	if svc == nil {
+		t.Fatal("expected Result Queue service")
+	}
+	if len(svc.ContactGroups) != 1 || svc.ContactGroups[0].Name != "admins" {
+		t.Errorf("expected contact group admins only, got %v", svc.ContactGroups)
+	}
+	if svc.CheckInterval != 1 {
+		t.Errorf("expected check interval 1 minute, got %v", svc.CheckInterval)
+	}
+}
+
+func TestRegister_KeepsConfiguredObjects(t *testing.T) {
+	store := objects.NewObjectStore()
+	host := &objects.Host{Name: "gogios", Alias: "configured"}
+	store.AddHost(host)
+	svc := &objects.Service{Host: host, Description: "Scheduler", MaxCheckAttempts: 5}
+	store.AddService(svc)
+	host.Services = append(host.Services, svc)
+
+	m := New("gogios", store, time.Minute)
+	m.AddService("Scheduler", nil)
+	m.AddService("NRDP", nil)
+	m.Register()
+
+	if store.GetHost("gogios").Alias != "configured" {
+		t.Error("configured host was replaced")
+	}
+	if store.GetService("gogios", "Scheduler").MaxCheckAttempts != 5 {
+		t.Error("configured service was replaced")
+	}
+	if store.GetService("gogios", "NRDP") == nil {
+		t.Error("expected missing service to be added")
+	}
+}
+
+func TestRunOnce_SubmitsResults(t *testing.T) {
+	store := objects.NewObjectStore()
+	m := New("gogios", store, time.Minute)
+	m.AddService("Scheduler", nil)
+	m.AddService("Result Queue", nil)
+	m.Register()
+	m.SetProbe("Scheduler", func() (int, string) { return 1, "WARNING - slow" })
+
+	ch := make(chan *objects.CheckResult, 10)
+	m.RunOnce(chanSink(ch))
+	close(ch)
+
+	got := make(map[string]*objects.CheckResult)
+	for cr := range ch {
+		got[cr.ServiceDescription] = cr
+		if cr.HostName != "gogios" || cr.CheckType != objects.CheckTypePassive || cr.Source != Source {
+			t.Errorf("unexpected result fields: %+v", cr)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected host result and 2 service results, got %d", len(got))
+	}
+	if cr := got[""]; cr.ReturnCode != 0 || !strings.HasPrefix(cr.Output, "gogios UP") {
+		t.Errorf("unexpected host result: %d %q", cr.ReturnCode, cr.Output)
+	}
+	if cr := got["Scheduler"]; cr.ReturnCode != 1 || cr.Output != "WARNING - slow" {
+		t.Errorf("unexpected Scheduler result: %d %q", cr.ReturnCode, cr.Output)
+	}
+	if cr := got["Result Queue"]; cr.ReturnCode != 3 {
+		t.Errorf("expected UNKNOWN without a probe, got %d", cr.ReturnCode)
+	}
+}
+
+func TestRunOnce_LogsDroppedResults(t *testing.T) {
+	m := New("gogios", objects.NewObjectStore(), time.Minute)
+	var warnings int
+	m.SetLogger(func(string, ...interface{}) { warnings++ })
+	m.AddService("Scheduler", func() (int, string) { return 0, "OK" })
+
+	m.RunOnce(func(*objects.CheckResult) bool { return false })
+	if warnings != 2 {
+		t.Errorf("expected a warning per dropped result, got %d", warnings)
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	cases := []struct {
+		value float64
+		want  int
+	}{
+		{0, 0}, {49.9, 0}, {50, 1}, {89, 1}, {90, 2}, {100, 2},
+	}
+	for _, c := range cases {
+		if got := Threshold(c.value, 50, 90); got != c.want {
+			t.Errorf("Threshold(%v) = %d, want %d", c.value, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package status
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// BenchmarkStatusWriter_Write measures a full status.dat write at several
+// config sizes (10 services per host). The writer runs on the scheduler
+// goroutine every status_update_interval, so this bounds how long the event
+// loop stalls per update:
+//
+//	go test ./internal/status -run '^$' -bench Writer -benchmem
+func BenchmarkStatusWriter_Write(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("services=%d", n), func(b *testing.B) {
+			store, global, cm, dm := benchStore(n)
+			sw := &StatusWriter{
+				Path:      b.TempDir() + "/status.dat",
+				Store:     store,
+				Global:    global,
+				Comments:  cm,
+				Downtimes: dm,
+				Version:   "4.1.1-go",
+			}
+			benchWrite(b, sw.Write)
+		})
+	}
+}
+
+// BenchmarkRetentionWriter_Write measures a full retention.dat write, done
+// every retention_update_interval and on shutdown, as one file and in 8
+// shards (retention_shards=8).
+func BenchmarkRetentionWriter_Write(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		for _, shards := range []int{1, 8} {
+			b.Run(fmt.Sprintf("services=%d/shards=%d", n, shards), func(b *testing.B) {
+				store, global, cm, dm := benchStore(n)
+				rw := &RetentionWriter{
+					Path:      b.TempDir() + "/retention.dat",
+					Store:     store,
+					Global:    global,
+					Comments:  cm,
+					Downtimes: dm,
+					Version:   "4.1.1-go",
+					Shards:    shards,
+				}
+				benchWrite(b, rw.Write)
+			})
+		}
+	}
+}
+
+// benchWrite runs write b.N times after one untimed write, so the numbers
+// are for the steady state of a long-running daemon whose writer buffer
+// has already grown to size.
+func benchWrite(b *testing.B, write func() error) {
+	if err := write(); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := write(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchStore(n int) (*objects.ObjectStore, *objects.GlobalState, *downtime.CommentManager, *downtime.DowntimeManager) {
+	store := objects.NewObjectStore()
+	now := time.Now()
+	for i := 0; i < n/10; i++ {
+		h := &objects.Host{
+			Name:           fmt.Sprintf("host%05d", i),
+			HasBeenChecked: true,
+			PluginOutput:   "PING OK - Packet loss = 0%, RTA = 0.05 ms",
+			LastCheck:      now,
+		}
+		store.AddHost(h)
+		for j := 0; j < 10; j++ {
+			store.AddService(&objects.Service{
+				Host:           h,
+				Description:    fmt.Sprintf("svc%d", j),
+				HasBeenChecked: true,
+				PluginOutput:   "OK - synthetic",
+				PerfData:       "time=0.001s;;;0",
+				LastCheck:      now,
+			})
+		}
+	}
+	cm := downtime.NewCommentManager(1)
+	return store, &objects.GlobalState{ProgramStart: now, PID: 1}, cm, downtime.NewDowntimeManager(1, cm, store)
+}
@@ -0,0 +1,128 @@
+package status
+
+import (
+	"strconv"
+	"time"
+)
+
+// blockBuf renders status.dat and retention.dat blocks. Fields are
+// appended with strconv.Append* into one byte slice instead of going
+// through fmt, which dominated write time at 100k services. Writers keep
+// their blockBuf between writes so the slice is only grown once.
+type blockBuf struct {
+	b      []byte
+	indent bool // status.dat indents fields and closing braces with a tab
+}
+
+func (w *blockBuf) reset() {
+	w.b = w.b[:0]
+}
+
+// begin opens a block: "name {".
+func (w *blockBuf) begin(name string) {
+	w.b = append(w.b, name...)
+	w.b = append(w.b, " {\n"...)
+}
+
+// end closes a block and leaves a blank line after it.
+func (w *blockBuf) end() {
+	if w.indent {
+		w.b = append(w.b, '\t')
+	}
+	w.b = append(w.b, "}\n\n"...)
+}
+
+func (w *blockBuf) key(k string) {
+	if w.indent {
+		w.b = append(w.b, '\t')
+	}
+	w.b = append(w.b, k...)
+	w.b = append(w.b, '=')
+}
+
+func (w *blockBuf) str(k, v string) {
+	w.key(k)
+	w.b = append(w.b, v...)
+	w.b = append(w.b, '\n')
+}
+
+func (w *blockBuf) int(k string, v int) {
+	w.key(k)
+	w.appendInt(v)
+	w.b = append(w.b, '\n')
+}
+
+// appendInt appends v, with a shortcut for the single digits most state,
+// type and attempt fields hold.
+func (w *blockBuf) appendInt(v int) {
+	if v >= 0 && v < 10 {
+		w.b = append(w.b, byte('0'+v))
+		return
+	}
+	w.b = strconv.AppendInt(w.b, int64(v), 10)
+}
+
+func (w *blockBuf) int64(k string, v int64) {
+	w.key(k)
+	w.b = strconv.AppendInt(w.b, v, 10)
+	w.b = append(w.b, '\n')
+}
+
+func (w *blockBuf) uint(k string, v uint64) {
+	w.key(k)
+	if v < 10 {
+		w.b = append(w.b, byte('0'+v))
+	} else {
+		w.b = strconv.AppendUint(w.b, v, 10)
+	}
+	w.b = append(w.b, '\n')
+}
+
+// bool writes 1 or 0.
+func (w *blockBuf) bool(k string, v bool) {
+	w.key(k)
+	if v {
+		w.b = append(w.b, '1', '\n')
+	} else {
+		w.b = append(w.b, '0', '\n')
+	}
+}
+
+// float writes v with six decimals, as %f does.
+func (w *blockBuf) float(k string, v float64) {
+	w.key(k)
+	w.b = strconv.AppendFloat(w.b, v, 'f', 6, 64)
+	w.b = append(w.b, '\n')
+}
+
+// time writes t as a Unix timestamp, 0 for the zero time.
+func (w *blockBuf) time(k string, t time.Time) {
+	w.int64(k, timeToUnix(t))
+}
+
+// ints writes a comma-separated list, as state_history is stored.
+func (w *blockBuf) ints(k string, vs []int) {
+	w.key(k)
+	for i, v := range vs {
+		if i > 0 {
+			w.b = append(w.b, ',')
+		}
+		w.appendInt(v)
+	}
+	w.b = append(w.b, '\n')
+}
+
+// customVars writes custom variables as "_NAME=0;value"; the 0 is the
+// Nagios "modified" flag.
+func (w *blockBuf) customVars(vars map[string]string) {
+	for k, v := range vars {
+		if w.indent {
+			w.b = append(w.b, '\t')
+		}
+		w.b = append(w.b, '_')
+		w.b = append(w.b, k...)
+		w.b = append(w.b, "=0;"...)
+		w.b = append(w.b, v...)
+		w.b = append(w.b, '\n')
+	}
+}
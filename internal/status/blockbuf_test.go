@@ -0,0 +1,45 @@
+package status
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestBlockBuf_MatchesFmt checks the strconv fast path renders values
+// exactly as the fmt verbs the files were originally written with.
+func TestBlockBuf_MatchesFmt(t *testing.T) {
+	var b blockBuf
+	var want string
+	for _, v := range []int{0, 7, 10, -1, -42, math.MaxInt64} {
+		b.int("i", v)
+		want += fmt.Sprintf("i=%d\n", v)
+	}
+	for _, v := range []uint64{0, 9, 12, math.MaxUint64} {
+		b.uint("u", v)
+		want += fmt.Sprintf("u=%d\n", v)
+	}
+	for _, v := range []float64{0, 1.5, -0.0000004, 123456789.123456789, math.NaN(), math.Inf(1)} {
+		b.float("f", v)
+		want += fmt.Sprintf("f=%f\n", v)
+	}
+	b.time("t", time.Time{})
+	b.time("t", time.Unix(1700000000, 0))
+	b.ints("h", []int{0, 3, 11, -1})
+	b.bool("b", true)
+	b.bool("b", false)
+	want += "t=0\nt=1700000000\nh=0,3,11,-1\nb=1\nb=0\n"
+	if got := string(b.b); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	b = blockBuf{indent: true}
+	b.begin("hoststatus")
+	b.str("host_name", "web01")
+	b.customVars(map[string]string{"OWNER": "ops"})
+	b.end()
+	if got, want := string(b.b), "hoststatus {\n\thost_name=web01\n\t_OWNER=0;ops\n\t}\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
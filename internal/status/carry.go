@@ -0,0 +1,107 @@
+package status
+
+import (
+	"bytes"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// CarryState copies the runtime state of the hosts, services and contacts
+// of from to the objects of the same name in to, for a configuration
+// reload where to holds the newly loaded objects. State carries over as it
+// would through retention.dat across a restart, so settings changed at
+// runtime (modified_attributes) win over the config and the rest comes
+// from the config. Check bookkeeping that retention does not keep carries
+// over too, so results of checks in flight are still accepted. Objects
+// only in to keep their initial state.
+func CarryState(from, to *objects.ObjectStore) {
+	rw := &RetentionWriter{Store: from}
+	var b blockBuf
+	for _, h := range from.Hosts {
+		rw.writeHost(&b, h)
+	}
+	for _, svc := range from.Services {
+		rw.writeService(&b, svc)
+	}
+	for _, c := range from.Contacts {
+		rw.writeContact(&b, c)
+	}
+	rr := &RetentionReader{Store: to}
+	parseBlocks(bytes.NewReader(b.b), rr.applyBlock)
+
+	for _, h := range from.Hosts {
+		if n := to.GetHost(h.Name); n != nil {
+			carryHost(h, n)
+		}
+	}
+	for _, c := range from.Contacts {
+		if n := to.GetContact(c.Name); n != nil {
+			n.NotificationCounts = c.NotificationCounts
+			n.CappedCounts = c.CappedCounts
+		}
+	}
+	for _, svc := range from.Services {
+		if svc.Host == nil {
+			continue
+		}
+		if n := to.GetService(svc.Host.Name, svc.Description); n != nil {
+			carryService(svc, n)
+		}
+	}
+}
+
+// carryHost copies the state retention.dat leaves out, or keeps only to
+// the second.
+func carryHost(from, to *objects.Host) {
+	to.IsExecuting = from.IsExecuting
+	to.CheckEpoch = from.CheckEpoch
+	to.ExecutionID = from.ExecutionID
+	to.CheckOptions = from.CheckOptions
+	to.CheckSource = from.CheckSource
+	to.CheckExecutor = from.CheckExecutor
+	to.Latency = from.Latency
+	to.ExecutionTime = from.ExecutionTime
+	to.LastCheck = from.LastCheck
+	to.NextCheck = from.NextCheck
+	to.LastStateChange = from.LastStateChange
+	to.LastHardStateChange = from.LastHardStateChange
+	to.LastHardStateCause = from.LastHardStateCause
+	to.StateHistoryIndex = from.StateHistoryIndex
+	to.PendingFlexDowntime = from.PendingFlexDowntime
+	to.FirstProblemTime = from.FirstProblemTime
+	to.CurrentEventID = from.CurrentEventID
+	to.LastEventID = from.LastEventID
+	to.IsBeingFreshened = from.IsBeingFreshened
+	to.Dynamic = to.Dynamic || from.Dynamic
+	to.LastSeen = from.LastSeen
+	to.NotificationCounts = from.NotificationCounts
+	to.CheckHistory = from.CheckHistory
+}
+
+// carryService is carryHost for services.
+func carryService(from, to *objects.Service) {
+	to.IsExecuting = from.IsExecuting
+	to.CheckEpoch = from.CheckEpoch
+	to.ExecutionID = from.ExecutionID
+	to.CheckOptions = from.CheckOptions
+	to.CheckSource = from.CheckSource
+	to.CheckExecutor = from.CheckExecutor
+	to.Latency = from.Latency
+	to.ExecutionTime = from.ExecutionTime
+	to.LastCheck = from.LastCheck
+	to.NextCheck = from.NextCheck
+	to.LastStateChange = from.LastStateChange
+	to.LastHardStateChange = from.LastHardStateChange
+	to.LastHardStateCause = from.LastHardStateCause
+	to.StateHistoryIndex = from.StateHistoryIndex
+	to.PendingFlexDowntime = from.PendingFlexDowntime
+	to.HostProblemAtLastCheck = from.HostProblemAtLastCheck
+	to.FirstProblemTime = from.FirstProblemTime
+	to.CurrentEventID = from.CurrentEventID
+	to.LastEventID = from.LastEventID
+	to.IsBeingFreshened = from.IsBeingFreshened
+	to.Dynamic = to.Dynamic || from.Dynamic
+	to.LastSeen = from.LastSeen
+	to.NotificationCounts = from.NotificationCounts
+	to.CheckHistory = from.CheckHistory
+}
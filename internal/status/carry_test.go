@@ -0,0 +1,62 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestCarryState(t *testing.T) {
+	from := objects.NewObjectStore()
+	h := &objects.Host{Name: "web01", NotificationsEnabled: true}
+	from.AddHost(h)
+	svc := &objects.Service{Host: h, Description: "HTTP", NotificationsEnabled: true}
+	from.AddService(svc)
+	gone := &objects.Service{Host: h, Description: "FTP"}
+	from.AddService(gone)
+
+	svc.CurrentState = objects.ServiceCritical
+	svc.StateType = objects.StateTypeHard
+	svc.HasBeenChecked = true
+	svc.PluginOutput = "CRITICAL - connection refused"
+	svc.ProblemAcknowledged = true
+	svc.IsExecuting = true
+	svc.ExecutionID = 42
+	svc.NextCheck = time.Unix(1700000000, 500)
+	// Disabled at runtime, so it stays disabled. The host's notifications
+	// were only off in the config, so the reloaded config turns them on.
+	svc.NotificationsEnabled = false
+	svc.ModifiedAttributes = objects.ModAttrNotificationsEnabled
+	h.NotificationsEnabled = false
+	h.Dynamic = true
+	svc.NotificationCounts = &objects.NotificationCounts{}
+	svc.CheckHistory = objects.NewCheckHistory(3)
+
+	to := objects.NewObjectStore()
+	nh := &objects.Host{Name: "web01", NotificationsEnabled: true}
+	to.AddHost(nh)
+	nsvc := &objects.Service{Host: nh, Description: "HTTP", NotificationsEnabled: true}
+	to.AddService(nsvc)
+	added := &objects.Service{Host: nh, Description: "SSH"}
+	to.AddService(added)
+	addedEpoch := added.CheckEpoch
+
+	CarryState(from, to)
+
+	if nsvc.CurrentState != objects.ServiceCritical || nsvc.PluginOutput != svc.PluginOutput || !nsvc.ProblemAcknowledged {
+		t.Errorf("state not carried: %+v", nsvc)
+	}
+	if nsvc.NotificationsEnabled || !nh.NotificationsEnabled {
+		t.Errorf("notifications_enabled: service %v, want off; host %v, want on", nsvc.NotificationsEnabled, nh.NotificationsEnabled)
+	}
+	if !nsvc.IsExecuting || nsvc.CheckEpoch != svc.CheckEpoch || nsvc.ExecutionID != 42 || !nsvc.NextCheck.Equal(svc.NextCheck) {
+		t.Error("check in flight not carried")
+	}
+	if !nh.Dynamic || nsvc.NotificationCounts != svc.NotificationCounts || nsvc.CheckHistory != svc.CheckHistory {
+		t.Error("dynamic flag, notification counts or check history not carried")
+	}
+	if added.HasBeenChecked || added.CheckEpoch != addedEpoch {
+		t.Error("new service should keep its initial state")
+	}
+}
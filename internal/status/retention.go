@@ -3,10 +3,12 @@ package status
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oceanplexian/gogios/internal/downtime"
@@ -20,294 +22,427 @@ type RetentionWriter struct {
 	Global    *objects.GlobalState
 	Comments  *downtime.CommentManager
 	Downtimes *downtime.DowntimeManager
+	Blackouts *downtime.BlackoutManager // optional; runtime blackouts are kept
 	Version   string
+	// Shards, when above 1, splits host and service blocks across that many
+	// files (Path.1 to Path.N) rendered and written in parallel. Path keeps
+	// the rest and names the shard count, so RetentionReader finds them.
+	Shards int
+
+	mu        sync.Mutex
+	buf       blockBuf   // reused between writes
+	shardBufs []blockBuf // likewise, one per shard
 }
 
-// Write atomically writes the retention.dat file.
+// Write atomically writes the retention.dat file, and its shards when
+// Shards is above 1.
 func (rw *RetentionWriter) Write() error {
-	// Always create the temp file alongside the target so os.Rename
-	// never crosses filesystem boundaries.
-	dir := filepath.Dir(rw.Path)
-	tmp, err := os.CreateTemp(dir, "retention.dat.tmp.*")
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.Shards > 1 {
+		return rw.writeSharded()
+	}
+	rw.buf.reset()
+	rw.render(&rw.buf, false)
+	if err := writeFileAtomic(rw.Path, rw.buf.b); err != nil {
+		return err
+	}
+	removeShardFiles(rw.Path, 1)
+	return nil
+}
+
+// writeSharded writes each shard, then the main file naming them. The
+// shards are renamed into place before the main file, so a reader never
+// sees a main file whose shards are missing.
+func (rw *RetentionWriter) writeSharded() error {
+	n := rw.Shards
+	if len(rw.shardBufs) != n {
+		rw.shardBufs = make([]blockBuf, n)
+	}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := &rw.shardBufs[i]
+			b.reset()
+			for j := i; j < len(rw.Store.Hosts); j += n {
+				rw.writeHost(b, rw.Store.Hosts[j])
+			}
+			for j := i; j < len(rw.Store.Services); j += n {
+				rw.writeService(b, rw.Store.Services[j])
+			}
+			errs[i] = writeFileAtomic(shardPath(rw.Path, i+1), b.b)
+		}(i)
+	}
+	rw.buf.reset()
+	rw.renderInfo(&rw.buf, time.Now(), n)
+	rw.writeProgram(&rw.buf)
+	rw.renderRest(&rw.buf, false)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("retention shard %d: %w", i+1, err)
+		}
+	}
+	if err := writeFileAtomic(rw.Path, rw.buf.b); err != nil {
+		return err
+	}
+	removeShardFiles(rw.Path, n+1)
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it
+// over path. The temp file is created alongside the target so os.Rename
+// never crosses filesystem boundaries.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp.*")
 	if err != nil {
 		return fmt.Errorf("create temp file: %w", err)
 	}
 	tmpName := tmp.Name()
-	defer func() {
-		if tmp != nil {
-			tmp.Close()
-			os.Remove(tmpName)
-		}
-	}()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
 
-	var b strings.Builder
-	now := time.Now()
+// shardPath returns the path of retention shard i (from 1).
+func shardPath(path string, i int) string {
+	return path + "." + strconv.Itoa(i)
+}
+
+// removeShardFiles removes the shards numbered from first up, left over
+// from a save with more shards, or before sharding was turned off.
+func removeShardFiles(path string, first int) {
+	for i := first; ; i++ {
+		if err := os.Remove(shardPath(path, i)); err != nil {
+			return
+		}
+	}
+}
 
-	// info
-	b.WriteString("info {\n")
-	fmt.Fprintf(&b, "created=%d\n", now.Unix())
-	fmt.Fprintf(&b, "version=%s\n", rw.Version)
-	b.WriteString("}\n\n")
+// render appends the retention data to b. Only persistent comments are
+// included unless allComments is set.
+func (rw *RetentionWriter) render(b *blockBuf, allComments bool) {
+	rw.renderInfo(b, time.Now(), 0)
 
 	// program
-	rw.writeProgram(&b)
+	rw.writeProgram(b)
 
 	// hosts
 	for _, h := range rw.Store.Hosts {
-		rw.writeHost(&b, h)
+		rw.writeHost(b, h)
 	}
 
 	// services
 	for _, s := range rw.Store.Services {
-		rw.writeService(&b, s)
+		rw.writeService(b, s)
 	}
 
+	rw.renderRest(b, allComments)
+}
+
+// renderInfo appends the info block, naming the shard count if sharded.
+func (rw *RetentionWriter) renderInfo(b *blockBuf, now time.Time, shards int) {
+	b.begin("info")
+	b.int64("created", now.Unix())
+	b.str("version", rw.Version)
+	if shards > 1 {
+		b.int("shards", shards)
+	}
+	b.end()
+}
+
+// renderRest appends the blocks that follow hosts and services.
+func (rw *RetentionWriter) renderRest(b *blockBuf, allComments bool) {
+	now := time.Now()
+
 	// contacts
 	for _, c := range rw.Store.Contacts {
-		rw.writeContact(&b, c)
+		rw.writeContact(b, c)
 	}
 
 	// comments
 	for _, c := range rw.Comments.All() {
-		if !c.Persistent {
+		if !c.Persistent && !allComments {
 			continue
 		}
-		rw.writeComment(&b, c)
+		rw.writeComment(b, c)
 	}
 
 	// downtimes
 	for _, d := range rw.Downtimes.All() {
-		rw.writeDowntime(&b, d)
+		rw.writeDowntime(b, d)
 	}
 
-	if _, err := tmp.WriteString(b.String()); err != nil {
-		return err
+	// runtime blackouts; configured ones come from the object config
+	if rw.Blackouts != nil {
+		for _, bo := range rw.Blackouts.All() {
+			if bo.Runtime && (bo.EndTime.IsZero() || bo.EndTime.After(now)) {
+				rw.writeBlackout(b, bo)
+			}
+		}
 	}
-	if err := tmp.Close(); err != nil {
-		return err
+}
+
+// nextCommentID returns the next comment ID to persist. The comment
+// manager is the only allocator; GlobalState.NextCommentID just carries the
+// value read from retention, which the reader also seeds the manager with.
+func nextCommentID(g *objects.GlobalState, cm *downtime.CommentManager) uint64 {
+	if cm != nil {
+		return cm.NextID()
+	}
+	return g.NextCommentID
+}
+
+// nextDowntimeID is nextCommentID for downtimes.
+func nextDowntimeID(g *objects.GlobalState, dm *downtime.DowntimeManager) uint64 {
+	if dm != nil {
+		return dm.NextID()
 	}
-	tmp = nil
-	return os.Rename(tmpName, rw.Path)
+	return g.NextDowntimeID
 }
 
-func (rw *RetentionWriter) writeProgram(b *strings.Builder) {
+func (rw *RetentionWriter) writeProgram(b *blockBuf) {
 	g := rw.Global
-	b.WriteString("program {\n")
-	fmt.Fprintf(b, "modified_host_attributes=%d\n", g.ModifiedHostAttributes)
-	fmt.Fprintf(b, "modified_service_attributes=%d\n", g.ModifiedServiceAttributes)
-	fmt.Fprintf(b, "enable_notifications=%s\n", boolStr(g.EnableNotifications))
-	fmt.Fprintf(b, "active_service_checks_enabled=%s\n", boolStr(g.ExecuteServiceChecks))
-	fmt.Fprintf(b, "passive_service_checks_enabled=%s\n", boolStr(g.AcceptPassiveServiceChecks))
-	fmt.Fprintf(b, "active_host_checks_enabled=%s\n", boolStr(g.ExecuteHostChecks))
-	fmt.Fprintf(b, "passive_host_checks_enabled=%s\n", boolStr(g.AcceptPassiveHostChecks))
-	fmt.Fprintf(b, "enable_event_handlers=%s\n", boolStr(g.EnableEventHandlers))
-	fmt.Fprintf(b, "obsess_over_services=%s\n", boolStr(g.ObsessOverServices))
-	fmt.Fprintf(b, "obsess_over_hosts=%s\n", boolStr(g.ObsessOverHosts))
-	fmt.Fprintf(b, "check_service_freshness=%s\n", boolStr(g.CheckServiceFreshness))
-	fmt.Fprintf(b, "check_host_freshness=%s\n", boolStr(g.CheckHostFreshness))
-	fmt.Fprintf(b, "enable_flap_detection=%s\n", boolStr(g.EnableFlapDetection))
-	fmt.Fprintf(b, "process_performance_data=%s\n", boolStr(g.ProcessPerformanceData))
-	fmt.Fprintf(b, "global_host_event_handler=%s\n", g.GlobalHostEventHandler)
-	fmt.Fprintf(b, "global_service_event_handler=%s\n", g.GlobalServiceEventHandler)
-	fmt.Fprintf(b, "next_comment_id=%d\n", g.NextCommentID)
-	fmt.Fprintf(b, "next_downtime_id=%d\n", g.NextDowntimeID)
-	fmt.Fprintf(b, "next_event_id=%d\n", g.NextEventID)
-	fmt.Fprintf(b, "next_problem_id=%d\n", g.NextProblemID)
-	fmt.Fprintf(b, "next_notification_id=%d\n", g.NextNotificationID)
-	b.WriteString("}\n\n")
-}
-
-func (rw *RetentionWriter) writeHost(b *strings.Builder, h *objects.Host) {
-	b.WriteString("host {\n")
-	fmt.Fprintf(b, "host_name=%s\n", h.Name)
-	fmt.Fprintf(b, "modified_attributes=%d\n", h.ModifiedAttributes)
-	fmt.Fprintf(b, "check_command=%s\n", cmdName(h.CheckCommand, h.CheckCommandArgs))
-	fmt.Fprintf(b, "check_interval=%f\n", h.CheckInterval)
-	fmt.Fprintf(b, "retry_interval=%f\n", h.RetryInterval)
-	fmt.Fprintf(b, "has_been_checked=%s\n", boolStr(h.HasBeenChecked))
-	fmt.Fprintf(b, "check_execution_time=%f\n", h.ExecutionTime)
-	fmt.Fprintf(b, "check_latency=%f\n", h.Latency)
-	fmt.Fprintf(b, "check_type=%d\n", h.CheckType)
-	fmt.Fprintf(b, "current_state=%d\n", h.CurrentState)
-	fmt.Fprintf(b, "last_state=%d\n", h.LastState)
-	fmt.Fprintf(b, "last_hard_state=%d\n", h.LastHardState)
-	fmt.Fprintf(b, "state_type=%d\n", h.StateType)
-	fmt.Fprintf(b, "current_attempt=%d\n", h.CurrentAttempt)
-	fmt.Fprintf(b, "plugin_output=%s\n", h.PluginOutput)
-	fmt.Fprintf(b, "long_plugin_output=%s\n", h.LongPluginOutput)
-	fmt.Fprintf(b, "performance_data=%s\n", h.PerfData)
-	fmt.Fprintf(b, "last_check=%d\n", timeToUnix(h.LastCheck))
-	fmt.Fprintf(b, "next_check=%d\n", timeToUnix(h.NextCheck))
-	fmt.Fprintf(b, "last_state_change=%d\n", timeToUnix(h.LastStateChange))
-	fmt.Fprintf(b, "last_hard_state_change=%d\n", timeToUnix(h.LastHardStateChange))
-	fmt.Fprintf(b, "last_time_up=%d\n", timeToUnix(h.LastTimeUp))
-	fmt.Fprintf(b, "last_time_down=%d\n", timeToUnix(h.LastTimeDown))
-	fmt.Fprintf(b, "last_time_unreachable=%d\n", timeToUnix(h.LastTimeUnreachable))
-	fmt.Fprintf(b, "last_notification=%d\n", timeToUnix(h.LastNotification))
-	fmt.Fprintf(b, "next_notification=%d\n", timeToUnix(h.NextNotification))
-	fmt.Fprintf(b, "no_more_notifications=%s\n", boolStr(h.NoMoreNotifications))
-	fmt.Fprintf(b, "current_notification_number=%d\n", h.CurrentNotificationNumber)
-	fmt.Fprintf(b, "current_notification_id=%d\n", h.CurrentNotificationID)
-	fmt.Fprintf(b, "notifications_enabled=%s\n", boolStr(h.NotificationsEnabled))
-	fmt.Fprintf(b, "problem_has_been_acknowledged=%s\n", boolStr(h.ProblemAcknowledged))
-	fmt.Fprintf(b, "acknowledgement_type=%d\n", h.AckType)
-	fmt.Fprintf(b, "active_checks_enabled=%s\n", boolStr(h.ActiveChecksEnabled))
-	fmt.Fprintf(b, "passive_checks_enabled=%s\n", boolStr(h.PassiveChecksEnabled))
-	fmt.Fprintf(b, "event_handler_enabled=%s\n", boolStr(h.EventHandlerEnabled))
-	fmt.Fprintf(b, "flap_detection_enabled=%s\n", boolStr(h.FlapDetectionEnabled))
-	fmt.Fprintf(b, "process_performance_data=%s\n", boolStr(h.ProcessPerfData))
-	fmt.Fprintf(b, "obsess=%s\n", boolStr(h.ObsessOver))
-	fmt.Fprintf(b, "is_flapping=%s\n", boolStr(h.IsFlapping))
-	fmt.Fprintf(b, "percent_state_change=%f\n", h.PercentStateChange)
-	fmt.Fprintf(b, "scheduled_downtime_depth=%d\n", h.ScheduledDowntimeDepth)
-	fmt.Fprintf(b, "notified_on_down=%s\n", boolStr(h.NotifiedOn&objects.OptDown != 0))
-	fmt.Fprintf(b, "notified_on_unreachable=%s\n", boolStr(h.NotifiedOn&objects.OptUnreachable != 0))
-	fmt.Fprintf(b, "check_flapping_recovery_notification=%s\n", boolStr(h.CheckFlapRecoveryNotif))
-	// state_history
-	histParts := make([]string, len(h.StateHistory))
-	for i, v := range h.StateHistory {
-		histParts[i] = strconv.Itoa(v)
-	}
-	fmt.Fprintf(b, "state_history=%s\n", strings.Join(histParts, ","))
-	for k, v := range h.CustomVars {
-		fmt.Fprintf(b, "_%s=%d;%s\n", k, 0, v)
-	}
-	b.WriteString("}\n\n")
-}
-
-func (rw *RetentionWriter) writeService(b *strings.Builder, s *objects.Service) {
+	b.begin("program")
+	b.uint("modified_host_attributes", g.ModifiedHostAttributes)
+	b.uint("modified_service_attributes", g.ModifiedServiceAttributes)
+	b.bool("enable_notifications", g.EnableNotifications)
+	b.bool("active_service_checks_enabled", g.ExecuteServiceChecks)
+	b.bool("passive_service_checks_enabled", g.AcceptPassiveServiceChecks)
+	b.bool("active_host_checks_enabled", g.ExecuteHostChecks)
+	b.bool("passive_host_checks_enabled", g.AcceptPassiveHostChecks)
+	b.bool("enable_event_handlers", g.EnableEventHandlers)
+	b.bool("obsess_over_services", g.ObsessOverServices)
+	b.bool("obsess_over_hosts", g.ObsessOverHosts)
+	b.bool("check_service_freshness", g.CheckServiceFreshness)
+	b.bool("check_host_freshness", g.CheckHostFreshness)
+	b.bool("enable_flap_detection", g.EnableFlapDetection)
+	b.bool("process_performance_data", g.ProcessPerformanceData)
+	b.str("global_host_event_handler", g.GlobalHostEventHandler)
+	b.str("global_service_event_handler", g.GlobalServiceEventHandler)
+	b.uint("next_comment_id", nextCommentID(g, rw.Comments))
+	b.uint("next_downtime_id", nextDowntimeID(g, rw.Downtimes))
+	b.uint("next_event_id", g.NextEventID)
+	b.uint("next_problem_id", g.NextProblemID)
+	b.uint("next_notification_id", g.NextNotificationID)
+	b.end()
+}
+
+func (rw *RetentionWriter) writeHost(b *blockBuf, h *objects.Host) {
+	b.begin("host")
+	b.str("host_name", h.Name)
+	b.uint("modified_attributes", h.ModifiedAttributes)
+	b.str("check_command", cmdName(h.CheckCommand, h.CheckCommandArgs))
+	b.str("check_period", timeperiodName(h.CheckPeriod))
+	b.str("notification_period", timeperiodName(h.NotificationPeriod))
+	b.str("event_handler", cmdName(h.EventHandler, h.EventHandlerArgs))
+	b.float("check_interval", h.CheckInterval)
+	b.float("retry_interval", h.RetryInterval)
+	b.int("max_attempts", h.MaxCheckAttempts)
+	b.bool("has_been_checked", h.HasBeenChecked)
+	b.float("check_execution_time", h.ExecutionTime)
+	b.float("check_latency", h.Latency)
+	b.int("check_type", h.CheckType)
+	b.int("current_state", h.CurrentState)
+	b.int("last_state", h.LastState)
+	b.int("last_hard_state", h.LastHardState)
+	b.int("state_type", h.StateType)
+	b.int("current_attempt", h.CurrentAttempt)
+	b.str("plugin_output", h.PluginOutput)
+	b.str("long_plugin_output", h.LongPluginOutput)
+	b.str("performance_data", h.PerfData)
+	b.time("last_check", h.LastCheck)
+	b.time("next_check", h.NextCheck)
+	b.time("last_state_change", h.LastStateChange)
+	b.time("last_hard_state_change", h.LastHardStateChange)
+	b.time("last_time_up", h.LastTimeUp)
+	b.time("last_time_down", h.LastTimeDown)
+	b.time("last_time_unreachable", h.LastTimeUnreachable)
+	b.time("last_notification", h.LastNotification)
+	b.time("next_notification", h.NextNotification)
+	b.bool("no_more_notifications", h.NoMoreNotifications)
+	b.int("current_notification_number", h.CurrentNotificationNumber)
+	b.uint("current_notification_id", h.CurrentNotificationID)
+	b.uint("current_problem_id", h.CurrentProblemID)
+	b.uint("last_problem_id", h.LastProblemID)
+	b.bool("notifications_enabled", h.NotificationsEnabled)
+	b.bool("problem_has_been_acknowledged", h.ProblemAcknowledged)
+	b.int("acknowledgement_type", h.AckType)
+	b.bool("active_checks_enabled", h.ActiveChecksEnabled)
+	b.bool("passive_checks_enabled", h.PassiveChecksEnabled)
+	b.bool("event_handler_enabled", h.EventHandlerEnabled)
+	b.bool("flap_detection_enabled", h.FlapDetectionEnabled)
+	b.bool("process_performance_data", h.ProcessPerfData)
+	b.bool("obsess", h.ObsessOver)
+	b.bool("is_flapping", h.IsFlapping)
+	b.float("percent_state_change", h.PercentStateChange)
+	b.int("scheduled_downtime_depth", h.ScheduledDowntimeDepth)
+	b.bool("notified_on_down", h.NotifiedOn&objects.OptDown != 0)
+	b.bool("notified_on_unreachable", h.NotifiedOn&objects.OptUnreachable != 0)
+	b.bool("check_flapping_recovery_notification", h.CheckFlapRecoveryNotif)
+	b.ints("state_history", oldestFirst(&h.StateHistory, h.StateHistoryIndex))
+	b.customVars(h.CustomVars)
+	b.end()
+}
+
+func (rw *RetentionWriter) writeService(b *blockBuf, s *objects.Service) {
 	hostName := ""
 	if s.Host != nil {
 		hostName = s.Host.Name
 	}
-	b.WriteString("service {\n")
-	fmt.Fprintf(b, "host_name=%s\n", hostName)
-	fmt.Fprintf(b, "service_description=%s\n", s.Description)
-	fmt.Fprintf(b, "modified_attributes=%d\n", s.ModifiedAttributes)
-	fmt.Fprintf(b, "check_command=%s\n", cmdName(s.CheckCommand, s.CheckCommandArgs))
-	fmt.Fprintf(b, "check_interval=%f\n", s.CheckInterval)
-	fmt.Fprintf(b, "retry_interval=%f\n", s.RetryInterval)
-	fmt.Fprintf(b, "has_been_checked=%s\n", boolStr(s.HasBeenChecked))
-	fmt.Fprintf(b, "check_execution_time=%f\n", s.ExecutionTime)
-	fmt.Fprintf(b, "check_latency=%f\n", s.Latency)
-	fmt.Fprintf(b, "check_type=%d\n", s.CheckType)
-	fmt.Fprintf(b, "current_state=%d\n", s.CurrentState)
-	fmt.Fprintf(b, "last_state=%d\n", s.LastState)
-	fmt.Fprintf(b, "last_hard_state=%d\n", s.LastHardState)
-	fmt.Fprintf(b, "state_type=%d\n", s.StateType)
-	fmt.Fprintf(b, "current_attempt=%d\n", s.CurrentAttempt)
-	fmt.Fprintf(b, "plugin_output=%s\n", s.PluginOutput)
-	fmt.Fprintf(b, "long_plugin_output=%s\n", s.LongPluginOutput)
-	fmt.Fprintf(b, "performance_data=%s\n", s.PerfData)
-	fmt.Fprintf(b, "last_check=%d\n", timeToUnix(s.LastCheck))
-	fmt.Fprintf(b, "next_check=%d\n", timeToUnix(s.NextCheck))
-	fmt.Fprintf(b, "last_state_change=%d\n", timeToUnix(s.LastStateChange))
-	fmt.Fprintf(b, "last_hard_state_change=%d\n", timeToUnix(s.LastHardStateChange))
-	fmt.Fprintf(b, "last_time_ok=%d\n", timeToUnix(s.LastTimeOK))
-	fmt.Fprintf(b, "last_time_warning=%d\n", timeToUnix(s.LastTimeWarning))
-	fmt.Fprintf(b, "last_time_critical=%d\n", timeToUnix(s.LastTimeCritical))
-	fmt.Fprintf(b, "last_time_unknown=%d\n", timeToUnix(s.LastTimeUnknown))
-	fmt.Fprintf(b, "last_notification=%d\n", timeToUnix(s.LastNotification))
-	fmt.Fprintf(b, "next_notification=%d\n", timeToUnix(s.NextNotification))
-	fmt.Fprintf(b, "no_more_notifications=%s\n", boolStr(s.NoMoreNotifications))
-	fmt.Fprintf(b, "current_notification_number=%d\n", s.CurrentNotificationNumber)
-	fmt.Fprintf(b, "current_notification_id=%d\n", s.CurrentNotificationID)
-	fmt.Fprintf(b, "notifications_enabled=%s\n", boolStr(s.NotificationsEnabled))
-	fmt.Fprintf(b, "problem_has_been_acknowledged=%s\n", boolStr(s.ProblemAcknowledged))
-	fmt.Fprintf(b, "acknowledgement_type=%d\n", s.AckType)
-	fmt.Fprintf(b, "active_checks_enabled=%s\n", boolStr(s.ActiveChecksEnabled))
-	fmt.Fprintf(b, "passive_checks_enabled=%s\n", boolStr(s.PassiveChecksEnabled))
-	fmt.Fprintf(b, "event_handler_enabled=%s\n", boolStr(s.EventHandlerEnabled))
-	fmt.Fprintf(b, "flap_detection_enabled=%s\n", boolStr(s.FlapDetectionEnabled))
-	fmt.Fprintf(b, "process_performance_data=%s\n", boolStr(s.ProcessPerfData))
-	fmt.Fprintf(b, "obsess=%s\n", boolStr(s.ObsessOver))
-	fmt.Fprintf(b, "is_flapping=%s\n", boolStr(s.IsFlapping))
-	fmt.Fprintf(b, "percent_state_change=%f\n", s.PercentStateChange)
-	fmt.Fprintf(b, "scheduled_downtime_depth=%d\n", s.ScheduledDowntimeDepth)
-	fmt.Fprintf(b, "notified_on_unknown=%s\n", boolStr(s.NotifiedOn&objects.OptUnknown != 0))
-	fmt.Fprintf(b, "notified_on_warning=%s\n", boolStr(s.NotifiedOn&objects.OptWarning != 0))
-	fmt.Fprintf(b, "notified_on_critical=%s\n", boolStr(s.NotifiedOn&objects.OptCritical != 0))
-	fmt.Fprintf(b, "check_flapping_recovery_notification=%s\n", boolStr(s.CheckFlapRecoveryNotif))
-	histParts := make([]string, len(s.StateHistory))
-	for i, v := range s.StateHistory {
-		histParts[i] = strconv.Itoa(v)
-	}
-	fmt.Fprintf(b, "state_history=%s\n", strings.Join(histParts, ","))
-	for k, v := range s.CustomVars {
-		fmt.Fprintf(b, "_%s=%d;%s\n", k, 0, v)
-	}
-	b.WriteString("}\n\n")
-}
-
-func (rw *RetentionWriter) writeContact(b *strings.Builder, c *objects.Contact) {
-	b.WriteString("contact {\n")
-	fmt.Fprintf(b, "contact_name=%s\n", c.Name)
-	fmt.Fprintf(b, "modified_attributes=%d\n", c.ModifiedAttributes)
-	fmt.Fprintf(b, "modified_host_attributes=%d\n", c.ModifiedHostAttributes)
-	fmt.Fprintf(b, "modified_service_attributes=%d\n", c.ModifiedServiceAttributes)
+	b.begin("service")
+	b.str("host_name", hostName)
+	b.str("service_description", s.Description)
+	b.uint("modified_attributes", s.ModifiedAttributes)
+	b.str("check_command", cmdName(s.CheckCommand, s.CheckCommandArgs))
+	b.str("check_period", timeperiodName(s.CheckPeriod))
+	b.str("notification_period", timeperiodName(s.NotificationPeriod))
+	b.str("event_handler", cmdName(s.EventHandler, s.EventHandlerArgs))
+	b.float("check_interval", s.CheckInterval)
+	b.float("retry_interval", s.RetryInterval)
+	b.int("max_attempts", s.MaxCheckAttempts)
+	b.bool("has_been_checked", s.HasBeenChecked)
+	b.float("check_execution_time", s.ExecutionTime)
+	b.float("check_latency", s.Latency)
+	b.int("check_type", s.CheckType)
+	b.int("current_state", s.CurrentState)
+	b.int("last_state", s.LastState)
+	b.int("last_hard_state", s.LastHardState)
+	b.int("state_type", s.StateType)
+	b.int("current_attempt", s.CurrentAttempt)
+	b.str("plugin_output", s.PluginOutput)
+	b.str("long_plugin_output", s.LongPluginOutput)
+	b.str("performance_data", s.PerfData)
+	b.time("last_check", s.LastCheck)
+	b.time("next_check", s.NextCheck)
+	b.time("last_state_change", s.LastStateChange)
+	b.time("last_hard_state_change", s.LastHardStateChange)
+	b.time("last_time_ok", s.LastTimeOK)
+	b.time("last_time_warning", s.LastTimeWarning)
+	b.time("last_time_critical", s.LastTimeCritical)
+	b.time("last_time_unknown", s.LastTimeUnknown)
+	b.time("last_notification", s.LastNotification)
+	b.time("next_notification", s.NextNotification)
+	b.bool("no_more_notifications", s.NoMoreNotifications)
+	b.int("current_notification_number", s.CurrentNotificationNumber)
+	b.uint("current_notification_id", s.CurrentNotificationID)
+	b.uint("current_problem_id", s.CurrentProblemID)
+	b.uint("last_problem_id", s.LastProblemID)
+	b.bool("notifications_enabled", s.NotificationsEnabled)
+	b.bool("problem_has_been_acknowledged", s.ProblemAcknowledged)
+	b.int("acknowledgement_type", s.AckType)
+	b.bool("active_checks_enabled", s.ActiveChecksEnabled)
+	b.bool("passive_checks_enabled", s.PassiveChecksEnabled)
+	b.bool("event_handler_enabled", s.EventHandlerEnabled)
+	b.bool("flap_detection_enabled", s.FlapDetectionEnabled)
+	b.bool("process_performance_data", s.ProcessPerfData)
+	b.bool("obsess", s.ObsessOver)
+	b.bool("is_flapping", s.IsFlapping)
+	b.float("percent_state_change", s.PercentStateChange)
+	b.int("scheduled_downtime_depth", s.ScheduledDowntimeDepth)
+	b.bool("notified_on_unknown", s.NotifiedOn&objects.OptUnknown != 0)
+	b.bool("notified_on_warning", s.NotifiedOn&objects.OptWarning != 0)
+	b.bool("notified_on_critical", s.NotifiedOn&objects.OptCritical != 0)
+	b.bool("check_flapping_recovery_notification", s.CheckFlapRecoveryNotif)
+	b.ints("state_history", oldestFirst(&s.StateHistory, s.StateHistoryIndex))
+	b.customVars(s.CustomVars)
+	b.end()
+}
+
+func (rw *RetentionWriter) writeContact(b *blockBuf, c *objects.Contact) {
+	b.begin("contact")
+	b.str("contact_name", c.Name)
+	b.uint("modified_attributes", c.ModifiedAttributes)
+	b.uint("modified_host_attributes", c.ModifiedHostAttributes)
+	b.uint("modified_service_attributes", c.ModifiedServiceAttributes)
 	tpName := ""
 	if c.HostNotificationPeriod != nil {
 		tpName = c.HostNotificationPeriod.Name
 	}
-	fmt.Fprintf(b, "host_notification_period=%s\n", tpName)
+	b.str("host_notification_period", tpName)
 	tpName = ""
 	if c.ServiceNotificationPeriod != nil {
 		tpName = c.ServiceNotificationPeriod.Name
 	}
-	fmt.Fprintf(b, "service_notification_period=%s\n", tpName)
-	fmt.Fprintf(b, "host_notifications_enabled=%s\n", boolStr(c.HostNotificationsEnabled))
-	fmt.Fprintf(b, "service_notifications_enabled=%s\n", boolStr(c.ServiceNotificationsEnabled))
-	fmt.Fprintf(b, "last_host_notification=%d\n", timeToUnix(c.LastHostNotification))
-	fmt.Fprintf(b, "last_service_notification=%d\n", timeToUnix(c.LastServiceNotification))
-	for k, v := range c.CustomVars {
-		fmt.Fprintf(b, "_%s=%d;%s\n", k, 0, v)
-	}
-	b.WriteString("}\n\n")
+	b.str("service_notification_period", tpName)
+	b.bool("host_notifications_enabled", c.HostNotificationsEnabled)
+	b.bool("service_notifications_enabled", c.ServiceNotificationsEnabled)
+	b.time("last_host_notification", c.LastHostNotification)
+	b.time("last_service_notification", c.LastServiceNotification)
+	b.customVars(c.CustomVars)
+	b.end()
 }
 
-func (rw *RetentionWriter) writeComment(b *strings.Builder, c *downtime.Comment) {
+func (rw *RetentionWriter) writeComment(b *blockBuf, c *downtime.Comment) {
 	blockName := "hostcomment"
 	if c.CommentType == objects.ServiceCommentType {
 		blockName = "servicecomment"
 	}
-	fmt.Fprintf(b, "%s {\n", blockName)
-	fmt.Fprintf(b, "host_name=%s\n", c.HostName)
+	b.begin(blockName)
+	b.str("host_name", c.HostName)
 	if c.CommentType == objects.ServiceCommentType {
-		fmt.Fprintf(b, "service_description=%s\n", c.ServiceDescription)
-	}
-	fmt.Fprintf(b, "entry_type=%d\n", c.EntryType)
-	fmt.Fprintf(b, "comment_id=%d\n", c.CommentID)
-	fmt.Fprintf(b, "source=%d\n", c.Source)
-	fmt.Fprintf(b, "persistent=%s\n", boolStr(c.Persistent))
-	fmt.Fprintf(b, "entry_time=%d\n", c.EntryTime.Unix())
-	fmt.Fprintf(b, "expires=%s\n", boolStr(c.Expires))
-	fmt.Fprintf(b, "expire_time=%d\n", timeToUnix(c.ExpireTime))
-	fmt.Fprintf(b, "author=%s\n", c.Author)
-	fmt.Fprintf(b, "comment_data=%s\n", c.Data)
-	b.WriteString("}\n\n")
+		b.str("service_description", c.ServiceDescription)
+	}
+	b.int("entry_type", c.EntryType)
+	b.uint("comment_id", c.CommentID)
+	b.int("source", c.Source)
+	b.bool("persistent", c.Persistent)
+	b.int64("entry_time", c.EntryTime.Unix())
+	b.bool("expires", c.Expires)
+	b.time("expire_time", c.ExpireTime)
+	b.str("author", c.Author)
+	b.str("comment_data", c.Data)
+	b.end()
 }
 
-func (rw *RetentionWriter) writeDowntime(b *strings.Builder, d *downtime.Downtime) {
+func (rw *RetentionWriter) writeDowntime(b *blockBuf, d *downtime.Downtime) {
 	blockName := "hostdowntime"
 	if d.Type == objects.ServiceDowntimeType {
 		blockName = "servicedowntime"
 	}
-	fmt.Fprintf(b, "%s {\n", blockName)
-	fmt.Fprintf(b, "host_name=%s\n", d.HostName)
+	b.begin(blockName)
+	b.str("host_name", d.HostName)
 	if d.Type == objects.ServiceDowntimeType {
-		fmt.Fprintf(b, "service_description=%s\n", d.ServiceDescription)
-	}
-	fmt.Fprintf(b, "downtime_id=%d\n", d.DowntimeID)
-	fmt.Fprintf(b, "entry_time=%d\n", d.EntryTime.Unix())
-	fmt.Fprintf(b, "start_time=%d\n", d.StartTime.Unix())
-	fmt.Fprintf(b, "end_time=%d\n", d.EndTime.Unix())
-	fmt.Fprintf(b, "triggered_by=%d\n", d.TriggeredBy)
-	fmt.Fprintf(b, "fixed=%s\n", boolStr(d.Fixed))
-	fmt.Fprintf(b, "duration=%d\n", int64(d.Duration.Seconds()))
-	fmt.Fprintf(b, "is_in_effect=%s\n", boolStr(d.IsInEffect))
-	fmt.Fprintf(b, "author=%s\n", d.Author)
-	fmt.Fprintf(b, "comment=%s\n", d.Comment)
-	b.WriteString("}\n\n")
+		b.str("service_description", d.ServiceDescription)
+	}
+	b.uint("downtime_id", d.DowntimeID)
+	b.uint("comment_id", d.CommentID)
+	b.int64("entry_time", d.EntryTime.Unix())
+	b.int64("start_time", d.StartTime.Unix())
+	b.time("flex_downtime_start", d.FlexDowntimeStart)
+	b.int64("end_time", d.EndTime.Unix())
+	b.uint("triggered_by", d.TriggeredBy)
+	b.bool("fixed", d.Fixed)
+	b.int64("duration", int64(d.Duration.Seconds()))
+	b.bool("is_in_effect", d.IsInEffect)
+	b.bool("start_notification_sent", d.StartNotificationSent)
+	b.str("author", d.Author)
+	b.str("comment", d.Comment)
+	b.end()
+}
+
+func (rw *RetentionWriter) writeBlackout(b *blockBuf, bo *objects.Blackout) {
+	b.begin("blackout")
+	b.str("blackout_name", bo.Name)
+	b.time("start_time", bo.StartTime)
+	b.time("end_time", bo.EndTime)
+	b.str("host_name", strings.Join(bo.HostNames, ","))
+	b.str("hostgroup_name", strings.Join(bo.HostGroups, ","))
+	b.str("service_description", strings.Join(bo.ServiceDescriptions, ","))
+	b.str("servicegroup_name", strings.Join(bo.ServiceGroups, ","))
+	b.str("author", bo.Author)
+	b.str("comment", bo.Comment)
+	b.end()
 }
 
 func cmdName(cmd *objects.Command, args string) string {
@@ -320,15 +455,27 @@ func cmdName(cmd *objects.Command, args string) string {
 	return cmd.Name
 }
 
+func timeperiodName(tp *objects.Timeperiod) string {
+	if tp == nil {
+		return ""
+	}
+	return tp.Name
+}
+
 // RetentionReader reads a retention.dat file and applies state to objects.
 type RetentionReader struct {
 	Store     *objects.ObjectStore
 	Global    *objects.GlobalState
 	Comments  *downtime.CommentManager
 	Downtimes *downtime.DowntimeManager
+	Blackouts *downtime.BlackoutManager // optional
 }
 
-// Read reads and applies the retention.dat file.
+// Read reads and applies the retention.dat file. If it was written in
+// shards, the shards are read as soon as its info block names them, so
+// hosts and services are applied before the comments and downtimes that
+// follow, as in a single file. A missing or unreadable shard does not stop
+// the rest from being applied, but is returned as an error.
 func (rr *RetentionReader) Read(path string) error {
 	f, err := os.Open(path)
 	if err != nil {
@@ -338,8 +485,37 @@ func (rr *RetentionReader) Read(path string) error {
 		return err
 	}
 	defer f.Close()
+	var shardErr error
+	err = parseBlocks(f, func(blockType string, fields map[string]string) {
+		if blockType == "info" {
+			for i := 1; i <= parseInt(fields["shards"]); i++ {
+				if err := rr.readShard(shardPath(path, i)); err != nil && shardErr == nil {
+					shardErr = err
+				}
+			}
+			return
+		}
+		rr.applyBlock(blockType, fields)
+	})
+	if err != nil {
+		return err
+	}
+	return shardErr
+}
 
-	scanner := bufio.NewScanner(f)
+func (rr *RetentionReader) readShard(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parseBlocks(f, rr.applyBlock)
+}
+
+// parseBlocks reads "type {" ... "}" blocks of key=value lines, as used by
+// retention.dat and status.dat, and calls fn for each complete block.
+func parseBlocks(r io.Reader, fn func(blockType string, fields map[string]string)) error {
+	scanner := bufio.NewScanner(r)
 	var blockType string
 	var fields map[string]string
 
@@ -357,7 +533,7 @@ func (rr *RetentionReader) Read(path string) error {
 
 		if line == "}" {
 			if fields != nil {
-				rr.applyBlock(blockType, fields)
+				fn(blockType, fields)
 			}
 			blockType = ""
 			fields = nil
@@ -388,6 +564,8 @@ func (rr *RetentionReader) applyBlock(blockType string, fields map[string]string
 		rr.applyComment(fields, blockType)
 	case "hostdowntime", "servicedowntime":
 		rr.applyDowntimeBlock(fields, blockType)
+	case "blackout":
+		rr.applyBlackout(fields)
 	}
 }
 
@@ -419,9 +597,15 @@ func (rr *RetentionReader) applyProgram(f map[string]string) {
 	}
 	if v, ok := f["next_comment_id"]; ok {
 		g.NextCommentID = parseUint64(v)
+		if rr.Comments != nil {
+			rr.Comments.SetNextID(g.NextCommentID)
+		}
 	}
 	if v, ok := f["next_downtime_id"]; ok {
 		g.NextDowntimeID = parseUint64(v)
+		if rr.Downtimes != nil {
+			rr.Downtimes.SetNextID(g.NextDowntimeID)
+		}
 	}
 	if v, ok := f["next_event_id"]; ok {
 		g.NextEventID = parseUint64(v)
@@ -441,7 +625,8 @@ func (rr *RetentionReader) applyHost(f map[string]string) {
 		return
 	}
 	// Only override config-level toggles (notifications, active/passive checks)
-	// if an admin explicitly changed them (modified_attributes != 0).
+	// and attributes if an admin explicitly changed them (modified_attributes
+	// != 0).
 	modAttrs := parseUint64(f["modified_attributes"])
 	if v, ok := f["current_state"]; ok {
 		h.CurrentState = parseInt(v)
@@ -462,10 +647,10 @@ func (rr *RetentionReader) applyHost(f map[string]string) {
 		h.HasBeenChecked = v == "1"
 	}
 	if v, ok := f["plugin_output"]; ok {
-		h.PluginOutput = v
+		h.PluginOutput = objects.Intern(v)
 	}
 	if v, ok := f["long_plugin_output"]; ok {
-		h.LongPluginOutput = v
+		h.LongPluginOutput = objects.Intern(v)
 	}
 	if v, ok := f["performance_data"]; ok {
 		h.PerfData = v
@@ -482,6 +667,21 @@ func (rr *RetentionReader) applyHost(f map[string]string) {
 	if v, ok := f["last_hard_state_change"]; ok {
 		h.LastHardStateChange = unixToTime(v)
 	}
+	if v, ok := f["last_time_up"]; ok {
+		h.LastTimeUp = unixToTime(v)
+	}
+	if v, ok := f["last_time_down"]; ok {
+		h.LastTimeDown = unixToTime(v)
+	}
+	if v, ok := f["last_time_unreachable"]; ok {
+		h.LastTimeUnreachable = unixToTime(v)
+	}
+	if v, ok := f["current_problem_id"]; ok {
+		h.CurrentProblemID = parseUint64(v)
+	}
+	if v, ok := f["last_problem_id"]; ok {
+		h.LastProblemID = parseUint64(v)
+	}
 	if v, ok := f["last_notification"]; ok {
 		h.LastNotification = unixToTime(v)
 	}
@@ -504,6 +704,14 @@ func (rr *RetentionReader) applyHost(f map[string]string) {
 		if v, ok := f["passive_checks_enabled"]; ok {
 			h.PassiveChecksEnabled = v == "1"
 		}
+		if v, ok := f["obsess"]; ok && modAttrs&objects.ModAttrObsessiveHandlerEnabled != 0 {
+			h.ObsessOver = v == "1"
+		}
+		rr.applyChangedAttributes(f, modAttrs, checkableFields{&h.CheckCommand, &h.CheckCommandArgs,
+			&h.EventHandler, &h.EventHandlerArgs, &h.CheckInterval, &h.RetryInterval,
+			&h.MaxCheckAttempts, &h.CurrentAttempt, &h.CheckPeriod, &h.NotificationPeriod, h.CustomVars})
+		// Keep the bits so the changes survive the next restart too.
+		h.ModifiedAttributes = modAttrs
 	}
 	if v, ok := f["problem_has_been_acknowledged"]; ok {
 		h.ProblemAcknowledged = v == "1"
@@ -520,7 +728,8 @@ func (rr *RetentionReader) applyHost(f map[string]string) {
 	if v, ok := f["scheduled_downtime_depth"]; ok {
 		h.ScheduledDowntimeDepth = parseInt(v)
 	}
-	// notified_on reconstruction
+	// notified_on reconstruction. Nagios 4 writes a single notified_on
+	// bitmask of 1<<state instead of a field per state.
 	var notified uint32
 	if f["notified_on_down"] == "1" {
 		notified |= objects.OptDown
@@ -528,12 +737,22 @@ func (rr *RetentionReader) applyHost(f map[string]string) {
 	if f["notified_on_unreachable"] == "1" {
 		notified |= objects.OptUnreachable
 	}
+	if v, ok := f["notified_on"]; ok {
+		n := parseUint64(v)
+		if n&(1<<objects.HostDown) != 0 {
+			notified |= objects.OptDown
+		}
+		if n&(1<<objects.HostUnreachable) != 0 {
+			notified |= objects.OptUnreachable
+		}
+	}
 	h.NotifiedOn = notified
 	if v, ok := f["check_flapping_recovery_notification"]; ok {
 		h.CheckFlapRecoveryNotif = v == "1"
 	}
 	if v, ok := f["state_history"]; ok {
 		rr.parseStateHistory(v, h.StateHistory[:])
+		h.StateHistoryIndex = 0
 	}
 }
 
@@ -564,10 +783,10 @@ func (rr *RetentionReader) applyService(f map[string]string) {
 		s.HasBeenChecked = v == "1"
 	}
 	if v, ok := f["plugin_output"]; ok {
-		s.PluginOutput = v
+		s.PluginOutput = objects.Intern(v)
 	}
 	if v, ok := f["long_plugin_output"]; ok {
-		s.LongPluginOutput = v
+		s.LongPluginOutput = objects.Intern(v)
 	}
 	if v, ok := f["performance_data"]; ok {
 		s.PerfData = v
@@ -584,6 +803,24 @@ func (rr *RetentionReader) applyService(f map[string]string) {
 	if v, ok := f["last_hard_state_change"]; ok {
 		s.LastHardStateChange = unixToTime(v)
 	}
+	if v, ok := f["last_time_ok"]; ok {
+		s.LastTimeOK = unixToTime(v)
+	}
+	if v, ok := f["last_time_warning"]; ok {
+		s.LastTimeWarning = unixToTime(v)
+	}
+	if v, ok := f["last_time_critical"]; ok {
+		s.LastTimeCritical = unixToTime(v)
+	}
+	if v, ok := f["last_time_unknown"]; ok {
+		s.LastTimeUnknown = unixToTime(v)
+	}
+	if v, ok := f["current_problem_id"]; ok {
+		s.CurrentProblemID = parseUint64(v)
+	}
+	if v, ok := f["last_problem_id"]; ok {
+		s.LastProblemID = parseUint64(v)
+	}
 	if v, ok := f["last_notification"]; ok {
 		s.LastNotification = unixToTime(v)
 	}
@@ -606,6 +843,14 @@ func (rr *RetentionReader) applyService(f map[string]string) {
 		if v, ok := f["passive_checks_enabled"]; ok {
 			s.PassiveChecksEnabled = v == "1"
 		}
+		if v, ok := f["obsess"]; ok && modAttrs&objects.ModAttrObsessiveHandlerEnabled != 0 {
+			s.ObsessOver = v == "1"
+		}
+		rr.applyChangedAttributes(f, modAttrs, checkableFields{&s.CheckCommand, &s.CheckCommandArgs,
+			&s.EventHandler, &s.EventHandlerArgs, &s.CheckInterval, &s.RetryInterval,
+			&s.MaxCheckAttempts, &s.CurrentAttempt, &s.CheckPeriod, &s.NotificationPeriod, s.CustomVars})
+		// Keep the bits so the changes survive the next restart too.
+		s.ModifiedAttributes = modAttrs
 	}
 	if v, ok := f["problem_has_been_acknowledged"]; ok {
 		s.ProblemAcknowledged = v == "1"
@@ -632,15 +877,102 @@ func (rr *RetentionReader) applyService(f map[string]string) {
 	if f["notified_on_critical"] == "1" {
 		notified |= objects.OptCritical
 	}
+	if v, ok := f["notified_on"]; ok {
+		n := parseUint64(v)
+		if n&(1<<objects.ServiceWarning) != 0 {
+			notified |= objects.OptWarning
+		}
+		if n&(1<<objects.ServiceCritical) != 0 {
+			notified |= objects.OptCritical
+		}
+		if n&(1<<objects.ServiceUnknown) != 0 {
+			notified |= objects.OptUnknown
+		}
+	}
 	s.NotifiedOn = notified
 	if v, ok := f["check_flapping_recovery_notification"]; ok {
 		s.CheckFlapRecoveryNotif = v == "1"
 	}
 	if v, ok := f["state_history"]; ok {
 		rr.parseStateHistory(v, s.StateHistory[:])
+		s.StateHistoryIndex = 0
 	}
 }
 
+// checkableFields points at the attributes of a host or service that the
+// CHANGE_* external commands set.
+type checkableFields struct {
+	checkCommand       **objects.Command
+	checkCommandArgs   *string
+	eventHandler       **objects.Command
+	eventHandlerArgs   *string
+	checkInterval      *float64
+	retryInterval      *float64
+	maxCheckAttempts   *int
+	currentAttempt     *int
+	checkPeriod        **objects.Timeperiod
+	notificationPeriod **objects.Timeperiod
+	customVars         map[string]string
+}
+
+// applyChangedAttributes restores the attributes whose bits are set in
+// modAttrs, so the CHANGE_* commands outlast a restart. A command or
+// timeperiod that is no longer defined leaves the configured one in place.
+// Custom variables are restored only if the object still defines them.
+func (rr *RetentionReader) applyChangedAttributes(f map[string]string, modAttrs uint64, c checkableFields) {
+	if v, ok := f["check_command"]; ok && modAttrs&objects.ModAttrCheckCommand != 0 {
+		if cmd, args := rr.command(v); cmd != nil {
+			*c.checkCommand, *c.checkCommandArgs = cmd, args
+		}
+	}
+	if v, ok := f["event_handler"]; ok && modAttrs&objects.ModAttrEventHandlerCommand != 0 {
+		if v == "" {
+			*c.eventHandler, *c.eventHandlerArgs = nil, ""
+		} else if cmd, args := rr.command(v); cmd != nil {
+			*c.eventHandler, *c.eventHandlerArgs = cmd, args
+		}
+	}
+	if v, ok := f["check_interval"]; ok && modAttrs&objects.ModAttrNormalCheckInterval != 0 {
+		*c.checkInterval = parseFloat(v)
+	}
+	if v, ok := f["retry_interval"]; ok && modAttrs&objects.ModAttrRetryCheckInterval != 0 {
+		*c.retryInterval = parseFloat(v)
+	}
+	if v, ok := f["max_attempts"]; ok && modAttrs&objects.ModAttrMaxCheckAttempts != 0 {
+		if n := parseInt(v); n >= 1 {
+			*c.maxCheckAttempts = n
+			*c.currentAttempt = min(*c.currentAttempt, n)
+		}
+	}
+	if v, ok := f["check_period"]; ok && modAttrs&objects.ModAttrCheckTimeperiod != 0 {
+		if tp := rr.Store.GetTimeperiod(v); tp != nil {
+			*c.checkPeriod = tp
+		}
+	}
+	if v, ok := f["notification_period"]; ok && modAttrs&objects.ModAttrNotificationTimeperiod != 0 {
+		if tp := rr.Store.GetTimeperiod(v); tp != nil {
+			*c.notificationPeriod = tp
+		}
+	}
+	if modAttrs&objects.ModAttrCustomVariable != 0 {
+		// Custom variables are written as "_NAME=modified;value".
+		for k, v := range f {
+			name, ok := strings.CutPrefix(k, "_")
+			if _, defined := c.customVars[name]; !ok || !defined {
+				continue
+			}
+			_, value, _ := strings.Cut(v, ";")
+			c.customVars[name] = value
+		}
+	}
+}
+
+// command resolves a "name!args" command line as written by cmdName.
+func (rr *RetentionReader) command(line string) (*objects.Command, string) {
+	name, args, _ := strings.Cut(line, "!")
+	return rr.Store.GetCommand(name), args
+}
+
 func (rr *RetentionReader) applyContact(f map[string]string) {
 	name := f["contact_name"]
 	c := rr.Store.GetContact(name)
@@ -691,23 +1023,66 @@ func (rr *RetentionReader) applyDowntimeBlock(f map[string]string, blockType str
 		dtype = objects.ServiceDowntimeType
 	}
 	d := &downtime.Downtime{
-		Type:               dtype,
-		HostName:           f["host_name"],
-		ServiceDescription: f["service_description"],
-		DowntimeID:         parseUint64(f["downtime_id"]),
-		EntryTime:          unixToTime(f["entry_time"]),
-		StartTime:          unixToTime(f["start_time"]),
-		EndTime:            unixToTime(f["end_time"]),
-		TriggeredBy:        parseUint64(f["triggered_by"]),
-		Fixed:              f["fixed"] == "1",
-		Duration:           time.Duration(parseInt(f["duration"])) * time.Second,
-		IsInEffect:         f["is_in_effect"] == "1",
-		Author:             f["author"],
-		Comment:            f["comment"],
+		Type:                  dtype,
+		HostName:              f["host_name"],
+		ServiceDescription:    f["service_description"],
+		DowntimeID:            parseUint64(f["downtime_id"]),
+		EntryTime:             unixToTime(f["entry_time"]),
+		StartTime:             unixToTime(f["start_time"]),
+		FlexDowntimeStart:     unixToTime(f["flex_downtime_start"]),
+		EndTime:               unixToTime(f["end_time"]),
+		TriggeredBy:           parseUint64(f["triggered_by"]),
+		Fixed:                 f["fixed"] == "1",
+		Duration:              time.Duration(parseInt(f["duration"])) * time.Second,
+		IsInEffect:            f["is_in_effect"] == "1",
+		StartNotificationSent: f["start_notification_sent"] == "1",
+		Author:                f["author"],
+		Comment:               f["comment"],
+		CommentID:             parseUint64(f["comment_id"]),
 	}
 	rr.Downtimes.ScheduleWithID(d)
 }
 
+func (rr *RetentionReader) applyBlackout(f map[string]string) {
+	if rr.Blackouts == nil || f["blackout_name"] == "" {
+		return
+	}
+	bo := &objects.Blackout{
+		Name:                f["blackout_name"],
+		StartTime:           unixToTime(f["start_time"]),
+		EndTime:             unixToTime(f["end_time"]),
+		HostNames:           splitList(f["host_name"]),
+		HostGroups:          splitList(f["hostgroup_name"]),
+		ServiceDescriptions: splitList(f["service_description"]),
+		ServiceGroups:       splitList(f["servicegroup_name"]),
+		Author:              f["author"],
+		Comment:             f["comment"],
+		Runtime:             true,
+	}
+	if !bo.EndTime.IsZero() && !bo.EndTime.After(time.Now()) {
+		return
+	}
+	rr.Blackouts.Add(bo)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// oldestFirst returns the flap history ring starting at its oldest entry,
+// idx, as Nagios writes state_history. Read back with the index at 0, the
+// next state then replaces the same entry it would have before a restart.
+func oldestFirst(hist *[objects.MaxStateHistoryEntries]int, idx int) []int {
+	out := make([]int, 0, len(hist))
+	for x := range len(hist) {
+		out = append(out, hist[(idx+x)%len(hist)])
+	}
+	return out
+}
+
 func (rr *RetentionReader) parseStateHistory(s string, hist []int) {
 	parts := strings.Split(s, ",")
 	for i := 0; i < len(parts) && i < len(hist); i++ {
@@ -0,0 +1,186 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// retentionFields lists, per block type, the fields RetentionReader
+// restores. ConvertRetention keeps these and reports everything else.
+var retentionFields = map[string][]string{
+	"program": {
+		"enable_notifications", "active_service_checks_enabled", "passive_service_checks_enabled",
+		"active_host_checks_enabled", "passive_host_checks_enabled", "enable_event_handlers",
+		"enable_flap_detection", "process_performance_data", "next_comment_id", "next_downtime_id",
+		"next_event_id", "next_problem_id", "next_notification_id",
+	},
+	"host": append([]string{
+		"host_name", "last_time_up", "last_time_down", "last_time_unreachable",
+		"notified_on_down", "notified_on_unreachable",
+	}, checkableRetentionFields...),
+	"service": append([]string{
+		"host_name", "service_description", "last_time_ok", "last_time_warning",
+		"last_time_critical", "last_time_unknown", "notified_on_unknown",
+		"notified_on_warning", "notified_on_critical",
+	}, checkableRetentionFields...),
+	"contact": {
+		"contact_name", "modified_attributes", "host_notifications_enabled",
+		"service_notifications_enabled", "last_host_notification", "last_service_notification",
+	},
+	"hostcomment":     commentRetentionFields,
+	"servicecomment":  append([]string{"service_description"}, commentRetentionFields...),
+	"hostdowntime":    downtimeRetentionFields,
+	"servicedowntime": append([]string{"service_description"}, downtimeRetentionFields...),
+}
+
+var checkableRetentionFields = []string{
+	"modified_attributes", "current_state", "last_state", "last_hard_state", "state_type",
+	"current_attempt", "has_been_checked", "plugin_output", "long_plugin_output",
+	"performance_data", "last_check", "next_check", "last_state_change",
+	"last_hard_state_change", "current_problem_id", "last_problem_id", "last_notification",
+	"next_notification", "current_notification_number", "current_notification_id",
+	"notifications_enabled", "active_checks_enabled", "passive_checks_enabled",
+	"problem_has_been_acknowledged", "acknowledgement_type", "is_flapping",
+	"percent_state_change", "scheduled_downtime_depth", "notified_on",
+	"check_flapping_recovery_notification", "state_history", "check_command",
+	"check_period", "notification_period", "event_handler", "check_interval",
+	"retry_interval", "max_attempts",
+}
+
+var commentRetentionFields = []string{
+	"host_name", "entry_type", "comment_id", "source", "persistent", "entry_time",
+	"expires", "expire_time", "author", "comment_data",
+}
+
+var downtimeRetentionFields = []string{
+	"host_name", "downtime_id", "comment_id", "entry_time", "start_time",
+	"flex_downtime_start", "end_time", "triggered_by", "fixed", "duration",
+	"is_in_effect", "start_notification_sent", "author", "comment",
+}
+
+// retentionKeyFields are written first in each converted block.
+var retentionKeyFields = []string{"host_name", "service_description", "contact_name"}
+
+// ConvertReport describes what ConvertRetention kept and dropped.
+type ConvertReport struct {
+	SourceVersion string         // version from the info block
+	Blocks        map[string]int // converted blocks by type
+	Dropped       []DroppedField // sorted by block type, then field
+}
+
+// DroppedField is a field gogios does not restore. Field is empty when the
+// whole block type is unknown.
+type DroppedField struct {
+	Block string
+	Field string
+	Count int
+}
+
+// String renders the report as text.
+func (r *ConvertReport) String() string {
+	var b strings.Builder
+	types := make([]string, 0, len(r.Blocks))
+	for t := range r.Blocks {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	fmt.Fprintf(&b, "Converted retention data from version %s:\n", r.SourceVersion)
+	for _, t := range types {
+		fmt.Fprintf(&b, "  %s: %d\n", t, r.Blocks[t])
+	}
+	fmt.Fprintf(&b, "Unconvertible fields: %d\n", len(r.Dropped))
+	for _, d := range r.Dropped {
+		if d.Field == "" {
+			fmt.Fprintf(&b, "  %s (unknown block type): %d blocks\n", d.Block, d.Count)
+		} else {
+			fmt.Fprintf(&b, "  %s.%s: %d\n", d.Block, d.Field, d.Count)
+		}
+	}
+	return b.String()
+}
+
+// ConvertRetention reads a Nagios retention.dat from r and writes a gogios
+// retention.dat to w holding only the fields gogios restores. Dropped fields
+// are counted in the report rather than treated as errors: the reader
+// already ignores them, so the report is what a migration loses.
+func ConvertRetention(r io.Reader, w io.Writer) (*ConvertReport, error) {
+	known := make(map[string]map[string]bool, len(retentionFields))
+	for t, fields := range retentionFields {
+		known[t] = make(map[string]bool, len(fields))
+		for _, f := range fields {
+			known[t][f] = true
+		}
+	}
+
+	report := &ConvertReport{SourceVersion: "unknown", Blocks: make(map[string]int)}
+	dropped := make(map[DroppedField]int)
+	var b strings.Builder
+	err := parseBlocks(r, func(blockType string, fields map[string]string) {
+		if blockType == "info" {
+			if v := fields["version"]; v != "" {
+				report.SourceVersion = v
+			}
+			return
+		}
+		kf, ok := known[blockType]
+		if !ok {
+			dropped[DroppedField{Block: blockType}]++
+			return
+		}
+		report.Blocks[blockType]++
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			// Hosts and services also restore their custom variables.
+			custom := strings.HasPrefix(name, "_") && (blockType == "host" || blockType == "service")
+			if !kf[name] && !custom {
+				dropped[DroppedField{Block: blockType, Field: name}]++
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			ki, kj := keyFieldRank(names[i]), keyFieldRank(names[j])
+			if ki != kj {
+				return ki < kj
+			}
+			return names[i] < names[j]
+		})
+		fmt.Fprintf(&b, "%s {\n", blockType)
+		for _, name := range names {
+			fmt.Fprintf(&b, "%s=%s\n", name, fields[name])
+		}
+		b.WriteString("}\n\n")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for d, n := range dropped {
+		d.Count = n
+		report.Dropped = append(report.Dropped, d)
+	}
+	sort.Slice(report.Dropped, func(i, j int) bool {
+		a, c := report.Dropped[i], report.Dropped[j]
+		return a.Block < c.Block || (a.Block == c.Block && a.Field < c.Field)
+	})
+
+	if _, err := fmt.Fprintf(w, "info {\ncreated=%d\nversion=converted from %s\n}\n\n", time.Now().Unix(), report.SourceVersion); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func keyFieldRank(name string) int {
+	for i, k := range retentionKeyFields {
+		if name == k {
+			return i
+		}
+	}
+	return len(retentionKeyFields)
+}
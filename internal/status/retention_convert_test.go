@@ -0,0 +1,348 @@
+package status
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// nagios44Retention is an abridged retention.dat as written by Nagios 4.4.6:
+// a notified_on bitmask instead of notified_on_<state>, and fields gogios
+// does not restore (check_options, event IDs, update checks).
+const nagios44Retention = `########################################
+#          NAGIOS STATE RETENTION FILE
+#
+# THIS FILE IS AUTOMATICALLY GENERATED
+# BY NAGIOS.  DO NOT MODIFY THIS FILE!
+########################################
+info {
+created=1718452800
+version=4.4.6
+last_update_check=1718400000
+update_available=0
+update_uid=0
+last_version=4.4.6
+new_version=4.4.14
+}
+program {
+modified_host_attributes=0
+modified_service_attributes=0
+enable_notifications=1
+active_service_checks_enabled=1
+passive_service_checks_enabled=1
+active_host_checks_enabled=1
+passive_host_checks_enabled=1
+enable_event_handlers=1
+obsess_over_services=0
+obsess_over_hosts=0
+check_service_freshness=1
+check_host_freshness=0
+enable_flap_detection=1
+process_performance_data=0
+global_host_event_handler=
+global_service_event_handler=
+next_comment_id=12
+next_downtime_id=8
+next_event_id=301
+next_problem_id=77
+next_notification_id=45
+}
+host {
+host_name=web01
+modified_attributes=0
+check_command=check-host-alive
+check_period=24x7
+notification_period=24x7
+event_handler=
+has_been_checked=1
+check_execution_time=0.012
+check_latency=0.003
+check_type=0
+current_state=1
+last_state=1
+last_hard_state=1
+last_event_id=290
+current_event_id=291
+current_problem_id=70
+last_problem_id=61
+plugin_output=CRITICAL - Host Unreachable (10.0.0.1)
+long_plugin_output=
+performance_data=rta=0.000ms;3000.000;5000.000;0; pl=100%;80;100;;
+last_check=1718452700
+next_check=1718453000
+check_options=0
+current_attempt=3
+max_attempts=3
+normal_check_interval=5.000000
+retry_check_interval=1.000000
+check_timeperiod=24x7
+state_type=1
+last_state_change=1718450000
+last_hard_state_change=1718450120
+last_time_up=1718449990
+last_time_down=1718452700
+last_time_unreachable=0
+notified_on=2
+last_notification=1718450125
+current_notification_number=2
+current_notification_id=40
+notifications_enabled=1
+problem_has_been_acknowledged=1
+acknowledgement_type=2
+active_checks_enabled=1
+passive_checks_enabled=1
+event_handler_enabled=1
+flap_detection_enabled=1
+process_performance_data=1
+obsess=1
+is_flapping=0
+percent_state_change=0.00
+check_flapping_recovery_notification=0
+state_history=0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,1,1
+_SNMP_COMMUNITY=0;public
+}
+host {
+host_name=decommissioned01
+current_state=0
+}
+service {
+host_name=web01
+service_description=HTTP
+modified_attributes=1
+check_command=check_http
+check_period=24x7
+notification_period=24x7
+event_handler=
+has_been_checked=1
+check_execution_time=0.100
+check_latency=0.010
+check_type=0
+current_state=2
+last_state=2
+last_hard_state=2
+last_event_id=295
+current_event_id=296
+current_problem_id=72
+last_problem_id=0
+current_attempt=4
+max_attempts=4
+normal_check_interval=5.000000
+retry_check_interval=1.000000
+check_timeperiod=24x7
+state_type=1
+last_state_change=1718450200
+last_hard_state_change=1718450380
+last_time_ok=1718450100
+last_time_warning=0
+last_time_unknown=0
+last_time_critical=1718452700
+plugin_output=connect to address 10.0.0.1 and port 80: No route to host
+long_plugin_output=
+performance_data=
+last_check=1718452700
+next_check=1718453000
+check_options=0
+notified_on=4
+last_notification=1718450385
+current_notification_number=1
+current_notification_id=41
+notifications_enabled=0
+active_checks_enabled=1
+passive_checks_enabled=1
+event_handler_enabled=1
+problem_has_been_acknowledged=0
+acknowledgement_type=0
+flap_detection_enabled=1
+process_performance_data=1
+obsess=1
+is_flapping=0
+percent_state_change=0.00
+check_flapping_recovery_notification=0
+state_history=0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,2,2
+}
+contact {
+contact_name=oncall
+modified_attributes=0
+modified_host_attributes=0
+modified_service_attributes=0
+host_notification_period=24x7
+service_notification_period=24x7
+last_host_notification=1718450125
+last_service_notification=1718450385
+host_notifications_enabled=1
+service_notifications_enabled=1
+}
+hostcomment {
+host_name=web01
+entry_type=4
+comment_id=10
+source=1
+persistent=1
+entry_time=1718450500
+expires=0
+expire_time=0
+author=alice
+comment_data=Switch replacement in progress
+}
+hostcomment {
+host_name=decommissioned01
+entry_type=1
+comment_id=9
+source=1
+persistent=1
+entry_time=1718000000
+expires=0
+expire_time=0
+author=bob
+comment_data=Old comment
+}
+servicedowntime {
+host_name=web01
+service_description=HTTP
+downtime_id=7
+comment_id=11
+entry_time=1718450600
+start_time=1718450600
+flex_downtime_start=0
+end_time=1718464600
+triggered_by=0
+fixed=1
+duration=14000
+is_in_effect=1
+start_notification_sent=1
+author=alice
+comment=Maintenance window
+}
+`
+
+func nagiosFixtureStore() (*objects.ObjectStore, *objects.Host, *objects.Service, *RetentionReader) {
+	store := objects.NewObjectStore()
+	h := &objects.Host{Name: "web01"}
+	store.AddHost(h)
+	svc := &objects.Service{Host: h, Description: "HTTP", NotificationsEnabled: true}
+	store.AddService(svc)
+	h.Services = append(h.Services, svc)
+	store.AddContact(&objects.Contact{Name: "oncall"})
+	cm := downtime.NewCommentManager(1)
+	rr := &RetentionReader{
+		Store:     store,
+		Global:    &objects.GlobalState{},
+		Comments:  cm,
+		Downtimes: downtime.NewDowntimeManager(1, cm, store),
+	}
+	return store, h, svc, rr
+}
+
+func checkNagiosFixture(t *testing.T, h *objects.Host, svc *objects.Service, rr *RetentionReader) {
+	t.Helper()
+	if h.CurrentState != objects.HostDown || !h.ProblemAcknowledged || h.AckType != objects.AckSticky {
+		t.Errorf("host state/ack not restored: state=%d ack=%v type=%d", h.CurrentState, h.ProblemAcknowledged, h.AckType)
+	}
+	if h.NotifiedOn != objects.OptDown {
+		t.Errorf("host notified_on bitmask not restored: %b", h.NotifiedOn)
+	}
+	if h.CurrentProblemID != 70 || !h.LastTimeUp.Equal(time.Unix(1718449990, 0)) {
+		t.Errorf("host problem ID / last_time_up not restored: %d %v", h.CurrentProblemID, h.LastTimeUp)
+	}
+	if svc.NotifiedOn != objects.OptCritical || svc.NotificationsEnabled {
+		t.Errorf("service notified_on / modified notifications_enabled not restored: %b %v", svc.NotifiedOn, svc.NotificationsEnabled)
+	}
+	if rr.Global.NextProblemID != 77 || rr.Global.NextCommentID != 12 {
+		t.Errorf("program IDs not restored: %+v", rr.Global)
+	}
+
+	comments := rr.Comments.All()
+	if len(comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(comments))
+	}
+	var ack *downtime.Comment
+	for _, c := range comments {
+		if c.CommentID == 10 {
+			ack = c
+		}
+	}
+	if ack == nil || ack.EntryType != objects.AcknowledgementCommentEntry || ack.Author != "alice" {
+		t.Errorf("acknowledgement comment not restored: %+v", ack)
+	}
+
+	dts := rr.Downtimes.All()
+	if len(dts) != 1 {
+		t.Fatalf("expected 1 downtime, got %d", len(dts))
+	}
+	d := dts[0]
+	if d.DowntimeID != 7 || d.CommentID != 11 || !d.IsInEffect || !d.StartNotificationSent || d.ServiceDescription != "HTTP" {
+		t.Errorf("downtime not restored: %+v", d)
+	}
+}
+
+func TestRetentionReader_Nagios44(t *testing.T) {
+	_, h, svc, rr := nagiosFixtureStore()
+	if err := parseBlocks(strings.NewReader(nagios44Retention), rr.applyBlock); err != nil {
+		t.Fatal(err)
+	}
+	checkNagiosFixture(t, h, svc, rr)
+}
+
+func TestConvertRetention(t *testing.T) {
+	var out bytes.Buffer
+	report, err := ConvertRetention(strings.NewReader(nagios44Retention), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SourceVersion != "4.4.6" {
+		t.Errorf("unexpected source version %q", report.SourceVersion)
+	}
+	if report.Blocks["host"] != 2 || report.Blocks["service"] != 1 || report.Blocks["servicedowntime"] != 1 {
+		t.Errorf("unexpected block counts %v", report.Blocks)
+	}
+	dropped := make(map[string]int)
+	for _, d := range report.Dropped {
+		dropped[d.Block+"."+d.Field] = d.Count
+	}
+	for _, want := range []string{"host.check_options", "service.last_event_id", "program.obsess_over_hosts"} {
+		if dropped[want] == 0 {
+			t.Errorf("expected %s to be reported, got %v", want, dropped)
+		}
+	}
+	for _, keep := range []string{"host.notified_on", "host._SNMP_COMMUNITY", "host.check_command"} {
+		if _, ok := dropped[keep]; ok {
+			t.Errorf("%s should be converted, not dropped", keep)
+		}
+	}
+	if !strings.Contains(report.String(), "  host.check_options: 1\n") {
+		t.Errorf("unexpected report text:\n%s", report)
+	}
+
+	// The converted file restores the same state.
+	_, h, svc, rr := nagiosFixtureStore()
+	if err := parseBlocks(&out, rr.applyBlock); err != nil {
+		t.Fatal(err)
+	}
+	checkNagiosFixture(t, h, svc, rr)
+}
+
+func TestConvertRetention_KeepsGogiosDowntimes(t *testing.T) {
+	// Comments and downtimes written by gogios must convert without loss.
+	// Host and service blocks also carry configuration fields (check_command,
+	// check_interval) that gogios restores when an admin changed them.
+	store, _, _, rr := nagiosFixtureStore()
+	rr.Downtimes.Schedule(&downtime.Downtime{Type: objects.HostDowntimeType, HostName: "web01",
+		StartTime: time.Now(), EndTime: time.Now().Add(time.Hour), Fixed: true})
+	rw := &RetentionWriter{Store: store, Global: rr.Global, Comments: rr.Comments, Downtimes: rr.Downtimes, Version: "test"}
+
+	var b blockBuf
+	rw.render(&b, true)
+	report, err := ConvertRetention(bytes.NewReader(b.b), &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range report.Dropped {
+		if d.Field == "" || d.Block == "hostdowntime" || d.Block == "hostcomment" {
+			t.Errorf("gogios retention field dropped: %+v", d)
+		}
+	}
+}
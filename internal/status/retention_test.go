@@ -0,0 +1,132 @@
+package status
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oceanplexian/gogios/internal/checker"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func TestRetention_FlapHistoryRoundTrip(t *testing.T) {
+	// 28 checks wrap the 21-entry ring, leaving the index at 7 with the
+	// flapping part of the history in the most recent entries.
+	store, h, svc, rr := nagiosFixtureStore()
+	for i := range 28 {
+		state := 0
+		if i >= 20 && i%2 == 0 {
+			state = 2
+		}
+		checker.UpdateFlapHistory(&svc.StateHistory, &svc.StateHistoryIndex, &svc.PercentStateChange, state)
+		checker.UpdateFlapHistory(&h.StateHistory, &h.StateHistoryIndex, &h.PercentStateChange, state/2)
+	}
+	if svc.StateHistoryIndex != 7 {
+		t.Fatalf("setup: index %d", svc.StateHistoryIndex)
+	}
+	rw := &RetentionWriter{Store: store, Global: rr.Global, Comments: rr.Comments, Downtimes: rr.Downtimes, Version: "test"}
+	var b blockBuf
+	rw.render(&b, true)
+
+	_, h2, svc2, rr2 := nagiosFixtureStore()
+	if err := parseBlocks(bytes.NewReader(b.b), rr2.applyBlock); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name          string
+		hist, hist2   *[objects.MaxStateHistoryEntries]int
+		idx, idx2     *int
+		pct, pct2     *float64
+		state, state2 int
+	}{
+		{"service", &svc.StateHistory, &svc2.StateHistory, &svc.StateHistoryIndex, &svc2.StateHistoryIndex,
+			&svc.PercentStateChange, &svc2.PercentStateChange, 2, 0},
+		{"host", &h.StateHistory, &h2.StateHistory, &h.StateHistoryIndex, &h2.StateHistoryIndex,
+			&h.PercentStateChange, &h2.PercentStateChange, 1, 0},
+	} {
+		if got, want := checker.CalculateFlapPercent(c.hist2, *c.idx2), checker.CalculateFlapPercent(c.hist, *c.idx); got != want {
+			t.Errorf("%s: percent state change after restart %.2f, want %.2f", c.name, got, want)
+		}
+		// The next checks replace the oldest entries, as they would have
+		// without the restart.
+		for _, state := range []int{c.state, c.state2} {
+			checker.UpdateFlapHistory(c.hist, c.idx, c.pct, state)
+			checker.UpdateFlapHistory(c.hist2, c.idx2, c.pct2, state)
+			if *c.pct2 != *c.pct {
+				t.Errorf("%s: percent state change %.2f after restart, want %.2f", c.name, *c.pct2, *c.pct)
+			}
+		}
+	}
+}
+
+func TestRetention_ChangedAttributesRoundTrip(t *testing.T) {
+	// Each restarted store starts from the configuration: check_http every
+	// 5 minutes with 3 attempts, in 24x7, with _PORT=80.
+	fixture := func() (*objects.ObjectStore, *objects.Service, *RetentionReader) {
+		store, _, svc, rr := nagiosFixtureStore()
+		for _, name := range []string{"check_http", "check_https", "restart_httpd"} {
+			store.AddCommand(&objects.Command{Name: name})
+		}
+		for _, name := range []string{"24x7", "workhours"} {
+			store.AddTimeperiod(&objects.Timeperiod{Name: name})
+		}
+		svc.CheckCommand = store.GetCommand("check_http")
+		svc.CheckPeriod = store.GetTimeperiod("24x7")
+		svc.NotificationPeriod = svc.CheckPeriod
+		svc.CheckInterval, svc.RetryInterval, svc.MaxCheckAttempts = 5, 1, 3
+		svc.CustomVars = map[string]string{"PORT": "80", "OWNER": "web"}
+		return store, svc, rr
+	}
+	store, svc, rr := fixture()
+	svc.CheckCommand, svc.CheckCommandArgs = store.GetCommand("check_https"), "-p 8443"
+	svc.EventHandler = store.GetCommand("restart_httpd")
+	svc.CheckInterval, svc.RetryInterval, svc.MaxCheckAttempts, svc.CurrentAttempt = 2, 0.5, 5, 4
+	svc.CheckPeriod = store.GetTimeperiod("workhours")
+	svc.CustomVars["PORT"] = "8443"
+	svc.CustomVars["OWNER"] = "ops"
+	svc.NotificationPeriod = store.GetTimeperiod("workhours") // not marked as changed
+	svc.ModifiedAttributes = objects.ModAttrCheckCommand | objects.ModAttrEventHandlerCommand |
+		objects.ModAttrNormalCheckInterval | objects.ModAttrRetryCheckInterval |
+		objects.ModAttrMaxCheckAttempts | objects.ModAttrCheckTimeperiod | objects.ModAttrCustomVariable
+	rw := &RetentionWriter{Store: store, Global: rr.Global, Comments: rr.Comments, Downtimes: rr.Downtimes, Version: "test"}
+	var b blockBuf
+	rw.render(&b, true)
+
+	_, svc2, rr2 := fixture()
+	delete(svc2.CustomVars, "OWNER") // removed from the configuration since
+	if err := parseBlocks(bytes.NewReader(b.b), rr2.applyBlock); err != nil {
+		t.Fatal(err)
+	}
+	if cmdName(svc2.CheckCommand, svc2.CheckCommandArgs) != "check_https!-p 8443" ||
+		cmdName(svc2.EventHandler, svc2.EventHandlerArgs) != "restart_httpd" {
+		t.Errorf("commands not restored: %q %q", cmdName(svc2.CheckCommand, svc2.CheckCommandArgs),
+			cmdName(svc2.EventHandler, svc2.EventHandlerArgs))
+	}
+	if svc2.CheckInterval != 2 || svc2.RetryInterval != 0.5 || svc2.MaxCheckAttempts != 5 || svc2.CurrentAttempt != 4 {
+		t.Errorf("check attributes not restored: %v %v %d %d", svc2.CheckInterval, svc2.RetryInterval,
+			svc2.MaxCheckAttempts, svc2.CurrentAttempt)
+	}
+	if svc2.CheckPeriod.Name != "workhours" || svc2.NotificationPeriod.Name != "24x7" {
+		t.Errorf("timeperiods: check %s, notification %s; want workhours, 24x7", svc2.CheckPeriod.Name,
+			svc2.NotificationPeriod.Name)
+	}
+	if len(svc2.CustomVars) != 1 || svc2.CustomVars["PORT"] != "8443" {
+		t.Errorf("custom variables %v, want only PORT=8443", svc2.CustomVars)
+	}
+	if svc2.ModifiedAttributes != svc.ModifiedAttributes {
+		t.Errorf("modified_attributes %d, want %d", svc2.ModifiedAttributes, svc.ModifiedAttributes)
+	}
+
+	// Without the bits, the configuration wins.
+	svc.ModifiedAttributes = objects.ModAttrNotificationsEnabled
+	b.reset()
+	rw.render(&b, true)
+	_, svc3, rr3 := fixture()
+	if err := parseBlocks(bytes.NewReader(b.b), rr3.applyBlock); err != nil {
+		t.Fatal(err)
+	}
+	if svc3.CheckCommand.Name != "check_http" || svc3.CheckInterval != 5 || svc3.MaxCheckAttempts != 3 ||
+		svc3.CheckPeriod.Name != "24x7" || svc3.CustomVars["PORT"] != "80" {
+		t.Errorf("unchanged attributes overridden: %s %v %d %s %v", svc3.CheckCommand.Name, svc3.CheckInterval,
+			svc3.MaxCheckAttempts, svc3.CheckPeriod.Name, svc3.CustomVars)
+	}
+}
@@ -0,0 +1,237 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+// SnapshotFormatVersion is the version of the JSON snapshot document.
+// ImportSnapshot refuses documents from a newer version.
+const SnapshotFormatVersion = 1
+
+// Snapshot is a complete dump of gogios state as one JSON document: the
+// object definitions for reference, and the runtime state of every object,
+// comment and downtime. Runtime state is carried as the same key/value
+// fields retention.dat uses, so anything retention restores, an import
+// restores, and a converter from a Nagios retention.dat only has to split
+// blocks into maps.
+type Snapshot struct {
+	FormatVersion  int                 `json:"format_version"`
+	Created        time.Time           `json:"created"`
+	ProgramVersion string              `json:"program_version"`
+	Objects        SnapshotObjects     `json:"objects"`
+	Program        map[string]string   `json:"program"`
+	Hosts          []map[string]string `json:"hosts"`
+	Services       []map[string]string `json:"services"`
+	Contacts       []map[string]string `json:"contacts"`
+	Comments       []map[string]string `json:"comments"`
+	Downtimes      []map[string]string `json:"downtimes"`
+}
+
+// SnapshotObjects describes the configured objects. It is informational:
+// an import applies state to the objects in the running configuration and
+// never creates objects.
+type SnapshotObjects struct {
+	Hosts    []SnapshotHost    `json:"hosts"`
+	Services []SnapshotService `json:"services"`
+	Contacts []SnapshotContact `json:"contacts"`
+}
+
+// SnapshotHost is a host definition.
+type SnapshotHost struct {
+	Name         string            `json:"host_name"`
+	Alias        string            `json:"alias,omitempty"`
+	Address      string            `json:"address,omitempty"`
+	Parents      []string          `json:"parents,omitempty"`
+	HostGroups   []string          `json:"hostgroups,omitempty"`
+	CheckCommand string            `json:"check_command,omitempty"`
+	CustomVars   map[string]string `json:"custom_variables,omitempty"`
+}
+
+// SnapshotService is a service definition.
+type SnapshotService struct {
+	HostName      string            `json:"host_name"`
+	Description   string            `json:"service_description"`
+	ServiceGroups []string          `json:"servicegroups,omitempty"`
+	CheckCommand  string            `json:"check_command,omitempty"`
+	CustomVars    map[string]string `json:"custom_variables,omitempty"`
+}
+
+// SnapshotContact is a contact definition.
+type SnapshotContact struct {
+	Name  string `json:"contact_name"`
+	Email string `json:"email,omitempty"`
+	Pager string `json:"pager,omitempty"`
+}
+
+// BuildSnapshot captures the current state. Unlike retention.dat it
+// includes non-persistent comments. The caller must hold at least a read
+// lock on the store.
+func BuildSnapshot(rw *RetentionWriter) *Snapshot {
+	snap := &Snapshot{
+		FormatVersion:  SnapshotFormatVersion,
+		Created:        time.Now(),
+		ProgramVersion: rw.Version,
+		Hosts:          []map[string]string{},
+		Services:       []map[string]string{},
+		Contacts:       []map[string]string{},
+		Comments:       []map[string]string{},
+		Downtimes:      []map[string]string{},
+	}
+	var b blockBuf
+	rw.render(&b, true)
+	parseBlocks(bytes.NewReader(b.b), func(blockType string, f map[string]string) {
+		switch blockType {
+		case "program":
+			snap.Program = f
+		case "host":
+			snap.Hosts = append(snap.Hosts, f)
+		case "service":
+			snap.Services = append(snap.Services, f)
+		case "contact":
+			snap.Contacts = append(snap.Contacts, f)
+		case "hostcomment", "servicecomment":
+			snap.Comments = append(snap.Comments, f)
+		case "hostdowntime", "servicedowntime":
+			snap.Downtimes = append(snap.Downtimes, f)
+		}
+	})
+	snap.Objects = snapshotObjects(rw.Store)
+	return snap
+}
+
+func snapshotObjects(store *objects.ObjectStore) SnapshotObjects {
+	var o SnapshotObjects
+	for _, h := range store.Hosts {
+		sh := SnapshotHost{
+			Name:         h.Name,
+			Alias:        h.Alias,
+			Address:      h.Address,
+			CheckCommand: cmdName(h.CheckCommand, h.CheckCommandArgs),
+			CustomVars:   h.CustomVars,
+		}
+		for _, p := range h.Parents {
+			sh.Parents = append(sh.Parents, p.Name)
+		}
+		for _, g := range h.HostGroups {
+			sh.HostGroups = append(sh.HostGroups, g.Name)
+		}
+		o.Hosts = append(o.Hosts, sh)
+	}
+	for _, s := range store.Services {
+		ss := SnapshotService{
+			HostName:     s.Host.Name,
+			Description:  s.Description,
+			CheckCommand: cmdName(s.CheckCommand, s.CheckCommandArgs),
+			CustomVars:   s.CustomVars,
+		}
+		for _, g := range s.ServiceGroups {
+			ss.ServiceGroups = append(ss.ServiceGroups, g.Name)
+		}
+		o.Services = append(o.Services, ss)
+	}
+	for _, c := range store.Contacts {
+		o.Contacts = append(o.Contacts, SnapshotContact{Name: c.Name, Email: c.Email, Pager: c.Pager})
+	}
+	sort.Slice(o.Hosts, func(i, j int) bool { return o.Hosts[i].Name < o.Hosts[j].Name })
+	sort.Slice(o.Services, func(i, j int) bool {
+		a, b := o.Services[i], o.Services[j]
+		return a.HostName < b.HostName || (a.HostName == b.HostName && a.Description < b.Description)
+	})
+	sort.Slice(o.Contacts, func(i, j int) bool { return o.Contacts[i].Name < o.Contacts[j].Name })
+	return o
+}
+
+// WriteSnapshot encodes snap as indented JSON.
+func WriteSnapshot(w io.Writer, snap *Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
+
+// ImportStats reports what ImportSnapshot applied.
+type ImportStats struct {
+	Hosts, Services, Contacts int // objects whose state was restored
+	Comments, Downtimes       int
+	Skipped                   int // hosts, services and contacts not in the configuration
+}
+
+// ImportSnapshot reads a snapshot document and applies it through rr, the
+// same way retention.dat is applied at startup. Objects missing from the
+// running configuration are skipped, as are their comments and downtimes.
+// It is meant to run at startup instead of reading retention.dat, before
+// any comments or downtimes exist.
+func ImportSnapshot(r io.Reader, rr *RetentionReader) (ImportStats, error) {
+	var snap Snapshot
+	var st ImportStats
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return st, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.FormatVersion < 1 || snap.FormatVersion > SnapshotFormatVersion {
+		return st, fmt.Errorf("unsupported snapshot format_version %d (this build reads up to %d)",
+			snap.FormatVersion, SnapshotFormatVersion)
+	}
+
+	if snap.Program != nil {
+		rr.applyBlock("program", snap.Program)
+	}
+	apply := func(blockType string, blocks []map[string]string, known func(f map[string]string) bool, n *int) {
+		for _, f := range blocks {
+			if !known(f) {
+				st.Skipped++
+				continue
+			}
+			rr.applyBlock(blockType, f)
+			*n++
+		}
+	}
+	hostKnown := func(f map[string]string) bool { return rr.Store.GetHost(f["host_name"]) != nil }
+	svcKnown := func(f map[string]string) bool {
+		return rr.Store.GetService(f["host_name"], f["service_description"]) != nil
+	}
+	apply("host", snap.Hosts, hostKnown, &st.Hosts)
+	apply("service", snap.Services, svcKnown, &st.Services)
+	apply("contact", snap.Contacts, func(f map[string]string) bool { return rr.Store.GetContact(f["contact_name"]) != nil }, &st.Contacts)
+
+	for _, f := range snap.Comments {
+		blockType, known := "hostcomment", hostKnown(f)
+		if _, ok := f["service_description"]; ok {
+			blockType, known = "servicecomment", svcKnown(f)
+		}
+		if known {
+			rr.applyBlock(blockType, f)
+			st.Comments++
+		}
+	}
+	for _, f := range snap.Downtimes {
+		blockType, known := "hostdowntime", hostKnown(f)
+		if _, ok := f["service_description"]; ok {
+			blockType, known = "servicedowntime", svcKnown(f)
+		}
+		if known {
+			rr.applyBlock(blockType, f)
+			st.Downtimes++
+		}
+	}
+	return st, nil
+}
+
+// SnapshotHandler serves BuildSnapshot as JSON. It takes the store read
+// lock while the snapshot is built.
+func SnapshotHandler(rw *RetentionWriter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw.Store.Mu.RLock()
+		snap := BuildSnapshot(rw)
+		rw.Store.Mu.RUnlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gogios-snapshot-%d.json"`, snap.Created.Unix()))
+		WriteSnapshot(w, snap)
+	})
+}
@@ -0,0 +1,96 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oceanplexian/gogios/internal/downtime"
+	"github.com/oceanplexian/gogios/internal/objects"
+)
+
+func snapshotFixture() (*objects.ObjectStore, *objects.GlobalState, *downtime.CommentManager, *downtime.DowntimeManager) {
+	store := objects.NewObjectStore()
+	h := &objects.Host{Name: "web01", Address: "10.0.0.1", CustomVars: map[string]string{"RACK": "a1"}}
+	store.AddHost(h)
+	svc := &objects.Service{Host: h, Description: "HTTP"}
+	store.AddService(svc)
+	h.Services = append(h.Services, svc)
+	cm := downtime.NewCommentManager(1)
+	dm := downtime.NewDowntimeManager(1, cm, store)
+	return store, &objects.GlobalState{}, cm, dm
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	store, gs, cm, dm := snapshotFixture()
+	gs.EnableNotifications = true
+	svc := store.GetService("web01", "HTTP")
+	svc.CurrentState = objects.ServiceCritical
+	svc.HasBeenChecked = true
+	svc.PluginOutput = "CRITICAL - connection refused"
+	svc.LastCheck = time.Unix(1700000000, 0)
+	cm.Add(&downtime.Comment{CommentType: objects.ServiceCommentType, HostName: "web01",
+		ServiceDescription: "HTTP", Author: "alice", Data: "looking into it", EntryTime: time.Unix(1700000000, 0)})
+	dm.Schedule(&downtime.Downtime{Type: objects.HostDowntimeType, HostName: "web01", Fixed: true,
+		StartTime: time.Unix(1700000000, 0), EndTime: time.Unix(1700003600, 0), Author: "bob"})
+
+	rw := &RetentionWriter{Store: store, Global: gs, Comments: cm, Downtimes: dm, Version: "1.0.0"}
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, BuildSnapshot(rw)); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw["format_version"]) != "1" {
+		t.Errorf("unexpected format_version %s", raw["format_version"])
+	}
+	if !strings.Contains(string(raw["objects"]), `"RACK": "a1"`) {
+		t.Errorf("expected object custom variables in snapshot, got %s", raw["objects"])
+	}
+
+	store2, gs2, cm2, dm2 := snapshotFixture()
+	rr := &RetentionReader{Store: store2, Global: gs2, Comments: cm2, Downtimes: dm2}
+	st, err := ImportSnapshot(&buf, rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The downtime adds a non-persistent comment of its own; retention.dat
+	// would drop both comments, a snapshot keeps them.
+	if st.Hosts != 1 || st.Services != 1 || st.Comments != 2 || st.Downtimes != 1 || st.Skipped != 0 {
+		t.Errorf("unexpected import stats %+v", st)
+	}
+	svc2 := store2.GetService("web01", "HTTP")
+	if svc2.CurrentState != objects.ServiceCritical || svc2.PluginOutput != "CRITICAL - connection refused" {
+		t.Errorf("service state not restored: %d %q", svc2.CurrentState, svc2.PluginOutput)
+	}
+	if !gs2.EnableNotifications {
+		t.Error("program state not restored")
+	}
+	if len(dm2.All()) != 1 || dm2.All()[0].Author != "bob" {
+		t.Errorf("downtime not restored: %+v", dm2.All())
+	}
+}
+
+func TestImportSnapshot_SkipsUnknownAndRejectsNewer(t *testing.T) {
+	store, gs, cm, dm := snapshotFixture()
+	rr := &RetentionReader{Store: store, Global: gs, Comments: cm, Downtimes: dm}
+
+	doc := `{"format_version":1,"hosts":[{"host_name":"gone01","current_state":"1"}],
+		"downtimes":[{"host_name":"gone01","downtime_id":"5","fixed":"1"}]}`
+	st, err := ImportSnapshot(strings.NewReader(doc), rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Skipped != 1 || st.Downtimes != 0 || len(dm.All()) != 0 {
+		t.Errorf("expected unknown host and its downtime skipped, got %+v", st)
+	}
+
+	if _, err := ImportSnapshot(strings.NewReader(`{"format_version":2}`), rr); err == nil {
+		t.Error("expected an error for a newer format_version")
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/oceanplexian/gogios/internal/downtime"
@@ -20,6 +20,9 @@ type StatusWriter struct {
 	Comments  *downtime.CommentManager
 	Downtimes *downtime.DowntimeManager
 	Version   string
+
+	mu  sync.Mutex
+	buf blockBuf // reused between writes
 }
 
 // Write atomically writes the status.dat file.
@@ -39,39 +42,48 @@ func (sw *StatusWriter) Write() error {
 		}
 	}()
 
-	var b strings.Builder
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	b := &sw.buf
+	b.reset()
+	b.indent = true
 	now := time.Now()
 
 	// info block
-	b.WriteString("info {\n")
-	fmt.Fprintf(&b, "\tcreated=%d\n", now.Unix())
-	fmt.Fprintf(&b, "\tversion=%s\n", sw.Version)
-	b.WriteString("\t}\n\n")
+	b.begin("info")
+	b.int64("created", now.Unix())
+	b.str("version", sw.Version)
+	b.end()
 
 	// programstatus block
-	sw.writeProgramStatus(&b)
+	sw.writeProgramStatus(b)
 
 	// hosts
 	for _, h := range sw.Store.Hosts {
-		sw.writeHostStatus(&b, h)
+		sw.writeHostStatus(b, h)
 	}
 
 	// services
 	for _, s := range sw.Store.Services {
-		sw.writeServiceStatus(&b, s)
+		sw.writeServiceStatus(b, s)
+	}
+
+	// contacts
+	for _, c := range sw.Store.Contacts {
+		sw.writeContactStatus(b, c)
 	}
 
 	// comments
 	for _, c := range sw.Comments.All() {
-		sw.writeComment(&b, c)
+		sw.writeComment(b, c)
 	}
 
 	// downtimes
 	for _, d := range sw.Downtimes.All() {
-		sw.writeDowntime(&b, d)
+		sw.writeDowntime(b, d)
 	}
 
-	if _, err := tmp.WriteString(b.String()); err != nil {
+	if _, err := tmp.Write(b.b); err != nil {
 		return fmt.Errorf("write temp file: %w", err)
 	}
 	if err := tmp.Close(); err != nil {
@@ -86,189 +98,203 @@ func (sw *StatusWriter) Write() error {
 	return nil
 }
 
-func (sw *StatusWriter) writeProgramStatus(b *strings.Builder) {
+func (sw *StatusWriter) writeProgramStatus(b *blockBuf) {
 	g := sw.Global
-	b.WriteString("programstatus {\n")
-	fmt.Fprintf(b, "\tnagios_pid=%d\n", g.PID)
-	fmt.Fprintf(b, "\tdaemon_mode=%s\n", boolStr(g.DaemonMode))
-	fmt.Fprintf(b, "\tprogram_start=%d\n", g.ProgramStart.Unix())
-	fmt.Fprintf(b, "\tenable_notifications=%s\n", boolStr(g.EnableNotifications))
-	fmt.Fprintf(b, "\tactive_service_checks_enabled=%s\n", boolStr(g.ExecuteServiceChecks))
-	fmt.Fprintf(b, "\tpassive_service_checks_enabled=%s\n", boolStr(g.AcceptPassiveServiceChecks))
-	fmt.Fprintf(b, "\tactive_host_checks_enabled=%s\n", boolStr(g.ExecuteHostChecks))
-	fmt.Fprintf(b, "\tpassive_host_checks_enabled=%s\n", boolStr(g.AcceptPassiveHostChecks))
-	fmt.Fprintf(b, "\tenable_event_handlers=%s\n", boolStr(g.EnableEventHandlers))
-	fmt.Fprintf(b, "\tobsess_over_services=%s\n", boolStr(g.ObsessOverServices))
-	fmt.Fprintf(b, "\tobsess_over_hosts=%s\n", boolStr(g.ObsessOverHosts))
-	fmt.Fprintf(b, "\tcheck_service_freshness=%s\n", boolStr(g.CheckServiceFreshness))
-	fmt.Fprintf(b, "\tcheck_host_freshness=%s\n", boolStr(g.CheckHostFreshness))
-	fmt.Fprintf(b, "\tenable_flap_detection=%s\n", boolStr(g.EnableFlapDetection))
-	fmt.Fprintf(b, "\tprocess_performance_data=%s\n", boolStr(g.ProcessPerformanceData))
-	fmt.Fprintf(b, "\tglobal_host_event_handler=%s\n", g.GlobalHostEventHandler)
-	fmt.Fprintf(b, "\tglobal_service_event_handler=%s\n", g.GlobalServiceEventHandler)
-	fmt.Fprintf(b, "\tnext_comment_id=%d\n", g.NextCommentID)
-	fmt.Fprintf(b, "\tnext_downtime_id=%d\n", g.NextDowntimeID)
-	fmt.Fprintf(b, "\tnext_event_id=%d\n", g.NextEventID)
-	fmt.Fprintf(b, "\tnext_problem_id=%d\n", g.NextProblemID)
-	fmt.Fprintf(b, "\tnext_notification_id=%d\n", g.NextNotificationID)
-	b.WriteString("\t}\n\n")
+	b.begin("programstatus")
+	b.int("nagios_pid", g.PID)
+	b.bool("daemon_mode", g.DaemonMode)
+	b.int64("program_start", g.ProgramStart.Unix())
+	b.bool("enable_notifications", g.EnableNotifications)
+	b.bool("active_service_checks_enabled", g.ExecuteServiceChecks)
+	b.bool("passive_service_checks_enabled", g.AcceptPassiveServiceChecks)
+	b.bool("active_host_checks_enabled", g.ExecuteHostChecks)
+	b.bool("passive_host_checks_enabled", g.AcceptPassiveHostChecks)
+	b.bool("enable_event_handlers", g.EnableEventHandlers)
+	b.bool("obsess_over_services", g.ObsessOverServices)
+	b.bool("obsess_over_hosts", g.ObsessOverHosts)
+	b.bool("check_service_freshness", g.CheckServiceFreshness)
+	b.bool("check_host_freshness", g.CheckHostFreshness)
+	b.bool("enable_flap_detection", g.EnableFlapDetection)
+	b.bool("process_performance_data", g.ProcessPerformanceData)
+	b.str("global_host_event_handler", g.GlobalHostEventHandler)
+	b.str("global_service_event_handler", g.GlobalServiceEventHandler)
+	b.uint("next_comment_id", nextCommentID(g, sw.Comments))
+	b.uint("next_downtime_id", nextDowntimeID(g, sw.Downtimes))
+	b.uint("next_event_id", g.NextEventID)
+	b.uint("next_problem_id", g.NextProblemID)
+	b.uint("next_notification_id", g.NextNotificationID)
+	b.end()
 }
 
-func (sw *StatusWriter) writeHostStatus(b *strings.Builder, h *objects.Host) {
-	b.WriteString("hoststatus {\n")
-	fmt.Fprintf(b, "\thost_name=%s\n", h.Name)
-	fmt.Fprintf(b, "\tmodified_attributes=%d\n", h.ModifiedAttributes)
+func (sw *StatusWriter) writeHostStatus(b *blockBuf, h *objects.Host) {
+	b.begin("hoststatus")
+	b.str("host_name", h.Name)
+	b.uint("modified_attributes", h.ModifiedAttributes)
 	writeCheckCommand(b, h.CheckCommand, h.CheckCommandArgs)
 	writeTimeperiodName(b, "check_period", h.CheckPeriod)
 	writeTimeperiodName(b, "notification_period", h.NotificationPeriod)
-	fmt.Fprintf(b, "\tcheck_interval=%f\n", h.CheckInterval)
-	fmt.Fprintf(b, "\tretry_interval=%f\n", h.RetryInterval)
+	b.float("check_interval", h.CheckInterval)
+	b.float("retry_interval", h.RetryInterval)
 	writeCommandName(b, "event_handler", h.EventHandler)
-	fmt.Fprintf(b, "\thas_been_checked=%s\n", boolStr(h.HasBeenChecked))
-	fmt.Fprintf(b, "\tshould_be_scheduled=%s\n", boolStr(h.ShouldBeScheduled))
-	fmt.Fprintf(b, "\tcheck_execution_time=%f\n", h.ExecutionTime)
-	fmt.Fprintf(b, "\tcheck_latency=%f\n", h.Latency)
-	fmt.Fprintf(b, "\tcheck_type=%d\n", h.CheckType)
-	fmt.Fprintf(b, "\tcurrent_state=%d\n", h.CurrentState)
-	fmt.Fprintf(b, "\tlast_hard_state=%d\n", h.LastHardState)
-	fmt.Fprintf(b, "\tplugin_output=%s\n", h.PluginOutput)
-	fmt.Fprintf(b, "\tlong_plugin_output=%s\n", h.LongPluginOutput)
-	fmt.Fprintf(b, "\tperformance_data=%s\n", h.PerfData)
-	fmt.Fprintf(b, "\tlast_check=%d\n", timeToUnix(h.LastCheck))
-	fmt.Fprintf(b, "\tnext_check=%d\n", timeToUnix(h.NextCheck))
-	fmt.Fprintf(b, "\tcurrent_attempt=%d\n", h.CurrentAttempt)
-	fmt.Fprintf(b, "\tmax_attempts=%d\n", h.MaxCheckAttempts)
-	fmt.Fprintf(b, "\tstate_type=%d\n", h.StateType)
-	fmt.Fprintf(b, "\tlast_state_change=%d\n", timeToUnix(h.LastStateChange))
-	fmt.Fprintf(b, "\tlast_hard_state_change=%d\n", timeToUnix(h.LastHardStateChange))
-	fmt.Fprintf(b, "\tlast_time_up=%d\n", timeToUnix(h.LastTimeUp))
-	fmt.Fprintf(b, "\tlast_time_down=%d\n", timeToUnix(h.LastTimeDown))
-	fmt.Fprintf(b, "\tlast_time_unreachable=%d\n", timeToUnix(h.LastTimeUnreachable))
-	fmt.Fprintf(b, "\tlast_notification=%d\n", timeToUnix(h.LastNotification))
-	fmt.Fprintf(b, "\tnext_notification=%d\n", timeToUnix(h.NextNotification))
-	fmt.Fprintf(b, "\tno_more_notifications=%s\n", boolStr(h.NoMoreNotifications))
-	fmt.Fprintf(b, "\tcurrent_notification_number=%d\n", h.CurrentNotificationNumber)
-	fmt.Fprintf(b, "\tcurrent_notification_id=%d\n", h.CurrentNotificationID)
-	fmt.Fprintf(b, "\tnotifications_enabled=%s\n", boolStr(h.NotificationsEnabled))
-	fmt.Fprintf(b, "\tproblem_has_been_acknowledged=%s\n", boolStr(h.ProblemAcknowledged))
-	fmt.Fprintf(b, "\tacknowledgement_type=%d\n", h.AckType)
-	fmt.Fprintf(b, "\tactive_checks_enabled=%s\n", boolStr(h.ActiveChecksEnabled))
-	fmt.Fprintf(b, "\tpassive_checks_enabled=%s\n", boolStr(h.PassiveChecksEnabled))
-	fmt.Fprintf(b, "\tevent_handler_enabled=%s\n", boolStr(h.EventHandlerEnabled))
-	fmt.Fprintf(b, "\tflap_detection_enabled=%s\n", boolStr(h.FlapDetectionEnabled))
-	fmt.Fprintf(b, "\tprocess_performance_data=%s\n", boolStr(h.ProcessPerfData))
-	fmt.Fprintf(b, "\tobsess=%s\n", boolStr(h.ObsessOver))
-	fmt.Fprintf(b, "\tis_flapping=%s\n", boolStr(h.IsFlapping))
-	fmt.Fprintf(b, "\tpercent_state_change=%f\n", h.PercentStateChange)
-	fmt.Fprintf(b, "\tscheduled_downtime_depth=%d\n", h.ScheduledDowntimeDepth)
-	for k, v := range h.CustomVars {
-		fmt.Fprintf(b, "\t_%s=%d;%s\n", k, 0, v)
-	}
-	b.WriteString("\t}\n\n")
+	b.bool("has_been_checked", h.HasBeenChecked)
+	b.bool("should_be_scheduled", h.ShouldBeScheduled)
+	b.float("check_execution_time", h.ExecutionTime)
+	b.float("check_latency", h.Latency)
+	b.int("check_type", h.CheckType)
+	b.int("current_state", h.CurrentState)
+	b.int("last_hard_state", h.LastHardState)
+	b.str("plugin_output", h.PluginOutput)
+	b.str("long_plugin_output", h.LongPluginOutput)
+	b.str("performance_data", h.PerfData)
+	b.str("check_source", h.CheckSource)
+	b.time("last_check", h.LastCheck)
+	b.time("next_check", h.NextCheck)
+	b.int("current_attempt", h.CurrentAttempt)
+	b.int("max_attempts", h.MaxCheckAttempts)
+	b.int("state_type", h.StateType)
+	b.time("last_state_change", h.LastStateChange)
+	b.time("last_hard_state_change", h.LastHardStateChange)
+	b.time("last_time_up", h.LastTimeUp)
+	b.time("last_time_down", h.LastTimeDown)
+	b.time("last_time_unreachable", h.LastTimeUnreachable)
+	b.time("last_notification", h.LastNotification)
+	b.time("next_notification", h.NextNotification)
+	b.bool("no_more_notifications", h.NoMoreNotifications)
+	b.int("current_notification_number", h.CurrentNotificationNumber)
+	b.uint("current_notification_id", h.CurrentNotificationID)
+	b.bool("notifications_enabled", h.NotificationsEnabled)
+	b.bool("problem_has_been_acknowledged", h.ProblemAcknowledged)
+	b.int("acknowledgement_type", h.AckType)
+	b.bool("active_checks_enabled", h.ActiveChecksEnabled)
+	b.bool("passive_checks_enabled", h.PassiveChecksEnabled)
+	b.bool("event_handler_enabled", h.EventHandlerEnabled)
+	b.bool("flap_detection_enabled", h.FlapDetectionEnabled)
+	b.bool("process_performance_data", h.ProcessPerfData)
+	b.bool("obsess", h.ObsessOver)
+	b.bool("is_flapping", h.IsFlapping)
+	b.float("percent_state_change", h.PercentStateChange)
+	b.int("scheduled_downtime_depth", h.ScheduledDowntimeDepth)
+	b.customVars(h.CustomVars)
+	b.end()
 }
 
-func (sw *StatusWriter) writeServiceStatus(b *strings.Builder, s *objects.Service) {
+func (sw *StatusWriter) writeServiceStatus(b *blockBuf, s *objects.Service) {
 	hostName := ""
 	if s.Host != nil {
 		hostName = s.Host.Name
 	}
-	b.WriteString("servicestatus {\n")
-	fmt.Fprintf(b, "\thost_name=%s\n", hostName)
-	fmt.Fprintf(b, "\tservice_description=%s\n", s.Description)
-	fmt.Fprintf(b, "\tmodified_attributes=%d\n", s.ModifiedAttributes)
+	b.begin("servicestatus")
+	b.str("host_name", hostName)
+	b.str("service_description", s.Description)
+	b.uint("modified_attributes", s.ModifiedAttributes)
 	writeCheckCommand(b, s.CheckCommand, s.CheckCommandArgs)
 	writeTimeperiodName(b, "check_period", s.CheckPeriod)
 	writeTimeperiodName(b, "notification_period", s.NotificationPeriod)
-	fmt.Fprintf(b, "\tcheck_interval=%f\n", s.CheckInterval)
-	fmt.Fprintf(b, "\tretry_interval=%f\n", s.RetryInterval)
+	b.float("check_interval", s.CheckInterval)
+	b.float("retry_interval", s.RetryInterval)
 	writeCommandName(b, "event_handler", s.EventHandler)
-	fmt.Fprintf(b, "\thas_been_checked=%s\n", boolStr(s.HasBeenChecked))
-	fmt.Fprintf(b, "\tshould_be_scheduled=%s\n", boolStr(s.ShouldBeScheduled))
-	fmt.Fprintf(b, "\tcheck_execution_time=%f\n", s.ExecutionTime)
-	fmt.Fprintf(b, "\tcheck_latency=%f\n", s.Latency)
-	fmt.Fprintf(b, "\tcheck_type=%d\n", s.CheckType)
-	fmt.Fprintf(b, "\tcurrent_state=%d\n", s.CurrentState)
-	fmt.Fprintf(b, "\tlast_hard_state=%d\n", s.LastHardState)
-	fmt.Fprintf(b, "\tplugin_output=%s\n", s.PluginOutput)
-	fmt.Fprintf(b, "\tlong_plugin_output=%s\n", s.LongPluginOutput)
-	fmt.Fprintf(b, "\tperformance_data=%s\n", s.PerfData)
-	fmt.Fprintf(b, "\tlast_check=%d\n", timeToUnix(s.LastCheck))
-	fmt.Fprintf(b, "\tnext_check=%d\n", timeToUnix(s.NextCheck))
-	fmt.Fprintf(b, "\tcurrent_attempt=%d\n", s.CurrentAttempt)
-	fmt.Fprintf(b, "\tmax_attempts=%d\n", s.MaxCheckAttempts)
-	fmt.Fprintf(b, "\tstate_type=%d\n", s.StateType)
-	fmt.Fprintf(b, "\tlast_state_change=%d\n", timeToUnix(s.LastStateChange))
-	fmt.Fprintf(b, "\tlast_hard_state_change=%d\n", timeToUnix(s.LastHardStateChange))
-	fmt.Fprintf(b, "\tlast_time_ok=%d\n", timeToUnix(s.LastTimeOK))
-	fmt.Fprintf(b, "\tlast_time_warning=%d\n", timeToUnix(s.LastTimeWarning))
-	fmt.Fprintf(b, "\tlast_time_critical=%d\n", timeToUnix(s.LastTimeCritical))
-	fmt.Fprintf(b, "\tlast_time_unknown=%d\n", timeToUnix(s.LastTimeUnknown))
-	fmt.Fprintf(b, "\tlast_notification=%d\n", timeToUnix(s.LastNotification))
-	fmt.Fprintf(b, "\tnext_notification=%d\n", timeToUnix(s.NextNotification))
-	fmt.Fprintf(b, "\tno_more_notifications=%s\n", boolStr(s.NoMoreNotifications))
-	fmt.Fprintf(b, "\tcurrent_notification_number=%d\n", s.CurrentNotificationNumber)
-	fmt.Fprintf(b, "\tcurrent_notification_id=%d\n", s.CurrentNotificationID)
-	fmt.Fprintf(b, "\tnotifications_enabled=%s\n", boolStr(s.NotificationsEnabled))
-	fmt.Fprintf(b, "\tproblem_has_been_acknowledged=%s\n", boolStr(s.ProblemAcknowledged))
-	fmt.Fprintf(b, "\tacknowledgement_type=%d\n", s.AckType)
-	fmt.Fprintf(b, "\tactive_checks_enabled=%s\n", boolStr(s.ActiveChecksEnabled))
-	fmt.Fprintf(b, "\tpassive_checks_enabled=%s\n", boolStr(s.PassiveChecksEnabled))
-	fmt.Fprintf(b, "\tevent_handler_enabled=%s\n", boolStr(s.EventHandlerEnabled))
-	fmt.Fprintf(b, "\tflap_detection_enabled=%s\n", boolStr(s.FlapDetectionEnabled))
-	fmt.Fprintf(b, "\tprocess_performance_data=%s\n", boolStr(s.ProcessPerfData))
-	fmt.Fprintf(b, "\tobsess=%s\n", boolStr(s.ObsessOver))
-	fmt.Fprintf(b, "\tis_flapping=%s\n", boolStr(s.IsFlapping))
-	fmt.Fprintf(b, "\tpercent_state_change=%f\n", s.PercentStateChange)
-	fmt.Fprintf(b, "\tscheduled_downtime_depth=%d\n", s.ScheduledDowntimeDepth)
-	for k, v := range s.CustomVars {
-		fmt.Fprintf(b, "\t_%s=%d;%s\n", k, 0, v)
-	}
-	b.WriteString("\t}\n\n")
+	b.bool("has_been_checked", s.HasBeenChecked)
+	b.bool("should_be_scheduled", s.ShouldBeScheduled)
+	b.float("check_execution_time", s.ExecutionTime)
+	b.float("check_latency", s.Latency)
+	b.int("check_type", s.CheckType)
+	b.int("current_state", s.CurrentState)
+	b.int("last_hard_state", s.LastHardState)
+	b.str("plugin_output", s.PluginOutput)
+	b.str("long_plugin_output", s.LongPluginOutput)
+	b.str("performance_data", s.PerfData)
+	b.str("check_source", s.CheckSource)
+	b.time("last_check", s.LastCheck)
+	b.time("next_check", s.NextCheck)
+	b.int("current_attempt", s.CurrentAttempt)
+	b.int("max_attempts", s.MaxCheckAttempts)
+	b.int("state_type", s.StateType)
+	b.time("last_state_change", s.LastStateChange)
+	b.time("last_hard_state_change", s.LastHardStateChange)
+	b.time("last_time_ok", s.LastTimeOK)
+	b.time("last_time_warning", s.LastTimeWarning)
+	b.time("last_time_critical", s.LastTimeCritical)
+	b.time("last_time_unknown", s.LastTimeUnknown)
+	b.time("last_notification", s.LastNotification)
+	b.time("next_notification", s.NextNotification)
+	b.bool("no_more_notifications", s.NoMoreNotifications)
+	b.int("current_notification_number", s.CurrentNotificationNumber)
+	b.uint("current_notification_id", s.CurrentNotificationID)
+	b.bool("notifications_enabled", s.NotificationsEnabled)
+	b.bool("problem_has_been_acknowledged", s.ProblemAcknowledged)
+	b.int("acknowledgement_type", s.AckType)
+	b.bool("active_checks_enabled", s.ActiveChecksEnabled)
+	b.bool("passive_checks_enabled", s.PassiveChecksEnabled)
+	b.bool("event_handler_enabled", s.EventHandlerEnabled)
+	b.bool("flap_detection_enabled", s.FlapDetectionEnabled)
+	b.bool("process_performance_data", s.ProcessPerfData)
+	b.bool("obsess", s.ObsessOver)
+	b.bool("is_flapping", s.IsFlapping)
+	b.float("percent_state_change", s.PercentStateChange)
+	b.int("scheduled_downtime_depth", s.ScheduledDowntimeDepth)
+	b.customVars(s.CustomVars)
+	b.end()
 }
 
-func (sw *StatusWriter) writeComment(b *strings.Builder, c *downtime.Comment) {
+func (sw *StatusWriter) writeContactStatus(b *blockBuf, c *objects.Contact) {
+	b.begin("contactstatus")
+	b.str("contact_name", c.Name)
+	b.uint("modified_attributes", c.ModifiedAttributes)
+	b.uint("modified_host_attributes", c.ModifiedHostAttributes)
+	b.uint("modified_service_attributes", c.ModifiedServiceAttributes)
+	writeTimeperiodName(b, "host_notification_period", c.HostNotificationPeriod)
+	writeTimeperiodName(b, "service_notification_period", c.ServiceNotificationPeriod)
+	b.int64("last_host_notification", timeToUnix(c.LastHostNotification))
+	b.int64("last_service_notification", timeToUnix(c.LastServiceNotification))
+	b.bool("host_notifications_enabled", c.HostNotificationsEnabled)
+	b.bool("service_notifications_enabled", c.ServiceNotificationsEnabled)
+	b.customVars(c.CustomVars)
+	b.end()
+}
+
+func (sw *StatusWriter) writeComment(b *blockBuf, c *downtime.Comment) {
 	blockName := "hostcomment"
 	if c.CommentType == objects.ServiceCommentType {
 		blockName = "servicecomment"
 	}
-	fmt.Fprintf(b, "%s {\n", blockName)
-	fmt.Fprintf(b, "\thost_name=%s\n", c.HostName)
+	b.begin(blockName)
+	b.str("host_name", c.HostName)
 	if c.CommentType == objects.ServiceCommentType {
-		fmt.Fprintf(b, "\tservice_description=%s\n", c.ServiceDescription)
+		b.str("service_description", c.ServiceDescription)
 	}
-	fmt.Fprintf(b, "\tentry_type=%d\n", c.EntryType)
-	fmt.Fprintf(b, "\tcomment_id=%d\n", c.CommentID)
-	fmt.Fprintf(b, "\tsource=%d\n", c.Source)
-	fmt.Fprintf(b, "\tpersistent=%s\n", boolStr(c.Persistent))
-	fmt.Fprintf(b, "\tentry_time=%d\n", c.EntryTime.Unix())
-	fmt.Fprintf(b, "\texpires=%s\n", boolStr(c.Expires))
-	fmt.Fprintf(b, "\texpire_time=%d\n", timeToUnix(c.ExpireTime))
-	fmt.Fprintf(b, "\tauthor=%s\n", c.Author)
-	fmt.Fprintf(b, "\tcomment_data=%s\n", c.Data)
-	b.WriteString("\t}\n\n")
+	b.int("entry_type", c.EntryType)
+	b.uint("comment_id", c.CommentID)
+	b.int("source", c.Source)
+	b.bool("persistent", c.Persistent)
+	b.int64("entry_time", c.EntryTime.Unix())
+	b.bool("expires", c.Expires)
+	b.time("expire_time", c.ExpireTime)
+	b.str("author", c.Author)
+	b.str("comment_data", c.Data)
+	b.end()
 }
 
-func (sw *StatusWriter) writeDowntime(b *strings.Builder, d *downtime.Downtime) {
+func (sw *StatusWriter) writeDowntime(b *blockBuf, d *downtime.Downtime) {
 	blockName := "hostdowntime"
 	if d.Type == objects.ServiceDowntimeType {
 		blockName = "servicedowntime"
 	}
-	fmt.Fprintf(b, "%s {\n", blockName)
-	fmt.Fprintf(b, "\thost_name=%s\n", d.HostName)
+	b.begin(blockName)
+	b.str("host_name", d.HostName)
 	if d.Type == objects.ServiceDowntimeType {
-		fmt.Fprintf(b, "\tservice_description=%s\n", d.ServiceDescription)
+		b.str("service_description", d.ServiceDescription)
 	}
-	fmt.Fprintf(b, "\tdowntime_id=%d\n", d.DowntimeID)
-	fmt.Fprintf(b, "\tentry_time=%d\n", d.EntryTime.Unix())
-	fmt.Fprintf(b, "\tstart_time=%d\n", d.StartTime.Unix())
-	fmt.Fprintf(b, "\tend_time=%d\n", d.EndTime.Unix())
-	fmt.Fprintf(b, "\ttriggered_by=%d\n", d.TriggeredBy)
-	fmt.Fprintf(b, "\tfixed=%s\n", boolStr(d.Fixed))
-	fmt.Fprintf(b, "\tduration=%d\n", int64(d.Duration.Seconds()))
-	fmt.Fprintf(b, "\tis_in_effect=%s\n", boolStr(d.IsInEffect))
-	fmt.Fprintf(b, "\tauthor=%s\n", d.Author)
-	fmt.Fprintf(b, "\tcomment=%s\n", d.Comment)
-	b.WriteString("\t}\n\n")
+	b.uint("downtime_id", d.DowntimeID)
+	b.int64("entry_time", d.EntryTime.Unix())
+	b.int64("start_time", d.StartTime.Unix())
+	b.int64("end_time", d.EndTime.Unix())
+	b.uint("triggered_by", d.TriggeredBy)
+	b.bool("fixed", d.Fixed)
+	b.int64("duration", int64(d.Duration.Seconds()))
+	b.bool("is_in_effect", d.IsInEffect)
+	b.str("author", d.Author)
+	b.str("comment", d.Comment)
+	b.end()
 }
 
 func boolStr(b bool) string {
@@ -285,29 +311,30 @@ func timeToUnix(t time.Time) int64 {
 	return t.Unix()
 }
 
-func writeCheckCommand(b *strings.Builder, cmd *objects.Command, args string) {
-	name := ""
+func writeCheckCommand(b *blockBuf, cmd *objects.Command, args string) {
+	b.key("check_command")
 	if cmd != nil {
-		name = cmd.Name
+		b.b = append(b.b, cmd.Name...)
 	}
 	if args != "" {
-		name += "!" + args
+		b.b = append(b.b, '!')
+		b.b = append(b.b, args...)
 	}
-	fmt.Fprintf(b, "\tcheck_command=%s\n", name)
+	b.b = append(b.b, '\n')
 }
 
-func writeTimeperiodName(b *strings.Builder, field string, tp *objects.Timeperiod) {
+func writeTimeperiodName(b *blockBuf, field string, tp *objects.Timeperiod) {
 	name := ""
 	if tp != nil {
 		name = tp.Name
 	}
-	fmt.Fprintf(b, "\t%s=%s\n", field, name)
+	b.str(field, name)
 }
 
-func writeCommandName(b *strings.Builder, field string, cmd *objects.Command) {
+func writeCommandName(b *blockBuf, field string, cmd *objects.Command) {
 	name := ""
 	if cmd != nil {
 		name = cmd.Name
 	}
-	fmt.Fprintf(b, "\t%s=%s\n", field, name)
+	b.str(field, name)
 }
@@ -1,6 +1,7 @@
 package status
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -36,9 +37,19 @@ func TestStatusWriter_Write(t *testing.T) {
 		HasBeenChecked:       true,
 		NotificationsEnabled: true,
 		PluginOutput:         "HTTP OK",
+		CheckSource:          "NRDP 10.0.0.5",
 	}
 	store.AddService(svc)
 
+	store.AddContact(&objects.Contact{
+		Name:                      "oncall",
+		HostNotificationsEnabled:  true,
+		ServiceNotificationPeriod: &objects.Timeperiod{Name: "workhours"},
+		LastServiceNotification:   time.Unix(1700000000, 0),
+		ModifiedAttributes:        objects.ModAttrNotificationsEnabled,
+		CustomVars:                map[string]string{"PAGER": "555-0100"},
+	})
+
 	cm := downtime.NewCommentManager(1)
 	dm := downtime.NewDowntimeManager(1, cm, store)
 
@@ -84,6 +95,13 @@ func TestStatusWriter_Write(t *testing.T) {
 		"service_description=HTTP",
 		"plugin_output=OK - Host alive",
 		"enable_notifications=1",
+		"check_source=NRDP 10.0.0.5",
+		"contactstatus {\n\tcontact_name=oncall\n\tmodified_attributes=1\n" +
+			"\tmodified_host_attributes=0\n\tmodified_service_attributes=0\n" +
+			"\thost_notification_period=\n\tservice_notification_period=workhours\n" +
+			"\tlast_host_notification=0\n\tlast_service_notification=1700000000\n" +
+			"\thost_notifications_enabled=1\n\tservice_notifications_enabled=0\n" +
+			"\t_PAGER=0;555-0100\n\t}",
 	} {
 		if !strings.Contains(content, expected) {
 			t.Errorf("status.dat missing expected string: %s", expected)
@@ -109,6 +127,7 @@ func TestRetentionWriter_WriteAndRead(t *testing.T) {
 		NotifiedOn:           objects.OptDown,
 		ProblemAcknowledged:  true,
 		AckType:              objects.AckSticky,
+		ModifiedAttributes:   objects.ModAttrPassiveChecksEnabled,
 	}
 	store.AddHost(h)
 
@@ -150,7 +169,7 @@ func TestRetentionWriter_WriteAndRead(t *testing.T) {
 
 	// Read it back
 	store2 := objects.NewObjectStore()
-	h2 := &objects.Host{Name: "host1"}
+	h2 := &objects.Host{Name: "host1", PassiveChecksEnabled: true}
 	store2.AddHost(h2)
 	contact2 := &objects.Contact{Name: "admin"}
 	store2.AddContact(contact2)
@@ -182,7 +201,192 @@ func TestRetentionWriter_WriteAndRead(t *testing.T) {
 	if h2.AckType != objects.AckSticky {
 		t.Errorf("expected sticky ack, got %d", h2.AckType)
 	}
+	if h2.PassiveChecksEnabled || h2.ModifiedAttributes != objects.ModAttrPassiveChecksEnabled {
+		t.Errorf("expected modified passive checks to be retained, got enabled=%v modified_attributes=%d",
+			h2.PassiveChecksEnabled, h2.ModifiedAttributes)
+	}
 	if gs2.NextNotificationID != 50 {
 		t.Errorf("expected next_notification_id=50, got %d", gs2.NextNotificationID)
 	}
 }
+
+func TestRetention_Shards(t *testing.T) {
+	retPath := t.TempDir() + "/retention.dat"
+	build := func(checked bool) (*objects.ObjectStore, *downtime.CommentManager, *downtime.DowntimeManager) {
+		store := objects.NewObjectStore()
+		for i := 0; i < 5; i++ {
+			h := &objects.Host{Name: fmt.Sprintf("host%d", i), HasBeenChecked: checked}
+			store.AddHost(h)
+			for j := 0; j < 2; j++ {
+				store.AddService(&objects.Service{Host: h, Description: fmt.Sprintf("svc%d", j), HasBeenChecked: checked,
+					PluginOutput: fmt.Sprintf("OK - %d/%d", i, j)})
+			}
+		}
+		cm := downtime.NewCommentManager(1)
+		return store, cm, downtime.NewDowntimeManager(1, cm, store)
+	}
+	store, cm, dm := build(true)
+	cm.Add(&downtime.Comment{HostName: "host3", CommentType: objects.HostCommentType, Persistent: true, Author: "ops", Data: "rack move"})
+	rw := &RetentionWriter{Path: retPath, Store: store, Global: &objects.GlobalState{},
+		Comments: cm, Downtimes: dm, Version: "test", Shards: 3}
+	if err := rw.Write(); err != nil {
+		t.Fatal(err)
+	}
+	main, err := os.ReadFile(retPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(main), "shards=3") || strings.Contains(string(main), "host {") {
+		t.Errorf("expected the main file to name 3 shards and hold no hosts:\n%s", main)
+	}
+	for i := 1; i <= 3; i++ {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", retPath, i)); err != nil {
+			t.Errorf("shard %d: %v", i, err)
+		}
+	}
+
+	store2, cm2, dm2 := build(false)
+	rr := &RetentionReader{Store: store2, Global: &objects.GlobalState{}, Comments: cm2, Downtimes: dm2}
+	if err := rr.Read(retPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range store2.Hosts {
+		if !h.HasBeenChecked {
+			t.Errorf("%s: state not read from its shard", h.Name)
+		}
+	}
+	if svc := store2.GetService("host4", "svc1"); svc == nil || svc.PluginOutput != "OK - 4/1" {
+		t.Errorf("host4/svc1: expected retained output, got %+v", svc)
+	}
+	if len(cm2.All()) != 1 {
+		t.Errorf("expected the comment from the main file, got %d", len(cm2.All()))
+	}
+
+	// Fewer shards, then none: the extra shard files are removed and the
+	// single file is read as before.
+	rw.Shards = 2
+	if err := rw.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(retPath + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected shard 3 to be removed, got %v", err)
+	}
+	rw.Shards = 0
+	if err := rw.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(retPath + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected shards to be removed once unsharded, got %v", err)
+	}
+	store3, cm3, dm3 := build(false)
+	rr = &RetentionReader{Store: store3, Global: &objects.GlobalState{}, Comments: cm3, Downtimes: dm3}
+	if err := rr.Read(retPath); err != nil {
+		t.Fatal(err)
+	}
+	if !store3.GetHost("host2").HasBeenChecked {
+		t.Error("host2: state not read from the single file")
+	}
+
+	// A missing shard is reported, but the others are still applied.
+	rw.Shards = 2
+	if err := rw.Write(); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(retPath + ".2")
+	store4, cm4, dm4 := build(false)
+	rr = &RetentionReader{Store: store4, Global: &objects.GlobalState{}, Comments: cm4, Downtimes: dm4}
+	if err := rr.Read(retPath); err == nil {
+		t.Error("expected an error for the missing shard")
+	}
+	if !store4.GetHost("host0").HasBeenChecked || store4.GetHost("host1").HasBeenChecked {
+		t.Error("expected only the hosts of shard 1 to be restored")
+	}
+}
+
+func TestRetention_RuntimeBlackouts(t *testing.T) {
+	retPath := t.TempDir() + "/retention.dat"
+	store := objects.NewObjectStore()
+	cm := downtime.NewCommentManager(1)
+	dm := downtime.NewDowntimeManager(1, cm, store)
+	bm := downtime.NewBlackoutManager(store)
+	bm.Add(&objects.Blackout{Name: "configured"})
+	bm.Add(&objects.Blackout{Name: "release", EndTime: time.Now().Add(time.Hour).Truncate(time.Second),
+		HostNames: []string{"web*", "db01"}, ServiceGroups: []string{"frontend"},
+		Author: "ops", Comment: "v2 rollout", Runtime: true})
+	bm.Add(&objects.Blackout{Name: "over", EndTime: time.Now().Add(-time.Minute), Runtime: true})
+
+	rw := &RetentionWriter{Path: retPath, Store: store, Global: &objects.GlobalState{},
+		Comments: cm, Downtimes: dm, Blackouts: bm, Version: "test"}
+	if err := rw.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	bm2 := downtime.NewBlackoutManager(store)
+	rr := &RetentionReader{Store: store, Global: &objects.GlobalState{}, Comments: cm, Downtimes: dm, Blackouts: bm2}
+	if err := rr.Read(retPath); err != nil {
+		t.Fatal(err)
+	}
+	all := bm2.All()
+	if len(all) != 1 || all[0].Name != "release" {
+		t.Fatalf("expected only the live runtime blackout to be retained, got %+v", all)
+	}
+	b := all[0]
+	if !b.Runtime || len(b.HostNames) != 2 || b.ServiceGroups[0] != "frontend" || b.Comment != "v2 rollout" || !b.StartTime.IsZero() {
+		t.Errorf("unexpected blackout %+v", b)
+	}
+}
+
+// Deleted comments and downtimes must not give their IDs back on restart,
+// including a second restart from state written by a restored process.
+func TestRetention_IDsSurviveRestart(t *testing.T) {
+	retPath := t.TempDir() + "/retention.dat"
+	store := objects.NewObjectStore()
+	store.AddHost(&objects.Host{Name: "web01"})
+
+	restart := func() (*downtime.CommentManager, *downtime.DowntimeManager, *objects.GlobalState) {
+		cm := downtime.NewCommentManager(1)
+		dm := downtime.NewDowntimeManager(1, cm, store)
+		gs := &objects.GlobalState{NextCommentID: 1, NextDowntimeID: 1}
+		rr := &RetentionReader{Store: store, Global: gs, Comments: cm, Downtimes: dm}
+		if err := rr.Read(retPath); err != nil {
+			t.Fatal(err)
+		}
+		return cm, dm, gs
+	}
+	save := func(cm *downtime.CommentManager, dm *downtime.DowntimeManager, gs *objects.GlobalState) {
+		rw := &RetentionWriter{Path: retPath, Store: store, Global: gs, Comments: cm, Downtimes: dm, Version: "test"}
+		if err := rw.Write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cm, dm, gs := restart()
+	var last uint64
+	for i := 0; i < 3; i++ {
+		last = cm.Add(&downtime.Comment{CommentType: objects.HostCommentType, HostName: "web01", Persistent: true, Data: "note"})
+		cm.Delete(last)
+	}
+	now := time.Now()
+	did := dm.Schedule(&downtime.Downtime{Type: objects.HostDowntimeType, HostName: "web01",
+		StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), Fixed: true})
+	dm.Unschedule(did)
+	save(cm, dm, gs)
+
+	for round := 1; round <= 2; round++ {
+		cm, dm, gs = restart()
+		if id := cm.Add(&downtime.Comment{CommentType: objects.HostCommentType, HostName: "web01", Data: "x"}); id <= last {
+			t.Errorf("restart %d: comment ID %d reused (last allocated %d)", round, id, last)
+		} else {
+			last = id
+		}
+		if id := dm.Schedule(&downtime.Downtime{Type: objects.HostDowntimeType, HostName: "web01",
+			StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour), Fixed: true}); id <= did {
+			t.Errorf("restart %d: downtime ID %d reused (last allocated %d)", round, id, did)
+		} else {
+			did = id
+		}
+		// The downtime's comment also took a comment ID.
+		last = cm.NextID() - 1
+		save(cm, dm, gs)
+	}
+}